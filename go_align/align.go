@@ -0,0 +1,261 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// width is the display width of s, counting each rune as one column
+func width(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// pad right-pads s with spaces to n columns
+func pad(s string, n int) string {
+	return s + strings.Repeat(" ", max(n-width(s), 0))
+}
+
+// indentOf returns the leading whitespace of line
+func indentOf(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// assignment is a line split around its assignment operator
+type assignment struct {
+	indent, lhs, op, rhs string
+}
+
+// splitAssignment finds the first assignment operator (=, :=, +=, ...)
+// outside strings and brackets. Comparisons (==, !=, <=, >=) are not
+// assignments.
+func splitAssignment(line string) (assignment, bool) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+			continue
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			continue
+		case c != '=' || depth != 0:
+			continue
+		}
+
+		if i+1 < len(line) && line[i+1] == '=' {
+			// ==
+			i++
+			continue
+		}
+		start := i
+		if i > 0 && strings.IndexByte(":+-*/%&|^<>!", line[i-1]) >= 0 {
+			start = i - 1
+			switch line[i-1] {
+			case '!', '<', '>':
+				// != <= >=, unless <<= or >>=
+				if line[i-1] == '!' || i < 2 || line[i-2] != line[i-1] {
+					continue
+				}
+				start = i - 2
+			}
+		}
+		lhs := strings.TrimSpace(line[:start])
+		if lhs == "" {
+			return assignment{}, false
+		}
+		return assignment{
+			indent: indentOf(line),
+			lhs:    lhs,
+			op:     line[start : i+1],
+			rhs:    strings.TrimSpace(line[i+1:]),
+		}, true
+	}
+	return assignment{}, false
+}
+
+// isAssignment reports whether line is an assignment
+func isAssignment(line string) bool {
+	_, ok := splitAssignment(line)
+	return ok
+}
+
+// alignAssignments pads the left-hand sides so the operators line up
+func alignAssignments(lines []string) []string {
+	var parts []assignment
+	lhsWidth, opWidth := 0, 0
+	for _, line := range lines {
+		a, ok := splitAssignment(line)
+		parts = append(parts, a)
+		if ok {
+			lhsWidth = max(lhsWidth, width(a.indent+a.lhs))
+			opWidth = max(opWidth, width(a.op))
+		}
+	}
+
+	out := make([]string, len(lines))
+	for i, a := range parts {
+		if a.op == "" {
+			out[i] = lines[i]
+			continue
+		}
+		// Operators line up on their final =
+		lead := pad(a.indent+a.lhs, lhsWidth) + " " + strings.Repeat(" ", opWidth-width(a.op)) + a.op
+		out[i] = strings.TrimRight(lead+" "+a.rhs, " ")
+	}
+	return out
+}
+
+// field is a Go struct field split into columns
+type field struct {
+	indent, names, typ, tag string
+}
+
+// splitField splits a struct field declaration: names, type and the tag
+// (from the opening backquote on, with any trailing comment)
+func splitField(line string) (field, bool) {
+	code, tag := line, ""
+	if i := strings.IndexByte(line, '`'); i >= 0 {
+		code, tag = line[:i], strings.TrimSpace(line[i:])
+	}
+	if strings.Contains(code, "//") || strings.ContainsAny(code, "={}") {
+		return field{}, false
+	}
+
+	tokens := strings.Fields(code)
+	if len(tokens) < 2 {
+		return field{}, false
+	}
+	// Names are identifiers separated by commas: a, b int
+	n := 1
+	for n < len(tokens) && strings.HasSuffix(tokens[n-1], ",") {
+		n++
+	}
+	names := strings.Join(tokens[:n], " ")
+	for _, r := range strings.ReplaceAll(names, ", ", "") {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return field{}, false
+		}
+	}
+	if n >= len(tokens) {
+		return field{}, false
+	}
+	return field{
+		indent: indentOf(line),
+		names:  names,
+		typ:    strings.Join(tokens[n:], " "),
+		tag:    tag,
+	}, true
+}
+
+// isField reports whether line is a struct field declaration
+func isField(line string) bool {
+	_, ok := splitField(line)
+	return ok
+}
+
+// alignStructTags lines up the types and tags of struct fields, as gofmt
+// does
+func alignStructTags(lines []string) []string {
+	var fields []field
+	nameWidth, typeWidth := 0, 0
+	for _, line := range lines {
+		f, ok := splitField(line)
+		fields = append(fields, f)
+		if ok {
+			nameWidth = max(nameWidth, width(f.indent+f.names))
+			if f.tag != "" {
+				typeWidth = max(typeWidth, width(f.typ))
+			}
+		}
+	}
+
+	out := make([]string, len(lines))
+	for i, f := range fields {
+		switch {
+		case f.names == "":
+			out[i] = lines[i]
+		case f.tag == "":
+			out[i] = pad(f.indent+f.names, nameWidth) + " " + f.typ
+		default:
+			out[i] = pad(f.indent+f.names, nameWidth) + " " + pad(f.typ, typeWidth) + " " + f.tag
+		}
+	}
+	return out
+}
+
+// alignTable pads the cells between delimiters so each column lines up.
+// The block takes the first line's indentation.
+func alignTable(lines []string, delim string) []string {
+	var rows [][]string
+	var widths []int
+	for _, line := range lines {
+		if !strings.Contains(line, delim) {
+			rows = append(rows, nil)
+			continue
+		}
+		cells := strings.Split(strings.TrimSpace(line), delim)
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], width(cells[i]))
+		}
+		rows = append(rows, cells)
+	}
+
+	indent := ""
+	if len(lines) > 0 {
+		indent = indentOf(lines[0])
+	}
+	out := make([]string, len(lines))
+	for i, cells := range rows {
+		if cells == nil {
+			out[i] = lines[i]
+			continue
+		}
+		var b strings.Builder
+		for j, cell := range cells {
+			if j > 0 {
+				b.WriteString(" " + delim + " ")
+			}
+			b.WriteString(pad(cell, widths[j]))
+		}
+		// A leading delimiter (| a | b |) leaves an empty first cell
+		row := strings.TrimRight(b.String(), " ")
+		if cells[0] == "" {
+			row = strings.TrimLeft(row, " ")
+		}
+		out[i] = indent + row
+	}
+	return out
+}
+
+// block returns the 0-based bounds, first to last inclusive, of the run
+// of lines around line at that match
+func block(lines []string, line int, match func(string) bool) (first, last int, ok bool) {
+	if line < 0 || line >= len(lines) || !match(lines[line]) {
+		return 0, 0, false
+	}
+	first, last = line, line
+	for first > 0 && match(lines[first-1]) {
+		first--
+	}
+	for last+1 < len(lines) && match(lines[last+1]) {
+		last++
+	}
+	return first, last, true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAlignAssignments(t *testing.T) {
+	got := alignAssignments([]string{
+		"\tx := 1",
+		"\tlongName = f(a == b)",
+		"\tn += 2",
+		"\tif a != b {",
+	})
+	want := []string{
+		"\tx        := 1",
+		"\tlongName  = f(a == b)",
+		"\tn        += 2",
+		"\tif a != b {",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	for _, line := range []string{"if a == b {", "x <= y", "a != b"} {
+		if isAssignment(line) {
+			t.Errorf("%s: taken for an assignment", line)
+		}
+	}
+	if a, ok := splitAssignment(`s := "a=b"`); !ok || a.lhs != "s" || a.op != ":=" || a.rhs != `"a=b"` {
+		t.Errorf("string with =: %+v", a)
+	}
+	if a, ok := splitAssignment("mask <<= 2"); !ok || a.op != "<<=" {
+		t.Errorf("shift assignment: %+v", a)
+	}
+}
+
+func TestAlignStructTags(t *testing.T) {
+	got := alignStructTags([]string{
+		"\tID int `json:\"id\"`",
+		"\tDisplayName string `json:\"display_name\" yaml:\"name\"`",
+		"\ta, b bool",
+	})
+	want := []string{
+		"\tID          int    `json:\"id\"`",
+		"\tDisplayName string `json:\"display_name\" yaml:\"name\"`",
+		"\ta, b        bool",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if isField("type T struct {") || isField("}") || isField("// comment here") {
+		t.Error("non-field taken for a field")
+	}
+}
+
+func TestAlignTable(t *testing.T) {
+	got := alignTable([]string{
+		"  | name | qty |",
+		"|---|---|",
+		"| apple | 3 |",
+	}, "|")
+	want := []string{
+		"  | name  | qty |",
+		"  | ---   | --- |",
+		"  | apple | 3   |",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	lines := []string{"func f() {", "\ta := 1", "\tbb := 2", "", "\tc := 3"}
+	if first, last, ok := block(lines, 2, isAssignment); !ok || first != 1 || last != 2 {
+		t.Errorf("block %d-%d %v", first, last, ok)
+	}
+	if _, _, ok := block(lines, 3, isAssignment); ok {
+		t.Error("block on a blank line")
+	}
+}
@@ -0,0 +1,3 @@
+module go_align
+
+go 1.21
@@ -0,0 +1,194 @@
+// go_align - Column alignment for μEmacs
+//
+// Aligns the block of lines around point: the run of lines of the kind
+// being aligned. C-u marks the line point is on as the start of a region
+// instead; the next call aligns from there to point.
+//
+// Commands:
+//   align             - Align assignments or struct fields, whichever
+//                       the block holds
+//   align-assignments - Line up the = (:=, +=, ...) of assignments
+//   align-struct-tags - Line up the names, types and tags of Go struct
+//                       fields
+//   align-table       - Line up the columns between a delimiter
+//                       (prompts for it)
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern void api_free(void *ptr);
+*/
+import "C"
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// anchor is the region start set with C-u: a 1-based line of bp, 0 when
+// unset
+var anchor struct {
+	bp   unsafe.Pointer
+	line int
+}
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// replaceText replaces the contents of buffer bp (the current buffer)
+func replaceText(bp unsafe.Pointer, text string) {
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+}
+
+// alignWith aligns the anchored region, or the block of lines around
+// point that match, with align. With a prefix argument it sets the
+// anchor instead.
+func alignWith(cmd string, f C.int, match func(string) bool, align func([]string) []string) C.int {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	if f != 0 {
+		anchor.bp, anchor.line = bp, int(line)
+		message("%s: region starts at line %d; run %s again at its end", cmd, line, cmd)
+		return 1
+	}
+
+	text, ok := bufferText(bp)
+	if !ok {
+		message("%s: cannot read buffer", cmd)
+		return 0
+	}
+	lines := strings.Split(text, "\n")
+
+	var first, last int
+	if anchor.bp == bp && anchor.line > 0 {
+		first = min(anchor.line, int(line)) - 1
+		last = min(max(anchor.line, int(line)), len(lines)) - 1
+		anchor.bp, anchor.line = nil, 0
+	} else if first, last, ok = block(lines, int(line)-1, match); !ok {
+		message("%s: nothing to align at point", cmd)
+		return 0
+	}
+
+	before := lines[first : last+1]
+	after := align(before)
+	if reflect.DeepEqual(before, after) {
+		message("%s: already aligned", cmd)
+		return 1
+	}
+	out := append(append(append([]string(nil), lines[:first]...), after...), lines[last+1:]...)
+	replaceText(bp, strings.Join(out, "\n"))
+	C.api_set_point(line, col)
+	message("%s: aligned %d lines", cmd, len(after))
+	return 1
+}
+
+//export go_align_auto
+func go_align_auto(f, n C.int) C.int {
+	return alignWith("align", f, func(line string) bool {
+		return isField(line) || isAssignment(line)
+	}, func(lines []string) []string {
+		// Whichever kind most lines are; fields are tested first since a
+		// tag can hold an =
+		fields, assignments := 0, 0
+		for _, line := range lines {
+			switch {
+			case isField(line):
+				fields++
+			case isAssignment(line):
+				assignments++
+			}
+		}
+		if fields >= assignments {
+			return alignStructTags(lines)
+		}
+		return alignAssignments(lines)
+	})
+}
+
+//export go_align_assignments
+func go_align_assignments(f, n C.int) C.int {
+	return alignWith("align-assignments", f, isAssignment, alignAssignments)
+}
+
+//export go_align_struct_tags
+func go_align_struct_tags(f, n C.int) C.int {
+	return alignWith("align-struct-tags", f, isField, alignStructTags)
+}
+
+//export go_align_table
+func go_align_table(f, n C.int) C.int {
+	delim := ""
+	if f == 0 {
+		input, ok := prompt("Align on delimiter (default |): ")
+		if !ok {
+			message("Cancelled")
+			return 0
+		}
+		if delim = strings.TrimSpace(input); delim == "" {
+			delim = "|"
+		}
+	}
+	return alignWith("align-table", f, func(line string) bool {
+		return strings.Contains(line, delim)
+	}, func(lines []string) []string {
+		return alignTable(lines, delim)
+	})
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Bookmark is a named line of a file
+type Bookmark struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// find returns the index of the bookmark called name, or -1
+func find(marks []Bookmark, name string) int {
+	for i, b := range marks {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// set adds b, or replaces the bookmark of the same name where it stands
+// so the definition order is kept
+func set(marks []Bookmark, b Bookmark) []Bookmark {
+	if i := find(marks, b.Name); i >= 0 {
+		marks[i] = b
+		return marks
+	}
+	return append(marks, b)
+}
+
+// remove deletes the bookmark called name, reporting whether there was one
+func remove(marks []Bookmark, name string) ([]Bookmark, bool) {
+	i := find(marks, name)
+	if i < 0 {
+		return marks, false
+	}
+	return append(marks[:i], marks[i+1:]...), true
+}
+
+// step returns the index of the bookmark dir (1 or -1) places from
+// current, wrapping around; a current of -1 starts before the first
+func step(count, current, dir int) int {
+	if current < 0 || current >= count {
+		if dir > 0 {
+			return 0
+		}
+		return count - 1
+	}
+	return ((current+dir)%count + count) % count
+}
+
+// bookmarksPath returns where the bookmarks are persisted
+func bookmarksPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs", "bookmarks.json")
+}
+
+// loadBookmarks reads the persisted bookmarks; a missing file yields none
+func loadBookmarks(path string) ([]Bookmark, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var marks []Bookmark
+	if err := json.Unmarshal(data, &marks); err != nil {
+		return nil, err
+	}
+	return marks, nil
+}
+
+// saveBookmarks persists the bookmarks, replacing the file atomically
+func saveBookmarks(path string, marks []Bookmark) error {
+	if marks == nil {
+		marks = []Bookmark{}
+	}
+	data, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
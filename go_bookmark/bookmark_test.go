@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetRemove(t *testing.T) {
+	var marks []Bookmark
+	marks = set(marks, Bookmark{"a", "/src/a.go", 1})
+	marks = set(marks, Bookmark{"b", "/src/b.go", 2})
+	marks = set(marks, Bookmark{"a", "/src/a.go", 10})
+	want := []Bookmark{{"a", "/src/a.go", 10}, {"b", "/src/b.go", 2}}
+	if !reflect.DeepEqual(marks, want) {
+		t.Errorf("got %+v, want %+v", marks, want)
+	}
+
+	marks, ok := remove(marks, "a")
+	if !ok || !reflect.DeepEqual(marks, want[1:]) {
+		t.Errorf("remove a: %+v, %v", marks, ok)
+	}
+	if _, ok := remove(marks, "missing"); ok {
+		t.Error("removed a missing bookmark")
+	}
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		current, dir, want int
+	}{
+		{-1, 1, 0},
+		{-1, -1, 2},
+		{0, 1, 1},
+		{2, 1, 0},
+		{0, -1, 2},
+		{5, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := step(3, tt.current, tt.dir); got != tt.want {
+			t.Errorf("step(3, %d, %d) = %d, want %d", tt.current, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "muemacs", "bookmarks.json")
+	if marks, err := loadBookmarks(path); err != nil || marks != nil {
+		t.Fatalf("missing file: %+v, %v", marks, err)
+	}
+	want := []Bookmark{{"main", "/src/main.go", 42}, {"todo", "/src/TODO", 3}}
+	if err := saveBookmarks(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadBookmarks(path)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, %v, want %+v", got, err, want)
+	}
+}
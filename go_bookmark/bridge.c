@@ -0,0 +1,257 @@
+/*
+ * bridge.c - C/CGO Bridge for Go Bookmark Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Named file/line bookmarks persisted in
+ * ~/.config/muemacs/bookmarks.json.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*current_buffer_fn)(void);
+typedef const char *(*buffer_filename_fn)(void*);
+typedef const char *(*buffer_name_fn)(void*);
+typedef char *(*get_current_line_fn)(void);
+typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef void *(*buffer_create_fn)(const char*);
+typedef int (*buffer_switch_fn)(void*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef void (*free_fn)(void*);
+typedef int (*find_file_line_fn)(const char*, int);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    current_buffer_fn current_buffer;
+    buffer_filename_fn buffer_filename;
+    buffer_name_fn buffer_name;
+    get_current_line_fn get_current_line;
+    get_point_fn get_point;
+    set_point_fn set_point;
+    buffer_create_fn buffer_create;
+    buffer_switch_fn buffer_switch;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_fn prompt;
+    free_fn free;
+    find_file_line_fn find_file_line;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_current_buffer(void) {
+    if (api.current_buffer) return api.current_buffer();
+    return NULL;
+}
+
+const char* api_buffer_filename(void *bp) {
+    if (api.buffer_filename) return api.buffer_filename(bp);
+    return NULL;
+}
+
+const char* api_buffer_name(void *bp) {
+    if (api.buffer_name) return api.buffer_name(bp);
+    return NULL;
+}
+
+char* api_get_current_line(void) {
+    if (api.get_current_line) return api.get_current_line();
+    return NULL;
+}
+
+void api_get_point(int *line, int *col) {
+    if (api.get_point) api.get_point(line, col);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+void* api_buffer_create(const char *name) {
+    if (api.buffer_create) return api.buffer_create(name);
+    return NULL;
+}
+
+int api_buffer_switch(void *bp) {
+    if (api.buffer_switch) return api.buffer_switch(bp);
+    return 0;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
+void api_free(void *ptr) {
+    if (api.free) api.free(ptr);
+}
+
+int api_find_file_line(const char *path, int line) {
+    if (api.find_file_line) return api.find_file_line(path, line);
+    return 0;
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_bookmark_set(int f, int n) { return go_bookmark_set(f, n); }
+static int cmd_bookmark_goto(int f, int n) { return go_bookmark_goto(f, n); }
+static int cmd_bookmark_list(int f, int n) { return go_bookmark_list(f, n); }
+static int cmd_bookmark_delete(int f, int n) { return go_bookmark_delete(f, n); }
+static int cmd_bookmark_next(int f, int n) { return go_bookmark_next(f, n); }
+static int cmd_bookmark_prev(int f, int n) { return go_bookmark_prev(f, n); }
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int bookmark_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_bookmark: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
+    api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.buffer_name = (buffer_name_fn)LOOKUP(buffer_name);
+    api.get_current_line = (get_current_line_fn)LOOKUP(get_current_line);
+    api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
+    api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.free = (free_fn)LOOKUP(free);
+    api.find_file_line = (find_file_line_fn)LOOKUP(find_file_line);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_bookmark: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Initialize Go side */
+    bookmark_init(editor_api_raw);
+
+    /* Register commands */
+    api.register_command("bookmark-set", cmd_bookmark_set);
+    api.register_command("bookmark-goto", cmd_bookmark_goto);
+    api.register_command("bookmark-list", cmd_bookmark_list);
+    api.register_command("bookmark-delete", cmd_bookmark_delete);
+    api.register_command("bookmark-next", cmd_bookmark_next);
+    api.register_command("bookmark-prev", cmd_bookmark_prev);
+
+    api.log_info("go_bookmark: Extension loaded");
+    api.log_info("  Commands: bookmark-set, bookmark-goto, bookmark-list, bookmark-delete, bookmark-next, bookmark-prev");
+    return 0;
+}
+
+static void bookmark_cleanup_c(void) {
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("bookmark-set");
+        api.unregister_command("bookmark-goto");
+        api.unregister_command("bookmark-list");
+        api.unregister_command("bookmark-delete");
+        api.unregister_command("bookmark-next");
+        api.unregister_command("bookmark-prev");
+    }
+
+    /* Cleanup Go side */
+    bookmark_cleanup();
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_bookmark",
+    .version = "1.0.0",
+    .description = "Named file/line bookmarks",
+    .init = bookmark_init_c,
+    .cleanup = bookmark_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
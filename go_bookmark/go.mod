@@ -0,0 +1,3 @@
+module go_bookmark
+
+go 1.21
@@ -0,0 +1,393 @@
+// go_bookmark - Named bookmarks for μEmacs
+//
+// Bookmarks name a line of a file and are kept in
+// ~/.config/muemacs/bookmarks.json, in the order they were first set.
+//
+// Commands:
+//   bookmark-set    - Bookmark the current line under a name (prompts)
+//   bookmark-goto   - Jump to a bookmark (prompts; in *bookmarks*, jump
+//                     to the one on the current line)
+//   bookmark-list   - List the bookmarks in *bookmarks*
+//   bookmark-delete - Delete a bookmark (prompts)
+//   bookmark-next   - Jump to the next bookmark, wrapping around
+//   bookmark-prev   - Jump to the previous bookmark, wrapping around
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
+extern char *api_get_current_line(void);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern void api_free(void *ptr);
+extern int api_find_file_line(const char *path, int line);
+*/
+import "C"
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// listBuffer shows the bookmarks
+const listBuffer = "*bookmarks*"
+
+// The bookmarks and the index of the last one visited or set, -1 if none
+var (
+	marksMu sync.Mutex
+	marks   []Bookmark
+	current = -1
+)
+
+// snapshot is a copy of the bookmarks to save; gen orders the copies
+type snapshot struct {
+	gen   int
+	marks []Bookmark
+}
+
+// saves hands the latest bookmarks to the saver; only the newest pending
+// copy is kept. writeMu keeps the saver and cleanup from writing at once
+// and from writing an older copy over a newer one.
+var (
+	saves   = make(chan snapshot, 1)
+	saveGen int
+	writeMu sync.Mutex
+	written int
+)
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// logError writes a message to the editor log
+func logError(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_log_error(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// takeString copies and frees a string allocated by the editor
+func takeString(cstr *C.char) string {
+	if cstr == nil {
+		return ""
+	}
+	defer C.api_free(unsafe.Pointer(cstr))
+	return C.GoString(cstr)
+}
+
+// getCurrentBufferInfo returns the current buffer's file and point
+func getCurrentBufferInfo() (filename string, line, col int) {
+	buf := C.api_current_buffer()
+	if buf == nil {
+		return
+	}
+
+	cFilename := C.api_buffer_filename(buf)
+	if cFilename != nil {
+		filename = C.GoString(cFilename)
+	}
+
+	var cLine, cCol C.int
+	C.api_get_point(&cLine, &cCol)
+	line = int(cLine)
+	col = int(cCol)
+
+	return
+}
+
+// scheduleSave queues the bookmarks to be written; call with marksMu held
+func scheduleSave() {
+	saveGen++
+	s := snapshot{saveGen, append([]Bookmark(nil), marks...)}
+	select {
+	case <-saves:
+	default:
+	}
+	saves <- s
+}
+
+// write persists a snapshot unless a newer one has been written
+func write(s snapshot) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	if s.gen <= written {
+		return
+	}
+	if err := saveBookmarks(bookmarksPath(), s.marks); err != nil {
+		logError("go_bookmark: saving %s: %v", bookmarksPath(), err)
+		return
+	}
+	written = s.gen
+}
+
+// saver writes the bookmarks as they are queued
+func saver() {
+	for s := range saves {
+		write(s)
+	}
+}
+
+// visit jumps to the bookmark at index i and makes it the current one
+func visit(cmd string, i int) C.int {
+	marksMu.Lock()
+	b := marks[i]
+	current = i
+	count := len(marks)
+	marksMu.Unlock()
+
+	cpath := C.CString(b.File)
+	defer C.free(unsafe.Pointer(cpath))
+	if C.api_find_file_line(cpath, C.int(max(b.Line, 1))) == 0 {
+		message("%s: cannot open %s", cmd, b.File)
+		return 0
+	}
+	message("Bookmark %s (%d/%d)", b.Name, i+1, count)
+	return 1
+}
+
+// row renders a bookmark for *bookmarks*
+func row(b Bookmark, width int) string {
+	return fmt.Sprintf("%-*s  %s:%d", width, b.Name, b.File, b.Line)
+}
+
+// nameWidth returns the width of the name column of *bookmarks*
+func nameWidth(marks []Bookmark) int {
+	width := 0
+	for _, b := range marks {
+		width = max(width, len(b.Name))
+	}
+	return width
+}
+
+// listed returns the index of the bookmark on the current line of
+// *bookmarks*, or -1
+func listed() int {
+	line := strings.TrimRight(takeString(C.api_get_current_line()), "\r\n")
+	marksMu.Lock()
+	defer marksMu.Unlock()
+	width := nameWidth(marks)
+	for i, b := range marks {
+		if strings.TrimRight(row(b, width), " ") == strings.TrimRight(line, " ") {
+			return i
+		}
+	}
+	return -1
+}
+
+// askName prompts for the name of an existing bookmark, returning its index
+func askName(cmd, text string) (int, bool) {
+	marksMu.Lock()
+	empty := len(marks) == 0
+	marksMu.Unlock()
+	if empty {
+		message("%s: no bookmarks", cmd)
+		return -1, false
+	}
+
+	input, ok := prompt(text)
+	name := strings.TrimSpace(input)
+	if !ok || name == "" {
+		message("Cancelled")
+		return -1, false
+	}
+	marksMu.Lock()
+	i := find(marks, name)
+	marksMu.Unlock()
+	if i < 0 {
+		message("%s: no bookmark %s", cmd, name)
+		return -1, false
+	}
+	return i, true
+}
+
+//export go_bookmark_set
+func go_bookmark_set(f, n C.int) C.int {
+	filename, line, _ := getCurrentBufferInfo()
+	if filename == "" {
+		message("bookmark-set: buffer has no file")
+		return 0
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+
+	def := fmt.Sprintf("%s:%d", filepath.Base(filename), line)
+	input, ok := prompt(fmt.Sprintf("Bookmark name (default %s): ", def))
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	name := strings.TrimSpace(input)
+	if name == "" {
+		name = def
+	}
+
+	marksMu.Lock()
+	marks = set(marks, Bookmark{Name: name, File: filename, Line: line})
+	current = find(marks, name)
+	scheduleSave()
+	marksMu.Unlock()
+	message("Bookmark %s set at %s:%d", name, filepath.Base(filename), line)
+	return 1
+}
+
+//export go_bookmark_goto
+func go_bookmark_goto(f, n C.int) C.int {
+	if bp := C.api_current_buffer(); bp != nil {
+		if cname := C.api_buffer_name(bp); cname != nil && C.GoString(cname) == listBuffer {
+			i := listed()
+			if i < 0 {
+				message("bookmark-goto: no bookmark on this line")
+				return 0
+			}
+			return visit("bookmark-goto", i)
+		}
+	}
+
+	i, ok := askName("bookmark-goto", "Go to bookmark: ")
+	if !ok {
+		return 0
+	}
+	return visit("bookmark-goto", i)
+}
+
+//export go_bookmark_list
+func go_bookmark_list(f, n C.int) C.int {
+	marksMu.Lock()
+	list := append([]Bookmark(nil), marks...)
+	marksMu.Unlock()
+	if len(list) == 0 {
+		message("bookmark-list: no bookmarks")
+		return 0
+	}
+
+	width := nameWidth(list)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d bookmarks\n\n", len(list))
+	for _, m := range list {
+		b.WriteString(row(m, width) + "\n")
+	}
+
+	cname := C.CString(listBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	out := C.api_buffer_create(cname)
+	if out == nil {
+		return 0
+	}
+	C.api_buffer_switch(out)
+	C.api_buffer_clear(out)
+	insertText(b.String())
+	C.api_set_point(3, 0)
+	message("%d bookmarks (bookmark-goto on a line visits it)", len(list))
+	return 1
+}
+
+//export go_bookmark_delete
+func go_bookmark_delete(f, n C.int) C.int {
+	i, ok := askName("bookmark-delete", "Delete bookmark: ")
+	if !ok {
+		return 0
+	}
+
+	marksMu.Lock()
+	name := marks[i].Name
+	marks, _ = remove(marks, name)
+	if current == i {
+		current = -1
+	} else if current > i {
+		current--
+	}
+	scheduleSave()
+	marksMu.Unlock()
+	message("Bookmark %s deleted", name)
+	return 1
+}
+
+// cycle visits the bookmark dir places from the current one
+func cycle(cmd string, dir int) C.int {
+	marksMu.Lock()
+	count, i := len(marks), current
+	marksMu.Unlock()
+	if count == 0 {
+		message("%s: no bookmarks", cmd)
+		return 0
+	}
+	return visit(cmd, step(count, i, dir))
+}
+
+//export go_bookmark_next
+func go_bookmark_next(f, n C.int) C.int {
+	return cycle("bookmark-next", 1)
+}
+
+//export go_bookmark_prev
+func go_bookmark_prev(f, n C.int) C.int {
+	return cycle("bookmark-prev", -1)
+}
+
+//export bookmark_init
+func bookmark_init(api unsafe.Pointer) {
+	loaded, err := loadBookmarks(bookmarksPath())
+	if err != nil {
+		logError("go_bookmark: loading %s: %v", bookmarksPath(), err)
+	}
+	marksMu.Lock()
+	marks = loaded
+	marksMu.Unlock()
+	go saver()
+}
+
+//export bookmark_cleanup
+func bookmark_cleanup() {
+	// Write a save still queued rather than lose it
+	select {
+	case s := <-saves:
+		write(s)
+	default:
+	}
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
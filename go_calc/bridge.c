@@ -0,0 +1,185 @@
+/*
+ * bridge.c - C/CGO Bridge for Go Calc Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Evaluates infix and RPN expressions, unit conversions and
+ * variable assignments.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void (*set_point_fn)(int, int);
+typedef void *(*buffer_create_fn)(const char*);
+typedef int (*buffer_switch_fn)(void*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    set_point_fn set_point;
+    buffer_create_fn buffer_create;
+    buffer_switch_fn buffer_switch;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_fn prompt;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+void* api_buffer_create(const char *name) {
+    if (api.buffer_create) return api.buffer_create(name);
+    return NULL;
+}
+
+int api_buffer_switch(void *bp) {
+    if (api.buffer_switch) return api.buffer_switch(bp);
+    return 0;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_go_calc(int f, int n) { return go_calc_eval(f, n); }
+static int cmd_go_calc_history(int f, int n) { return go_calc_history(f, n); }
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int calc_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_calc: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
+    api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_calc: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Register commands */
+    api.register_command("go-calc", cmd_go_calc);
+    api.register_command("go-calc-history", cmd_go_calc_history);
+
+    api.log_info("go_calc: Extension loaded");
+    api.log_info("  Commands: go-calc, go-calc-history");
+    return 0;
+}
+
+static void calc_cleanup_c(void) {
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("go-calc");
+        api.unregister_command("go-calc-history");
+    }
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_calc",
+    .version = "1.0.0",
+    .description = "Infix/RPN calculator with unit conversion",
+    .init = calc_init_c,
+    .cleanup = calc_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
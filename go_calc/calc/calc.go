@@ -0,0 +1,105 @@
+// Package calc evaluates calculator input: infix or RPN expressions, unit
+// conversions and variable assignments.
+package calc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxHistory bounds the number of remembered calculations
+const MaxHistory = 100
+
+// Entry is one calculation in the history
+type Entry struct {
+	Input  string
+	Value  float64
+	Result string // Value as shown, with its unit if any
+}
+
+// Calculator holds the variables and history of a session. The result of
+// the last calculation is also the variable ans.
+type Calculator struct {
+	Vars    map[string]float64
+	History []Entry // Oldest first
+}
+
+// New returns an empty calculator
+func New() *Calculator {
+	return &Calculator{Vars: map[string]float64{}}
+}
+
+// assignPattern matches "name = expression"
+var assignPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=(.*)$`)
+
+// Eval evaluates one line of input and records it in the history. The
+// input is an assignment (x = 42), a unit conversion (42 km to miles),
+// an RPN expression (3 4 +) or an infix expression (3 + 4).
+func (c *Calculator) Eval(input string) (Entry, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return Entry{}, fmt.Errorf("empty expression")
+	}
+
+	name, expr := "", input
+	if m := assignPattern.FindStringSubmatch(input); m != nil {
+		name, expr = m[1], m[2]
+		if _, ok := constants[name]; ok {
+			return Entry{}, fmt.Errorf("cannot assign to constant %s", name)
+		}
+		if _, ok := functions[name]; ok {
+			return Entry{}, fmt.Errorf("cannot assign to function %s", name)
+		}
+	}
+
+	unitName := ""
+	value, err := 0.0, error(nil)
+	if e, from, to, ok := parseConversion(expr); ok {
+		if value, err = c.evalExpr(e); err == nil {
+			value, err = convert(value, from, to)
+		}
+		unitName = unitNames[strings.ToLower(to)]
+	} else {
+		value, err = c.evalExpr(expr)
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Entry{}, fmt.Errorf("result is %s", Format(value))
+	}
+
+	entry := Entry{Input: input, Value: value, Result: Format(value)}
+	if unitName != "" {
+		entry.Result += " " + unitName
+	}
+	if name != "" {
+		c.Vars[name] = value
+	}
+	c.Vars["ans"] = value
+	c.History = append(c.History, entry)
+	if len(c.History) > MaxHistory {
+		c.History = c.History[len(c.History)-MaxHistory:]
+	}
+	return entry, nil
+}
+
+// evalExpr evaluates an RPN or infix expression
+func (c *Calculator) evalExpr(expr string) (float64, error) {
+	if isRPN(expr) {
+		return evalRPN(expr, c.Vars)
+	}
+	return evalInfix(expr, c.Vars)
+}
+
+// Format renders a value: integers in full, others to 12 significant
+// digits
+func Format(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 12, 64)
+}
@@ -0,0 +1,52 @@
+package calc
+
+import "testing"
+
+func TestEval(t *testing.T) {
+	c := New()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(1 + 2) * 3", "9"},
+		{"2 ^ 3 ^ 2", "512"},
+		{"-2 ^ 2", "-4"},
+		{"10 % 4 - 7 / 2", "-1.5"},
+		{"sqrt(16) + abs(-2) + floor(2.7) + ceil(0.2)", "9"},
+		{"cos(0) + log(1000) + sin(0)", "4"},
+		{"1.5e3 / 3", "500"},
+		{"3 4 + 2 *", "14"},
+		{"16 sqrt", "4"},
+		{"x = 42", "42"},
+		{"x / 2 + ans", "63"},
+		{"y = x 2 *", "84"},
+		{"42 km to miles", "26.097590074 miles"},
+		{"100 C to F", "212 F"},
+		{"1 GB to MB", "1000 MB"},
+		{"1 GiB in KiB", "1048576 KiB"},
+		{"2 * 3 ft to in", "72 in"},
+	}
+	for _, tt := range tests {
+		got, err := c.Eval(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if got.Result != tt.want {
+			t.Errorf("%s = %s, want %s", tt.input, got.Result, tt.want)
+		}
+	}
+	if c.Vars["y"] != 84 {
+		t.Errorf("y = %v, want 84", c.Vars["y"])
+	}
+	if len(c.History) != len(tests) {
+		t.Errorf("history has %d entries, want %d", len(c.History), len(tests))
+	}
+
+	for _, bad := range []string{"1 +", "(1 + 2", "1 / 0", "z * 2", "1 2 3 +", "1 km to kg", "pi = 3", "sqrt 16"} {
+		if got, err := c.Eval(bad); err == nil {
+			t.Errorf("%s: got %s, want an error", bad, got.Result)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Functions callable as name(x) in infix or as x name in RPN
+var functions = map[string]func(float64) float64{
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"sqrt":  math.Sqrt,
+	"log":   math.Log10,
+	"ln":    math.Log,
+	"exp":   math.Exp,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"abs":   math.Abs,
+}
+
+// Constants, which variables of the same name cannot hide
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// tokenKind classifies a token of an expression
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokIdent
+	tokOp // One of + - * / % ^ ( )
+	tokEnd
+)
+
+// token is one lexical element of an expression
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+}
+
+// tokenize splits an expression into tokens, ending with tokEnd
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			// Exponent: 1e6, 2.5E-3
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				k := j + 1
+				if k < len(s) && (s[k] == '+' || s[k] == '-') {
+					k++
+				}
+				if k < len(s) && isDigit(s[k]) {
+					for j = k; j < len(s) && isDigit(s[j]); j++ {
+					}
+				}
+			}
+			n, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q", s[i:j])
+			}
+			toks = append(toks, token{kind: tokNumber, text: s[i:j], num: n})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (isIdentChar(s[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: s[i:j]})
+			i = j
+		case strings.ContainsRune("+-*/%^()", c):
+			toks = append(toks, token{kind: tokOp, text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return append(toks, token{kind: tokEnd}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || unicode.IsLetter(rune(c))
+}
+
+// parser evaluates infix expressions by recursive descent:
+//
+//	expr  = term { ("+" | "-") term }
+//	term  = unary { ("*" | "/" | "%") unary }
+//	unary = "-" unary | "+" unary | power
+//	power = atom [ "^" unary ]
+//	atom  = number | name | name "(" expr ")" | "(" expr ")"
+type parser struct {
+	toks []token
+	pos  int
+	vars map[string]float64
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEnd {
+		p.pos++
+	}
+	return t
+}
+
+// isOp reports whether the next token is operator op
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+// evalInfix evaluates an infix expression with variables vars
+func evalInfix(s string, vars map[string]float64) (float64, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return 0, err
+	}
+	p := &parser{toks: toks, vars: vars}
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if t := p.peek(); t.kind != tokEnd {
+		return 0, fmt.Errorf("unexpected %q", t.text)
+	}
+	return v, nil
+}
+
+func (p *parser) expr() (float64, error) {
+	v, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		r, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			v += r
+		} else {
+			v -= r
+		}
+	}
+	return v, nil
+}
+
+func (p *parser) term() (float64, error) {
+	v, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.next().text
+		r, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		if v, err = binary(op, v, r); err != nil {
+			return 0, err
+		}
+	}
+	return v, nil
+}
+
+func (p *parser) unary() (float64, error) {
+	if p.isOp("-") || p.isOp("+") {
+		neg := p.next().text == "-"
+		v, err := p.unary()
+		if neg {
+			v = -v
+		}
+		return v, err
+	}
+	return p.power()
+}
+
+func (p *parser) power() (float64, error) {
+	v, err := p.atom()
+	if err != nil {
+		return 0, err
+	}
+	if p.isOp("^") {
+		p.next()
+		// Right-associative, and binds tighter than a unary minus on its
+		// left: -2^2 is -4, 2^-1 is 0.5
+		r, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(v, r), nil
+	}
+	return v, nil
+}
+
+func (p *parser) atom() (float64, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return t.num, nil
+
+	case tokIdent:
+		if fn, ok := functions[t.text]; ok {
+			if !p.isOp("(") {
+				return 0, fmt.Errorf("%s needs an argument in parentheses", t.text)
+			}
+			arg, err := p.atom()
+			if err != nil {
+				return 0, err
+			}
+			return fn(arg), nil
+		}
+		return lookup(t.text, p.vars)
+
+	case tokOp:
+		if t.text == "(" {
+			v, err := p.expr()
+			if err != nil {
+				return 0, err
+			}
+			if !p.isOp(")") {
+				return 0, fmt.Errorf("missing )")
+			}
+			p.next()
+			return v, nil
+		}
+		return 0, fmt.Errorf("unexpected %q", t.text)
+	}
+	return 0, fmt.Errorf("unexpected end of expression")
+}
+
+// lookup returns the value of a constant or variable
+func lookup(name string, vars map[string]float64) (float64, error) {
+	if v, ok := constants[name]; ok {
+		return v, nil
+	}
+	if v, ok := vars[name]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("unknown name %s", name)
+}
+
+// binary applies an arithmetic operator
+func binary(op string, l, r float64) (float64, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	case "^":
+		return math.Pow(l, r), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", op)
+}
@@ -0,0 +1,87 @@
+package calc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isRPN reports whether s reads as RPN: space-separated fields that start
+// with an operand and end with an operator or function, as in "3 4 +" or
+// "16 sqrt". Infix like "3 + 4" ends with an operand instead.
+func isRPN(s string) bool {
+	fields := strings.Fields(s)
+	if len(fields) < 2 || strings.ContainsAny(s, "()") {
+		return false
+	}
+	last := fields[len(fields)-1]
+	_, isFunc := functions[last]
+	return isOperand(fields[0]) && (isOperator(last) || isFunc)
+}
+
+// isOperator reports whether field is an arithmetic operator
+func isOperator(field string) bool {
+	return len(field) == 1 && strings.Contains("+-*/%^", field)
+}
+
+// isOperand reports whether field is a number or a name
+func isOperand(field string) bool {
+	if _, err := strconv.ParseFloat(field, 64); err == nil {
+		return true
+	}
+	if _, isFunc := functions[field]; isFunc {
+		return false
+	}
+	for i := 0; i < len(field); i++ {
+		if !isIdentChar(field[i]) || (i == 0 && isDigit(field[i])) {
+			return false
+		}
+	}
+	return field != ""
+}
+
+// evalRPN evaluates an RPN expression with variables vars
+func evalRPN(s string, vars map[string]float64) (float64, error) {
+	var stack []float64
+	pop := func() float64 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+
+	for _, field := range strings.Fields(s) {
+		if isOperator(field) {
+			if len(stack) < 2 {
+				return 0, fmt.Errorf("%s needs two operands", field)
+			}
+			r, l := pop(), pop()
+			v, err := binary(field, l, r)
+			if err != nil {
+				return 0, err
+			}
+			stack = append(stack, v)
+			continue
+		}
+		if fn, ok := functions[field]; ok {
+			if len(stack) < 1 {
+				return 0, fmt.Errorf("%s needs an operand", field)
+			}
+			stack = append(stack, fn(pop()))
+			continue
+		}
+		if n, err := strconv.ParseFloat(field, 64); err == nil {
+			stack = append(stack, n)
+			continue
+		}
+		v, err := lookup(field, vars)
+		if err != nil {
+			return 0, err
+		}
+		stack = append(stack, v)
+	}
+
+	if len(stack) != 1 {
+		return 0, fmt.Errorf("%d values left on the stack", len(stack))
+	}
+	return stack[0], nil
+}
@@ -0,0 +1,120 @@
+package calc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// unit is a unit of measure: a value in it times factor is in the base
+// unit of its dimension. Temperatures are converted by formula instead.
+type unit struct {
+	dimension string
+	factor    float64
+}
+
+// units maps the accepted spellings of each unit
+var units = map[string]unit{}
+
+// unitNames gives each unit's display name, keyed by any spelling
+var unitNames = map[string]string{}
+
+func init() {
+	define := func(dimension string, factor float64, names ...string) {
+		for _, name := range names {
+			units[strings.ToLower(name)] = unit{dimension, factor}
+			unitNames[strings.ToLower(name)] = names[0]
+		}
+	}
+
+	// Length, in metres
+	define("length", 1e-3, "mm", "millimeter", "millimeters", "millimetre", "millimetres")
+	define("length", 1e-2, "cm", "centimeter", "centimeters", "centimetre", "centimetres")
+	define("length", 1, "m", "meter", "meters", "metre", "metres")
+	define("length", 1e3, "km", "kilometer", "kilometers", "kilometre", "kilometres")
+	define("length", 0.0254, "in", "inch", "inches")
+	define("length", 0.3048, "ft", "foot", "feet")
+	define("length", 0.9144, "yd", "yard", "yards")
+	define("length", 1609.344, "miles", "mi", "mile")
+
+	// Mass, in grams
+	define("mass", 1e-3, "mg", "milligram", "milligrams")
+	define("mass", 1, "g", "gram", "grams")
+	define("mass", 1e3, "kg", "kilogram", "kilograms")
+	define("mass", 28.349523125, "oz", "ounce", "ounces")
+	define("mass", 453.59237, "lb", "lbs", "pound", "pounds")
+
+	// Time, in seconds
+	define("time", 1e-3, "ms", "millisecond", "milliseconds")
+	define("time", 1, "s", "sec", "second", "seconds")
+	define("time", 60, "min", "minute", "minutes")
+	define("time", 3600, "h", "hr", "hour", "hours")
+	define("time", 86400, "days", "day")
+
+	// Data, in bytes: KB, MB... are decimal, KiB, MiB... binary
+	define("data", 1, "B", "byte", "bytes")
+	define("data", 1e3, "KB")
+	define("data", 1e6, "MB")
+	define("data", 1e9, "GB")
+	define("data", 1e12, "TB")
+	define("data", 1<<10, "KiB")
+	define("data", 1<<20, "MiB")
+	define("data", 1<<30, "GiB")
+	define("data", 1<<40, "TiB")
+
+	// Temperature, converted by toKelvin and fromKelvin
+	define("temperature", 0, "C", "celsius")
+	define("temperature", 0, "F", "fahrenheit")
+	define("temperature", 0, "K", "kelvin")
+}
+
+// conversionPattern matches "<expression> <unit> to <unit>" ("in" works
+// as well as "to")
+var conversionPattern = regexp.MustCompile(`^(.*\S)\s+([A-Za-z]+)\s+(?:to|in)\s+([A-Za-z]+)$`)
+
+// parseConversion splits s into an expression and the units to convert
+// between, if it is a conversion of known units
+func parseConversion(s string) (expr, from, to string, ok bool) {
+	m := conversionPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", "", "", false
+	}
+	_, fromOK := units[strings.ToLower(m[2])]
+	_, toOK := units[strings.ToLower(m[3])]
+	if !fromOK || !toOK {
+		return "", "", "", false
+	}
+	return m[1], m[2], m[3], true
+}
+
+// convert converts v from one unit to another of the same dimension
+func convert(v float64, from, to string) (float64, error) {
+	f, t := units[strings.ToLower(from)], units[strings.ToLower(to)]
+	if f.dimension != t.dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, f.dimension, to, t.dimension)
+	}
+	if f.dimension == "temperature" {
+		return fromKelvin(toKelvin(v, unitNames[strings.ToLower(from)]), unitNames[strings.ToLower(to)]), nil
+	}
+	return v * f.factor / t.factor, nil
+}
+
+func toKelvin(v float64, name string) float64 {
+	switch name {
+	case "C":
+		return v + 273.15
+	case "F":
+		return (v-32)*5/9 + 273.15
+	}
+	return v
+}
+
+func fromKelvin(v float64, name string) float64 {
+	switch name {
+	case "C":
+		return v - 273.15
+	case "F":
+		return (v-273.15)*9/5 + 32
+	}
+	return v
+}
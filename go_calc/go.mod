@@ -0,0 +1,3 @@
+module go_calc
+
+go 1.21
@@ -0,0 +1,144 @@
+// go_calc - Infix and RPN calculator with unit conversion for μEmacs
+//
+// Commands:
+//   go-calc         - Evaluate an expression and show the result
+//                     (C-u to also insert it at point)
+//   go-calc-history - List past calculations and variables in
+//                     *calc-history* (C-u N inserts result N at point)
+//
+// Input is infix (2 * (3 + 4)), RPN (3 4 + 2 *), a unit conversion
+// (42 km to miles, 100 C to F, 1 GB to MB) or an assignment (x = 42).
+// Variables, and ans for the last result, last for the editor session.
+// The go- prefix keeps clear of haskell_calc's calc commands.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+*/
+import "C"
+
+import (
+	"fmt"
+	"go_calc/calc"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// historyBuffer lists past calculations
+const historyBuffer = "*calc-history*"
+
+// calculator holds the session's variables and history
+var calculator = calc.New()
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+//export go_calc_eval
+func go_calc_eval(f, n C.int) C.int {
+	input, ok := prompt("Calc: ")
+	if !ok || strings.TrimSpace(input) == "" {
+		message("Cancelled")
+		return 0
+	}
+	entry, err := calculator.Eval(input)
+	if err != nil {
+		message("go-calc: %v", err)
+		return 0
+	}
+	if f != 0 {
+		insertText(entry.Result)
+	}
+	message("%s = %s", entry.Input, entry.Result)
+	return 1
+}
+
+//export go_calc_history
+func go_calc_history(f, n C.int) C.int {
+	history := calculator.History
+	if len(history) == 0 {
+		message("go-calc-history: no calculations yet")
+		return 0
+	}
+
+	// Entries are numbered newest first
+	if f != 0 {
+		if n < 1 || int(n) > len(history) {
+			message("go-calc-history: no result %d (have %d)", n, len(history))
+			return 0
+		}
+		entry := history[len(history)-int(n)]
+		insertText(entry.Result)
+		message("Inserted %s = %s", entry.Input, entry.Result)
+		return 1
+	}
+
+	var b strings.Builder
+	for i := len(history) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%3d  %s = %s\n", len(history)-i, history[i].Input, history[i].Result)
+	}
+	names := make([]string, 0, len(calculator.Vars))
+	for name := range calculator.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString("\nVariables:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s = %s\n", name, calc.Format(calculator.Vars[name]))
+	}
+
+	cname := C.CString(historyBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	bp := C.api_buffer_create(cname)
+	if bp == nil {
+		return 0
+	}
+	C.api_buffer_switch(bp)
+	C.api_buffer_clear(bp)
+	insertText(b.String())
+	C.api_set_point(1, 0)
+	message("C-u N go-calc-history inserts result N at point")
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,269 @@
+package main
+
+import "math/bits"
+
+// K+P vs K bitbase, computed at startup by retrograde analysis.
+//
+// Positions are indexed by wKing*64*64 + wPawn*64 + bKing (white pawn,
+// absolute squares). KPKBitbase holds three bit planes of 8192 words each:
+// white-to-move wins, black-to-move wins, and legal placements. Positions
+// with a black pawn are probed by flipping the board vertically.
+
+// WDL results from White's perspective
+const (
+	WDLLoss = -1
+	WDLDraw = 0
+	WDLWin  = 1
+)
+
+// KnownWinScore is returned by the search for bitbase wins; well above any
+// material evaluation but below mate scores (and within the TT's int16)
+const KnownWinScore = 10000
+
+const (
+	kpkPositions  = 64 * 64 * 64
+	kpkPlaneWords = kpkPositions / 32
+
+	kpkPlaneWhiteWin = 0
+	kpkPlaneBlackWin = 1
+	kpkPlaneLegal    = 2
+)
+
+// KPKBitbase is 24576 * 4 bytes = 96KB
+var KPKBitbase [3 * kpkPlaneWords]uint32
+
+// Generation states
+const (
+	kpkInvalid uint8 = iota
+	kpkUnknown
+	kpkDraw
+	kpkWin
+)
+
+// kpkKingSteps lists the squares a king can step to from each square
+var kpkKingSteps [64][]Square
+
+func init() {
+	for sq := Square(0); sq < 64; sq++ {
+		for dr := -1; dr <= 1; dr++ {
+			for df := -1; df <= 1; df++ {
+				r, f := sq.Rank()+dr, sq.File()+df
+				if (dr != 0 || df != 0) && r >= 0 && r < 8 && f >= 0 && f < 8 {
+					kpkKingSteps[sq] = append(kpkKingSteps[sq], FromRankFile(r, f))
+				}
+			}
+		}
+	}
+	generateKPK()
+}
+
+// kpkIndex returns the bitbase index for a white king, white pawn and black king
+func kpkIndex(wKing, wPawn, bKing Square) int {
+	return int(wKing)*64*64 + int(wPawn)*64 + int(bKing)
+}
+
+// kingDistance is the number of king steps between two squares
+func kingDistance(a, b Square) int {
+	return max(abs(a.Rank()-b.Rank()), abs(a.File()-b.File()))
+}
+
+// whitePawnAttacks reports whether a white pawn on p attacks sq
+func whitePawnAttacks(p, sq Square) bool {
+	return sq.Rank() == p.Rank()+1 && abs(sq.File()-p.File()) == 1
+}
+
+// kpkClassifyInitial assigns states that need no search: illegal
+// placements, immediate promotions, pawn captures and stalemates
+func kpkClassifyInitial(wk, wp, bk Square, stm Color) uint8 {
+	if wk == bk || wk == wp || bk == wp || kingDistance(wk, bk) <= 1 ||
+		wp.Rank() == 0 || wp.Rank() == 7 {
+		return kpkInvalid
+	}
+
+	if stm == White {
+		// Black king in check with White to move
+		if whitePawnAttacks(wp, bk) {
+			return kpkInvalid
+		}
+		// Safe promotion: queening square empty and not lost to the black king
+		promo := wp + 8
+		if wp.Rank() == 6 && promo != wk && promo != bk &&
+			(kingDistance(bk, promo) > 1 || kingDistance(wk, promo) == 1) {
+			return kpkWin
+		}
+		return kpkUnknown
+	}
+
+	hasMove := false
+	for _, to := range kpkKingSteps[bk] {
+		if kingDistance(to, wk) <= 1 || whitePawnAttacks(wp, to) {
+			continue
+		}
+		if to == wp {
+			return kpkDraw // Undefended pawn is captured
+		}
+		hasMove = true
+	}
+	if !hasMove {
+		if whitePawnAttacks(wp, bk) {
+			return kpkWin // Checkmate
+		}
+		return kpkDraw // Stalemate
+	}
+	return kpkUnknown
+}
+
+// kpkClassify resolves an unknown position from its successors. White needs
+// one winning move; Black needs one drawing move.
+func kpkClassify(states *[2][kpkPositions]uint8, wk, wp, bk Square, stm Color) uint8 {
+	if stm == White {
+		allDraw := true
+		try := func(s uint8) bool {
+			if s == kpkWin {
+				return true
+			}
+			if s != kpkDraw {
+				allDraw = false
+			}
+			return false
+		}
+
+		for _, to := range kpkKingSteps[wk] {
+			if to == wp || kingDistance(to, bk) <= 1 {
+				continue
+			}
+			if try(states[Black][kpkIndex(to, wp, bk)]) {
+				return kpkWin
+			}
+		}
+
+		// Pawn pushes (promotion was resolved in the initial pass)
+		if wp.Rank() < 6 {
+			push := wp + 8
+			if push != wk && push != bk {
+				if try(states[Black][kpkIndex(wk, push, bk)]) {
+					return kpkWin
+				}
+				double := push + 8
+				if wp.Rank() == 1 && double != wk && double != bk {
+					if try(states[Black][kpkIndex(wk, double, bk)]) {
+						return kpkWin
+					}
+				}
+			}
+		}
+
+		if allDraw {
+			return kpkDraw
+		}
+		return kpkUnknown
+	}
+
+	allWin := true
+	for _, to := range kpkKingSteps[bk] {
+		if to == wp || kingDistance(to, wk) <= 1 || whitePawnAttacks(wp, to) {
+			continue
+		}
+		s := states[White][kpkIndex(wk, wp, to)]
+		if s == kpkDraw {
+			return kpkDraw
+		}
+		if s != kpkWin {
+			allWin = false
+		}
+	}
+	if allWin {
+		return kpkWin
+	}
+	return kpkUnknown
+}
+
+// generateKPK fills KPKBitbase by iterating to a fixed point; positions
+// still unknown afterwards are draws
+func generateKPK() {
+	states := new([2][kpkPositions]uint8)
+
+	for idx := 0; idx < kpkPositions; idx++ {
+		wk, wp, bk := Square(idx/4096), Square(idx/64%64), Square(idx%64)
+		states[White][idx] = kpkClassifyInitial(wk, wp, bk, White)
+		states[Black][idx] = kpkClassifyInitial(wk, wp, bk, Black)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for idx := 0; idx < kpkPositions; idx++ {
+			wk, wp, bk := Square(idx/4096), Square(idx/64%64), Square(idx%64)
+			for _, stm := range []Color{White, Black} {
+				if states[stm][idx] != kpkUnknown {
+					continue
+				}
+				if s := kpkClassify(states, wk, wp, bk, stm); s != kpkUnknown {
+					states[stm][idx] = s
+					changed = true
+				}
+			}
+		}
+	}
+
+	for idx := 0; idx < kpkPositions; idx++ {
+		word, bit := idx/32, uint32(1)<<(idx%32)
+		if states[White][idx] == kpkWin {
+			KPKBitbase[kpkPlaneWhiteWin*kpkPlaneWords+word] |= bit
+		}
+		if states[Black][idx] == kpkWin {
+			KPKBitbase[kpkPlaneBlackWin*kpkPlaneWords+word] |= bit
+		}
+		if states[White][idx] != kpkInvalid || states[Black][idx] != kpkInvalid {
+			KPKBitbase[kpkPlaneLegal*kpkPlaneWords+word] |= bit
+		}
+	}
+}
+
+// probeKPK returns the WDL result for White in a K+P vs K position with a
+// white pawn. Illegal placements are reported as draws.
+func probeKPK(wKing, wPawn, bKing Square, sideToMove Color) int {
+	idx := kpkIndex(wKing, wPawn, bKing)
+	word, bit := idx/32, uint32(1)<<(idx%32)
+
+	if KPKBitbase[kpkPlaneLegal*kpkPlaneWords+word]&bit == 0 {
+		return WDLDraw
+	}
+	plane := kpkPlaneWhiteWin
+	if sideToMove == Black {
+		plane = kpkPlaneBlackWin
+	}
+	if KPKBitbase[plane*kpkPlaneWords+word]&bit != 0 {
+		return WDLWin
+	}
+	return WDLDraw
+}
+
+// probeEndgame returns an exact white-perspective score for positions
+// covered by the bitbase (two kings and a single pawn)
+func probeEndgame(b *Board) (int, bool) {
+	pawns := b.BB.WPawns | b.BB.BPawns
+	occupied := b.BB.colorOccupancy(White) | b.BB.colorOccupancy(Black)
+	if bits.OnesCount64(pawns) != 1 || occupied != pawns|b.BB.WKings|b.BB.BKings {
+		return 0, false
+	}
+	pawn := Square(bits.TrailingZeros64(pawns))
+
+	// Normalize to a white pawn by flipping ranks and colors
+	strong := b.Squares[pawn].Color()
+	wKing, bKing, wPawn, stm := b.KingSquare[White], b.KingSquare[Black], pawn, b.SideToMove
+	if strong == Black {
+		wKing, bKing, wPawn, stm = FlipSquare(bKing), FlipSquare(wKing), FlipSquare(pawn), stm.Opponent()
+	}
+
+	if probeKPK(wKing, wPawn, bKing, stm) != WDLWin {
+		return 0, true
+	}
+
+	// Prefer advancing the pawn and keeping the king close to it so the
+	// search makes progress between equally winning positions
+	score := KnownWinScore + 20*wPawn.Rank() - 5*kingDistance(wKing, wPawn)
+	if strong == Black {
+		return -score, true
+	}
+	return score, true
+}
@@ -0,0 +1,288 @@
+package main
+
+import "math/bits"
+
+// Bitboard move generation
+//
+// Board keeps a Bitboard alongside its Squares array: the array stays the
+// display-friendly source for rendering and FEN, the bitboards drive move
+// generation. MakeMove/UnmakeMove update both; code that edits Squares
+// directly must go through SetSquare or call SyncBitboards.
+
+// Bitboard holds one occupancy set per piece type and color (bit n = square n)
+type Bitboard struct {
+	WPawns, WKnights, WBishops, WRooks, WQueens, WKings uint64
+	BPawns, BKnights, BBishops, BRooks, BQueens, BKings uint64
+}
+
+// Pre-computed attack tables and line masks, filled in by init
+var (
+	knightAttacks [64]uint64
+	kingAttacks   [64]uint64
+	pawnAttacks   [2][64]uint64 // Squares a pawn of each color attacks
+	fileMasks     [64]uint64    // Lines through each square, excluding it
+	rankMasks     [64]uint64
+	diagMasks     [64]uint64
+	antiDiagMasks [64]uint64
+)
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		r, f := sq/8, sq%8
+
+		for _, d := range [][2]int{{2, 1}, {1, 2}, {-1, 2}, {-2, 1}, {-2, -1}, {-1, -2}, {1, -2}, {2, -1}} {
+			knightAttacks[sq] |= squareBit(r+d[0], f+d[1])
+		}
+		for dr := -1; dr <= 1; dr++ {
+			for df := -1; df <= 1; df++ {
+				if dr != 0 || df != 0 {
+					kingAttacks[sq] |= squareBit(r+dr, f+df)
+				}
+			}
+		}
+		pawnAttacks[White][sq] = squareBit(r+1, f-1) | squareBit(r+1, f+1)
+		pawnAttacks[Black][sq] = squareBit(r-1, f-1) | squareBit(r-1, f+1)
+
+		for i := 0; i < 8; i++ {
+			fileMasks[sq] |= squareBit(i, f)
+			rankMasks[sq] |= squareBit(r, i)
+			diagMasks[sq] |= squareBit(r+i-f, i)
+			antiDiagMasks[sq] |= squareBit(r+f-i, i)
+		}
+		self := uint64(1) << uint(sq)
+		fileMasks[sq] &^= self
+		rankMasks[sq] &^= self
+		diagMasks[sq] &^= self
+		antiDiagMasks[sq] &^= self
+	}
+}
+
+// squareBit returns the bit for (rank, file), or 0 off the board
+func squareBit(rank, file int) uint64 {
+	if rank < 0 || rank > 7 || file < 0 || file > 7 {
+		return 0
+	}
+	return 1 << uint(rank*8+file)
+}
+
+// of returns the set for piece p (p must not be Empty)
+func (bb *Bitboard) of(p Piece) *uint64 {
+	switch p {
+	case WPawn:
+		return &bb.WPawns
+	case WKnight:
+		return &bb.WKnights
+	case WBishop:
+		return &bb.WBishops
+	case WRook:
+		return &bb.WRooks
+	case WQueen:
+		return &bb.WQueens
+	case WKing:
+		return &bb.WKings
+	case BPawn:
+		return &bb.BPawns
+	case BKnight:
+		return &bb.BKnights
+	case BBishop:
+		return &bb.BBishops
+	case BRook:
+		return &bb.BRooks
+	case BQueen:
+		return &bb.BQueens
+	default:
+		return &bb.BKings
+	}
+}
+
+// toggle flips the bit for piece p on sq (no-op for Empty)
+func (bb *Bitboard) toggle(p Piece, sq Square) {
+	if p != Empty {
+		*bb.of(p) ^= 1 << uint(sq)
+	}
+}
+
+// colorOccupancy returns all squares holding pieces of color c
+func (bb *Bitboard) colorOccupancy(c Color) uint64 {
+	if c == White {
+		return bb.WPawns | bb.WKnights | bb.WBishops | bb.WRooks | bb.WQueens | bb.WKings
+	}
+	return bb.BPawns | bb.BKnights | bb.BBishops | bb.BRooks | bb.BQueens | bb.BKings
+}
+
+// SetSquare places p (or Empty) on sq, keeping the bitboards in step
+func (b *Board) SetSquare(sq Square, p Piece) {
+	b.BB.toggle(b.Squares[sq], sq)
+	b.Squares[sq] = p
+	b.BB.toggle(p, sq)
+}
+
+// SyncBitboards rebuilds the bitboards from the Squares array
+func (b *Board) SyncBitboards() {
+	b.BB = Bitboard{}
+	for sq := Square(0); sq < 64; sq++ {
+		b.BB.toggle(b.Squares[sq], sq)
+	}
+}
+
+// ensureBitboards rebuilds the bitboards of a board that was filled in
+// through Squares alone (no king bits means they were never set)
+func (b *Board) ensureBitboards() {
+	if b.BB.WKings|b.BB.BKings == 0 {
+		b.SyncBitboards()
+	}
+}
+
+// lineAttacks returns slider attacks from sq along one line mask using
+// hyperbola quintessence: (o - 2s) ^ reverse(reverse(o) - 2*reverse(s))
+func lineAttacks(occ uint64, sq Square, mask uint64) uint64 {
+	o := occ & mask
+	s := uint64(1) << uint(sq)
+	forward := o - 2*s
+	reverse := bits.Reverse64(bits.Reverse64(o) - 2*bits.Reverse64(s))
+	return (forward ^ reverse) & mask
+}
+
+// rookAttacks returns the squares a rook on sq attacks given occupancy occ
+func rookAttacks(occ uint64, sq Square) uint64 {
+	return lineAttacks(occ, sq, fileMasks[sq]) | lineAttacks(occ, sq, rankMasks[sq])
+}
+
+// bishopAttacks returns the squares a bishop on sq attacks given occupancy occ
+func bishopAttacks(occ uint64, sq Square) uint64 {
+	return lineAttacks(occ, sq, diagMasks[sq]) | lineAttacks(occ, sq, antiDiagMasks[sq])
+}
+
+// isAttackedBB is IsAttacked computed from the bitboards
+func (b *Board) isAttackedBB(sq Square, by Color) bool {
+	bb := &b.BB
+	occ := bb.colorOccupancy(White) | bb.colorOccupancy(Black)
+
+	pawns, knights, bishops, rooks, queens, king := bb.WPawns, bb.WKnights, bb.WBishops, bb.WRooks, bb.WQueens, bb.WKings
+	if by == Black {
+		pawns, knights, bishops, rooks, queens, king = bb.BPawns, bb.BKnights, bb.BBishops, bb.BRooks, bb.BQueens, bb.BKings
+	}
+
+	// A pawn of color by attacks sq from where a pawn of the other color on sq would attack
+	return pawnAttacks[by.Opponent()][sq]&pawns != 0 ||
+		knightAttacks[sq]&knights != 0 ||
+		kingAttacks[sq]&king != 0 ||
+		bishopAttacks(occ, sq)&(bishops|queens) != 0 ||
+		rookAttacks(occ, sq)&(rooks|queens) != 0
+}
+
+// appendTargets adds a move from from to every square in targets
+func appendTargets(moves []Move, from Square, targets uint64) []Move {
+	for ; targets != 0; targets &= targets - 1 {
+		moves = append(moves, Move{From: from, To: Square(bits.TrailingZeros64(targets))})
+	}
+	return moves
+}
+
+// appendPawnMoves adds pawn moves to every square in targets, expanding
+// moves onto the last rank into the four promotions
+func appendPawnMoves(moves []Move, from Square, targets uint64, promos []Piece) []Move {
+	for ; targets != 0; targets &= targets - 1 {
+		to := Square(bits.TrailingZeros64(targets))
+		if to.Rank() == 0 || to.Rank() == 7 {
+			for _, promo := range promos {
+				moves = append(moves, Move{From: from, To: to, Promotion: promo})
+			}
+		} else {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+	return moves
+}
+
+// Promotion pieces per color, best first
+var promoPiecesFor = [2][]Piece{
+	{WQueen, WRook, WBishop, WKnight},
+	{BQueen, BRook, BBishop, BKnight},
+}
+
+// GenerateMovesFromBitboard generates all pseudo-legal moves from the
+// bitboards; it yields the same set as GenerateMovesFromArray
+func (b *Board) GenerateMovesFromBitboard() []Move {
+	b.ensureBitboards()
+	moves := make([]Move, 0, 48)
+	us := b.SideToMove
+	them := us.Opponent()
+	bb := &b.BB
+
+	own := bb.colorOccupancy(us)
+	enemy := bb.colorOccupancy(them)
+	occ := own | enemy
+
+	pawns, knights, bishops, rooks, queens, king := bb.WPawns, bb.WKnights, bb.WBishops, bb.WRooks, bb.WQueens, bb.WKings
+	dir, startRank := 8, 1
+	if us == Black {
+		pawns, knights, bishops, rooks, queens, king = bb.BPawns, bb.BKnights, bb.BBishops, bb.BRooks, bb.BQueens, bb.BKings
+		dir, startRank = -8, 6
+	}
+
+	captureTargets := enemy
+	if b.EnPassant != NoSquare {
+		captureTargets |= 1 << uint(b.EnPassant)
+	}
+
+	// Pawns: pushes, double pushes from the start rank, captures
+	for p := pawns; p != 0; p &= p - 1 {
+		from := Square(bits.TrailingZeros64(p))
+		to := from + Square(dir)
+		var targets uint64
+		if occ&(1<<uint(to)) == 0 {
+			targets |= 1 << uint(to)
+			if from.Rank() == startRank {
+				if to2 := to + Square(dir); occ&(1<<uint(to2)) == 0 {
+					targets |= 1 << uint(to2)
+				}
+			}
+		}
+		targets |= pawnAttacks[us][from] & captureTargets
+		moves = appendPawnMoves(moves, from, targets, promoPiecesFor[us])
+	}
+
+	for p := knights; p != 0; p &= p - 1 {
+		from := Square(bits.TrailingZeros64(p))
+		moves = appendTargets(moves, from, knightAttacks[from]&^own)
+	}
+	for p := bishops | queens; p != 0; p &= p - 1 {
+		from := Square(bits.TrailingZeros64(p))
+		moves = appendTargets(moves, from, bishopAttacks(occ, from)&^own)
+	}
+	for p := rooks | queens; p != 0; p &= p - 1 {
+		from := Square(bits.TrailingZeros64(p))
+		moves = appendTargets(moves, from, rookAttacks(occ, from)&^own)
+	}
+
+	for p := king; p != 0; p &= p - 1 {
+		from := Square(bits.TrailingZeros64(p))
+		moves = appendTargets(moves, from, kingAttacks[from]&^own)
+
+		// Castling: path empty, king not passing through or out of check
+		if b.Chess960 {
+			moves = b.appendCastles960(moves)
+		} else if us == White && from == 4 {
+			if b.Castling&CastleWK != 0 && occ&0x60 == 0 &&
+				!b.isAttackedBB(4, them) && !b.isAttackedBB(5, them) && !b.isAttackedBB(6, them) {
+				moves = append(moves, Move{From: 4, To: 6})
+			}
+			if b.Castling&CastleWQ != 0 && occ&0x0E == 0 &&
+				!b.isAttackedBB(4, them) && !b.isAttackedBB(3, them) && !b.isAttackedBB(2, them) {
+				moves = append(moves, Move{From: 4, To: 2})
+			}
+		} else if us == Black && from == 60 {
+			if b.Castling&CastleBK != 0 && occ&(0x60<<56) == 0 &&
+				!b.isAttackedBB(60, them) && !b.isAttackedBB(61, them) && !b.isAttackedBB(62, them) {
+				moves = append(moves, Move{From: 60, To: 62})
+			}
+			if b.Castling&CastleBQ != 0 && occ&(0x0E<<56) == 0 &&
+				!b.isAttackedBB(60, them) && !b.isAttackedBB(59, them) && !b.isAttackedBB(58, them) {
+				moves = append(moves, Move{From: 60, To: 58})
+			}
+		}
+	}
+
+	return moves
+}
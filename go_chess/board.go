@@ -6,7 +6,7 @@ import (
 )
 
 // Board representation and move generation for chess
-// Uses 8x8 array-based representation for simplicity
+// Uses an 8x8 array for display and bitboards for move generation
 
 // Move slice pool to reduce allocations during search
 // Average chess position has ~35 legal moves, cap at 256 for promotions
@@ -167,14 +167,17 @@ func (m Move) String() string {
 
 // Board represents the chess position
 type Board struct {
-	Squares    [64]Piece
-	SideToMove Color
-	Castling   uint8   // KQkq flags
-	EnPassant  Square  // En passant target square, or NoSquare
-	HalfMoves  int     // Half-move clock (for 50-move rule)
-	FullMoves  int     // Full move number
-	KingSquare [2]Square // King positions indexed by color
-	History    []uint64  // Zobrist hashes for repetition detection
+	Squares     [64]Piece
+	SideToMove  Color
+	Castling    uint8     // KQkq flags
+	EnPassant   Square    // En passant target square, or NoSquare
+	HalfMoves   int       // Half-move clock (for 50-move rule)
+	FullMoves   int       // Full move number
+	KingSquare  [2]Square // King positions indexed by color
+	History     []uint64  // Zobrist hashes for repetition detection
+	BB          Bitboard  // Bitboard mirror of Squares (see bitboard.go)
+	Chess960    bool      // Castling with rooks on CastleRooks (see chess960.go)
+	CastleRooks [4]Square // Chess960 rook home squares per castling flag (WK, WQ, BK, BQ)
 }
 
 // NewBoard creates the starting position
@@ -200,6 +203,7 @@ func NewBoard() *Board {
 
 	b.KingSquare[White] = 4  // e1
 	b.KingSquare[Black] = 60 // e8
+	b.SyncBitboards()
 
 	return b
 }
@@ -256,23 +260,7 @@ func (b *Board) ToFEN() string {
 	}
 
 	// Castling rights
-	castling := ""
-	if b.Castling&CastleWK != 0 {
-		castling += "K"
-	}
-	if b.Castling&CastleWQ != 0 {
-		castling += "Q"
-	}
-	if b.Castling&CastleBK != 0 {
-		castling += "k"
-	}
-	if b.Castling&CastleBQ != 0 {
-		castling += "q"
-	}
-	if castling == "" {
-		castling = "-"
-	}
-	sb.WriteString(castling)
+	sb.WriteString(b.castlingFEN())
 
 	// En passant
 	if b.EnPassant == NoSquare {
@@ -338,6 +326,12 @@ func (b *Board) IsOccupied(sq Square) bool {
 // GenerateMoves generates all pseudo-legal moves
 // (Does not check if king is left in check)
 func (b *Board) GenerateMoves() []Move {
+	return b.GenerateMovesFromBitboard()
+}
+
+// GenerateMovesFromArray is the original square-scanning generator, kept
+// as a reference for perft comparisons with the bitboard generator
+func (b *Board) GenerateMovesFromArray() []Move {
 	moves := make([]Move, 0, 40)
 	us := b.SideToMove
 	them := us.Opponent()
@@ -547,7 +541,9 @@ func (b *Board) genKingMoves(sq Square, us Color, them Color, moves []Move) []Mo
 	}
 
 	// Castling
-	if us == White && sq == 4 { // e1
+	if b.Chess960 {
+		moves = b.appendCastles960(moves)
+	} else if us == White && sq == 4 { // e1
 		if b.Castling&CastleWK != 0 && b.IsEmpty(5) && b.IsEmpty(6) {
 			// Kingside castling: check squares not attacked
 			if !b.IsAttacked(4, them) && !b.IsAttacked(5, them) && !b.IsAttacked(6, them) {
@@ -698,64 +694,61 @@ func (b *Board) MakeMove(m *Move) {
 	if piece == WPawn || piece == BPawn {
 		if m.To == b.EnPassant {
 			// Remove captured pawn
+			epSq := m.To + 8
 			if us == White {
-				b.Squares[m.To-8] = Empty
-			} else {
-				b.Squares[m.To+8] = Empty
+				epSq = m.To - 8
 			}
+			b.BB.toggle(b.Squares[epSq], epSq)
+			b.Squares[epSq] = Empty
 			m.Captured = Empty // EP capture doesn't capture on target square
 		}
 	}
 
-	// Move the piece
-	b.Squares[m.To] = piece
-	b.Squares[m.From] = Empty
-
-	// Handle promotion
-	if m.Promotion != Empty {
-		b.Squares[m.To] = m.Promotion
-	}
-
-	// Handle castling move
-	if piece == WKing || piece == BKing {
-		b.KingSquare[us] = m.To
-
-		// Kingside castling
-		if m.From == 4 && m.To == 6 { // White kingside
-			b.Squares[5] = WRook
-			b.Squares[7] = Empty
-		} else if m.From == 4 && m.To == 2 { // White queenside
-			b.Squares[3] = WRook
-			b.Squares[0] = Empty
-		} else if m.From == 60 && m.To == 62 { // Black kingside
-			b.Squares[61] = BRook
-			b.Squares[63] = Empty
-		} else if m.From == 60 && m.To == 58 { // Black queenside
-			b.Squares[59] = BRook
-			b.Squares[56] = Empty
+	if i := b.castle960Index(b.Castling, us, m.To); i >= 0 && (piece == WKing || piece == BKing) {
+		// Chess960 castling: the king "captures" its own rook
+		m.Captured = Empty
+		b.makeCastle960(m.From, i)
+	} else {
+		// Move the piece
+		placed := piece
+		if m.Promotion != Empty {
+			placed = m.Promotion
+		}
+		b.BB.toggle(m.Captured, m.To)
+		b.BB.toggle(piece, m.From)
+		b.BB.toggle(placed, m.To)
+		b.Squares[m.To] = placed
+		b.Squares[m.From] = Empty
+
+		// Handle castling move
+		if piece == WKing || piece == BKing {
+			b.KingSquare[us] = m.To
+
+			// Kingside castling
+			if m.From == 4 && m.To == 6 { // White kingside
+				b.moveRook(WRook, 7, 5)
+			} else if m.From == 4 && m.To == 2 { // White queenside
+				b.moveRook(WRook, 0, 3)
+			} else if m.From == 60 && m.To == 62 { // Black kingside
+				b.moveRook(BRook, 63, 61)
+			} else if m.From == 60 && m.To == 58 { // Black queenside
+				b.moveRook(BRook, 56, 59)
+			}
 		}
 	}
 
 	// Update castling rights
 	// King moves
-	if m.From == 4 {
+	if piece == WKing {
 		b.Castling &^= (CastleWK | CastleWQ)
-	}
-	if m.From == 60 {
+	} else if piece == BKing {
 		b.Castling &^= (CastleBK | CastleBQ)
 	}
 	// Rook moves or captures
-	if m.From == 0 || m.To == 0 {
-		b.Castling &^= CastleWQ
-	}
-	if m.From == 7 || m.To == 7 {
-		b.Castling &^= CastleWK
-	}
-	if m.From == 56 || m.To == 56 {
-		b.Castling &^= CastleBQ
-	}
-	if m.From == 63 || m.To == 63 {
-		b.Castling &^= CastleBK
+	for i, sq := range b.castleRookSquares() {
+		if m.From == sq || m.To == sq {
+			b.Castling &^= castleFlags[i]
+		}
 	}
 
 	// Update en passant square
@@ -783,12 +776,26 @@ func (b *Board) MakeMove(m *Move) {
 	b.History = append(b.History, b.ZobristHash())
 }
 
+// moveRook moves a castling rook between from and to
+func (b *Board) moveRook(rook Piece, from, to Square) {
+	b.Squares[to] = rook
+	b.Squares[from] = Empty
+	b.BB.toggle(rook, from)
+	b.BB.toggle(rook, to)
+}
+
 // UnmakeMove reverses a move
 func (b *Board) UnmakeMove(m *Move) {
 	them := b.SideToMove
 	us := them.Opponent()
 	b.SideToMove = us
 
+	if i := b.castle960Index(m.OldCastle, us, m.To); i >= 0 {
+		b.unmakeCastle960(m.From, i)
+		b.restoreState(m, us)
+		return
+	}
+
 	piece := b.Squares[m.To]
 
 	// Undo promotion
@@ -801,6 +808,9 @@ func (b *Board) UnmakeMove(m *Move) {
 	}
 
 	// Move piece back
+	b.BB.toggle(b.Squares[m.To], m.To)
+	b.BB.toggle(piece, m.From)
+	b.BB.toggle(m.Captured, m.To)
 	b.Squares[m.From] = piece
 	b.Squares[m.To] = m.Captured
 
@@ -808,8 +818,10 @@ func (b *Board) UnmakeMove(m *Move) {
 	if (piece == WPawn || piece == BPawn) && m.To == m.OldEP {
 		if us == White {
 			b.Squares[m.To-8] = BPawn
+			b.BB.toggle(BPawn, m.To-8)
 		} else {
 			b.Squares[m.To+8] = WPawn
+			b.BB.toggle(WPawn, m.To+8)
 		}
 	}
 
@@ -818,21 +830,22 @@ func (b *Board) UnmakeMove(m *Move) {
 		b.KingSquare[us] = m.From
 
 		if m.From == 4 && m.To == 6 {
-			b.Squares[7] = WRook
-			b.Squares[5] = Empty
+			b.moveRook(WRook, 5, 7)
 		} else if m.From == 4 && m.To == 2 {
-			b.Squares[0] = WRook
-			b.Squares[3] = Empty
+			b.moveRook(WRook, 3, 0)
 		} else if m.From == 60 && m.To == 62 {
-			b.Squares[63] = BRook
-			b.Squares[61] = Empty
+			b.moveRook(BRook, 61, 63)
 		} else if m.From == 60 && m.To == 58 {
-			b.Squares[56] = BRook
-			b.Squares[59] = Empty
+			b.moveRook(BRook, 59, 56)
 		}
 	}
 
-	// Restore state
+	b.restoreState(m, us)
+}
+
+// restoreState restores the castling rights, en passant square, clocks and
+// repetition history saved in m when us played it
+func (b *Board) restoreState(m *Move, us Color) {
 	b.Castling = m.OldCastle
 	b.EnPassant = m.OldEP
 	b.HalfMoves = m.OldHalf
@@ -935,7 +948,7 @@ func (b *Board) GenerateLegalMoves() []Move {
 	for _, m := range pseudo {
 		b.MakeMove(&m)
 		// After making move, check if our king (now 'them' since side switched) is in check
-		if !b.IsAttacked(b.KingSquare[b.SideToMove.Opponent()], b.SideToMove) {
+		if !b.isAttackedBB(b.KingSquare[b.SideToMove.Opponent()], b.SideToMove) {
 			legal = append(legal, m)
 		}
 		b.UnmakeMove(&m)
@@ -1045,12 +1058,22 @@ func (b *Board) ParseMove(s string) (Move, bool) {
 	}
 
 	// Validate move exists in legal moves
-	for _, m := range b.GenerateLegalMoves() {
+	legal := b.GenerateLegalMoves()
+	for _, m := range legal {
 		if m.From == from && m.To == to && (promo == Empty || m.Promotion == promo) {
 			return m, true
 		}
 	}
 
+	// Castling written king-to-rook (e1h1) or, in Chess960, king-to-square (e1g1)
+	if alias, ok := b.castleAlias(from, to); ok {
+		for _, m := range legal {
+			if m.From == from && m.To == alias {
+				return m, true
+			}
+		}
+	}
+
 	return Move{}, false
 }
 
@@ -279,6 +279,7 @@ func parseFENForHash(fen string) *Board {
 		b.EnPassant = Square(rank*8 + file)
 	}
 
+	b.SyncBitboards()
 	return b
 }
 
@@ -802,8 +803,15 @@ func (book *OpeningBook) LearnFromGame(history []Move, fens []string, result Gam
 	}
 
 	book.mu.Lock()
-	defer book.mu.Unlock()
+	book.learnGame(history, fens, result)
+	book.mu.Unlock()
+
+	// Persist asynchronously
+	go book.Save()
+}
 
+// learnGame records one game's moves and result (caller must hold lock)
+func (book *OpeningBook) learnGame(history []Move, fens []string, result GameResult) {
 	// Learn from first 15 moves (30 plies) - both sides
 	maxPly := min(len(history), 30)
 	if maxPly > len(fens) {
@@ -865,9 +873,6 @@ func (book *OpeningBook) LearnFromGame(history []Move, fens []string, result Gam
 	}
 
 	book.recordGameHistory(history, result)
-
-	// Persist asynchronously
-	go book.Save()
 }
 
 // recordGameHistory adds a game to the history log (caller must hold lock)
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Opening book editing: add, remove and reweight book moves by hand. Each
+// edit rebuilds the hash index and saves the book.
+
+// bookKey returns the Positions key for fen, preferring the normalized FEN
+// as LookupFEN does; new positions are stored under the normalized FEN.
+// Caller holds book.mu.
+func (book *OpeningBook) bookKey(fen string) string {
+	normalized := normalizeFEN(fen)
+	if _, ok := book.Positions[normalized]; ok {
+		return normalized
+	}
+	if _, ok := book.Positions[fen]; ok {
+		return fen
+	}
+	return normalized
+}
+
+// editPosition applies edit to the book position for fen, then rebuilds
+// the hash index and saves the book
+func (book *OpeningBook) editPosition(fen string, edit func(pos *BookPosition) error) error {
+	if book == nil {
+		return fmt.Errorf("no opening book loaded")
+	}
+
+	book.mu.Lock()
+	key := book.bookKey(fen)
+	pos, ok := book.Positions[key]
+	if !ok {
+		pos = BookPosition{FEN: key}
+	}
+	if err := edit(&pos); err != nil {
+		book.mu.Unlock()
+		return err
+	}
+	if book.Positions == nil {
+		book.Positions = make(map[string]BookPosition)
+	}
+	book.Positions[key] = pos
+	book.buildHashIndex()
+	book.mu.Unlock()
+
+	return book.Save()
+}
+
+// findBookMove returns the index of uci among the position's moves, or -1
+func (pos *BookPosition) findBookMove(uci string) int {
+	for i := range pos.Moves {
+		if pos.Moves[i].UCI == uci {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddBookMove adds the legal move uci from b to the book with a weight of
+// one master game
+func (book *OpeningBook) AddBookMove(b *Board, uci string) error {
+	m, ok := b.ParseMove(uci)
+	if !ok {
+		return fmt.Errorf("illegal move: %s", uci)
+	}
+	uci = m.String()
+	san := MoveToSAN(b, m)
+
+	return book.editPosition(b.ToFEN(), func(pos *BookPosition) error {
+		if pos.findBookMove(uci) >= 0 {
+			return fmt.Errorf("%s is already in the book", san)
+		}
+		pos.Moves = append(pos.Moves, BookMove{UCI: uci, SAN: san, MasterGames: 1})
+		return nil
+	})
+}
+
+// RemoveBookMove deletes uci from the book position for fen
+func (book *OpeningBook) RemoveBookMove(fen, uci string) error {
+	return book.editPosition(fen, func(pos *BookPosition) error {
+		i := pos.findBookMove(uci)
+		if i < 0 {
+			return fmt.Errorf("%s is not in the book", uci)
+		}
+		pos.Moves = append(pos.Moves[:i], pos.Moves[i+1:]...)
+		return nil
+	})
+}
+
+// SetBookMoveWeight sets the master game count of uci, its base weight in
+// PickBookMove (a weight of 0 keeps the move from being picked on master
+// data alone)
+func (book *OpeningBook) SetBookMoveWeight(fen, uci string, games int) error {
+	if games < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	return book.editPosition(fen, func(pos *BookPosition) error {
+		i := pos.findBookMove(uci)
+		if i < 0 {
+			return fmt.Errorf("%s is not in the book", uci)
+		}
+		pos.Moves[i].MasterGames = games
+		return nil
+	})
+}
+
+// RenderBookPosition formats a book position for *chess-book*, moves by
+// descending master games
+func RenderBookPosition(fen string, pos *BookPosition) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Opening book: %s\n", fen))
+	if pos == nil {
+		sb.WriteString("\nPosition not in book\n")
+		return sb.String()
+	}
+	if pos.ECO != "" || pos.Name != "" {
+		sb.WriteString(fmt.Sprintf("%s %s\n", pos.ECO, pos.Name))
+	}
+	if total := pos.PosWhiteWins + pos.PosBlackWins + pos.PosDraws; total > 0 {
+		sb.WriteString(fmt.Sprintf("Games reaching here: %d (+%d =%d -%d)\n",
+			total, pos.PosWhiteWins, pos.PosDraws, pos.PosBlackWins))
+	}
+	sb.WriteString("\n")
+
+	if len(pos.Moves) == 0 {
+		sb.WriteString("No book moves\n")
+		return sb.String()
+	}
+
+	moves := make([]BookMove, len(pos.Moves))
+	copy(moves, pos.Moves)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].MasterGames > moves[j].MasterGames
+	})
+
+	sb.WriteString("Move   UCI     Master  White  Draws  Black |  Ours  Wins  Draws  Losses\n")
+	for _, bm := range moves {
+		sb.WriteString(fmt.Sprintf("%-6s %-6s %7d %6d %6d %6d | %5d %5d %6d %7d\n",
+			bm.SAN, bm.UCI, bm.MasterGames, bm.MasterWhite, bm.MasterDraws, bm.MasterBlack,
+			bm.OurGames, bm.OurWins, bm.OurDraws, bm.OurLosses))
+	}
+	return sb.String()
+}
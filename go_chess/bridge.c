@@ -24,6 +24,7 @@ typedef void (*message_fn)(const char*, ...);
 typedef void (*log_fn)(const char*, ...);
 typedef void *(*current_buffer_fn)(void);
 typedef const char *(*buffer_filename_fn)(void*);
+typedef const char *(*buffer_name_fn)(void*);
 typedef char *(*buffer_contents_fn)(void*, size_t*);
 typedef void (*get_point_fn)(int*, int*);
 typedef void (*set_point_fn)(int, int);
@@ -40,6 +41,7 @@ typedef int (*register_command_fn)(const char*, cmd_fn_t);
 typedef int (*unregister_command_fn)(const char*);
 typedef int (*config_int_fn)(const char*, const char*, int);
 typedef bool (*config_bool_fn)(const char*, const char*, bool);
+typedef const char *(*config_string_fn)(const char*, const char*, const char*);
 
 /*
  * Local API struct - only the functions we actually use
@@ -50,6 +52,7 @@ static struct {
     log_fn log_error;
     current_buffer_fn current_buffer;
     buffer_filename_fn buffer_filename;
+    buffer_name_fn buffer_name;
     buffer_contents_fn buffer_contents;
     get_point_fn get_point;
     set_point_fn set_point;
@@ -66,6 +69,7 @@ static struct {
     unregister_command_fn unregister_command;
     config_int_fn config_int;
     config_bool_fn config_bool;
+    config_string_fn config_string;
 } api;
 
 /* Extension name for config lookups */
@@ -97,6 +101,11 @@ const char* api_buffer_filename(void *bp) {
     return NULL;
 }
 
+const char* api_buffer_name(void *bp) {
+    if (api.buffer_name && bp) return api.buffer_name(bp);
+    return NULL;
+}
+
 char* api_buffer_contents(void *bp, size_t *len) {
     if (api.buffer_contents) return api.buffer_contents(bp, len);
     return NULL;
@@ -161,6 +170,11 @@ bool api_config_bool(const char *key, bool default_val) {
     return default_val;
 }
 
+const char *api_config_string(const char *key, const char *default_val) {
+    if (api.config_string) return api.config_string(EXT_NAME, key, default_val);
+    return default_val;
+}
+
 /* ============================================================================
  * Command wrappers (call Go functions)
  * ============================================================================ */
@@ -175,6 +189,39 @@ static int cmd_chess_flip(int f, int n) { return go_chess_flip(f, n); }
 static int cmd_chess_fen(int f, int n) { return go_chess_fen(f, n); }
 static int cmd_chess_auto(int f, int n) { return go_chess_auto(f, n); }
 static int cmd_chess_stop(int f, int n) { return go_chess_stop(f, n); }
+static int cmd_chess_setup(int f, int n) { return go_chess_setup(f, n); }
+static int cmd_chess_report(int f, int n) { return go_chess_report(f, n); }
+static int cmd_chess_load_fen(int f, int n) { return go_chess_load_fen(f, n); }
+static int cmd_chess_pgn(int f, int n) { return go_chess_pgn(f, n); }
+static int cmd_chess_load_pgn(int f, int n) { return go_chess_load_pgn(f, n); }
+static int cmd_chess_multi_pv(int f, int n) { return go_chess_multi_pv(f, n); }
+static int cmd_chess_toggle_ponder(int f, int n) { return go_chess_toggle_ponder(f, n); }
+static int cmd_chess_perft(int f, int n) { return go_chess_perft(f, n); }
+static int cmd_chess_tablebase(int f, int n) { return go_chess_tablebase(f, n); }
+static int cmd_chess_tt_stats(int f, int n) { return go_chess_tt_stats(f, n); }
+static int cmd_chess_tt_clear(int f, int n) { return go_chess_tt_clear(f, n); }
+static int cmd_chess_prep_mode(int f, int n) { return go_chess_prep_mode(f, n); }
+static int cmd_chess_show_prep_line(int f, int n) { return go_chess_show_prep_line(f, n); }
+static int cmd_chess_drill(int f, int n) { return go_chess_drill(f, n); }
+static int cmd_chess_list_games(int f, int n) { return go_chess_list_games(f, n); }
+static int cmd_chess_null_move_reduction(int f, int n) { return go_chess_null_move_reduction(f, n); }
+static int cmd_chess_import_pgn(int f, int n) { return go_chess_import_pgn(f, n); }
+static int cmd_chess_eval_breakdown(int f, int n) { return go_chess_eval_breakdown(f, n); }
+static int cmd_chess_960(int f, int n) { return go_chess_960(f, n); }
+static int cmd_chess_back(int f, int n) { return go_chess_back(f, n); }
+static int cmd_chess_forward(int f, int n) { return go_chess_forward(f, n); }
+static int cmd_chess_goto_move(int f, int n) { return go_chess_goto_move(f, n); }
+static int cmd_chess_edit(int f, int n) { return go_chess_edit(f, n); }
+static int cmd_chess_set_side(int f, int n) { return go_chess_set_side(f, n); }
+static int cmd_chess_set_castling(int f, int n) { return go_chess_set_castling(f, n); }
+static int cmd_chess_edit_done(int f, int n) { return go_chess_edit_done(f, n); }
+static int cmd_chess_tournament(int f, int n) { return go_chess_tournament(f, n); }
+static int cmd_chess_tournament_stop(int f, int n) { return go_chess_tournament_stop(f, n); }
+static int cmd_chess_book_add(int f, int n) { return go_chess_book_add(f, n); }
+static int cmd_chess_book_remove(int f, int n) { return go_chess_book_remove(f, n); }
+static int cmd_chess_book_set_weight(int f, int n) { return go_chess_book_set_weight(f, n); }
+static int cmd_chess_book_stats(int f, int n) { return go_chess_book_stats(f, n); }
+static int cmd_chess_uci_mode(int f, int n) { return go_chess_uci_mode(f, n); }
 
 /* ============================================================================
  * Extension lifecycle
@@ -209,6 +256,7 @@ static int chess_init_c(void *editor_api_raw) {
     api.log_error = (log_fn)LOOKUP(log_error);
     api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
     api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.buffer_name = (buffer_name_fn)LOOKUP(buffer_name);
     api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
     api.get_point = (get_point_fn)LOOKUP(get_point);
     api.set_point = (set_point_fn)LOOKUP(set_point);
@@ -225,6 +273,7 @@ static int chess_init_c(void *editor_api_raw) {
     api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
     api.config_int = (config_int_fn)LOOKUP(config_int);
     api.config_bool = (config_bool_fn)LOOKUP(config_bool);
+    api.config_string = (config_string_fn)LOOKUP(config_string);
 
     #undef LOOKUP
 
@@ -252,6 +301,39 @@ static int chess_init_c(void *editor_api_raw) {
     api.register_command("chess-fen", cmd_chess_fen);
     api.register_command("chess-auto", cmd_chess_auto);
     api.register_command("chess-stop", cmd_chess_stop);
+    api.register_command("chess-setup", cmd_chess_setup);
+    api.register_command("chess-report", cmd_chess_report);
+    api.register_command("chess-load-fen", cmd_chess_load_fen);
+    api.register_command("chess-pgn", cmd_chess_pgn);
+    api.register_command("chess-load-pgn", cmd_chess_load_pgn);
+    api.register_command("chess-multi-pv", cmd_chess_multi_pv);
+    api.register_command("chess-toggle-ponder", cmd_chess_toggle_ponder);
+    api.register_command("chess-perft", cmd_chess_perft);
+    api.register_command("chess-tablebase", cmd_chess_tablebase);
+    api.register_command("chess-tt-stats", cmd_chess_tt_stats);
+    api.register_command("chess-tt-clear", cmd_chess_tt_clear);
+    api.register_command("chess-prep-mode", cmd_chess_prep_mode);
+    api.register_command("chess-show-prep-line", cmd_chess_show_prep_line);
+    api.register_command("chess-drill", cmd_chess_drill);
+    api.register_command("chess-list-games", cmd_chess_list_games);
+    api.register_command("chess-null-move-reduction", cmd_chess_null_move_reduction);
+    api.register_command("chess-import-pgn", cmd_chess_import_pgn);
+    api.register_command("chess-eval-breakdown", cmd_chess_eval_breakdown);
+    api.register_command("chess-960", cmd_chess_960);
+    api.register_command("chess-back", cmd_chess_back);
+    api.register_command("chess-forward", cmd_chess_forward);
+    api.register_command("chess-goto-move", cmd_chess_goto_move);
+    api.register_command("chess-edit", cmd_chess_edit);
+    api.register_command("chess-set-side", cmd_chess_set_side);
+    api.register_command("chess-set-castling", cmd_chess_set_castling);
+    api.register_command("chess-edit-done", cmd_chess_edit_done);
+    api.register_command("chess-tournament", cmd_chess_tournament);
+    api.register_command("chess-tournament-stop", cmd_chess_tournament_stop);
+    api.register_command("chess-book-add", cmd_chess_book_add);
+    api.register_command("chess-book-remove", cmd_chess_book_remove);
+    api.register_command("chess-book-set-weight", cmd_chess_book_set_weight);
+    api.register_command("chess-book-stats", cmd_chess_book_stats);
+    api.register_command("chess-uci-mode", cmd_chess_uci_mode);
 
     api.log_info("go_chess: Work-stealing chess engine loaded (parallel alpha-beta)");
     return 0;
@@ -272,6 +354,39 @@ static void chess_cleanup_c(void) {
         api.unregister_command("chess-fen");
         api.unregister_command("chess-auto");
         api.unregister_command("chess-stop");
+        api.unregister_command("chess-setup");
+        api.unregister_command("chess-report");
+        api.unregister_command("chess-load-fen");
+        api.unregister_command("chess-pgn");
+        api.unregister_command("chess-load-pgn");
+        api.unregister_command("chess-multi-pv");
+        api.unregister_command("chess-toggle-ponder");
+        api.unregister_command("chess-perft");
+        api.unregister_command("chess-tablebase");
+        api.unregister_command("chess-tt-stats");
+        api.unregister_command("chess-tt-clear");
+        api.unregister_command("chess-prep-mode");
+        api.unregister_command("chess-show-prep-line");
+        api.unregister_command("chess-drill");
+        api.unregister_command("chess-list-games");
+        api.unregister_command("chess-null-move-reduction");
+        api.unregister_command("chess-import-pgn");
+        api.unregister_command("chess-eval-breakdown");
+        api.unregister_command("chess-960");
+        api.unregister_command("chess-back");
+        api.unregister_command("chess-forward");
+        api.unregister_command("chess-goto-move");
+        api.unregister_command("chess-edit");
+        api.unregister_command("chess-set-side");
+        api.unregister_command("chess-set-castling");
+        api.unregister_command("chess-edit-done");
+        api.unregister_command("chess-tournament");
+        api.unregister_command("chess-tournament-stop");
+        api.unregister_command("chess-book-add");
+        api.unregister_command("chess-book-remove");
+        api.unregister_command("chess-book-set-weight");
+        api.unregister_command("chess-book-stats");
+        api.unregister_command("chess-uci-mode");
     }
 }
 
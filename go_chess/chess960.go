@@ -0,0 +1,321 @@
+package main
+
+import "fmt"
+
+// Chess960 (Fischer Random Chess)
+//
+// A Chess960 board castles with the rooks on b.CastleRooks. Its castling
+// moves are encoded king-takes-own-rook (e1h1, as UCI_Chess960 does) so that
+// a king already standing on its destination stays unambiguous; the king
+// still ends on c1/g1 and the rook on d1/f1. Standard boards keep the e1g1
+// encoding.
+
+// Chess960Positions is the number of Chess960 starting positions
+const Chess960Positions = 960
+
+// Castling rights in flag order (WK, WQ, BK, BQ) with the king and rook
+// destinations, and the rook home squares of standard chess
+var (
+	castleFlags         = [4]uint8{CastleWK, CastleWQ, CastleBK, CastleBQ}
+	castleKingTo        = [4]Square{6, 2, 62, 58}
+	castleRookTo        = [4]Square{5, 3, 61, 59}
+	standardCastleRooks = [4]Square{7, 0, 63, 56}
+)
+
+// chess960Knights places the two knights on the five squares left after
+// the bishops and queen (index = position number / 96)
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// NewChess960Board creates starting position n (0-959) using the Scharnagl
+// numbering: bishops on opposite colors, the king between the rooks.
+// Position 518 is the standard setup.
+func NewChess960Board(n int) *Board {
+	n = ((n % Chess960Positions) + Chess960Positions) % Chess960Positions
+
+	var rank [8]Piece
+	rank[2*(n%4)+1] = WBishop // Light square: b, d, f or h
+	n /= 4
+	rank[2*(n%4)] = WBishop // Dark square: a, c, e or g
+	n /= 4
+
+	// Queen, knights, then rook-king-rook fill the free squares left to right
+	placeNth := func(p Piece, nth int) {
+		for f := 0; f < 8; f++ {
+			if rank[f] == Empty {
+				if nth == 0 {
+					rank[f] = p
+					return
+				}
+				nth--
+			}
+		}
+	}
+	placeNth(WQueen, n%6)
+	n /= 6
+	knights := chess960Knights[n]
+	placeNth(WKnight, knights[1]) // Higher index first so the lower one still counts from the left
+	placeNth(WKnight, knights[0])
+	for _, p := range []Piece{WRook, WKing, WRook} {
+		placeNth(p, 0)
+	}
+
+	b := NewEmptyBoard()
+	b.Chess960 = true
+	b.Castling = CastleWK | CastleWQ | CastleBK | CastleBQ
+	rookSeen := 0
+	for f, p := range rank {
+		b.SetSquare(Square(f), p)
+		b.SetSquare(Square(56+f), p+(BPawn-WPawn))
+		b.SetSquare(Square(8+f), WPawn)
+		b.SetSquare(Square(48+f), BPawn)
+		switch p {
+		case WKing:
+			b.KingSquare[White], b.KingSquare[Black] = Square(f), Square(56+f)
+		case WRook:
+			// The first rook is the queenside (a-side) one
+			side := 1 - rookSeen
+			b.CastleRooks[side], b.CastleRooks[side+2] = Square(f), Square(56+f)
+			rookSeen++
+		}
+	}
+	return b
+}
+
+// castleColor returns the side a castling right (0-3) belongs to
+func castleColor(i int) Color {
+	if i < 2 {
+		return White
+	}
+	return Black
+}
+
+// castleRookSquares returns the home square of each castling rook
+func (b *Board) castleRookSquares() [4]Square {
+	if b.Chess960 {
+		return b.CastleRooks
+	}
+	return standardCastleRooks
+}
+
+// castle960Index returns the castling right (0-3) that a king move by us
+// onto to uses on a Chess960 board with the given rights, or -1. With the
+// right still held its rook is on to, so no other move can land there.
+func (b *Board) castle960Index(castling uint8, us Color, to Square) int {
+	if !b.Chess960 {
+		return -1
+	}
+	for i, flag := range castleFlags {
+		if castleColor(i) == us && castling&flag != 0 && b.CastleRooks[i] == to {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsCastle reports whether m, played from b, castles, and on which wing
+func (b *Board) IsCastle(m Move) (castle, kingside bool) {
+	piece := b.Squares[m.From]
+	if piece != WKing && piece != BKing {
+		return false, false
+	}
+	if b.Chess960 {
+		i := b.castle960Index(b.Castling, piece.Color(), m.To)
+		return i >= 0, i%2 == 0
+	}
+	switch int(m.To) - int(m.From) {
+	case 2:
+		return true, true
+	case -2:
+		return true, false
+	}
+	return false, false
+}
+
+// appendCastles960 adds the Chess960 castling moves of the side to move.
+// Every square between the king, the rook and their destinations must be
+// empty apart from those two pieces, and the king may not start on or
+// cross an attacked square; its destination is checked by the legality
+// filter once the rook has moved.
+func (b *Board) appendCastles960(moves []Move) []Move {
+	b.ensureBitboards()
+	us := b.SideToMove
+	them := us.Opponent()
+	kingSq := b.KingSquare[us]
+
+	for i, flag := range castleFlags {
+		if castleColor(i) != us || b.Castling&flag == 0 {
+			continue
+		}
+		rookSq, kingTo, rookTo := b.CastleRooks[i], castleKingTo[i], castleRookTo[i]
+
+		lo, hi := kingSq, kingSq
+		for _, sq := range []Square{rookSq, kingTo, rookTo} {
+			if sq < lo {
+				lo = sq
+			}
+			if sq > hi {
+				hi = sq
+			}
+		}
+		clear := true
+		for sq := lo; sq <= hi; sq++ {
+			if sq != kingSq && sq != rookSq && b.Squares[sq] != Empty {
+				clear = false
+				break
+			}
+		}
+		if !clear {
+			continue
+		}
+
+		step := Square(1)
+		if kingTo < kingSq {
+			step = -1
+		}
+		safe := true
+		for sq := kingSq; sq != kingTo; sq += step {
+			if b.isAttackedBB(sq, them) {
+				safe = false
+				break
+			}
+		}
+		if safe {
+			moves = append(moves, Move{From: kingSq, To: rookSq})
+		}
+	}
+	return moves
+}
+
+// makeCastle960 plays castling right i for the king on from
+func (b *Board) makeCastle960(from Square, i int) {
+	king, rook := b.Squares[from], b.Squares[b.CastleRooks[i]]
+	b.SetSquare(from, Empty)
+	b.SetSquare(b.CastleRooks[i], Empty)
+	b.SetSquare(castleKingTo[i], king)
+	b.SetSquare(castleRookTo[i], rook)
+	b.KingSquare[king.Color()] = castleKingTo[i]
+}
+
+// unmakeCastle960 takes back castling right i, returning the king to from
+func (b *Board) unmakeCastle960(from Square, i int) {
+	king, rook := b.Squares[castleKingTo[i]], b.Squares[castleRookTo[i]]
+	b.SetSquare(castleKingTo[i], Empty)
+	b.SetSquare(castleRookTo[i], Empty)
+	b.SetSquare(from, king)
+	b.SetSquare(b.CastleRooks[i], rook)
+	b.KingSquare[king.Color()] = from
+}
+
+// castleAlias translates a castling move written in the other notation:
+// king-to-destination (e1g1) on a Chess960 board, king-to-rook (e1h1) on a
+// standard one. It reports false when from/to is not such a move.
+func (b *Board) castleAlias(from, to Square) (Square, bool) {
+	us := b.SideToMove
+	if from != b.KingSquare[us] {
+		return NoSquare, false
+	}
+	rooks := b.castleRookSquares()
+	for i, flag := range castleFlags {
+		if castleColor(i) != us || b.Castling&flag == 0 {
+			continue
+		}
+		if b.Chess960 && to == castleKingTo[i] {
+			return rooks[i], true
+		}
+		if !b.Chess960 && to == rooks[i] {
+			return castleKingTo[i], true
+		}
+	}
+	return NoSquare, false
+}
+
+// castlingFEN returns the castling field of the FEN: KQkq on a standard
+// board, rook files (Shredder-FEN, e.g. HAha) on a Chess960 one
+func (b *Board) castlingFEN() string {
+	s := ""
+	for i, flag := range castleFlags {
+		if b.Castling&flag == 0 {
+			continue
+		}
+		c := "KQkq"[i]
+		if b.Chess960 {
+			c = byte('A' + b.CastleRooks[i].File())
+			if castleColor(i) == Black {
+				c += 'a' - 'A'
+			}
+		}
+		s += string(c)
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// addCastlingRight parses one character of a FEN castling field: K/Q/k/q
+// take the outermost rook on that wing, A-H/a-h name the rook's file. A
+// right that is not king-on-e, rook-on-a/h makes the board Chess960.
+func (b *Board) addCastlingRight(c byte) error {
+	c960 := false
+	us, rank, file := White, 0, -1
+	switch {
+	case c == 'K' || c == 'Q':
+	case c == 'k' || c == 'q':
+		us, rank = Black, 7
+	case c >= 'A' && c <= 'H':
+		file, c960 = int(c-'A'), true
+	case c >= 'a' && c <= 'h':
+		us, rank, file, c960 = Black, 7, int(c-'a'), true
+	default:
+		return fmt.Errorf("invalid castling flag '%c'", c)
+	}
+
+	king := WKing
+	if us == Black {
+		king = BKing
+	}
+	rook := king - WKing + WRook
+	kingFile := -1
+	for f := 0; f < 8; f++ {
+		if b.Squares[FromRankFile(rank, f)] == king {
+			kingFile = f
+		}
+	}
+	if kingFile < 0 {
+		return fmt.Errorf("castling right %c without a king on the back rank", c)
+	}
+
+	switch c {
+	case 'K', 'k':
+		for f := 7; f > kingFile && file < 0; f-- {
+			if b.Squares[FromRankFile(rank, f)] == rook {
+				file = f
+			}
+		}
+	case 'Q', 'q':
+		for f := 0; f < kingFile && file < 0; f++ {
+			if b.Squares[FromRankFile(rank, f)] == rook {
+				file = f
+			}
+		}
+	}
+	if file < 0 || file == kingFile || b.Squares[FromRankFile(rank, file)] != rook {
+		return fmt.Errorf("castling right %c without a rook to castle with", c)
+	}
+
+	i := 0
+	if file < kingFile {
+		i = 1
+	}
+	if us == Black {
+		i += 2
+	}
+	b.Castling |= castleFlags[i]
+	b.CastleRooks[i] = FromRankFile(rank, file)
+	if c960 || kingFile != 4 || (file != 0 && file != 7) {
+		b.Chess960 = true
+	}
+	return nil
+}
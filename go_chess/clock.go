@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time controls ("5+3" = 5 minutes per side plus 3 seconds per move)
+
+// ParseTimeControl parses "minutes[+increment]" where minutes may be
+// fractional and the increment is in seconds
+func ParseTimeControl(tc string) (base, increment time.Duration, err error) {
+	baseStr, incStr, hasInc := strings.Cut(strings.TrimSpace(tc), "+")
+
+	minutes, err := strconv.ParseFloat(strings.TrimSpace(baseStr), 64)
+	if err != nil || minutes <= 0 {
+		return 0, 0, fmt.Errorf("invalid time control %q (expected e.g. 5+3)", tc)
+	}
+	base = time.Duration(minutes * float64(time.Minute))
+
+	if hasInc {
+		secs, err := strconv.ParseFloat(strings.TrimSpace(incStr), 64)
+		if err != nil || secs < 0 {
+			return 0, 0, fmt.Errorf("invalid increment in time control %q", tc)
+		}
+		increment = time.Duration(secs * float64(time.Second))
+	}
+	return base, increment, nil
+}
+
+// FormatClock renders a remaining time as m:ss, with tenths under 10s
+func FormatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	if d < 10*time.Second {
+		return fmt.Sprintf("0:%04.1f", d.Seconds())
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -175,6 +175,25 @@ func IsEndgame(b *Board) bool {
 	return whiteMaterial < 1300 || blackMaterial < 1300
 }
 
+// EvalBreakdown holds the components of the static evaluation, each from
+// White's perspective in centipawns
+type EvalBreakdown struct {
+	Material      int // Piece values
+	Position      int // Piece-square tables and bishop pair
+	Mobility      int // Pseudo-legal move count difference
+	KingSafety    int // Shelter, open files and king-zone attacks (middlegame only)
+	PawnStructure int // Passed, isolated, doubled and backward pawns
+	Tempo         int // Bonus for the side to move
+}
+
+// TempoBonus is the side to move's advantage of having the next move
+const TempoBonus = 10
+
+// Total returns the sum of the components
+func (e EvalBreakdown) Total() int {
+	return e.Material + e.Position + e.Mobility + e.KingSafety + e.PawnStructure + e.Tempo
+}
+
 // Evaluate returns the static evaluation of a position
 // Positive = White advantage, Negative = Black advantage (in centipawns)
 func Evaluate(b *Board) int {
@@ -189,7 +208,13 @@ func Evaluate(b *Board) int {
 		return 0
 	}
 
-	score := 0
+	return EvaluateBreakdown(b).Total()
+}
+
+// EvaluateBreakdown returns the components of the static evaluation,
+// ignoring checkmate and draws
+func EvaluateBreakdown(b *Board) EvalBreakdown {
+	var e EvalBreakdown
 	endgame := IsEndgame(b)
 
 	// Material and piece-square evaluation
@@ -202,9 +227,9 @@ func Evaluate(b *Board) int {
 		// Material
 		val := PieceValue[p.Type()]
 		if p.IsWhite() {
-			score += val
+			e.Material += val
 		} else {
-			score -= val
+			e.Material -= val
 		}
 
 		// Piece-square bonus
@@ -222,12 +247,12 @@ func Evaluate(b *Board) int {
 			}
 			psVal := pst[idx]
 			if p.IsWhite() {
-				score += psVal
+				e.Position += psVal
 			} else {
-				score -= psVal
+				e.Position -= psVal
 			}
 		} else {
-			score += GetPST(p, sq)
+			e.Position += GetPST(p, sq)
 		}
 	}
 
@@ -244,7 +269,7 @@ func Evaluate(b *Board) int {
 
 	b.SideToMove = origSide
 
-	score += (whiteMobility - blackMobility) * 2 // 2 centipawns per move
+	e.Mobility = (whiteMobility - blackMobility) * 2 // 2 centipawns per move
 
 	// Bishop pair bonus
 	whiteBishops, blackBishops := 0, 0
@@ -256,13 +281,25 @@ func Evaluate(b *Board) int {
 		}
 	}
 	if whiteBishops >= 2 {
-		score += 30
+		e.Position += 30
 	}
 	if blackBishops >= 2 {
-		score -= 30
+		e.Position -= 30
 	}
 
-	return score
+	// King safety matters while there is material to attack with
+	if !endgame {
+		e.KingSafety = kingSafetyScore(b, White) - kingSafetyScore(b, Black)
+	}
+
+	e.PawnStructure = pawnStructure(b)
+
+	e.Tempo = TempoBonus
+	if b.SideToMove == Black {
+		e.Tempo = -TempoBonus
+	}
+
+	return e
 }
 
 // MVV_LVA returns a score for move ordering (Most Valuable Victim - Least Valuable Attacker)
@@ -285,6 +322,139 @@ func MVV_LVA(b *Board, m Move) int {
 	return PieceValue[captured.Type()]*10 - PieceValue[attacker.Type()]
 }
 
+// SEE performs static exchange evaluation of a capture (or promotion).
+// Returns the expected material gain in centipawns for the side making
+// the move, assuming both sides recapture on m.To with their least
+// valuable attacker and may stop whenever continuing would lose material.
+func SEE(b *Board, m Move) int {
+	// Work on a scratch board: only squares matter for attack detection
+	tmp := Board{Squares: b.Squares}
+	target := m.To
+	attacker := tmp.Squares[m.From]
+	captured := tmp.Squares[target]
+
+	// En passant: the captured pawn is beside the target square
+	if captured == Empty && attacker.Type() == 1 && m.From.File() != target.File() {
+		epSq := FromRankFile(m.From.Rank(), target.File())
+		captured = tmp.Squares[epSq]
+		tmp.Squares[epSq] = Empty
+	}
+
+	var gain [32]int
+	gain[0] = PieceValue[captured.Type()]
+
+	onTarget := attacker
+	if m.Promotion != Empty {
+		gain[0] += PieceValue[m.Promotion.Type()] - PieceValue[1]
+		onTarget = m.Promotion
+	}
+	tmp.Squares[m.From] = Empty
+	tmp.Squares[target] = onTarget
+
+	side := attacker.Color().Opponent()
+	d := 0
+	for d < len(gain)-1 {
+		from := tmp.leastValuableAttacker(target, side)
+		if from == NoSquare {
+			break
+		}
+		piece := tmp.Squares[from]
+		tmp.Squares[from] = Empty
+
+		// A king may only recapture if the square is no longer defended
+		if piece.Type() == 6 && tmp.IsAttacked(target, side.Opponent()) {
+			break
+		}
+
+		d++
+		gain[d] = PieceValue[onTarget.Type()] - gain[d-1]
+		onTarget = piece
+		tmp.Squares[target] = piece
+		side = side.Opponent()
+	}
+
+	// Negamax the swap list: either side may decline to continue
+	for ; d > 0; d-- {
+		gain[d-1] = -max(-gain[d-1], gain[d])
+	}
+	return gain[0]
+}
+
+// leastValuableAttacker returns the square of the cheapest piece of color
+// by that attacks sq, or NoSquare. Sliders see through pieces already
+// removed from the board, so x-ray attackers are found naturally.
+func (b *Board) leastValuableAttacker(sq Square, by Color) Square {
+	offset := Piece(0)
+	pawnDir := -8
+	if by == Black {
+		offset = BPawn - WPawn
+		pawnDir = 8
+	}
+
+	// Pawns
+	for _, fd := range []int{-1, 1} {
+		from := sq + Square(pawnDir+fd)
+		if from.IsValid() && abs(from.File()-sq.File()) == 1 && b.Squares[from] == WPawn+offset {
+			return from
+		}
+	}
+
+	// Knights
+	for _, dir := range knightDirs {
+		from := sq + Square(dir)
+		if from.IsValid() && b.Squares[from] == WKnight+offset {
+			dr := abs(from.Rank() - sq.Rank())
+			df := abs(from.File() - sq.File())
+			if (dr == 2 && df == 1) || (dr == 1 && df == 2) {
+				return from
+			}
+		}
+	}
+
+	// Sliders, cheapest first
+	if from := b.sliderAttacker(sq, bishopDirs, WBishop+offset); from != NoSquare {
+		return from
+	}
+	if from := b.sliderAttacker(sq, rookDirs, WRook+offset); from != NoSquare {
+		return from
+	}
+	if from := b.sliderAttacker(sq, queenDirs, WQueen+offset); from != NoSquare {
+		return from
+	}
+
+	// King
+	for _, dir := range kingDirs {
+		from := sq + Square(dir)
+		if from.IsValid() && abs(from.Rank()-sq.Rank()) <= 1 && abs(from.File()-sq.File()) <= 1 &&
+			b.Squares[from] == WKing+offset {
+			return from
+		}
+	}
+
+	return NoSquare
+}
+
+// sliderAttacker finds a piece p attacking sq along any of dirs
+func (b *Board) sliderAttacker(sq Square, dirs []int, p Piece) Square {
+	for _, dir := range dirs {
+		to := sq
+		for {
+			prev := to
+			to += Square(dir)
+			if !to.IsValid() || abs(to.Rank()-prev.Rank()) > 1 || abs(to.File()-prev.File()) > 1 {
+				break
+			}
+			if q := b.Squares[to]; q != Empty {
+				if q == p {
+					return to
+				}
+				break
+			}
+		}
+	}
+	return NoSquare
+}
+
 // OrderMoves sorts moves by MVV-LVA heuristic (in place)
 // Returns the sorted slice (same backing array)
 func OrderMoves(b *Board, moves []Move) []Move {
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FEN import
+
+// fenPieces maps FEN piece letters to pieces
+var fenPieces = map[byte]Piece{
+	'P': WPawn, 'N': WKnight, 'B': WBishop, 'R': WRook, 'Q': WQueen, 'K': WKing,
+	'p': BPawn, 'n': BKnight, 'b': BBishop, 'r': BRook, 'q': BQueen, 'k': BKing,
+}
+
+// ParseFEN parses a FEN string into a validated board. The half-move clock
+// and full move number may be omitted (defaulting to 0 and 1).
+func ParseFEN(fen string) (*Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || len(fields) > 6 {
+		return nil, fmt.Errorf("expected 4-6 fields, got %d", len(fields))
+	}
+
+	b := NewEmptyBoard()
+
+	// Piece placement, rank 8 first
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("expected 8 ranks, got %d", len(ranks))
+	}
+	for i, row := range ranks {
+		rank, file := 7-i, 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+			p, ok := fenPieces[c]
+			if !ok {
+				return nil, fmt.Errorf("invalid piece '%c' on rank %d", c, rank+1)
+			}
+			if file > 7 {
+				return nil, fmt.Errorf("rank %d has more than 8 squares", rank+1)
+			}
+			b.SetSquare(FromRankFile(rank, file), p)
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("rank %d has %d squares", rank+1, file)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		b.SideToMove = White
+	case "b":
+		b.SideToMove = Black
+	default:
+		return nil, fmt.Errorf("side to move must be w or b: %s", fields[1])
+	}
+
+	if fields[2] != "-" {
+		for i := 0; i < len(fields[2]); i++ {
+			if err := b.addCastlingRight(fields[2][i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		sq, ok := ParseSquare(fields[3])
+		if !ok {
+			return nil, fmt.Errorf("invalid en passant square: %s", fields[3])
+		}
+		b.EnPassant = sq
+	}
+
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid half-move clock: %s", fields[4])
+		}
+		b.HalfMoves = n
+	}
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid full move number: %s", fields[5])
+		}
+		b.FullMoves = n
+	}
+
+	if err := validateFEN(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// validateFEN checks the pieces (validatePlacement), castling rights and
+// en passant square of a parsed position, and sets the king squares
+func validateFEN(b *Board) error {
+	if err := validatePlacement(b); err != nil {
+		return err
+	}
+
+	// Each castling right needs the king on its back rank and that rook on
+	// its home square, on the right wing
+	rooks := b.castleRookSquares()
+	for i, flag := range castleFlags {
+		if b.Castling&flag == 0 {
+			continue
+		}
+		c := castleColor(i)
+		king, rook := b.KingSquare[c], rooks[i]
+		rookPiece := WRook
+		if c == Black {
+			rookPiece = BRook
+		}
+		kingside := i%2 == 0
+		if king.Rank() != rook.Rank() || (rook.Rank() != 0 && rook.Rank() != 7) ||
+			b.Squares[rook] != rookPiece || (rook > king) != kingside ||
+			(!b.Chess960 && king.File() != 4) {
+			return fmt.Errorf("castling right %c without king and rook on %s and %s",
+				"KQkq"[i], SquareName(king), SquareName(rook))
+		}
+	}
+
+	// En passant target: behind a pawn of the side that just moved
+	if b.EnPassant != NoSquare {
+		rank, pawnSq, pawn := 5, b.EnPassant-8, BPawn
+		if b.SideToMove == Black {
+			rank, pawnSq, pawn = 2, b.EnPassant+8, WPawn
+		}
+		if b.EnPassant.Rank() != rank || b.Squares[b.EnPassant] != Empty || b.Squares[pawnSq] != pawn {
+			return fmt.Errorf("invalid en passant square %s", SquareName(b.EnPassant))
+		}
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ extern const char *_GoStringPtr(_GoString_ s);
 /* Start of preamble from import "C" comments.  */
 
 
-#line 23 "main.go"
+#line 56 "main.go"
 
 #include <stdlib.h>
 #include <stdint.h>
@@ -32,6 +32,7 @@ extern void api_message(const char *msg);
 extern void *api_current_buffer(void);
 extern char *api_buffer_contents(void *bp, size_t *len);
 extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
 extern void api_get_point(int *line, int *col);
 extern void api_set_point(int line, int col);
 extern int api_buffer_insert(const char *text, size_t len);
@@ -47,6 +48,7 @@ extern void api_log_error(const char *msg);
 extern void api_update_display(void);
 extern int api_config_int(const char *key, int default_val);
 extern _Bool api_config_bool(const char *key, _Bool default_val);
+extern const char *api_config_string(const char *key, const char *default_val);
 
 #line 1 "cgo-generated-wrapper"
 
@@ -114,13 +116,46 @@ extern void chess_init(void* api);
 extern int go_chess_new(int f, int n);
 extern int go_chess_move(int f, int n);
 extern int go_chess_undo(int f, int n);
+extern int go_chess_back(int f, int n);
+extern int go_chess_forward(int f, int n);
+extern int go_chess_goto_move(int f, int n);
 extern int go_chess_depth(int f, int n);
+extern int go_chess_null_move_reduction(int f, int n);
 extern int go_chess_eval(int f, int n);
+extern int go_chess_eval_breakdown(int f, int n);
+extern int go_chess_tablebase(int f, int n);
+extern int go_chess_tt_stats(int f, int n);
+extern int go_chess_tt_clear(int f, int n);
 extern int go_chess_hint(int f, int n);
 extern int go_chess_flip(int f, int n);
 extern int go_chess_fen(int f, int n);
 extern int go_chess_auto(int f, int n);
+extern int go_chess_tournament(int f, int n);
+extern int go_chess_tournament_stop(int f, int n);
+extern int go_chess_uci_mode(int f, int n);
 extern int go_chess_stop(int f, int n);
+extern int go_chess_setup(int f, int n);
+extern int go_chess_edit(int f, int n);
+extern int go_chess_set_side(int f, int n);
+extern int go_chess_set_castling(int f, int n);
+extern int go_chess_edit_done(int f, int n);
+extern int go_chess_load_fen(int f, int n);
+extern int go_chess_960(int f, int n);
+extern int go_chess_report(int f, int n);
+extern int go_chess_pgn(int f, int n);
+extern int go_chess_load_pgn(int f, int n);
+extern int go_chess_prep_mode(int f, int n);
+extern int go_chess_show_prep_line(int f, int n);
+extern int go_chess_drill(int f, int n);
+extern int go_chess_import_pgn(int f, int n);
+extern int go_chess_multi_pv(int f, int n);
+extern int go_chess_toggle_ponder(int f, int n);
+extern int go_chess_perft(int f, int n);
+extern int go_chess_book_add(int f, int n);
+extern int go_chess_book_remove(int f, int n);
+extern int go_chess_book_set_weight(int f, int n);
+extern int go_chess_book_stats(int f, int n);
+extern int go_chess_list_games(int f, int n);
 extern void go_chess_cleanup(void);
 
 #ifdef __cplusplus
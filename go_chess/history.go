@@ -0,0 +1,83 @@
+package main
+
+import "time"
+
+// History navigation: stepping back moves plies from History onto Forward,
+// stepping forward replays them. While Forward is non-empty the game is in
+// review mode; playing a move from there discards the remaining plies.
+
+// reviewing reports whether the board is showing an earlier position
+func (g *Game) reviewing() bool {
+	return len(g.Forward) > 0
+}
+
+// stepBack takes back the last ply, keeping it (and its thinking time)
+// for stepForward
+func (g *Game) stepBack() bool {
+	n := len(g.History)
+	if n == 0 {
+		return false
+	}
+	m := g.History[n-1]
+	g.Board.UnmakeMove(&m)
+	g.History = g.History[:n-1]
+	g.Forward = append([]Move{m}, g.Forward...)
+
+	var elapsed time.Duration
+	if len(g.MoveTimes) == n {
+		elapsed = g.MoveTimes[n-1]
+		g.MoveTimes = g.MoveTimes[:n-1]
+	}
+	g.forwardTimes = append([]time.Duration{elapsed}, g.forwardTimes...)
+
+	g.updateLastMove()
+	return true
+}
+
+// stepForward replays the next ply stepped back over
+func (g *Game) stepForward() bool {
+	if len(g.Forward) == 0 {
+		return false
+	}
+	m, elapsed := g.Forward[0], g.forwardTimes[0]
+	g.Forward, g.forwardTimes = g.Forward[1:], g.forwardTimes[1:]
+	g.Board.MakeMove(&m)
+	if len(g.MoveTimes) == len(g.History) {
+		g.MoveTimes = append(g.MoveTimes, elapsed)
+	}
+	g.History = append(g.History, m)
+
+	g.updateLastMove()
+	return true
+}
+
+// seekPly steps back or forward until ply plies of the game have been
+// played, clamped to the start and the end of the game
+func (g *Game) seekPly(ply int) {
+	for len(g.History) > ply && g.stepBack() {
+	}
+	for len(g.History) < ply && g.stepForward() {
+	}
+}
+
+// totalPlies returns the length of the game including reviewed plies
+func (g *Game) totalPlies() int {
+	return len(g.History) + len(g.Forward)
+}
+
+// resumeFromReview leaves review mode at the current position, dropping
+// the plies after it
+func (g *Game) resumeFromReview() {
+	g.Forward = nil
+	g.forwardTimes = nil
+	g.turnStart = time.Now()
+}
+
+// updateLastMove points LastMove at the last ply of History
+func (g *Game) updateLastMove() {
+	if len(g.History) > 0 {
+		g.LastMove = g.History[len(g.History)-1]
+	} else {
+		g.LastMove = Move{}
+	}
+}
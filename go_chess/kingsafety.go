@@ -0,0 +1,121 @@
+package main
+
+import "math/bits"
+
+// King safety: pawn shelter, open files and enemy pieces attacking the
+// squares around the king. Only scored in the middlegame.
+
+// kingAttackZone holds the squares around a king on each square,
+// including the king square itself
+var kingAttackZone [64]uint64
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		r, f := sq/8, sq%8
+		for dr := -1; dr <= 1; dr++ {
+			for df := -1; df <= 1; df++ {
+				kingAttackZone[sq] |= squareBit(r+dr, f+df)
+			}
+		}
+	}
+}
+
+// King safety weights (centipawns)
+const (
+	shelterNearBonus    = 10 // Own pawn directly in front of the king's files
+	shelterFarBonus     = 5  // Own pawn two ranks in front
+	shelterMissing      = 15 // No shelter pawn on a file next to a castled king
+	halfOpenFilePenalty = 15 // No own pawn on a file next to the king
+	openFilePenalty     = 10 // Additionally no enemy pawn on that file
+	kingAttackUnit      = 10 // Centipawns per weighted attack on the zone
+)
+
+// kingAttackWeight is the attack weight per zone square, indexed by piece type
+var kingAttackWeight = [7]int{0, 0, 2, 2, 3, 5, 0}
+
+// kingAttackerScale scales the attack penalty (percent) by the number of
+// enemy pieces attacking the zone: a lone attacker is rarely dangerous
+var kingAttackerScale = [8]int{0, 0, 50, 75, 88, 94, 97, 99}
+
+// kingSafetyScore returns the king safety of side in centipawns: shelter
+// bonuses minus open-file and attack penalties. Higher is safer.
+func kingSafetyScore(b *Board, side Color) int {
+	b.ensureBitboards()
+	bb := &b.BB
+	them := side.Opponent()
+
+	king, ownPawns, enemyPawns := bb.WKings, bb.WPawns, bb.BPawns
+	if side == Black {
+		king, ownPawns, enemyPawns = bb.BKings, bb.BPawns, bb.WPawns
+	}
+	if king == 0 {
+		return 0
+	}
+	kingSq := Square(bits.TrailingZeros64(king))
+	kingFile := kingSq.File()
+	kingRank := kingSq.Rank()
+	forward, homeRank := 1, 0
+	if side == Black {
+		forward, homeRank = -1, 7
+	}
+
+	score := 0
+
+	// Pawn shelter: a king castled to either wing wants pawns in front
+	castled := kingRank == homeRank && (kingFile <= 2 || kingFile >= 5)
+	for f := kingFile - 1; f <= kingFile+1; f++ {
+		if f < 0 || f > 7 {
+			continue
+		}
+		if castled {
+			switch {
+			case ownPawns&squareBit(kingRank+forward, f) != 0:
+				score += shelterNearBonus
+			case ownPawns&squareBit(kingRank+2*forward, f) != 0:
+				score += shelterFarBonus
+			default:
+				score -= shelterMissing
+			}
+		}
+
+		// Open files next to the king
+		file := fileMasks[f] | 1<<uint(f)
+		if ownPawns&file == 0 {
+			score -= halfOpenFilePenalty
+			if enemyPawns&file == 0 {
+				score -= openFilePenalty
+			}
+		}
+	}
+
+	// Enemy pieces attacking the king zone
+	zone := kingAttackZone[kingSq]
+	occ := bb.colorOccupancy(White) | bb.colorOccupancy(Black)
+	attackers, weight := 0, 0
+	for occupied := bb.colorOccupancy(them); occupied != 0; occupied &= occupied - 1 {
+		sq := Square(bits.TrailingZeros64(occupied))
+		var attacks uint64
+		switch pt := b.Squares[sq].Type(); pt {
+		case 2:
+			attacks = knightAttacks[sq]
+		case 3:
+			attacks = bishopAttacks(occ, sq)
+		case 4:
+			attacks = rookAttacks(occ, sq)
+		case 5:
+			attacks = bishopAttacks(occ, sq) | rookAttacks(occ, sq)
+		default:
+			continue
+		}
+		if hits := bits.OnesCount64(attacks & zone); hits > 0 {
+			attackers++
+			weight += kingAttackWeight[b.Squares[sq].Type()] * hits
+		}
+	}
+	if attackers >= len(kingAttackerScale) {
+		attackers = len(kingAttackerScale) - 1
+	}
+	score -= weight * kingAttackUnit * kingAttackerScale[attackers] / 100
+
+	return score
+}
@@ -4,7 +4,7 @@
 // for parallel alpha-beta search. Human vs AI with configurable depth.
 //
 // Commands:
-//   chess         - Start new game (Human=White, AI=Black)
+//   chess         - Start new game (Human=White, AI=Black); prefix N plays in *chess-N*
 //   chess-move    - Make a move (e.g., "e2e4", "e7e8q" for promotion)
 //   chess-undo    - Undo last move pair
 //   chess-depth   - Set search depth (default: 6)
@@ -15,6 +15,39 @@
 //   chess-auto    - AI vs AI mode (runs until game ends)
 //   chess-workers - Set worker count (default: 2 for auto, NumCPU for hint)
 //   chess-stop    - Stop AI vs AI game
+//   chess-setup   - Set up a custom position piece by piece
+//   chess-report  - Post-game analysis summary
+//   chess-load-fen - Load a position from a FEN string
+//   chess-pgn     - Export the game as PGN (optionally saved to a file)
+//   chess-load-pgn - Load and replay a game from a PGN file
+//   chess-multi-pv - Show the N best moves in *chess-analysis*
+//   chess-toggle-ponder - Let the AI think during your turn
+//   chess-eval-breakdown - Show each evaluation component in *chess-eval*
+//   chess-960 - Start a Chess960 (Fischer Random) game
+//   chess-back - Step one move back through the game
+//   chess-forward - Step one move forward again
+//   chess-goto-move - Jump to a move number
+//   chess-edit - Edit the position piece by piece
+//   chess-set-side - Set the side to move while editing
+//   chess-set-castling - Set castling rights while editing
+//   chess-edit-done - Validate the edited position and play from it
+//   chess-tournament - Play N self-play games and summarize them
+//   chess-tournament-stop - Abort the tournament
+//   chess-book-add - Add a move to the opening book for this position
+//   chess-book-remove - Remove a book move from this position
+//   chess-book-set-weight - Set a book move's weight (master games)
+//   chess-book-stats - Show the book entry for this position in *chess-book*
+//   chess-uci-mode - Serve the UCI protocol on a named pipe or stdin
+//   chess-perft - Count move-generator leaf nodes and check them against reference values
+//   chess-tablebase - Show the Syzygy tablebase result (WDL and DTZ) for the position
+//   chess-tt-stats - Show transposition table occupancy, hit rate and store rate
+//   chess-tt-clear - Clear the transposition table and its statistics
+//   chess-prep-mode - Practise an opening line from a PGN or FEN file
+//   chess-show-prep-line - Show the moves left in the line in *chess-prep*
+//   chess-drill - Play the line on from a random position in its middle
+//   chess-list-games - Show every game and its board in *chess-games*
+//   chess-null-move-reduction - Set the null-move pruning reduction (0 disables it)
+//   chess-import-pgn - Teach the opening book the finished games of a PGN collection
 //
 // Built with CGO as a shared library for μEmacs extension system.
 
@@ -30,6 +63,7 @@ extern void api_message(const char *msg);
 extern void *api_current_buffer(void);
 extern char *api_buffer_contents(void *bp, size_t *len);
 extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
 extern void api_get_point(int *line, int *col);
 extern void api_set_point(int line, int col);
 extern int api_buffer_insert(const char *text, size_t len);
@@ -45,35 +79,104 @@ extern void api_log_error(const char *msg);
 extern void api_update_display(void);
 extern int api_config_int(const char *key, int default_val);
 extern _Bool api_config_bool(const char *key, _Bool default_val);
+extern const char *api_config_string(const char *key, const char *default_val);
 */
 import "C"
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 	"unsafe"
 )
 
 // Game holds the current game state
 type Game struct {
-	Board        *Board
-	History      []Move
-	FENHistory   []string // Track FENs for opening book learning
-	LastMove     Move
-	Flipped      bool
-	SearchDepth  int
-	TimeLimit    time.Duration
-	Workers      int  // Max workers for search (0 = NumCPU)
-	AutoDelayMs  int  // Delay between moves in auto mode
-	AutoStop     bool // Flag to stop AI vs AI
-}
-
-// Global game state
+	Board       *Board
+	StartBoard  *Board // Position the game started from (for SAN replay)
+	History     []Move
+	FENHistory  []string // Track FENs for opening book learning
+	LastMove    Move
+	Flipped     bool
+	SearchDepth int
+	TimeLimit   time.Duration
+	Workers     int              // Max workers for search (0 = NumCPU)
+	AutoDelayMs int              // Delay between moves in auto mode
+	AutoStop    bool             // Flag to stop AI vs AI
+	MoveTimes   []time.Duration  // Thinking time per move (parallel to History)
+	StartTime   time.Time        // When the game began (PGN Date tag)
+	AIvsAI      bool             // Set once chess-auto plays a move (PGN player tags)
+	Clock       [2]time.Duration // Remaining time per color (time control only)
+	ClockBase   time.Duration    // Starting time per side (0 = untimed)
+	Increment   time.Duration    // Added to the mover's clock after each move
+	Ponder      bool             // Think on the human's time
+	Forward     []Move           // Plies stepped back over, next first (review mode)
+	Prep        *PrepSession     // Opening line being practised (nil outside prep mode)
+	Buffer      string           // Buffer the game is shown in ("*chess*", "*chess-N*")
+	turnStart   time.Time        // When the side to move started thinking
+
+	ponder       *ponderSearch   // Running ponder search (nil when none)
+	forwardTimes []time.Duration // Thinking times of Forward
+}
+
+// defaultGameBuffer holds the game chess starts without a prefix argument
+const defaultGameBuffer = "*chess*"
+
+// games maps buffer names to the game shown in each (buffer name -> *Game)
+var games sync.Map
+
+// currentGame is the game commands act on: the current buffer's game, or
+// the last one used when the current buffer has none
 var currentGame *Game
 
+// currentBufferName returns the name of the buffer point is in
+func currentBufferName() string {
+	name := C.api_buffer_name(C.api_current_buffer())
+	if name == nil {
+		return ""
+	}
+	return C.GoString(name)
+}
+
+// selectGame points currentGame at the current buffer's game, if it has one
+func selectGame() {
+	if g, ok := games.Load(currentBufferName()); ok {
+		currentGame = g.(*Game)
+	}
+}
+
+// gameBuffer returns the buffer of the game commands act on
+func gameBuffer() string {
+	if currentGame != nil && currentGame.Buffer != "" {
+		return currentGame.Buffer
+	}
+	return defaultGameBuffer
+}
+
+// setCurrentGame makes g the game commands act on, replacing the game in
+// its buffer (by default the buffer of the game it replaces)
+func setCurrentGame(g *Game) {
+	if g.Buffer == "" {
+		g.Buffer = gameBuffer()
+	}
+	if old, ok := games.Load(g.Buffer); ok && old.(*Game) != g {
+		old.(*Game).AutoStop = true
+		stopPonder(old.(*Game))
+	}
+	games.Store(g.Buffer, g)
+	currentGame = g
+}
+
+// editBoard is the position in the board editor (nil when not editing)
+var editBoard *Board
+
 // configInt reads an integer config value from TOML
 func configInt(key string, defaultVal int) int {
 	ckey := C.CString(key)
@@ -81,11 +184,28 @@ func configInt(key string, defaultVal int) int {
 	return int(C.api_config_int(ckey, C.int(defaultVal)))
 }
 
+// configBool reads a boolean config value from TOML
+func configBool(key string, defaultVal bool) bool {
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	return bool(C.api_config_bool(ckey, C._Bool(defaultVal)))
+}
+
+// configString reads a string config value from TOML
+func configString(key, defaultVal string) string {
+	ckey := C.CString(key)
+	cdef := C.CString(defaultVal)
+	defer C.free(unsafe.Pointer(ckey))
+	defer C.free(unsafe.Pointer(cdef))
+	return C.GoString(C.api_config_string(ckey, cdef))
+}
+
 // NewGame creates a new game with config-driven defaults
 func NewGame() *Game {
 	board := NewBoard()
-	return &Game{
+	g := &Game{
 		Board:       board,
+		StartBoard:  board.Copy(),
 		History:     make([]Move, 0, 100),
 		FENHistory:  []string{board.ToFEN()}, // Track initial position
 		Flipped:     false,
@@ -94,38 +214,125 @@ func NewGame() *Game {
 		Workers:     configInt("workers", 2),      // Default 2 to save CPU
 		AutoDelayMs: configInt("auto_delay_ms", 500),
 		AutoStop:    false,
+		Ponder:      configBool("ponder", false),
+		StartTime:   time.Now(),
+		turnStart:   time.Now(),
+	}
+
+	if tc := configString("time_control", ""); tc != "" {
+		if base, inc, err := ParseTimeControl(tc); err == nil {
+			g.ClockBase, g.Increment = base, inc
+			g.Clock = [2]time.Duration{base, base}
+		}
+	}
+	return g
+}
+
+// recordMove appends a move made on the board to the game history
+func (g *Game) recordMove(m Move) {
+	g.History = append(g.History, m)
+	var elapsed time.Duration
+	if !g.turnStart.IsZero() {
+		elapsed = time.Since(g.turnStart)
 	}
+	g.MoveTimes = append(g.MoveTimes, elapsed)
+	g.LastMove = m
+	g.turnStart = time.Now()
+
+	// The board has already switched sides: charge the player who moved
+	if g.ClockBase > 0 {
+		mover := g.Board.SideToMove.Opponent()
+		g.Clock[mover] -= elapsed
+		if g.Clock[mover] > 0 {
+			g.Clock[mover] += g.Increment
+		}
+	}
+}
+
+// moveTime returns the AI's budget for the side to move (0 = untimed)
+func (g *Game) moveTime() time.Duration {
+	if g.ClockBase <= 0 {
+		return 0
+	}
+	return calculateMoveTime(g.Clock[g.Board.SideToMove], g.Increment, 0)
+}
+
+// flagFallen reports the side whose clock has run out, if any
+func (g *Game) flagFallen() (Color, bool) {
+	if g.ClockBase > 0 {
+		for _, c := range []Color{White, Black} {
+			if g.Clock[c] <= 0 {
+				return c, true
+			}
+		}
+	}
+	return White, false
 }
 
-// makeAIMove has the AI search and play (with opening book integration)
-func (g *Game) makeAIMove() (Move, SearchResult) {
+// errNoAIMove is returned by makeAIMove when the search comes back without
+// a move in a position that has legal moves
+var errNoAIMove = errors.New("search returned no move")
+
+// makeAIMove has the AI search and play (with opening book integration).
+// With no legal moves it returns the null move; a search that finds none
+// although there are legal moves is an error, and nothing is played.
+func (g *Game) makeAIMove() (Move, SearchResult, error) {
 	workers := g.Workers
 	if workers <= 0 {
 		workers = runtime.NumCPU()
 	}
 
-	// Use hybrid search with book bonus and graduated depth
-	ply := len(g.History) + 1
-	result := SearchWithBook(g.Board, ply, g.SearchDepth, workers)
-
-	if !result.BestMove.IsNull() {
-		// Track FEN before move for learning
+	// In prep mode the AI answers with the line's move
+	if g.Prep.inLine(g) {
+		m, _ := g.Prep.expected(g)
 		g.FENHistory = append(g.FENHistory, g.Board.ToFEN())
+		g.Board.MakeMove(&m)
+		g.recordMove(m)
+		return m, SearchResult{BestMove: m}, nil
+	}
+
+	// Use hybrid search with book bonus and graduated depth. A correctly
+	// predicted reply takes over the ponder search; the book and the move
+	// filters apply to it as to any other.
+	ply := len(g.History) + 1
+	search := Search
+	if g.ponderHit() {
+		search = g.ponderResult
+	}
+	result := searchWithBook(search, g.Board, ply, g.SearchDepth, workers, g.moveTime())
+	stopPonder(g)
 
-		g.Board.MakeMove(&result.BestMove)
-		g.History = append(g.History, result.BestMove)
-		g.LastMove = result.BestMove
+	if result.BestMove.IsNull() {
+		if len(g.Board.GenerateLegalMoves()) > 0 {
+			return Move{}, result, errNoAIMove
+		}
+		return Move{}, result, nil
 	}
 
-	return result.BestMove, result
+	// Track FEN before move for learning
+	g.FENHistory = append(g.FENHistory, g.Board.ToFEN())
+
+	g.Board.MakeMove(&result.BestMove)
+	g.recordMove(result.BestMove)
+
+	return result.BestMove, result, nil
 }
 
-// displayGame shows the game in a buffer
+// displayGame shows the current game in its buffer
 func displayGame() {
 	if currentGame == nil {
 		return
 	}
+	currentGame.display()
+}
+
+// display shows g in its buffer
+func (g *Game) display() {
+	showBuffer(g.Buffer, RenderGameState(g, true))
+}
 
+// showBuffer replaces the contents of a chess scratch buffer with output
+func showBuffer(name, output string) {
 	// Clear modified flag on current buffer BEFORE switching (avoids "Discard changes?" prompt)
 	prevBuf := C.api_current_buffer()
 	if prevBuf != nil {
@@ -133,7 +340,7 @@ func displayGame() {
 	}
 
 	// Create/switch to chess buffer
-	bufName := C.CString("*chess*")
+	bufName := C.CString(name)
 	resultBuf := C.api_buffer_create(bufName)
 	C.free(unsafe.Pointer(bufName))
 
@@ -145,8 +352,6 @@ func displayGame() {
 	C.api_buffer_set_unmodified(resultBuf)  // Also clear chess buffer
 	C.api_buffer_clear(resultBuf)
 
-	// Render game state
-	output := RenderGameState(currentGame, true)
 	coutput := C.CString(output)
 	C.api_buffer_insert(coutput, C.size_t(len(output)))
 	C.free(unsafe.Pointer(coutput))
@@ -160,14 +365,23 @@ func displayGame() {
 func chess_init(api unsafe.Pointer) {
 	// Initialize opening book from Lichess master data
 	InitOpeningBook()
+
+	SetSyzygyPath(configString("syzygy_path", ""))
+	SetNullMoveDefaults(configInt("null_move_reduction", NullMoveR), configBool("null_move_verification", false))
 }
 
 //export go_chess_new
 func go_chess_new(f, n C.int) C.int {
-	currentGame = NewGame()
+	// A prefix argument N starts the game in its own buffer *chess-N*
+	g := NewGame()
+	g.Buffer = defaultGameBuffer
+	if f != 0 {
+		g.Buffer = fmt.Sprintf("*chess-%d*", int(n))
+	}
+	setCurrentGame(g)
 	displayGame()
 
-	msg := C.CString("New game started. You are White. Use chess-move to play.")
+	msg := C.CString(fmt.Sprintf("New game started in %s. You are White. Use chess-move to play.", g.Buffer))
 	C.api_message(msg)
 	C.free(unsafe.Pointer(msg))
 
@@ -176,18 +390,41 @@ func go_chess_new(f, n C.int) C.int {
 
 //export go_chess_move
 func go_chess_move(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
 	// Check if game is over
-	if currentGame.Board.IsCheckmate() || currentGame.Board.IsDraw() {
+	_, flagged := currentGame.flagFallen()
+	if flagged || currentGame.Board.IsCheckmate() || currentGame.Board.IsDraw() {
 		msg := C.CString("Game is over. Use chess to start a new game.")
 		C.api_message(msg)
 		C.free(unsafe.Pointer(msg))
 		return 0
 	}
 
+	// Resume play from the reviewed position; with Black to move the AI
+	// plays first
+	if currentGame.reviewing() {
+		currentGame.resumeFromReview()
+		if currentGame.Board.SideToMove == Black {
+			aiMove, result, err := currentGame.makeAIMove()
+			displayGame()
+			if err != nil {
+				msg := C.CString(fmt.Sprintf("Resumed play. AI: %v", err))
+				C.api_message(msg)
+				C.free(unsafe.Pointer(msg))
+				return 0
+			}
+			startPonder(currentGame)
+			msg := C.CString(fmt.Sprintf("Resumed play. AI plays: %s | %s", aiMove.String(), RenderSearchInfo(result)))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 1
+		}
+	}
+
 	// Check if it's human's turn (human plays White)
 	if currentGame.Board.SideToMove == Black {
 		msg := C.CString("It's the AI's turn. Please wait...")
@@ -221,15 +458,36 @@ func go_chess_move(f, n C.int) C.int {
 		return 0
 	}
 
+	// Prep mode: the move has to follow the line
+	prepNote := ""
+	if currentGame.Prep.inLine(currentGame) {
+		if want, _ := currentGame.Prep.expected(currentGame); !movesEqual(want, move) {
+			text := fmt.Sprintf("Deviation — expected %s", moveToPGN(currentGame.Board, want))
+			if !configBool("prep_engine_on_deviation", false) {
+				msg := C.CString(text + " (chess-show-prep-line shows the line)")
+				C.api_message(msg)
+				C.free(unsafe.Pointer(msg))
+				return 0
+			}
+			currentGame.Prep = nil
+			prepNote = text + "; the engine takes over | "
+		}
+	}
+
 	// Make human move
 	currentGame.Board.MakeMove(&move)
-	currentGame.History = append(currentGame.History, move)
-	currentGame.LastMove = move
+	currentGame.recordMove(move)
 
 	// Display after human move
 	displayGame()
 
 	// Check if game ended
+	if _, flagged := currentGame.flagFallen(); flagged {
+		msg := C.CString("Time! You lost on time.")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 1
+	}
 	if currentGame.Board.IsCheckmate() {
 		msg := C.CString("Checkmate! You win!")
 		C.api_message(msg)
@@ -249,19 +507,37 @@ func go_chess_move(f, n C.int) C.int {
 	C.free(unsafe.Pointer(thinkingMsg))
 	C.api_update_display()
 
-	aiMove, result := currentGame.makeAIMove()
+	inLine := currentGame.Prep.inLine(currentGame)
+	if p := currentGame.Prep; p != nil && p.ply(currentGame) == len(p.Line.Moves) {
+		prepNote = "Correct! Line complete; the engine takes over | "
+	}
+	aiMove, result, err := currentGame.makeAIMove()
 
 	// Display after AI move
 	displayGame()
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("%sAI: %v", prepNote, err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	startPonder(currentGame)
 
 	// Show AI move info
 	info := RenderSearchInfo(result)
-	msg := C.CString(fmt.Sprintf("AI plays: %s | %s", aiMove.String(), info))
+	if inLine {
+		info = currentGame.Prep.status(currentGame)
+	}
+	msg := C.CString(fmt.Sprintf("%sAI plays: %s | %s", prepNote, aiMove.String(), info))
 	C.api_message(msg)
 	C.free(unsafe.Pointer(msg))
 
 	// Check if game ended
-	if currentGame.Board.IsCheckmate() {
+	if _, flagged := currentGame.flagFallen(); flagged {
+		endMsg := C.CString("Time! AI lost on time.")
+		C.api_message(endMsg)
+		C.free(unsafe.Pointer(endMsg))
+	} else if currentGame.Board.IsCheckmate() {
 		endMsg := C.CString("Checkmate! AI wins.")
 		C.api_message(endMsg)
 		C.free(unsafe.Pointer(endMsg))
@@ -280,6 +556,7 @@ func go_chess_move(f, n C.int) C.int {
 
 //export go_chess_undo
 func go_chess_undo(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil || len(currentGame.History) < 2 {
 		msg := C.CString("Nothing to undo")
 		C.api_message(msg)
@@ -287,6 +564,9 @@ func go_chess_undo(f, n C.int) C.int {
 		return 0
 	}
 
+	stopPonder(currentGame)
+	currentGame.resumeFromReview()
+
 	// Undo AI move
 	aiMove := currentGame.History[len(currentGame.History)-1]
 	currentGame.Board.UnmakeMove(&aiMove)
@@ -297,12 +577,13 @@ func go_chess_undo(f, n C.int) C.int {
 	currentGame.Board.UnmakeMove(&humanMove)
 	currentGame.History = currentGame.History[:len(currentGame.History)-1]
 
-	// Update last move
-	if len(currentGame.History) > 0 {
-		currentGame.LastMove = currentGame.History[len(currentGame.History)-1]
-	} else {
-		currentGame.LastMove = Move{}
+	if len(currentGame.MoveTimes) > len(currentGame.History) {
+		currentGame.MoveTimes = currentGame.MoveTimes[:len(currentGame.History)]
 	}
+	currentGame.turnStart = time.Now()
+
+	// Update last move
+	currentGame.updateLastMove()
 
 	displayGame()
 
@@ -313,10 +594,97 @@ func go_chess_undo(f, n C.int) C.int {
 	return 1
 }
 
+// navigate applies a history step and reports where the game now stands
+func navigate(step func(g *Game) bool, none string) C.int {
+	selectGame()
+	if currentGame == nil || !step(currentGame) {
+		msg := C.CString(none)
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	displayGame()
+
+	status := fmt.Sprintf("Reviewing move %d of %d. Use chess-move to play from here.",
+		len(currentGame.History), currentGame.totalPlies())
+	if !currentGame.reviewing() {
+		status = "At the current position"
+	}
+	msg := C.CString(status)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// stopForReview halts pondering and AI vs AI play before the board changes
+func stopForReview(g *Game) bool {
+	if g != nil {
+		g.AutoStop = true
+		stopPonder(g)
+	}
+	return g != nil
+}
+
+//export go_chess_back
+func go_chess_back(f, n C.int) C.int {
+	return navigate(func(g *Game) bool {
+		return stopForReview(g) && g.stepBack()
+	}, "At the start of the game")
+}
+
+//export go_chess_forward
+func go_chess_forward(f, n C.int) C.int {
+	return navigate(func(g *Game) bool {
+		return stopForReview(g) && g.stepForward()
+	}, "No moves to step forward to")
+}
+
+//export go_chess_goto_move
+func go_chess_goto_move(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil || currentGame.totalPlies() == 0 {
+		msg := C.CString("No moves to go to")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	// Numeric prefix argument gives the move directly, otherwise prompt
+	ply := int(n)
+	if int(f) == 0 {
+		var plyBuf [8]C.char
+		prompt := C.CString(fmt.Sprintf("Go to move (0-%d, current=%d): ",
+			currentGame.totalPlies(), len(currentGame.History)))
+		if C.api_prompt(prompt, &plyBuf[0], 8) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		if _, err := fmt.Sscanf(C.GoString(&plyBuf[0]), "%d", &ply); err != nil {
+			ply = -1
+		}
+	}
+	if ply < 0 || ply > currentGame.totalPlies() {
+		msg := C.CString(fmt.Sprintf("Invalid move (must be 0-%d)", currentGame.totalPlies()))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	return navigate(func(g *Game) bool {
+		stopForReview(g)
+		g.seekPly(ply)
+		return true
+	}, "")
+}
+
 //export go_chess_depth
 func go_chess_depth(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
 	// If numeric prefix argument given (f != 0), use it directly
@@ -354,10 +722,49 @@ func go_chess_depth(f, n C.int) C.int {
 	return 1
 }
 
+// maxNullMoveReduction bounds chess-null-move-reduction
+const maxNullMoveReduction = 6
+
+//export go_chess_null_move_reduction
+func go_chess_null_move_reduction(f, n C.int) C.int {
+	r, verify := NullMoveDefaults()
+
+	// Numeric prefix argument gives R directly, otherwise prompt
+	if int(f) == 0 || int(n) < 0 || int(n) > maxNullMoveReduction {
+		var rBuf [8]C.char
+		prompt := C.CString(fmt.Sprintf("Null-move reduction (0-%d, 0 disables, current=%d): ", maxNullMoveReduction, r))
+		if C.api_prompt(prompt, &rBuf[0], 8) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		if _, err := fmt.Sscanf(C.GoString(&rBuf[0]), "%d", &r); err != nil || r < 0 || r > maxNullMoveReduction {
+			msg := C.CString(fmt.Sprintf("Invalid reduction (must be 0-%d)", maxNullMoveReduction))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 0
+		}
+	} else {
+		r = int(n)
+	}
+
+	SetNullMoveDefaults(r, verify)
+	text := fmt.Sprintf("Null-move reduction set to %d", r)
+	if r == 0 {
+		text = "Null-move pruning disabled"
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
 //export go_chess_eval
 func go_chess_eval(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
 	eval := Evaluate(currentGame.Board)
@@ -379,10 +786,85 @@ func go_chess_eval(f, n C.int) C.int {
 	return 1
 }
 
+//export go_chess_eval_breakdown
+func go_chess_eval_breakdown(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil {
+		setCurrentGame(NewGame())
+	}
+
+	b := currentGame.Board
+	showBuffer("*chess-eval*", RenderEvalBreakdown(b, EvaluateBreakdown(b)))
+	return 1
+}
+
+//export go_chess_tablebase
+func go_chess_tablebase(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil {
+		setCurrentGame(NewGame())
+	}
+	b := currentGame.Board
+
+	var text string
+	dtz, wdl, err := SyzygyLookup(b)
+	switch {
+	case syzygyCache.Load() == nil:
+		text = "chess-tablebase: set syzygy_path to the directory of your Syzygy files"
+	case b.Castling != 0:
+		text = "chess-tablebase: positions with castling rights are not in the tablebases"
+	case tbPieceCount(b) > syzygyMaxPieces:
+		text = fmt.Sprintf("chess-tablebase: %d pieces; the tablebases cover up to %d", tbPieceCount(b), syzygyMaxPieces)
+	case errors.Is(err, ErrNoTablebase):
+		text = fmt.Sprintf("chess-tablebase: no table for %sv%s", materialCode(b, White), materialCode(b, Black))
+		if lerr := tablebaseError(b); lerr != nil {
+			text = fmt.Sprintf("chess-tablebase: %v", lerr)
+		}
+	case errors.Is(err, ErrNoDTZ):
+		text = fmt.Sprintf("Tablebase: %s for %s (no DTZ table)", WDLName(wdl), colorName(b.SideToMove))
+	default:
+		text = fmt.Sprintf("Tablebase: %s for %s, DTZ %d", WDLName(wdl), colorName(b.SideToMove), dtz)
+	}
+
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
+//export go_chess_tt_stats
+func go_chess_tt_stats(f, n C.int) C.int {
+	hits, misses := ttStats.hits.Load(), ttStats.misses.Load()
+	stores, collisions := ttStats.stores.Load(), ttStats.collisions.Load()
+
+	hitRate, storeRate := 0.0, 0.0
+	if probes := hits + misses; probes > 0 {
+		hitRate = 100 * float64(hits) / float64(probes)
+		storeRate = 100 * float64(stores) / float64(probes)
+	}
+
+	text := fmt.Sprintf("TT: %.1f%% full of %dK | Probes: %d, %.1f%% hits | Stores: %d (%.1f%% of probes), %d collisions",
+		100*ttOccupancy(1000), TTSize/1024, hits+misses, hitRate, stores, storeRate, collisions)
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
+//export go_chess_tt_clear
+func go_chess_tt_clear(f, n C.int) C.int {
+	ttClear()
+	msg := C.CString("Transposition table cleared")
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
 //export go_chess_hint
 func go_chess_hint(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
 	if currentGame.Board.IsCheckmate() || currentGame.Board.IsDraw() {
@@ -411,8 +893,9 @@ func go_chess_hint(f, n C.int) C.int {
 
 //export go_chess_flip
 func go_chess_flip(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
 	currentGame.Flipped = !currentGame.Flipped
@@ -433,8 +916,9 @@ func go_chess_flip(f, n C.int) C.int {
 
 //export go_chess_fen
 func go_chess_fen(f, n C.int) C.int {
+	selectGame()
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
 	// Generate FEN string
@@ -476,23 +960,7 @@ func go_chess_fen(f, n C.int) C.int {
 	}
 
 	// Castling
-	castling := ""
-	if currentGame.Board.Castling&CastleWK != 0 {
-		castling += "K"
-	}
-	if currentGame.Board.Castling&CastleWQ != 0 {
-		castling += "Q"
-	}
-	if currentGame.Board.Castling&CastleBK != 0 {
-		castling += "k"
-	}
-	if currentGame.Board.Castling&CastleBQ != 0 {
-		castling += "q"
-	}
-	if castling == "" {
-		castling = "-"
-	}
-	sb.WriteString(castling)
+	sb.WriteString(currentGame.Board.castlingFEN())
 
 	// En passant
 	if currentGame.Board.EnPassant == NoSquare {
@@ -515,12 +983,25 @@ func go_chess_fen(f, n C.int) C.int {
 	return 1
 }
 
-// autoGameLoop runs the AI vs AI game in the background
-func autoGameLoop(g *Game, delayMs int) {
+// autoGameLoop runs the AI vs AI game in the background. It returns the
+// result and the number of moves played, or ResultUnknown when stopped or
+// when the search fails to find a move.
+func autoGameLoop(g *Game, delayMs int) (GameResult, int) {
 	moveNum := 0
 
 	for !g.AutoStop {
 		// Check if game is over
+		if loser, flagged := g.flagFallen(); flagged {
+			endMsg := C.CString(fmt.Sprintf("Time! %s wins on time after %d moves.",
+				colorName(loser.Opponent()), moveNum))
+			C.api_message(endMsg)
+			C.free(unsafe.Pointer(endMsg))
+			g.display()
+			if loser == White {
+				return ResultBlackWins, moveNum
+			}
+			return ResultWhiteWins, moveNum
+		}
 		if g.Board.IsCheckmate() {
 			var winner string
 			var result GameResult
@@ -540,8 +1021,8 @@ func autoGameLoop(g *Game, delayMs int) {
 			endMsg := C.CString(fmt.Sprintf("Checkmate! %s wins after %d moves.", winner, moveNum))
 			C.api_message(endMsg)
 			C.free(unsafe.Pointer(endMsg))
-			displayGame()
-			return
+			g.display()
+			return result, moveNum
 		}
 		if g.Board.IsDraw() {
 			// Learn from this game (draw)
@@ -552,8 +1033,8 @@ func autoGameLoop(g *Game, delayMs int) {
 			endMsg := C.CString(fmt.Sprintf("Draw after %d moves.", moveNum))
 			C.api_message(endMsg)
 			C.free(unsafe.Pointer(endMsg))
-			displayGame()
-			return
+			g.display()
+			return ResultDraw, moveNum
 		}
 
 		// Show thinking message
@@ -566,12 +1047,20 @@ func autoGameLoop(g *Game, delayMs int) {
 		C.free(unsafe.Pointer(thinkMsg))
 		C.api_update_display()
 
-		// Make AI move
-		aiMove, result := g.makeAIMove()
+		// Make AI move (the display delay doesn't count against the clock)
+		g.turnStart = time.Now()
+		aiMove, result, err := g.makeAIMove()
+		if err != nil {
+			errMsg := C.CString(fmt.Sprintf("%s: %v; game abandoned after %d moves.", side, err, moveNum))
+			C.api_message(errMsg)
+			C.free(unsafe.Pointer(errMsg))
+			g.display()
+			return ResultUnknown, moveNum
+		}
 		moveNum++
 
 		// Display the board
-		displayGame()
+		g.display()
 
 		// Show move info
 		info := RenderSearchInfo(result)
@@ -588,17 +1077,73 @@ func autoGameLoop(g *Game, delayMs int) {
 	stopMsg := C.CString(fmt.Sprintf("AI vs AI stopped after %d moves.", moveNum))
 	C.api_message(stopMsg)
 	C.free(unsafe.Pointer(stopMsg))
+	return ResultUnknown, moveNum
+}
+
+// Tournament state: one tournament runs at a time
+var (
+	tournamentRunning bool
+	tournamentStop    bool
+)
+
+// tournamentLoop plays the given number of self-play games in buffer in
+// the background with exploration and asymmetric contempt, then shows the
+// summary
+func tournamentLoop(games, delayMs int, buffer string) {
+	prevWhite, prevBlack, prevGlobal, prevTraining := globalContemptWhite, globalContemptBlack, globalContempt, trainingMode
+	SetAsymmetricContempt(tournamentWhiteDrawValue, tournamentBlackDrawValue)
+	SetTrainingMode(true)
+	defer func() {
+		globalContemptWhite, globalContemptBlack, globalContempt = prevWhite, prevBlack, prevGlobal
+		SetTrainingMode(prevTraining)
+		tournamentRunning = false
+	}()
+
+	rec := &TournamentRecord{Started: time.Now()}
+	stopped := false
+	for i := 0; i < games; i++ {
+		if tournamentStop {
+			stopped = true
+			break
+		}
+
+		g := NewGame()
+		g.AIvsAI = true
+		g.Buffer = buffer
+		setCurrentGame(g)
+		result, plies := autoGameLoop(g, delayMs)
+		if result == ResultUnknown {
+			stopped = true
+			break
+		}
+		rec.Add(result, plies)
+
+		msg := C.CString(fmt.Sprintf("Tournament: game %d/%d done (White +%d =%d -%d)",
+			i+1, games, rec.Wins, rec.Draws, rec.Losses))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+	}
+
+	showBuffer("*chess-tournament*", RenderTournament(rec, games, stopped))
+	msg := C.CString(fmt.Sprintf("Tournament over: White +%d =%d -%d in %d games",
+		rec.Wins, rec.Draws, rec.Losses, rec.Games()))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
 }
 
 //export go_chess_auto
 func go_chess_auto(f, n C.int) C.int {
+	selectGame()
 	// Start a new game if needed
 	if currentGame == nil {
-		currentGame = NewGame()
+		setCurrentGame(NewGame())
 	}
 
-	// Reset stop flag
+	// Reset stop flag; both sides are the AI, so nothing to ponder
+	stopPonder(currentGame)
+	currentGame.resumeFromReview()
 	currentGame.AutoStop = false
+	currentGame.AIvsAI = true
 
 	// Get delay from config (re-read in case it changed)
 	delayMs := currentGame.AutoDelayMs
@@ -624,32 +1169,997 @@ func go_chess_auto(f, n C.int) C.int {
 	return 1
 }
 
-//export go_chess_stop
-func go_chess_stop(f, n C.int) C.int {
-	if currentGame == nil {
-		msg := C.CString("No game in progress")
+//export go_chess_tournament
+func go_chess_tournament(f, n C.int) C.int {
+	selectGame()
+	if tournamentRunning {
+		msg := C.CString("A tournament is already running (chess-tournament-stop aborts it)")
 		C.api_message(msg)
 		C.free(unsafe.Pointer(msg))
 		return 0
 	}
 
-	currentGame.AutoStop = true
-	msg := C.CString("Stopping AI vs AI...")
-	C.api_message(msg)
-	C.free(unsafe.Pointer(msg))
+	// Numeric prefix argument gives the game count directly, otherwise prompt
+	games := DefaultTournamentGames
+	if int(f) != 0 && int(n) >= 1 {
+		games = int(n)
+	} else {
+		var countBuf [8]C.char
+		prompt := C.CString(fmt.Sprintf("Number of games (default %d): ", DefaultTournamentGames))
+		if C.api_prompt(prompt, &countBuf[0], 8) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		countStr := strings.TrimSpace(C.GoString(&countBuf[0]))
+		if countStr != "" {
+			if _, err := fmt.Sscanf(countStr, "%d", &games); err != nil || games < 1 {
+				msg := C.CString("Invalid number of games")
+				C.api_message(msg)
+				C.free(unsafe.Pointer(msg))
+				return 0
+			}
+		}
+	}
+
+	if currentGame != nil {
+		currentGame.AutoStop = true
+		stopPonder(currentGame)
+	}
+
+	startMsg := C.CString(fmt.Sprintf("Tournament: %d games (use chess-tournament-stop to abort)", games))
+	C.api_message(startMsg)
+	C.free(unsafe.Pointer(startMsg))
+
+	tournamentRunning = true
+	tournamentStop = false
+	go tournamentLoop(games, tournamentDelayMs, gameBuffer())
 
 	return 1
 }
 
-//export go_chess_cleanup
-func go_chess_cleanup() {
-	// Signal any running goroutine to stop
+//export go_chess_tournament_stop
+func go_chess_tournament_stop(f, n C.int) C.int {
+	selectGame()
+	if !tournamentRunning {
+		msg := C.CString("No tournament running")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	tournamentStop = true
 	if currentGame != nil {
 		currentGame.AutoStop = true
 	}
-	// Force exit Go runtime - goroutine may be blocked in search for seconds
-	// Without this, the ext_runner process orphans until the search completes
-	os.Exit(0)
+	msg := C.CString("Stopping tournament...")
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// uciRunning is set while chess-uci-mode serves a UCI session
+var uciRunning bool
+
+// uciPipeLoop serves UCI on the named pipe path, answering on path.out,
+// creating both pipes if needed
+func uciPipeLoop(path string) {
+	defer func() { uciRunning = false }()
+
+	for _, p := range []string{path, path + ".out"} {
+		if err := syscall.Mkfifo(p, 0600); err != nil && err != syscall.EEXIST {
+			msg := C.CString(fmt.Sprintf("UCI: cannot create %s: %v", p, err))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return
+		}
+	}
+
+	// Read-write so that opening does not wait for the GUI to connect
+	out, err := os.OpenFile(path+".out", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	defer out.Close()
+	in, err := os.Open(path) // Blocks until a writer connects
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	runUCI(in, out)
 }
 
-func main() {}
+//export go_chess_uci_mode
+func go_chess_uci_mode(f, n C.int) C.int {
+	if uciRunning {
+		msg := C.CString("UCI mode is already running")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	var pathBuf [256]C.char
+	prompt := C.CString("UCI pipe: ")
+	if C.api_prompt(prompt, &pathBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(prompt))
+
+	// The editor owns stdin and stdout: a standalone engine on them is
+	// the uci_main build
+	path := strings.TrimSpace(C.GoString(&pathBuf[0]))
+	if path == "" {
+		msg := C.CString("chess-uci-mode needs a pipe path; build with -tags uci_main for stdin/stdout")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	uciRunning = true
+	go uciPipeLoop(path)
+	msg := C.CString(fmt.Sprintf("UCI mode: commands to %s, replies on %s.out", path, path))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_stop
+func go_chess_stop(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil {
+		msg := C.CString("No game in progress")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	currentGame.AutoStop = true
+	msg := C.CString("Stopping AI vs AI...")
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// startGameFrom starts a new game from board (a validated position) and
+// lets the AI reply if Black is to move. what names how the position was
+// made ("Position loaded").
+func startGameFrom(board *Board, what string) C.int {
+	game := NewGame()
+	game.Board = board
+	game.StartBoard = board.Copy()
+	game.FENHistory = []string{board.ToFEN()}
+	setCurrentGame(game)
+	displayGame()
+
+	if board.IsCheckmate() || board.IsDraw() {
+		msg := C.CString(fmt.Sprintf("%s, but the game is already over.", what))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 1
+	}
+
+	// Human plays White; let the AI reply if Black is to move
+	if board.SideToMove == Black {
+		aiMove, result, err := currentGame.makeAIMove()
+		displayGame()
+		if err != nil {
+			msg := C.CString(fmt.Sprintf("%s. AI: %v", what, err))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 0
+		}
+		startPonder(currentGame)
+		msg := C.CString(fmt.Sprintf("AI plays: %s | %s", aiMove.String(), RenderSearchInfo(result)))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 1
+	}
+
+	msg := C.CString(fmt.Sprintf("%s. You are White. Use chess-move to play.", what))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_setup
+func go_chess_setup(f, n C.int) C.int {
+	selectGame()
+	if currentGame != nil {
+		currentGame.AutoStop = true
+		stopPonder(currentGame)
+	}
+
+	board := NewEmptyBoard()
+	status := "Empty board. Place pieces, then type done."
+
+	for {
+		side := "White"
+		if board.SideToMove == Black {
+			side = "Black"
+		}
+		showBuffer(gameBuffer(), RenderBoard(board, false, Move{}, true) +
+			fmt.Sprintf("\nSetup: %s to move\n%s\n\n", side, status) +
+			"  we4   place white pawn on e4\n" +
+			"  bKe8  place black king on e8 (K Q R B N P)\n" +
+			"  white/black  set side to move\n" +
+			"  clear  empty board    start  initial position\n" +
+			"  done   validate and start the game\n")
+
+		var cmdBuf [32]C.char
+		prompt := C.CString("Setup (we4, bKe8, clear, start, done): ")
+		if C.api_prompt(prompt, &cmdBuf[0], 32) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			msg := C.CString("Setup cancelled")
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		cmd := strings.TrimSpace(C.GoString(&cmdBuf[0]))
+		if cmd == "" {
+			continue
+		}
+
+		done, err := ApplySetupCommand(board, cmd)
+		if err != nil {
+			status = fmt.Sprintf("Error: %v", err)
+			continue
+		}
+		if !done {
+			status = fmt.Sprintf("OK: %s", cmd)
+			continue
+		}
+
+		if err := ValidatePosition(board); err != nil {
+			status = fmt.Sprintf("Invalid position: %v", err)
+			continue
+		}
+		break
+	}
+
+	return startGameFrom(board, "Position set up")
+}
+
+// editPrompt asks for one editor input; ok is false when cancelled
+func editPrompt(text string) (string, bool) {
+	var buf [16]C.char
+	prompt := C.CString(text)
+	defer C.free(unsafe.Pointer(prompt))
+	if C.api_prompt(prompt, &buf[0], 16) < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(C.GoString(&buf[0])), true
+}
+
+// showEditor renders the board editor with the position's validity
+func showEditor() {
+	status, _ := EditStatus(editBoard)
+	showBuffer(gameBuffer(), RenderEditBoard(editBoard, status))
+}
+
+// requireEditMode reports whether the board editor is active
+func requireEditMode() bool {
+	if editBoard == nil {
+		msg := C.CString("Not editing a position (use chess-edit)")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return false
+	}
+	return true
+}
+
+//export go_chess_edit
+func go_chess_edit(f, n C.int) C.int {
+	selectGame()
+	// Start from the current position the first time
+	if editBoard == nil {
+		if currentGame != nil {
+			currentGame.AutoStop = true
+			stopPonder(currentGame)
+			editBoard = currentGame.Board.Copy()
+		} else {
+			editBoard = NewBoard()
+		}
+		editBoard.History = nil
+	}
+
+	// Place pieces until the prompt is cancelled or left empty
+	for {
+		showEditor()
+		pc, ok := editPrompt("Piece (PNBRQK, pnbrqk, . to clear; empty to stop): ")
+		if !ok || pc == "" {
+			break
+		}
+		sq, ok := editPrompt(fmt.Sprintf("Square for %s: ", pc))
+		if !ok {
+			break
+		}
+		if err := EditPlace(editBoard, pc, sq); err != nil {
+			showEditor()
+			msg := C.CString(fmt.Sprintf("Error: %v", err))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 0
+		}
+	}
+
+	msg := C.CString("Editing. chess-edit-done plays from this position.")
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_set_side
+func go_chess_set_side(f, n C.int) C.int {
+	selectGame()
+	if !requireEditMode() {
+		return 0
+	}
+
+	side, ok := editPrompt("Side to move (w/b): ")
+	if !ok {
+		return 0
+	}
+	switch strings.ToLower(side) {
+	case "w", "white":
+		editBoard.SideToMove = White
+	case "b", "black":
+		editBoard.SideToMove = Black
+	default:
+		msg := C.CString("Side must be w or b")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	editBoard.EnPassant = NoSquare
+	showEditor()
+
+	return 1
+}
+
+//export go_chess_set_castling
+func go_chess_set_castling(f, n C.int) C.int {
+	selectGame()
+	if !requireEditMode() {
+		return 0
+	}
+
+	flags, ok := editPrompt(fmt.Sprintf("Castling rights (KQkq, HAha or -, current=%s): ", editBoard.castlingFEN()))
+	if !ok {
+		return 0
+	}
+	err := EditCastling(editBoard, flags)
+	showEditor()
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("Error: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	return 1
+}
+
+//export go_chess_edit_done
+func go_chess_edit_done(f, n C.int) C.int {
+	selectGame()
+	if !requireEditMode() {
+		return 0
+	}
+
+	if _, err := EditStatus(editBoard); err != nil {
+		showEditor()
+		msg := C.CString(fmt.Sprintf("Invalid position: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	board := editBoard
+	editBoard = nil
+	if currentGame != nil {
+		currentGame.AutoStop = true
+		stopPonder(currentGame)
+	}
+	return startGameFrom(board, "Position edited")
+}
+
+//export go_chess_load_fen
+func go_chess_load_fen(f, n C.int) C.int {
+	selectGame()
+	var fenBuf [128]C.char
+	prompt := C.CString("FEN: ")
+	if C.api_prompt(prompt, &fenBuf[0], 128) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(prompt))
+
+	board, err := ParseFEN(C.GoString(&fenBuf[0]))
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("Invalid FEN: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	if currentGame != nil {
+		currentGame.AutoStop = true
+		stopPonder(currentGame)
+	}
+	return startGameFrom(board, "Position loaded")
+}
+
+//export go_chess_960
+func go_chess_960(f, n C.int) C.int {
+	selectGame()
+	// Numeric prefix argument gives the position directly, otherwise prompt
+	pos := int(n)
+	if int(f) == 0 {
+		var posBuf [8]C.char
+		prompt := C.CString(fmt.Sprintf("Chess960 position (0-%d, blank for random): ", Chess960Positions-1))
+		if C.api_prompt(prompt, &posBuf[0], 8) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		posStr := strings.TrimSpace(C.GoString(&posBuf[0]))
+		if posStr == "" {
+			pos = rand.Intn(Chess960Positions)
+		} else if _, err := fmt.Sscanf(posStr, "%d", &pos); err != nil {
+			pos = -1
+		}
+	}
+	if pos < 0 || pos >= Chess960Positions {
+		msg := C.CString(fmt.Sprintf("Invalid position (must be 0-%d)", Chess960Positions-1))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	if currentGame != nil {
+		currentGame.AutoStop = true
+		stopPonder(currentGame)
+	}
+	board := NewChess960Board(pos)
+	game := NewGame()
+	game.Board = board
+	game.StartBoard = board.Copy()
+	game.FENHistory = []string{board.ToFEN()}
+	setCurrentGame(game)
+	displayGame()
+
+	msg := C.CString(fmt.Sprintf("Chess960 position %d. You are White; castle by moving the king onto its rook (e.g. e1h1).", pos))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_report
+func go_chess_report(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil || len(currentGame.History) == 0 {
+		msg := C.CString("No game to report on")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	thinkingMsg := C.CString(fmt.Sprintf("Analyzing %d moves at depth %d...", len(currentGame.History), ReportDepth))
+	C.api_message(thinkingMsg)
+	C.free(unsafe.Pointer(thinkingMsg))
+	C.api_update_display()
+
+	report := AnalyzeGame(currentGame, ReportDepth)
+	showBuffer("*chess-report*", RenderReport(report, currentGame.StartBoard))
+
+	msg := C.CString(fmt.Sprintf("Report: %s | avg loss White %.0f cp, Black %.0f cp",
+		report.Result, report.AvgLoss[White], report.AvgLoss[Black]))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_pgn
+func go_chess_pgn(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil {
+		msg := C.CString("No game to export")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	var pathBuf [256]C.char
+	prompt := C.CString("Save PGN to (empty for buffer only): ")
+	if C.api_prompt(prompt, &pathBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(prompt))
+	path := strings.TrimSpace(C.GoString(&pathBuf[0]))
+
+	pgn := FormatPGN(currentGame)
+	showBuffer("*lsp-pgn*", pgn)
+
+	text := fmt.Sprintf("PGN: %d moves", len(currentGame.History))
+	if path != "" {
+		if err := currentGame.SavePGN(path); err != nil {
+			text = fmt.Sprintf("PGN save failed: %v", err)
+		} else {
+			text += " saved to " + path
+		}
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_load_pgn
+func go_chess_load_pgn(f, n C.int) C.int {
+	selectGame()
+	var pathBuf [256]C.char
+	prompt := C.CString("PGN file: ")
+	if C.api_prompt(prompt, &pathBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(prompt))
+
+	path := strings.TrimSpace(C.GoString(&pathBuf[0]))
+	if path == "" {
+		return 0
+	}
+
+	data, err := os.ReadFile(expandHome(path))
+	if err == nil {
+		var game *Game
+		if game, err = LoadPGN(string(data)); err == nil {
+			if currentGame != nil {
+				currentGame.AutoStop = true
+				stopPonder(currentGame)
+			}
+			setCurrentGame(game)
+		}
+	}
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("PGN load failed: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	displayGame()
+
+	result, reason := gameResult(currentGame.Board)
+	status := fmt.Sprintf("%s to move", colorName(currentGame.Board.SideToMove))
+	if result != "*" {
+		status = fmt.Sprintf("%s (%s)", result, reason)
+	}
+	msg := C.CString(fmt.Sprintf("Loaded %d moves from %s | %s",
+		len(currentGame.History), filepath.Base(path), status))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// startPrep starts a game in prep mode after ply moves of line
+func startPrep(line *PrepLine, ply int, what string) C.int {
+	if currentGame != nil {
+		currentGame.AutoStop = true
+		stopPonder(currentGame)
+	}
+
+	board := line.boardAt(ply)
+	game := NewGame()
+	game.Board = board
+	game.StartBoard = board.Copy()
+	game.FENHistory = []string{board.ToFEN()}
+	game.Prep = &PrepSession{Line: line, Offset: ply}
+	setCurrentGame(game)
+
+	// Human plays White; the line supplies Black's first move
+	if board.SideToMove == Black {
+		if aiMove, _, err := game.makeAIMove(); err != nil {
+			what += fmt.Sprintf(" | AI: %v", err)
+		} else {
+			what += fmt.Sprintf(" | AI plays: %s", aiMove.String())
+		}
+	}
+	displayGame()
+
+	msg := C.CString(what + ". Play the line with chess-move.")
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
+//export go_chess_prep_mode
+func go_chess_prep_mode(f, n C.int) C.int {
+	selectGame()
+	var pathBuf [256]C.char
+	prompt := C.CString("Prep line (PGN or FEN file): ")
+	if C.api_prompt(prompt, &pathBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(prompt))
+
+	path := strings.TrimSpace(C.GoString(&pathBuf[0]))
+	if path == "" {
+		return 0
+	}
+
+	data, err := os.ReadFile(expandHome(path))
+	var line *PrepLine
+	if err == nil {
+		line, err = ParsePrepLine(filepath.Base(path), string(data))
+	}
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("Prep load failed: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	prepLine = line
+	return startPrep(line, 0, fmt.Sprintf("Prep: %s, %d plies", line.Name, len(line.Moves)))
+}
+
+//export go_chess_show_prep_line
+func go_chess_show_prep_line(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil || currentGame.Prep == nil {
+		msg := C.CString("Not in prep mode (chess-prep-mode loads a line)")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	showBuffer("*chess-prep*", RenderPrepLine(currentGame.Prep, currentGame))
+	return 1
+}
+
+//export go_chess_drill
+func go_chess_drill(f, n C.int) C.int {
+	selectGame()
+	line := prepLine
+	if currentGame != nil && currentGame.Prep != nil {
+		line = currentGame.Prep.Line
+	}
+	if line == nil {
+		msg := C.CString("No prep line loaded (use chess-prep-mode)")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	ply := line.drillPly()
+	if ply < 0 {
+		msg := C.CString(fmt.Sprintf("%s has no White move to drill", line.Name))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	return startPrep(line, ply, fmt.Sprintf("Drill: %s from move %d", line.Name, line.boardAt(ply).FullMoves))
+}
+
+//export go_chess_import_pgn
+func go_chess_import_pgn(f, n C.int) C.int {
+	var pathBuf [256]C.char
+	prompt := C.CString("Import PGN games into the book: ")
+	if C.api_prompt(prompt, &pathBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(prompt))
+
+	path := strings.TrimSpace(C.GoString(&pathBuf[0]))
+	if path == "" {
+		return 0
+	}
+
+	before, _ := GetLearningStats()
+	imported, err := ImportPGNGames(path)
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("PGN import failed: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	after, _ := GetLearningStats()
+
+	msg := C.CString(fmt.Sprintf("Imported %d games from %s | %d moves learned",
+		imported, filepath.Base(path), after-before))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
+//export go_chess_multi_pv
+func go_chess_multi_pv(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil {
+		setCurrentGame(NewGame())
+	}
+
+	if currentGame.Board.IsCheckmate() || currentGame.Board.IsDraw() {
+		msg := C.CString("Game is over")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	// Numeric prefix argument gives N directly, otherwise prompt
+	pvCount := DefaultMultiPV
+	if int(f) != 0 && int(n) >= 1 {
+		pvCount = int(n)
+	} else {
+		var countBuf [8]C.char
+		prompt := C.CString(fmt.Sprintf("Number of lines (default %d): ", DefaultMultiPV))
+		if C.api_prompt(prompt, &countBuf[0], 8) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		countStr := strings.TrimSpace(C.GoString(&countBuf[0]))
+		if countStr != "" {
+			if _, err := fmt.Sscanf(countStr, "%d", &pvCount); err != nil || pvCount < 1 {
+				msg := C.CString("Invalid number of lines")
+				C.api_message(msg)
+				C.free(unsafe.Pointer(msg))
+				return 0
+			}
+		}
+	}
+
+	thinkingMsg := C.CString(fmt.Sprintf("Analyzing top %d moves at depth %d...", pvCount, currentGame.SearchDepth))
+	C.api_message(thinkingMsg)
+	C.free(unsafe.Pointer(thinkingMsg))
+	C.api_update_display()
+
+	opts := DefaultSearchOptions(1)
+	opts.MaxDepth = currentGame.SearchDepth
+	opts.TimeLimit = currentGame.TimeLimit
+	results := MultiPVSearch(currentGame.Board, opts, pvCount)
+	showBuffer("*chess-analysis*", RenderMultiPV(currentGame.Board, results))
+
+	msg := C.CString(fmt.Sprintf("Multi-PV: %d lines", len(results)))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_toggle_ponder
+func go_chess_toggle_ponder(f, n C.int) C.int {
+	selectGame()
+	if currentGame == nil {
+		setCurrentGame(NewGame())
+	}
+
+	currentGame.Ponder = !currentGame.Ponder
+	state := "off"
+	if currentGame.Ponder {
+		state = "on"
+	} else {
+		stopPonder(currentGame)
+	}
+
+	msg := C.CString(fmt.Sprintf("Pondering %s", state))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_perft
+func go_chess_perft(f, n C.int) C.int {
+	selectGame()
+	// Numeric prefix argument gives the depth directly, otherwise prompt
+	depth := int(n)
+	if int(f) == 0 {
+		var depthBuf [8]C.char
+		prompt := C.CString("Perft depth (1-6): ")
+		if C.api_prompt(prompt, &depthBuf[0], 8) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		if _, err := fmt.Sscanf(C.GoString(&depthBuf[0]), "%d", &depth); err != nil {
+			depth = 0
+		}
+	}
+	if depth < 1 || depth > 6 {
+		msg := C.CString("Invalid depth (must be 1-6)")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	board := NewBoard()
+	if currentGame != nil {
+		board = currentGame.Board.Copy()
+	}
+
+	runningMsg := C.CString(fmt.Sprintf("Running perft %d...", depth))
+	C.api_message(runningMsg)
+	C.free(unsafe.Pointer(runningMsg))
+	C.api_update_display()
+
+	start := time.Now()
+	divide := perftDivide(board, depth)
+	elapsed := time.Since(start)
+	showBuffer("*chess-perft*", RenderPerft(board, depth, divide, elapsed))
+
+	var nodes uint64
+	for _, count := range divide {
+		nodes += count
+	}
+	status := ""
+	if expected, ok := perftExpected(board, depth); ok {
+		status = " - PASS"
+		if nodes != expected {
+			status = fmt.Sprintf(" - FAIL (expected %d)", expected)
+		}
+	}
+	msg := C.CString(fmt.Sprintf("Perft %d: %d nodes%s", depth, nodes, status))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// bookBoard returns the position the book commands work on
+func bookBoard() *Board {
+	if currentGame != nil {
+		return currentGame.Board
+	}
+	return NewBoard()
+}
+
+// bookPrompt asks for a UCI book move; ok is false if the prompt was aborted
+func bookPrompt(text string) (string, bool) {
+	var moveBuf [16]C.char
+	prompt := C.CString(text)
+	if C.api_prompt(prompt, &moveBuf[0], 16) < 0 {
+		C.free(unsafe.Pointer(prompt))
+		return "", false
+	}
+	C.free(unsafe.Pointer(prompt))
+	return strings.TrimSpace(C.GoString(&moveBuf[0])), true
+}
+
+// bookEditResult reports the outcome of a book edit
+func bookEditResult(err error, done string) C.int {
+	text := done
+	if err != nil {
+		text = fmt.Sprintf("Book: %v", err)
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+//export go_chess_book_add
+func go_chess_book_add(f, n C.int) C.int {
+	selectGame()
+	uci, ok := bookPrompt("Add book move (e.g. e2e4): ")
+	if !ok {
+		return 0
+	}
+	err := globalBook.AddBookMove(bookBoard(), uci)
+	return bookEditResult(err, fmt.Sprintf("Added %s to the book", uci))
+}
+
+//export go_chess_book_remove
+func go_chess_book_remove(f, n C.int) C.int {
+	selectGame()
+	uci, ok := bookPrompt("Remove book move: ")
+	if !ok {
+		return 0
+	}
+	err := globalBook.RemoveBookMove(bookBoard().ToFEN(), uci)
+	return bookEditResult(err, fmt.Sprintf("Removed %s from the book", uci))
+}
+
+//export go_chess_book_set_weight
+func go_chess_book_set_weight(f, n C.int) C.int {
+	selectGame()
+	uci, ok := bookPrompt("Book move to reweight: ")
+	if !ok {
+		return 0
+	}
+
+	// Numeric prefix argument gives the weight directly, otherwise prompt
+	weight := int(n)
+	if int(f) == 0 {
+		var weightBuf [16]C.char
+		prompt := C.CString(fmt.Sprintf("Master games for %s: ", uci))
+		if C.api_prompt(prompt, &weightBuf[0], 16) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		if _, err := fmt.Sscanf(C.GoString(&weightBuf[0]), "%d", &weight); err != nil {
+			weight = -1
+		}
+	}
+
+	err := globalBook.SetBookMoveWeight(bookBoard().ToFEN(), uci, weight)
+	return bookEditResult(err, fmt.Sprintf("Book weight of %s set to %d", uci, weight))
+}
+
+//export go_chess_book_stats
+func go_chess_book_stats(f, n C.int) C.int {
+	selectGame()
+	fen := bookBoard().ToFEN()
+	pos, ok := globalBook.LookupFEN(fen)
+	if !ok {
+		pos = nil
+	}
+	showBuffer("*chess-book*", RenderBookPosition(fen, pos))
+
+	text := "Position not in book"
+	if pos != nil {
+		text = fmt.Sprintf("Book: %d moves", len(pos.Moves))
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_chess_list_games
+func go_chess_list_games(f, n C.int) C.int {
+	var list []*Game
+	games.Range(func(_, g any) bool {
+		list = append(list, g.(*Game))
+		return true
+	})
+	if len(list) == 0 {
+		msg := C.CString("No chess games (use chess to start one)")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	showBuffer("*chess-games*", RenderGameList(list, currentGame))
+	return 1
+}
+
+//export go_chess_cleanup
+func go_chess_cleanup() {
+	// Signal any running goroutine to stop
+	games.Range(func(_, g any) bool {
+		g.(*Game).AutoStop = true
+		stopPonder(g.(*Game))
+		return true
+	})
+	// Force exit Go runtime - goroutine may be blocked in search for seconds
+	// Without this, the ext_runner process orphans until the search completes
+	os.Exit(0)
+}
@@ -0,0 +1,8 @@
+//go:build !winboard_main && !uci_main
+
+package main
+
+// main is required but unused for c-shared (see xboard.go for the
+// standalone XBoard engine built with -tags winboard_main, and uci.go
+// for the UCI engine built with -tags uci_main)
+func main() {}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Multi-PV analysis: the N best root moves, each with its own search
+
+// DefaultMultiPV is the number of lines shown when none is given
+const DefaultMultiPV = 3
+
+// MultiPVSearch returns up to pvCount results ordered best-first for the
+// side to move. Each result comes from a fresh root search that excludes
+// the best moves already found, with an equal share of what is left of
+// opts.TimeLimit. Scores are from White's perspective, like Search.
+func MultiPVSearch(b *Board, opts SearchOptions, pvCount int) []SearchResult {
+	if pvCount < 1 {
+		pvCount = 1
+	}
+
+	start := time.Now()
+	forbiddenRoots := make(map[string]bool)
+	var results []SearchResult
+	for len(results) < pvCount {
+		ctx := context.Background()
+		var cancel context.CancelFunc
+		if opts.TimeLimit > 0 {
+			share := (opts.TimeLimit - time.Since(start)) / time.Duration(pvCount-len(results))
+			ctx, cancel = context.WithTimeout(ctx, share)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+		result, ok := searchRootExcluding(newSearchState(ctx, opts), b, opts.MaxDepth, forbiddenRoots)
+		cancel()
+		if !ok {
+			break
+		}
+		results = append(results, result)
+		forbiddenRoots[result.BestMove.String()] = true
+	}
+	return results
+}
+
+// searchRootExcluding runs iterative deepening over the legal root moves
+// not in forbidden. It reports false when no root move is left. When the
+// search is stopped the last completed depth is returned.
+func searchRootExcluding(ss *searchState, b *Board, maxDepth int, forbidden map[string]bool) (SearchResult, bool) {
+	start := time.Now()
+	maximizing := b.SideToMove == White
+
+	var roots []Move
+	for _, m := range b.Copy().GenerateLegalMoves() {
+		if !forbidden[m.String()] {
+			roots = append(roots, m)
+		}
+	}
+	if len(roots) == 0 {
+		return SearchResult{}, false
+	}
+
+	result := SearchResult{BestMove: roots[0]}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		alpha, beta := -Infinity, Infinity
+		bestScore := Infinity
+		if maximizing {
+			bestScore = -Infinity
+		}
+		var bestMove Move
+		completed := true
+
+		for _, m := range roots {
+			if ss.stopped() && depth > 1 {
+				completed = false
+				break
+			}
+			child := b.Copy()
+			mm := m
+			child.MakeMove(&mm)
+			score, _ := sequentialAlphaBeta(ss, child, depth-1, 1, alpha, beta, !maximizing, true)
+			if ss.stopped() && depth > 2 {
+				completed = false // The child was cut short
+				break
+			}
+
+			if (maximizing && score > bestScore) || (!maximizing && score < bestScore) || bestMove.IsNull() {
+				bestScore, bestMove = score, m
+			}
+			if maximizing && score > alpha {
+				alpha = score
+			} else if !maximizing && score < beta {
+				beta = score
+			}
+		}
+		if !completed {
+			break
+		}
+
+		result.BestMove = bestMove
+		result.Score = bestScore
+		result.Depth = depth
+
+		// Search the previous best first at the next depth
+		for i, m := range roots {
+			if movesEqual(m, bestMove) {
+				roots[0], roots[i] = roots[i], roots[0]
+				break
+			}
+		}
+	}
+
+	result.Metrics.ElapsedMs = time.Since(start).Milliseconds()
+	return result, true
+}
+
+// RenderMultiPV formats Multi-PV results for the *chess-analysis* buffer
+func RenderMultiPV(b *Board, results []SearchResult) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Multi-PV analysis (%s to move)\n", colorName(b.SideToMove)))
+	sb.WriteString(fmt.Sprintf("FEN: %s\n\n", b.ToFEN()))
+
+	for i, r := range results {
+		sb.WriteString(fmt.Sprintf("%d. %s  %+.2f  depth=%d\n",
+			i+1, r.BestMove.String(), float64(r.Score)/100.0, r.Depth))
+	}
+	if len(results) == 0 {
+		sb.WriteString("No legal moves.\n")
+	}
+
+	return sb.String()
+}
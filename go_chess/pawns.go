@@ -0,0 +1,113 @@
+package main
+
+import "math/bits"
+
+// Pawn structure: passed, isolated, doubled and backward pawns. The score
+// depends on the pawns alone, so it is cached by pawn hash.
+
+// Pre-computed pawn masks, filled in by init
+var (
+	passedPawnMask    [2][64]uint64 // Enemy pawns that can stop a pawn of each color
+	adjacentFilesMask [8]uint64     // Files either side of each file
+	pawnFileMask      [8]uint64     // Every square of each file
+)
+
+func init() {
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			pawnFileMask[f] |= squareBit(r, f)
+			adjacentFilesMask[f] |= squareBit(r, f-1) | squareBit(r, f+1)
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		r, f := sq/8, sq%8
+		for df := -1; df <= 1; df++ {
+			for ahead := r + 1; ahead < 8; ahead++ {
+				passedPawnMask[White][sq] |= squareBit(ahead, f+df)
+			}
+			for ahead := r - 1; ahead >= 0; ahead-- {
+				passedPawnMask[Black][sq] |= squareBit(ahead, f+df)
+			}
+		}
+	}
+}
+
+// Pawn structure weights (centipawns)
+const (
+	isolatedPawnPenalty = 15
+	doubledPawnPenalty  = 10 // Per extra pawn on a file
+	backwardPawnPenalty = 8
+)
+
+// passedPawnBonus is indexed by the pawn's rank from its own side (1-6)
+var passedPawnBonus = [8]int{0, 5, 10, 20, 35, 60, 100, 0}
+
+// pawnStructureScore returns the pawn structure of side in centipawns:
+// passed pawn bonuses minus isolated, doubled and backward penalties
+func pawnStructureScore(b *Board, side Color) int {
+	b.ensureBitboards()
+	own, enemy := b.BB.WPawns, b.BB.BPawns
+	if side == Black {
+		own, enemy = b.BB.BPawns, b.BB.WPawns
+	}
+
+	score := 0
+	for f := 0; f < 8; f++ {
+		if n := bits.OnesCount64(own & pawnFileMask[f]); n > 1 {
+			score -= doubledPawnPenalty * (n - 1)
+		}
+	}
+
+	for p := own; p != 0; p &= p - 1 {
+		sq := Square(bits.TrailingZeros64(p))
+		r, f := sq.Rank(), sq.File()
+		relRank, stop := r, sq+8
+		// Own pawns level with or behind this one that could advance to support it
+		support := adjacentFilesMask[f] &^ passedPawnMask[White][sq]
+		if side == Black {
+			relRank, stop = 7-r, sq-8
+			support = adjacentFilesMask[f] &^ passedPawnMask[Black][sq]
+		}
+
+		if enemy&passedPawnMask[side][sq] == 0 {
+			score += passedPawnBonus[relRank]
+		}
+
+		switch {
+		case own&adjacentFilesMask[f] == 0:
+			score -= isolatedPawnPenalty
+		case own&support == 0 && pawnAttacks[side][stop]&enemy != 0:
+			// No pawn can come up to defend it and the square ahead is
+			// covered by an enemy pawn
+			score -= backwardPawnPenalty
+		}
+	}
+
+	return score
+}
+
+// PawnTTEntry caches the pawn structure scores of one pawn configuration
+type PawnTTEntry struct {
+	Key   uint32 // Upper half of the pawn hash (for verification)
+	White int16
+	Black int16
+}
+
+// Pawn hash table: 8K entries × 8 bytes = 64KB
+const pawnTTMask = 1<<13 - 1
+
+var pawnTT [1 << 13]PawnTTEntry
+
+// pawnStructure returns pawnStructureScore for White minus Black, using
+// the pawn hash table
+func pawnStructure(b *Board) int {
+	h := b.PawnHash()
+	entry := &pawnTT[h&pawnTTMask]
+	if entry.Key == uint32(h>>32) {
+		return int(entry.White) - int(entry.Black)
+	}
+
+	white, black := pawnStructureScore(b, White), pawnStructureScore(b, Black)
+	*entry = PawnTTEntry{Key: uint32(h >> 32), White: int16(white), Black: int16(black)}
+	return white - black
+}
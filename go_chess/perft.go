@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Perft: move generator verification by counting leaf nodes
+
+// perft counts the leaf nodes of the legal move tree below b to depth
+func perft(b *Board, depth int) uint64 {
+	return perftWith(b, depth, (*Board).GenerateLegalMoves)
+}
+
+// perftWith is perft using the given legal move generator
+func perftWith(b *Board, depth int, legalMoves func(*Board) []Move) uint64 {
+	if depth == 0 {
+		return 1
+	}
+	moves := legalMoves(b)
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, m := range moves {
+		b.MakeMove(&m)
+		nodes += perftWith(b, depth-1, legalMoves)
+		b.UnmakeMove(&m)
+	}
+	return nodes
+}
+
+// perftDivide returns the perft count below each legal root move, keyed by
+// the move in coordinate notation
+func perftDivide(b *Board, depth int) map[string]uint64 {
+	divide := make(map[string]uint64)
+	if depth < 1 {
+		return divide
+	}
+	for _, m := range b.GenerateLegalMoves() {
+		b.MakeMove(&m)
+		divide[m.String()] = perft(b, depth-1)
+		b.UnmakeMove(&m)
+	}
+	return divide
+}
+
+// perftReference holds published node counts (index = depth-1) for
+// standard test positions, keyed by the first four FEN fields
+var perftReference = map[string][]uint64{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -":             {20, 400, 8902, 197281, 4865609, 119060324},
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq -": {48, 2039, 97862, 4085603, 193690690},
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -":                            {14, 191, 2812, 43238, 674624, 11030083},
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq -":     {6, 264, 9467, 422333, 15833292},
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ -":            {44, 1486, 62379, 2103487, 89941194},
+}
+
+// perftExpected returns the reference count for b at depth, if known
+func perftExpected(b *Board, depth int) (uint64, bool) {
+	fields := strings.Fields(b.ToFEN())
+	counts, ok := perftReference[strings.Join(fields[:4], " ")]
+	if !ok || depth < 1 || depth > len(counts) {
+		return 0, false
+	}
+	return counts[depth-1], true
+}
+
+// RenderPerft formats a perft run for the *chess-perft* buffer
+func RenderPerft(b *Board, depth int, divide map[string]uint64, elapsed time.Duration) string {
+	var sb strings.Builder
+
+	var nodes uint64
+	moves := make([]string, 0, len(divide))
+	for m, n := range divide {
+		nodes += n
+		moves = append(moves, m)
+	}
+	sort.Strings(moves)
+
+	sb.WriteString(fmt.Sprintf("Perft depth %d\n", depth))
+	sb.WriteString(fmt.Sprintf("FEN:      %s\n\n", b.ToFEN()))
+	sb.WriteString(fmt.Sprintf("Nodes:    %d\n", nodes))
+	if expected, ok := perftExpected(b, depth); ok {
+		status := "PASS"
+		if nodes != expected {
+			status = "FAIL"
+		}
+		sb.WriteString(fmt.Sprintf("Expected: %d  %s\n", expected, status))
+	} else {
+		sb.WriteString("Expected: (no reference for this position)\n")
+	}
+	sb.WriteString(fmt.Sprintf("Time:     %s", elapsed.Round(time.Millisecond)))
+	if secs := elapsed.Seconds(); secs > 0 {
+		sb.WriteString(fmt.Sprintf(" (%.0f nodes/s)", float64(nodes)/secs))
+	}
+	sb.WriteString("\n\nDivide:\n")
+	for _, m := range moves {
+		sb.WriteString(fmt.Sprintf("  %-6s %d\n", m, divide[m]))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PGN export and import
+
+// pgnEvent is the Event tag written for every exported game
+const pgnEvent = "μEmacs Chess"
+
+// moveToPGN returns the SAN for move m played from b, including check and
+// checkmate suffixes and disambiguation
+func moveToPGN(b *Board, m Move) string {
+	return MoveToSAN(b, m)
+}
+
+// gameResult returns the PGN result ("1-0", "0-1", "1/2-1/2" or "*") for
+// the position b and a short reason
+func gameResult(b *Board) (result, reason string) {
+	switch {
+	case b.IsCheckmate():
+		if b.SideToMove == White {
+			return "0-1", "checkmate"
+		}
+		return "1-0", "checkmate"
+	case b.IsStalemate():
+		return "1/2-1/2", "stalemate"
+	case b.IsDraw():
+		return "1/2-1/2", "50-move rule"
+	default:
+		return "*", "game in progress"
+	}
+}
+
+// pgnPlayers returns the White and Black tag values for g
+func pgnPlayers(g *Game) (white, black string) {
+	if g.AIvsAI {
+		return "go_chess AI", "go_chess AI"
+	}
+	return "Human", "go_chess AI"
+}
+
+// FormatPGN renders g as a PGN game: the tag section followed by the
+// movetext, one move pair per line
+func FormatPGN(g *Game) string {
+	var sb strings.Builder
+
+	result, _ := gameResult(g.Board)
+	white, black := pgnPlayers(g)
+	date := "????.??.??"
+	if !g.StartTime.IsZero() {
+		date = g.StartTime.Format("2006.01.02")
+	}
+
+	start := g.StartBoard
+	if start == nil {
+		start = NewBoard()
+	}
+
+	sb.WriteString(fmt.Sprintf("[Event \"%s\"]\n", pgnEvent))
+	sb.WriteString(fmt.Sprintf("[Date \"%s\"]\n", date))
+	sb.WriteString(fmt.Sprintf("[White \"%s\"]\n", white))
+	sb.WriteString(fmt.Sprintf("[Black \"%s\"]\n", black))
+	sb.WriteString(fmt.Sprintf("[Result \"%s\"]\n", result))
+	if start.Chess960 {
+		sb.WriteString("[Variant \"Chess960\"]\n")
+	}
+	if fen := start.ToFEN(); fen != NewBoard().ToFEN() {
+		sb.WriteString("[SetUp \"1\"]\n")
+		sb.WriteString(fmt.Sprintf("[FEN \"%s\"]\n", fen))
+	}
+	sb.WriteString("\n")
+
+	b := start.Copy()
+	moveNum := b.FullMoves
+	if moveNum < 1 {
+		moveNum = 1
+	}
+
+	for i, m := range g.History {
+		san := moveToPGN(b, m)
+		switch {
+		case b.SideToMove == White:
+			sb.WriteString(fmt.Sprintf("%d. %s", moveNum, san))
+		case i == 0:
+			sb.WriteString(fmt.Sprintf("%d... %s\n", moveNum, san))
+		default:
+			sb.WriteString(fmt.Sprintf(" %s\n", san))
+		}
+		if b.SideToMove == Black {
+			moveNum++
+		}
+		mm := m
+		b.MakeMove(&mm)
+	}
+	if len(g.History) > 0 && b.SideToMove == Black {
+		sb.WriteString(" ")
+	}
+
+	sb.WriteString(result)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// expandHome replaces a leading "~/" with the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// SavePGN writes g as PGN to path, creating parent directories as needed
+func (g *Game) SavePGN(path string) error {
+	path = expandHome(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(FormatPGN(g)), 0644)
+}
+
+// pgnResults are the game termination markers that end the movetext
+var pgnResults = map[string]bool{"1-0": true, "0-1": true, "1/2-1/2": true, "*": true}
+
+// pgnTags returns the tag pairs ([Name "Value"]) of the first game in text
+func pgnTags(text string) map[string]string {
+	tags := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			if line != "" && len(tags) > 0 {
+				break // Movetext reached
+			}
+			continue
+		}
+		name, value, ok := strings.Cut(line[1:len(line)-1], " ")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+		}
+		tags[name] = value
+	}
+	return tags
+}
+
+// parsePGN extracts the SAN move tokens of the first game in text. Tag
+// pairs, comments, NAGs, variations, move numbers and the result are
+// dropped.
+func parsePGN(text string) ([]string, error) {
+	var moves []string
+	var token strings.Builder
+	depth := 0 // Variation nesting
+
+	flush := func() bool {
+		t := token.String()
+		token.Reset()
+		if t == "" || depth > 0 {
+			return false
+		}
+		if pgnResults[t] {
+			return true
+		}
+		// Move numbers, possibly glued to the move ("12.e4", "12...Nf6")
+		if i := strings.LastIndexByte(t, '.'); i >= 0 && strings.Trim(t[:i+1], "0123456789.") == "" {
+			t = t[i+1:]
+		}
+		t = strings.TrimRight(t, "!?")
+		if t != "" {
+			moves = append(moves, t)
+		}
+		return false
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '[' && depth == 0 && token.Len() == 0:
+			// Tag pair: skip to the closing bracket
+			end := strings.IndexByte(text[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated tag pair")
+			}
+			i += end
+		case c == '{':
+			if flush() {
+				return moves, nil
+			}
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end
+		case c == ';':
+			if flush() {
+				return moves, nil
+			}
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			i += end
+		case c == '$':
+			// NAG: $ followed by digits
+			if flush() {
+				return moves, nil
+			}
+			for i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9' {
+				i++
+			}
+		case c == '(':
+			if flush() {
+				return moves, nil
+			}
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("unbalanced ')' in movetext")
+			}
+			token.Reset()
+			depth--
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if flush() {
+				return moves, nil
+			}
+		default:
+			token.WriteByte(c)
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("unterminated variation")
+	}
+	flush()
+	return moves, nil
+}
+
+// sanToMove finds the legal move in b matching san. Check, checkmate and
+// annotation suffixes are ignored, "0-0" is accepted for castling, and
+// redundant disambiguation ("Ng1f3") is allowed; an ambiguous token fails.
+func sanToMove(b *Board, san string) (Move, bool) {
+	s := strings.TrimRight(san, "+#!?")
+	s = strings.ReplaceAll(s, "0", "O")
+
+	legal := b.Copy().GenerateLegalMoves()
+
+	// Castling
+	if s == "O-O" || s == "O-O-O" {
+		for _, m := range legal {
+			if castle, kingside := b.IsCastle(m); castle && kingside == (s == "O-O") {
+				return m, true
+			}
+		}
+		return Move{}, false
+	}
+
+	// Promotion: "e8=Q" or "e8Q"
+	promo := 0
+	if n := len(s); n >= 3 && strings.IndexByte("NBRQ", s[n-1]) >= 0 {
+		promo = strings.Index("..NBRQ", s[n-1:])
+		s = strings.TrimSuffix(s[:n-1], "=")
+	}
+
+	// Piece letter
+	pieceType := 1
+	if len(s) > 0 && strings.IndexByte("NBRQK", s[0]) >= 0 {
+		pieceType = strings.Index(".PNBRQK", s[:1])
+		s = s[1:]
+	}
+
+	s = strings.NewReplacer("x", "", "-", "", ":", "").Replace(s)
+	if len(s) < 2 {
+		return Move{}, false
+	}
+	to, ok := ParseSquare(s[len(s)-2:])
+	if !ok {
+		return Move{}, false
+	}
+	hint := s[:len(s)-2]
+	if len(hint) > 2 {
+		return Move{}, false
+	}
+
+	var found Move
+	matches := 0
+	for _, m := range legal {
+		if m.To != to || b.Squares[m.From].Type() != pieceType {
+			continue
+		}
+		mp := 0
+		if m.Promotion != Empty {
+			mp = m.Promotion.Type()
+		}
+		if mp != promo {
+			continue
+		}
+		if !sanHintMatches(hint, m.From) {
+			continue
+		}
+		found = m
+		matches++
+	}
+	return found, matches == 1
+}
+
+// sanHintMatches reports whether a SAN disambiguation hint (file, rank or
+// both) describes the square from
+func sanHintMatches(hint string, from Square) bool {
+	for i := 0; i < len(hint); i++ {
+		switch c := hint[i]; {
+		case c >= 'a' && c <= 'h':
+			if from.File() != int(c-'a') {
+				return false
+			}
+		case c >= '1' && c <= '8':
+			if from.Rank() != int(c-'1') {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LoadPGN builds a game from the first game in a PGN text, replaying its
+// moves from the [FEN] tag position or the standard start
+func LoadPGN(text string) (*Game, error) {
+	tags := pgnTags(text)
+	start, history, fens, err := replayPGN(text, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	game := NewGame()
+	game.Board = start.Copy()
+	game.StartBoard = start
+	game.FENHistory = fens
+	if t, err := time.Parse("2006.01.02", tags["Date"]); err == nil {
+		game.StartTime = t
+	}
+
+	for _, m := range history {
+		game.Board.MakeMove(&m)
+		game.History = append(game.History, m)
+		game.MoveTimes = append(game.MoveTimes, 0)
+		game.LastMove = m
+	}
+	return game, nil
+}
+
+// replayPGN plays the first game in text from its FEN tag (or the standard
+// position) and returns the start, the moves and the FEN before each move
+// followed by the final FEN
+func replayPGN(text string, tags map[string]string) (start *Board, history []Move, fens []string, err error) {
+	sans, err := parsePGN(text)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	start = NewBoard()
+	if fen, ok := tags["FEN"]; ok {
+		if start, err = ParseFEN(fen); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid FEN tag: %v", err)
+		}
+	}
+
+	b := start.Copy()
+	fens = []string{b.ToFEN()}
+	for i, san := range sans {
+		m, ok := sanToMove(b, san)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("ply %d: illegal or ambiguous move %q", i+1, san)
+		}
+		b.MakeMove(&m)
+		history = append(history, m)
+		fens = append(fens, b.ToFEN())
+	}
+	return start, history, fens, nil
+}
+
+// splitPGNGames splits a PGN collection into its games: a tag pair after
+// movetext starts the next game
+func splitPGNGames(text string) []string {
+	var games []string
+	var cur strings.Builder
+	inMoves := false
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && inMoves {
+			games = append(games, cur.String())
+			cur.Reset()
+			inMoves = false
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "[") {
+			inMoves = true
+		}
+		cur.WriteString(line)
+	}
+	if strings.TrimSpace(cur.String()) != "" {
+		games = append(games, cur.String())
+	}
+	return games
+}
+
+// pgnResultTags maps the Result tags of finished games to GameResults
+var pgnResultTags = map[string]GameResult{
+	"1-0":     ResultWhiteWins,
+	"0-1":     ResultBlackWins,
+	"1/2-1/2": ResultDraw,
+}
+
+// ImportPGNGames teaches the opening book every finished game in the PGN
+// collection at path and returns the number of games learned. Games
+// without a 1-0, 0-1 or 1/2-1/2 Result tag, with unreadable moves, or set
+// up from a FEN tag other than the standard position are skipped: the book
+// only holds lines from the initial position. The book is saved once at
+// the end.
+func ImportPGNGames(path string) (int, error) {
+	if globalBook == nil {
+		return 0, fmt.Errorf("no opening book loaded")
+	}
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return 0, err
+	}
+
+	type learnedGame struct {
+		history []Move
+		fens    []string
+		result  GameResult
+	}
+	var learned []learnedGame
+	initial := NewBoard().ZobristHash()
+	for _, text := range splitPGNGames(string(data)) {
+		tags := pgnTags(text)
+		result, ok := pgnResultTags[tags["Result"]]
+		if !ok {
+			continue
+		}
+		start, history, fens, err := replayPGN(text, tags)
+		if err != nil || len(history) == 0 || start.ZobristHash() != initial {
+			continue
+		}
+		learned = append(learned, learnedGame{history, fens, result})
+	}
+
+	globalBook.mu.Lock()
+	for _, g := range learned {
+		globalBook.learnGame(g.history, g.fens, g.result)
+	}
+	globalBook.buildHashIndex()
+	globalBook.mu.Unlock()
+
+	return len(learned), globalBook.Save()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// Pondering: after the AI moves, it predicts the human reply and searches
+// the resulting position while the human thinks. A correct prediction
+// (ponderhit) reuses that search; a miss cancels it. Either way the shared
+// transposition table keeps what was learned.
+
+// ponderPredictDepth is the depth of the quick search that picks the
+// expected human reply
+const ponderPredictDepth = 3
+
+// ponderSearch is a running ponder: the prediction, then the search of the
+// answer to it, in one goroutine
+type ponderSearch struct {
+	cancel context.CancelFunc
+	ready  chan struct{} // Closed once move is known
+	done   chan struct{} // Closed when the goroutine returns
+	move   Move          // Predicted human reply; null if there is none
+	result SearchResult  // Written by the goroutine before done closes
+}
+
+// startPonder starts predicting the human reply to the current position
+// and searching the AI's answer to it in the background
+func startPonder(g *Game) {
+	stopPonder(g)
+	if !g.Ponder || g.Board.IsCheckmate() || g.Board.IsDraw() {
+		return
+	}
+
+	workers := g.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	opts := DefaultSearchOptions(workers)
+	opts.MaxDepth = GetDepthForPly(len(g.History)+2, g.SearchDepth)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ponderSearch{
+		cancel: cancel,
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	g.ponder = p
+	go p.run(ctx, g.Board.Copy(), opts)
+}
+
+// run predicts the reply to board and searches the answer to it
+func (p *ponderSearch) run(ctx context.Context, board *Board, opts SearchOptions) {
+	defer close(p.done)
+
+	predictOpts := DefaultSearchOptions(1)
+	predictOpts.MaxDepth = ponderPredictDepth
+	predictOpts.Deterministic = true
+	predicted := SearchContext(ctx, board, predictOpts).BestMove
+	if !predicted.IsNull() {
+		mm := predicted
+		board.MakeMove(&mm)
+		if board.IsCheckmate() || board.IsDraw() {
+			predicted = Move{}
+		}
+	}
+	p.move = predicted
+	close(p.ready)
+
+	if !predicted.IsNull() {
+		p.result = SearchContext(ctx, board, opts)
+	}
+}
+
+// stopPonder cancels any ponder search and waits for it to return
+func stopPonder(g *Game) {
+	if g == nil || g.ponder == nil {
+		return
+	}
+	g.ponder.cancel()
+	<-g.ponder.done
+	g.ponder = nil
+}
+
+// ponderHit reports whether the human played the predicted move
+// (g.LastMove), waiting for the prediction if need be. On a miss
+// pondering is stopped.
+func (g *Game) ponderHit() bool {
+	p := g.ponder
+	if p == nil {
+		return false
+	}
+	<-p.ready
+	if p.move.IsNull() || !movesEqual(g.LastMove, p.move) {
+		stopPonder(g)
+		return false
+	}
+	return true
+}
+
+// ponderResult stands in for Search after a ponder hit, b being the
+// position pondered: the ponder search is given until it completes, or
+// opts.TimeLimit in a timed game. If it completed no depth, b is searched
+// afresh.
+func (g *Game) ponderResult(b *Board, opts SearchOptions) SearchResult {
+	p := g.ponder
+	if budget := opts.TimeLimit; budget > 0 {
+		select {
+		case <-p.done:
+		case <-time.After(budget):
+			p.cancel()
+			<-p.done
+		}
+	} else {
+		<-p.done
+	}
+
+	if p.result.BestMove.IsNull() {
+		return Search(b, opts)
+	}
+	return p.result
+}
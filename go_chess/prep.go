@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Opening preparation: practise a prepared line against the AI. The human
+// plays White's moves of the line and the AI answers with Black's, until
+// the line ends or the human deviates from it.
+
+// PrepLine is an opening line loaded by chess-prep-mode
+type PrepLine struct {
+	Name  string
+	Start *Board
+	Moves []Move
+}
+
+// PrepSession follows a game through a PrepLine
+type PrepSession struct {
+	Line   *PrepLine
+	Offset int // Plies of the line played before the game started (drills)
+}
+
+// prepLine is the last line loaded; chess-drill uses it after a deviation
+// has ended prep mode
+var prepLine *PrepLine
+
+// ParsePrepLine reads a line from a PGN game or from a sequence of FEN
+// positions, one per line, each reached from the previous by a legal move
+func ParsePrepLine(name, text string) (*PrepLine, error) {
+	var positions []*Board
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.TrimSpace(l); l == "" {
+			continue
+		}
+		b, err := ParseFEN(l)
+		if err != nil {
+			positions = nil
+			break
+		}
+		positions = append(positions, b)
+	}
+
+	line := &PrepLine{Name: name}
+	if len(positions) >= 2 {
+		line.Start = positions[0]
+		b := line.Start.Copy()
+		for i, next := range positions[1:] {
+			m, ok := moveBetween(b, next)
+			if !ok {
+				return nil, fmt.Errorf("position %d does not follow from position %d by a legal move", i+2, i+1)
+			}
+			b.MakeMove(&m)
+			line.Moves = append(line.Moves, m)
+		}
+	} else {
+		var err error
+		if line.Start, line.Moves, _, err = replayPGN(text, pgnTags(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(line.Moves) == 0 {
+		return nil, fmt.Errorf("no moves in %s", name)
+	}
+	return line, nil
+}
+
+// moveBetween finds the legal move in b that leads to the position next
+func moveBetween(b, next *Board) (Move, bool) {
+	for _, m := range b.GenerateLegalMoves() {
+		after := b.Copy()
+		after.MakeMove(&m)
+		if after.Squares == next.Squares && after.SideToMove == next.SideToMove {
+			return m, true
+		}
+	}
+	return Move{}, false
+}
+
+// boardAt returns the position after the first ply moves of the line
+func (l *PrepLine) boardAt(ply int) *Board {
+	b := l.Start.Copy()
+	for _, m := range l.Moves[:ply] {
+		b.MakeMove(&m)
+	}
+	return b
+}
+
+// drillPly picks a random ply from the middle half of the line with White
+// to move, or -1 if White has no move in the line
+func (l *PrepLine) drillPly() int {
+	var middle, all []int
+	b := l.Start.Copy()
+	for ply, m := range l.Moves {
+		if b.SideToMove == White {
+			all = append(all, ply)
+			if ply >= len(l.Moves)/4 && ply <= 3*len(l.Moves)/4 {
+				middle = append(middle, ply)
+			}
+		}
+		b.MakeMove(&m)
+	}
+
+	if len(middle) == 0 {
+		middle = all // Short line: any White move will do
+	}
+	if len(middle) == 0 {
+		return -1
+	}
+	return middle[rand.Intn(len(middle))]
+}
+
+// ply returns the line index of the next move in g
+func (s *PrepSession) ply(g *Game) int {
+	return s.Offset + len(g.History)
+}
+
+// expected returns the line's next move in g; ok is false once the line
+// is complete
+func (s *PrepSession) expected(g *Game) (Move, bool) {
+	if i := s.ply(g); i < len(s.Line.Moves) {
+		return s.Line.Moves[i], true
+	}
+	return Move{}, false
+}
+
+// inLine reports whether g is in prep mode with moves of the line left
+func (s *PrepSession) inLine(g *Game) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.expected(g)
+	return ok
+}
+
+// status describes the progress through the line after the AI's reply
+func (s *PrepSession) status(g *Game) string {
+	left := len(s.Line.Moves) - s.ply(g)
+	if left <= 0 {
+		return "Correct! Line complete; the engine takes over from here"
+	}
+	return fmt.Sprintf("Correct! %d moves left in the line", left)
+}
+
+// formatLine renders moves played from b as numbered SAN, one move pair
+// per line
+func formatLine(b *Board, moves []Move) []string {
+	var lines []string
+	var cur strings.Builder
+	b = b.Copy()
+	moveNum := max(b.FullMoves, 1)
+
+	for i, m := range moves {
+		san := moveToPGN(b, m)
+		switch {
+		case b.SideToMove == White:
+			cur.WriteString(fmt.Sprintf("%d. %s", moveNum, san))
+		case i == 0:
+			cur.WriteString(fmt.Sprintf("%d... %s", moveNum, san))
+		default:
+			cur.WriteString(" " + san)
+		}
+		if b.SideToMove == Black {
+			lines = append(lines, cur.String())
+			cur.Reset()
+			moveNum++
+		}
+		b.MakeMove(&m)
+	}
+	if cur.Len() > 0 {
+		lines = append(lines, cur.String())
+	}
+	return lines
+}
+
+// RenderPrepLine shows the moves played and still to come (chess-show-prep-line)
+func RenderPrepLine(s *PrepSession, g *Game) string {
+	var sb strings.Builder
+	ply := min(s.ply(g), len(s.Line.Moves))
+
+	sb.WriteString(fmt.Sprintf("Prep line: %s (%d plies)\n\n", s.Line.Name, len(s.Line.Moves)))
+
+	sb.WriteString("Played:\n")
+	played := formatLine(s.Line.Start, s.Line.Moves[:ply])
+	if len(played) == 0 {
+		played = []string{"(none)"}
+	}
+	for _, l := range played {
+		sb.WriteString("  " + l + "\n")
+	}
+
+	remaining := s.Line.Moves[ply:]
+	if len(remaining) == 0 {
+		sb.WriteString("\nThe line is complete.\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("\nRemaining (%d plies):\n", len(remaining)))
+	for _, l := range formatLine(s.Line.boardAt(ply), remaining) {
+		sb.WriteString("  " + l + "\n")
+	}
+	return sb.String()
+}
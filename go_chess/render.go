@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -103,8 +104,10 @@ func RenderBoard(b *Board, flipped bool, lastMove Move, showCoords bool) string
 func RenderGameState(g *Game, showEval bool) string {
 	var sb strings.Builder
 
-	// Board
-	sb.WriteString(RenderBoard(g.Board, g.Flipped, g.LastMove, true))
+	// Board with the move list alongside, scrolled to the current move
+	board := RenderBoard(g.Board, g.Flipped, g.LastMove, true)
+	moves := MoveListToSAN(g.History, g.StartBoard)
+	sb.WriteString(sideBySide(board, tailLines(moves, strings.Count(board, "\n")), 4))
 	sb.WriteString("\n")
 
 	// Status line
@@ -113,7 +116,12 @@ func RenderGameState(g *Game, showEval bool) string {
 		sideStr = "Black"
 	}
 
-	if g.Board.IsCheckmate() {
+	if g.reviewing() {
+		sb.WriteString(fmt.Sprintf("[reviewing: move %d of %d]\n", len(g.History), g.totalPlies()))
+	}
+	if loser, ok := g.flagFallen(); ok {
+		sb.WriteString(fmt.Sprintf("TIME! %s wins on time.\n", colorName(loser.Opponent())))
+	} else if g.Board.IsCheckmate() {
 		winner := "Black"
 		if g.Board.SideToMove == Black {
 			winner = "White"
@@ -131,6 +139,12 @@ func RenderGameState(g *Game, showEval bool) string {
 		sb.WriteString("\n")
 	}
 
+	// Clocks
+	if g.ClockBase > 0 {
+		sb.WriteString(fmt.Sprintf("Clock: White %s | Black %s (+%ds)\n",
+			FormatClock(g.Clock[White]), FormatClock(g.Clock[Black]), int(g.Increment.Seconds())))
+	}
+
 	// Evaluation
 	if showEval {
 		eval := Evaluate(g.Board)
@@ -149,6 +163,48 @@ func RenderGameState(g *Game, showEval bool) string {
 	return sb.String()
 }
 
+// tailLines returns the last n lines of text
+func tailLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sideBySide places right to the right of left, separated by gap spaces
+func sideBySide(left, right string, gap int) string {
+	leftLines := strings.Split(strings.TrimRight(left, "\n"), "\n")
+	var rightLines []string
+	if right != "" {
+		rightLines = strings.Split(right, "\n")
+	}
+
+	width := 0
+	for _, l := range leftLines {
+		if w := len([]rune(l)); w > width {
+			width = w
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(leftLines) || i < len(rightLines); i++ {
+		l := ""
+		if i < len(leftLines) {
+			l = leftLines[i]
+		}
+		if i < len(rightLines) && rightLines[i] != "" {
+			sb.WriteString(l)
+			sb.WriteString(strings.Repeat(" ", width-len([]rune(l))+gap))
+			sb.WriteString(rightLines[i])
+		} else {
+			sb.WriteString(l)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
 // RenderMoveList generates a list of moves in algebraic notation
 func RenderMoveList(history []Move) string {
 	var sb strings.Builder
@@ -171,13 +227,58 @@ func RenderMoveList(history []Move) string {
 	return sb.String()
 }
 
+// RenderGameList shows each game's buffer, status and board
+// (chess-list-games); current is marked
+func RenderGameList(list []*Game, current *Game) string {
+	var sb strings.Builder
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Buffer < list[j].Buffer })
+	sb.WriteString(fmt.Sprintf("Chess games (%d)\n", len(list)))
+	for _, g := range list {
+		status := fmt.Sprintf("%s to move", colorName(g.Board.SideToMove))
+		if result, reason := gameResult(g.Board); result != "*" {
+			status = fmt.Sprintf("%s (%s)", result, reason)
+		}
+		mark := ""
+		if g == current {
+			mark = " [current]"
+		}
+
+		sb.WriteString(fmt.Sprintf("\n%s%s: move %d, %s\n\n", g.Buffer, mark, g.Board.FullMoves, status))
+		sb.WriteString(RenderBoard(g.Board, g.Flipped, g.LastMove, true))
+		sb.WriteString(fmt.Sprintf("FEN: %s\n", g.Board.ToFEN()))
+	}
+
+	return sb.String()
+}
+
+// pvDisplayMoves is how much of the principal variation the search info shows
+const pvDisplayMoves = 5
+
 // RenderSearchInfo generates search metrics display
 func RenderSearchInfo(result SearchResult) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("Best: %s | ", result.BestMove.String()))
 	sb.WriteString(fmt.Sprintf("Eval: %+.2f | ", float64(result.Score)/100.0))
-	sb.WriteString(fmt.Sprintf("Depth: %d | ", result.Depth))
+	if result.MaxDepth > result.Depth {
+		// Time ran out before the full depth
+		sb.WriteString(fmt.Sprintf("Depth: %d/%d | ", result.Depth, result.MaxDepth))
+	} else {
+		sb.WriteString(fmt.Sprintf("Depth: %d | ", result.Depth))
+	}
+
+	if len(result.PV) > 0 {
+		pv := make([]string, 0, pvDisplayMoves)
+		for i, m := range result.PV {
+			if i == pvDisplayMoves {
+				pv = append(pv, "...")
+				break
+			}
+			pv = append(pv, m.String())
+		}
+		sb.WriteString(fmt.Sprintf("PV: %s | ", strings.Join(pv, " ")))
+	}
 
 	if result.Metrics.NodesSearched > 0 {
 		nodes := result.Metrics.NodesSearched
@@ -201,30 +302,45 @@ func RenderSearchInfo(result SearchResult) string {
 	return sb.String()
 }
 
-// FormatPGN generates PGN notation for a game
-func FormatPGN(history []Move, result string) string {
+// RenderEvalBreakdown lists the evaluation components (chess-eval-breakdown)
+func RenderEvalBreakdown(b *Board, e EvalBreakdown) string {
 	var sb strings.Builder
 
-	sb.WriteString("[Event \"μEmacs Chess\"]\n")
-	sb.WriteString("[Date \"????.??.??\"]\n")
-	sb.WriteString("[White \"Human\"]\n")
-	sb.WriteString("[Black \"go_chess AI\"]\n")
-	sb.WriteString(fmt.Sprintf("[Result \"%s\"]\n", result))
-	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("Evaluation of %s (centipawns, + favours White)\n\n", b.ToFEN()))
+	rows := []struct {
+		name  string
+		score int
+	}{
+		{"Material", e.Material},
+		{"Position", e.Position},
+		{"Pawn struct", e.PawnStructure},
+		{"King safety", e.KingSafety},
+		{"Mobility", e.Mobility},
+		{"Tempo", e.Tempo},
+		{"Total", e.Total()},
+	}
+	for _, r := range rows {
+		sb.WriteString(fmt.Sprintf("%-15s%+d\n", r.name+":", r.score))
+	}
+	if score, ok := tablebaseScore(b); ok {
+		sb.WriteString(fmt.Sprintf("\nThe tablebases score this position %+d\n", score))
+	}
 
-	for i := 0; i < len(history); i += 2 {
-		moveNum := i/2 + 1
-		whiteMove := history[i].String()
-		sb.WriteString(fmt.Sprintf("%d. %s", moveNum, whiteMove))
+	return sb.String()
+}
 
-		if i+1 < len(history) {
-			blackMove := history[i+1].String()
-			sb.WriteString(fmt.Sprintf(" %s ", blackMove))
-		}
-	}
+// RenderEditBoard renders the board editor (chess-edit) with its status
+func RenderEditBoard(b *Board, status string) string {
+	var sb strings.Builder
 
-	sb.WriteString(result)
-	sb.WriteString("\n")
+	sb.WriteString(RenderBoard(b, false, Move{}, true))
+	sb.WriteString(fmt.Sprintf("\nEdit: %s to move | Castling %s\n", colorName(b.SideToMove), b.castlingFEN()))
+	sb.WriteString(fmt.Sprintf("FEN: %s\n", b.ToFEN()))
+	sb.WriteString(status + "\n\n")
+	sb.WriteString("  chess-edit          place pieces (PNBRQK white, pnbrqk black, . clears)\n")
+	sb.WriteString("  chess-set-side      set the side to move (w/b)\n")
+	sb.WriteString("  chess-set-castling  set castling rights (KQkq, HAha or -)\n")
+	sb.WriteString("  chess-edit-done     validate and play from this position\n")
 
 	return sb.String()
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Post-game analysis: re-searches every position of a finished game to
+// estimate centipawn loss per move and summarize the game.
+
+// ReportDepth is the search depth used for post-game re-analysis
+const ReportDepth = 4
+
+// reportEvalCap bounds evaluations (mates, huge material swings) so a
+// single position can't dominate the averages
+const reportEvalCap = 1000
+
+// accurateMoveLoss is the largest centipawn loss still counted as accurate
+const accurateMoveLoss = 20
+
+// MoveAnalysis holds the re-analysis of one ply
+type MoveAnalysis struct {
+	Ply       int // 0-based index into the game history
+	SAN       string
+	Side      Color
+	EvalAfter int  // White-perspective eval after the move (centipawns)
+	CPLoss    int  // Centipawns lost versus the engine's best (>= 0)
+	InBook    bool // Move was an opening book move
+}
+
+// GameReport summarizes a game for go_chess_report
+type GameReport struct {
+	Result      string // "1-0", "0-1", "1/2-1/2", or "*" if unfinished
+	Reason      string
+	Opening     string
+	Moves       []MoveAnalysis
+	StartEval   int
+	AvgLoss     [2]float64 // Indexed by Color
+	BookMoves   [2]int     // Indexed by Color
+	MoveTimes   []time.Duration
+	Blunder     int // Index into Moves of the biggest loss, -1 if none
+	BestRunFrom int // Start of the longest accurate sequence (index into Moves)
+	BestRunLen  int
+}
+
+// clampEval limits an evaluation to +/- reportEvalCap
+func clampEval(score int) int {
+	if score > reportEvalCap {
+		return reportEvalCap
+	}
+	if score < -reportEvalCap {
+		return -reportEvalCap
+	}
+	return score
+}
+
+// analyzePosition returns a white-perspective evaluation of b.
+// Re-analysis runs single-threaded so results are reproducible.
+func analyzePosition(b *Board, depth int) int {
+	if b.IsCheckmate() {
+		if b.SideToMove == White {
+			return -reportEvalCap
+		}
+		return reportEvalCap
+	}
+	if b.IsDraw() {
+		return 0
+	}
+	opts := DefaultSearchOptions(1)
+	opts.MaxDepth = depth
+	opts.Deterministic = true
+	return clampEval(Search(b.Copy(), opts).Score)
+}
+
+// AnalyzeGame replays g from its start position, re-searching every
+// position at the given depth
+func AnalyzeGame(g *Game, depth int) *GameReport {
+	r := &GameReport{Blunder: -1, MoveTimes: g.MoveTimes}
+
+	r.Result, r.Reason = gameResult(g.Board)
+
+	start := g.StartBoard
+	if start == nil {
+		start = NewBoard()
+	}
+	b := start.Copy()
+
+	prevEval := analyzePosition(b, depth)
+	r.StartEval = prevEval
+
+	var lossSum [2]int
+	var moveCount [2]int
+
+	for i, m := range g.History {
+		side := b.SideToMove
+		fen := b.ToFEN()
+		ma := MoveAnalysis{Ply: i, SAN: MoveToSAN(b, m), Side: side}
+
+		if globalBook != nil {
+			if pos, ok := globalBook.LookupFEN(fen); ok && pos.Name != "" {
+				r.Opening = pos.Name
+				if pos.ECO != "" {
+					r.Opening = pos.ECO + " " + pos.Name
+				}
+			}
+			if globalBook.IsInBook(fen, m.String()) {
+				ma.InBook = true
+				r.BookMoves[side]++
+			}
+		}
+
+		mm := m
+		b.MakeMove(&mm)
+		ma.EvalAfter = analyzePosition(b, depth)
+
+		// Loss from the mover's perspective
+		loss := prevEval - ma.EvalAfter
+		if side == Black {
+			loss = -loss
+		}
+		if loss < 0 || ma.InBook {
+			loss = 0
+		}
+		ma.CPLoss = loss
+		lossSum[side] += loss
+		moveCount[side]++
+		prevEval = ma.EvalAfter
+
+		if r.Blunder < 0 || loss > r.Moves[r.Blunder].CPLoss {
+			r.Blunder = len(r.Moves)
+		}
+		r.Moves = append(r.Moves, ma)
+	}
+
+	for c := range lossSum {
+		if moveCount[c] > 0 {
+			r.AvgLoss[c] = float64(lossSum[c]) / float64(moveCount[c])
+		}
+	}
+	if r.Blunder >= 0 && r.Moves[r.Blunder].CPLoss == 0 {
+		r.Blunder = -1
+	}
+
+	// Longest run of consecutive accurate plies
+	run := 0
+	for i, ma := range r.Moves {
+		if ma.CPLoss <= accurateMoveLoss {
+			run++
+			if run > r.BestRunLen {
+				r.BestRunLen = run
+				r.BestRunFrom = i - run + 1
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	return r
+}
+
+// moveLabel formats a ply as "12. Nf3" or "12... Nc6"
+func moveLabel(ma MoveAnalysis, startBoard *Board) string {
+	firstMove := 1
+	offset := 0
+	if startBoard != nil {
+		if startBoard.FullMoves > 0 {
+			firstMove = startBoard.FullMoves
+		}
+		if startBoard.SideToMove == Black {
+			offset = 1
+		}
+	}
+	num := firstMove + (ma.Ply+offset)/2
+	if ma.Side == White {
+		return fmt.Sprintf("%d. %s", num, ma.SAN)
+	}
+	return fmt.Sprintf("%d... %s", num, ma.SAN)
+}
+
+// evalBar draws a horizontal bar centered on 0, scaled to +/- 5 pawns
+func evalBar(eval int) string {
+	const half = 20
+	n := eval * half / 500
+	if n > half {
+		n = half
+	}
+	if n < -half {
+		n = -half
+	}
+	left := strings.Repeat(" ", half)
+	right := strings.Repeat(" ", half)
+	if n < 0 {
+		left = strings.Repeat(" ", half+n) + strings.Repeat("#", -n)
+	} else if n > 0 {
+		right = strings.Repeat("#", n) + strings.Repeat(" ", half-n)
+	}
+	return left + "|" + right
+}
+
+// RenderReport formats a game report for the *chess-report* buffer
+func RenderReport(r *GameReport, startBoard *Board) string {
+	var sb strings.Builder
+
+	sb.WriteString("Game Report\n")
+	sb.WriteString("===========\n\n")
+
+	sb.WriteString(fmt.Sprintf("Result:     %s (%s)\n", r.Result, r.Reason))
+	sb.WriteString(fmt.Sprintf("Moves:      %d (%d plies)\n", (len(r.Moves)+1)/2, len(r.Moves)))
+	opening := r.Opening
+	if opening == "" {
+		opening = "unknown"
+	}
+	sb.WriteString(fmt.Sprintf("Opening:    %s\n", opening))
+	sb.WriteString(fmt.Sprintf("Book moves: White %d, Black %d\n", r.BookMoves[White], r.BookMoves[Black]))
+	sb.WriteString(fmt.Sprintf("Avg loss:   White %.1f cp, Black %.1f cp (depth %d)\n\n",
+		r.AvgLoss[White], r.AvgLoss[Black], ReportDepth))
+
+	if r.Blunder >= 0 {
+		b := r.Moves[r.Blunder]
+		sb.WriteString(fmt.Sprintf("Biggest blunder: %s by %s (-%d cp)\n",
+			moveLabel(b, startBoard), colorName(b.Side), b.CPLoss))
+	} else {
+		sb.WriteString("Biggest blunder: none\n")
+	}
+	if r.BestRunLen > 0 {
+		first := r.Moves[r.BestRunFrom]
+		last := r.Moves[r.BestRunFrom+r.BestRunLen-1]
+		sb.WriteString(fmt.Sprintf("Most accurate sequence: %s to %s (%d plies, loss <= %d cp)\n",
+			moveLabel(first, startBoard), moveLabel(last, startBoard), r.BestRunLen, accurateMoveLoss))
+	}
+
+	// Per-move table with evaluation graph
+	timed := len(r.MoveTimes) == len(r.Moves) && len(r.Moves) > 0
+	sb.WriteString("\nEvaluation (White +, Black -; bar scale +/-5.00)\n\n")
+	sb.WriteString(fmt.Sprintf("%-16s %7s %5s", "Start", fmt.Sprintf("%+.2f", float64(r.StartEval)/100.0), ""))
+	if timed {
+		sb.WriteString(fmt.Sprintf(" %7s", ""))
+	}
+	sb.WriteString("  " + evalBar(r.StartEval) + "\n")
+
+	for i, ma := range r.Moves {
+		label := moveLabel(ma, startBoard)
+		if ma.InBook {
+			label += " (book)"
+		}
+		loss := ""
+		if ma.CPLoss > 0 {
+			loss = fmt.Sprintf("-%d", ma.CPLoss)
+		}
+		sb.WriteString(fmt.Sprintf("%-16s %+7.2f %5s", label, float64(ma.EvalAfter)/100.0, loss))
+		if timed {
+			sb.WriteString(fmt.Sprintf(" %6.1fs", r.MoveTimes[i].Seconds()))
+		}
+		sb.WriteString("  " + evalBar(ma.EvalAfter) + "\n")
+	}
+
+	return sb.String()
+}
+
+// colorName returns "White" or "Black"
+func colorName(c Color) string {
+	if c == Black {
+		return "Black"
+	}
+	return "White"
+}
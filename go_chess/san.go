@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Standard Algebraic Notation (SAN) for display and PGN
+
+// sanPieceLetters maps a piece type (1-6) to its SAN letter
+var sanPieceLetters = [7]string{"", "", "N", "B", "R", "Q", "K"}
+
+// SANMoveListWidth is the column at which move list lines wrap
+const SANMoveListWidth = 40
+
+// MoveToSAN returns the SAN for move m played from position b (e.g. "Nbd7",
+// "exd5", "e8=Q+", "O-O#"). The board is left unchanged.
+func MoveToSAN(b *Board, m Move) string {
+	piece := b.Squares[m.From]
+	pt := piece.Type()
+
+	var sb strings.Builder
+
+	castle, kingside := b.IsCastle(m)
+	switch {
+	case castle && kingside:
+		sb.WriteString("O-O")
+	case castle:
+		sb.WriteString("O-O-O")
+	default:
+		isCapture := b.Squares[m.To] != Empty ||
+			(pt == 1 && m.From.File() != m.To.File()) // en passant
+
+		if pt == 1 {
+			if isCapture {
+				sb.WriteByte(byte('a' + m.From.File()))
+			}
+		} else {
+			sb.WriteString(sanPieceLetters[pt])
+			sb.WriteString(sanDisambiguation(b, m, piece))
+		}
+
+		if isCapture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(SquareName(m.To))
+
+		if m.Promotion != Empty {
+			sb.WriteByte('=')
+			sb.WriteString(sanPieceLetters[m.Promotion.Type()])
+		}
+	}
+
+	// Check and checkmate suffixes
+	after := b.Copy()
+	mm := m
+	after.MakeMove(&mm)
+	if after.InCheck() {
+		if len(after.GenerateLegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+
+	return sb.String()
+}
+
+// sanDisambiguation returns the file, rank, or square needed to tell m
+// apart from other legal moves of the same piece type to the same square
+func sanDisambiguation(b *Board, m Move, piece Piece) string {
+	var ambiguous, sameFile, sameRank bool
+
+	scratch := b.Copy()
+	for _, other := range scratch.GenerateLegalMoves() {
+		if other.To != m.To || other.From == m.From || b.Squares[other.From] != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(rune('a' + m.From.File()))
+	case !sameRank:
+		return string(rune('1' + m.From.Rank()))
+	default:
+		return SquareName(m.From)
+	}
+}
+
+// MoveListToSAN replays history from startBoard and formats it as
+// "1. e4 e5" with one move pair per line, wrapping at SANMoveListWidth.
+// The most recent move is marked with ">>>".
+func MoveListToSAN(history []Move, startBoard *Board) string {
+	if len(history) == 0 {
+		return ""
+	}
+	if startBoard == nil {
+		startBoard = NewBoard()
+	}
+
+	b := startBoard.Copy()
+	moveNum := b.FullMoves
+	if moveNum < 1 {
+		moveNum = 1
+	}
+
+	var lines []string
+	var line strings.Builder
+
+	flush := func() {
+		if line.Len() > 0 {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+	}
+	add := func(token string) {
+		if line.Len() > 0 && line.Len()+1+len(token) > SANMoveListWidth {
+			flush()
+			line.WriteString("   ")
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(token)
+	}
+
+	for i, m := range history {
+		san := MoveToSAN(b, m)
+		if i == len(history)-1 {
+			san = ">>>" + san
+		}
+
+		if b.SideToMove == White {
+			flush()
+			add(fmt.Sprintf("%d.", moveNum))
+		} else if i == 0 {
+			add(fmt.Sprintf("%d...", moveNum))
+		}
+		add(san)
+
+		if b.SideToMove == Black {
+			moveNum++
+		}
+		mm := m
+		b.MakeMove(&mm)
+	}
+	flush()
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -73,6 +73,14 @@ const TTMask = TTSize - 1
 
 var transpositionTable [TTSize]TTEntry
 
+// ttStats counts transposition table traffic for chess-tt-stats
+var ttStats struct {
+	hits       atomic.Uint64 // Probes that returned a usable score
+	misses     atomic.Uint64 // Probes with no entry or an unusable score
+	stores     atomic.Uint64
+	collisions atomic.Uint64 // Stores that replaced another position
+}
+
 // encodeMove packs a move into 16 bits: from(6) | to(6) | promo(4)
 func encodeMove(m Move) uint16 {
 	promo := uint16(0)
@@ -137,6 +145,7 @@ func ttProbe(hash uint64, depth, alpha, beta int, sideToMove Color) (int, Move,
 
 	// Verify hash match (avoid collision false positives)
 	if entry.Hash != hash {
+		ttStats.misses.Add(1)
 		return 0, Move{}, false
 	}
 
@@ -148,21 +157,25 @@ func ttProbe(hash uint64, depth, alpha, beta int, sideToMove Color) (int, Move,
 
 		switch entry.Flag {
 		case TTFlagExact:
+			ttStats.hits.Add(1)
 			return score, bestMove, true
 		case TTFlagLower:
 			// Score is a lower bound (real score >= stored)
 			if score >= beta {
+				ttStats.hits.Add(1)
 				return score, bestMove, true
 			}
 		case TTFlagUpper:
 			// Score is an upper bound (real score <= stored)
 			if score <= alpha {
+				ttStats.hits.Add(1)
 				return score, bestMove, true
 			}
 		}
 	}
 
 	// No usable score, but return best move for ordering
+	ttStats.misses.Add(1)
 	return 0, bestMove, false
 }
 
@@ -175,6 +188,10 @@ func ttStore(hash uint64, depth int, score int, flag uint8, bestMove Move) {
 	// Replace if: different position OR deeper/equal depth search
 	// This prioritizes deeper results while allowing updates for same position
 	if entry.Hash != hash || int(entry.Depth) <= depth {
+		ttStats.stores.Add(1)
+		if entry.Hash != hash && entry.Hash != 0 {
+			ttStats.collisions.Add(1)
+		}
 		entry.Hash = hash
 		entry.Depth = int8(depth)
 		entry.Score = int16(score)
@@ -188,6 +205,21 @@ func ttClear() {
 	for i := range transpositionTable {
 		transpositionTable[i] = TTEntry{}
 	}
+	ttStats.hits.Store(0)
+	ttStats.misses.Store(0)
+	ttStats.stores.Store(0)
+	ttStats.collisions.Store(0)
+}
+
+// ttOccupancy estimates the fraction of used entries from a random sample
+func ttOccupancy(samples int) float64 {
+	used := 0
+	for i := 0; i < samples; i++ {
+		if transpositionTable[rand.Intn(TTSize)].Hash != 0 {
+			used++
+		}
+	}
+	return float64(used) / float64(samples)
 }
 
 // ============================================================================
@@ -197,6 +229,9 @@ func ttClear() {
 // Maximum quiescence depth to prevent explosion
 const MaxQDepth = 8
 
+// Captures with SEE below this (centipawns) are skipped in quiescence
+const SEEPruneMargin = -50
+
 // quiescence searches captures until the position is "quiet"
 // This prevents the horizon effect where evaluation happens mid-tactic
 func quiescence(b *Board, alpha, beta int, maximizing bool, qdepth int) int {
@@ -214,6 +249,9 @@ func quiescence(b *Board, alpha, beta int, maximizing bool, qdepth int) int {
 	if b.IsDraw() {
 		return 0
 	}
+	if score, ok := probeEndgame(b); ok {
+		return score
+	}
 
 	// Depth limit to prevent explosion
 	if qdepth >= MaxQDepth {
@@ -247,14 +285,30 @@ func quiescence(b *Board, alpha, beta int, maximizing bool, qdepth int) int {
 		}
 	}
 
-	// Order captures by MVV-LVA (Most Valuable Victim - Least Valuable Attacker)
-	captures = OrderMoves(b, captures)
+	// Order captures by SEE, winning exchanges first; prune clearly losing
+	// ones since standing pat is almost always better
+	type seeMove struct {
+		move Move
+		see  int
+	}
+	scored := make([]seeMove, 0, len(captures))
+	for _, m := range OrderMoves(b, captures) {
+		see := SEE(b, m)
+		if see < SEEPruneMargin {
+			continue
+		}
+		// Insertion sort (stable, keeps MVV-LVA order on ties)
+		scored = append(scored, seeMove{m, see})
+		for j := len(scored) - 1; j > 0 && scored[j-1].see < scored[j].see; j-- {
+			scored[j-1], scored[j] = scored[j], scored[j-1]
+		}
+	}
 
 	if maximizing {
-		for _, m := range captures {
-			// Delta pruning: skip if capture can't possibly improve alpha
-			// Even capturing the best piece won't be enough
-			if standPat+pieceValue(m.Captured)+200 < alpha {
+		for _, sm := range scored {
+			m := sm.move
+			// Delta pruning: skip if even winning the exchange can't improve alpha
+			if standPat+sm.see+200 < alpha {
 				continue
 			}
 
@@ -271,9 +325,10 @@ func quiescence(b *Board, alpha, beta int, maximizing bool, qdepth int) int {
 		}
 		return alpha
 	} else {
-		for _, m := range captures {
+		for _, sm := range scored {
+			m := sm.move
 			// Delta pruning for minimizing
-			if standPat-pieceValue(m.Captured)-200 > beta {
+			if standPat-sm.see-200 > beta {
 				continue
 			}
 
@@ -292,24 +347,6 @@ func quiescence(b *Board, alpha, beta int, maximizing bool, qdepth int) int {
 	}
 }
 
-// pieceValue returns the material value of a piece for delta pruning
-func pieceValue(p Piece) int {
-	switch p {
-	case WPawn, BPawn:
-		return 100
-	case WKnight, BKnight:
-		return 320
-	case WBishop, BBishop:
-		return 330
-	case WRook, BRook:
-		return 500
-	case WQueen, BQueen:
-		return 900
-	default:
-		return 0
-	}
-}
-
 // ============================================================================
 // Killer Moves and History Heuristic
 // ============================================================================
@@ -417,6 +454,8 @@ type parallelTask struct {
 
 	// Sibling coordination
 	siblingAlpha *atomic.Int64 // Shared bound among siblings
+
+	search *searchState // The search this task belongs to
 }
 
 // taskResult carries search results back to parent
@@ -528,6 +567,29 @@ type SearchOptions struct {
 	Deterministic          bool
 	TimeLimit              time.Duration // 0 = no limit
 
+	// Time control: used when TimeLimit is 0. TimePerMove is a fixed
+	// budget; otherwise RemainingTime, Increment and MovesToGo are turned
+	// into a budget by calculateMoveTime.
+	TimePerMove   time.Duration
+	Increment     time.Duration
+	MovesToGo     int // 0 = unknown (sudden death)
+	RemainingTime time.Duration
+
+	// SoftLimit stops iterative deepening after the first depth completed
+	// past it; HardLimit cancels the search, mid-depth, once reached. Both
+	// are measured from the start of the search; when 0 they are derived
+	// from the time control (see timeLimits).
+	SoftLimit time.Duration
+	HardLimit time.Duration
+
+	// Null-move pruning: search a pass NullMoveReduction plies shallower
+	// and prune when even that fails high. NullMoveVerification confirms
+	// each cutoff with a reduced search of the real moves, which guards
+	// against zugzwang at some cost in speed.
+	EnableNullMove       bool
+	NullMoveReduction    int
+	NullMoveVerification bool
+
 	// Contempt: centipawns added to eval to discourage draws
 	// Higher contempt = more aggressive play, avoiding repetitions
 	// Formula: contempt = (0.5 - draw_value) * 100
@@ -543,6 +605,9 @@ type SearchOptions struct {
 	// Metrics hook
 	MetricsHook     func(SearchMetrics)
 	MetricsInterval time.Duration
+
+	// DepthHook is called with the result of each completed depth
+	DepthHook func(SearchResult)
 }
 
 // SearchMetrics provides observability into the search
@@ -563,7 +628,9 @@ type SearchMetrics struct {
 type SearchResult struct {
 	BestMove Move
 	Score    int
-	Depth    int
+	Depth    int    // Last completed depth
+	MaxDepth int    // Depth asked for: more than Depth when time ran out
+	PV       []Move // Principal variation, starting with BestMove
 	Metrics  SearchMetrics
 }
 
@@ -572,6 +639,7 @@ func DefaultSearchOptions(workers int) SearchOptions {
 	if workers <= 0 {
 		workers = runtime.NumCPU()
 	}
+	nm := nullMoveDefault.Load()
 	return SearchOptions{
 		MaxWorkers:             workers,
 		MaxDepth:               6,
@@ -583,6 +651,90 @@ func DefaultSearchOptions(workers int) SearchOptions {
 		ChunkStealSize:         2,
 		QueuePressureLow:       4,
 		QueuePressureHigh:      32,
+		EnableNullMove:         nm.R > 0,
+		NullMoveReduction:      nm.R,
+		NullMoveVerification:   nm.Verify,
+	}
+}
+
+// DefaultMovesToGo is the number of moves assumed left in a sudden-death
+// time control
+const DefaultMovesToGo = 30
+
+// calculateMoveTime allocates a share of the remaining clock for one move:
+// remaining/(movesToGo+5) plus most of the increment, never more than half
+// of what is left
+func calculateMoveTime(remaining, increment time.Duration, movesToGo int) time.Duration {
+	if remaining <= 0 {
+		return 0
+	}
+	if movesToGo <= 0 {
+		movesToGo = DefaultMovesToGo
+	}
+	budget := remaining/time.Duration(movesToGo+5) + increment*8/10
+	if budget > remaining/2 {
+		budget = remaining / 2
+	}
+	return budget
+}
+
+// calculateTimeLimits turns a clock into soft and hard limits: the
+// calculateMoveTime share is the soft limit, and a depth begun before it
+// may run on to three times that, never past half the remaining time
+func calculateTimeLimits(remaining, increment time.Duration, movesToGo int) (soft, hard time.Duration) {
+	soft = calculateMoveTime(remaining, increment, movesToGo)
+	hard = 3 * soft
+	if hard > remaining/2 {
+		hard = remaining / 2
+	}
+	if hard < soft {
+		hard = soft
+	}
+	return soft, hard
+}
+
+// timeLimits returns the soft and hard limits of a search (0 = none).
+// Unset limits come from the time control: a fixed budget is the hard
+// limit with half of it the soft one, since each depth costs several
+// times the last; a clock goes through calculateTimeLimits.
+func (opts SearchOptions) timeLimits() (soft, hard time.Duration) {
+	soft, hard = opts.SoftLimit, opts.HardLimit
+	if soft > 0 && hard > 0 {
+		return soft, hard
+	}
+
+	var budgetSoft, budgetHard time.Duration
+	switch {
+	case opts.TimeLimit > 0:
+		budgetSoft, budgetHard = opts.TimeLimit/2, opts.TimeLimit
+	case opts.TimePerMove > 0:
+		budgetSoft, budgetHard = opts.TimePerMove/2, opts.TimePerMove
+	case opts.RemainingTime > 0:
+		budgetSoft, budgetHard = calculateTimeLimits(opts.RemainingTime, opts.Increment, opts.MovesToGo)
+	case hard > 0:
+		budgetSoft = hard / 2
+	}
+	if soft == 0 {
+		soft = budgetSoft
+	}
+	if hard == 0 {
+		hard = budgetHard
+	}
+	return soft, hard
+}
+
+// shouldStop reports whether iterative deepening should stop before
+// another depth: the soft limit has passed
+func shouldStop(start time.Time, opts SearchOptions) bool {
+	soft, _ := opts.timeLimits()
+	return soft > 0 && time.Since(start) >= soft
+}
+
+// reportDepth passes a completed depth to DepthHook, if set
+func (opts SearchOptions) reportDepth(result SearchResult, start time.Time) {
+	if opts.DepthHook != nil {
+		result.Metrics.ElapsedMs = time.Since(start).Milliseconds()
+		opts.DepthHook(result)
 	}
 }
 
@@ -607,17 +759,36 @@ const (
 )
 
 func Search(b *Board, opts SearchOptions) SearchResult {
+	return SearchContext(context.Background(), b, opts)
+}
+
+// SearchContext is Search, stopping at the last completed depth when ctx
+// is cancelled
+func SearchContext(ctx context.Context, b *Board, opts SearchOptions) SearchResult {
 	start := time.Now()
 
-	ctx := context.Background()
-	if opts.TimeLimit > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, opts.TimeLimit)
-		defer cancel()
+	var cancel context.CancelFunc
+	if _, hard := opts.timeLimits(); hard > 0 {
+		ctx, cancel = context.WithTimeout(ctx, hard)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
 	}
+	defer cancel()
+	ss := newSearchState(ctx, opts)
 
 	result := SearchResult{
-		Depth: opts.MaxDepth,
+		MaxDepth: opts.MaxDepth,
+	}
+
+	// In a tablebase position the DTZ tables choose the move
+	if move, score, ok := tablebaseRootMove(b); ok {
+		result.BestMove = move
+		result.Score = score
+		result.Depth = 1
+		result.PV = []Move{move}
+		opts.reportDepth(result, start)
+		result.Metrics.ElapsedMs = time.Since(start).Milliseconds()
+		return result
 	}
 
 	prevScore := 0 // Score from previous iteration for aspiration
@@ -630,6 +801,12 @@ func Search(b *Board, opts SearchOptions) SearchResult {
 		default:
 		}
 
+		// Past the soft limit the next depth would most likely be cut
+		// short
+		if depth > 1 && shouldStop(start, opts) {
+			goto done
+		}
+
 		var score int
 		var move Move
 
@@ -637,29 +814,41 @@ func Search(b *Board, opts SearchOptions) SearchResult {
 		if depth <= 2 || opts.MaxWorkers <= 1 {
 			if depth == 1 {
 				// First iteration: full window
-				score, move = sequentialAlphaBeta(b, depth, 0, -Infinity, Infinity, b.SideToMove == White, true)
+				score, move = sequentialAlphaBeta(ss, b, depth, 0, -Infinity, Infinity, b.SideToMove == White, true)
 			} else {
 				// Aspiration window search
-				score, move = aspirationSearch(b, depth, prevScore, b.SideToMove == White)
+				score, move = aspirationSearch(ss, b, depth, prevScore, b.SideToMove == White)
+			}
+			if depth > 1 && ss.stopped() {
+				// Interrupted: keep the last completed depth
+				break
 			}
 			if !move.IsNull() {
 				result.BestMove = move
 				result.Score = score
 				result.Depth = depth
+				result.PV = principalVariation(b, move, depth)
 				prevScore = score
+				opts.reportDepth(result, start)
 			}
 			continue
 		}
 
 		// Parallel search using work-stealing
 		// TODO: Add aspiration to parallel search (more complex)
-		move, score, metrics := parallelSearch(ctx, b, depth, opts)
+		move, score, metrics := parallelSearch(ctx, ss, b, depth, opts)
+		if ctx.Err() != nil {
+			// Interrupted: keep the last completed depth
+			break
+		}
 		if !move.IsNull() {
 			result.BestMove = move
 			result.Score = score
 			result.Depth = depth
+			result.PV = principalVariation(b, move, depth)
 			result.Metrics = metrics
 			prevScore = score
+			opts.reportDepth(result, start)
 		}
 	}
 
@@ -668,16 +857,56 @@ done:
 	return result
 }
 
+// extractPV follows the transposition table's best moves from b for up
+// to depth plies, stopping at a miss, an illegal move or a repetition
+func extractPV(b *Board, depth int) []Move {
+	var pv []Move
+	board := b.Copy()
+	seen := make(map[uint64]bool)
+
+	for len(pv) < depth && !board.IsCheckmate() {
+		hash := board.ZobristHash()
+		if seen[hash] {
+			break
+		}
+		seen[hash] = true
+
+		_, m, _ := ttProbe(hash, 0, -Infinity, Infinity, board.SideToMove)
+		if m.IsNull() {
+			break
+		}
+		legal, ok := board.ParseMove(m.String())
+		if !ok {
+			break
+		}
+		board.MakeMove(&legal)
+		pv = append(pv, legal)
+	}
+	return pv
+}
+
+// principalVariation returns best followed by the transposition table's
+// replies, up to depth plies. The root entry is not used: parallel
+// searches don't store it, and it may hold an older depth's move.
+func principalVariation(b *Board, best Move, depth int) []Move {
+	if best.IsNull() {
+		return nil
+	}
+	board := b.Copy()
+	board.MakeMove(&best)
+	return append([]Move{best}, extractPV(board, depth-1)...)
+}
+
 // aspirationSearch performs search with aspiration windows
 // Starts with a narrow window around expected score, widens on fail high/low
-func aspirationSearch(b *Board, depth int, expected int, maximizing bool) (int, Move) {
+func aspirationSearch(ss *searchState, b *Board, depth int, expected int, maximizing bool) (int, Move) {
 	window := aspirationInitialWindow
 
 	alpha := expected - window
 	beta := expected + window
 
 	for window <= aspirationMaxWindow {
-		score, move := sequentialAlphaBeta(b, depth, 0, alpha, beta, maximizing, true)
+		score, move := sequentialAlphaBeta(ss, b, depth, 0, alpha, beta, maximizing, true)
 
 		// Check if score is within window
 		if score > alpha && score < beta {
@@ -700,15 +929,74 @@ func aspirationSearch(b *Board, depth int, expected int, maximizing bool) (int,
 	}
 
 	// Window got too wide, fall back to full search
-	return sequentialAlphaBeta(b, depth, 0, -Infinity, Infinity, maximizing, true)
+	return sequentialAlphaBeta(ss, b, depth, 0, -Infinity, Infinity, maximizing, true)
 }
 
 // Null-move reduction depth
 const NullMoveR = 3
 
+// nullMoveConfig holds the null-move pruning parameters
+type nullMoveConfig struct {
+	R      int  // Depth reduction (0 = no null-move pruning)
+	Verify bool // Verify cutoffs with a reduced search
+}
+
+// nullMoveDefault seeds DefaultSearchOptions (chess-null-move-reduction)
+var nullMoveDefault atomic.Pointer[nullMoveConfig]
+
+func init() {
+	nullMoveDefault.Store(&nullMoveConfig{R: NullMoveR})
+}
+
+// SetNullMoveDefaults sets the null-move reduction (0 disables null-move
+// pruning) and verification of later DefaultSearchOptions
+func SetNullMoveDefaults(r int, verify bool) {
+	nullMoveDefault.Store(&nullMoveConfig{R: max(r, 0), Verify: verify})
+}
+
+// NullMoveDefaults returns the values set by SetNullMoveDefaults
+func NullMoveDefaults() (r int, verify bool) {
+	nm := nullMoveDefault.Load()
+	return nm.R, nm.Verify
+}
+
+// nullMove returns the options' null-move settings
+func (opts SearchOptions) nullMove() nullMoveConfig {
+	r := opts.NullMoveReduction
+	if !opts.EnableNullMove || r < 0 {
+		r = 0
+	}
+	return nullMoveConfig{R: r, Verify: opts.NullMoveVerification}
+}
+
+// searchState is what one search shares with every node it visits
+type searchState struct {
+	stop     atomic.Bool    // The search's context is done
+	nullMove nullMoveConfig // Null-move pruning of this search
+}
+
+// newSearchState returns the state of a search with opts that stops when
+// ctx is done. ctx must be cancelled once the search is over.
+func newSearchState(ctx context.Context, opts SearchOptions) *searchState {
+	ss := &searchState{nullMove: opts.nullMove()}
+	context.AfterFunc(ctx, func() { ss.stop.Store(true) })
+	return ss
+}
+
+// stopped reports whether the search was told to stop; the scores of
+// nodes searched since are meaningless and must be discarded
+func (ss *searchState) stopped() bool {
+	return ss.stop.Load()
+}
+
 // sequentialAlphaBeta is the standard recursive alpha-beta
 // canNullMove prevents consecutive null-move searches
-func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing bool, canNullMove bool) (int, Move) {
+func sequentialAlphaBeta(ss *searchState, b *Board, depth, ply int, alpha, beta int, maximizing bool, canNullMove bool) (int, Move) {
+	// Nodes just above the quiescence search always finish, so a depth
+	// once started is never left without a move
+	if depth > 1 && ss.stopped() {
+		return 0, Move{}
+	}
 	origAlpha := alpha
 
 	// Check for repetition - penalized based on side-specific contempt
@@ -739,6 +1027,22 @@ func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing b
 		return Evaluate(b), Move{}
 	}
 
+	// K+P vs K: exact result from the bitbase (the root still needs a move)
+	if ply > 0 {
+		if score, ok := probeEndgame(b); ok {
+			return score, Move{}
+		}
+	}
+
+	// Six pieces or fewer after a capture or pawn move: the WDL tables
+	// know the result. Positions further from the last zeroing move were
+	// reached through one, so probing there would only repeat the work.
+	if ply > 0 && b.HalfMoves == 0 {
+		if score, ok := tablebaseWDLScore(b); ok {
+			return score, Move{}
+		}
+	}
+
 	if depth == 0 {
 		// Quiescence search: continue searching captures until position is quiet
 		return quiescence(b, alpha, beta, maximizing, 0), Move{}
@@ -748,7 +1052,8 @@ func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing b
 
 	// Null-move pruning: try passing to see if position is so good we can prune
 	// Conditions: not in check, have non-pawn material (avoid zugzwang), sufficient depth
-	if canNullMove && !inCheck && depth >= NullMoveR && b.HasNonPawnMaterial(b.SideToMove) {
+	nm := ss.nullMove
+	if canNullMove && nm.R > 0 && !inCheck && depth >= nm.R && b.HasNonPawnMaterial(b.SideToMove) {
 		// Make null move (pass)
 		nullInfo := b.MakeNullMove()
 
@@ -756,18 +1061,29 @@ func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing b
 		// Use zero-window around beta for efficiency
 		var nullScore int
 		if maximizing {
-			nullScore, _ = sequentialAlphaBeta(b, depth-NullMoveR, ply+1, beta-1, beta, false, false)
+			nullScore, _ = sequentialAlphaBeta(ss, b, depth-nm.R, ply+1, beta-1, beta, false, false)
 		} else {
-			nullScore, _ = sequentialAlphaBeta(b, depth-NullMoveR, ply+1, alpha, alpha+1, true, false)
+			nullScore, _ = sequentialAlphaBeta(ss, b, depth-nm.R, ply+1, alpha, alpha+1, true, false)
 		}
 
 		b.UnmakeNullMove(nullInfo)
 
-		// If null-move search fails high, prune this node
-		if maximizing && nullScore >= beta {
-			return beta, Move{}
+		// If null-move search fails high, prune this node; with
+		// verification only if a reduced search of the real moves agrees
+		cutoff := (maximizing && nullScore >= beta) || (!maximizing && nullScore <= alpha)
+		if cutoff && nm.Verify {
+			if maximizing {
+				verifyScore, _ := sequentialAlphaBeta(ss, b, depth-nm.R, ply, beta-1, beta, true, false)
+				cutoff = verifyScore >= beta
+			} else {
+				verifyScore, _ := sequentialAlphaBeta(ss, b, depth-nm.R, ply, alpha, alpha+1, false, false)
+				cutoff = verifyScore <= alpha
+			}
 		}
-		if !maximizing && nullScore <= alpha {
+		if cutoff {
+			if maximizing {
+				return beta, Move{}
+			}
 			return alpha, Move{}
 		}
 	}
@@ -804,13 +1120,13 @@ func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing b
 			// LMR: reduce depth for late quiet moves
 			if i >= lmrFullDepthMoves && depth >= lmrReductionLimit && m.Captured == Empty && m.Promotion == Empty && !inCheck {
 				// Search at reduced depth first
-				eval, _ = sequentialAlphaBeta(b, depth-1-lmrReduction, ply+1, alpha, beta, false, true)
+				eval, _ = sequentialAlphaBeta(ss, b, depth-1-lmrReduction, ply+1, alpha, beta, false, true)
 				// If it looks good, re-search at full depth
 				if eval > alpha {
-					eval, _ = sequentialAlphaBeta(b, depth-1, ply+1, alpha, beta, false, true)
+					eval, _ = sequentialAlphaBeta(ss, b, depth-1, ply+1, alpha, beta, false, true)
 				}
 			} else {
-				eval, _ = sequentialAlphaBeta(b, depth-1, ply+1, alpha, beta, false, true)
+				eval, _ = sequentialAlphaBeta(ss, b, depth-1, ply+1, alpha, beta, false, true)
 			}
 
 			b.UnmakeMove(&m)
@@ -836,13 +1152,13 @@ func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing b
 			// LMR: reduce depth for late quiet moves
 			if i >= lmrFullDepthMoves && depth >= lmrReductionLimit && m.Captured == Empty && m.Promotion == Empty && !inCheck {
 				// Search at reduced depth first
-				eval, _ = sequentialAlphaBeta(b, depth-1-lmrReduction, ply+1, alpha, beta, true, true)
+				eval, _ = sequentialAlphaBeta(ss, b, depth-1-lmrReduction, ply+1, alpha, beta, true, true)
 				// If it looks good, re-search at full depth
 				if eval < beta {
-					eval, _ = sequentialAlphaBeta(b, depth-1, ply+1, alpha, beta, true, true)
+					eval, _ = sequentialAlphaBeta(ss, b, depth-1, ply+1, alpha, beta, true, true)
 				}
 			} else {
-				eval, _ = sequentialAlphaBeta(b, depth-1, ply+1, alpha, beta, true, true)
+				eval, _ = sequentialAlphaBeta(ss, b, depth-1, ply+1, alpha, beta, true, true)
 			}
 
 			b.UnmakeMove(&m)
@@ -861,6 +1177,11 @@ func sequentialAlphaBeta(b *Board, depth, ply int, alpha, beta int, maximizing b
 		}
 	}
 
+	// A stopped search's scores must not reach the table
+	if ss.stopped() {
+		return bestScore, bestMove
+	}
+
 	// Transposition table store
 	var flag uint8
 	if maximizing {
@@ -911,7 +1232,7 @@ func processNodeParallel(t parallelTask, deques []*deque, workerID int, opts Sea
 	var bestScore int
 	if t.depth-1 <= opts.DepthParallelThreshold {
 		// Below threshold - finish first child sequentially
-		bestScore, _ = sequentialAlphaBeta(firstBoard, t.depth-1, 0, -t.beta, -t.alpha,
+		bestScore, _ = sequentialAlphaBeta(t.search, firstBoard, t.depth-1, 0, -t.beta, -t.alpha,
 			firstBoard.SideToMove == White, true)
 	} else {
 		// Above threshold - recurse with parallel processing
@@ -928,6 +1249,7 @@ func processNodeParallel(t parallelTask, deques []*deque, workerID int, opts Sea
 			taskID:       newTaskID(),
 			resultCh:     childCh,
 			siblingAlpha: nil,
+			search:       t.search,
 		}
 		// Process inline (we wait for it anyway)
 		score := processNodeParallel(childTask, deques, workerID, opts, tasksWG, rootSide)
@@ -972,6 +1294,7 @@ func processNodeParallel(t parallelTask, deques []*deque, workerID int, opts Sea
 			taskID:       newTaskID(),
 			resultCh:     childResultCh,
 			siblingAlpha: siblingAlpha,
+			search:       t.search,
 		})
 	}
 
@@ -1059,7 +1382,7 @@ func processTaskInline(task parallelTask, deques []*deque, workerID int,
 	if task.depth > opts.DepthParallelThreshold {
 		score = processNodeParallel(task, deques, workerID, opts, tasksWG, rootSide)
 	} else {
-		score, _ = sequentialAlphaBeta(task.board, task.depth, 0, task.alpha, task.beta,
+		score, _ = sequentialAlphaBeta(task.search, task.board, task.depth, 0, task.alpha, task.beta,
 			task.board.SideToMove == White, true)
 	}
 
@@ -1070,7 +1393,7 @@ func processTaskInline(task parallelTask, deques []*deque, workerID int,
 // parallelSearch uses work-stealing for the top few ply
 // Based on Young Brothers Wait Concept (YBWC)
 // Workers push children to deques, allowing work to flow DOWN the tree
-func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOptions) (Move, int, SearchMetrics) {
+func parallelSearch(ctx context.Context, ss *searchState, b *Board, maxDepth int, opts SearchOptions) (Move, int, SearchMetrics) {
 	clearCancelled() // Reset cancellation state
 
 	// Generate root moves
@@ -1084,7 +1407,7 @@ func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOpti
 	// Search first move sequentially (YBWC: establishes good bounds)
 	firstBoard := b.Copy()
 	firstBoard.MakeMove(&moves[0])
-	firstScore, _ := sequentialAlphaBeta(firstBoard, maxDepth-1, 0, -Infinity, Infinity, firstBoard.SideToMove == White, true)
+	firstScore, _ := sequentialAlphaBeta(ss, firstBoard, maxDepth-1, 0, -Infinity, Infinity, firstBoard.SideToMove == White, true)
 	// Negate to get score from root side's perspective
 	if rootSide == Black {
 		firstScore = -firstScore
@@ -1099,6 +1422,7 @@ func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOpti
 		return bestMove, bestScore, SearchMetrics{NodesSearched: 1}
 	}
 
+	parent := ctx
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -1155,7 +1479,7 @@ func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOpti
 					score = processNodeParallel(t, deques, id, opts, &tasksWG, rootSide)
 				} else {
 					// Below threshold: finish subtree sequentially
-					score, _ = sequentialAlphaBeta(t.board, t.depth, 0, t.alpha, t.beta,
+					score, _ = sequentialAlphaBeta(t.search, t.board, t.depth, 0, t.alpha, t.beta,
 						t.board.SideToMove == White, true)
 				}
 				atomic.AddUint64(&metrics.NodesSearched, 1)
@@ -1248,6 +1572,7 @@ func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOpti
 			taskID:       newTaskID(),
 			resultCh:     rootResultCh,
 			siblingAlpha: nil, // Root moves use sharedAlpha differently
+			search:       ss,
 		})
 		atomic.AddUint64(&metrics.Pushes, 1)
 	}
@@ -1267,6 +1592,20 @@ func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOpti
 
 	workersWG.Wait()
 
+	// On timeout, root tasks still queued were never run. Settle them so
+	// the waiter above can close the channel; the caller discards this
+	// incomplete depth.
+	if parent.Err() != nil {
+		for _, d := range deques {
+			for {
+				if _, ok := popTop(d); !ok {
+					break
+				}
+				tasksWG.Done()
+			}
+		}
+	}
+
 	// Collect root results
 	for r := range rootResultCh {
 		score := -r.score // Negamax: child's score is negated
@@ -1289,7 +1628,14 @@ func parallelSearch(ctx context.Context, b *Board, maxDepth int, opts SearchOpti
 // - Filters weakening moves (blunder avoidance)
 // - Uses graduated depth based on ply
 // - Adds book bonus to move scores
-func SearchWithBook(b *Board, ply int, configuredDepth int, workers int) SearchResult {
+// moveTime bounds the search (0 = depth only).
+func SearchWithBook(b *Board, ply int, configuredDepth int, workers int, moveTime time.Duration) SearchResult {
+	return searchWithBook(Search, b, ply, configuredDepth, workers, moveTime)
+}
+
+// searchWithBook is SearchWithBook with search run in place of Search, so
+// that a ponder search can stand in for it
+func searchWithBook(search func(*Board, SearchOptions) SearchResult, b *Board, ply int, configuredDepth int, workers int, moveTime time.Duration) SearchResult {
 	// Generate and filter moves
 	moves := b.GenerateLegalMoves()
 	if len(moves) == 0 {
@@ -1322,7 +1668,7 @@ func SearchWithBook(b *Board, ply int, configuredDepth int, workers int) SearchR
 		if bookMove, ok := globalBook.PickBookMove(b, moves, ply); ok {
 			bc := b.Copy()
 			bc.MakeMove(&bookMove)
-			bookScore, _ := sequentialAlphaBeta(bc, 3, 0, -Infinity, Infinity, bc.SideToMove == White, true)
+			bookScore, _ := sequentialAlphaBeta(newSearchState(context.Background(), DefaultSearchOptions(1)), bc, 3, 0, -Infinity, Infinity, bc.SideToMove == White, true)
 			if b.SideToMove == Black {
 				bookScore = -bookScore
 			}
@@ -1336,6 +1682,7 @@ func SearchWithBook(b *Board, ply int, configuredDepth int, workers int) SearchR
 					BestMove: bookMove,
 					Score:    bookScore,
 					Depth:    3,
+					MaxDepth: 3,
 				}
 			}
 		}
@@ -1351,13 +1698,14 @@ func SearchWithBook(b *Board, ply int, configuredDepth int, workers int) SearchR
 
 	// If we have book bonus, do move-by-move evaluation with bonus
 	if bookBonus > 0 && globalBook != nil {
-		return searchWithBookBonus(b, fen, moves, searchDepth, bookBonus, workers)
+		return searchWithBookBonus(search, b, fen, moves, searchDepth, bookBonus, workers, moveTime)
 	}
 
 	// Standard search (no book bonus)
 	opts := DefaultSearchOptions(workers)
 	opts.MaxDepth = searchDepth
-	result := Search(b, opts)
+	opts.TimeLimit = moveTime
+	result := search(b, opts)
 
 	// TRAINING MODE: Use temperature-based exploration
 	// This prevents identical self-play games (AlphaZero/Leela approach)
@@ -1384,7 +1732,9 @@ func SearchWithBook(b *Board, ply int, configuredDepth int, workers int) SearchR
 			return SearchResult{
 				BestMove: selected,
 				Score:    selectedScore,
-				Depth:    searchDepth,
+				Depth:    result.Depth,
+				MaxDepth: result.MaxDepth,
+				PV:       principalVariation(b, selected, result.Depth),
 				Metrics:  result.Metrics,
 			}
 		}
@@ -1396,7 +1746,7 @@ func SearchWithBook(b *Board, ply int, configuredDepth int, workers int) SearchR
 // searchWithBookBonus runs ONE search and applies book bonus to move selection
 // FIXED: Previous implementation called Search() for EACH move (N times), causing
 // 30-minute timeouts. Now we pre-compute bonuses and run a single search.
-func searchWithBookBonus(b *Board, fen string, moves []Move, depth int, bookBonus int, workers int) SearchResult {
+func searchWithBookBonus(search func(*Board, SearchOptions) SearchResult, b *Board, fen string, moves []Move, depth int, bookBonus int, workers int, moveTime time.Duration) SearchResult {
 	start := time.Now()
 
 	// Pre-compute book bonuses ONCE (not N times!)
@@ -1421,7 +1771,8 @@ func searchWithBookBonus(b *Board, fen string, moves []Move, depth int, bookBonu
 	// Run ONE search (the parallel search handles all moves efficiently)
 	opts := DefaultSearchOptions(workers)
 	opts.MaxDepth = depth
-	result := Search(b, opts)
+	opts.TimeLimit = moveTime
+	result := search(b, opts)
 
 	// Check if another move with high book bonus beats the search result
 	bestMove := result.BestMove
@@ -1444,7 +1795,7 @@ func searchWithBookBonus(b *Board, fen string, moves []Move, depth int, bookBonu
 		// Quick evaluation to get this move's approximate score
 		bc := b.Copy()
 		bc.MakeMove(&m)
-		moveScore, _ := sequentialAlphaBeta(bc, 3, 0, -Infinity, Infinity, bc.SideToMove == White, true)
+		moveScore, _ := sequentialAlphaBeta(newSearchState(context.Background(), DefaultSearchOptions(1)), bc, 3, 0, -Infinity, Infinity, bc.SideToMove == White, true)
 		if b.SideToMove == Black {
 			moveScore = -moveScore
 		}
@@ -1462,7 +1813,9 @@ func searchWithBookBonus(b *Board, fen string, moves []Move, depth int, bookBonu
 	return SearchResult{
 		BestMove: bestMove,
 		Score:    result.Score, // Return actual search score, not bonus-inflated
-		Depth:    depth,
+		Depth:    result.Depth,
+		MaxDepth: result.MaxDepth,
+		PV:       principalVariation(b, bestMove, result.Depth),
 		Metrics: SearchMetrics{
 			NodesSearched: result.Metrics.NodesSearched,
 			ElapsedMs:     time.Since(start).Milliseconds(),
@@ -1555,7 +1908,7 @@ func getTopMovesWithScores(b *Board, moves []Move, n int) ([]Move, []int) {
 	for _, m := range moves {
 		bc := b.Copy()
 		bc.MakeMove(&m)
-		score, _ := sequentialAlphaBeta(bc, 3, 0, -Infinity, Infinity, bc.SideToMove == White, true)
+		score, _ := sequentialAlphaBeta(newSearchState(context.Background(), DefaultSearchOptions(1)), bc, 3, 0, -Infinity, Infinity, bc.SideToMove == White, true)
 		if b.SideToMove == Black {
 			score = -score
 		}
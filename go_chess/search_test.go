@@ -1,6 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,3 +65,802 @@ func TestParallelSearchDepth5(t *testing.T) {
 		t.Errorf("Search took too long: %v", elapsed)
 	}
 }
+
+// legalMovesFromArray is GenerateLegalMoves using the array generator and
+// array attack detection, as before bitboards
+func legalMovesFromArray(b *Board) []Move {
+	var legal []Move
+	for _, m := range b.GenerateMovesFromArray() {
+		b.MakeMove(&m)
+		if !b.IsAttacked(b.KingSquare[b.SideToMove.Opponent()], b.SideToMove) {
+			legal = append(legal, m)
+		}
+		b.UnmakeMove(&m)
+	}
+	return legal
+}
+
+// Reference perft counts (chessprogramming.org "Perft Results")
+var perftPositions = []struct {
+	name  string
+	fen   string
+	depth int
+	nodes uint64
+}{
+	{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 4, 197281},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 3, 97862},
+	{"position3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 4, 43238},
+	{"position4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 3, 9467},
+	{"position5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 3, 62379},
+}
+
+func TestPerftBitboardMatchesArray(t *testing.T) {
+	for _, p := range perftPositions {
+		b, err := ParseFEN(p.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		fen := b.ToFEN()
+		bbNodes := perft(b, p.depth)
+		arrayNodes := perftWith(b, p.depth, legalMovesFromArray)
+
+		if bbNodes != p.nodes || arrayNodes != p.nodes {
+			t.Errorf("%s depth %d: bitboard=%d array=%d want %d", p.name, p.depth, bbNodes, arrayNodes, p.nodes)
+		}
+		if b.ToFEN() != fen {
+			t.Errorf("%s: board not restored after perft: %s", p.name, b.ToFEN())
+		}
+		incremental := b.BB
+		b.SyncBitboards()
+		if incremental != b.BB {
+			t.Errorf("%s: bitboards out of sync with squares after perft", p.name)
+		}
+	}
+}
+
+func TestPerftDivideMatchesReference(t *testing.T) {
+	for _, p := range perftPositions {
+		b, err := ParseFEN(p.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		expected, ok := perftExpected(b, p.depth)
+		if !ok || expected != p.nodes {
+			t.Fatalf("%s: reference for depth %d = %d (%v), want %d", p.name, p.depth, expected, ok, p.nodes)
+		}
+		var nodes uint64
+		for _, count := range perftDivide(b, p.depth) {
+			nodes += count
+		}
+		if nodes != p.nodes {
+			t.Errorf("%s: divide sums to %d, want %d", p.name, nodes, p.nodes)
+		}
+	}
+}
+
+func BenchmarkPerftBitboard(bm *testing.B) {
+	b := NewBoard()
+	for i := 0; i < bm.N; i++ {
+		perft(b, 4)
+	}
+}
+
+func BenchmarkPerftArray(bm *testing.B) {
+	b := NewBoard()
+	for i := 0; i < bm.N; i++ {
+		perftWith(b, 4, legalMovesFromArray)
+	}
+}
+
+func TestKingSafetyPenalizesExposedKing(t *testing.T) {
+	sheltered, _ := ParseFEN("r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2NP1N2/PPP2PPP/R1BQ1RK1 w - - 0 1")
+	exposed, _ := ParseFEN("r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2NP1N2/PPP2P2/R1BQ1RK1 w - - 0 1")
+	if s, e := kingSafetyScore(sheltered, White), kingSafetyScore(exposed, White); e >= s {
+		t.Errorf("king safety without g/h pawns = %d, want below sheltered %d", e, s)
+	}
+	if e := EvaluateBreakdown(sheltered); e.Total() != Evaluate(sheltered) {
+		t.Errorf("breakdown total %d != Evaluate %d", e.Total(), Evaluate(sheltered))
+	}
+}
+
+func TestPawnStructureScores(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int // White's pawnStructureScore
+	}{
+		{"passed on 6th", "4k3/8/3P4/8/8/8/8/4K3 w - - 0 1", passedPawnBonus[5] - isolatedPawnPenalty},
+		{"doubled isolated", "4k3/p7/8/8/8/3P4/3P4/4K3 w - - 0 1", -doubledPawnPenalty - 2*isolatedPawnPenalty + passedPawnBonus[1] + passedPawnBonus[2]},
+		{"backward", "4k3/8/8/8/2P1p3/8/3P4/4K3 w - - 0 1", passedPawnBonus[3] - backwardPawnPenalty},
+	}
+	for _, tt := range tests {
+		b, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := pawnStructureScore(b, White); got != tt.want {
+			t.Errorf("%s: pawnStructureScore = %d, want %d", tt.name, got, tt.want)
+		}
+		if got, want := pawnStructure(b), tt.want-pawnStructureScore(b, Black); got != want {
+			t.Errorf("%s: cached pawnStructure = %d, want %d", tt.name, got, want)
+		}
+	}
+}
+
+func TestChess960(t *testing.T) {
+	if fen := NewChess960Board(518).ToFEN(); fen != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1" {
+		t.Errorf("position 518 = %s, want the standard setup", fen)
+	}
+	if fen := NewChess960Board(0).ToFEN(); fen != "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1" {
+		t.Errorf("position 0 = %s", fen)
+	}
+
+	// Reference counts from the Chess960 perft suite
+	positions := []struct {
+		fen   string
+		nodes uint64 // Depth 3
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", 12189},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", 18002},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", 10471},
+		{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", 13440},
+	}
+	for _, p := range positions {
+		b, err := ParseFEN(p.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", p.fen, err)
+		}
+		if got := b.ToFEN(); got != p.fen {
+			t.Errorf("ToFEN = %s, want %s", got, p.fen)
+		}
+		if got := perft(b, 3); got != p.nodes {
+			t.Errorf("%s: perft(3) = %d, want %d", p.fen, got, p.nodes)
+		}
+		if got := perftWith(b, 3, legalMovesFromArray); got != p.nodes {
+			t.Errorf("%s: array perft(3) = %d, want %d", p.fen, got, p.nodes)
+		}
+	}
+
+	// Castling parses as king-to-square or king-to-rook on both boards
+	b, _ := ParseFEN("4k3/8/8/8/8/8/8/1R2K2R w HB - 0 1")
+	for _, s := range []string{"e1g1", "e1h1"} {
+		m, ok := b.ParseMove(s)
+		if castle, kingside := b.IsCastle(m); !ok || !castle || !kingside {
+			t.Errorf("Chess960 ParseMove(%s) = %v, %v; want kingside castling", s, m, ok)
+		}
+	}
+	std, _ := ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	for _, s := range []string{"e1g1", "e1h1"} {
+		if m, ok := std.ParseMove(s); !ok || m.To != 6 {
+			t.Errorf("ParseMove(%s) = %v, %v; want e1g1", s, m, ok)
+		}
+	}
+}
+
+func TestHistoryNavigation(t *testing.T) {
+	g := &Game{Board: NewBoard()}
+	for _, s := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+		m, ok := g.Board.ParseMove(s)
+		if !ok {
+			t.Fatalf("ParseMove(%s) failed", s)
+		}
+		g.Board.MakeMove(&m)
+		g.History = append(g.History, m)
+		g.MoveTimes = append(g.MoveTimes, 0)
+	}
+	end := g.Board.ToFEN()
+
+	g.seekPly(1)
+	if len(g.History) != 1 || len(g.Forward) != 3 || !g.reviewing() {
+		t.Fatalf("after seek to 1: history %d, forward %d", len(g.History), len(g.Forward))
+	}
+	if g.LastMove.String() != "e2e4" {
+		t.Errorf("LastMove = %s, want e2e4", g.LastMove)
+	}
+	if g.stepBack(); g.Board.ToFEN() != NewBoard().ToFEN() || g.stepBack() {
+		t.Errorf("stepping back past the start: %s", g.Board.ToFEN())
+	}
+
+	g.seekPly(g.totalPlies())
+	if g.Board.ToFEN() != end || g.reviewing() || len(g.MoveTimes) != 4 {
+		t.Errorf("back at the end: %s, reviewing=%v", g.Board.ToFEN(), g.reviewing())
+	}
+
+	g.stepBack()
+	g.resumeFromReview()
+	if g.reviewing() || g.totalPlies() != 3 {
+		t.Errorf("resume kept %d forward plies", len(g.Forward))
+	}
+}
+
+func TestBoardEditor(t *testing.T) {
+	b := NewEmptyBoard()
+	if _, err := EditStatus(b); err == nil {
+		t.Error("empty board validated")
+	}
+	for _, p := range [][2]string{{"K", "e1"}, {"k", "e8"}, {"R", "h1"}, {"q", "a6"}} {
+		if err := EditPlace(b, p[0], p[1]); err != nil {
+			t.Fatalf("EditPlace(%s, %s): %v", p[0], p[1], err)
+		}
+	}
+	if err := EditPlace(b, "x", "e4"); err == nil {
+		t.Error("unknown piece accepted")
+	}
+	if err := EditCastling(b, "K"); err != nil {
+		t.Fatalf("EditCastling(K): %v", err)
+	}
+	if err := EditCastling(b, "Q"); err == nil || b.Castling != 0 {
+		t.Errorf("castling without a queenside rook: err=%v rights=%d", err, b.Castling)
+	}
+	EditCastling(b, "K")
+	if _, err := EditStatus(b); err != nil {
+		t.Errorf("valid position rejected: %v", err)
+	}
+	if fen := b.ToFEN(); fen != "4k3/8/q7/8/8/8/8/4K2R w K - 0 1" {
+		t.Errorf("FEN = %s", fen)
+	}
+
+	// The queen checks the white king: with Black to move White is in check
+	EditPlace(b, "q", "a1")
+	b.SideToMove = Black
+	if _, err := EditStatus(b); err == nil {
+		t.Error("side not to move in check validated")
+	}
+	EditPlace(b, ".", "a1")
+	if _, err := EditStatus(b); err != nil {
+		t.Errorf("cleared square: %v", err)
+	}
+}
+
+func TestTournamentRecord(t *testing.T) {
+	r := &TournamentRecord{}
+	r.Add(ResultWhiteWins, 60)
+	r.Add(ResultDraw, 100)
+	r.Add(ResultBlackWins, 80)
+	r.Add(ResultUnknown, 10) // Aborted games are not counted
+	if r.Games() != 3 || r.Wins != 1 || r.Draws != 1 || r.Losses != 1 {
+		t.Fatalf("record = %+v", r)
+	}
+	if got := r.AverageLength(); got != 40 {
+		t.Errorf("AverageLength = %v, want 40", got)
+	}
+	if got := r.DecisivePercent(); int(got) != 66 {
+		t.Errorf("DecisivePercent = %v, want 66.7", got)
+	}
+}
+
+func TestBookEditing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chess_book.json")
+	book := &OpeningBook{Source: "test", Positions: make(map[string]BookPosition), filepath: path}
+	b := NewBoard()
+	fen := b.ToFEN()
+
+	if err := book.AddBookMove(b, "e2e4"); err != nil {
+		t.Fatalf("AddBookMove(e2e4): %v", err)
+	}
+	if err := book.AddBookMove(b, "e2e4"); err == nil {
+		t.Error("duplicate book move accepted")
+	}
+	if err := book.AddBookMove(b, "e2e5"); err == nil {
+		t.Error("illegal book move accepted")
+	}
+	book.AddBookMove(b, "d2d4")
+
+	pos, ok := book.LookupHash(b.ZobristHash())
+	if !ok || len(pos.Moves) != 2 || pos.Moves[0].SAN != "e4" || pos.Moves[0].MasterGames != 1 {
+		t.Fatalf("hash index not rebuilt: %+v", pos)
+	}
+
+	if err := book.SetBookMoveWeight(fen, "d2d4", 50); err != nil {
+		t.Fatalf("SetBookMoveWeight: %v", err)
+	}
+	if err := book.RemoveBookMove(fen, "e2e4"); err != nil {
+		t.Fatalf("RemoveBookMove: %v", err)
+	}
+	if err := book.RemoveBookMove(fen, "e2e4"); err == nil {
+		t.Error("removing a missing move succeeded")
+	}
+
+	saved := loadBook(path)
+	moves := saved.GetBookMoves(fen)
+	if len(moves) != 1 || moves[0].UCI != "d2d4" || moves[0].MasterGames != 50 {
+		t.Errorf("saved book moves = %+v", moves)
+	}
+}
+
+func TestUCICommands(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader(strings.Join([]string{
+		"uci",
+		"isready",
+		"setoption name Threads value 1",
+		"position startpos moves e2e4 e7e5",
+		"go depth 2",
+		"position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+		"go depth 3",
+		"position startpos moves e2e5",
+		"quit",
+	}, "\n")), &out)
+	got := out.String()
+
+	for _, want := range []string{"uciok\n", "readyok\n", "info depth 2 score cp ", "score mate 1 ", "bestmove a1a8\n", "info string illegal move: e2e5\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "bestmove "); n != 2 {
+		t.Errorf("%d bestmove lines, want 2:\n%s", n, got)
+	}
+}
+
+func TestSearchStops(t *testing.T) {
+	// Stop just after depth 7 completes: depth 8 takes longer than all of
+	// depth 7, so the search must notice the stop inside the tree
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const delay = 10 * time.Millisecond
+	opts := DefaultSearchOptions(1)
+	opts.MaxDepth = 64
+	start := time.Now()
+	var reached time.Time
+	opts.DepthHook = func(r SearchResult) {
+		if r.Depth == 7 {
+			reached = time.Now()
+			time.AfterFunc(delay, cancel)
+		}
+	}
+
+	result := SearchContext(ctx, NewBoard(), opts)
+	if result.Depth != 7 || result.BestMove.IsNull() {
+		t.Fatalf("stopped at depth %d with %s, want depth 7's move", result.Depth, result.BestMove)
+	}
+	took := reached.Sub(start)
+	if d := time.Since(reached) - delay; d > took/4 && d > 50*time.Millisecond {
+		t.Errorf("stop took %v; depth 7 took %v", d, took)
+	}
+}
+
+func TestSyzygyIndexTables(t *testing.T) {
+	if tbMapPawns[8] != 47 || tbMapPawns[15] != 46 || tbMapPawns[16] != 45 {
+		t.Errorf("MapPawns a2 h2 a3 = %d %d %d, want 47 46 45", tbMapPawns[8], tbMapPawns[15], tbMapPawns[16])
+	}
+	if tbBinomial[2][4] != 6 || tbBinomial[5][63] != 7028847 {
+		t.Errorf("binomials %d %d", tbBinomial[2][4], tbBinomial[5][63])
+	}
+
+	// Every placement of three unique pieces, or of the two kings, maps
+	// into the leading group's index range
+	unique := &tbTable{uniquePiece: true}
+	kings := &tbTable{}
+	maxUnique, maxKings := uint64(0), uint64(0)
+	for s0 := 0; s0 < 64; s0++ {
+		for s1 := 0; s1 < 64; s1++ {
+			if s1 == s0 {
+				continue
+			}
+			if kingAttacks[s0]&(1<<uint(s1)) == 0 {
+				sq := []int{s0, s1}
+				mirrorFiles(sq)
+				mirrorLeading(sq, 2)
+				maxKings = max64(maxKings, kings.encodeLeading(sq))
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				if s2 == s0 || s2 == s1 {
+					continue
+				}
+				sq := []int{s0, s1, s2}
+				mirrorFiles(sq)
+				mirrorLeading(sq, 3)
+				maxUnique = max64(maxUnique, unique.encodeLeading(sq))
+			}
+		}
+	}
+	if maxUnique != 31331 || maxKings != 461 {
+		t.Errorf("highest leading indexes %d and %d, want 31331 and 461", maxUnique, maxKings)
+	}
+
+	SetSyzygyPath("")
+	if _, _, err := SyzygyLookup(NewBoard()); err != ErrNoTablebase {
+		t.Errorf("lookup without tables: %v", err)
+	}
+}
+
+// writeKQvK writes a KQvK table holding a single value for each side to
+// move it stores: the smallest file the prober reads
+func writeKQvK(t *testing.T, dir, ext string, magic uint32, flags, values []byte) {
+	t.Helper()
+	data := make([]byte, 80) // Headers, then the (empty) data at 64
+	binary.LittleEndian.PutUint32(data, magic)
+	data[4] = 1                              // Not symmetric, no pawns
+	data[5] = 0                              // The leading group first
+	copy(data[6:], []byte{0x66, 0x55, 0xEE}) // K, Q, k for each side to move
+	pos := 10
+	for i, v := range values {
+		data[pos] = flags[i] | tbFlagSingleValue
+		data[pos+1] = v
+		pos += 2
+	}
+	if err := os.WriteFile(filepath.Join(dir, "KQvK"+ext), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyzygyKQvK(t *testing.T) {
+	dir := t.TempDir()
+	// White to move wins, Black to move loses (but for captures), and
+	// the DTZ table holds White to move 9 plies from zeroing
+	writeKQvK(t, dir, ".rtbw", tbWDLMagic, []byte{0, 0}, []byte{tbWin + 2, tbLoss + 2})
+	writeKQvK(t, dir, ".rtbz", tbDTZMagic, []byte{0}, []byte{4})
+	SetSyzygyPath(dir)
+	defer SetSyzygyPath("")
+
+	tests := []struct {
+		fen      string
+		dtz, wdl int
+	}{
+		{"8/8/8/8/8/2k5/8/1Q5K w - - 0 1", 9, tbWin},
+		{"k7/8/8/8/8/8/8/Q6K b - - 0 1", -10, tbLoss}, // One ply more than White's replies
+		{"8/8/8/8/8/2k5/1Q6/7K b - - 0 1", 0, tbDraw}, // Kxb2 leaves two kings
+	}
+	for _, tt := range tests {
+		b, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dtz, wdl, err := SyzygyLookup(b)
+		if err != nil || dtz != tt.dtz || wdl != tt.wdl {
+			t.Errorf("%s: DTZ %d WDL %d (%v), want %d %d", tt.fen, dtz, wdl, err, tt.dtz, tt.wdl)
+		}
+
+		// The search's probe resolves the capture too, leaving the board
+		// as it was
+		want := 0
+		if tt.wdl != tbDraw {
+			want = KnownWinScore // White wins from either side to move
+		}
+		if score, ok := tablebaseWDLScore(b); !ok || score != want || b.ToFEN() != tt.fen {
+			t.Errorf("%s: search probe %d %v, leaving %s", tt.fen, score, ok, b.ToFEN())
+		}
+	}
+
+	// At the root the DTZ tables pick the move: never one hanging the queen
+	b, _ := ParseFEN(tests[0].fen)
+	opts := DefaultSearchOptions(1)
+	opts.MaxDepth = 2
+	result := SearchContext(context.Background(), b, opts)
+	if result.Score != KnownWinScore-9 {
+		t.Errorf("root score %d, want %d", result.Score, KnownWinScore-9)
+	}
+	b.MakeMove(&result.BestMove)
+	if _, wdl, err := SyzygyLookup(b); err != nil || wdl != tbLoss {
+		t.Errorf("root move %s leaves WDL %d (%v), want a loss for Black", result.BestMove, wdl, err)
+	}
+
+	// With the half-move clock that high the 50-move rule comes first:
+	// the win is a draw
+	b, _ = ParseFEN("8/8/8/8/8/2k5/8/1Q5K w - - 95 1")
+	if result := SearchContext(context.Background(), b, opts); result.Score != 0 {
+		t.Errorf("root score %d with 95 half-moves played, want a draw", result.Score)
+	}
+	b, _ = ParseFEN("8/8/8/8/8/2k5/8/1Q5K w - - 80 1")
+	if result := SearchContext(context.Background(), b, opts); result.Score != KnownWinScore-9 {
+		t.Errorf("root score %d with 80 half-moves played, want %d", result.Score, KnownWinScore-9)
+	}
+}
+
+func mirrorFiles(sq []int) {
+	if sq[0]%8 > 3 {
+		for i := range sq {
+			sq[i] ^= 7
+		}
+	}
+}
+
+func max64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func TestTimeLimits(t *testing.T) {
+	opts := DefaultSearchOptions(1)
+	if soft, hard := opts.timeLimits(); soft != 0 || hard != 0 {
+		t.Errorf("untimed search has limits %v/%v", soft, hard)
+	}
+
+	opts.TimeLimit = 2 * time.Second
+	if soft, hard := opts.timeLimits(); soft != time.Second || hard != 2*time.Second {
+		t.Errorf("TimeLimit 2s: limits %v/%v", soft, hard)
+	}
+
+	opts.TimeLimit = 0
+	opts.RemainingTime = 60 * time.Second
+	soft, hard := opts.timeLimits()
+	if soft != calculateMoveTime(60*time.Second, 0, 0) || hard != 3*soft {
+		t.Errorf("60s clock: limits %v/%v", soft, hard)
+	}
+	if _, hard := calculateTimeLimits(time.Second, time.Second, 1); hard != 500*time.Millisecond {
+		t.Errorf("hard limit %v exceeds half the clock", hard)
+	}
+
+	opts.SoftLimit = time.Millisecond
+	if !shouldStop(time.Now().Add(-2*time.Millisecond), opts) || shouldStop(time.Now(), opts) {
+		t.Error("shouldStop ignores SoftLimit")
+	}
+
+	// A search cut short by the hard limit reports the depth it reached
+	b := NewBoard()
+	opts = DefaultSearchOptions(2)
+	opts.MaxDepth = 40
+	opts.SoftLimit, opts.HardLimit = 20*time.Millisecond, 200*time.Millisecond
+	r := Search(b, opts)
+	if r.MaxDepth != 40 || r.Depth >= 40 || r.Depth < 1 || r.BestMove.IsNull() {
+		t.Errorf("time-limited search: depth %d/%d, move %s", r.Depth, r.MaxDepth, r.BestMove)
+	}
+	if !strings.Contains(RenderSearchInfo(r), fmt.Sprintf("Depth: %d/40", r.Depth)) {
+		t.Errorf("search info: %s", RenderSearchInfo(r))
+	}
+}
+
+func TestPrincipalVariation(t *testing.T) {
+	ttClear()
+	b := NewBoard()
+	opts := DefaultSearchOptions(1)
+	opts.MaxDepth = 4
+	r := Search(b, opts)
+	if len(r.PV) < 2 || r.PV[0].String() != r.BestMove.String() || len(r.PV) > r.Depth {
+		t.Fatalf("PV %v for best move %s at depth %d", r.PV, r.BestMove, r.Depth)
+	}
+
+	// Every PV move is legal in turn
+	board := b.Copy()
+	for _, m := range r.PV {
+		legal, ok := board.ParseMove(m.String())
+		if !ok {
+			t.Fatalf("PV move %s is illegal", m)
+		}
+		board.MakeMove(&legal)
+	}
+
+	r.PV = append(r.PV, make([]Move, pvDisplayMoves)...)
+	info := RenderSearchInfo(r)
+	if !strings.Contains(info, "PV: "+r.PV[0].String()+" ") || !strings.Contains(info, " ... |") {
+		t.Errorf("search info: %s", info)
+	}
+}
+
+func TestTTStats(t *testing.T) {
+	ttClear()
+	if ttStats.stores.Load() != 0 || ttOccupancy(1000) != 0 {
+		t.Fatal("ttClear left entries or counts behind")
+	}
+
+	move := Move{From: 12, To: 28}
+	ttStore(1, 4, 25, TTFlagExact, move)
+	ttStore(1+TTSize, 2, 10, TTFlagExact, move) // Same slot, another position
+	if _, _, hit := ttProbe(1+TTSize, 2, -Infinity, Infinity, White); !hit {
+		t.Error("probe after store missed")
+	}
+	if _, _, hit := ttProbe(1, 2, -Infinity, Infinity, White); hit {
+		t.Error("probe of the replaced position hit")
+	}
+	if ttStats.stores.Load() != 2 || ttStats.collisions.Load() != 1 ||
+		ttStats.hits.Load() != 1 || ttStats.misses.Load() != 1 {
+		t.Errorf("stats: %d stores, %d collisions, %d hits, %d misses", ttStats.stores.Load(),
+			ttStats.collisions.Load(), ttStats.hits.Load(), ttStats.misses.Load())
+	}
+	ttClear()
+}
+
+func TestEvalBreakdownTempo(t *testing.T) {
+	white := NewBoard()
+	black := NewBoard()
+	black.SideToMove = Black
+	w, bl := EvaluateBreakdown(white), EvaluateBreakdown(black)
+	if w.Tempo != TempoBonus || bl.Tempo != -TempoBonus || w.Total()-w.Tempo != bl.Total()-bl.Tempo {
+		t.Errorf("tempo %d/%d, totals %d/%d", w.Tempo, bl.Tempo, w.Total(), bl.Total())
+	}
+
+	out := RenderEvalBreakdown(white, w)
+	for _, line := range []string{"Material:      +0\n", "Tempo:         +10\n", fmt.Sprintf("Total:         %+d\n", w.Total())} {
+		if !strings.Contains(out, line) {
+			t.Errorf("breakdown missing %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestPrepLine(t *testing.T) {
+	line, err := ParsePrepLine("sicilian.pgn", "[Event \"Prep\"]\n\n1. e4 c5 2. Nf3 d6 3. d4 cxd4 4. Nxd4 *\n")
+	if err != nil || len(line.Moves) != 7 {
+		t.Fatalf("PGN line: %v, %v", line, err)
+	}
+
+	// The same line as a FEN sequence
+	var fens []string
+	for ply := 0; ply <= 3; ply++ {
+		fens = append(fens, line.boardAt(ply).ToFEN())
+	}
+	fromFEN, err := ParsePrepLine("sicilian.fen", strings.Join(fens, "\n"))
+	if err != nil || len(fromFEN.Moves) != 3 || !movesEqual(fromFEN.Moves[2], line.Moves[2]) {
+		t.Fatalf("FEN line: %v, %v", fromFEN, err)
+	}
+	if _, err := ParsePrepLine("bad.fen", fens[0]+"\n"+fens[2]); err == nil {
+		t.Error("FEN sequence skipping a move was accepted")
+	}
+
+	// A drill starts from White's move in the middle of the line
+	for i := 0; i < 20; i++ {
+		ply := line.drillPly()
+		if ply < 1 || ply > 5 || line.boardAt(ply).SideToMove != White {
+			t.Fatalf("drill ply %d", ply)
+		}
+	}
+
+	// Two plies into the line, 2. Nf3 comes next
+	s := &PrepSession{Line: line, Offset: 1}
+	g := &Game{Board: line.boardAt(2), History: line.Moves[1:2]}
+	if want, ok := s.expected(g); !ok || MoveToSAN(g.Board, want) != "Nf3" {
+		t.Errorf("expected %v, %v", want, ok)
+	}
+	out := RenderPrepLine(s, g)
+	if !strings.Contains(out, "  1. e4 c5\n") || !strings.Contains(out, "Remaining (5 plies):\n  2. Nf3 d6\n") {
+		t.Errorf("prep line:\n%s", out)
+	}
+
+	g = &Game{Board: line.boardAt(7), History: line.Moves}
+	if (&PrepSession{Line: line}).inLine(g) || (*PrepSession)(nil).inLine(g) {
+		t.Error("completed line still in progress")
+	}
+}
+
+func TestRenderGameList(t *testing.T) {
+	mated, _ := ParseFEN("rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	a := &Game{Board: NewBoard(), Buffer: "*chess*"}
+	b := &Game{Board: mated, Buffer: "*chess-2*"}
+
+	out := RenderGameList([]*Game{b, a}, b)
+	first, second := strings.Index(out, "*chess*: "), strings.Index(out, "*chess-2* [current]: move 3, 0-1 (checkmate)")
+	if first < 0 || second < first || strings.Count(out, "FEN: ") != 2 {
+		t.Errorf("game list:\n%s", out)
+	}
+}
+
+func TestNullMoveSettings(t *testing.T) {
+	defer SetNullMoveDefaults(NullMoveDefaults())
+	if opts := DefaultSearchOptions(1); !opts.EnableNullMove || opts.NullMoveReduction != NullMoveR || opts.NullMoveVerification {
+		t.Errorf("default null move: %v R=%d verify %v", opts.EnableNullMove, opts.NullMoveReduction, opts.NullMoveVerification)
+	}
+	SetNullMoveDefaults(0, true)
+	if opts := DefaultSearchOptions(1); opts.EnableNullMove || !opts.NullMoveVerification {
+		t.Error("reduction 0 leaves null-move pruning on")
+	}
+
+	// Zugzwang-prone and tactical positions: pruning must not change the
+	// best move
+	positions := []struct{ fen, best string }{
+		{"1q1k4/2Rr4/8/2Q3K1/8/8/8/8 w - - 0 1", "g5h6"},
+		{"8/6B1/p5p1/Pp4kp/1P5r/5P1Q/4q1PK/8 w - - 0 32", "g7f6"},
+		{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", "h5f7"},
+	}
+	configs := []struct {
+		enable bool
+		r      int
+		verify bool
+	}{{false, 0, false}, {true, 2, false}, {true, 3, false}, {true, 3, true}}
+	for _, p := range positions {
+		for _, c := range configs {
+			b, _ := ParseFEN(p.fen)
+			ttClear()
+			opts := DefaultSearchOptions(1)
+			opts.MaxDepth = 5
+			opts.EnableNullMove, opts.NullMoveReduction, opts.NullMoveVerification = c.enable, c.r, c.verify
+			if r := Search(b, opts); r.BestMove.String() != p.best {
+				t.Errorf("%s with %+v: best %s, want %s", p.fen, c, r.BestMove, p.best)
+			}
+		}
+	}
+	opts := DefaultSearchOptions(1)
+	opts.EnableNullMove, opts.NullMoveReduction = false, 3
+	if nm := newSearchState(context.Background(), opts).nullMove; nm.R != 0 {
+		t.Errorf("disabled null move searches with %+v", nm)
+	}
+}
+
+func TestImportPGNGames(t *testing.T) {
+	dir := t.TempDir()
+	prev := globalBook
+	defer func() { globalBook = prev }()
+	globalBook = &OpeningBook{Source: "test", Positions: make(map[string]BookPosition), filepath: filepath.Join(dir, "chess_book.json")}
+
+	collection := `[Event "One"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0
+
+[Event "Two"]
+[Result "1/2-1/2"]
+
+1. d4 d5 1/2-1/2
+
+[Event "Unfinished"]
+[Result "*"]
+
+1. c4 *
+
+[Event "Broken"]
+[Result "0-1"]
+
+1. e4 e4 0-1
+
+[Event "Set up"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+[Result "1-0"]
+
+1. Rh8+ Kd7 1-0
+`
+	path := filepath.Join(dir, "games.pgn")
+	if err := os.WriteFile(path, []byte(collection), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if games := splitPGNGames(collection); len(games) != 5 {
+		t.Fatalf("split into %d games", len(games))
+	}
+
+	n, err := ImportPGNGames(path)
+	if err != nil || n != 2 {
+		t.Fatalf("ImportPGNGames = %d, %v; want 2 games", n, err)
+	}
+	pos, ok := globalBook.LookupHash(NewBoard().ZobristHash())
+	if !ok || len(pos.Moves) != 2 || len(globalBook.Games) != 2 {
+		t.Fatalf("start position after import: %+v (%d games)", pos, len(globalBook.Games))
+	}
+	if _, err := os.Stat(globalBook.filepath); err != nil {
+		t.Errorf("book not saved: %v", err)
+	}
+}
+
+func TestKPKProbe(t *testing.T) {
+	tests := []struct {
+		fen     string
+		want    int // WDL for White
+		covered bool
+	}{
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", WDLWin, true},     // King on the 6th in front of the pawn
+		{"8/4k3/8/4K3/4P3/8/8/8 b - - 0 1", WDLWin, true},     // White has the opposition
+		{"8/4k3/8/4K3/4P3/8/8/8 w - - 0 1", WDLDraw, true},    // Black has the opposition
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", WDLDraw, true},       // Rook pawn, defender in the corner
+		{"8/8/8/4p3/4k3/8/4K3/8 w - - 0 1", WDLLoss, true},    // Mirrored: Black's pawn wins
+		{"8/8/8/4p3/4k3/8/4K3/8 b - - 0 1", WDLDraw, true},    // Mirrored draw
+		{"4k3/8/4K3/4P3/8/8/8/7N w - - 0 1", WDLDraw, false},  // Extra knight
+		{"4k3/8/4K3/4P3/4P3/8/8/8 w - - 0 1", WDLDraw, false}, // Two pawns
+		{"4k3/8/4K3/8/8/8/8/8 w - - 0 1", WDLDraw, false},     // Bare kings
+	}
+	for _, tt := range tests {
+		b, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		score, ok := probeEndgame(b)
+		if ok != tt.covered {
+			t.Errorf("%s: covered %v, want %v", tt.fen, ok, tt.covered)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		var wdl int
+		switch {
+		case score >= KnownWinScore:
+			wdl = WDLWin
+		case score <= -KnownWinScore:
+			wdl = WDLLoss
+		case score != 0:
+			t.Errorf("%s: score %d is neither a known win nor a draw", tt.fen, score)
+		}
+		if wdl != tt.want {
+			t.Errorf("%s: score %d, want WDL %d", tt.fen, score, tt.want)
+		}
+	}
+}
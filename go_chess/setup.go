@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interactive position setup
+// Commands: "we4" (white pawn e4), "bKe8" (black king e8), "clear", "start",
+// "white"/"black" (side to move), "done"
+
+// setupPieces maps a piece letter to its white piece (pawn when omitted)
+var setupPieces = map[byte]Piece{
+	'K': WKing,
+	'Q': WQueen,
+	'R': WRook,
+	'B': WBishop,
+	'N': WKnight,
+	'P': WPawn,
+}
+
+// NewEmptyBoard creates a board with no pieces and White to move
+func NewEmptyBoard() *Board {
+	b := &Board{
+		SideToMove: White,
+		EnPassant:  NoSquare,
+		FullMoves:  1,
+	}
+	b.KingSquare[White] = NoSquare
+	b.KingSquare[Black] = NoSquare
+	return b
+}
+
+// ParseSquare parses algebraic notation ("e4") into a square
+func ParseSquare(s string) (Square, bool) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare, false
+	}
+	return FromRankFile(int(s[1]-'1'), int(s[0]-'a')), true
+}
+
+// SquareName returns the algebraic name of a square ("e4")
+func SquareName(sq Square) string {
+	return string([]byte{byte('a' + sq.File()), byte('1' + sq.Rank())})
+}
+
+// ApplySetupCommand applies a single placement command to the board.
+// Returns done=true when the user typed "done".
+func ApplySetupCommand(b *Board, cmd string) (done bool, err error) {
+	cmd = strings.TrimSpace(cmd)
+
+	switch strings.ToLower(cmd) {
+	case "done":
+		return true, nil
+	case "clear":
+		*b = *NewEmptyBoard()
+		return false, nil
+	case "start":
+		*b = *NewBoard()
+		return false, nil
+	case "white":
+		b.SideToMove = White
+		return false, nil
+	case "black":
+		b.SideToMove = Black
+		return false, nil
+	}
+
+	// Placement: color, optional piece letter, square
+	if len(cmd) != 3 && len(cmd) != 4 {
+		return false, fmt.Errorf("unknown command: %s", cmd)
+	}
+
+	var color Color
+	switch cmd[0] {
+	case 'w':
+		color = White
+	case 'b':
+		color = Black
+	default:
+		return false, fmt.Errorf("color must be w or b: %s", cmd)
+	}
+
+	piece := WPawn
+	sqStr := cmd[1:]
+	if len(cmd) == 4 {
+		p, ok := setupPieces[strings.ToUpper(cmd[1:2])[0]]
+		if !ok {
+			return false, fmt.Errorf("unknown piece '%c' (use K, Q, R, B, N or P)", cmd[1])
+		}
+		piece = p
+		sqStr = cmd[2:]
+	}
+
+	sq, ok := ParseSquare(sqStr)
+	if !ok {
+		return false, fmt.Errorf("invalid square: %s", sqStr)
+	}
+
+	if color == Black {
+		piece += BPawn - WPawn
+	}
+	b.SetSquare(sq, piece)
+	return false, nil
+}
+
+// ValidatePosition checks that a set-up position is playable and fixes up
+// derived state (king squares, castling rights, clocks).
+func ValidatePosition(b *Board) error {
+	if err := validatePlacement(b); err != nil {
+		return err
+	}
+
+	// Castling rights only where king and rook are still on their home squares
+	b.Castling = 0
+	if b.Squares[4] == WKing {
+		if b.Squares[7] == WRook {
+			b.Castling |= CastleWK
+		}
+		if b.Squares[0] == WRook {
+			b.Castling |= CastleWQ
+		}
+	}
+	if b.Squares[60] == BKing {
+		if b.Squares[63] == BRook {
+			b.Castling |= CastleBK
+		}
+		if b.Squares[56] == BRook {
+			b.Castling |= CastleBQ
+		}
+	}
+
+	b.EnPassant = NoSquare
+	b.HalfMoves = 0
+	b.FullMoves = 1
+	b.History = nil
+
+	return nil
+}
+
+// validatePlacement checks the pieces of a position (one king per side, no
+// pawns on the back ranks, the side not to move not in check) and sets the
+// king squares
+func validatePlacement(b *Board) error {
+	var kings [2]int
+	for sq := Square(0); sq < 64; sq++ {
+		p := b.Squares[sq]
+		switch p {
+		case WKing, BKing:
+			kings[p.Color()]++
+			b.KingSquare[p.Color()] = sq
+		case WPawn, BPawn:
+			if sq.Rank() == 0 || sq.Rank() == 7 {
+				return fmt.Errorf("pawn on back rank at %s", SquareName(sq))
+			}
+		}
+	}
+
+	for _, c := range []Color{White, Black} {
+		if kings[c] == 0 {
+			return fmt.Errorf("%s has no king", colorName(c))
+		}
+		if kings[c] > 1 {
+			return fmt.Errorf("%s has %d kings (exactly one required)", colorName(c), kings[c])
+		}
+	}
+
+	them := b.SideToMove.Opponent()
+	if b.IsAttacked(b.KingSquare[them], b.SideToMove) {
+		return fmt.Errorf("%s is in check but it is not their move", colorName(them))
+	}
+	return nil
+}
+
+// Board editor (chess-edit): pieces are placed one at a time on a copy of
+// the position and the result is validated after every change
+
+// EditPlace puts the piece named by pc (a FEN letter such as "N" or "k",
+// or "." to clear) on the square named by sq. Any en passant square is
+// dropped since it only follows a move.
+func EditPlace(b *Board, pc, sq string) error {
+	if len(pc) != 1 {
+		return fmt.Errorf("piece must be one of PNBRQK pnbrqk or .")
+	}
+	piece, ok := fenPieces[pc[0]]
+	if pc[0] == '.' {
+		piece, ok = Empty, true
+	}
+	if !ok {
+		return fmt.Errorf("unknown piece '%s' (use PNBRQK, pnbrqk or .)", pc)
+	}
+	square, ok := ParseSquare(sq)
+	if !ok {
+		return fmt.Errorf("invalid square: %s", sq)
+	}
+	b.SetSquare(square, piece)
+	b.EnPassant = NoSquare
+	return nil
+}
+
+// EditCastling replaces the castling rights with a FEN castling field
+// ("KQkq", "HAha" or "-")
+func EditCastling(b *Board, flags string) error {
+	b.Castling = 0
+	b.Chess960 = false
+	if flags == "-" || flags == "" {
+		return nil
+	}
+	for i := 0; i < len(flags); i++ {
+		if err := b.addCastlingRight(flags[i]); err != nil {
+			b.Castling = 0
+			b.Chess960 = false
+			return err
+		}
+	}
+	return nil
+}
+
+// EditStatus validates an edited position (one king per side, no pawns on
+// the back ranks, castling rights and en passant square consistent, the
+// side not to move not in check) and describes the result
+func EditStatus(b *Board) (string, error) {
+	if err := validateFEN(b); err != nil {
+		return fmt.Sprintf("Invalid: %v", err), err
+	}
+	return "Valid position", nil
+}
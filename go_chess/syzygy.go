@@ -0,0 +1,1245 @@
+package main
+
+// Syzygy endgame tablebase probing.
+//
+// Reads the .rtbw (win/draw/loss) and .rtbz (distance to zeroing) files of
+// Ronald de Man's generator, following the layout used by Stockfish's
+// prober: a table per material signature ("KRPvKR"), split by the file of
+// the leading pawn when there are pawns, each part a Huffman-coded stream
+// of values compressed by recursive pairing. Files are memory mapped on
+// first use. Positions with castling rights are not in the tables.
+//
+// WDL values are from the side to move: 2 win, 1 win that the 50-move rule
+// turns into a draw ("cursed"), 0 draw, -1 loss saved by the 50-move rule
+// ("blessed"), -2 loss. DTZ is the number of plies to the next capture or
+// pawn move on the best line, negative when losing; 100 is added for
+// cursed wins and blessed losses.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Syzygy WDL values, from the side to move
+const (
+	tbLoss        = -2
+	tbBlessedLoss = -1
+	tbDraw        = 0
+	tbCursedWin   = 1
+	tbWin         = 2
+)
+
+// syzygyMaxPieces is the most pieces (kings included) probed
+const syzygyMaxPieces = 6
+
+// Table flags stored with each part of a table
+const (
+	tbFlagSTM         = 1
+	tbFlagMapped      = 2
+	tbFlagWinPlies    = 4
+	tbFlagLossPlies   = 8
+	tbFlagWide        = 16
+	tbFlagSingleValue = 128
+)
+
+// File magics (the first four bytes, little endian)
+const (
+	tbWDLMagic = 0x5d23e871
+	tbDTZMagic = 0xa50c66d7
+)
+
+var (
+	// ErrNoTablebase means the position is not covered by a table on disk
+	ErrNoTablebase = errors.New("no tablebase for this position")
+
+	// ErrNoDTZ means the WDL table was found but not the DTZ one; the
+	// WDL value is still valid
+	ErrNoDTZ = errors.New("no DTZ tablebase for this position")
+)
+
+// tbPieceChars maps piece types to the letters of table names
+const tbPieceChars = " PNBRQK"
+
+// Index tables, filled in by init
+var (
+	tbMapPawns      [64]int     // Squares a2-h7 to 0..47, leading pawn highest
+	tbMapB1H1H7     [64]int     // Squares below the a1-h8 diagonal to 0..27
+	tbMapA1D1D4     [64]int     // The a1-d1-d4 triangle to 0..9, diagonal last
+	tbMapKK         [10][64]int // Legal king pairs, first king in the triangle
+	tbBinomial      [6][64]uint64
+	tbLeadPawnIdx   [6][64]uint64
+	tbLeadPawnsSize [6][4]uint64
+)
+
+func init() {
+	code := 0
+	for s := 0; s < 64; s++ {
+		if offA1H8(s) < 0 {
+			tbMapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for s := 0; s <= 27; s++ { // a1..d4
+		if offA1H8(s) < 0 && s%8 <= 3 {
+			tbMapA1D1D4[s] = code
+			code++
+		} else if offA1H8(s) == 0 && s%8 <= 3 {
+			diagonal = append(diagonal, s)
+		}
+	}
+	for _, s := range diagonal {
+		tbMapA1D1D4[s] = code
+		code++
+	}
+
+	// 462 king pairs: if the first king is on the diagonal the second must
+	// not be above it; pairs with both on the diagonal come last
+	type pair struct{ idx, sq int }
+	var bothOnDiagonal []pair
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if tbMapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) { // b1 maps to 0
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case s1 == s2 || kingAttacks[s1]&(1<<uint(s2)) != 0:
+					// Illegal
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+					// First on the diagonal, second above it
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, pair{idx, s2})
+				default:
+					tbMapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		tbMapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	// Binomial[k][n]: ways to choose k of n squares
+	tbBinomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				tbBinomial[k][n] += tbBinomial[k-1][n-1]
+			}
+			if k < n {
+				tbBinomial[k][n] += tbBinomial[k][n-1]
+			}
+		}
+	}
+
+	// The leading pawn is the one nearest the edge and, on the same file,
+	// the lowest; the others can only be on squares mapped below it
+	available := 47
+	for leadPawns := 1; leadPawns <= 5; leadPawns++ {
+		for f := 0; f < 4; f++ {
+			idx := uint64(0)
+			for r := 1; r <= 6; r++ {
+				sq := r*8 + f
+				if leadPawns == 1 {
+					tbMapPawns[sq] = available
+					available--
+					tbMapPawns[sq^7] = available
+					available--
+				}
+				tbLeadPawnIdx[leadPawns][sq] = idx
+				idx += tbBinomial[leadPawns-1][tbMapPawns[sq]]
+			}
+			tbLeadPawnsSize[leadPawns][f] = idx
+		}
+	}
+}
+
+// offA1H8 is positive above the a1-h8 diagonal, negative below it
+func offA1H8(sq int) int {
+	return sq/8 - sq%8
+}
+
+// tbPairs is the decoding information of one part of a table: a side to
+// move and, with pawns, a leading pawn file
+type tbPairs struct {
+	flags      uint8
+	maxSymLen  int
+	minSymLen  int // The value itself for single value tables
+	numBlocks  int
+	blockLens  int // Block length entries: numBlocks plus padding
+	blockSize  int
+	span       int
+	lowestSym  int // Offsets into the file data
+	btree      int
+	blockLen   int
+	sparse     int
+	sparseSize int
+	data       int
+	base64     []uint64
+	symLen     []uint8
+	pieces     [syzygyMaxPieces]int // Syzygy piece codes: 1-6 white, 9-14 black
+	groupIdx   [syzygyMaxPieces + 1]uint64
+	groupLen   [syzygyMaxPieces + 1]int
+	mapIdx     [4]int // DTZ value maps for win, loss, cursed win, blessed loss
+}
+
+// tbTable is one .rtbw or .rtbz file
+type tbTable struct {
+	dtz         bool
+	name        string // "KRvK": the first side is White in the table
+	path        string // Directories searched for the file
+	pieceCount  int
+	hasPawns    bool
+	symmetric   bool   // Both sides have the same pieces
+	uniquePiece bool   // Some side has a single piece besides its king
+	pawnCount   [2]int // Leading color, other color
+
+	once   sync.Once
+	err    error
+	data   []byte
+	dtzMap int
+	parts  [2][4]tbPairs // [side to move][leading pawn file]
+}
+
+// part returns the decoding information for a side to move and file
+func (t *tbTable) part(stm, file int) *tbPairs {
+	if t.dtz {
+		stm = 0 // DTZ tables hold one side
+	}
+	if !t.hasPawns {
+		file = 0
+	}
+	return &t.parts[stm][file]
+}
+
+// tbCache holds the tables of one tablebase path found so far, by table
+// name (".rtbz" appended for DTZ); nil for names looked up and not found.
+// The search reads it at every probe, so it takes no lock: a new path gets
+// a new cache.
+type tbCache struct {
+	path   string
+	tables sync.Map
+}
+
+// syzygyCache is the cache of the current path, nil when none is set
+var syzygyCache atomic.Pointer[tbCache]
+
+// SetSyzygyPath sets the directories holding tablebase files (separated
+// by ':') and forgets the tables opened so far. Their mappings are left
+// in place: a search may still be reading them.
+func SetSyzygyPath(path string) {
+	if path == "" {
+		syzygyCache.Store(nil)
+		return
+	}
+	syzygyCache.Store(&tbCache{path: path})
+}
+
+// materialCode returns the pieces of a color as in table names: "KRP"
+func materialCode(b *Board, c Color) string {
+	b.ensureBitboards()
+	var sb strings.Builder
+	for t := 6; t >= 1; t-- {
+		p := Piece(t)
+		if c == Black {
+			p += BPawn - WPawn
+		}
+		sb.WriteString(strings.Repeat(string(tbPieceChars[t]), bits.OnesCount64(*b.BB.of(p))))
+	}
+	return sb.String()
+}
+
+// findTable returns the table covering a position's material, and whether
+// Black holds the pieces of the table's first side
+func findTable(b *Board, dtz bool) (*tbTable, bool) {
+	cache := syzygyCache.Load()
+	if cache == nil {
+		return nil, false
+	}
+	w, bl := materialCode(b, White), materialCode(b, Black)
+
+	for _, flip := range []bool{false, true} {
+		name := w + "v" + bl
+		if flip {
+			name = bl + "v" + w
+		}
+		key := name
+		if dtz {
+			key += ".rtbz"
+		}
+		v, seen := cache.tables.Load(key)
+		if !seen {
+			v, _ = cache.tables.LoadOrStore(key, newTable(cache.path, name, dtz))
+		}
+		if t := v.(*tbTable); t != nil {
+			return t, flip
+		}
+	}
+	return nil, false
+}
+
+// newTable looks for a table file on path, returning nil if missing
+func newTable(path, name string, dtz bool) *tbTable {
+	ext := ".rtbw"
+	if dtz {
+		ext = ".rtbz"
+	}
+	found := false
+	for _, dir := range filepath.SplitList(path) {
+		if _, err := os.Stat(filepath.Join(dir, name+ext)); err == nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	first, second, _ := strings.Cut(name, "v")
+	t := &tbTable{
+		dtz:        dtz,
+		name:       name,
+		path:       path,
+		pieceCount: len(first) + len(second),
+		symmetric:  first == second,
+	}
+	for _, side := range []string{first, second} {
+		for _, ch := range "QRBNP" {
+			if strings.Count(side, string(ch)) == 1 {
+				t.uniquePiece = true
+			}
+		}
+	}
+	p0, p1 := strings.Count(first, "P"), strings.Count(second, "P")
+	t.hasPawns = p0+p1 > 0
+	// The leading color is the one with fewer pawns (but some)
+	if p1 == 0 || (p0 > 0 && p1 >= p0) {
+		t.pawnCount = [2]int{p0, p1}
+	} else {
+		t.pawnCount = [2]int{p1, p0}
+	}
+	return t
+}
+
+// load maps the table file and reads its headers, once
+func (t *tbTable) load() error {
+	t.once.Do(func() {
+		t.err = t.mapFile()
+		if t.err == nil {
+			t.err = t.parse()
+		}
+	})
+	return t.err
+}
+
+// mapFile memory maps the table file and checks its magic
+func (t *tbTable) mapFile() error {
+	ext, magic := ".rtbw", uint32(tbWDLMagic)
+	if t.dtz {
+		ext, magic = ".rtbz", tbDTZMagic
+	}
+	for _, dir := range filepath.SplitList(t.path) {
+		f, err := os.Open(filepath.Join(dir, t.name+ext))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Size() < 16 || info.Size()%64 != 16 {
+			return fmt.Errorf("%s%s: bad size %d", t.name, ext, info.Size())
+		}
+		data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+		if err != nil {
+			return fmt.Errorf("%s%s: %v", t.name, ext, err)
+		}
+		if binary.LittleEndian.Uint32(data) != magic {
+			syscall.Munmap(data)
+			return fmt.Errorf("%s%s: corrupted table", t.name, ext)
+		}
+		t.data = data
+		return nil
+	}
+	return fmt.Errorf("%s%s: not found", t.name, ext)
+}
+
+// parse reads the piece orders, group sizes and section offsets
+func (t *tbTable) parse() (err error) {
+	// A truncated file shows up as an index out of range
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: corrupted table", t.name)
+		}
+	}()
+
+	data := t.data
+	pos := 4
+	const split, hasPawns = 1, 2
+	if (data[pos]&hasPawns != 0) != t.hasPawns || (data[pos]&split != 0) == t.symmetric {
+		return fmt.Errorf("%s: table does not match its name", t.name)
+	}
+	pos++
+
+	sides := 1
+	if !t.dtz && !t.symmetric {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0 // Pawns on both sides
+
+	for f := 0; f <= maxFile; f++ {
+		order := [2][2]int{{int(data[pos] & 0xF), 0xF}, {int(data[pos] >> 4), 0xF}}
+		if pp {
+			order[0][1], order[1][1] = int(data[pos+1]&0xF), int(data[pos+1]>>4)
+		}
+		pos++
+		if pp {
+			pos++
+		}
+		for k := 0; k < t.pieceCount; k, pos = k+1, pos+1 {
+			for i := 0; i < sides; i++ {
+				piece := data[pos] & 0xF
+				if i == 1 {
+					piece = data[pos] >> 4
+				}
+				t.parts[i][f].pieces[k] = int(piece)
+			}
+		}
+		for i := 0; i < sides; i++ {
+			t.setGroups(&t.parts[i][f], order[i], f)
+		}
+	}
+	pos += pos & 1
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = t.setSizes(&t.parts[i][f], pos)
+		}
+	}
+
+	if t.dtz {
+		t.dtzMap = pos
+		for f := 0; f <= maxFile; f++ {
+			d := &t.parts[0][f]
+			if d.flags&tbFlagMapped == 0 {
+				continue
+			}
+			if d.flags&tbFlagWide != 0 {
+				pos += pos & 1
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = (pos-t.dtzMap)/2 + 1
+					pos += 2*int(binary.LittleEndian.Uint16(data[pos:])) + 2
+				}
+			} else {
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = pos - t.dtzMap + 1
+					pos += int(data[pos]) + 1
+				}
+			}
+		}
+		pos += pos & 1
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := &t.parts[i][f]
+			d.sparse = pos
+			pos += d.sparseSize * 6
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := &t.parts[i][f]
+			d.blockLen = pos
+			pos += d.blockLens * 2
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := &t.parts[i][f]
+			pos = (pos + 0x3F) &^ 0x3F
+			d.data = pos
+			pos += d.numBlocks * d.blockSize
+		}
+	}
+	if pos > len(data) {
+		return fmt.Errorf("%s: truncated table", t.name)
+	}
+	return nil
+}
+
+// setGroups groups the pieces encoded together: the leading group (pawns,
+// or three unique pieces, or the two kings), the other color's pawns, then
+// runs of identical pieces, and computes each group's index factor in the
+// table's encoding order
+func (t *tbTable) setGroups(d *tbPairs, order [2]int, file int) {
+	n := 0
+	firstLen := 0
+	if !t.hasPawns {
+		firstLen = 2
+		if t.uniquePiece {
+			firstLen = 3
+		}
+	}
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch k {
+		case order[0]: // Leading pawns or pieces
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= tbLeadPawnsSize[d.groupLen[0]][file]
+			case t.uniquePiece:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case order[1]: // The other color's pawns
+			d.groupIdx[1] = idx
+			idx *= tbBinomial[d.groupLen[1]][48-d.groupLen[0]]
+		default: // Other pieces
+			d.groupIdx[next] = idx
+			idx *= tbBinomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// setSizes reads a part's Huffman code header, returning the offset after
+// it
+func (t *tbTable) setSizes(d *tbPairs, pos int) int {
+	data := t.data
+	d.flags = data[pos]
+	pos++
+	if d.flags&tbFlagSingleValue != 0 {
+		d.minSymLen = int(data[pos])
+		return pos + 1
+	}
+
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.blockSize = 1 << data[pos]
+	d.span = 1 << data[pos+1]
+	d.sparseSize = int((tbSize + uint64(d.span) - 1) / uint64(d.span))
+	d.numBlocks = int(binary.LittleEndian.Uint32(data[pos+3:]))
+	d.blockLens = d.numBlocks + int(data[pos+2]) // Padded for the sparse index
+	d.maxSymLen = int(data[pos+7])
+	d.minSymLen = int(data[pos+8])
+	pos += 9
+	d.lowestSym = pos
+
+	lowest := func(i int) uint64 {
+		return uint64(binary.LittleEndian.Uint16(data[d.lowestSym+2*i:]))
+	}
+	// Longer codes have lower values: base64[l] is the lowest code of
+	// length minSymLen+l, left-aligned in 64 bits
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + lowest(i) - lowest(i+1)) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+	pos += len(d.base64) * 2
+
+	d.symLen = make([]uint8, binary.LittleEndian.Uint16(data[pos:]))
+	pos += 2
+	d.btree = pos
+
+	visited := make([]bool, len(d.symLen))
+	for sym := range d.symLen {
+		if !visited[sym] {
+			d.symLen[sym] = t.setSymLen(d, sym, visited)
+		}
+	}
+	return pos + len(d.symLen)*3 + len(d.symLen)&1
+}
+
+// btreePair returns the two symbols a symbol expands into; a leaf has
+// right symbol 0xFFF and its value on the left
+func (t *tbTable) btreePair(d *tbPairs, sym int) (int, int) {
+	p := t.data[d.btree+3*sym:]
+	left := int(p[1]&0xF)<<8 | int(p[0])
+	right := int(p[2])<<4 | int(p[1]>>4)
+	return left, right
+}
+
+// setSymLen returns how many values (minus one) a symbol stands for
+func (t *tbTable) setSymLen(d *tbPairs, sym int, visited []bool) uint8 {
+	visited[sym] = true
+	left, right := t.btreePair(d, sym)
+	if right == 0xFFF {
+		return 0
+	}
+	if !visited[left] {
+		d.symLen[left] = t.setSymLen(d, left, visited)
+	}
+	if !visited[right] {
+		d.symLen[right] = t.setSymLen(d, right, visited)
+	}
+	return d.symLen[left] + d.symLen[right] + 1
+}
+
+// decompress returns the value stored at an index of a part
+func (t *tbTable) decompress(d *tbPairs, idx uint64) int {
+	if d.flags&tbFlagSingleValue != 0 {
+		return d.minSymLen
+	}
+	data := t.data
+
+	// The sparse index points into the block lengths every span values,
+	// at the middle of the span
+	k := idx / uint64(d.span)
+	entry := data[d.sparse+6*int(k):]
+	block := int(binary.LittleEndian.Uint32(entry))
+	offset := int(binary.LittleEndian.Uint16(entry[4:]))
+	offset += int(idx%uint64(d.span)) - d.span/2
+
+	blockLen := func(i int) int {
+		return int(binary.LittleEndian.Uint16(data[d.blockLen+2*i:]))
+	}
+	for offset < 0 {
+		block--
+		offset += blockLen(block) + 1
+	}
+	for offset > blockLen(block) {
+		offset -= blockLen(block) + 1
+		block++
+	}
+
+	// Walk the canonical Huffman codes of the block to the symbol holding
+	// the offset
+	ptr := d.data + block*d.blockSize
+	buf := binary.BigEndian.Uint64(data[ptr:])
+	ptr += 8
+	bufSize := 64
+	var sym int
+	for {
+		l := 0
+		for buf < d.base64[l] {
+			l++
+		}
+		sym = int((buf - d.base64[l]) >> uint(64-l-d.minSymLen))
+		sym += int(binary.LittleEndian.Uint16(data[d.lowestSym+2*l:]))
+		if offset < int(d.symLen[sym])+1 {
+			break
+		}
+		offset -= int(d.symLen[sym]) + 1
+		l += d.minSymLen
+		buf <<= uint(l)
+		bufSize -= l
+		if bufSize <= 32 {
+			bufSize += 32
+			buf |= uint64(binary.BigEndian.Uint32(data[ptr:])) << uint(64-bufSize)
+			ptr += 4
+		}
+	}
+
+	// Expand the pairs down to the value
+	for d.symLen[sym] != 0 {
+		left, right := t.btreePair(d, sym)
+		if offset < int(d.symLen[left])+1 {
+			sym = left
+		} else {
+			offset -= int(d.symLen[left]) + 1
+			sym = right
+		}
+	}
+	left, _ := t.btreePair(d, sym)
+	return left
+}
+
+// probe reads a position's value from a table. For DTZ tables, ok is false
+// when the table holds the other side to move; wdl selects the value map.
+func (t *tbTable) probe(b *Board, blackFirst bool, wdl int) (value int, ok bool) {
+	if err := t.load(); err != nil {
+		return 0, false
+	}
+
+	// Tables hold the first side as White and, for symmetric material,
+	// only White to move: otherwise swap colors and mirror the ranks
+	flip := blackFirst || (t.symmetric && b.SideToMove == Black)
+	flipColor, flipSquares, stm := 0, 0, int(b.SideToMove)
+	if flip {
+		flipColor, flipSquares, stm = 8, 56, 1-stm
+	}
+
+	var squares [syzygyMaxPieces]int
+	var pieces [syzygyMaxPieces]int
+	size, leadPawns, file := 0, 0, 0
+	var leadBB uint64
+
+	if t.hasPawns {
+		// Pawns lead in every part and their color is the reference
+		pc := t.parts[0][0].pieces[0] ^ flipColor
+		leadColor := White
+		if pc >= 8 {
+			leadColor = Black
+		}
+		for sq, p := range b.Squares {
+			if p != Empty && p.Type() == 1 && p.Color() == leadColor {
+				leadBB |= 1 << uint(sq)
+				squares[size] = sq ^ flipSquares
+				size++
+			}
+		}
+		leadPawns = size
+		best := 0
+		for i := 1; i < leadPawns; i++ {
+			if tbMapPawns[squares[i]] > tbMapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+		file = min(squares[0]%8, 7-squares[0]%8)
+	}
+
+	if t.dtz && !t.dtzHoldsSide(stm, file) {
+		return 0, false
+	}
+
+	for sq, p := range b.Squares {
+		if p == Empty || leadBB&(1<<uint(sq)) != 0 {
+			continue
+		}
+		squares[size] = sq ^ flipSquares
+		pieces[size] = tbPiece(p) ^ flipColor
+		size++
+	}
+
+	d := t.part(stm, file)
+
+	// Order the pieces as the table does
+	for i := leadPawns; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Mirror the leading piece onto files a-d
+	if squares[0]%8 > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = tbLeadPawnIdx[leadPawns][squares[0]]
+		rest := squares[1:leadPawns]
+		sort.SliceStable(rest, func(i, j int) bool { return tbMapPawns[rest[i]] < tbMapPawns[rest[j]] })
+		for i := 1; i < leadPawns; i++ {
+			idx += tbBinomial[i][tbMapPawns[squares[i]]]
+		}
+	} else {
+		mirrorLeading(squares[:size], d.groupLen[0])
+		idx = t.encodeLeading(squares[:size])
+	}
+
+	idx *= d.groupIdx[0]
+	group := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		g := squares[group : group+d.groupLen[next]]
+		sort.Ints(g)
+		n := uint64(0)
+		for i, sq := range g {
+			// Squares after those of earlier groups are shifted down
+			adjust := 0
+			for _, s := range squares[:group] {
+				if sq > s {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += tbBinomial[i+1][sq-adjust]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		group += d.groupLen[next]
+	}
+
+	value = t.decompress(d, idx)
+	if !t.dtz {
+		return value - 2, true
+	}
+	return t.mapDTZ(d, value, wdl), true
+}
+
+// mirrorLeading finishes placing a position without pawns, its leading
+// piece already on files a-d: the leading piece goes below rank 5 and the
+// first leading piece off the a1-h8 diagonal below it
+func mirrorLeading(squares []int, leadLen int) {
+	if squares[0]/8 > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+	for i := 0; i < leadLen; i++ {
+		if offA1H8(squares[i]) == 0 {
+			continue
+		}
+		if offA1H8(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+			}
+		}
+		break
+	}
+}
+
+// encodeLeading indexes the leading group of a table without pawns:
+// three unique pieces together, or else the two kings
+func (t *tbTable) encodeLeading(sq []int) uint64 {
+	if !t.uniquePiece {
+		return uint64(tbMapKK[tbMapA1D1D4[sq[0]]][sq[1]])
+	}
+	adjust1, adjust2 := 0, 0
+	if sq[1] > sq[0] {
+		adjust1 = 1
+	}
+	if sq[2] > sq[0] {
+		adjust2++
+	}
+	if sq[2] > sq[1] {
+		adjust2++
+	}
+	switch {
+	case offA1H8(sq[0]) != 0:
+		return uint64((tbMapA1D1D4[sq[0]]*63+sq[1]-adjust1)*62 + sq[2] - adjust2)
+	case offA1H8(sq[1]) != 0:
+		return uint64((6*63+sq[0]/8*28+tbMapB1H1H7[sq[1]])*62 + sq[2] - adjust2)
+	case offA1H8(sq[2]) != 0:
+		return uint64(6*63*62 + 4*28*62 + sq[0]/8*7*28 + (sq[1]/8-adjust1)*28 + tbMapB1H1H7[sq[2]])
+	}
+	return uint64(6*63*62 + 4*28*62 + 4*7*28 + sq[0]/8*6*7 + (sq[1]/8-adjust1)*6 + sq[2]/8 - adjust2)
+}
+
+// dtzHoldsSide reports whether a DTZ table stores the given side to move
+func (t *tbTable) dtzHoldsSide(stm, file int) bool {
+	flags := t.part(0, file).flags
+	return int(flags&tbFlagSTM) == stm || (t.symmetric && !t.hasPawns)
+}
+
+// mapDTZ turns a stored DTZ value into plies
+func (t *tbTable) mapDTZ(d *tbPairs, value, wdl int) int {
+	wdlMap := [5]int{1, 3, 0, 2, 0}
+	if d.flags&tbFlagMapped != 0 {
+		i := d.mapIdx[wdlMap[wdl+2]] + value
+		if d.flags&tbFlagWide != 0 {
+			value = int(binary.LittleEndian.Uint16(t.data[t.dtzMap+2*i:]))
+		} else {
+			value = int(t.data[t.dtzMap+i])
+		}
+	}
+	if (wdl == tbWin && d.flags&tbFlagWinPlies == 0) ||
+		(wdl == tbLoss && d.flags&tbFlagLossPlies == 0) ||
+		wdl == tbCursedWin || wdl == tbBlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
+
+// tbPiece converts a piece to the tables' code: 1-6 white, 9-14 black
+func tbPiece(p Piece) int {
+	if p.Color() == Black {
+		return p.Type() + 8
+	}
+	return p.Type()
+}
+
+// probeState tells the callers of the probes how a value was found
+type probeState int
+
+const (
+	probeFail        probeState = iota
+	probeOK                     // Read from a table
+	probeZeroingBest            // The best move is a capture or pawn move
+	probeChangeSTM              // The DTZ table holds the other side to move
+)
+
+// tbPieceCount returns the number of pieces on the board
+func tbPieceCount(b *Board) int {
+	b.ensureBitboards()
+	return bits.OnesCount64(b.BB.colorOccupancy(White) | b.BB.colorOccupancy(Black))
+}
+
+// isZeroing reports whether a move resets the 50-move counter
+func isZeroing(b *Board, m Move) bool {
+	p := b.Squares[m.From]
+	return p.Type() == 1 || b.Squares[m.To] != Empty
+}
+
+// isCapture reports whether a move captures, en passant included
+func isCapture(b *Board, m Move) bool {
+	return b.Squares[m.To] != Empty || (b.Squares[m.From].Type() == 1 && m.To == b.EnPassant)
+}
+
+// probeWDLTable reads the WDL table without the capture search
+func probeWDLTable(b *Board) (int, probeState) {
+	if tbPieceCount(b) == 2 {
+		return tbDraw, probeOK
+	}
+	t, blackFirst := findTable(b, false)
+	if t == nil {
+		return 0, probeFail
+	}
+	v, ok := t.probe(b, blackFirst, tbDraw)
+	if !ok {
+		return 0, probeFail
+	}
+	return v, probeOK
+}
+
+// tbSearch resolves captures (and, for DTZ, pawn moves) before reading
+// the table: the tables store "don't care" values where such a move is
+// best, and know nothing of en passant
+func tbSearch(b *Board, zeroing bool) (int, probeState) {
+	best := tbLoss
+	moves := b.GenerateLegalMoves()
+	count := 0
+
+	for _, m := range moves {
+		if !isCapture(b, m) && (!zeroing || b.Squares[m.From].Type() != 1) {
+			continue
+		}
+		count++
+
+		b.MakeMove(&m)
+		v, state := tbSearch(b, false)
+		b.UnmakeMove(&m)
+		v = -v
+
+		if state == probeFail {
+			return tbDraw, probeFail
+		}
+		if v > best {
+			best = v
+			if v >= tbWin {
+				return v, probeZeroingBest
+			}
+		}
+	}
+
+	// With every legal move searched, the table value could be wrong
+	// (an en passant position, say)
+	noMoreMoves := count > 0 && count == len(moves)
+	var v int
+	if noMoreMoves {
+		v = best
+	} else {
+		var state probeState
+		if v, state = probeWDLTable(b); state == probeFail {
+			return tbDraw, probeFail
+		}
+	}
+
+	if best >= v {
+		if best > tbDraw || noMoreMoves {
+			return best, probeZeroingBest
+		}
+		return best, probeOK
+	}
+	return v, probeOK
+}
+
+// dtzBeforeZeroing is the DTZ of a position whose best move zeroes
+func dtzBeforeZeroing(wdl int) int {
+	switch wdl {
+	case tbWin:
+		return 1
+	case tbCursedWin:
+		return 101
+	case tbBlessedLoss:
+		return -101
+	case tbLoss:
+		return -1
+	}
+	return 0
+}
+
+// sign returns -1, 0 or 1
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// probeDTZ returns the DTZ of a position, searching one ply when the
+// table holds the other side to move
+func probeDTZ(b *Board) (int, probeState) {
+	wdl, state := tbSearch(b, true)
+	if state == probeFail || wdl == tbDraw {
+		return 0, state
+	}
+	if state == probeZeroingBest {
+		return dtzBeforeZeroing(wdl), probeOK
+	}
+
+	t, blackFirst := findTable(b, true)
+	if t == nil {
+		return 0, probeFail
+	}
+	if dtz, ok := t.probe(b, blackFirst, wdl); ok {
+		if wdl == tbBlessedLoss || wdl == tbCursedWin {
+			dtz += 100
+		}
+		return dtz * sign(wdl), probeOK
+	}
+	if t.err != nil {
+		return 0, probeFail
+	}
+
+	// The table holds the other side: find the best reply's DTZ
+	minDTZ := 0xFFFF
+	for _, m := range b.GenerateLegalMoves() {
+		zeroing := isZeroing(b, m) || isCapture(b, m)
+
+		b.MakeMove(&m)
+		var dtz int
+		if zeroing {
+			v, s := tbSearch(b, false)
+			dtz, state = -dtzBeforeZeroing(v), s
+		} else {
+			dtz, state = probeDTZ(b)
+			dtz = -dtz
+		}
+		mates := dtz == 1 && b.InCheck() && len(b.GenerateLegalMoves()) == 0
+		b.UnmakeMove(&m)
+
+		if state == probeFail {
+			return 0, probeFail
+		}
+		if mates {
+			minDTZ = 1
+		}
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+		if dtz < minDTZ && sign(dtz) == sign(wdl) {
+			minDTZ = dtz
+		}
+	}
+	if minDTZ == 0xFFFF {
+		return -1, probeOK // Mated
+	}
+	return minDTZ, probeOK
+}
+
+// SyzygyLookup probes the tablebases for a position with at most six
+// pieces and no castling rights, returning its DTZ and WDL from the side
+// to move. With only the WDL table on disk it returns ErrNoDTZ and a
+// valid WDL.
+func SyzygyLookup(b *Board) (DTZ int, WDL int, err error) {
+	if !tablebaseCovers(b) {
+		return 0, 0, ErrNoTablebase
+	}
+	b = b.Copy()
+
+	wdl, state := tbSearch(b, false)
+	if state == probeFail {
+		return 0, 0, ErrNoTablebase
+	}
+	dtz, state := probeDTZ(b)
+	if state == probeFail {
+		return 0, wdl, ErrNoDTZ
+	}
+	return dtz, wdl, nil
+}
+
+// tablebaseCovers reports whether a position could be in the tablebases
+// on the current path
+func tablebaseCovers(b *Board) bool {
+	return syzygyCache.Load() != nil && b.Castling == 0 && tbPieceCount(b) <= syzygyMaxPieces
+}
+
+// tablebaseError returns why the WDL table for a position's material
+// could not be read, or nil
+func tablebaseError(b *Board) error {
+	t, _ := findTable(b, false)
+	if t == nil {
+		return nil
+	}
+	return t.load()
+}
+
+// tablebaseScore is the evaluation of a tablebase position from White's
+// perspective: wins just below KnownWinScore, nearer zeroing moves better;
+// results the 50-move rule turns into draws count as draws
+func tablebaseScore(b *Board) (int, bool) {
+	dtz, wdl, err := SyzygyLookup(b)
+	if err != nil && !errors.Is(err, ErrNoDTZ) {
+		return 0, false
+	}
+	score := 0
+	switch wdl {
+	case tbWin:
+		score = KnownWinScore - min(abs(dtz), 100)
+	case tbLoss:
+		score = -KnownWinScore + min(abs(dtz), 100)
+	}
+	if b.SideToMove == Black {
+		score = -score
+	}
+	return score, true
+}
+
+// tablebaseWDLScore is the search's score for a tablebase position from
+// White's perspective, read from the WDL tables only: wins and losses at
+// KnownWinScore, results the 50-move rule turns into draws as draws. The
+// captures resolved before reading the table are made and unmade on b.
+func tablebaseWDLScore(b *Board) (int, bool) {
+	if !tablebaseCovers(b) {
+		return 0, false
+	}
+	wdl, state := tbSearch(b, false)
+	if state == probeFail {
+		return 0, false
+	}
+	score := 0
+	switch wdl {
+	case tbWin:
+		score = KnownWinScore
+	case tbLoss:
+		score = -KnownWinScore
+	}
+	if b.SideToMove == Black {
+		score = -score
+	}
+	return score, true
+}
+
+// tablebaseRootMove picks the move to play in a tablebase position with
+// the DTZ tables: the one keeping the best result and, among wins, the
+// soonest capture or pawn move (among losses, the latest). A win or loss
+// the 50-move rule reaches first, counting the half-move clock, ranks as a
+// cursed win or blessed loss and scores as a draw. It returns the move and
+// the position's score from White's perspective; ok is false when a table
+// is missing.
+func tablebaseRootMove(b *Board) (best Move, score int, ok bool) {
+	if _, _, err := SyzygyLookup(b); err != nil {
+		return Move{}, 0, false
+	}
+	b = b.Copy()
+
+	bestRank, bestWDL := 0, tbDraw
+	for _, m := range b.GenerateLegalMoves() {
+		zeroing := isZeroing(b, m) || isCapture(b, m)
+		b.MakeMove(&m)
+		mates := b.IsCheckmate()
+		dtz, wdl, err := SyzygyLookup(b)
+		halfMoves := b.HalfMoves
+		b.UnmakeMove(&m)
+		if err != nil && !mates {
+			return Move{}, 0, false
+		}
+
+		// From the mover's side: result first, then distance to zeroing
+		wdl, dtz = -wdl, abs(dtz)
+		if dtz+halfMoves > 100 {
+			switch wdl {
+			case tbWin:
+				wdl = tbCursedWin
+			case tbLoss:
+				wdl = tbBlessedLoss
+			}
+		}
+		if mates {
+			wdl = tbWin
+		}
+		rank := wdl * 10000
+		switch {
+		case mates:
+			rank = 3 * 10000
+		case wdl > tbDraw && zeroing:
+			rank += 1000
+		case wdl > tbDraw:
+			rank -= dtz
+		case wdl < tbDraw && !zeroing:
+			rank += dtz
+		}
+		if best.IsNull() || rank > bestRank {
+			best, bestRank, bestWDL = m, rank, wdl
+		}
+	}
+	if best.IsNull() {
+		return Move{}, 0, false
+	}
+	if bestWDL != tbWin && bestWDL != tbLoss {
+		return best, 0, true
+	}
+	score, _ = tablebaseScore(b)
+	return best, score, true
+}
+
+// WDLName describes a Syzygy WDL value
+func WDLName(wdl int) string {
+	switch wdl {
+	case tbWin:
+		return "win"
+	case tbCursedWin:
+		return "win, drawn by the 50-move rule"
+	case tbBlessedLoss:
+		return "loss, drawn by the 50-move rule"
+	case tbLoss:
+		return "loss"
+	}
+	return "draw"
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Self-play tournament: N engine-vs-engine games with exploration on and
+// asymmetric contempt, each one feeding the learning book
+
+// DefaultTournamentGames is the number of games when none is given
+const DefaultTournamentGames = 10
+
+// tournamentDelayMs is the pause between moves (time for display updates)
+const tournamentDelayMs = 50
+
+// Tournament contempt as draw values: White aggressive, Black neutral
+const (
+	tournamentWhiteDrawValue = 0.3
+	tournamentBlackDrawValue = 0.5
+)
+
+// TournamentRecord tabulates tournament results from White's perspective
+type TournamentRecord struct {
+	Wins       int // White wins
+	Draws      int
+	Losses     int // Black wins
+	TotalPlies int
+	Started    time.Time
+}
+
+// Games returns the number of finished games
+func (r *TournamentRecord) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Add records a finished game of plies half-moves
+func (r *TournamentRecord) Add(result GameResult, plies int) {
+	switch result {
+	case ResultWhiteWins:
+		r.Wins++
+	case ResultBlackWins:
+		r.Losses++
+	case ResultDraw:
+		r.Draws++
+	default:
+		return
+	}
+	r.TotalPlies += plies
+}
+
+// AverageLength returns the mean game length in moves (move pairs)
+func (r *TournamentRecord) AverageLength() float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	return float64(r.TotalPlies) / 2 / float64(r.Games())
+}
+
+// DecisivePercent returns the share of games that did not end drawn
+func (r *TournamentRecord) DecisivePercent() float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	return 100 * float64(r.Wins+r.Losses) / float64(r.Games())
+}
+
+// RenderTournament formats a tournament summary for *chess-tournament*
+func RenderTournament(r *TournamentRecord, planned int, stopped bool) string {
+	var sb strings.Builder
+
+	sb.WriteString("Self-play tournament\n")
+	sb.WriteString(fmt.Sprintf("Contempt: White %dcp, Black %dcp\n\n",
+		contemptFromDrawValue(tournamentWhiteDrawValue), contemptFromDrawValue(tournamentBlackDrawValue)))
+
+	sb.WriteString(fmt.Sprintf("Games:     %d of %d", r.Games(), planned))
+	if stopped {
+		sb.WriteString(" (stopped)")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("White:     +%d =%d -%d\n", r.Wins, r.Draws, r.Losses))
+	sb.WriteString(fmt.Sprintf("Decisive:  %.0f%%\n", r.DecisivePercent()))
+	sb.WriteString(fmt.Sprintf("Avg moves: %.1f\n", r.AverageLength()))
+	if !r.Started.IsZero() {
+		sb.WriteString(fmt.Sprintf("Time:      %s\n", time.Since(r.Started).Round(time.Second)))
+	}
+
+	return sb.String()
+}
+
+// contemptFromDrawValue converts a draw value to contempt in centipawns,
+// as SetAsymmetricContempt does
+func contemptFromDrawValue(dv float64) int {
+	if c := int((0.5 - dv) * 100); c > 0 {
+		return c
+	}
+	return 0
+}
@@ -0,0 +1,344 @@
+package main
+
+// UCI (Universal Chess Interface) front end.
+//
+// Build a standalone engine for Arena, Scid, cutechess and other UCI GUIs
+// with:
+//
+//	go build -tags uci_main -o chess_uci .
+//
+// Inside the editor, chess-uci-mode runs the same loop on a named pipe.
+// Supported commands: uci, isready, ucinewgame, setoption (Threads, Depth,
+// SyzygyPath),
+// position [startpos | fen F] [moves ...], go [depth N] [movetime MS]
+// [wtime MS btime MS winc MS binc MS movestogo N] [infinite], stop, quit.
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uciDefaultDepth is the search depth when "go" gives no depth or time
+const uciDefaultDepth = 6
+
+// uciMaxDepth bounds "go infinite" and timed searches
+const uciMaxDepth = 64
+
+// uciEngine holds the state kept between UCI commands
+type uciEngine struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	board   *Board
+	depth   int
+	workers int
+
+	// Running search: cancel stops it, done closes once bestmove is sent
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newUCIEngine(out io.Writer) *uciEngine {
+	return &uciEngine{
+		out:     out,
+		board:   NewBoard(),
+		depth:   uciDefaultDepth,
+		workers: runtime.NumCPU(),
+	}
+}
+
+// send writes one line; the search goroutine writes too
+func (u *uciEngine) send(format string, args ...interface{}) {
+	u.outMu.Lock()
+	defer u.outMu.Unlock()
+	fmt.Fprintf(u.out, format+"\n", args...)
+}
+
+// handleUCICommand processes one command line; returns false on "quit"
+func (u *uciEngine) handleUCICommand(cmd string) bool {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return true
+	}
+	args := fields[1:]
+
+	switch fields[0] {
+	case "uci":
+		u.send("id name go_chess")
+		u.send("id author muEmacs extensions")
+		u.send("option name Threads type spin default %d min 1 max 256", runtime.NumCPU())
+		u.send("option name Depth type spin default %d min 1 max %d", uciDefaultDepth, uciMaxDepth)
+		u.send("option name SyzygyPath type string default <empty>")
+		u.send("uciok")
+	case "isready":
+		u.send("readyok")
+	case "ucinewgame":
+		u.waitSearch()
+		ttClear()
+		u.board = NewBoard()
+	case "setoption":
+		u.setOption(args)
+	case "position":
+		u.waitSearch()
+		if err := u.setPosition(args); err != nil {
+			u.send("info string %v", err)
+		}
+	case "go":
+		u.waitSearch()
+		u.startSearch(args)
+	case "stop":
+		u.stopSearch()
+	case "quit":
+		u.stopSearch()
+		return false
+	case "debug", "register", "ponderhit":
+		// Acknowledged, nothing to do
+	default:
+		u.send("info string unknown command: %s", fields[0])
+	}
+	return true
+}
+
+// setOption handles "setoption name N value V"
+func (u *uciEngine) setOption(args []string) {
+	var name, value string
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "name":
+			name = args[i+1]
+		case "value":
+			value = args[i+1]
+			if strings.EqualFold(name, "SyzygyPath") {
+				// Paths may hold spaces
+				value = strings.Join(args[i+1:], " ")
+			}
+		}
+	}
+	if strings.EqualFold(name, "SyzygyPath") {
+		if value == "<empty>" {
+			value = ""
+		}
+		SetSyzygyPath(value)
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return
+	}
+	switch strings.ToLower(name) {
+	case "threads":
+		u.workers = n
+	case "depth":
+		u.depth = minInt(n, uciMaxDepth)
+	}
+}
+
+// setPosition handles "position startpos|fen F [moves m1 m2 ...]"
+func (u *uciEngine) setPosition(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("position needs startpos or fen")
+	}
+
+	var board *Board
+	rest := args[1:]
+	switch args[0] {
+	case "startpos":
+		board = NewBoard()
+	case "fen":
+		end := len(rest)
+		for i, a := range rest {
+			if a == "moves" {
+				end = i
+				break
+			}
+		}
+		b, err := ParseFEN(strings.Join(rest[:end], " "))
+		if err != nil {
+			return fmt.Errorf("invalid fen: %v", err)
+		}
+		board, rest = b, rest[end:]
+	default:
+		return fmt.Errorf("position needs startpos or fen, got %s", args[0])
+	}
+
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, s := range rest[1:] {
+			m, ok := board.ParseMove(s)
+			if !ok {
+				return fmt.Errorf("illegal move: %s", s)
+			}
+			board.MakeMove(&m)
+		}
+	}
+	u.board = board
+	return nil
+}
+
+// searchOptions turns the arguments of "go" into search options
+func (u *uciEngine) searchOptions(args []string) SearchOptions {
+	opts := DefaultSearchOptions(u.workers)
+	opts.MaxDepth = u.depth
+
+	timed := false
+	var clock [2]time.Duration
+	var inc [2]time.Duration
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			opts.MaxDepth = uciMaxDepth
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+		ms := time.Duration(n) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			opts.MaxDepth = minInt(max(n, 1), uciMaxDepth)
+		case "movetime":
+			opts.TimePerMove, timed = ms, true
+		case "wtime":
+			clock[White], timed = ms, true
+		case "btime":
+			clock[Black], timed = ms, true
+		case "winc":
+			inc[White] = ms
+		case "binc":
+			inc[Black] = ms
+		case "movestogo":
+			opts.MovesToGo = n
+		default:
+			continue
+		}
+		i++
+	}
+
+	us := u.board.SideToMove
+	opts.RemainingTime, opts.Increment = clock[us], inc[us]
+	if timed && !hasArg(args, "depth") {
+		opts.MaxDepth = uciMaxDepth
+	}
+	return opts
+}
+
+func hasArg(args []string, name string) bool {
+	for _, a := range args {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// startSearch searches the current position in the background, sending
+// an info line per completed depth and bestmove at the end
+func (u *uciEngine) startSearch(args []string) {
+	opts := u.searchOptions(args)
+	board := u.board.Copy()
+	opts.DepthHook = func(r SearchResult) {
+		u.send("info %s", uciInfo(board, r))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	u.cancel, u.done = cancel, done
+
+	go func() {
+		defer close(done)
+		result := SearchContext(ctx, board, opts)
+		move := result.BestMove
+		if move.IsNull() {
+			// Nothing searched (e.g. stopped at once): any legal move
+			if moves := board.Copy().GenerateLegalMoves(); len(moves) > 0 {
+				move = moves[0]
+			}
+		}
+		if move.IsNull() {
+			u.send("bestmove 0000")
+		} else {
+			u.send("bestmove %s", move.String())
+		}
+	}()
+}
+
+// stopSearch cancels a running search and waits for its bestmove
+func (u *uciEngine) stopSearch() {
+	if u.cancel != nil {
+		u.cancel()
+	}
+	u.waitSearch()
+}
+
+// waitSearch waits for a running search to send its bestmove; commands
+// that change the position do not interrupt a search, only "stop" does
+func (u *uciEngine) waitSearch() {
+	if u.done == nil {
+		return
+	}
+	<-u.done
+	u.cancel()
+	u.cancel, u.done = nil, nil
+}
+
+// uciInfo formats a completed depth as the body of an info line. Scores
+// are from the side to move's point of view, as UCI requires.
+func uciInfo(b *Board, r SearchResult) string {
+	score := r.Score
+	if b.SideToMove == Black {
+		score = -score
+	}
+	pv, mated := uciPV(b, r.BestMove, r.Depth)
+
+	scoreStr := fmt.Sprintf("cp %d", score)
+	if max(score, -score) >= 100000 {
+		// Mate distance comes from the PV when it reaches the mate,
+		// otherwise from the search depth
+		plies := r.Depth
+		if mated {
+			plies = len(pv)
+		}
+		moves := (plies + 1) / 2
+		if score < 0 {
+			moves = -moves
+		}
+		scoreStr = fmt.Sprintf("mate %d", moves)
+	}
+
+	return fmt.Sprintf("depth %d score %s nodes %d time %d pv %s",
+		r.Depth, scoreStr, r.Metrics.NodesSearched, r.Metrics.ElapsedMs, strings.Join(pv, " "))
+}
+
+// uciPV returns the principal variation: the best move followed by the
+// transposition table's best replies, up to depth plies. mated reports
+// whether it ends in checkmate.
+func uciPV(b *Board, best Move, depth int) (pv []string, mated bool) {
+	board := b.Copy()
+	for _, m := range principalVariation(b, best, depth) {
+		board.MakeMove(&m)
+		pv = append(pv, m.String())
+	}
+	return pv, len(pv) > 0 && board.IsCheckmate()
+}
+
+// runUCI reads UCI commands from in until "quit" or end of input
+func runUCI(in io.Reader, out io.Writer) {
+	u := newUCIEngine(out)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !u.handleUCICommand(strings.TrimSpace(scanner.Text())) {
+			return
+		}
+	}
+	// Input closed: let a running search finish and report its move
+	u.waitSearch()
+}
@@ -0,0 +1,10 @@
+//go:build uci_main
+
+package main
+
+import "os"
+
+// main runs the UCI protocol loop on stdin/stdout
+func main() {
+	runUCI(os.Stdin, os.Stdout)
+}
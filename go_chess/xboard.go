@@ -0,0 +1,312 @@
+//go:build winboard_main
+
+package main
+
+// XBoard/WinBoard protocol front end.
+//
+// Build a standalone engine with:
+//
+//	go build -tags winboard_main -o go_chess_xboard .
+//
+// and register it with the GUI, e.g. `xboard -fcp ./go_chess_xboard`.
+// Commands are read from stdin and replies ("move e7e5", "pong 3", results)
+// are written to stdout, one per line.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xboardDefaultDepth is the search depth when the GUI sends no "sd"
+const xboardDefaultDepth = 6
+
+// xboardFeatures is sent in reply to "protover 2"
+const xboardFeatures = `feature myname="go_chess" ping=1 setboard=0 playother=1 san=0 ` +
+	`usermove=1 time=1 draw=0 sigint=0 sigterm=0 reuse=1 analyze=0 colors=0 ` +
+	`smp=1 done=1`
+
+// xboardEngine holds the state kept between XBoard commands
+type xboardEngine struct {
+	out io.Writer
+
+	game        *Game
+	engineColor Color
+	force       bool // Engine plays neither side
+	post        bool // Print thinking output
+	gameOver    bool
+
+	depth   int
+	workers int
+
+	// Time control
+	moveTime  time.Duration // "st": fixed time per move
+	movesPer  int           // "level": moves per session (0 = whole game)
+	increment time.Duration
+	clock     time.Duration // "time": engine's remaining time
+}
+
+func newXboardEngine(out io.Writer) *xboardEngine {
+	x := &xboardEngine{
+		out:     out,
+		workers: runtime.NumCPU(),
+	}
+	x.newGame()
+	return x
+}
+
+// newGame resets the board; the engine plays Black and leaves force mode
+func (x *xboardEngine) newGame() {
+	board := NewBoard()
+	x.game = &Game{
+		Board:      board,
+		StartBoard: board.Copy(),
+		History:    make([]Move, 0, 100),
+		FENHistory: []string{board.ToFEN()},
+		turnStart:  time.Now(),
+	}
+	x.engineColor = Black
+	x.force = false
+	x.gameOver = false
+	x.depth = xboardDefaultDepth
+	x.moveTime = 0
+}
+
+func (x *xboardEngine) send(format string, args ...interface{}) {
+	fmt.Fprintf(x.out, format+"\n", args...)
+}
+
+// handle processes one command line; returns false on "quit"
+func (x *xboardEngine) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "quit":
+		return false
+	case "xboard", "accepted", "rejected", "random", "hard", "easy",
+		"computer", "name", "rating", "otim", "?", "variant":
+		// Acknowledged, nothing to do
+	case "protover":
+		x.send("%s", xboardFeatures)
+	case "new":
+		x.newGame()
+	case "force", "result":
+		x.force = true
+	case "go":
+		x.force = false
+		x.engineColor = x.game.Board.SideToMove
+	case "playother":
+		x.force = false
+		x.engineColor = x.game.Board.SideToMove.Opponent()
+	case "white", "black":
+		// Protocol 1: side to move is set, engine plays the other side
+		x.game.Board.SideToMove = White
+		if cmd == "black" {
+			x.game.Board.SideToMove = Black
+		}
+		x.engineColor = x.game.Board.SideToMove.Opponent()
+	case "ping":
+		x.send("pong %s", strings.Join(args, " "))
+	case "post":
+		x.post = true
+	case "nopost":
+		x.post = false
+	case "sd":
+		if d, err := strconv.Atoi(argAt(args, 0)); err == nil && d > 0 {
+			x.depth = d
+		}
+	case "st":
+		if secs, err := strconv.ParseFloat(argAt(args, 0), 64); err == nil && secs > 0 {
+			x.moveTime = time.Duration(secs * float64(time.Second))
+		}
+	case "cores":
+		if n, err := strconv.Atoi(argAt(args, 0)); err == nil && n > 0 {
+			x.workers = n
+		}
+	case "level":
+		x.setLevel(args)
+	case "time":
+		if cs, err := strconv.Atoi(argAt(args, 0)); err == nil {
+			x.clock = time.Duration(cs) * 10 * time.Millisecond
+		}
+	case "usermove":
+		x.userMove(argAt(args, 0))
+	case "undo":
+		x.undo(1)
+	case "remove":
+		x.undo(2)
+	default:
+		// Protocol 1 sends moves without "usermove"
+		if _, ok := x.game.Board.ParseMove(cmd); ok {
+			x.userMove(cmd)
+		} else {
+			x.send("Error (unknown command): %s", cmd)
+		}
+	}
+
+	x.maybeThink()
+	return true
+}
+
+func argAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// setLevel parses "level MPS BASE INC" (BASE is minutes or min:sec)
+func (x *xboardEngine) setLevel(args []string) {
+	if len(args) < 3 {
+		x.send("Error (bad level): %s", strings.Join(args, " "))
+		return
+	}
+	x.movesPer, _ = strconv.Atoi(args[0])
+
+	var base time.Duration
+	if mins, secs, ok := strings.Cut(args[1], ":"); ok {
+		m, _ := strconv.Atoi(mins)
+		s, _ := strconv.Atoi(secs)
+		base = time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	} else {
+		m, _ := strconv.ParseFloat(args[1], 64)
+		base = time.Duration(m * float64(time.Minute))
+	}
+	x.clock = base
+
+	inc, _ := strconv.ParseFloat(args[2], 64)
+	x.increment = time.Duration(inc * float64(time.Second))
+	x.moveTime = 0
+}
+
+// userMove applies the opponent's move, rejecting illegal ones
+func (x *xboardEngine) userMove(s string) {
+	m, ok := x.game.Board.ParseMove(s)
+	if !ok || x.gameOver {
+		x.send("Illegal move: %s", s)
+		return
+	}
+	x.playMove(m)
+}
+
+// playMove makes a move on the board and reports the result if it ends
+// the game
+func (x *xboardEngine) playMove(m Move) {
+	g := x.game
+	g.FENHistory = append(g.FENHistory, g.Board.ToFEN())
+	g.Board.MakeMove(&m)
+	g.recordMove(m)
+
+	switch {
+	case g.Board.IsCheckmate():
+		x.gameOver = true
+		if g.Board.SideToMove == White {
+			x.send("0-1 {Black mates}")
+		} else {
+			x.send("1-0 {White mates}")
+		}
+	case g.Board.IsStalemate():
+		x.gameOver = true
+		x.send("1/2-1/2 {Stalemate}")
+	case g.Board.IsDraw():
+		x.gameOver = true
+		x.send("1/2-1/2 {50 move rule}")
+	}
+}
+
+// undo takes back n plies
+func (x *xboardEngine) undo(n int) {
+	g := x.game
+	for i := 0; i < n && len(g.History) > 0; i++ {
+		m := g.History[len(g.History)-1]
+		g.Board.UnmakeMove(&m)
+		g.History = g.History[:len(g.History)-1]
+		if len(g.FENHistory) > 1 {
+			g.FENHistory = g.FENHistory[:len(g.FENHistory)-1]
+		}
+	}
+	if len(g.MoveTimes) > len(g.History) {
+		g.MoveTimes = g.MoveTimes[:len(g.History)]
+	}
+	if len(g.History) > 0 {
+		g.LastMove = g.History[len(g.History)-1]
+	} else {
+		g.LastMove = Move{}
+	}
+	x.gameOver = false
+}
+
+// moveBudget returns the time to spend on the next move (0 = no limit)
+func (x *xboardEngine) moveBudget() time.Duration {
+	if x.moveTime > 0 {
+		return x.moveTime
+	}
+	if x.clock <= 0 {
+		return 0
+	}
+
+	movesLeft := 0
+	if x.movesPer > 0 {
+		played := (len(x.game.History) + 1) / 2
+		movesLeft = x.movesPer - played%x.movesPer
+	}
+	return calculateMoveTime(x.clock, x.increment, movesLeft)
+}
+
+// maybeThink searches and plays a move when it is the engine's turn
+func (x *xboardEngine) maybeThink() {
+	b := x.game.Board
+	if x.force || x.gameOver || b.SideToMove != x.engineColor {
+		return
+	}
+
+	start := time.Now()
+	var result SearchResult
+	if budget := x.moveBudget(); budget > 0 {
+		opts := DefaultSearchOptions(x.workers)
+		opts.MaxDepth = x.depth
+		opts.TimeLimit = budget
+		result = Search(b, opts)
+	} else {
+		result = SearchWithBook(b, len(x.game.History)+1, x.depth, x.workers, 0)
+	}
+	if result.BestMove.IsNull() {
+		return
+	}
+
+	if x.post {
+		// depth score(engine perspective) time(cs) nodes pv
+		score := result.Score
+		if x.engineColor == Black {
+			score = -score
+		}
+		x.send("%d %d %d %d %s", result.Depth, score,
+			time.Since(start).Milliseconds()/10, result.Metrics.NodesSearched, result.BestMove.String())
+	}
+
+	move := result.BestMove
+	x.send("move %s", move.String())
+	x.playMove(move)
+}
+
+// main runs the XBoard protocol loop on stdin/stdout
+func main() {
+	InitOpeningBook()
+
+	x := newXboardEngine(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if !x.handle(strings.TrimSpace(scanner.Text())) {
+			break
+		}
+	}
+}
@@ -4,6 +4,7 @@ package main
 // Used for opening book lookup and transposition tables
 
 import (
+	"math/bits"
 	"math/rand"
 )
 
@@ -45,11 +46,10 @@ func init() {
 func (b *Board) ZobristHash() uint64 {
 	var h uint64
 
-	// Hash pieces on squares
-	for sq := Square(0); sq < 64; sq++ {
-		piece := b.Squares[sq]
-		if piece != Empty {
-			h ^= zobristPieces[piece][sq]
+	// Hash pieces on squares, walking the occupied bits of each bitboard
+	for piece := WPawn; piece <= BKing; piece++ {
+		for set := *b.BB.of(piece); set != 0; set &= set - 1 {
+			h ^= zobristPieces[piece][bits.TrailingZeros64(set)]
 		}
 	}
 
@@ -69,6 +69,18 @@ func (b *Board) ZobristHash() uint64 {
 	return h
 }
 
+// PawnHash returns the Zobrist hash of the pawns alone (0 with no pawns)
+func (b *Board) PawnHash() uint64 {
+	b.ensureBitboards()
+	var h uint64
+	for _, piece := range [2]Piece{WPawn, BPawn} {
+		for set := *b.BB.of(piece); set != 0; set &= set - 1 {
+			h ^= zobristPieces[piece][bits.TrailingZeros64(set)]
+		}
+	}
+	return h
+}
+
 // ZobristHashString returns the hash as a hex string (for JSON keys)
 func (b *Board) ZobristHashString() string {
 	return formatHash(b.ZobristHash())
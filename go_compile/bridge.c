@@ -0,0 +1,260 @@
+/*
+ * bridge.c - C/CGO Bridge for Go Compile Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Runs the project's build command, streams its output into
+ * *compilation* and steps through the errors it reports.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+typedef bool (*event_fn_t)(void*, void*);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*current_buffer_fn)(void);
+typedef const char *(*buffer_filename_fn)(void*);
+typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef void *(*buffer_create_fn)(const char*);
+typedef int (*buffer_switch_fn)(void*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef int (*find_file_line_fn)(const char*, int);
+typedef const char *(*config_string_fn)(const char*, const char*, const char*);
+typedef int (*on_fn)(const char*, event_fn_t, void*, int);
+typedef int (*off_fn)(const char*, event_fn_t);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    current_buffer_fn current_buffer;
+    buffer_filename_fn buffer_filename;
+    get_point_fn get_point;
+    set_point_fn set_point;
+    buffer_create_fn buffer_create;
+    buffer_switch_fn buffer_switch;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_fn prompt;
+    find_file_line_fn find_file_line;
+    config_string_fn config_string;
+    on_fn on;
+    off_fn off;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* Extension name for config lookups */
+static const char *EXT_NAME = "go_compile";
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_current_buffer(void) {
+    if (api.current_buffer) return api.current_buffer();
+    return NULL;
+}
+
+const char* api_buffer_filename(void *bp) {
+    if (api.buffer_filename) return api.buffer_filename(bp);
+    return NULL;
+}
+
+void api_get_point(int *line, int *col) {
+    if (api.get_point) api.get_point(line, col);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+void* api_buffer_create(const char *name) {
+    if (api.buffer_create) return api.buffer_create(name);
+    return NULL;
+}
+
+int api_buffer_switch(void *bp) {
+    if (api.buffer_switch) return api.buffer_switch(bp);
+    return 0;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
+int api_find_file_line(const char *path, int line) {
+    if (api.find_file_line) return api.find_file_line(path, line);
+    return 0;
+}
+
+const char* api_config_string(const char *key, const char *default_val) {
+    if (api.config_string) return api.config_string(EXT_NAME, key, default_val);
+    return default_val;
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_compile(int f, int n) { return go_compile_run(f, n); }
+static int cmd_compile_next_error(int f, int n) { return go_compile_next_error(f, n); }
+static int cmd_compile_prev_error(int f, int n) { return go_compile_prev_error(f, n); }
+static int cmd_compile_kill(int f, int n) { return go_compile_kill(f, n); }
+
+/* ============================================================================
+ * Event handlers
+ * ============================================================================ */
+
+/* Write build output queued since the last key, on the editor thread */
+static bool on_input_key(void *event_raw, void *user_data) {
+    (void)event_raw;
+    (void)user_data;
+    go_compile_flush();
+    return false;
+}
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int compile_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_compile: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
+    api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
+    api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.find_file_line = (find_file_line_fn)LOOKUP(find_file_line);
+    api.config_string = (config_string_fn)LOOKUP(config_string);
+    api.on = (on_fn)LOOKUP(on);
+    api.off = (off_fn)LOOKUP(off);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_compile: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Register commands */
+    api.register_command("compile", cmd_compile);
+    api.register_command("compile-next-error", cmd_compile_next_error);
+    api.register_command("compile-prev-error", cmd_compile_prev_error);
+    api.register_command("compile-kill", cmd_compile_kill);
+
+    /* Build output is written as keys are pressed */
+    if (api.on) {
+        api.on("input:key", on_input_key, NULL, 0);
+    }
+
+    api.log_info("go_compile: Extension loaded");
+    api.log_info("  Commands: compile, compile-next-error, compile-prev-error, compile-kill");
+    return 0;
+}
+
+static void compile_cleanup_c(void) {
+    if (api.off) {
+        api.off("input:key", on_input_key);
+    }
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("compile");
+        api.unregister_command("compile-next-error");
+        api.unregister_command("compile-prev-error");
+        api.unregister_command("compile-kill");
+    }
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_compile",
+    .version = "1.0.0",
+    .description = "Build runner with error navigation",
+    .init = compile_init_c,
+    .cleanup = compile_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
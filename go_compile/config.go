@@ -0,0 +1,35 @@
+package main
+
+// The build command is command in [extension.go_compile] of settings.toml,
+// read with the extension config API:
+//
+//	[extension.go_compile]
+//	command = "make -j8"
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// defaultCommand runs when settings.toml sets no command
+const defaultCommand = "make"
+
+// projectMarkers are the files whose directory is taken as the project root
+var projectMarkers = []string{"Makefile", "build.gradle", "CMakeLists.txt"}
+
+// projectRoot walks up from dir to the nearest directory holding one of
+// projectMarkers, falling back to dir itself
+func projectRoot(dir string) string {
+	for d := dir; ; {
+		for _, marker := range projectMarkers {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
@@ -0,0 +1,3 @@
+module go_compile
+
+go 1.21
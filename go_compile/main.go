@@ -0,0 +1,412 @@
+// go_compile - Build runner with error navigation for μEmacs
+//
+// Runs the project's build command in the background, streaming its
+// output into *compilation* (written at each key press), and collects
+// file:line:col: message errors from it to step through.
+//
+// Commands:
+//   compile            - Run the build command in the project root
+//                        (C-u to edit the command first)
+//   compile-next-error - Jump to the next error of the last build
+//   compile-prev-error - Jump to the previous error
+//   compile-kill       - Stop the running build
+//
+// The command is command in [extension.go_compile] of settings.toml
+// (default make); the project root is the nearest directory above the
+// current file holding a Makefile, build.gradle or CMakeLists.txt.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_find_file_line(const char *path, int line);
+extern const char *api_config_string(const char *key, const char *default_val);
+*/
+import "C"
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// compilationBuffer receives the build output
+const compilationBuffer = "*compilation*"
+
+// CompileError is one file:line[:col]: message line of build output
+type CompileError struct {
+	File    string // Absolute, resolved against the project root
+	Line    int
+	Col     int // 0 when the line has no column
+	Message string
+}
+
+// errorPattern matches file:line:col: message, the column being optional
+var errorPattern = regexp.MustCompile(`^([^:\s]+):(\d+):(?:(\d+):)?\s*(.*)$`)
+
+// State of the last build: buildErrors grows while it runs and errorIndex
+// is the error last jumped to (-1 before the first). pendingOutput is
+// output not yet written to *compilation*, which it continues from
+// pendingLine; only the editor thread may write buffers, so
+// go_compile_flush writes it on the next key press.
+var (
+	buildMu       sync.Mutex
+	buildCmd      *exec.Cmd
+	buildID       int
+	buildErrors   []CompileError
+	errorIndex    = -1
+	pendingOutput strings.Builder
+	pendingLine   int
+)
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// configuredCommand returns command from [extension.go_compile], or
+// defaultCommand
+func configuredCommand() string {
+	ckey := C.CString("command")
+	defer C.free(unsafe.Pointer(ckey))
+	cdefault := C.CString(defaultCommand)
+	defer C.free(unsafe.Pointer(cdefault))
+	if v := C.api_config_string(ckey, cdefault); v != nil {
+		if cmd := strings.TrimSpace(C.GoString(v)); cmd != "" {
+			return cmd
+		}
+	}
+	return defaultCommand
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// withBuffer runs fn with bp as the current buffer, then switches back to
+// the buffer the user was in, so build output can be written without
+// taking over the window. Only call it on the editor thread.
+func withBuffer(bp unsafe.Pointer, fn func()) {
+	prev := C.api_current_buffer()
+	if prev != bp {
+		C.api_buffer_switch(bp)
+	}
+	fn()
+	if prev != nil && prev != bp {
+		C.api_buffer_switch(prev)
+	}
+}
+
+// currentDir returns the directory of the current buffer's file, or the
+// working directory
+func currentDir() string {
+	if bp := C.api_current_buffer(); bp != nil {
+		if cname := C.api_buffer_filename(bp); cname != nil {
+			if name := C.GoString(cname); name != "" {
+				if abs, err := filepath.Abs(name); err == nil {
+					return filepath.Dir(abs)
+				}
+			}
+		}
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// parseError parses one line of build output, resolving a relative file
+// against root
+func parseError(line, root string) (CompileError, bool) {
+	m := errorPattern.FindStringSubmatch(line)
+	if m == nil {
+		return CompileError{}, false
+	}
+	lineNum, err := strconv.Atoi(m[2])
+	if err != nil || lineNum < 1 {
+		return CompileError{}, false
+	}
+	col, _ := strconv.Atoi(m[3])
+	file := m[1]
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(root, file)
+	}
+	return CompileError{File: file, Line: lineNum, Col: col, Message: m[4]}, true
+}
+
+//export go_compile_run
+func go_compile_run(f, n C.int) C.int {
+	command := configuredCommand()
+	if f != 0 {
+		input, ok := prompt(fmt.Sprintf("Compile command (default %s): ", command))
+		if !ok {
+			message("Cancelled")
+			return 0
+		}
+		if strings.TrimSpace(input) != "" {
+			command = strings.TrimSpace(input)
+		}
+	}
+	root := projectRoot(currentDir())
+
+	cname := C.CString(compilationBuffer)
+	bp := C.api_buffer_create(cname)
+	C.free(unsafe.Pointer(cname))
+	if bp == nil {
+		message("compile: cannot create %s", compilationBuffer)
+		return 0
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = root
+	// Its own process group, so killBuild reaches make and the compilers
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		message("compile: %v", err)
+		return 0
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		message("compile: %v", err)
+		return 0
+	}
+
+	// A new build replaces the running one
+	buildMu.Lock()
+	killBuild(buildCmd)
+	buildID++
+	id := buildID
+	buildErrors = nil
+	errorIndex = -1
+	pendingOutput.Reset()
+	buildMu.Unlock()
+
+	header := fmt.Sprintf("-*- Compiling in %s -*-\n%s\n\n", root, command)
+	C.api_buffer_switch(bp)
+	C.api_buffer_clear(bp)
+	insertText(header)
+
+	if err := cmd.Start(); err != nil {
+		insertText(fmt.Sprintf("Failed to start: %v\n", err))
+		message("compile: %v", err)
+		return 0
+	}
+	buildMu.Lock()
+	buildCmd = cmd
+	buildMu.Unlock()
+
+	lines := make(chan string, 256)
+	var readers sync.WaitGroup
+	for _, r := range []io.Reader{stdout, stderr} {
+		readers.Add(1)
+		go func(r io.Reader) {
+			defer readers.Done()
+			scanner := bufio.NewScanner(r)
+			scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}(r)
+	}
+	go func() {
+		readers.Wait()
+		close(lines)
+	}()
+
+	go streamBuild(strings.Count(header, "\n")+1, lines, cmd, id, root, time.Now())
+	message("compile: %s (in %s)", command, root)
+	return 1
+}
+
+// killBuild kills cmd's whole process group; the caller holds buildMu
+func killBuild(cmd *exec.Cmd) {
+	if cmd != nil && cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// streamBuild queues the build output for *compilation* from line on,
+// collects the errors in it, and reports how the build ended. Output of a
+// build replaced by a newer one is dropped.
+func streamBuild(line int, lines <-chan string, cmd *exec.Cmd, id int, root string, start time.Time) {
+	output := func(text string) {
+		buildMu.Lock()
+		defer buildMu.Unlock()
+		if buildID != id {
+			return
+		}
+		if pendingOutput.Len() == 0 {
+			pendingLine = line
+		}
+		pendingOutput.WriteString(text)
+		line += strings.Count(text, "\n")
+	}
+
+	for l := range lines {
+		output(l + "\n")
+		if e, ok := parseError(l, root); ok {
+			buildMu.Lock()
+			if buildID == id {
+				buildErrors = append(buildErrors, e)
+			}
+			buildMu.Unlock()
+		}
+	}
+
+	err := cmd.Wait()
+	status := "finished"
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status = fmt.Sprintf("exited abnormally with code %d", exitErr.ExitCode())
+	} else if err != nil {
+		status = fmt.Sprintf("failed: %v", err)
+	}
+	elapsed := time.Since(start).Round(10 * time.Millisecond)
+	output(fmt.Sprintf("\nCompilation %s after %v\n", status, elapsed))
+
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	if buildID != id {
+		return
+	}
+	buildCmd = nil
+	message("Compilation %s, %d error(s) (compile-next-error to visit)", status, len(buildErrors))
+}
+
+// go_compile_flush writes the queued build output to *compilation*. It
+// runs from the key handler, on the editor thread, before the key is
+// processed.
+//
+//export go_compile_flush
+func go_compile_flush() {
+	buildMu.Lock()
+	text, at := pendingOutput.String(), pendingLine
+	pendingOutput.Reset()
+	buildMu.Unlock()
+	if text == "" {
+		return
+	}
+
+	cname := C.CString(compilationBuffer)
+	bp := C.api_buffer_create(cname)
+	C.free(unsafe.Pointer(cname))
+	if bp == nil {
+		return
+	}
+	withBuffer(bp, func() {
+		// Keep point where the user left it
+		var line, col C.int
+		C.api_get_point(&line, &col)
+		C.api_set_point(C.int(at), 1)
+		insertText(text)
+		if int(line) >= at {
+			line += C.int(strings.Count(text, "\n"))
+		}
+		C.api_set_point(line, col)
+	})
+}
+
+//export go_compile_kill
+func go_compile_kill(f, n C.int) C.int {
+	buildMu.Lock()
+	cmd := buildCmd
+	if cmd != nil {
+		killBuild(cmd)
+	}
+	buildMu.Unlock()
+	if cmd == nil || cmd.Process == nil {
+		message("compile-kill: no build running")
+		return 0
+	}
+	message("compile-kill: build stopped")
+	return 1
+}
+
+//export go_compile_next_error
+func go_compile_next_error(f, n C.int) C.int {
+	return stepError(1)
+}
+
+//export go_compile_prev_error
+func go_compile_prev_error(f, n C.int) C.int {
+	return stepError(-1)
+}
+
+// stepError moves to the next (dir 1) or previous (dir -1) error of the
+// last build and opens its file at the error line
+func stepError(dir int) C.int {
+	buildMu.Lock()
+	if len(buildErrors) == 0 {
+		buildMu.Unlock()
+		message("No compilation errors")
+		return 0
+	}
+	next := errorIndex + dir
+	if next < 0 || next >= len(buildErrors) {
+		buildMu.Unlock()
+		if dir > 0 {
+			message("No more errors")
+		} else {
+			message("At the first error")
+		}
+		return 0
+	}
+	errorIndex = next
+	e := buildErrors[next]
+	total := len(buildErrors)
+	buildMu.Unlock()
+
+	cPath := C.CString(e.File)
+	defer C.free(unsafe.Pointer(cPath))
+	C.api_find_file_line(cPath, C.int(e.Line))
+	message("Error %d/%d: %s", next+1, total, e.Message)
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		line string
+		want CompileError
+		ok   bool
+	}{
+		{"main.c:12:5: error: expected ';'", CompileError{"/proj/main.c", 12, 5, "error: expected ';'"}, true},
+		{"src/lib.go:7: undefined: x", CompileError{"/proj/src/lib.go", 7, 0, "undefined: x"}, true},
+		{"/abs/file.rs:3:1: warning: unused", CompileError{"/abs/file.rs", 3, 1, "warning: unused"}, true},
+		{"make: *** [all] Error 1", CompileError{}, false},
+		{"file.c:0:1: line zero", CompileError{}, false},
+		{"Compiling main.c", CompileError{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseError(tt.line, "/proj")
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseError(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestProjectRoot(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "src", "pkg")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := projectRoot(dir); got != dir {
+		t.Errorf("without a marker: %s, want %s", got, dir)
+	}
+	if err := os.WriteFile(filepath.Join(root, "Makefile"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := projectRoot(dir); got != root {
+		t.Errorf("with a Makefile: %s, want %s", got, root)
+	}
+}
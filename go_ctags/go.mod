@@ -0,0 +1,3 @@
+module go_ctags
+
+go 1.21
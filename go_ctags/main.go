@@ -0,0 +1,325 @@
+// go_ctags - Universal Ctags symbol navigation for μEmacs
+//
+// Indexes the project with ctags -R and jumps to the definitions of the
+// word at point. The index is kept in ~/.config/muemacs/tags.json so it
+// is ready at startup.
+//
+// Commands:
+//   ctags-goto    - Jump to the definition of the word at point, listing
+//                   them in *tags* when there are several (in *tags*,
+//                   visit the definition on the current line)
+//   ctags-rebuild - Re-index the project in the background
+//
+// lsp-definition falls back to ctags-goto when no language server is
+// running. The project root is the nearest directory holding .git above
+// the current file.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
+extern char *api_get_current_line(void);
+extern char *api_get_word_at_point(void);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern void api_free(void *ptr);
+extern void api_update_display(void);
+extern int api_find_file_line(const char *path, int line);
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// tagsBuffer lists the definitions of a tag with several
+const tagsBuffer = "*tags*"
+
+// The index in use and whether a rebuild is running
+var (
+	indexMu  sync.Mutex
+	index    *Index
+	building bool
+)
+
+// tagLinePattern matches a *tags* line: file:line: ...
+var tagLinePattern = regexp.MustCompile(`^(.+?):(\d+):`)
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// logError writes a message to the editor log
+func logError(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_log_error(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// takeString copies and frees a string allocated by the editor
+func takeString(cstr *C.char) string {
+	if cstr == nil {
+		return ""
+	}
+	defer C.api_free(unsafe.Pointer(cstr))
+	return C.GoString(cstr)
+}
+
+// currentRoot returns the project root of the current buffer's file, or
+// of the working directory
+func currentRoot() string {
+	dir, _ := os.Getwd()
+	if bp := C.api_current_buffer(); bp != nil {
+		if cname := C.api_buffer_filename(bp); cname != nil && C.GoString(cname) != "" {
+			if abs, err := filepath.Abs(C.GoString(cname)); err == nil {
+				dir = filepath.Dir(abs)
+			}
+		}
+	}
+	return projectRoot(dir)
+}
+
+// buildIndex runs ctags over root and parses its output
+func buildIndex(root string) (*Index, error) {
+	cmd := exec.Command("ctags", "-R", "-f", "-", "--fields=+nK", root)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			return nil, fmt.Errorf("ctags is not installed")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	tags, err := parseTags(bytes.NewReader(out))
+	if err != nil {
+		return nil, err
+	}
+	return &Index{Root: root, Tags: tags}, nil
+}
+
+// setIndex makes idx the index in use and persists it
+func setIndex(idx *Index) {
+	indexMu.Lock()
+	index = idx
+	indexMu.Unlock()
+	if err := saveIndex(indexPath(), idx); err != nil {
+		logError("go_ctags: saving %s: %v", indexPath(), err)
+	}
+}
+
+// indexFor returns the index of root, building it first if the one in use
+// belongs to another project
+func indexFor(cmd, root string) (*Index, bool) {
+	indexMu.Lock()
+	idx, busy := index, building
+	indexMu.Unlock()
+	if idx != nil && idx.Root == root {
+		return idx, true
+	}
+	if busy {
+		message("%s: the tags are being rebuilt, try again shortly", cmd)
+		return nil, false
+	}
+
+	message("Indexing %s...", root)
+	C.api_update_display()
+	idx, err := buildIndex(root)
+	if err != nil {
+		message("%s: %v", cmd, err)
+		return nil, false
+	}
+	setIndex(idx)
+	return idx, true
+}
+
+// visit opens file at line
+func visit(file string, line int) C.int {
+	cpath := C.CString(file)
+	defer C.free(unsafe.Pointer(cpath))
+	if C.api_find_file_line(cpath, C.int(max(line, 1))) == 0 {
+		message("ctags-goto: cannot open %s", file)
+		return 0
+	}
+	return 1
+}
+
+// gotoListed visits the definition on the current line of *tags*
+func gotoListed() C.int {
+	line := takeString(C.api_get_current_line())
+	m := tagLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		message("ctags-goto: no definition on this line")
+		return 0
+	}
+	n, _ := strconv.Atoi(m[2])
+	return visit(m[1], n)
+}
+
+// describe renders a definition for *tags* and the message line
+func describe(e TagEntry) string {
+	s := fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Kind)
+	if e.Scope != "" {
+		s += " in " + e.Scope
+	}
+	return s
+}
+
+//export go_ctags_goto
+func go_ctags_goto(f, n C.int) C.int {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	if cname := C.api_buffer_name(bp); cname != nil && C.GoString(cname) == tagsBuffer {
+		return gotoListed()
+	}
+
+	word := strings.TrimSpace(takeString(C.api_get_word_at_point()))
+	if word == "" {
+		input, ok := prompt("Tag: ")
+		if !ok || strings.TrimSpace(input) == "" {
+			message("Cancelled")
+			return 0
+		}
+		word = strings.TrimSpace(input)
+	}
+
+	idx, ok := indexFor("ctags-goto", currentRoot())
+	if !ok {
+		return 0
+	}
+	entries := idx.Tags[word]
+	switch len(entries) {
+	case 0:
+		message("ctags-goto: no tag %s in %s", word, idx.Root)
+		return 0
+	case 1:
+		if visit(entries[0].File, entries[0].Line) == 0 {
+			return 0
+		}
+		message("%s", describe(entries[0]))
+		return 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d definitions of %s\n\n", len(entries), word)
+	for _, e := range entries {
+		b.WriteString(describe(e) + "\n")
+	}
+	cname := C.CString(tagsBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	out := C.api_buffer_create(cname)
+	if out == nil {
+		return 0
+	}
+	C.api_buffer_switch(out)
+	C.api_buffer_clear(out)
+	insertText(b.String())
+	C.api_set_point(3, 0)
+	message("%d definitions of %s (ctags-goto on a line visits it)", len(entries), word)
+	return 1
+}
+
+//export go_ctags_rebuild
+func go_ctags_rebuild(f, n C.int) C.int {
+	root := currentRoot()
+	indexMu.Lock()
+	if building {
+		indexMu.Unlock()
+		message("ctags-rebuild: already running")
+		return 0
+	}
+	building = true
+	indexMu.Unlock()
+
+	go func() {
+		idx, err := buildIndex(root)
+		indexMu.Lock()
+		building = false
+		indexMu.Unlock()
+		if err != nil {
+			message("ctags-rebuild: %v", err)
+			return
+		}
+		setIndex(idx)
+		message("ctags-rebuild: %d tags in %s", len(idx.Tags), root)
+	}()
+	message("ctags-rebuild: indexing %s in the background", root)
+	return 1
+}
+
+//export ctags_init
+func ctags_init(api unsafe.Pointer) {
+	// Load the persisted index without holding up startup
+	go func() {
+		idx, err := loadIndex(indexPath())
+		if err != nil {
+			logError("go_ctags: loading %s: %v", indexPath(), err)
+			return
+		}
+		if idx == nil {
+			return
+		}
+		indexMu.Lock()
+		if index == nil {
+			index = idx
+		}
+		indexMu.Unlock()
+	}()
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
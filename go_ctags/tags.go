@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TagEntry is one definition of a tag
+type TagEntry struct {
+	File  string
+	Line  int
+	Kind  string
+	Scope string // Enclosing class, struct, namespace... as kind:name
+}
+
+// Index is the tags of one project, keyed by tag name
+type Index struct {
+	Root string
+	Tags map[string][]TagEntry
+}
+
+// tagPattern splits a tag line: name, file, address, extension fields.
+// The address is a line number or a /pattern/ that may hold tabs.
+var tagPattern = regexp.MustCompile(`^([^\t]+)\t([^\t]+)\t(.*?);"(?:\t(.*))?$`)
+
+// scopeKinds are the extension fields naming an enclosing scope
+var scopeKinds = map[string]bool{
+	"class": true, "struct": true, "namespace": true, "interface": true,
+	"enum": true, "union": true, "module": true, "package": true,
+	"function": true, "method": true, "implementation": true,
+}
+
+// parseTags reads ctags output in the extended tags format, as written by
+// ctags --fields=+nK. Pseudo-tags (!_TAG_...) are skipped.
+func parseTags(r io.Reader) (map[string][]TagEntry, error) {
+	tags := map[string][]TagEntry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "!_") {
+			continue
+		}
+		m := tagPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		entry := TagEntry{File: m[2]}
+		if n, err := strconv.Atoi(m[3]); err == nil {
+			entry.Line = n
+		}
+		for _, field := range strings.Split(m[4], "\t") {
+			key, value, ok := strings.Cut(field, ":")
+			switch {
+			case !ok:
+				// A bare kind letter
+				if entry.Kind == "" {
+					entry.Kind = field
+				}
+			case key == "kind":
+				entry.Kind = value
+			case key == "line":
+				if n, err := strconv.Atoi(value); err == nil {
+					entry.Line = n
+				}
+			case key == "scope":
+				entry.Scope = value
+			case scopeKinds[key] && entry.Scope == "":
+				entry.Scope = field
+			}
+		}
+		tags[m[1]] = append(tags[m[1]], entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, entries := range tags {
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].File != entries[j].File {
+				return entries[i].File < entries[j].File
+			}
+			return entries[i].Line < entries[j].Line
+		})
+	}
+	return tags, nil
+}
+
+// indexPath returns where the index is persisted
+func indexPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs", "tags.json")
+}
+
+// loadIndex reads a persisted index; a missing file yields nil
+func loadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// saveIndex persists an index, replacing the file atomically
+func saveIndex(path string, idx *Index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// projectRoot returns the nearest directory at or above dir holding .git,
+// or dir itself
+func projectRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleTags = "!_TAG_FILE_FORMAT\t2\t/extended format/\n" +
+	"Parse\t/src/a.go\t/^func Parse(s string) {$/;\"\tkind:func\tline:12\n" +
+	"Parse\t/src/b.go\t/^func (p *P) Parse() {$/;\"\tkind:method\tline:40\tstruct:P\n" +
+	"Value\t/src/a.go\t7;\"\tm\tscope:struct:Config\n" +
+	"broken line\n"
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags(strings.NewReader(sampleTags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]TagEntry{
+		"Parse": {
+			{File: "/src/a.go", Line: 12, Kind: "func"},
+			{File: "/src/b.go", Line: 40, Kind: "method", Scope: "struct:P"},
+		},
+		"Value": {{File: "/src/a.go", Line: 7, Kind: "m", Scope: "struct:Config"}},
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("got  %+v\nwant %+v", tags, want)
+	}
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tags.json")
+	if idx, err := loadIndex(path); idx != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", idx, err)
+	}
+	idx := &Index{Root: "/src", Tags: map[string][]TagEntry{"x": {{File: "/src/x.go", Line: 3, Kind: "var"}}}}
+	if err := saveIndex(path, idx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadIndex(path)
+	if err != nil || !reflect.DeepEqual(got, idx) {
+		t.Errorf("round trip: %+v, %v", got, err)
+	}
+}
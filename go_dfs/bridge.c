@@ -16,6 +16,7 @@
 #include "_cgo_export.h"
 
 typedef int (*cmd_fn_t)(int, int);
+typedef bool (*event_fn_t)(void*, void*);
 
 /*
  * Function pointer types for the API functions we use
@@ -24,6 +25,7 @@ typedef void (*message_fn)(const char*, ...);
 typedef void (*log_fn)(const char*, ...);
 typedef void *(*current_buffer_fn)(void);
 typedef const char *(*buffer_filename_fn)(void*);
+typedef const char *(*buffer_name_fn)(void*);
 typedef char *(*buffer_contents_fn)(void*, size_t*);
 typedef void (*get_point_fn)(int*, int*);
 typedef void (*set_point_fn)(int, int);
@@ -32,9 +34,12 @@ typedef int (*buffer_switch_fn)(void*);
 typedef int (*buffer_clear_fn)(void*);
 typedef int (*buffer_insert_fn)(const char*, size_t);
 typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef int (*prompt_yn_fn)(const char*);
 typedef void (*free_fn)(void*);
 typedef void (*update_display_fn)(void);
 typedef int (*find_file_line_fn)(const char*, int);
+typedef int (*on_fn)(const char*, event_fn_t, void*, int);
+typedef int (*off_fn)(const char*, event_fn_t);
 typedef int (*register_command_fn)(const char*, cmd_fn_t);
 typedef int (*unregister_command_fn)(const char*);
 
@@ -47,6 +52,7 @@ static struct {
     log_fn log_error;
     current_buffer_fn current_buffer;
     buffer_filename_fn buffer_filename;
+    buffer_name_fn buffer_name;
     buffer_contents_fn buffer_contents;
     get_point_fn get_point;
     set_point_fn set_point;
@@ -55,9 +61,12 @@ static struct {
     buffer_clear_fn buffer_clear;
     buffer_insert_fn buffer_insert;
     prompt_fn prompt;
+    prompt_yn_fn prompt_yn;
     free_fn free;
     update_display_fn update_display;
     find_file_line_fn find_file_line;
+    on_fn on;
+    off_fn off;
     register_command_fn register_command;
     unregister_command_fn unregister_command;
 } api;
@@ -88,6 +97,11 @@ const char* api_buffer_filename(void *bp) {
     return NULL;
 }
 
+const char* api_buffer_name(void *bp) {
+    if (api.buffer_name) return api.buffer_name(bp);
+    return NULL;
+}
+
 char* api_buffer_contents(void *bp, size_t *len) {
     if (api.buffer_contents) return api.buffer_contents(bp, len);
     return NULL;
@@ -126,6 +140,11 @@ int api_prompt(const char *prompt, char *buf, size_t buflen) {
     return -1;
 }
 
+int api_prompt_yn(const char *prompt) {
+    if (api.prompt_yn) return api.prompt_yn(prompt);
+    return 0;
+}
+
 void api_free(void *ptr) {
     if (api.free) api.free(ptr);
 }
@@ -145,8 +164,40 @@ int api_find_file_line(const char *path, int line) {
 
 static int cmd_dfs_find(int f, int n) { return go_dfs_find(f, n); }
 static int cmd_dfs_grep(int f, int n) { return go_dfs_grep(f, n); }
+static int cmd_dfs_grep_fixed(int f, int n) { return go_dfs_grep_fixed(f, n); }
 static int cmd_dfs_count(int f, int n) { return go_dfs_count(f, n); }
 static int cmd_dfs_tree(int f, int n) { return go_dfs_tree(f, n); }
+static int cmd_dfs_suid(int f, int n) { return go_dfs_suid(f, n); }
+static int cmd_dfs_world_writable(int f, int n) { return go_dfs_world_writable(f, n); }
+static int cmd_dfs_replace(int f, int n) { return go_dfs_replace(f, n); }
+static int cmd_dfs_duplicates(int f, int n) { return go_dfs_duplicates(f, n); }
+static int cmd_dfs_remove_duplicates(int f, int n) { return go_dfs_remove_duplicates(f, n); }
+static int cmd_dfs_open_result(int f, int n) { return go_dfs_open_result(f, n); }
+static int cmd_dfs_next_result(int f, int n) { return go_dfs_next_result(f, n); }
+static int cmd_dfs_prev_result(int f, int n) { return go_dfs_prev_result(f, n); }
+static int cmd_dfs_cancel(int f, int n) { return go_dfs_cancel(f, n); }
+static int cmd_dfs_watch(int f, int n) { return go_dfs_watch(f, n); }
+static int cmd_dfs_unwatch(int f, int n) { return go_dfs_unwatch(f, n); }
+
+/* ============================================================================
+ * Event handlers
+ * ============================================================================ */
+
+/* Enter in *dfs-grep* / *dfs-find* opens the result on the current line */
+static bool on_input_key(void *event_raw, void *user_data) {
+    (void)user_data;
+    uemacs_event_t *event = event_raw;
+    if (!event || !event->data) return false;
+
+    /* Write results queued by background searches */
+    go_dfs_flush();
+
+    int key = *(int *)event->data;
+    if (key == '\r' || key == '\n') {
+        return go_dfs_result_enter() != 0;
+    }
+    return false;
+}
 
 /* ============================================================================
  * Extension lifecycle
@@ -181,6 +232,7 @@ static int dfs_init_c(void *editor_api_raw) {
     api.log_error = (log_fn)LOOKUP(log_error);
     api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
     api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.buffer_name = (buffer_name_fn)LOOKUP(buffer_name);
     api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
     api.get_point = (get_point_fn)LOOKUP(get_point);
     api.set_point = (set_point_fn)LOOKUP(set_point);
@@ -189,9 +241,12 @@ static int dfs_init_c(void *editor_api_raw) {
     api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
     api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
     api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.prompt_yn = (prompt_yn_fn)LOOKUP(prompt_yn);
     api.free = (free_fn)LOOKUP(free);
     api.update_display = (update_display_fn)LOOKUP(update_display);
     api.find_file_line = (find_file_line_fn)LOOKUP(find_file_line);
+    api.on = (on_fn)LOOKUP(on);
+    api.off = (off_fn)LOOKUP(off);
     api.register_command = (register_command_fn)LOOKUP(register_command);
     api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
 
@@ -209,19 +264,52 @@ static int dfs_init_c(void *editor_api_raw) {
     /* Register commands */
     api.register_command("dfs-find", cmd_dfs_find);
     api.register_command("dfs-grep", cmd_dfs_grep);
+    api.register_command("dfs-grep-fixed", cmd_dfs_grep_fixed);
     api.register_command("dfs-count", cmd_dfs_count);
     api.register_command("dfs-tree", cmd_dfs_tree);
+    api.register_command("dfs-suid", cmd_dfs_suid);
+    api.register_command("dfs-world-writable", cmd_dfs_world_writable);
+    api.register_command("dfs-replace", cmd_dfs_replace);
+    api.register_command("dfs-duplicates", cmd_dfs_duplicates);
+    api.register_command("dfs-remove-duplicates", cmd_dfs_remove_duplicates);
+    api.register_command("dfs-open-result", cmd_dfs_open_result);
+    api.register_command("dfs-next-result", cmd_dfs_next_result);
+    api.register_command("dfs-prev-result", cmd_dfs_prev_result);
+    api.register_command("dfs-cancel", cmd_dfs_cancel);
+    api.register_command("dfs-watch", cmd_dfs_watch);
+    api.register_command("dfs-unwatch", cmd_dfs_unwatch);
+
+    /* Register Enter key handler for result buffers */
+    if (api.on) {
+        api.on("input:key", on_input_key, NULL, 0);
+    }
 
     api.log_info("go_dfs: Concurrent DFS extension loaded (work-stealing traversal)");
     return 0;
 }
 
 static void dfs_cleanup_c(void) {
+    dfs_cleanup();
+    if (api.off) {
+        api.off("input:key", on_input_key);
+    }
     if (api.unregister_command) {
         api.unregister_command("dfs-find");
         api.unregister_command("dfs-grep");
+        api.unregister_command("dfs-grep-fixed");
         api.unregister_command("dfs-count");
         api.unregister_command("dfs-tree");
+        api.unregister_command("dfs-suid");
+        api.unregister_command("dfs-world-writable");
+        api.unregister_command("dfs-replace");
+        api.unregister_command("dfs-duplicates");
+        api.unregister_command("dfs-remove-duplicates");
+        api.unregister_command("dfs-open-result");
+        api.unregister_command("dfs-next-result");
+        api.unregister_command("dfs-prev-result");
+        api.unregister_command("dfs-cancel");
+        api.unregister_command("dfs-watch");
+        api.unregister_command("dfs-unwatch");
     }
 }
 
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultDuplicateMaxSize is the largest file hashed by FindDuplicates
+const DefaultDuplicateMaxSize = 100 << 20
+
+// DuplicateGroup is a set of files with the same content (or name)
+type DuplicateGroup struct {
+	Key   string // "sha256:<hex>" or "name:<base name>"
+	Paths []string
+}
+
+// FindDuplicates groups the files under root (skipping what .gitignore or
+// DefaultPrune would) by SHA-256 of their content, or by base name when
+// byName is set, and returns the groups with more than one file. Files larger than maxSize
+// are not hashed; only files sharing a size with another are.
+func FindDuplicates(root string, byName bool, maxSize int64, maxWorkers int) ([]DuplicateGroup, []string) {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.NumCPU()
+	}
+
+	opts := DefaultFileOptions(maxWorkers)
+	opts.UseGitignore = InGitRepo(root)
+	opts.Match = func(path string, isDir bool) bool {
+		return !isDir
+	}
+	files := FileTraverse(context.Background(), root, opts, nil)
+
+	groups := make(map[string][]string)
+	if byName {
+		for _, path := range files.Matches {
+			key := "name:" + filepath.Base(path)
+			groups[key] = append(groups[key], path)
+		}
+		return duplicateGroups(groups), files.Errors
+	}
+
+	// Only files of equal size can be identical
+	bySize := make(map[int64][]string)
+	for _, path := range files.Matches {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxSize {
+			continue
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], path)
+	}
+
+	pathCh := make(chan string, len(files.Matches))
+	for _, paths := range bySize {
+		if len(paths) > 1 {
+			for _, path := range paths {
+				pathCh <- path
+			}
+		}
+	}
+	close(pathCh)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < maxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range pathCh {
+				sum, err := hashFile(path)
+				if err != nil {
+					continue
+				}
+				key := "sha256:" + sum
+				mu.Lock()
+				groups[key] = append(groups[key], path)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return duplicateGroups(groups), files.Errors
+}
+
+// hashFile returns the hex SHA-256 of a file, streaming its content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// duplicateGroups keeps the groups with more than one path, paths sorted
+// and groups ordered by their first path
+func duplicateGroups(groups map[string][]string) []DuplicateGroup {
+	var result []DuplicateGroup
+	for key, paths := range groups {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		result = append(result, DuplicateGroup{Key: key, Paths: paths})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Paths[0] < result[j].Paths[0] })
+	return result
+}
+
+// RemoveDuplicates deletes every path in g except g.Paths[keep], first
+// checking that the kept copy and each deleted one still have the group's
+// content hash. It returns the paths removed.
+func RemoveDuplicates(g DuplicateGroup, keep int) ([]string, []string) {
+	var removed, errs []string
+	if sum, err := hashFile(g.Paths[keep]); err != nil || "sha256:"+sum != g.Key {
+		return nil, []string{g.Paths[keep] + ": kept copy missing or changed, nothing removed"}
+	}
+	for i, path := range g.Paths {
+		if i == keep {
+			continue
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			errs = append(errs, path+": "+err.Error())
+			continue
+		}
+		if "sha256:"+sum != g.Key {
+			errs = append(errs, path+": content changed, kept")
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, path+": "+err.Error())
+			continue
+		}
+		removed = append(removed, path)
+	}
+	return removed, errs
+}
+
+// RenderDuplicates formats duplicate groups for *dfs-duplicates*: a
+// "=== key  (N files)" line per group, then its paths numbered relative
+// to root
+func RenderDuplicates(root string, groups []DuplicateGroup) string {
+	var sb strings.Builder
+	for _, g := range groups {
+		key := g.Key
+		if strings.HasPrefix(key, "sha256:") && len(key) > len("sha256:")+16 {
+			key = key[:len("sha256:")+16] + "..."
+		}
+		sb.WriteString(fmt.Sprintf("=== %s  (%d files)\n", key, len(g.Paths)))
+		for i, path := range g.Paths {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				path = rel
+			}
+			sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, path))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"math/rand"
 	"os"
@@ -22,9 +23,25 @@ type FileTraverseOptions struct {
 
 	// Heuristics for file traversal
 	Prune        func(path string, isDir bool) bool // Return true to skip this path
+	UseGitignore bool                               // Without Prune, also skip what .gitignore ignores (GitignoreAwarePrune)
 	Match        func(path string, isDir bool) bool // Return true if this path matches
 	EstimateWork func(path string) int              // Estimate children count
 
+	// Content search
+	FixedString bool // Treat the search pattern as a literal (bytes.Contains, no regex)
+
+	// File name suffixes to skip (files only), e.g. ".min.js" or ".lock"
+	SkipExtensions []string
+
+	// Permission filters (files only, checked via entry.Info()).
+	// PermissionMask uses Unix octal bits, e.g. 0o4000 (SUID) or 0o002.
+	PermissionMask  os.FileMode                 // Require all of these bits
+	PermissionCheck func(info os.FileInfo) bool // Return true to include
+
+	// Modification time window (files only); nil leaves that end open
+	ModifiedAfter  *time.Time
+	ModifiedBefore *time.Time
+
 	// Scheduling knobs
 	StealDepthMin     int
 	ChunkStealSize    int
@@ -49,14 +66,63 @@ type FileMetrics struct {
 	QueueHighHits  uint64
 	QueueLenMax    uint64
 	IdleYields     uint64
+	BinarySkipped  uint64
 	ElapsedNs      int64
 }
 
 // FileTraverseResult holds the results of a file traversal
 type FileTraverseResult struct {
 	Matches []string
+	Modes   map[string]os.FileMode // Unix mode bits per match (permission filters only)
 	Errors  []string
 	Metrics FileMetrics
+
+	// ResultChannel, if non-nil, receives each match as it is found and is
+	// closed when the traversal ends (see FileTraverseStream)
+	ResultChannel chan string
+}
+
+// UnixMode converts a Go FileMode to Unix permission bits, including
+// setuid (0o4000), setgid (0o2000) and sticky (0o1000)
+func UnixMode(m os.FileMode) os.FileMode {
+	mode := m.Perm()
+	if m&os.ModeSetuid != 0 {
+		mode |= 0o4000
+	}
+	if m&os.ModeSetgid != 0 {
+		mode |= 0o2000
+	}
+	if m&os.ModeSticky != 0 {
+		mode |= 0o1000
+	}
+	return mode
+}
+
+// permissionMatch reports whether info passes the permission filters.
+// Symlinks never match: their mode bits say nothing about the target.
+func permissionMatch(info os.FileInfo, opts *FileTraverseOptions) bool {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return false
+	}
+	if opts.PermissionMask != 0 && UnixMode(info.Mode())&opts.PermissionMask != opts.PermissionMask {
+		return false
+	}
+	if opts.PermissionCheck != nil && !opts.PermissionCheck(info) {
+		return false
+	}
+	return true
+}
+
+// timeMatch reports whether info's modification time lies in the window
+func timeMatch(info os.FileInfo, opts *FileTraverseOptions) bool {
+	mtime := info.ModTime()
+	if opts.ModifiedAfter != nil && mtime.Before(*opts.ModifiedAfter) {
+		return false
+	}
+	if opts.ModifiedBefore != nil && !mtime.Before(*opts.ModifiedBefore) {
+		return false
+	}
+	return true
 }
 
 // DefaultFileOptions returns sensible defaults for file traversal
@@ -77,6 +143,21 @@ func DefaultFileOptions(workers int) FileTraverseOptions {
 	}
 }
 
+// contentMatcher returns the function a content search for pattern
+// matches text with: bytes.Contains with FixedString set, else the
+// compiled regular expression
+func (o FileTraverseOptions) contentMatcher(pattern string) (func([]byte) bool, error) {
+	if o.FixedString {
+		needle := []byte(pattern)
+		return func(text []byte) bool { return bytes.Contains(text, needle) }, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.Match, nil
+}
+
 // Common prune patterns
 func DefaultPrune(path string, isDir bool) bool {
 	if !isDir {
@@ -93,12 +174,47 @@ func DefaultPrune(path string, isDir bool) bool {
 	return false
 }
 
+// traversalPrune returns the prune rule a traversal of root with opts
+// uses: opts.Prune, else DefaultPrune plus the .gitignore rules with
+// UseGitignore, else DefaultPrune alone
+func traversalPrune(root string, opts FileTraverseOptions) func(path string, isDir bool) bool {
+	switch {
+	case opts.Prune != nil:
+		return opts.Prune
+	case opts.UseGitignore:
+		return GitignoreAwarePrune(root)
+	default:
+		return DefaultPrune
+	}
+}
+
 // FileTraverse performs work-stealing parallel DFS on a file system tree
 func FileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pattern *regexp.Regexp) *FileTraverseResult {
 	result := &FileTraverseResult{
 		Matches: make([]string, 0, 256),
 		Errors:  make([]string, 0),
 	}
+	return fileTraverse(ctx, root, opts, pattern, result)
+}
+
+// FileTraverseStream runs FileTraverse in the background and returns at
+// once. Matches arrive on ResultChannel as they are found; the other
+// fields are complete once it has been closed. The caller must drain it.
+func FileTraverseStream(ctx context.Context, root string, opts FileTraverseOptions, pattern *regexp.Regexp) *FileTraverseResult {
+	result := &FileTraverseResult{
+		Matches:       make([]string, 0, 256),
+		Errors:        make([]string, 0),
+		ResultChannel: make(chan string, 256),
+	}
+	go func() {
+		defer close(result.ResultChannel)
+		fileTraverse(ctx, root, opts, pattern, result)
+	}()
+	return result
+}
+
+// fileTraverse does the traversal for FileTraverse and FileTraverseStream
+func fileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pattern *regexp.Regexp, result *FileTraverseResult) *FileTraverseResult {
 
 	if opts.MaxWorkers <= 0 {
 		opts.MaxWorkers = runtime.NumCPU()
@@ -110,10 +226,13 @@ func FileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pa
 		opts.DepthParallelThreshold = 3
 	}
 
-	// Set default prune if not provided
-	if opts.Prune == nil {
-		opts.Prune = DefaultPrune
+	opts.Prune = traversalPrune(root, opts)
+
+	permFilter := opts.PermissionMask != 0 || opts.PermissionCheck != nil
+	if permFilter {
+		result.Modes = make(map[string]os.FileMode)
 	}
+	timeFilter := opts.ModifiedAfter != nil || opts.ModifiedBefore != nil
 
 	// Set default match if not provided
 	if opts.Match == nil && pattern != nil {
@@ -206,6 +325,40 @@ func FileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pa
 	var matchesMu sync.Mutex
 	var errorsMu sync.Mutex
 
+	// stealFrom moves tasks from the bottom of victim to thief, a chunk at
+	// a time with ChunkStealSize > 1. Tasks shallower than StealDepthMin
+	// go back to victim, or they would be lost and the traversal would
+	// wait for them forever. It reports whether anything was stolen.
+	stealFrom := func(victim, thief *deque) bool {
+		chunk, ok := stealChunkBottom(victim, opts.ChunkStealSize)
+		if !ok {
+			return false
+		}
+		filtered := chunk[:0]
+		var shallow []task
+		for _, t := range chunk {
+			if t.depth >= opts.StealDepthMin {
+				filtered = append(filtered, t)
+			} else {
+				shallow = append(shallow, t)
+			}
+		}
+		for _, t := range shallow {
+			pushTop(victim, t)
+		}
+		if len(filtered) == 0 {
+			return false
+		}
+		atomic.AddUint64(&metrics.Steals, uint64(len(filtered)))
+		if opts.ChunkStealSize > 1 {
+			atomic.AddUint64(&metrics.StealChunks, 1)
+		}
+		for _, t := range filtered {
+			pushTop(thief, t)
+		}
+		return true
+	}
+
 	// Visited set to avoid re-processing (handles symlinks)
 	visited := sync.Map{}
 
@@ -280,12 +433,34 @@ func FileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pa
 						continue
 					}
 
+					// Check extension, permissions and modification time (files only)
+					if !isDir && hasSkippedExtension(entry.Name(), opts.SkipExtensions) {
+						atomic.AddUint64(&metrics.FilesVisited, 1)
+						continue
+					}
+					var mode os.FileMode
+					if (permFilter || timeFilter) && !isDir {
+						info, err := entry.Info()
+						if err != nil || (permFilter && !permissionMatch(info, &opts)) ||
+							(timeFilter && !timeMatch(info, &opts)) {
+							atomic.AddUint64(&metrics.FilesVisited, 1)
+							continue
+						}
+						mode = UnixMode(info.Mode())
+					}
+
 					// Check match
 					if opts.Match != nil && opts.Match(childPath, isDir) {
 						matchesMu.Lock()
 						result.Matches = append(result.Matches, childPath)
+						if permFilter && !isDir {
+							result.Modes[childPath] = mode
+						}
 						matchesMu.Unlock()
 						atomic.AddUint64(&metrics.Matches, 1)
+						if result.ResultChannel != nil {
+							result.ResultChannel <- childPath
+						}
 					}
 
 					if isDir && t.depth < opts.MaxDepth {
@@ -334,72 +509,15 @@ func FileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pa
 			// Try to steal work
 			stole := false
 			if opts.Deterministic {
-				for v := 0; v < opts.MaxWorkers; v++ {
-					if v == id {
-						continue
-					}
-					if opts.ChunkStealSize > 1 {
-						if chunk, ok := stealChunkBottom(deques[v], opts.ChunkStealSize); ok {
-							filtered := chunk[:0]
-							for _, t := range chunk {
-								if t.depth >= opts.StealDepthMin {
-									filtered = append(filtered, t)
-								}
-							}
-							if len(filtered) > 0 {
-								stole = true
-								atomic.AddUint64(&metrics.Steals, uint64(len(filtered)))
-								atomic.AddUint64(&metrics.StealChunks, 1)
-								for _, t := range filtered {
-									pushTop(deques[id], t)
-								}
-								break
-							}
-						}
-					} else {
-						if t, ok := stealBottom(deques[v]); ok {
-							if t.depth >= opts.StealDepthMin {
-								stole = true
-								atomic.AddUint64(&metrics.Steals, 1)
-								pushTop(deques[id], t)
-								break
-							}
-						}
+				for v := 0; v < opts.MaxWorkers && !stole; v++ {
+					if v != id {
+						stole = stealFrom(deques[v], deques[id])
 					}
 				}
 			} else {
-				for tries := 0; tries < opts.MaxWorkers-1; tries++ {
-					v := rnd.Intn(opts.MaxWorkers)
-					if v == id {
-						continue
-					}
-					if opts.ChunkStealSize > 1 {
-						if chunk, ok := stealChunkBottom(deques[v], opts.ChunkStealSize); ok {
-							filtered := chunk[:0]
-							for _, t := range chunk {
-								if t.depth >= opts.StealDepthMin {
-									filtered = append(filtered, t)
-								}
-							}
-							if len(filtered) > 0 {
-								stole = true
-								atomic.AddUint64(&metrics.Steals, uint64(len(filtered)))
-								atomic.AddUint64(&metrics.StealChunks, 1)
-								for _, t := range filtered {
-									pushTop(deques[id], t)
-								}
-								break
-							}
-						}
-					} else {
-						if t, ok := stealBottom(deques[v]); ok {
-							if t.depth >= opts.StealDepthMin {
-								stole = true
-								atomic.AddUint64(&metrics.Steals, 1)
-								pushTop(deques[id], t)
-								break
-							}
-						}
+				for tries := 0; tries < opts.MaxWorkers-1 && !stole; tries++ {
+					if v := rnd.Intn(opts.MaxWorkers); v != id {
+						stole = stealFrom(deques[v], deques[id])
 					}
 				}
 			}
@@ -425,9 +543,31 @@ func FileTraverse(ctx context.Context, root string, opts FileTraverseOptions, pa
 
 	if !rootInfo.IsDir() {
 		// Root is a file, just check if it matches
+		if hasSkippedExtension(filepath.Base(root), opts.SkipExtensions) {
+			metrics.FilesVisited = 1
+			metrics.ElapsedNs = time.Since(start).Nanoseconds()
+			result.Metrics = metrics
+			return result
+		}
+		if permFilter || timeFilter {
+			info, err := os.Lstat(root)
+			if err != nil || (permFilter && !permissionMatch(info, &opts)) ||
+				(timeFilter && !timeMatch(info, &opts)) {
+				metrics.FilesVisited = 1
+				metrics.ElapsedNs = time.Since(start).Nanoseconds()
+				result.Metrics = metrics
+				return result
+			}
+			if permFilter {
+				result.Modes[root] = UnixMode(info.Mode())
+			}
+		}
 		if opts.Match != nil && opts.Match(root, false) {
 			result.Matches = append(result.Matches, root)
 			metrics.Matches = 1
+			if result.ResultChannel != nil {
+				result.ResultChannel <- root
+			}
 		}
 		metrics.FilesVisited = 1
 		metrics.ElapsedNs = time.Since(start).Nanoseconds()
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Every task is too shallow to steal, so each steal attempt hands its tasks
+// back to the victim. None may be lost: the traversal must finish and find
+// every file.
+func TestFileTraverseShallowSteals(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			dir := filepath.Join(root, fmt.Sprint(i), fmt.Sprint(j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "f.txt"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, chunk := range []int{1, 4} {
+		for _, deterministic := range []bool{false, true} {
+			opts := DefaultFileOptions(4)
+			opts.ChunkStealSize = chunk
+			opts.Deterministic = deterministic
+			opts.StealDepthMin = 100
+			opts.QueuePressureLow = 1 << 20 // Queue every subdirectory
+			opts.QueuePressureHigh = 1 << 20
+			opts.Match = func(path string, isDir bool) bool {
+				time.Sleep(100 * time.Microsecond) // Give idle workers time to steal
+				return !isDir && filepath.Base(path) == "f.txt"
+			}
+
+			done := make(chan *FileTraverseResult, 1)
+			go func() { done <- FileTraverse(context.Background(), root, opts, nil) }()
+			select {
+			case result := <-done:
+				if len(result.Matches) != 64 {
+					t.Errorf("chunk %d, deterministic %v: %d matches, want 64",
+						chunk, deterministic, len(result.Matches))
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("chunk %d, deterministic %v: traversal did not finish", chunk, deterministic)
+			}
+		}
+	}
+}
+
+// With FixedString set the pattern is a literal, so "a.b" matches only
+// itself; without it, it is a regex that also matches "axb"
+func TestGrepFilesFixedString(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"dot.txt": "one\nsee a.b here\n",
+		"x.txt":   "axb\n",
+		"no.txt":  "nothing\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		fixed bool
+		want  []string
+	}{
+		{true, []string{"dot.txt:2: see a.b here"}},
+		{false, []string{"dot.txt:2: see a.b here", "x.txt:1: axb"}},
+	} {
+		opts := grepFileOptions(root, regexp.MustCompile(`\.txt$`), 2)
+		opts.FixedString = tt.fixed
+		result, err := GrepFiles(context.Background(), root, opts, "a.b", GrepOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range result.matches {
+			got = append(got, strings.TrimPrefix(line, root+string(filepath.Separator)))
+		}
+		sort.Strings(got)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("fixed %v: got %q, want %q", tt.fixed, got, tt.want)
+		}
+	}
+
+	// An invalid regex is only an error without FixedString
+	opts := grepFileOptions(root, regexp.MustCompile(`\.txt$`), 2)
+	if _, err := GrepFiles(context.Background(), root, opts, "a(b", GrepOptions{}); err == nil {
+		t.Error("invalid regex accepted")
+	}
+	opts.FixedString = true
+	if _, err := GrepFiles(context.Background(), root, opts, "a(b", GrepOptions{}); err != nil {
+		t.Errorf("literal a(b: %v", err)
+	}
+}
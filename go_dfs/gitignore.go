@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// gitignoreRule is one pattern line of a .gitignore file
+type gitignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes what earlier rules ignored
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// PatternSet holds the rules of one .gitignore file, in file order
+type PatternSet struct {
+	Base  string // Directory the patterns are relative to
+	rules []gitignoreRule
+}
+
+// ParseGitignore parses .gitignore content whose patterns are relative to
+// base. Patterns without a / (other than a trailing one) match at any
+// depth; the others are anchored to base. Lines that do not compile are
+// skipped.
+func ParseGitignore(base string, content []byte) *PatternSet {
+	set := &PatternSet{Base: base}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule gitignoreRule
+		switch {
+		case strings.HasPrefix(line, `\#`), strings.HasPrefix(line, `\!`):
+			line = line[1:]
+		case strings.HasPrefix(line, "!"):
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		re, err := regexp.Compile(GlobToRegex(line))
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		set.rules = append(set.rules, rule)
+	}
+	return set
+}
+
+// Match checks rel (relative to Base, / separated) against the rules. The
+// last matching rule decides: ignored is false when it is a negation.
+// matched is false when no rule applies.
+func (s *PatternSet) Match(rel string, isDir bool) (ignored, matched bool) {
+	for _, rule := range s.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(rel) {
+			ignored, matched = !rule.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// FindGitRoot returns the nearest directory at or above dir containing a
+// .git entry (a directory, or a file for worktrees and submodules), or ""
+// outside any repository
+func FindGitRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// InGitRepo reports whether dir is inside a git repository
+func InGitRepo(dir string) bool {
+	return FindGitRoot(dir) != ""
+}
+
+// gitignoreCache loads each directory's .gitignore once per traversal
+type gitignoreCache struct {
+	top  string   // Repository root
+	sets sync.Map // Directory -> *PatternSet (nil without a .gitignore)
+}
+
+// patterns returns the rules of dir's .gitignore; the repository root also
+// gets .git/info/exclude, with lower precedence
+func (c *gitignoreCache) patterns(dir string) *PatternSet {
+	if set, ok := c.sets.Load(dir); ok {
+		return set.(*PatternSet)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if dir == c.top {
+		if exclude, excludeErr := os.ReadFile(filepath.Join(dir, ".git", "info", "exclude")); excludeErr == nil {
+			content = append(append(exclude, '\n'), content...)
+			err = nil
+		}
+	}
+	var set *PatternSet
+	if err == nil {
+		set = ParseGitignore(dir, content)
+	}
+	actual, _ := c.sets.LoadOrStore(dir, set)
+	return actual.(*PatternSet)
+}
+
+// ignored applies the .gitignore files from the repository root down to
+// path's directory, deeper files taking precedence
+func (c *gitignoreCache) ignored(path string, isDir bool) bool {
+	rel, err := filepath.Rel(c.top, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return DefaultPrune(path, isDir)
+	}
+	rel = filepath.ToSlash(rel)
+
+	ignored := false
+	dir := c.top
+	elems := strings.Split(rel, "/")
+	for i := range elems {
+		if set := c.patterns(dir); set != nil {
+			if ign, ok := set.Match(strings.Join(elems[i:], "/"), isDir); ok {
+				ignored = ign
+			}
+		}
+		dir = filepath.Join(dir, elems[i])
+	}
+	return ignored
+}
+
+// GitignoreAwarePrune returns a prune function for traversals of root that
+// skips what DefaultPrune skips (.git among it) and whatever the
+// repository's .gitignore files (and .git/info/exclude) ignore. .gitignore
+// files are read on first use and cached by directory. Outside a
+// repository it is DefaultPrune.
+func GitignoreAwarePrune(root string) func(path string, isDir bool) bool {
+	top := FindGitRoot(root)
+	if top == "" {
+		return DefaultPrune
+	}
+	cache := &gitignoreCache{top: top}
+	return func(path string, isDir bool) bool {
+		if DefaultPrune(path, isDir) {
+			return true
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return cache.ignored(path, isDir)
+	}
+}
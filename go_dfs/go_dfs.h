@@ -21,7 +21,7 @@ extern const char *_GoStringPtr(_GoString_ s);
 /* Start of preamble from import "C" comments.  */
 
 
-#line 15 "main.go"
+#line 27 "main.go"
 
 #include <stdlib.h>
 #include <stdint.h>
@@ -32,6 +32,7 @@ extern void api_message(const char *msg);
 extern void *api_current_buffer(void);
 extern char *api_buffer_contents(void *bp, size_t *len);
 extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
 extern void api_get_point(int *line, int *col);
 extern void api_set_point(int line, int col);
 extern int api_buffer_insert(const char *text, size_t len);
@@ -39,6 +40,7 @@ extern void *api_buffer_create(const char *name);
 extern int api_buffer_switch(void *bp);
 extern int api_buffer_clear(void *bp);
 extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_prompt_yn(const char *prompt);
 extern void api_free(void *ptr);
 extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
@@ -108,9 +110,24 @@ extern "C" {
 #endif
 
 extern void dfs_init(void* api);
+extern void dfs_cleanup(void);
 extern int go_dfs_find(int f, int n);
 extern int go_dfs_grep(int f, int n);
+extern int go_dfs_grep_fixed(int f, int n);
+extern int go_dfs_cancel(int f, int n);
+extern int go_dfs_watch(int f, int n);
+extern int go_dfs_unwatch(int f, int n);
+extern void go_dfs_flush(void);
+extern int go_dfs_result_enter(void);
+extern int go_dfs_open_result(int f, int n);
+extern int go_dfs_next_result(int f, int n);
+extern int go_dfs_prev_result(int f, int n);
+extern int go_dfs_replace(int f, int n);
+extern int go_dfs_duplicates(int f, int n);
+extern int go_dfs_remove_duplicates(int f, int n);
 extern int go_dfs_count(int f, int n);
+extern int go_dfs_suid(int f, int n);
+extern int go_dfs_world_writable(int f, int n);
 extern int go_dfs_tree(int f, int n);
 
 #ifdef __cplusplus
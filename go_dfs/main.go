@@ -4,9 +4,21 @@
 // work-stealing deques and adaptive parallelism.
 //
 // Commands:
-//   dfs-find      - Find files matching pattern (concurrent)
-//   dfs-grep      - Search file contents concurrently
+//   dfs-find      - Find files matching a glob or regex (concurrent)
+//   dfs-grep      - Search file contents concurrently (with context lines),
+//                   writing matches found so far at each key press
+//   dfs-grep-fixed - Search file contents for a literal string
+//   dfs-open-result - Open the result on the current line (also Enter)
+//   dfs-next-result / dfs-prev-result - Step through results, opening each
+//   dfs-cancel    - Cancel the running dfs-grep
+//   dfs-watch     - Run dfs-find or dfs-grep and re-run it when files change
+//   dfs-unwatch   - Stop the running dfs-watch
+//   dfs-replace   - Search and replace across files, with a preview
+//   dfs-duplicates - Find duplicate files by content hash (C-u: by name)
+//   dfs-remove-duplicates - Delete duplicates, choosing the copy to keep
 //   dfs-count     - Count files/directories concurrently
+//   dfs-suid      - Find SUID files (mode 4000)
+//   dfs-world-writable - Find world-writable files (mode 002)
 //
 // Built with CGO as a shared library for μEmacs extension system.
 
@@ -22,6 +34,7 @@ extern void api_message(const char *msg);
 extern void *api_current_buffer(void);
 extern char *api_buffer_contents(void *bp, size_t *len);
 extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
 extern void api_get_point(int *line, int *col);
 extern void api_set_point(int line, int col);
 extern int api_buffer_insert(const char *text, size_t len);
@@ -29,6 +42,7 @@ extern void *api_buffer_create(const char *name);
 extern int api_buffer_switch(void *bp);
 extern int api_buffer_clear(void *bp);
 extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_prompt_yn(const char *prompt);
 extern void api_free(void *ptr);
 extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
@@ -38,12 +52,15 @@ extern int api_find_file_line(const char *path, int line);
 import "C"
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -75,12 +92,19 @@ type TraversalResult struct {
 	matches []string
 	count   int64
 	errors  []string
+	stream  chan<- string // Also receives each line as it is added, if set
+
+	// streamMu keeps lines on stream in the order of matches; it is taken
+	// before mu is released so sends don't hold mu
+	streamMu sync.Mutex
+
+	binarySkipped uint64 // Files a content search skipped as binary
 }
 
 func (r *TraversalResult) AddMatch(path string) {
 	r.mu.Lock()
 	r.matches = append(r.matches, path)
-	r.mu.Unlock()
+	r.send(path)
 	atomic.AddInt64(&r.count, 1)
 }
 
@@ -106,8 +130,9 @@ func ConcurrentFind(root string, pattern *regexp.Regexp, maxWorkers int) *Traver
 		return pattern.MatchString(filepath.Base(path))
 	}
 
-	// Use default prune (skips .git, node_modules, etc.)
-	opts.Prune = DefaultPrune
+	// Inside a repository skip what .gitignore ignores, elsewhere the
+	// default prune (.git, node_modules, etc.)
+	opts.UseGitignore = InGitRepo(root)
 
 	// Run work-stealing traversal
 	ctx := context.Background()
@@ -121,47 +146,202 @@ func ConcurrentFind(root string, pattern *regexp.Regexp, maxWorkers int) *Traver
 	return result
 }
 
-// ConcurrentGrep searches file contents in parallel
-func ConcurrentGrep(root string, filePattern, contentPattern *regexp.Regexp, maxWorkers int) *TraversalResult {
+// findOptions sets opts.Match to match pattern the way FindWithOptions does
+func findOptions(root string, pattern *regexp.Regexp, opts FileTraverseOptions, matchPath bool) FileTraverseOptions {
+	opts.Match = func(path string, isDir bool) bool {
+		if !matchPath {
+			return pattern.MatchString(filepath.Base(path))
+		}
+		rel, err := filepath.Rel(root, path)
+		return err == nil && pattern.MatchString(filepath.ToSlash(rel))
+	}
+	return opts
+}
+
+// FindWithOptions is ConcurrentFind with caller-supplied traversal options
+// (e.g. a modification time window). With matchPath set the pattern is
+// matched against each path relative to root (with / separators) instead
+// of its base name.
+func FindWithOptions(root string, pattern *regexp.Regexp, opts FileTraverseOptions, matchPath bool) *TraversalResult {
 	result := &TraversalResult{matches: make([]string, 0, 100)}
 
+	opts = findOptions(root, pattern, opts, matchPath)
+	ftResult := FileTraverse(context.Background(), root, opts, pattern)
+	result.matches = ftResult.Matches
+	result.count = int64(len(ftResult.Matches))
+	result.errors = ftResult.Errors
+
+	return result
+}
+
+// GrepOptions controls how content matches are reported
+type GrepOptions struct {
+	ContextBefore int  // Lines shown before each match
+	ContextAfter  int  // Lines shown after each match
+	SearchBinary  bool // Also search files IsBinaryFile reports as binary
+
+	// Results, if set, receives the output lines as they are found, each
+	// file's lines together; the caller closes it after the grep returns
+	Results chan<- string
+}
+
+// hasContext reports whether context lines are requested
+func (o GrepOptions) hasContext() bool {
+	return o.ContextBefore > 0 || o.ContextAfter > 0
+}
+
+// AddBlock appends one file's output lines holding matches matches, kept
+// together. With sep set, blocks are separated by "--".
+func (r *TraversalResult) AddBlock(lines []string, matches int, sep bool) {
+	if len(lines) == 0 {
+		return
+	}
+	r.mu.Lock()
+	if sep && len(r.matches) > 0 {
+		lines = append([]string{"--"}, lines...)
+	}
+	r.matches = append(r.matches, lines...)
+	r.send(lines...)
+	atomic.AddInt64(&r.count, int64(matches))
+}
+
+// send unlocks r.mu, which the caller holds, and then puts lines on the
+// stream, if any. A slow reader of the stream holds up only other sends.
+func (r *TraversalResult) send(lines ...string) {
+	if r.stream == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.streamMu.Lock()
+	r.mu.Unlock()
+	for _, line := range lines {
+		r.stream <- line
+	}
+	r.streamMu.Unlock()
+}
+
+// grepContent formats the lines of content for which match is true, as
+// path:N: line, with the context lines requested by opts as path-N- line
+// and "--" between disjoint blocks (ripgrep style). It also returns the
+// number of matching lines.
+func grepContent(path string, content []byte, match func(line []byte) bool, opts GrepOptions) ([]string, int) {
+	lines := bytes.Split(content, []byte("\n"))
+	if n := len(lines); n > 1 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1] // Trailing newline
+	}
+
+	var out []string
+	matches := 0
+	lastPrinted := -1   // Last line written, match or context
+	lastMatchLine := -1 // Last matching line, for after-context
+	for i, line := range lines {
+		if match(line) {
+			start := i - opts.ContextBefore
+			if start <= lastPrinted {
+				start = lastPrinted + 1
+			}
+			if start < 0 {
+				start = 0
+			}
+			if lastPrinted >= 0 && start > lastPrinted+1 && opts.hasContext() {
+				out = append(out, "--")
+			}
+			for j := start; j < i; j++ {
+				out = append(out, fmt.Sprintf("%s-%d- %s", path, j+1, bytes.TrimSpace(lines[j])))
+			}
+			out = append(out, fmt.Sprintf("%s:%d: %s", path, i+1, bytes.TrimSpace(line)))
+			matches++
+			lastPrinted, lastMatchLine = i, i
+		} else if lastMatchLine >= 0 && i-lastMatchLine <= opts.ContextAfter {
+			out = append(out, fmt.Sprintf("%s-%d- %s", path, i+1, bytes.TrimSpace(line)))
+			lastPrinted = i
+		}
+	}
+	return out, matches
+}
+
+// grepFileOptions selects the files a content search of root reads: those
+// whose name matches filePattern, minus ignored and generated files
+func grepFileOptions(root string, filePattern *regexp.Regexp, workers int) FileTraverseOptions {
+	opts := DefaultFileOptions(workers)
+	opts.Match = func(path string, isDir bool) bool {
+		return filePattern.MatchString(filepath.Base(path))
+	}
+	opts.UseGitignore = InGitRepo(root)
+	opts.SkipExtensions = DefaultSkipExtensions
+	return opts
+}
+
+// ConcurrentGrep searches file contents in parallel. Files are searched
+// as the traversal finds them rather than after it has finished.
+// Cancelling ctx stops the search with the matches found so far.
+func ConcurrentGrep(ctx context.Context, root string, filePattern, contentPattern *regexp.Regexp, maxWorkers int, grepOpts GrepOptions) *TraversalResult {
 	if maxWorkers <= 0 {
 		maxWorkers = runtime.NumCPU()
 	}
+	opts := grepFileOptions(root, filePattern, maxWorkers/2)
+	return grepStreamed(ctx, root, opts, contentPattern.Match, maxWorkers, grepOpts)
+}
 
-	// First find all matching files
-	files := ConcurrentFind(root, filePattern, maxWorkers/2)
-
-	// Then search contents in parallel
-	fileCh := make(chan string, len(files.matches))
-	for _, f := range files.matches {
-		fileCh <- f
+// GrepFiles searches the contents of the files opts selects under root
+// for pattern: a literal with opts.FixedString set, searched during the
+// traversal (grepWhileTraversing), else a regular expression searched by
+// workers fed with the files found (grepStreamed).
+func GrepFiles(ctx context.Context, root string, opts FileTraverseOptions, pattern string, grepOpts GrepOptions) (*TraversalResult, error) {
+	match, err := opts.contentMatcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if opts.FixedString {
+		return grepWhileTraversing(ctx, root, opts, match, grepOpts), nil
 	}
-	close(fileCh)
+	return grepStreamed(ctx, root, opts, match, runtime.NumCPU(), grepOpts), nil
+}
+
+// grepStreamed searches the files opts selects with match in workers
+// content workers as the traversal streams them
+func grepStreamed(ctx context.Context, root string, opts FileTraverseOptions, match func([]byte) bool, workers int, grepOpts GrepOptions) *TraversalResult {
+	result := &TraversalResult{matches: make([]string, 0, 100), stream: grepOpts.Results}
+
+	// Find matching files, streaming them to the content workers
+	files := FileTraverseStream(ctx, root, opts, nil)
+
+	var binarySkipped uint64
 
 	var wg sync.WaitGroup
-	for i := 0; i < maxWorkers; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for path := range fileCh {
+			for path := range files.ResultChannel {
+				if ctx.Err() != nil {
+					continue // Cancelled: drain without reading
+				}
+				if !grepOpts.SearchBinary {
+					binary, err := IsBinaryFile(path)
+					if binary {
+						atomic.AddUint64(&binarySkipped, 1)
+					}
+					if err != nil || binary {
+						continue
+					}
+				}
 				content, err := os.ReadFile(path)
 				if err != nil {
 					continue
 				}
-				if contentPattern.Match(content) {
+				if match(content) {
 					// Find line numbers
-					lines := strings.Split(string(content), "\n")
-					for i, line := range lines {
-						if contentPattern.MatchString(line) {
-							result.AddMatch(fmt.Sprintf("%s:%d: %s", path, i+1, strings.TrimSpace(line)))
-						}
-					}
+					lines, n := grepContent(path, content, match, grepOpts)
+					result.AddBlock(lines, n, grepOpts.hasContext())
 				}
 			}
 		}()
 	}
 	wg.Wait()
+	files.Metrics.BinarySkipped = binarySkipped
+	result.errors = files.Errors
+	result.binarySkipped = binarySkipped
 
 	return result
 }
@@ -171,11 +351,56 @@ func dfs_init(api unsafe.Pointer) {
 	// Nothing special to initialize
 }
 
+//export dfs_cleanup
+func dfs_cleanup() {
+	if w := activeWatch.Swap(nil); w != nil {
+		w.Stop()
+	}
+}
+
+// parseTimeFilter turns a time filter into a point in time: a duration
+// back from now (30m, 1h, 3d, 2w) or a date (2024-01-01, or RFC3339)
+func parseTimeFilter(s string) (time.Time, error) {
+	now := time.Now()
+	if n := len(s); n >= 2 {
+		if count, err := strconv.Atoi(s[:n-1]); err == nil && count >= 0 {
+			unit := map[byte]time.Duration{
+				'm': time.Minute,
+				'h': time.Hour,
+				'd': 24 * time.Hour,
+				'w': 7 * 24 * time.Hour,
+			}[s[n-1]]
+			if unit != 0 {
+				return now.Add(-time.Duration(count) * unit), nil
+			}
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration (30m, 1h, 3d, 2w) nor a date (2024-01-01)", s)
+}
+
 //export go_dfs_find
 func go_dfs_find(f, n C.int) C.int {
+	// Prefix argument (C-u) forces regex mode for glob-like input
+	return dfsFind(int(f) != 0, false)
+}
+
+// dfsFind prompts for a file pattern and time filter and lists matching
+// files in *dfs-find*. With watch set the search is re-run whenever files
+// under the root change, until dfs-unwatch.
+func dfsFind(forceRegex, watch bool) C.int {
 	// Prompt for pattern
+	promptText := "Find files matching (glob or regex): "
+	if forceRegex {
+		promptText = "Find files matching (regex): "
+	}
 	var patternBuf [256]C.char
-	if C.api_prompt(C.CString("Find files matching: "), &patternBuf[0], 256) < 0 {
+	if C.api_prompt(C.CString(promptText), &patternBuf[0], 256) < 0 {
 		return 0
 	}
 	pattern := C.GoString(&patternBuf[0])
@@ -183,7 +408,17 @@ func go_dfs_find(f, n C.int) C.int {
 		pattern = ".*" // Match all
 	}
 
-	re, err := regexp.Compile(pattern)
+	var re *regexp.Regexp
+	var err error
+	kind := "regex"
+	if forceRegex {
+		re, err = regexp.Compile(pattern)
+	} else {
+		if IsGlob(pattern) {
+			kind = "glob"
+		}
+		re, err = ParsePattern(pattern)
+	}
 	if err != nil {
 		msg := C.CString(fmt.Sprintf("Invalid pattern: %v", err))
 		C.api_message(msg)
@@ -191,6 +426,31 @@ func go_dfs_find(f, n C.int) C.int {
 		return 0
 	}
 
+	// Optional modification time filter
+	var timeBuf [64]C.char
+	timePrompt := C.CString("Modified since (e.g. 1h, 3d, 2w, 2024-01-01; - prefix for before; empty for any): ")
+	if C.api_prompt(timePrompt, &timeBuf[0], 64) < 0 {
+		C.free(unsafe.Pointer(timePrompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(timePrompt))
+	var after, before *time.Time
+	timeSpec := strings.TrimSpace(C.GoString(&timeBuf[0]))
+	if timeSpec != "" {
+		t, err := parseTimeFilter(strings.TrimPrefix(timeSpec, "-"))
+		if err != nil {
+			msg := C.CString(fmt.Sprintf("Invalid time filter: %v", err))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 0
+		}
+		if strings.HasPrefix(timeSpec, "-") {
+			before = &t
+		} else {
+			after = &t
+		}
+	}
+
 	// Get current directory from buffer filename or use cwd
 	bp := C.api_current_buffer()
 	var root string
@@ -204,35 +464,48 @@ func go_dfs_find(f, n C.int) C.int {
 		root, _ = os.Getwd()
 	}
 
-	// Run concurrent find
-	start := time.Now()
-	result := ConcurrentFind(root, re, runtime.NumCPU())
-	elapsed := time.Since(start)
+	// Run concurrent find; patterns with a / match the relative path
+	opts := DefaultFileOptions(runtime.NumCPU())
+	opts.ModifiedAfter, opts.ModifiedBefore = after, before
+	opts.UseGitignore = InGitRepo(root)
+	search := func() (string, int, time.Duration) {
+		start := time.Now()
+		result := FindWithOptions(root, re, opts, strings.Contains(pattern, "/"))
+		elapsed := time.Since(start)
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("DFS Find: %s (%s) in %s%s\n", pattern, kind, root, watchTag(watch)))
+		if after != nil {
+			sb.WriteString(fmt.Sprintf("Modified since %s\n", after.Format("2006-01-02 15:04")))
+		}
+		if before != nil {
+			sb.WriteString(fmt.Sprintf("Modified before %s\n", before.Format("2006-01-02 15:04")))
+		}
+		sb.WriteString(fmt.Sprintf("Found %d matches in %v\n\n", result.count, elapsed))
+
+		for _, match := range result.matches {
+			// Make path relative if possible
+			rel, err := filepath.Rel(root, match)
+			if err == nil {
+				sb.WriteString(rel + "\n")
+			} else {
+				sb.WriteString(match + "\n")
+			}
+		}
+		return sb.String(), int(result.count), elapsed
+	}
+	output, count, elapsed := search()
 
 	// Create results buffer
 	resultBuf := C.api_buffer_create(C.CString("*dfs-find*"))
 	if resultBuf == nil {
 		return 0
 	}
+	stopWatch("*dfs-find*")
+	setResultRoot("*dfs-find*", root)
 	C.api_buffer_switch(resultBuf)
 	C.api_buffer_clear(resultBuf)
 
-	// Build output
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("DFS Find: %s in %s\n", pattern, root))
-	sb.WriteString(fmt.Sprintf("Found %d matches in %v\n\n", result.count, elapsed))
-
-	for _, match := range result.matches {
-		// Make path relative if possible
-		rel, err := filepath.Rel(root, match)
-		if err == nil {
-			sb.WriteString(rel + "\n")
-		} else {
-			sb.WriteString(match + "\n")
-		}
-	}
-
-	output := sb.String()
 	coutput := C.CString(output)
 	C.api_buffer_insert(coutput, C.size_t(len(output)))
 	C.free(unsafe.Pointer(coutput))
@@ -240,15 +513,99 @@ func go_dfs_find(f, n C.int) C.int {
 	C.api_set_point(1, 1)
 	C.api_update_display()
 
-	msg := C.CString(fmt.Sprintf("Found %d files in %v", result.count, elapsed))
+	text := fmt.Sprintf("Found %d files in %v", count, elapsed)
+	if watch {
+		watchOpts := findOptions(root, re, opts, strings.Contains(pattern, "/"))
+		startWatch("*dfs-find*", root, watchOpts, func() (string, int, bool) {
+			output, count, _ := search()
+			return output, count, true
+		})
+		text += "; watching for changes (dfs-unwatch to stop)"
+	}
+	msg := C.CString(text)
 	C.api_message(msg)
 	C.free(unsafe.Pointer(msg))
 
 	return 1
 }
 
+// regexMetaChars are the characters that make a search pattern a regex
+const regexMetaChars = `.*+?[]{}()^$|\`
+
+// hasRegexMeta reports whether pattern contains any regex metacharacters
+func hasRegexMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, regexMetaChars)
+}
+
+// ConcurrentGrepFixed searches file contents for a literal string in parallel.
+// Files are matched and scanned during the traversal itself, so each file is
+// read once and no regex is compiled for the content search. Cancelling ctx
+// stops the search with the matches found so far.
+func ConcurrentGrepFixed(ctx context.Context, root string, filePattern *regexp.Regexp, literal string, maxWorkers int, grepOpts GrepOptions) *TraversalResult {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.NumCPU()
+	}
+	opts := grepFileOptions(root, filePattern, maxWorkers)
+	opts.FixedString = true
+	result, _ := GrepFiles(ctx, root, opts, literal, grepOpts) // A literal always compiles
+	return result
+}
+
+// grepWhileTraversing searches each file opts selects with match as the
+// traversal finds it
+func grepWhileTraversing(ctx context.Context, root string, opts FileTraverseOptions, match func([]byte) bool, grepOpts GrepOptions) *TraversalResult {
+	result := &TraversalResult{matches: make([]string, 0, 100), stream: grepOpts.Results}
+
+	var binarySkipped uint64
+	fileMatch := opts.Match
+	opts.Match = func(path string, isDir bool) bool {
+		if isDir || ctx.Err() != nil || (fileMatch != nil && !fileMatch(path, false)) {
+			return false
+		}
+		if !grepOpts.SearchBinary {
+			binary, err := IsBinaryFile(path)
+			if binary {
+				atomic.AddUint64(&binarySkipped, 1)
+			}
+			if err != nil || binary {
+				return false
+			}
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || !match(content) {
+			return false
+		}
+		// Find line numbers
+		lines, n := grepContent(path, content, match, grepOpts)
+		result.AddBlock(lines, n, grepOpts.hasContext())
+		return true
+	}
+
+	ftResult := FileTraverse(ctx, root, opts, nil)
+	ftResult.Metrics.BinarySkipped = binarySkipped
+	result.errors = ftResult.Errors
+	result.binarySkipped = binarySkipped
+
+	return result
+}
+
 //export go_dfs_grep
 func go_dfs_grep(f, n C.int) C.int {
+	return dfsGrep(false, false)
+}
+
+//export go_dfs_grep_fixed
+func go_dfs_grep_fixed(f, n C.int) C.int {
+	return dfsGrep(true, false)
+}
+
+// dfsGrep prompts for file and content patterns and shows matches in
+// *dfs-grep*. Content patterns without regex metacharacters (or any
+// pattern when fixed is set) are searched as literal strings. The search
+// runs in the background; dfs-cancel or a new search stops it. With watch
+// set the search is re-run whenever files under the root change, until
+// dfs-unwatch.
+func dfsGrep(fixed, watch bool) C.int {
 	// Prompt for file pattern
 	var fileBuf [256]C.char
 	if C.api_prompt(C.CString("File pattern (regex): "), &fileBuf[0], 256) < 0 {
@@ -260,8 +617,12 @@ func go_dfs_grep(f, n C.int) C.int {
 	}
 
 	// Prompt for content pattern
+	searchPrompt := "Search for: "
+	if fixed {
+		searchPrompt = "Search for (literal): "
+	}
 	var contentBuf [256]C.char
-	if C.api_prompt(C.CString("Search for: "), &contentBuf[0], 256) < 0 {
+	if C.api_prompt(C.CString(searchPrompt), &contentBuf[0], 256) < 0 {
 		return 0
 	}
 	contentPattern := C.GoString(&contentBuf[0])
@@ -269,17 +630,29 @@ func go_dfs_grep(f, n C.int) C.int {
 		return 0
 	}
 
-	fileRe, err := regexp.Compile(filePattern)
-	if err != nil {
-		msg := C.CString(fmt.Sprintf("Invalid file pattern: %v", err))
-		C.api_message(msg)
-		C.free(unsafe.Pointer(msg))
+	// Prompt for context lines around each match
+	var contextBuf [16]C.char
+	contextPrompt := C.CString("Context lines (default 0): ")
+	if C.api_prompt(contextPrompt, &contextBuf[0], 16) < 0 {
+		C.free(unsafe.Pointer(contextPrompt))
 		return 0
 	}
+	C.free(unsafe.Pointer(contextPrompt))
+	var grepOpts GrepOptions
+	if contextStr := strings.TrimSpace(C.GoString(&contextBuf[0])); contextStr != "" {
+		n, err := strconv.Atoi(contextStr)
+		if err != nil || n < 0 {
+			msg := C.CString(fmt.Sprintf("Invalid context line count: %s", contextStr))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+			return 0
+		}
+		grepOpts.ContextBefore, grepOpts.ContextAfter = n, n
+	}
 
-	contentRe, err := regexp.Compile(contentPattern)
+	fileRe, err := regexp.Compile(filePattern)
 	if err != nil {
-		msg := C.CString(fmt.Sprintf("Invalid search pattern: %v", err))
+		msg := C.CString(fmt.Sprintf("Invalid file pattern: %v", err))
 		C.api_message(msg)
 		C.free(unsafe.Pointer(msg))
 		return 0
@@ -298,64 +671,1029 @@ func go_dfs_grep(f, n C.int) C.int {
 		root, _ = os.Getwd()
 	}
 
-	// Run concurrent grep
-	start := time.Now()
-	result := ConcurrentGrep(root, fileRe, contentRe, runtime.NumCPU())
-	elapsed := time.Since(start)
+	// Literal searches skip the regex engine entirely
+	searchOpts := grepFileOptions(root, fileRe, runtime.NumCPU())
+	searchOpts.FixedString = fixed || !hasRegexMeta(contentPattern)
+	if _, err := searchOpts.contentMatcher(contentPattern); err != nil {
+		msg := C.CString(fmt.Sprintf("Invalid search pattern: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
 
 	// Create results buffer
 	resultBuf := C.api_buffer_create(C.CString("*dfs-grep*"))
 	if resultBuf == nil {
 		return 0
 	}
+	stopWatch("*dfs-grep*")
+	setResultRoot("*dfs-grep*", root)
 	C.api_buffer_switch(resultBuf)
 	C.api_buffer_clear(resultBuf)
 
-	// Build output
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("DFS Grep: '%s' in files matching '%s'\n", contentPattern, filePattern))
-	sb.WriteString(fmt.Sprintf("Root: %s\n", root))
-	sb.WriteString(fmt.Sprintf("Found %d matches in %v\n\n", result.count, elapsed))
+	mode := "regex"
+	if searchOpts.FixedString {
+		mode = "fixed"
+	}
+
+	header := fmt.Sprintf("DFS Grep (%s): '%s' in files matching '%s'%s\n", mode, contentPattern, filePattern, watchTag(watch))
+	header += fmt.Sprintf("Root: %s\n", root)
+	if grepOpts.hasContext() {
+		header += fmt.Sprintf("Context: %d lines\n", grepOpts.ContextBefore)
+	}
+	searching := header + "Searching...\n\n"
+	insertText(searching)
+	C.api_update_display()
+
+	grep := func(ctx context.Context, opts GrepOptions) *TraversalResult {
+		result, _ := GrepFiles(ctx, root, searchOpts, contentPattern, opts) // Pattern checked above
+		return result
+	}
+	if watch {
+		// Re-runs replace any search still streaming into the buffer and
+		// write it in one go once done
+		startWatch("*dfs-grep*", root, grepFileOptions(root, fileRe, 0), func() (string, int, bool) {
+			ctx, _, done := beginSearch()
+			defer done()
+			start := time.Now()
+			result := grep(ctx, grepOpts)
+			if ctx.Err() != nil {
+				return "", 0, false
+			}
+			return grepResultText(header, "Found", result, time.Since(start)), int(result.count), true
+		})
+	}
+
+	// Run concurrent grep in the background so dfs-cancel can stop it,
+	// queueing lines for the buffer as they arrive
+	ctx, id, done := beginSearch()
+	lines := make(chan string, 256)
+	grepOpts.Results = lines
+	start := time.Now()
+	go func() {
+		defer done()
+
+		var result *TraversalResult
+		go func() {
+			result = grep(ctx, grepOpts)
+			close(lines)
+		}()
+		streamResults("*dfs-grep*", strings.Count(searching, "\n")+1, lines, id, start)
+		elapsed := time.Since(start)
+
+		// A newer search owns the buffer now
+		if searchID.Load() != id {
+			return
+		}
+
+		// Final rewrite with the match count in the header
+		status := "Found"
+		if ctx.Err() != nil {
+			status = "Cancelled after"
+		}
+		queueUpdate(bufferUpdate{
+			buffer:  "*dfs-grep*",
+			search:  id,
+			line:    1,
+			replace: true,
+			text:    grepResultText(header, status, result, elapsed),
+		})
+
+		text := fmt.Sprintf("Found %d matches in %v", result.count, elapsed)
+		if ctx.Err() != nil {
+			text = fmt.Sprintf("Search cancelled: %d matches in %v", result.count, elapsed)
+		}
+		if watch {
+			text += "; watching for changes (dfs-unwatch to stop)"
+		}
+		msg := C.CString(text)
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+	}()
+
+	return 1
+}
 
+// grepResultText renders a finished grep as the full *dfs-grep* contents
+func grepResultText(header, status string, result *TraversalResult, elapsed time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString(header)
+	sb.WriteString(fmt.Sprintf("%s %d matches in %v", status, result.count, elapsed))
+	if result.binarySkipped > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d binary files skipped)", result.binarySkipped))
+	}
+	sb.WriteString("\n\n")
 	for _, match := range result.matches {
 		sb.WriteString(match + "\n")
 	}
+	return sb.String()
+}
 
-	output := sb.String()
-	coutput := C.CString(output)
-	C.api_buffer_insert(coutput, C.size_t(len(output)))
-	C.free(unsafe.Pointer(coutput))
+// searchCancel cancels the running background search; searchID numbers
+// searches so that one replaced by a newer search leaves the results
+// buffer alone
+var (
+	searchCancel atomic.Pointer[context.CancelFunc]
+	searchID     atomic.Int64
+)
 
-	C.api_set_point(1, 1)
-	C.api_update_display()
+// beginSearch starts a cancellable search, cancelling the previous one.
+// done must be called when the search ends.
+func beginSearch() (ctx context.Context, id int64, done func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &cancel
+	if prev := searchCancel.Swap(p); prev != nil {
+		(*prev)()
+	}
+	id = searchID.Add(1)
+	return ctx, id, func() {
+		searchCancel.CompareAndSwap(p, nil)
+		cancel()
+	}
+}
 
-	msg := C.CString(fmt.Sprintf("Found %d matches in %v", result.count, elapsed))
+//export go_dfs_cancel
+func go_dfs_cancel(f, n C.int) C.int {
+	cancel := searchCancel.Swap(nil)
+	text := "No search running"
+	if cancel != nil {
+		(*cancel)()
+		text = "Search cancelled"
+	}
+	msg := C.CString(text)
 	C.api_message(msg)
 	C.free(unsafe.Pointer(msg))
+	if cancel == nil {
+		return 0
+	}
+	return 1
+}
+
+// activeWatch is the running dfs-watch; starting another replaces it
+var activeWatch atomic.Pointer[dfsWatch]
+
+// watchIndicator marks the header of a result buffer being watched
+const watchIndicator = " [watching]"
+
+// watchTag returns the header indicator for a watched search
+func watchTag(watch bool) string {
+	if watch {
+		return watchIndicator
+	}
+	return ""
+}
+
+// startWatch re-runs search whenever the files under root that a search
+// with opts would look at change, queueing its output to replace the
+// contents of buffer. search returns false if it was cancelled. Any
+// earlier watch is stopped.
+func startWatch(buffer, root string, opts FileTraverseOptions, search func() (output string, count int, ok bool)) {
+	var w *dfsWatch
+	w = newWatch(buffer, root, opts, func() {
+		output, count, ok := search()
+		if !ok {
+			return
+		}
+		queueUpdate(bufferUpdate{
+			buffer:  buffer,
+			line:    1,
+			replace: true,
+			text:    output,
+			// Dropped if the watch has been stopped by then
+			valid: func() bool { return activeWatch.Load() == w },
+		})
+
+		msg := C.CString(fmt.Sprintf("Files changed: %d matches in %s (dfs-unwatch to stop)", count, buffer))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+	})
+	if prev := activeWatch.Swap(w); prev != nil {
+		prev.Stop()
+		if prev.Buffer != buffer {
+			dropWatchIndicator(prev.Buffer)
+		}
+	}
+	go w.run()
+}
+
+// stopWatch stops the active watch if it is refreshing buffer, before a
+// new search takes the buffer over
+func stopWatch(buffer string) {
+	if w := activeWatch.Load(); w != nil && w.Buffer == buffer && activeWatch.CompareAndSwap(w, nil) {
+		w.Stop()
+	}
+}
+
+// dropWatchIndicator removes the [watching] mark from the header of a
+// result buffer whose watch has stopped, keeping point where it was
+func dropWatchIndicator(buffer string) {
+	cName := C.CString(buffer)
+	bp := C.api_buffer_create(cName)
+	C.free(unsafe.Pointer(cName))
+	if bp == nil {
+		return
+	}
+	content, ok := bufferText(bp)
+	if !ok {
+		return
+	}
+	header, rest, _ := strings.Cut(content, "\n")
+	if !strings.HasSuffix(header, watchIndicator) {
+		return
+	}
+	content = strings.TrimSuffix(header, watchIndicator) + "\n" + rest
+	withBuffer(bp, func() {
+		var line, col C.int
+		C.api_get_point(&line, &col)
+		C.api_buffer_clear(bp)
+		insertText(content)
+		C.api_set_point(line, col)
+	})
+	C.api_update_display()
+}
+
+//export go_dfs_watch
+func go_dfs_watch(f, n C.int) C.int {
+	var kindBuf [16]C.char
+	kindPrompt := C.CString("Watch (f)ind or (g)rep (default find): ")
+	if C.api_prompt(kindPrompt, &kindBuf[0], 16) < 0 {
+		C.free(unsafe.Pointer(kindPrompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(kindPrompt))
+
+	switch kind := strings.ToLower(strings.TrimSpace(C.GoString(&kindBuf[0]))); kind {
+	case "", "f", "find":
+		return dfsFind(false, true)
+	case "g", "grep":
+		return dfsGrep(false, true)
+	default:
+		msg := C.CString(fmt.Sprintf("Unknown search %q: expected find or grep", kind))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+}
+
+//export go_dfs_unwatch
+func go_dfs_unwatch(f, n C.int) C.int {
+	w := activeWatch.Swap(nil)
+	if w == nil {
+		msg := C.CString("No watch running")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	w.Stop()
+	dropWatchIndicator(w.Buffer)
 
+	msg := C.CString(fmt.Sprintf("Stopped watching %s", w.Root))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
 	return 1
 }
 
-//export go_dfs_count
-func go_dfs_count(f, n C.int) C.int {
-	bp := C.api_current_buffer()
-	var root string
-	if bp != nil {
-		fname := C.GoString(C.api_buffer_filename(bp))
-		if fname != "" {
-			root = filepath.Dir(fname)
+// streamFlushLines and streamFlushInterval bound how long streamed results
+// wait before being shown: every 50 lines or 200ms, whichever comes first
+const (
+	streamFlushLines    = 50
+	streamFlushInterval = 200 * time.Millisecond
+)
+
+// streamProgressInterval is how often a running search reports progress
+const streamProgressInterval = time.Second
+
+// spinnerFrames animate the progress message
+const spinnerFrames = `|/-\`
+
+// streamResults queues lines for buffer from line on as they arrive, in
+// batches, and reports progress every second until lines is closed. Once
+// search id has been replaced by a newer one the lines are drained without
+// being shown.
+func streamResults(buffer string, line int, lines <-chan string, id int64, start time.Time) {
+	ticker := time.NewTicker(streamFlushInterval)
+	defer ticker.Stop()
+	progress := time.NewTicker(streamProgressInterval)
+	defer progress.Stop()
+
+	var batch strings.Builder
+	pending, received, frame := 0, 0, 0
+	flush := func() {
+		if pending == 0 {
+			return
 		}
+		queueUpdate(bufferUpdate{buffer: buffer, search: id, line: line, text: batch.String()})
+		line += pending
+		batch.Reset()
+		pending = 0
 	}
-	if root == "" {
-		root, _ = os.Getwd()
+
+	for {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				flush()
+				return
+			}
+			batch.WriteString(l + "\n")
+			pending++
+			received++
+			if pending >= streamFlushLines {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-progress.C:
+			if searchID.Load() != id {
+				continue
+			}
+			frame++
+			msg := C.CString(fmt.Sprintf("Searching %c %ds, %d lines (dfs-cancel to stop)",
+				spinnerFrames[frame%len(spinnerFrames)], int(time.Since(start).Seconds()), received))
+			C.api_message(msg)
+			C.free(unsafe.Pointer(msg))
+		}
 	}
+}
 
-	// Count all files
-	start := time.Now()
-	result := ConcurrentFind(root, regexp.MustCompile(".*"), runtime.NumCPU())
-	elapsed := time.Since(start)
+// bufferUpdate is a change to a result buffer made by a background search.
+// Only the editor thread may touch buffers, so updates are queued and
+// written by go_dfs_flush on the next key press.
+type bufferUpdate struct {
+	buffer  string // Result buffer name
+	search  int64  // searchID of the search that made it; 0 for any
+	line    int    // Line the text is inserted at
+	replace bool   // Replace the buffer contents instead of inserting
+	text    string
+
+	valid func() bool // If set, checked before writing; false drops it
+}
 
-	msg := C.CString(fmt.Sprintf("Counted %d files in %s (%v)", result.count, root, elapsed))
+// pendingUpdates are the buffer updates waiting for the editor thread
+var (
+	pendingMu      sync.Mutex
+	pendingUpdates []bufferUpdate
+)
+
+// queueUpdate adds u to the pending updates. Replacing a buffer drops the
+// updates queued for it before, and an insert that continues the previous
+// one is merged into it.
+func queueUpdate(u bufferUpdate) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	if u.replace {
+		kept := pendingUpdates[:0]
+		for _, p := range pendingUpdates {
+			if p.buffer != u.buffer {
+				kept = append(kept, p)
+			}
+		}
+		pendingUpdates = kept
+	} else if n := len(pendingUpdates); n > 0 {
+		last := &pendingUpdates[n-1]
+		if !last.replace && last.buffer == u.buffer && last.search == u.search &&
+			last.line+strings.Count(last.text, "\n") == u.line {
+			last.text += u.text
+			return
+		}
+	}
+	pendingUpdates = append(pendingUpdates, u)
+}
+
+// go_dfs_flush writes the pending buffer updates. It runs from the key
+// handler, on the editor thread, before the key is processed.
+//
+//export go_dfs_flush
+func go_dfs_flush() {
+	pendingMu.Lock()
+	updates := pendingUpdates
+	pendingUpdates = nil
+	pendingMu.Unlock()
+
+	for _, u := range updates {
+		if u.search != 0 && u.search != searchID.Load() {
+			continue // A newer search owns the buffer now
+		}
+		if u.valid != nil && !u.valid() {
+			continue
+		}
+		cName := C.CString(u.buffer)
+		bp := C.api_buffer_create(cName)
+		C.free(unsafe.Pointer(cName))
+		if bp == nil {
+			continue
+		}
+		withBuffer(bp, func() {
+			if u.replace {
+				C.api_buffer_clear(bp)
+				insertText(u.text)
+				C.api_set_point(1, 1)
+				return
+			}
+			// Keep point where the user left it
+			var line, col C.int
+			C.api_get_point(&line, &col)
+			C.api_set_point(C.int(u.line), 1)
+			insertText(u.text)
+			if int(line) >= u.line {
+				line += C.int(strings.Count(u.text, "\n"))
+			}
+			C.api_set_point(line, col)
+		})
+	}
+}
+
+// withBuffer runs fn with bp as the current buffer, then switches back to
+// the buffer the user was in, so results can be written without taking
+// over the window. Only call it on the editor thread.
+func withBuffer(bp unsafe.Pointer, fn func()) {
+	prev := C.api_current_buffer()
+	if prev != bp {
+		C.api_buffer_switch(bp)
+	}
+	fn()
+	if prev != nil && prev != bp {
+		C.api_buffer_switch(prev)
+	}
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// resultRoots maps each result buffer to the directory its paths are
+// relative to. lastResult is the buffer and line last opened by
+// dfs-open-result, dfs-next-result or dfs-prev-result, so stepping works
+// from the opened file too.
+var (
+	resultRoots    = map[string]string{}
+	lastResultBuf  string
+	lastResultLine int // 1-based, 0 before the first result
+)
+
+// setResultRoot records the root of a result buffer being (re)written
+func setResultRoot(name, root string) {
+	resultRoots[name] = root
+	if lastResultBuf == name {
+		lastResultLine = 0
+	}
+}
+
+// currentResultBuffer returns the name of the current buffer if it is a
+// dfs result buffer
+func currentResultBuffer() (string, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return "", false
+	}
+	cName := C.api_buffer_name(bp)
+	if cName == nil {
+		return "", false
+	}
+	name := C.GoString(cName)
+	_, ok := resultRoots[name]
+	return name, ok
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// openResult opens the file and line referred to by line (1-based) of
+// result buffer name
+func openResult(name, content string, line int) bool {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return false
+	}
+	isResult := false
+	for _, i := range ResultLines(content) {
+		if i == line-1 {
+			isResult = true
+			break
+		}
+	}
+	if !isResult {
+		return false
+	}
+
+	path, target, ok := ParseResultLine(lines[line-1], resultRoots[name], name == "*dfs-grep*")
+	if !ok {
+		return false
+	}
+	lastResultBuf, lastResultLine = name, line
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	C.api_find_file_line(cPath, C.int(target))
+	return true
+}
+
+// openResultAtPoint opens the result on the current line of the current
+// buffer, if it is a dfs result buffer
+func openResultAtPoint() bool {
+	name, ok := currentResultBuffer()
+	if !ok {
+		return false
+	}
+	content, ok := bufferText(C.api_current_buffer())
+	if !ok {
+		return false
+	}
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	return openResult(name, content, int(line))
+}
+
+//export go_dfs_result_enter
+func go_dfs_result_enter() C.int {
+	if openResultAtPoint() {
+		return 1
+	}
+	return 0
+}
+
+//export go_dfs_open_result
+func go_dfs_open_result(f, n C.int) C.int {
+	if openResultAtPoint() {
+		return 1
+	}
+	text := "No result on this line"
+	if _, ok := currentResultBuffer(); !ok {
+		text = "Not in a dfs result buffer (*dfs-grep* or *dfs-find*)"
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 0
+}
+
+//export go_dfs_next_result
+func go_dfs_next_result(f, n C.int) C.int {
+	return stepResult(1)
+}
+
+//export go_dfs_prev_result
+func go_dfs_prev_result(f, n C.int) C.int {
+	return stepResult(-1)
+}
+
+// stepResult moves to the next (dir 1) or previous (dir -1) result line
+// and opens it. From a result buffer it starts at point, elsewhere at the
+// result last opened.
+func stepResult(dir int) C.int {
+	name, inResults := currentResultBuffer()
+	line := lastResultLine
+	if inResults {
+		var cLine, cCol C.int
+		C.api_get_point(&cLine, &cCol)
+		line = int(cLine)
+	} else {
+		name = lastResultBuf
+	}
+	if name == "" {
+		msg := C.CString("No dfs results (run dfs-grep or dfs-find first)")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	cName := C.CString(name)
+	bp := C.api_buffer_create(cName)
+	C.free(unsafe.Pointer(cName))
+	if bp == nil {
+		return 0
+	}
+	content, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+
+	// Result lines are 0-based indices; line is 1-based
+	results := ResultLines(content)
+	next, pos := -1, 0
+	if dir > 0 {
+		for i, idx := range results {
+			if idx+1 > line {
+				next, pos = idx, i
+				break
+			}
+		}
+	} else {
+		for i := len(results) - 1; i >= 0; i-- {
+			if results[i]+1 < line || (line == 0 && i == len(results)-1) {
+				next, pos = results[i], i
+				break
+			}
+		}
+	}
+	if next < 0 {
+		text := "No more results"
+		if dir < 0 {
+			text = "No previous results"
+		}
+		msg := C.CString(text)
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	// Leave the result buffer's point on the result, then open it
+	C.api_buffer_switch(bp)
+	C.api_set_point(C.int(next+1), 1)
+	if !openResult(name, content, next+1) {
+		return 0
+	}
+
+	msg := C.CString(fmt.Sprintf("Result %d of %d", pos+1, len(results)))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+	return 1
+}
+
+//export go_dfs_replace
+func go_dfs_replace(f, n C.int) C.int {
+	// Prompt for file pattern, search regex and replacement
+	var fileBuf [256]C.char
+	filePrompt := C.CString("File pattern (regex): ")
+	if C.api_prompt(filePrompt, &fileBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(filePrompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(filePrompt))
+	filePattern := C.GoString(&fileBuf[0])
+	if filePattern == "" {
+		filePattern = "\\.(go|c|h|py|js|ts|rs)$" // Common source files
+	}
+
+	var searchBuf [256]C.char
+	searchPrompt := C.CString("Replace (regex): ")
+	if C.api_prompt(searchPrompt, &searchBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(searchPrompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(searchPrompt))
+	searchPattern := C.GoString(&searchBuf[0])
+	if searchPattern == "" {
+		return 0
+	}
+
+	var replBuf [256]C.char
+	replPrompt := C.CString(fmt.Sprintf("Replace '%s' with ($1 for groups): ", searchPattern))
+	if C.api_prompt(replPrompt, &replBuf[0], 256) < 0 {
+		C.free(unsafe.Pointer(replPrompt))
+		return 0
+	}
+	C.free(unsafe.Pointer(replPrompt))
+	repl := C.GoString(&replBuf[0])
+
+	fileRe, err := regexp.Compile(filePattern)
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("Invalid file pattern: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+	searchRe, err := regexp.Compile(searchPattern)
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("Invalid search pattern: %v", err))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	// Get root directory
+	bp := C.api_current_buffer()
+	var root string
+	if bp != nil {
+		fname := C.GoString(C.api_buffer_filename(bp))
+		if fname != "" {
+			root = filepath.Dir(fname)
+		}
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+
+	changes, _ := PlanReplace(root, fileRe, searchRe, repl, runtime.NumCPU())
+	if len(changes) == 0 {
+		msg := C.CString(fmt.Sprintf("No matches for '%s'", searchPattern))
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	// Preview the affected files before touching any of them
+	total := 0
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("DFS Replace: '%s' -> '%s' in files matching '%s'\n", searchPattern, repl, filePattern))
+	sb.WriteString(fmt.Sprintf("Root: %s\n\n", root))
+	for _, c := range changes {
+		rel, err := filepath.Rel(root, c.Path)
+		if err != nil {
+			rel = c.Path
+		}
+		sb.WriteString(fmt.Sprintf("%6d  %s\n", c.Count, rel))
+		total += c.Count
+	}
+	sb.WriteString(fmt.Sprintf("\n%d substitutions in %d files (originals kept as .bak)\n", total, len(changes)))
+
+	resultBuf := C.api_buffer_create(C.CString("*dfs-replace-preview*"))
+	if resultBuf == nil {
+		return 0
+	}
+	C.api_buffer_switch(resultBuf)
+	C.api_buffer_clear(resultBuf)
+	insertText(sb.String())
+	C.api_set_point(1, 1)
+	C.api_update_display()
+
+	confirm := C.CString("Apply changes?")
+	apply := C.api_prompt_yn(confirm) > 0
+	C.free(unsafe.Pointer(confirm))
+	if !apply {
+		msg := C.CString("Replace cancelled, no files changed")
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	files, substitutions, errs := ApplyReplace(changes)
+	text := fmt.Sprintf("Replaced %d occurrences in %d files", substitutions, files)
+	if len(errs) > 0 {
+		text += fmt.Sprintf(" (%d failed: %s)", len(errs), errs[0])
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// Last dfs-duplicates run, for dfs-remove-duplicates
+var (
+	lastDuplicates       []DuplicateGroup
+	lastDuplicatesRoot   string
+	lastDuplicatesByName bool
+)
+
+//export go_dfs_duplicates
+func go_dfs_duplicates(f, n C.int) C.int {
+	// Prefix argument (C-u) groups by file name instead of content
+	byName := int(f) != 0
+
+	bp := C.api_current_buffer()
+	var root string
+	if bp != nil {
+		fname := C.GoString(C.api_buffer_filename(bp))
+		if fname != "" {
+			root = filepath.Dir(fname)
+		}
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+
+	maxSize := int64(DefaultDuplicateMaxSize)
+	if !byName {
+		var sizeBuf [32]C.char
+		sizePrompt := C.CString(fmt.Sprintf("Skip files larger than MB (default %d): ", DefaultDuplicateMaxSize>>20))
+		if C.api_prompt(sizePrompt, &sizeBuf[0], 32) < 0 {
+			C.free(unsafe.Pointer(sizePrompt))
+			return 0
+		}
+		C.free(unsafe.Pointer(sizePrompt))
+		if sizeStr := strings.TrimSpace(C.GoString(&sizeBuf[0])); sizeStr != "" {
+			mb, err := strconv.Atoi(sizeStr)
+			if err != nil || mb <= 0 {
+				msg := C.CString(fmt.Sprintf("Invalid size limit: %s", sizeStr))
+				C.api_message(msg)
+				C.free(unsafe.Pointer(msg))
+				return 0
+			}
+			maxSize = int64(mb) << 20
+		}
+	}
+
+	start := time.Now()
+	groups, errs := FindDuplicates(root, byName, maxSize, runtime.NumCPU())
+	elapsed := time.Since(start)
+	lastDuplicates, lastDuplicatesRoot, lastDuplicatesByName = groups, root, byName
+
+	resultBuf := C.api_buffer_create(C.CString("*dfs-duplicates*"))
+	if resultBuf == nil {
+		return 0
+	}
+	C.api_buffer_switch(resultBuf)
+	C.api_buffer_clear(resultBuf)
+
+	mode := "content (sha256)"
+	if byName {
+		mode = "name"
+	}
+	extra := 0
+	for _, g := range groups {
+		extra += len(g.Paths) - 1
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("DFS Duplicates by %s in %s\n", mode, root))
+	sb.WriteString(fmt.Sprintf("%d groups, %d extra copies in %v (%d errors)\n\n", len(groups), extra, elapsed, len(errs)))
+	sb.WriteString(RenderDuplicates(root, groups))
+	insertText(sb.String())
+
+	C.api_set_point(1, 1)
+	C.api_update_display()
+
+	msg := C.CString(fmt.Sprintf("Duplicates: %d groups, %d extra copies in %v", len(groups), extra, elapsed))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_dfs_remove_duplicates
+func go_dfs_remove_duplicates(f, n C.int) C.int {
+	text := ""
+	switch {
+	case len(lastDuplicates) == 0:
+		text = "No duplicates to remove (run dfs-duplicates first)"
+	case lastDuplicatesByName:
+		text = "Files with the same name may differ; run dfs-duplicates without C-u"
+	}
+	if text != "" {
+		msg := C.CString(text)
+		C.api_message(msg)
+		C.free(unsafe.Pointer(msg))
+		return 0
+	}
+
+	removed := 0
+	var errs []string
+	for gi, g := range lastDuplicates {
+		var choiceBuf [16]C.char
+		prompt := C.CString(fmt.Sprintf("Group %d/%d (%s): keep which copy? (1-%d, empty to skip): ",
+			gi+1, len(lastDuplicates), filepath.Base(g.Paths[0]), len(g.Paths)))
+		if C.api_prompt(prompt, &choiceBuf[0], 16) < 0 {
+			C.free(unsafe.Pointer(prompt))
+			break
+		}
+		C.free(unsafe.Pointer(prompt))
+
+		choice := strings.TrimSpace(C.GoString(&choiceBuf[0]))
+		if choice == "" {
+			continue
+		}
+		keep, err := strconv.Atoi(choice)
+		if err != nil || keep < 1 || keep > len(g.Paths) {
+			errs = append(errs, fmt.Sprintf("group %d: invalid choice %s, skipped", gi+1, choice))
+			continue
+		}
+		paths, groupErrs := RemoveDuplicates(g, keep-1)
+		removed += len(paths)
+		errs = append(errs, groupErrs...)
+	}
+
+	// Removed files no longer belong to any group
+	lastDuplicates = nil
+
+	text = fmt.Sprintf("Removed %d duplicate files under %s", removed, lastDuplicatesRoot)
+	if len(errs) > 0 {
+		text += fmt.Sprintf(" (%d problems: %s)", len(errs), errs[0])
+	}
+	msg := C.CString(text)
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+//export go_dfs_count
+func go_dfs_count(f, n C.int) C.int {
+	bp := C.api_current_buffer()
+	var root string
+	if bp != nil {
+		fname := C.GoString(C.api_buffer_filename(bp))
+		if fname != "" {
+			root = filepath.Dir(fname)
+		}
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+
+	// Count all files
+	start := time.Now()
+	result := ConcurrentFind(root, regexp.MustCompile(".*"), runtime.NumCPU())
+	elapsed := time.Since(start)
+
+	msg := C.CString(fmt.Sprintf("Counted %d files in %s (%v)", result.count, root, elapsed))
+	C.api_message(msg)
+	C.free(unsafe.Pointer(msg))
+
+	return 1
+}
+
+// ============================================================================
+// Permission Audit Commands
+// ============================================================================
+
+// FindByPermission finds files under root whose Unix mode has every bit
+// in mask set (e.g. 0o4000 for SUID)
+func FindByPermission(root string, mask os.FileMode, maxWorkers int) *FileTraverseResult {
+	opts := DefaultFileOptions(maxWorkers)
+	opts.PermissionMask = mask
+	opts.Match = func(path string, isDir bool) bool {
+		return !isDir
+	}
+	// Audits look inside hidden and build directories; skip only VCS metadata
+	opts.Prune = func(path string, isDir bool) bool {
+		if !isDir {
+			return false
+		}
+		switch filepath.Base(path) {
+		case ".git", ".svn", ".hg":
+			return true
+		}
+		return false
+	}
+
+	return FileTraverse(context.Background(), root, opts, nil)
+}
+
+//export go_dfs_suid
+func go_dfs_suid(f, n C.int) C.int {
+	return dfsPermissionAudit("SUID files", 0o4000)
+}
+
+//export go_dfs_world_writable
+func go_dfs_world_writable(f, n C.int) C.int {
+	return dfsPermissionAudit("World-writable files", 0o002)
+}
+
+// dfsPermissionAudit lists files matching mask in *dfs-perms* with their
+// permission bits in octal
+func dfsPermissionAudit(title string, mask os.FileMode) C.int {
+	bp := C.api_current_buffer()
+	var root string
+	if bp != nil {
+		fname := C.GoString(C.api_buffer_filename(bp))
+		if fname != "" {
+			root = filepath.Dir(fname)
+		}
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+
+	var dirBuf [256]C.char
+	if C.api_prompt(C.CString(fmt.Sprintf("Directory (default %s): ", root)), &dirBuf[0], 256) < 0 {
+		return 0
+	}
+	if dir := strings.TrimSpace(C.GoString(&dirBuf[0])); dir != "" {
+		root = dir
+	}
+
+	start := time.Now()
+	result := FindByPermission(root, mask, runtime.NumCPU())
+	elapsed := time.Since(start)
+
+	sort.Strings(result.Matches)
+
+	resultBuf := C.api_buffer_create(C.CString("*dfs-perms*"))
+	if resultBuf == nil {
+		return 0
+	}
+	C.api_buffer_switch(resultBuf)
+	C.api_buffer_clear(resultBuf)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("DFS %s (mode %04o) in %s\n", title, mask, root))
+	sb.WriteString(fmt.Sprintf("Found %d files in %v (%d errors)\n\n", len(result.Matches), elapsed, len(result.Errors)))
+
+	for _, match := range result.Matches {
+		sb.WriteString(fmt.Sprintf("%04o  %s\n", result.Modes[match], match))
+	}
+
+	output := sb.String()
+	coutput := C.CString(output)
+	C.api_buffer_insert(coutput, C.size_t(len(output)))
+	C.free(unsafe.Pointer(coutput))
+
+	C.api_set_point(1, 1)
+	C.api_update_display()
+
+	msg := C.CString(fmt.Sprintf("%s: %d found in %v", title, len(result.Matches), elapsed))
 	C.api_message(msg)
 	C.free(unsafe.Pointer(msg))
 
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// regexOnlyChars only have a meaning in regular expressions; a pattern
+// containing any of them is never read as a glob
+const regexOnlyChars = `^$+()|\{}`
+
+// globChars are the glob wildcards
+const globChars = `*?[`
+
+// IsGlob reports whether s reads as a glob: it uses glob wildcards and no
+// regex-only syntax (".*" counts as regex). Plain text is not a glob and
+// keeps matching as a regex substring.
+func IsGlob(s string) bool {
+	return strings.ContainsAny(s, globChars) &&
+		!strings.ContainsAny(s, regexOnlyChars) &&
+		!strings.Contains(s, ".*")
+}
+
+// ParsePattern compiles a find pattern, converting it from a glob when
+// IsGlob says so and compiling it as a regex otherwise
+func ParsePattern(s string) (*regexp.Regexp, error) {
+	if !IsGlob(s) {
+		return regexp.Compile(s)
+	}
+	return regexp.Compile(GlobToRegex(s))
+}
+
+// GlobToRegex converts a glob to an anchored regex with filepath.Match
+// semantics: * matches within a path element, ? one character, and
+// bracket expressions ([a-z], [!0-9]) pass through. ** matches across
+// elements, and **/ also matches no directory at all.
+func GlobToRegex(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
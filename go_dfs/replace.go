@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ReplaceChange is a pending substitution in one file
+type ReplaceChange struct {
+	Path  string
+	Count int // Number of substitutions
+
+	original []byte
+	replaced []byte
+}
+
+// PlanReplace finds the files under root matching filePattern that a
+// content search reads (grepFileOptions) and computes the substitutions of
+// search by repl ($1 expands to a submatch) without writing anything.
+// Binary files are skipped. Changes are sorted by path.
+func PlanReplace(root string, filePattern, search *regexp.Regexp, repl string, maxWorkers int) ([]ReplaceChange, []string) {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.NumCPU()
+	}
+	files := FileTraverse(context.Background(), root, grepFileOptions(root, filePattern, maxWorkers), filePattern)
+
+	fileCh := make(chan string, len(files.Matches))
+	for _, f := range files.Matches {
+		fileCh <- f
+	}
+	close(fileCh)
+
+	var changes []ReplaceChange
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < maxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range fileCh {
+				info, err := os.Stat(path)
+				if err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+				content, err := os.ReadFile(path)
+				if err != nil || isBinaryContent(content) {
+					continue
+				}
+				count := len(search.FindAllIndex(content, -1))
+				if count == 0 {
+					continue
+				}
+				replaced := search.ReplaceAll(content, []byte(repl))
+				if bytes.Equal(replaced, content) {
+					continue
+				}
+				mu.Lock()
+				changes = append(changes, ReplaceChange{Path: path, Count: count, original: content, replaced: replaced})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, files.Errors
+}
+
+// ApplyReplace writes the planned changes, keeping each original as
+// path.bak. A file that changed since it was planned is left alone and
+// reported as an error. It returns the files and substitutions written.
+func ApplyReplace(changes []ReplaceChange) (files, substitutions int, errs []string) {
+	for _, c := range changes {
+		info, err := os.Stat(c.Path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Path, err))
+			continue
+		}
+		current, err := os.ReadFile(c.Path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Path, err))
+			continue
+		}
+		if !bytes.Equal(current, c.original) {
+			errs = append(errs, fmt.Sprintf("%s: modified since the preview, skipped", c.Path))
+			continue
+		}
+
+		mode := info.Mode().Perm()
+		if err := os.WriteFile(c.Path+".bak", c.original, mode); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.bak: %v", c.Path, err))
+			continue
+		}
+		if err := os.WriteFile(c.Path, c.replaced, mode); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Path, err))
+			continue
+		}
+		files++
+		substitutions += c.Count
+	}
+	return files, substitutions, errs
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Result buffer line formats written by grepContent
+var (
+	grepMatchLine   = regexp.MustCompile(`^(.+?):(\d+): `)
+	grepContextLine = regexp.MustCompile(`^(.+?)-(\d+)- `)
+)
+
+// ResultLines returns the indices of the result lines in the text of a
+// *dfs-grep* or *dfs-find* buffer: the non-blank lines after the header
+// (which ends at the first blank line), except "--" block separators
+func ResultLines(content string) []int {
+	var indices []int
+	inHeader := true
+	for i, line := range strings.Split(content, "\n") {
+		if inHeader {
+			inHeader = strings.TrimSpace(line) != ""
+			continue
+		}
+		if strings.TrimSpace(line) == "" || line == "--" {
+			continue
+		}
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// ParseResultLine returns the file and line a result line refers to.
+// Grep lines are path:N: text (matches) or path-N- text (context); find
+// lines are a bare path, opened at line 1. Relative paths are joined to
+// root.
+func ParseResultLine(text, root string, grep bool) (path string, line int, ok bool) {
+	if grep {
+		m := grepMatchLine.FindStringSubmatch(text)
+		if m == nil {
+			m = grepContextLine.FindStringSubmatch(text)
+		}
+		if m == nil {
+			return "", 0, false
+		}
+		path = m[1]
+		line, _ = strconv.Atoi(m[2])
+	} else {
+		path, line = strings.TrimSpace(text), 1
+		if path == "" {
+			return "", 0, false
+		}
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	return path, line, true
+}
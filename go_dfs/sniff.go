@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// sniffLen is how much of a file IsBinaryFile reads (all that
+// http.DetectContentType looks at)
+const sniffLen = 512
+
+// textContentTypes are the non-text/ MIME types that are still text
+var textContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/postscript",
+	"image/svg+xml",
+}
+
+// DefaultSkipExtensions are file name suffixes content searches skip:
+// minified bundles, source maps and lock files
+var DefaultSkipExtensions = []string{".min.js", ".min.css", ".map", ".lock"}
+
+// IsBinaryFile sniffs the first 512 bytes of a file with
+// http.DetectContentType and reports whether it is not text (text/...,
+// JSON, XML, JavaScript and the like). Empty files are text.
+func IsBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return isBinaryContent(buf[:n]), nil
+}
+
+// isBinaryContent is IsBinaryFile for content already read; only its
+// first 512 bytes are looked at
+func isBinaryContent(content []byte) bool {
+	contentType := http.DetectContentType(content)
+	if strings.HasPrefix(contentType, "text/") {
+		return false
+	}
+	for _, t := range textContentTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasSkippedExtension reports whether name ends with one of exts (case
+// insensitive, so ".min.js" also skips "app.MIN.JS")
+func hasSkippedExtension(name string, exts []string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range exts {
+		if strings.HasSuffix(name, strings.ToLower(ext)) {
+			return true
+		}
+	}
+	return false
+}
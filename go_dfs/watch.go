@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watch mode polls the search root for changes instead of using inotify,
+// so the extension keeps to the standard library
+
+// watchPollInterval is how often the search root is scanned for changes
+const watchPollInterval = time.Second
+
+// watchDebounce is how long the tree must stay unchanged before a search
+// is re-run, so a burst of writes (a save, a git checkout) re-runs it once
+const watchDebounce = 500 * time.Millisecond
+
+// treeSignature hashes the path, size and modification time of the entries
+// under root that a traversal with opts would match, so only changes that
+// can alter the search results change it. Pruned directories (.git,
+// ignored or build output) are not walked at all. opts.Prune must be set.
+func treeSignature(root string, opts FileTraverseOptions) uint64 {
+	h := fnv.New64a()
+	var buf [16]byte
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil // Unreadable entries are left out
+		}
+		isDir := d.IsDir()
+		if opts.Prune(path, isDir) {
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isDir && hasSkippedExtension(d.Name(), opts.SkipExtensions) {
+			return nil
+		}
+		if opts.Match == nil || opts.Match(path, isDir) {
+			if info, err := d.Info(); err == nil {
+				h.Write([]byte(path))
+				binary.LittleEndian.PutUint64(buf[:8], uint64(info.Size()))
+				binary.LittleEndian.PutUint64(buf[8:], uint64(info.ModTime().UnixNano()))
+				h.Write(buf[:])
+			}
+		}
+		// The traversal lists directories down to MaxDepth below root
+		if isDir {
+			if rel, err := filepath.Rel(root, path); err == nil &&
+				strings.Count(rel, string(filepath.Separator)) >= opts.MaxDepth {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	return h.Sum64()
+}
+
+// dfsWatch re-runs a search when files under its root change
+type dfsWatch struct {
+	Buffer string // Results buffer being kept up to date
+	Root   string
+
+	opts     FileTraverseOptions // The search's prune and match rules
+	refresh  func()
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newWatch returns a watch calling refresh after each change under root
+// to the files a traversal with opts would match
+func newWatch(buffer, root string, opts FileTraverseOptions, refresh func()) *dfsWatch {
+	opts.Prune = traversalPrune(root, opts)
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultFileOptions(0).MaxDepth
+	}
+	return &dfsWatch{
+		Buffer:  buffer,
+		Root:    root,
+		opts:    opts,
+		refresh: refresh,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Stop ends the watch; it is safe to call more than once
+func (w *dfsWatch) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// sleep waits for d, returning false if the watch was stopped meanwhile
+func (w *dfsWatch) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-w.stop:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// run polls the root until the watch is stopped, calling refresh once the
+// tree has changed and then stayed still for watchDebounce
+func (w *dfsWatch) run() {
+	last := treeSignature(w.Root, w.opts)
+	for w.sleep(watchPollInterval) {
+		sig := treeSignature(w.Root, w.opts)
+		if sig == last {
+			continue
+		}
+		for {
+			if !w.sleep(watchDebounce) {
+				return
+			}
+			next := treeSignature(w.Root, w.opts)
+			if next == sig {
+				break
+			}
+			sig = next
+		}
+		last = sig
+		w.refresh()
+	}
+}
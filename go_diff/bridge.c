@@ -0,0 +1,303 @@
+/*
+ * bridge.c - C/CGO Bridge for Go Diff Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Shows unified diffs in *diff*, coloured by a registered lexer,
+ * and steps through and applies their hunks.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*current_buffer_fn)(void);
+typedef const char *(*buffer_filename_fn)(void*);
+typedef const char *(*buffer_name_fn)(void*);
+typedef char *(*buffer_contents_fn)(void*, size_t*);
+typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef void *(*buffer_create_fn)(const char*);
+typedef int (*buffer_switch_fn)(void*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef int (*prompt_yn_fn)(const char*);
+typedef void (*free_fn)(void*);
+typedef int (*find_file_line_fn)(const char*, int);
+typedef int (*syntax_add_token_fn)(uemacs_line_tokens_t*, int, int);
+typedef int (*syntax_register_lexer_fn)(const char*, const char**, uemacs_syntax_lex_fn, void*);
+typedef int (*syntax_unregister_lexer_fn)(const char*);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    current_buffer_fn current_buffer;
+    buffer_filename_fn buffer_filename;
+    buffer_name_fn buffer_name;
+    buffer_contents_fn buffer_contents;
+    get_point_fn get_point;
+    set_point_fn set_point;
+    buffer_create_fn buffer_create;
+    buffer_switch_fn buffer_switch;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_fn prompt;
+    prompt_yn_fn prompt_yn;
+    free_fn free;
+    find_file_line_fn find_file_line;
+    syntax_add_token_fn syntax_add_token;
+    syntax_register_lexer_fn syntax_register_lexer;
+    syntax_unregister_lexer_fn syntax_unregister_lexer;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_current_buffer(void) {
+    if (api.current_buffer) return api.current_buffer();
+    return NULL;
+}
+
+const char* api_buffer_filename(void *bp) {
+    if (api.buffer_filename) return api.buffer_filename(bp);
+    return NULL;
+}
+
+const char* api_buffer_name(void *bp) {
+    if (api.buffer_name) return api.buffer_name(bp);
+    return NULL;
+}
+
+char* api_buffer_contents(void *bp, size_t *len) {
+    if (api.buffer_contents) return api.buffer_contents(bp, len);
+    return NULL;
+}
+
+void api_get_point(int *line, int *col) {
+    if (api.get_point) api.get_point(line, col);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+void* api_buffer_create(const char *name) {
+    if (api.buffer_create) return api.buffer_create(name);
+    return NULL;
+}
+
+int api_buffer_switch(void *bp) {
+    if (api.buffer_switch) return api.buffer_switch(bp);
+    return 0;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
+int api_prompt_yn(const char *prompt) {
+    if (api.prompt_yn) return api.prompt_yn(prompt);
+    return 0;
+}
+
+void api_free(void *ptr) {
+    if (api.free) api.free(ptr);
+}
+
+int api_find_file_line(const char *path, int line) {
+    if (api.find_file_line) return api.find_file_line(path, line);
+    return 0;
+}
+
+int api_syntax_add_token(void *tokens, int end_col, int face) {
+    if (api.syntax_add_token)
+        return api.syntax_add_token((uemacs_line_tokens_t*)tokens, end_col, face);
+    return 0;
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_diff_buffer(int f, int n) { return go_diff_buffer(f, n); }
+static int cmd_diff_files(int f, int n) { return go_diff_files(f, n); }
+static int cmd_diff_next_hunk(int f, int n) { return go_diff_next_hunk(f, n); }
+static int cmd_diff_prev_hunk(int f, int n) { return go_diff_prev_hunk(f, n); }
+static int cmd_diff_apply_hunk(int f, int n) { return go_diff_apply_hunk(f, n); }
+
+/* Lexer callback wrapper - calls Go function */
+static uemacs_lexer_state_t diff_lexer_callback(
+    const struct syntax_language *lang,
+    struct buffer *buffer,
+    int line_num,
+    const char *line,
+    int len,
+    uemacs_lexer_state_t prev_state,
+    uemacs_line_tokens_t *out
+) {
+    (void)lang;
+    (void)buffer;
+    (void)line_num;
+    (void)prev_state;
+
+    go_diff_lex_line((char*)line, len, out);
+
+    uemacs_lexer_state_t result = {0, 0, 0, 0};
+    return result;
+}
+
+static const char *diff_patterns[] = { "*diff*", NULL };
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int diff_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_diff: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
+    api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.buffer_name = (buffer_name_fn)LOOKUP(buffer_name);
+    api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
+    api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
+    api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.prompt_yn = (prompt_yn_fn)LOOKUP(prompt_yn);
+    api.free = (free_fn)LOOKUP(free);
+    api.find_file_line = (find_file_line_fn)LOOKUP(find_file_line);
+    api.syntax_add_token = (syntax_add_token_fn)LOOKUP(syntax_add_token);
+    api.syntax_register_lexer = (syntax_register_lexer_fn)LOOKUP(syntax_register_lexer);
+    api.syntax_unregister_lexer = (syntax_unregister_lexer_fn)LOOKUP(syntax_unregister_lexer);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_diff: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Colour the diff buffer */
+    if (api.syntax_register_lexer) {
+        api.syntax_register_lexer("go-diff", diff_patterns, diff_lexer_callback, NULL);
+    }
+
+    /* Register commands */
+    api.register_command("diff-buffer", cmd_diff_buffer);
+    api.register_command("diff-files", cmd_diff_files);
+    api.register_command("diff-next-hunk", cmd_diff_next_hunk);
+    api.register_command("diff-prev-hunk", cmd_diff_prev_hunk);
+    api.register_command("diff-apply-hunk", cmd_diff_apply_hunk);
+
+    api.log_info("go_diff: Extension loaded");
+    api.log_info("  Commands: diff-buffer, diff-files, diff-next-hunk, diff-prev-hunk, diff-apply-hunk");
+    return 0;
+}
+
+static void diff_cleanup_c(void) {
+    if (api.syntax_unregister_lexer) {
+        api.syntax_unregister_lexer("go-diff");
+    }
+
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("diff-buffer");
+        api.unregister_command("diff-files");
+        api.unregister_command("diff-next-hunk");
+        api.unregister_command("diff-prev-hunk");
+        api.unregister_command("diff-apply-hunk");
+    }
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_diff",
+    .version = "1.0.0",
+    .description = "Unified diff viewer with hunk navigation",
+    .init = diff_init_c,
+    .cleanup = diff_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
@@ -0,0 +1,193 @@
+// Package diff parses unified diffs and applies their hunks.
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hunk is one @@ section of a unified diff
+type Hunk struct {
+	OldFile, NewFile   string // From the --- and +++ lines before the hunk
+	OldStart, OldLines int
+	NewStart, NewLines int
+
+	Line  int      // 1-based line of the @@ header in the diff text
+	Lines []string // Body lines, each starting with ' ', '-', '+' or '\'
+}
+
+// hunkHeader matches "@@ -l[,s] +l[,s] @@"
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse returns the hunks of a unified diff. Lines outside hunks (file
+// headers, "diff" and "index" lines) only name the files.
+func Parse(text string) ([]Hunk, error) {
+	var hunks []Hunk
+	var oldFile, newFile string
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			oldFile = fileName(line[4:])
+			continue
+		case strings.HasPrefix(line, "+++ "):
+			newFile = fileName(line[4:])
+			continue
+		}
+
+		m := hunkHeader.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		h := Hunk{OldFile: oldFile, NewFile: newFile, Line: i + 1}
+		h.OldStart, h.OldLines = rangeOf(m[1], m[2])
+		h.NewStart, h.NewLines = rangeOf(m[3], m[4])
+
+		// The counts say how long the body is
+		oldLeft, newLeft := h.OldLines, h.NewLines
+		for i+1 < len(lines) && (oldLeft > 0 || newLeft > 0 || strings.HasPrefix(lines[i+1], `\`)) {
+			body := lines[i+1]
+			switch {
+			case body == "" || body[0] == ' ':
+				oldLeft--
+				newLeft--
+				if body == "" {
+					body = " "
+				}
+			case body[0] == '-':
+				oldLeft--
+			case body[0] == '+':
+				newLeft--
+			case body[0] == '\\':
+			default:
+				return nil, fmt.Errorf("line %d: unexpected %q in hunk", i+2, body)
+			}
+			h.Lines = append(h.Lines, body)
+			i++
+		}
+		if oldLeft != 0 || newLeft != 0 {
+			return nil, fmt.Errorf("line %d: hunk is shorter than its header says", h.Line)
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks, nil
+}
+
+// fileName strips the timestamp diff appends to file names after a tab
+func fileName(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// rangeOf parses the start and the optional count (default 1) of a range
+func rangeOf(start, count string) (int, int) {
+	s, _ := strconv.Atoi(start)
+	n := 1
+	if count != "" {
+		n, _ = strconv.Atoi(count)
+	}
+	return s, n
+}
+
+// HunkAt returns the index of the hunk whose header or body holds line
+// (1-based) of the diff text, or -1
+func HunkAt(hunks []Hunk, line int) int {
+	for i, h := range hunks {
+		if line >= h.Line && line <= h.Line+len(h.Lines) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sides returns the old and new text of the hunk as lines with their
+// newlines, honouring "\ No newline at end of file"
+func (h Hunk) sides() (old, new []string) {
+	for i, l := range h.Lines {
+		if l[0] == '\\' {
+			continue
+		}
+		text := l[1:]
+		if i+1 >= len(h.Lines) || h.Lines[i+1][0] != '\\' {
+			text += "\n"
+		}
+		switch l[0] {
+		case ' ':
+			old = append(old, text)
+			new = append(new, text)
+		case '-':
+			old = append(old, text)
+		case '+':
+			new = append(new, text)
+		}
+	}
+	return old, new
+}
+
+// Apply applies the hunk to text. The old lines are looked for at the
+// line the hunk names first, then ever further from it, so text that
+// moved since the diff was made still takes the hunk. With reverse the
+// hunk is undone instead.
+func (h Hunk) Apply(text string, reverse bool) (string, error) {
+	from, to := h.sides()
+	start := h.OldStart
+	if reverse {
+		from, to = to, from
+		start = h.NewStart
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// An empty side starts after the line it names
+	want := start - 1
+	if len(from) == 0 {
+		want = start
+	}
+
+	at := -1
+	for dist := 0; at < 0 && (want-dist >= 0 || want+dist <= len(lines)); dist++ {
+		for _, pos := range []int{want - dist, want + dist} {
+			if matchAt(lines, from, pos) {
+				at = pos
+				break
+			}
+		}
+	}
+	if at < 0 {
+		return "", fmt.Errorf("hunk does not match the text")
+	}
+
+	var sb strings.Builder
+	for _, l := range lines[:at] {
+		sb.WriteString(l)
+	}
+	for _, l := range to {
+		sb.WriteString(l)
+	}
+	for _, l := range lines[at+len(from):] {
+		sb.WriteString(l)
+	}
+	return sb.String(), nil
+}
+
+// matchAt reports whether want appears in lines at pos
+func matchAt(lines, want []string, pos int) bool {
+	if pos < 0 || pos+len(want) > len(lines) {
+		return false
+	}
+	for i, w := range want {
+		if lines[pos+i] != w {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package diff
+
+import "testing"
+
+const sample = `--- a/greet.txt	2024-01-01 10:00:00
++++ b/greet.txt	2024-01-02 10:00:00
+@@ -1,3 +1,3 @@
+ hello
+-world
++there
+ bye
+@@ -6,2 +6,3 @@
+ six
+ seven
++eight
+`
+
+func TestParse(t *testing.T) {
+	hunks, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(hunks))
+	}
+	h := hunks[1]
+	if h.OldFile != "a/greet.txt" || h.NewFile != "b/greet.txt" {
+		t.Errorf("files %q %q", h.OldFile, h.NewFile)
+	}
+	if h.Line != 8 || h.OldStart != 6 || h.OldLines != 2 || h.NewStart != 6 || h.NewLines != 3 {
+		t.Errorf("hunk %+v", h)
+	}
+	if i := HunkAt(hunks, 6); i != 0 {
+		t.Errorf("line 6 is in hunk %d, want 0", i)
+	}
+	if i := HunkAt(hunks, 2); i != -1 {
+		t.Errorf("line 2 is in hunk %d, want none", i)
+	}
+
+	if _, err := Parse("@@ -1,3 +1,3 @@\n a\n"); err == nil {
+		t.Error("truncated hunk parsed")
+	}
+}
+
+func TestApply(t *testing.T) {
+	hunks, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const old = "hello\nworld\nbye\nfour\nfive\nsix\nseven\n"
+
+	got, err := hunks[0].Apply(old, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hello\nthere\nbye\nfour\nfive\nsix\nseven\n"; got != want {
+		t.Errorf("apply:\ngot  %q\nwant %q", got, want)
+	}
+	if back, err := hunks[0].Apply(got, true); err != nil || back != old {
+		t.Errorf("reverse apply: %q, %v", back, err)
+	}
+	if _, err := hunks[0].Apply(got, false); err == nil {
+		t.Error("hunk applied twice")
+	}
+
+	// Text that moved down two lines still takes the hunk
+	got, err = hunks[1].Apply("extra\nextra\n"+old, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "extra\nextra\n" + old + "eight\n"; got != want {
+		t.Errorf("moved apply:\ngot  %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,3 @@
+module go_diff
+
+go 1.21
@@ -0,0 +1,373 @@
+// go_diff - Unified diff viewer for μEmacs
+//
+// Shows unified diffs in *diff*, coloured by a lexer registered for it,
+// and steps through and applies their hunks.
+//
+// Commands:
+//   diff-buffer     - Diff the current buffer against its file on disk
+//   diff-files      - Diff two files (prompts for the old and new file)
+//   diff-next-hunk  - Move to the next @@ hunk header in *diff*
+//   diff-prev-hunk  - Move to the previous hunk header
+//   diff-apply-hunk - Apply the hunk at point to the old file's buffer,
+//                     offering to revert it when it is already applied
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_prompt_yn(const char *prompt);
+extern void api_free(void *ptr);
+extern int api_find_file_line(const char *path, int line);
+extern int api_syntax_add_token(void *tokens, int end_col, int face);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"go_diff/diff"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+// Face IDs (must match UEMACS_FACE_* in extension_api.h)
+const (
+	FaceDefault = 0
+	FaceKeyword = 1
+	FaceString  = 2
+	FaceComment = 3
+	FaceType    = 5
+)
+
+// diffBuffer shows the diffs
+const diffBuffer = "*diff*"
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// replaceText replaces the contents of buffer bp (the current buffer)
+func replaceText(bp unsafe.Pointer, text string) {
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+}
+
+// currentLine returns the 1-based line of point
+func currentLine() int {
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	return int(line)
+}
+
+// runDiff runs diff -u with args, feeding it stdin. Exit status 1 only
+// means the inputs differ.
+func runDiff(stdin string, args ...string) (string, error) {
+	cmd := exec.Command("diff", append([]string{"-u"}, args...)...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == 1 {
+			return string(out), nil
+		}
+		if len(exitErr.Stderr) > 0 {
+			return "", errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+	}
+	return string(out), err
+}
+
+// showDiff fills *diff* with text and moves point to its first hunk
+func showDiff(text string) {
+	cname := C.CString(diffBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	bp := C.api_buffer_create(cname)
+	if bp == nil {
+		return
+	}
+	C.api_buffer_switch(bp)
+	replaceText(bp, text)
+
+	line := 1
+	if hunks, err := diff.Parse(text); err == nil && len(hunks) > 0 {
+		line = hunks[0].Line
+	}
+	C.api_set_point(C.int(line), 0)
+}
+
+//export go_diff_buffer
+func go_diff_buffer(f, n C.int) C.int {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	cname := C.api_buffer_filename(bp)
+	if cname == nil || C.GoString(cname) == "" {
+		message("diff-buffer: buffer has no file")
+		return 0
+	}
+	file, err := filepath.Abs(C.GoString(cname))
+	if err != nil {
+		message("diff-buffer: %v", err)
+		return 0
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("diff-buffer: cannot read buffer")
+		return 0
+	}
+
+	// The buffer is the new side; both sides are labelled with the file so
+	// diff-apply-hunk finds it
+	out, err := runDiff(text, "--label", file, "--label", file, file, "-")
+	if err != nil {
+		message("diff-buffer: %v", err)
+		return 0
+	}
+	if out == "" {
+		message("diff-buffer: no changes since %s was saved", filepath.Base(file))
+		return 1
+	}
+	showDiff(out)
+	return 1
+}
+
+//export go_diff_files
+func go_diff_files(f, n C.int) C.int {
+	oldFile, ok := prompt("Diff old file: ")
+	if !ok || strings.TrimSpace(oldFile) == "" {
+		message("Cancelled")
+		return 0
+	}
+	newFile, ok := prompt(fmt.Sprintf("Diff %s with: ", strings.TrimSpace(oldFile)))
+	if !ok || strings.TrimSpace(newFile) == "" {
+		message("Cancelled")
+		return 0
+	}
+	oldPath, err1 := filepath.Abs(strings.TrimSpace(oldFile))
+	newPath, err2 := filepath.Abs(strings.TrimSpace(newFile))
+	if err := errors.Join(err1, err2); err != nil {
+		message("diff-files: %v", err)
+		return 0
+	}
+
+	out, err := runDiff("", oldPath, newPath)
+	if err != nil {
+		message("diff-files: %v", err)
+		return 0
+	}
+	if out == "" {
+		message("diff-files: files are identical")
+		return 1
+	}
+	showDiff(out)
+	return 1
+}
+
+// diffHunks parses the current buffer, which must be *diff*
+func diffHunks(cmd string) ([]diff.Hunk, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return nil, false
+	}
+	if cname := C.api_buffer_name(bp); cname == nil || C.GoString(cname) != diffBuffer {
+		message("%s: not in %s", cmd, diffBuffer)
+		return nil, false
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		return nil, false
+	}
+	hunks, err := diff.Parse(text)
+	if err != nil {
+		message("%s: %v", cmd, err)
+		return nil, false
+	}
+	if len(hunks) == 0 {
+		message("%s: no hunks", cmd)
+		return nil, false
+	}
+	return hunks, true
+}
+
+//export go_diff_next_hunk
+func go_diff_next_hunk(f, n C.int) C.int {
+	hunks, ok := diffHunks("diff-next-hunk")
+	if !ok {
+		return 0
+	}
+	line := currentLine()
+	for i, h := range hunks {
+		if h.Line > line {
+			C.api_set_point(C.int(h.Line), 0)
+			message("Hunk %d/%d", i+1, len(hunks))
+			return 1
+		}
+	}
+	message("No next hunk")
+	return 0
+}
+
+//export go_diff_prev_hunk
+func go_diff_prev_hunk(f, n C.int) C.int {
+	hunks, ok := diffHunks("diff-prev-hunk")
+	if !ok {
+		return 0
+	}
+	line := currentLine()
+	for i := len(hunks) - 1; i >= 0; i-- {
+		if hunks[i].Line < line {
+			C.api_set_point(C.int(hunks[i].Line), 0)
+			message("Hunk %d/%d", i+1, len(hunks))
+			return 1
+		}
+	}
+	message("No previous hunk")
+	return 0
+}
+
+//export go_diff_apply_hunk
+func go_diff_apply_hunk(f, n C.int) C.int {
+	hunks, ok := diffHunks("diff-apply-hunk")
+	if !ok {
+		return 0
+	}
+	i := diff.HunkAt(hunks, currentLine())
+	if i < 0 {
+		message("diff-apply-hunk: point is not in a hunk")
+		return 0
+	}
+	h := hunks[i]
+	if h.OldFile == "" || h.OldFile == "/dev/null" {
+		message("diff-apply-hunk: hunk has no old file")
+		return 0
+	}
+
+	// Apply to the buffer visiting the old file, unsaved edits included
+	cpath := C.CString(h.OldFile)
+	found := C.api_find_file_line(cpath, C.int(h.OldStart))
+	C.free(unsafe.Pointer(cpath))
+	bp := C.api_current_buffer()
+	if found == 0 || bp == nil {
+		message("diff-apply-hunk: cannot open %s", h.OldFile)
+		return 0
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("diff-apply-hunk: cannot read %s", h.OldFile)
+		return 0
+	}
+
+	reverse := false
+	result, err := h.Apply(text, false)
+	if err != nil {
+		if _, rerr := h.Apply(text, true); rerr != nil {
+			message("diff-apply-hunk: hunk %d does not match %s", i+1, filepath.Base(h.OldFile))
+			return 0
+		}
+		cq := C.CString("Hunk already applied; revert it? ")
+		yes := C.api_prompt_yn(cq)
+		C.free(unsafe.Pointer(cq))
+		if yes == 0 {
+			message("Cancelled")
+			return 0
+		}
+		result, _ = h.Apply(text, true)
+		reverse = true
+	}
+
+	line := currentLine()
+	replaceText(bp, result)
+	C.api_set_point(C.int(line), 0)
+	if reverse {
+		message("Hunk %d reverted in %s", i+1, filepath.Base(h.OldFile))
+	} else {
+		message("Hunk %d applied to %s", i+1, filepath.Base(h.OldFile))
+	}
+	return 1
+}
+
+// Lexer callback - called from C for each line of *diff*
+//
+//export go_diff_lex_line
+func go_diff_lex_line(line *C.char, lineLen C.int, outTokens unsafe.Pointer) {
+	if outTokens == nil || lineLen <= 0 {
+		return
+	}
+	if face := lineFace(C.GoStringN(line, lineLen)); face != FaceDefault {
+		C.api_syntax_add_token(outTokens, lineLen, C.int(face))
+	}
+}
+
+// lineFace picks the face of a line of unified diff
+func lineFace(text string) int {
+	switch {
+	case strings.HasPrefix(text, "+++ "), strings.HasPrefix(text, "--- "),
+		strings.HasPrefix(text, "diff "), strings.HasPrefix(text, "index "):
+		return FaceType
+	case strings.HasPrefix(text, "@@"):
+		return FaceKeyword
+	case strings.HasPrefix(text, "+"):
+		return FaceString
+	case strings.HasPrefix(text, "-"):
+		return FaceComment
+	}
+	return FaceDefault
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,251 @@
+/*
+ * bridge.c - C/CGO Bridge for Go Format Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Pipes the current buffer through the formatter for its language
+ * (gofmt, black, clang-format, prettier, rustfmt).
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+typedef bool (*event_fn_t)(void*, void*);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*current_buffer_fn)(void);
+typedef const char *(*buffer_filename_fn)(void*);
+typedef char *(*buffer_contents_fn)(void*, size_t*);
+typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef void (*buffer_set_unmodified_fn)(void*);
+typedef void (*free_fn)(void*);
+typedef const char *(*config_string_fn)(const char*, const char*, const char*);
+typedef bool (*config_bool_fn)(const char*, const char*, bool);
+typedef int (*on_fn)(const char*, event_fn_t, void*, int);
+typedef int (*off_fn)(const char*, event_fn_t);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    current_buffer_fn current_buffer;
+    buffer_filename_fn buffer_filename;
+    buffer_contents_fn buffer_contents;
+    get_point_fn get_point;
+    set_point_fn set_point;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    buffer_set_unmodified_fn buffer_set_unmodified;
+    free_fn free;
+    config_string_fn config_string;
+    config_bool_fn config_bool;
+    on_fn on;
+    off_fn off;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* Extension name for config lookups */
+static const char *EXT_NAME = "go_format";
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_current_buffer(void) {
+    if (api.current_buffer) return api.current_buffer();
+    return NULL;
+}
+
+const char* api_buffer_filename(void *bp) {
+    if (api.buffer_filename) return api.buffer_filename(bp);
+    return NULL;
+}
+
+char* api_buffer_contents(void *bp, size_t *len) {
+    if (api.buffer_contents) return api.buffer_contents(bp, len);
+    return NULL;
+}
+
+void api_get_point(int *line, int *col) {
+    if (api.get_point) api.get_point(line, col);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+void api_buffer_set_unmodified(void *bp) {
+    if (api.buffer_set_unmodified) api.buffer_set_unmodified(bp);
+}
+
+void api_free(void *ptr) {
+    if (api.free) api.free(ptr);
+}
+
+const char* api_config_string(const char *key, const char *default_val) {
+    if (api.config_string) return api.config_string(EXT_NAME, key, default_val);
+    return default_val;
+}
+
+bool api_config_bool(const char *key, bool default_val) {
+    if (api.config_bool) return api.config_bool(EXT_NAME, key, default_val);
+    return default_val;
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_format_buffer(int f, int n) { return go_format_buffer(f, n); }
+
+/* ============================================================================
+ * Event handlers
+ * ============================================================================ */
+
+/* Format on save (when enabled); never consumes the event */
+static bool on_buffer_saved(void *event, void *user_data) {
+    (void)event;
+    (void)user_data;
+    go_format_on_save();
+    return false;
+}
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int format_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_format: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
+    api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
+    api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.buffer_set_unmodified = (buffer_set_unmodified_fn)LOOKUP(buffer_set_unmodified);
+    api.free = (free_fn)LOOKUP(free);
+    api.config_string = (config_string_fn)LOOKUP(config_string);
+    api.config_bool = (config_bool_fn)LOOKUP(config_bool);
+    api.on = (on_fn)LOOKUP(on);
+    api.off = (off_fn)LOOKUP(off);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_format: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Run ahead of go_lsp's buffer:saved handler (priority 0) so the
+     * server sees the formatted file */
+    if (api.on) {
+        api.on("buffer:saved", on_buffer_saved, NULL, 10);
+    }
+
+    /* Register commands */
+    api.register_command("format-buffer", cmd_format_buffer);
+
+    api.log_info("go_format: Extension loaded");
+    api.log_info("  Commands: format-buffer");
+    return 0;
+}
+
+static void format_cleanup_c(void) {
+    if (api.off) {
+        api.off("buffer:saved", on_buffer_saved);
+    }
+
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("format-buffer");
+    }
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_format",
+    .version = "1.0.0",
+    .description = "Code formatter dispatcher",
+    .init = format_init_c,
+    .cleanup = format_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
@@ -0,0 +1,36 @@
+package main
+
+// Formatter overrides are [extension.go_format] keys of settings.toml,
+// read with the extension config API. Each language can name its
+// formatter and add arguments, and on_save formats buffers as they are
+// saved:
+//
+//	[extension.go_format]
+//	on_save = true
+//	python = "/opt/venv/bin/black"
+//	python_args = "--line-length 100"
+//	c_args = "--style=file"
+
+import "strings"
+
+// formatConfig is the formatter overrides by language
+type formatConfig struct {
+	Commands map[string]string   // Language -> formatter path
+	Args     map[string][]string // Language -> extra arguments
+}
+
+// loadConfig reads the overrides of every language in formatters with
+// get, which returns a key's value or "" when it is unset. Arguments are
+// split at spaces.
+func loadConfig(get func(key string) string) formatConfig {
+	cfg := formatConfig{Commands: map[string]string{}, Args: map[string][]string{}}
+	for _, fm := range formatters {
+		if cmd := get(fm.Language); cmd != "" {
+			cfg.Commands[fm.Language] = cmd
+		}
+		if args := strings.Fields(get(fm.Language + "_args")); len(args) > 0 {
+			cfg.Args[fm.Language] = args
+		}
+	}
+	return cfg
+}
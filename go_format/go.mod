@@ -0,0 +1,3 @@
+module go_format
+
+go 1.21
@@ -0,0 +1,284 @@
+// go_format - Code formatter dispatcher for μEmacs
+//
+// Picks a formatter from the current file's extension, pipes the buffer
+// through it and replaces the buffer with the result. A failing formatter
+// leaves the buffer alone and its stderr goes to the log.
+//
+//   .go       gofmt
+//   .py       black
+//   .c .h     clang-format
+//   .js .ts   prettier
+//   .rs       rustfmt
+//
+// Commands:
+//   format-buffer - Format the current buffer
+//
+// With on_save set in [extension.go_format] of settings.toml, buffers are
+// also formatted as they are saved, before go_lsp reports the save.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern void api_buffer_set_unmodified(void *bp);
+extern void api_free(void *ptr);
+extern const char *api_config_string(const char *key, const char *default_val);
+extern bool api_config_bool(const char *key, bool default_val);
+*/
+import "C"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// formatter is how to format one language: Command reads the source on
+// stdin and writes the formatted source to stdout. "{file}" in Args is
+// replaced by the buffer's file name.
+type formatter struct {
+	Language string // Config key of the language (python, python_args)
+	Command  string
+	Args     []string
+}
+
+// formatters maps file extensions to their formatter
+var formatters = map[string]formatter{
+	".go": {"go", "gofmt", nil},
+	".py": {"python", "black", []string{"-q", "-"}},
+	".c":  {"c", "clang-format", []string{"--assume-filename={file}"}},
+	".h":  {"c", "clang-format", []string{"--assume-filename={file}"}},
+	".js": {"js", "prettier", []string{"--stdin-filepath", "{file}"}},
+	".ts": {"js", "prettier", []string{"--stdin-filepath", "{file}"}},
+	".rs": {"rust", "rustfmt", nil},
+}
+
+// formatTimeout is how long a formatter may run before it is killed and
+// the buffer left alone
+var formatTimeout = 10 * time.Second
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// logError writes a message to the editor log
+func logError(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_log_error(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// configString returns an [extension.go_format] string, "" when unset
+func configString(key string) string {
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	empty := C.CString("")
+	defer C.free(unsafe.Pointer(empty))
+	if v := C.api_config_string(ckey, empty); v != nil {
+		return C.GoString(v)
+	}
+	return ""
+}
+
+// configBool returns an [extension.go_format] flag
+func configBool(key string, defaultVal bool) bool {
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	return bool(C.api_config_bool(ckey, C.bool(defaultVal)))
+}
+
+// bufferFile returns the file name of buffer bp
+func bufferFile(bp unsafe.Pointer) string {
+	cname := C.api_buffer_filename(bp)
+	if cname == nil {
+		return ""
+	}
+	return C.GoString(cname)
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// formatterFor returns the formatter of file with the overrides of cfg
+// applied
+func formatterFor(file string, cfg formatConfig) (formatter, bool) {
+	fm, ok := formatters[strings.ToLower(filepath.Ext(file))]
+	if !ok {
+		return formatter{}, false
+	}
+	if cmd := cfg.Commands[fm.Language]; cmd != "" {
+		fm.Command = cmd
+	}
+	args := make([]string, 0, len(fm.Args)+len(cfg.Args[fm.Language]))
+	for _, a := range fm.Args {
+		args = append(args, strings.ReplaceAll(a, "{file}", file))
+	}
+	fm.Args = append(args, cfg.Args[fm.Language]...)
+	return fm, true
+}
+
+// runFormatter pipes text through fm, killing it after formatTimeout. On
+// failure the error carries the formatter's stderr.
+func runFormatter(fm formatter, text string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), formatTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, fm.Command, fm.Args...)
+	cmd.Stdin = strings.NewReader(text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Children holding stdout open
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("%s not found", fm.Command)
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("timed out after %v", formatTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// formatBuffer formats buffer bp (the current buffer) in place, keeping
+// point on the same line. It reports whether the text changed.
+func formatBuffer(bp unsafe.Pointer, fm formatter) (string, bool, error) {
+	text, ok := bufferText(bp)
+	if !ok {
+		return "", false, fmt.Errorf("cannot read buffer")
+	}
+	formatted, err := runFormatter(fm, text)
+	if err != nil {
+		return "", false, err
+	}
+	if formatted == text {
+		return text, false, nil
+	}
+
+	var line, col C.int
+	C.api_get_point(&line, &col)
+
+	C.api_buffer_clear(bp)
+	if len(formatted) > 0 {
+		ctext := C.CString(formatted)
+		C.api_buffer_insert(ctext, C.size_t(len(formatted)))
+		C.free(unsafe.Pointer(ctext))
+	}
+
+	if lines := strings.Count(formatted, "\n") + 1; int(line) > lines {
+		line = C.int(lines)
+	}
+	C.api_set_point(line, col)
+	return formatted, true, nil
+}
+
+//export go_format_buffer
+func go_format_buffer(f, n C.int) C.int {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	file := bufferFile(bp)
+	fm, ok := formatterFor(file, loadConfig(configString))
+	if !ok {
+		message("format-buffer: no formatter for %s", filepath.Base(file))
+		return 0
+	}
+
+	_, changed, err := formatBuffer(bp, fm)
+	if err != nil {
+		logError("go_format: %s %s: %v", fm.Command, file, err)
+		message("format-buffer: %s failed, buffer unchanged (see log)", fm.Command)
+		return 0
+	}
+	if !changed {
+		message("format-buffer: already formatted (%s)", fm.Command)
+		return 1
+	}
+	message("format-buffer: formatted with %s", fm.Command)
+	return 1
+}
+
+// go_format_on_save formats a buffer that was just saved when on_save is
+// set, writing the result back so the file and buffer agree
+//
+//export go_format_on_save
+func go_format_on_save() C.int {
+	if !configBool("on_save", false) {
+		return 0
+	}
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	file := bufferFile(bp)
+	fm, ok := formatterFor(file, loadConfig(configString))
+	if !ok {
+		return 0
+	}
+
+	formatted, changed, err := formatBuffer(bp, fm)
+	if err != nil {
+		logError("go_format: %s %s: %v", fm.Command, file, err)
+		message("Saved; %s failed, not formatted (see log)", fm.Command)
+		return 0
+	}
+	if !changed {
+		return 1
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(file, []byte(formatted), mode); err != nil {
+		logError("go_format: writing %s: %v", file, err)
+		message("Formatted with %s, but writing failed: %v", fm.Command, err)
+		return 0
+	}
+	C.api_buffer_set_unmodified(bp)
+	message("Saved and formatted with %s", fm.Command)
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
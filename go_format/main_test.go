@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	settings := map[string]string{
+		"python":      "/opt/venv/bin/black",
+		"python_args": " --line-length  100 ",
+		"c_args":      "--style=file",
+		"ruby":        "rubocop",
+	}
+	cfg := loadConfig(func(key string) string { return settings[key] })
+	if !reflect.DeepEqual(cfg.Commands, map[string]string{"python": "/opt/venv/bin/black"}) {
+		t.Errorf("commands %v", cfg.Commands)
+	}
+	want := map[string][]string{"python": {"--line-length", "100"}, "c": {"--style=file"}}
+	if !reflect.DeepEqual(cfg.Args, want) {
+		t.Errorf("args %v", cfg.Args)
+	}
+}
+
+func TestFormatterFor(t *testing.T) {
+	cfg := formatConfig{
+		Commands: map[string]string{"python": "/opt/venv/bin/black"},
+		Args:     map[string][]string{"python": {"--line-length", "100"}, "c": {"--style=file"}},
+	}
+	fm, ok := formatterFor("/src/app.py", cfg)
+	if !ok || fm.Command != "/opt/venv/bin/black" || !reflect.DeepEqual(fm.Args, []string{"-q", "-", "--line-length", "100"}) {
+		t.Errorf("app.py: %+v %v", fm, ok)
+	}
+	fm, ok = formatterFor("/src/MAIN.H", cfg)
+	if !ok || fm.Command != "clang-format" || !reflect.DeepEqual(fm.Args, []string{"--assume-filename=/src/MAIN.H", "--style=file"}) {
+		t.Errorf("MAIN.H: %+v %v", fm, ok)
+	}
+	if fm, ok := formatterFor("/src/main.go", formatConfig{}); !ok || fm.Command != "gofmt" || len(fm.Args) != 0 {
+		t.Errorf("main.go: %+v %v", fm, ok)
+	}
+	if _, ok := formatterFor("notes.txt", cfg); ok {
+		t.Error("formatter for .txt")
+	}
+}
+
+func TestRunFormatter(t *testing.T) {
+	out, err := runFormatter(formatter{Command: "tr", Args: []string{"a-z", "A-Z"}}, "x := 1\n")
+	if err != nil || out != "X := 1\n" {
+		t.Errorf("tr: %q, %v", out, err)
+	}
+
+	_, err = runFormatter(formatter{Command: "sh", Args: []string{"-c", "echo 'syntax error' >&2; exit 2"}}, "")
+	if err == nil || err.Error() != "syntax error" {
+		t.Errorf("failing formatter: %v", err)
+	}
+	if _, err := runFormatter(formatter{Command: "no-such-formatter"}, ""); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing formatter: %v", err)
+	}
+
+	defer func(d time.Duration) { formatTimeout = d }(formatTimeout)
+	formatTimeout = 50 * time.Millisecond
+	start := time.Now()
+	if _, err := runFormatter(formatter{Command: "sleep", Args: []string{"5"}}, ""); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("hung formatter: %v", err)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("hung formatter ran %v", d)
+	}
+}
@@ -0,0 +1,3 @@
+module go_git
+
+go 1.21
@@ -0,0 +1,366 @@
+// go_git - Git status, diff, log and blame for μEmacs
+//
+// Runs git in the repository of the current buffer's file and shows the
+// output in result buffers, coloured by a lexer registered for them.
+//
+// Commands:
+//   go-git-status - Changed files (git status --porcelain) in *git-status*
+//   go-git-diff   - Diff of the current file against HEAD in *git-diff*
+//   go-git-log    - Last 20 commits (C-u N for N) in *git-log*
+//   go-git-blame  - Blame of the current file in *git-blame*, with the
+//                   current line highlighted
+//
+// The git-* command names belong to c_git.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern const char *api_buffer_name(void *bp);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_syntax_add_token(void *tokens, int end_col, int face);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Face IDs (must match UEMACS_FACE_* in extension_api.h)
+const (
+	FaceDefault = 0
+	FaceKeyword = 1
+	FaceString  = 2
+	FaceComment = 3
+	FaceNumber  = 4
+	FaceSpecial = 14
+	FaceError   = 15
+	FaceWarning = 16
+)
+
+// Result buffer names
+const (
+	statusBuffer = "*git-status*"
+	diffBuffer   = "*git-diff*"
+	logBuffer    = "*git-log*"
+	blameBuffer  = "*git-blame*"
+)
+
+// defaultLogCount is how many commits go-git-log shows without C-u N
+const defaultLogCount = 20
+
+// blameLine is the 1-based line of *git-blame* highlighted as the line
+// point was on when blame ran; 0 when nothing is highlighted
+var blameLine atomic.Int64
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// showBuffer replaces the contents of the named buffer with text, switches
+// to it and moves point to line
+func showBuffer(name, text string, line int) bool {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	bp := C.api_buffer_create(cname)
+	if bp == nil {
+		return false
+	}
+	C.api_buffer_switch(bp)
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+	C.api_set_point(C.int(line), 0)
+	return true
+}
+
+// currentFile returns the file of the current buffer, if it has one
+func currentFile() (string, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return "", false
+	}
+	cname := C.api_buffer_filename(bp)
+	if cname == nil {
+		return "", false
+	}
+	name := C.GoString(cname)
+	if name == "" {
+		return "", false
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	return name, true
+}
+
+// runGit runs git with args in dir and returns its standard output. On
+// failure the error carries git's own message.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// repoRoot returns the top of the work tree holding the current buffer's
+// file, or the working directory's repository when the buffer has no file
+func repoRoot() (string, error) {
+	dir := ""
+	if file, ok := currentFile(); ok {
+		dir = filepath.Dir(file)
+	} else if wd, err := os.Getwd(); err == nil {
+		dir = wd
+	}
+	out, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// fileInRepo returns the current buffer's file and its repository root
+func fileInRepo(cmd string) (file, root string, ok bool) {
+	file, ok = currentFile()
+	if !ok {
+		message("%s: buffer has no file", cmd)
+		return "", "", false
+	}
+	root, err := repoRoot()
+	if err != nil {
+		message("%s: %v", cmd, err)
+		return "", "", false
+	}
+	return file, root, true
+}
+
+//export go_git_status
+func go_git_status(f, n C.int) C.int {
+	root, err := repoRoot()
+	if err != nil {
+		message("go-git-status: %v", err)
+		return 0
+	}
+	out, err := runGit(root, "status", "--porcelain")
+	if err != nil {
+		message("go-git-status: %v", err)
+		return 0
+	}
+	if out == "" {
+		message("go-git-status: working tree clean")
+		return 1
+	}
+
+	showBuffer(statusBuffer, out, 1)
+	count := strings.Count(out, "\n")
+	message("go-git-status: %d changed file(s) in %s", count, root)
+	return 1
+}
+
+//export go_git_diff
+func go_git_diff(f, n C.int) C.int {
+	file, root, ok := fileInRepo("go-git-diff")
+	if !ok {
+		return 0
+	}
+	out, err := runGit(root, "diff", "HEAD", "--", file)
+	if err != nil {
+		message("go-git-diff: %v", err)
+		return 0
+	}
+	if out == "" {
+		message("go-git-diff: %s has no changes against HEAD", filepath.Base(file))
+		return 1
+	}
+
+	showBuffer(diffBuffer, out, 1)
+	return 1
+}
+
+//export go_git_log
+func go_git_log(f, n C.int) C.int {
+	count := defaultLogCount
+	if f != 0 && n > 0 {
+		count = int(n)
+	}
+
+	root, err := repoRoot()
+	if err != nil {
+		message("go-git-log: %v", err)
+		return 0
+	}
+	out, err := runGit(root, "log", "--oneline", fmt.Sprintf("-%d", count))
+	if err != nil {
+		message("go-git-log: %v", err)
+		return 0
+	}
+	if out == "" {
+		message("go-git-log: no commits")
+		return 1
+	}
+
+	showBuffer(logBuffer, out, 1)
+	return 1
+}
+
+//export go_git_blame
+func go_git_blame(f, n C.int) C.int {
+	file, root, ok := fileInRepo("go-git-blame")
+	if !ok {
+		return 0
+	}
+
+	var line, col C.int
+	C.api_get_point(&line, &col)
+
+	out, err := runGit(root, "blame", "-l", "--", file)
+	if err != nil {
+		message("go-git-blame: %v", err)
+		return 0
+	}
+
+	// Blame reads the file on disk; clamp to its lines
+	target := int(line)
+	if lines := strings.Count(out, "\n"); target > lines {
+		target = lines
+	}
+	if target < 1 {
+		target = 1
+	}
+	blameLine.Store(int64(target))
+
+	showBuffer(blameBuffer, out, target)
+	message("go-git-blame: %s, line %d", filepath.Base(file), target)
+	return 1
+}
+
+// Lexer callback - called from C for each line of a result buffer
+//
+//export go_git_lex_line
+func go_git_lex_line(buffer unsafe.Pointer, lineNum C.int, line *C.char, lineLen C.int, outTokens unsafe.Pointer) {
+	if buffer == nil || outTokens == nil || lineLen <= 0 {
+		return
+	}
+	cname := C.api_buffer_name(buffer)
+	if cname == nil {
+		return
+	}
+	text := C.GoStringN(line, lineLen)
+
+	for _, span := range lineFaces(C.GoString(cname), int(lineNum), text) {
+		C.api_syntax_add_token(outTokens, C.int(span.end), C.int(span.face))
+	}
+}
+
+// faceSpan colours the columns up to end (from the previous span's end)
+type faceSpan struct {
+	end  int
+	face int
+}
+
+// lineFaces returns the face spans of line lineNum (0-based) of a result
+// buffer
+func lineFaces(buffer string, lineNum int, text string) []faceSpan {
+	whole := func(face int) []faceSpan {
+		if face == FaceDefault {
+			return nil
+		}
+		return []faceSpan{{len(text), face}}
+	}
+
+	switch buffer {
+	case statusBuffer:
+		return whole(statusFace(text))
+
+	case diffBuffer:
+		switch {
+		case strings.HasPrefix(text, "diff "), strings.HasPrefix(text, "index "),
+			strings.HasPrefix(text, "+++"), strings.HasPrefix(text, "---"):
+			return whole(FaceComment)
+		case strings.HasPrefix(text, "@@"):
+			return whole(FaceKeyword)
+		case strings.HasPrefix(text, "+"):
+			return whole(FaceString)
+		case strings.HasPrefix(text, "-"):
+			return whole(FaceError)
+		}
+
+	case logBuffer:
+		if hash := strings.IndexByte(text, ' '); hash > 0 {
+			return []faceSpan{{hash, FaceNumber}}
+		}
+
+	case blameBuffer:
+		if int64(lineNum+1) == blameLine.Load() {
+			return whole(FaceSpecial)
+		}
+		if hash := strings.IndexByte(text, ' '); hash > 0 {
+			return []faceSpan{{hash, FaceNumber}}
+		}
+	}
+	return nil
+}
+
+// statusFace picks the face of a git status --porcelain line from its
+// XY code: conflicts and deletions as errors, unstaged changes as
+// warnings, staged changes as strings and untracked files as comments
+func statusFace(text string) int {
+	if len(text) < 2 {
+		return FaceDefault
+	}
+	x, y := text[0], text[1]
+	switch {
+	case x == '?' && y == '?':
+		return FaceComment
+	case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+		return FaceError
+	case x == 'D' || y == 'D':
+		return FaceError
+	case y != ' ':
+		return FaceWarning
+	case x != ' ':
+		return FaceString
+	}
+	return FaceDefault
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestStatusFace(t *testing.T) {
+	tests := map[string]int{
+		"?? new.go":    FaceComment,
+		"UU merge.go":  FaceError,
+		"AA both.go":   FaceError,
+		" D gone.go":   FaceError,
+		" M edited.go": FaceWarning,
+		"MM both.go":   FaceWarning,
+		"M  staged.go": FaceString,
+		"A  added.go":  FaceString,
+		"R  a.go -> b": FaceString,
+		"":             FaceDefault,
+		"!":            FaceDefault,
+		"  clean":      FaceDefault,
+	}
+	for line, want := range tests {
+		if got := statusFace(line); got != want {
+			t.Errorf("statusFace(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestLineFaces(t *testing.T) {
+	tests := []struct {
+		buffer string
+		line   string
+		want   []faceSpan
+	}{
+		{diffBuffer, "diff --git a/x b/x", []faceSpan{{18, FaceComment}}},
+		{diffBuffer, "@@ -1,2 +1,3 @@", []faceSpan{{15, FaceKeyword}}},
+		{diffBuffer, "+added", []faceSpan{{6, FaceString}}},
+		{diffBuffer, "-removed", []faceSpan{{8, FaceError}}},
+		{diffBuffer, " context", nil},
+		{logBuffer, "1a2b3c4 Fix the build", []faceSpan{{7, FaceNumber}}},
+		{statusBuffer, "?? new.go", []faceSpan{{9, FaceComment}}},
+		{"*scratch*", "+added", nil},
+	}
+	for _, tt := range tests {
+		if got := lineFaces(tt.buffer, 0, tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lineFaces(%s, %q) = %v, want %v", tt.buffer, tt.line, got, tt.want)
+		}
+	}
+
+	defer blameLine.Store(0)
+	blameLine.Store(2)
+	if got := lineFaces(blameBuffer, 1, "^1a2b3c (ada 2024-01-01 2) x"); !reflect.DeepEqual(got, []faceSpan{{28, FaceSpecial}}) {
+		t.Errorf("blame line at point: %v", got)
+	}
+	if got := lineFaces(blameBuffer, 0, "^1a2b3c (ada 2024-01-01 1) x"); !reflect.DeepEqual(got, []faceSpan{{7, FaceNumber}}) {
+		t.Errorf("other blame line: %v", got)
+	}
+}
+
+func TestRunGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if _, err := runGit(dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runGit(dir, "status", "--porcelain"); err != nil || out != "" {
+		t.Errorf("status of an empty repository: %q, %v", out, err)
+	}
+	if _, err := runGit(dir, "log"); err == nil || err.Error() == "exit status 128" {
+		t.Errorf("log without commits: %v, want git's message", err)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+ * bridge.c - C/CGO Bridge for Go HTTP Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Sends HTTP requests and shows the responses in *http-response*,
+ * pretty-printing JSON bodies.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*find_buffer_fn)(const char*);
+typedef char *(*buffer_contents_fn)(void*, size_t*);
+typedef void (*set_point_fn)(int, int);
+typedef void *(*buffer_create_fn)(const char*);
+typedef int (*buffer_switch_fn)(void*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef void (*free_fn)(void*);
+typedef bool (*config_bool_fn)(const char*, const char*, bool);
+typedef void (*update_display_fn)(void);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    find_buffer_fn find_buffer;
+    buffer_contents_fn buffer_contents;
+    set_point_fn set_point;
+    buffer_create_fn buffer_create;
+    buffer_switch_fn buffer_switch;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_fn prompt;
+    free_fn free;
+    config_bool_fn config_bool;
+    update_display_fn update_display;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* Extension name for config lookups */
+static const char *EXT_NAME = "go_http";
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_find_buffer(const char *name) {
+    if (api.find_buffer) return api.find_buffer(name);
+    return NULL;
+}
+
+char* api_buffer_contents(void *bp, size_t *len) {
+    if (api.buffer_contents) return api.buffer_contents(bp, len);
+    return NULL;
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+void* api_buffer_create(const char *name) {
+    if (api.buffer_create) return api.buffer_create(name);
+    return NULL;
+}
+
+int api_buffer_switch(void *bp) {
+    if (api.buffer_switch) return api.buffer_switch(bp);
+    return 0;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
+void api_free(void *ptr) {
+    if (api.free) api.free(ptr);
+}
+
+bool api_config_bool(const char *key, bool default_val) {
+    if (api.config_bool) return api.config_bool(EXT_NAME, key, default_val);
+    return default_val;
+}
+
+void api_update_display(void) {
+    if (api.update_display) api.update_display();
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_http_request(int f, int n) { return go_http_request(f, n); }
+static int cmd_http_repeat(int f, int n) { return go_http_repeat(f, n); }
+static int cmd_http_history(int f, int n) { return go_http_history(f, n); }
+static int cmd_http_set_token(int f, int n) { return go_http_set_token(f, n); }
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int http_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_http: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.find_buffer = (find_buffer_fn)LOOKUP(find_buffer);
+    api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
+    api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.free = (free_fn)LOOKUP(free);
+    api.config_bool = (config_bool_fn)LOOKUP(config_bool);
+    api.update_display = (update_display_fn)LOOKUP(update_display);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_http: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Register commands */
+    api.register_command("http-request", cmd_http_request);
+    api.register_command("http-repeat", cmd_http_repeat);
+    api.register_command("http-history", cmd_http_history);
+    api.register_command("http-set-token", cmd_http_set_token);
+
+    api.log_info("go_http: Extension loaded");
+    api.log_info("  Commands: http-request, http-repeat, http-history, http-set-token");
+    return 0;
+}
+
+static void http_cleanup_c(void) {
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("http-request");
+        api.unregister_command("http-repeat");
+        api.unregister_command("http-history");
+        api.unregister_command("http-set-token");
+    }
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_http",
+    .version = "1.0.0",
+    .description = "HTTP client for REST APIs",
+    .init = http_init_c,
+    .cleanup = http_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
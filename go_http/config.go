@@ -0,0 +1,63 @@
+package main
+
+// Settings are [extension.go_http] keys of settings.toml, read with the
+// extension config API:
+//
+//	[extension.go_http]
+//	follow_redirects = false
+//
+// Bearer tokens are kept apart, per host, in
+// ~/.config/muemacs/http_tokens.json:
+//
+//	{"api.example.com": "eyJhbGciOi..."}
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// configDir returns the editor's configuration directory
+func configDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs")
+}
+
+// tokensPath returns the path of the saved bearer tokens
+func tokensPath() string {
+	return filepath.Join(configDir(), "http_tokens.json")
+}
+
+// loadTokens reads the host -> token map at path. A missing file yields
+// an empty map.
+func loadTokens(path string) (map[string]string, error) {
+	tokens := map[string]string{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// saveTokens writes the token map to path, readable by the owner only
+func saveTokens(path string, tokens map[string]string) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
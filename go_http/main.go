@@ -0,0 +1,290 @@
+// go_http - HTTP client for REST API exploration in μEmacs
+//
+// Sends requests with net/http and shows the status, headers and body of
+// the response in *http-response*, pretty-printing JSON bodies.
+//
+// Commands:
+//   http-request   - Prompt for a method and URL and send the request,
+//                    taking headers and a body from *http-request*
+//   http-repeat    - Send the last request again
+//   http-history   - List the last 10 requests (C-u N sends request N)
+//   http-set-token - Save a bearer token for a host
+//
+// The *http-request* buffer, when it exists, holds Name: value header
+// lines, a blank line and the body. Requests to a host with a saved token
+// and no Authorization header of their own carry
+// Authorization: Bearer <token>.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_find_buffer(const char *name);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern void api_free(void *ptr);
+extern void api_update_display(void);
+extern bool api_config_bool(const char *key, bool default_val);
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// Buffers used by the extension
+const (
+	requestBuffer  = "*http-request*"
+	responseBuffer = "*http-response*"
+	historyBuffer  = "*http-history*"
+)
+
+// history holds the last maxHistory requests, newest first
+var history []Request
+
+// followRedirects returns follow_redirects from [extension.go_http], true
+// when unset
+func followRedirects() bool {
+	ckey := C.CString("follow_redirects")
+	defer C.free(unsafe.Pointer(ckey))
+	return bool(C.api_config_bool(ckey, C.bool(true)))
+}
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// showBuffer switches to buffer name, replacing its contents with text
+func showBuffer(name, text string) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	bp := C.api_buffer_create(cname)
+	if bp == nil {
+		return
+	}
+	C.api_buffer_switch(bp)
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+	C.api_set_point(1, 0)
+}
+
+// template returns the headers and body in *http-request*, if it exists
+func template() ([]Header, string, error) {
+	cname := C.CString(requestBuffer)
+	bp := C.api_find_buffer(cname)
+	C.free(unsafe.Pointer(cname))
+	if bp == nil {
+		return nil, "", nil
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		return nil, "", nil
+	}
+	headers, body, err := parseTemplate(text)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s %v", requestBuffer, err)
+	}
+	return headers, body, nil
+}
+
+// perform sends req, adding a saved bearer token, and shows the response
+func perform(req Request) C.int {
+	history = addHistory(history, req)
+
+	if !hasHeader(req.Headers, "Authorization") {
+		tokens, err := loadTokens(tokensPath())
+		if err != nil {
+			message("http: %s: %v", tokensPath(), err)
+			return 0
+		}
+		if token := tokens[host(req.URL)]; token != "" {
+			// Copy so the history does not keep the token
+			req.Headers = append(append([]Header(nil), req.Headers...),
+				Header{Name: "Authorization", Value: "Bearer " + token})
+		}
+	}
+
+	message("Sending %s %s...", req.Method, req.URL)
+	C.api_update_display()
+
+	resp, err := send(req, followRedirects())
+	if err != nil {
+		message("http: %v", err)
+		return 0
+	}
+	showBuffer(responseBuffer, formatResponse(req, resp))
+	message("%s (%d ms, %d bytes)", resp.Status, resp.Elapsed.Milliseconds(), len(resp.Body))
+	return 1
+}
+
+//export go_http_request
+func go_http_request(f, n C.int) C.int {
+	input, ok := prompt("HTTP method (GET): ")
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	method, err := parseMethod(input)
+	if err != nil {
+		message("http-request: %v", err)
+		return 0
+	}
+	input, ok = prompt(method + " URL: ")
+	if !ok || strings.TrimSpace(input) == "" {
+		message("Cancelled")
+		return 0
+	}
+	url, err := parseURL(input)
+	if err != nil {
+		message("http-request: %v", err)
+		return 0
+	}
+
+	headers, body, err := template()
+	if err != nil {
+		message("http-request: %v", err)
+		return 0
+	}
+	if body == "" && (method == "POST" || method == "PUT" || method == "PATCH") {
+		if body, ok = prompt("Body (empty for none): "); !ok {
+			message("Cancelled")
+			return 0
+		}
+		body = strings.TrimSpace(body)
+	}
+
+	return perform(Request{Method: method, URL: url, Headers: headers, Body: body})
+}
+
+//export go_http_repeat
+func go_http_repeat(f, n C.int) C.int {
+	if len(history) == 0 {
+		message("http-repeat: no previous request")
+		return 0
+	}
+	return perform(history[0])
+}
+
+//export go_http_history
+func go_http_history(f, n C.int) C.int {
+	if len(history) == 0 {
+		message("http-history: no requests yet")
+		return 0
+	}
+	if f != 0 {
+		if n < 1 || int(n) > len(history) {
+			message("http-history: no request %d (have %d)", n, len(history))
+			return 0
+		}
+		return perform(history[n-1])
+	}
+
+	var b strings.Builder
+	for i, req := range history {
+		fmt.Fprintf(&b, "%2d  %-6s %s", i+1, req.Method, req.URL)
+		if req.Body != "" {
+			fmt.Fprintf(&b, "  (%d byte body)", len(req.Body))
+		}
+		b.WriteString("\n")
+	}
+	showBuffer(historyBuffer, b.String())
+	message("C-u N http-history sends request N again")
+	return 1
+}
+
+//export go_http_set_token
+func go_http_set_token(f, n C.int) C.int {
+	hostPrompt := "Token for host: "
+	last := ""
+	if len(history) > 0 {
+		last = host(history[0].URL)
+		hostPrompt = fmt.Sprintf("Token for host (default %s): ", last)
+	}
+	h, ok := prompt(hostPrompt)
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	if h = strings.TrimSpace(h); h == "" {
+		h = last
+	}
+	if h == "" {
+		message("Cancelled")
+		return 0
+	}
+
+	token, ok := prompt(fmt.Sprintf("Bearer token for %s (empty to remove): ", h))
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	tokens, err := loadTokens(tokensPath())
+	if err != nil {
+		message("http-set-token: %s: %v", tokensPath(), err)
+		return 0
+	}
+	if token = strings.TrimSpace(token); token == "" {
+		delete(tokens, h)
+	} else {
+		tokens[h] = token
+	}
+	if err := saveTokens(tokensPath(), tokens); err != nil {
+		message("http-set-token: %v", err)
+		return 0
+	}
+	if token == "" {
+		message("Token for %s removed", h)
+	} else {
+		message("Token for %s saved", h)
+	}
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// methods are the HTTP methods a request may use
+var methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// maxHistory is how many requests the history keeps
+const maxHistory = 10
+
+// maxBody bounds how much of a response body is read
+const maxBody = 10 << 20
+
+// requestTimeout bounds a whole exchange, redirects included
+const requestTimeout = 30 * time.Second
+
+// Header is one request header, kept in the order given
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Request is a request as sent, kept in the history to send again
+type Request struct {
+	Method  string
+	URL     string
+	Headers []Header
+	Body    string
+}
+
+// Response is what came back for a request
+type Response struct {
+	Proto   string
+	Status  string
+	Header  http.Header
+	Body    []byte
+	Elapsed time.Duration
+}
+
+// parseMethod upper-cases s and checks it is a supported method; an
+// empty s is GET
+func parseMethod(s string) (string, error) {
+	m := strings.ToUpper(strings.TrimSpace(s))
+	if m == "" {
+		return "GET", nil
+	}
+	for _, ok := range methods {
+		if m == ok {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported method %s (want %s)", m, strings.Join(methods, ", "))
+}
+
+// parseURL checks that s is an absolute http(s) URL, adding http:// when
+// the scheme is left out
+func parseURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("no URL")
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("no host in %s", s)
+	}
+	return u.String(), nil
+}
+
+// parseTemplate reads the *http-request* buffer: Name: value header lines
+// up to the first blank line, then the body. Lines starting with # before
+// the body are comments.
+func parseTemplate(text string) ([]Header, string, error) {
+	var headers []Header
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			body := strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+			return headers, body, nil
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, "", fmt.Errorf("line %d: expected Name: value", i+1)
+		}
+		headers = append(headers, Header{Name: name, Value: strings.TrimSpace(value)})
+	}
+	return headers, "", nil
+}
+
+// hasHeader reports whether headers set name, ignoring case
+func hasHeader(headers []Header, name string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// host returns the host name of rawURL, without the port
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// send performs req. A JSON body without a Content-Type header is sent as
+// application/json; redirects are followed only when follow is set.
+func send(req Request, follow bool) (*Response, error) {
+	var body io.Reader
+	if req.Body != "" {
+		body = strings.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range req.Headers {
+		httpReq.Header.Add(h.Name, h.Value)
+	}
+	if req.Body != "" && !hasHeader(req.Headers, "Content-Type") && json.Valid([]byte(req.Body)) {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	if !follow {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Proto:   resp.Proto,
+		Status:  resp.Status,
+		Header:  resp.Header,
+		Body:    data,
+		Elapsed: time.Since(start),
+	}, nil
+}
+
+// prettyJSON indents a JSON body the way json-format does; other bodies
+// come back unchanged
+func prettyJSON(body []byte, contentType string) string {
+	if strings.Contains(contentType, "json") || json.Valid(body) {
+		var out bytes.Buffer
+		if json.Indent(&out, body, "", "  ") == nil {
+			return out.String()
+		}
+	}
+	return string(body)
+}
+
+// formatResponse renders resp for *http-response*: status line, sorted
+// headers, a blank line and the body
+func formatResponse(req Request, resp *Response) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", resp.Proto, resp.Status)
+	fmt.Fprintf(&b, "# %s %s (%d ms)\n", req.Method, req.URL, resp.Elapsed.Milliseconds())
+
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+
+	switch {
+	case len(resp.Body) == 0:
+	case !utf8.Valid(resp.Body):
+		fmt.Fprintf(&b, "[%d bytes of binary data]\n", len(resp.Body))
+	default:
+		body := prettyJSON(resp.Body, resp.Header.Get("Content-Type"))
+		b.WriteString(body)
+		if !strings.HasSuffix(body, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// addHistory puts req at the front of history, dropping the oldest past
+// maxHistory
+func addHistory(history []Request, req Request) []Request {
+	history = append([]Request{req}, history...)
+	if len(history) > maxHistory {
+		history = history[:maxHistory]
+	}
+	return history
+}
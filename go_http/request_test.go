@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	headers, body, err := parseTemplate("# Headers, then a blank line and the body\nAccept: application/json\nX-Trace:  abc \n\n{\"name\": \"ada\"}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers[0] != (Header{"Accept", "application/json"}) || headers[1] != (Header{"X-Trace", "abc"}) {
+		t.Errorf("headers %+v", headers)
+	}
+	if body != `{"name": "ada"}` {
+		t.Errorf("body %q", body)
+	}
+
+	if _, _, err := parseTemplate("not a header\n"); err == nil {
+		t.Error("bad header line parsed")
+	}
+}
+
+func TestSend(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"method":%q,"type":%q,"auth":%q,"body":%q}`,
+			r.Method, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), body)
+	}))
+	defer srv.Close()
+
+	req := Request{
+		Method:  "POST",
+		URL:     srv.URL + "/old",
+		Headers: []Header{{"Authorization", "Bearer t0k"}},
+		Body:    `{"a":1}`,
+	}
+	resp, err := send(req, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "302 Found" {
+		t.Errorf("without following redirects got %s", resp.Status)
+	}
+
+	resp, err = send(Request{Method: "GET", URL: srv.URL + "/old"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "200 OK" {
+		t.Errorf("following redirects got %s", resp.Status)
+	}
+
+	req.URL = srv.URL + "/new"
+	resp, err = send(req, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := formatResponse(req, resp)
+	for _, want := range []string{
+		"HTTP/1.1 200 OK\n",
+		"Content-Type: application/json\n",
+		"\n{\n  \"method\": \"POST\",\n  \"type\": \"application/json\",\n  \"auth\": \"Bearer t0k\",\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("response missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestAddHistory(t *testing.T) {
+	var history []Request
+	for i := 0; i < maxHistory+3; i++ {
+		history = addHistory(history, Request{Method: "GET", URL: fmt.Sprint(i)})
+	}
+	if len(history) != maxHistory || history[0].URL != fmt.Sprint(maxHistory+2) {
+		t.Errorf("history %+v", history)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * bridge.c - C/CGO Bridge for Go JSON Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Formats, minifies, queries and validates JSON in the current buffer
+ * or the region between mark and point.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*current_buffer_fn)(void);
+typedef char *(*buffer_contents_fn)(void*, size_t*);
+typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef void (*get_mark_fn)(int*, int*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_fn)(const char*, char*, size_t);
+typedef void (*free_fn)(void*);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    current_buffer_fn current_buffer;
+    buffer_contents_fn buffer_contents;
+    get_point_fn get_point;
+    set_point_fn set_point;
+    get_mark_fn get_mark;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_fn prompt;
+    free_fn free;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_current_buffer(void) {
+    if (api.current_buffer) return api.current_buffer();
+    return NULL;
+}
+
+char* api_buffer_contents(void *bp, size_t *len) {
+    if (api.buffer_contents) return api.buffer_contents(bp, len);
+    return NULL;
+}
+
+void api_get_point(int *line, int *col) {
+    if (api.get_point) api.get_point(line, col);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+/* Mark position; returns 0 if the editor does not export get_mark */
+int api_get_mark(int *line, int *col) {
+    if (!api.get_mark) return 0;
+    api.get_mark(line, col);
+    return 1;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
+void api_free(void *ptr) {
+    if (api.free) api.free(ptr);
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_json_format(int f, int n) { return go_json_format(f, n); }
+static int cmd_json_minify(int f, int n) { return go_json_minify(f, n); }
+static int cmd_json_query(int f, int n) { return go_json_query(f, n); }
+static int cmd_json_validate(int f, int n) { return go_json_validate(f, n); }
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int json_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_json: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
+    api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
+    api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.get_mark = (get_mark_fn)LOOKUP(get_mark);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
+    api.free = (free_fn)LOOKUP(free);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_json: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Register commands */
+    api.register_command("json-format", cmd_json_format);
+    api.register_command("json-minify", cmd_json_minify);
+    api.register_command("json-query", cmd_json_query);
+    api.register_command("json-validate", cmd_json_validate);
+
+    api.log_info("go_json: Extension loaded");
+    api.log_info("  Commands: json-format, json-minify, json-query, json-validate");
+    return 0;
+}
+
+static void json_cleanup_c(void) {
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("json-format");
+        api.unregister_command("json-minify");
+        api.unregister_command("json-query");
+        api.unregister_command("json-validate");
+    }
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_json",
+    .version = "1.0.0",
+    .description = "JSON format, minify, query and validate",
+    .init = json_init_c,
+    .cleanup = json_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
@@ -0,0 +1,276 @@
+// go_json - JSON formatting and path queries for μEmacs
+//
+// Works on the whole buffer when it holds JSON, otherwise on the region
+// between mark and point.
+//
+// Commands:
+//   json-format   - Pretty-print with two-space indentation
+//   json-minify   - Remove all insignificant whitespace
+//   json-query    - Evaluate a jq-style path (.users[0].name) and show
+//                   the result
+//   json-validate - Check the JSON, moving point to the first error
+//
+// Formatting keeps the order of object keys and numbers exactly as
+// written.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_get_mark(int *line, int *col);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern void api_free(void *ptr);
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// maxResultLen bounds the query result shown in the message line
+const maxResultLen = 300
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// target is the JSON a command works on: text[start:end] of buffer bp
+type target struct {
+	bp         unsafe.Pointer
+	text       string
+	start, end int
+	whole      bool // The whole buffer rather than the region
+}
+
+// json returns the targeted text
+func (t target) json() string {
+	return t.text[t.start:t.end]
+}
+
+// currentTarget picks the whole buffer when it is valid JSON, else the
+// region between mark and point when there is one, else the whole buffer
+func currentTarget(cmd string) (target, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return target{}, false
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("%s: cannot read buffer", cmd)
+		return target{}, false
+	}
+	t := target{bp: bp, text: text, start: 0, end: len(text), whole: true}
+	if json.Valid([]byte(text)) {
+		return t, true
+	}
+
+	var line, col, markLine, markCol C.int
+	C.api_get_point(&line, &col)
+	if C.api_get_mark(&markLine, &markCol) != 0 && markLine > 0 {
+		point := lineColToOffset(text, int(line), int(col))
+		mark := lineColToOffset(text, int(markLine), int(markCol))
+		if point != mark {
+			t.start, t.end, t.whole = min(point, mark), max(point, mark), false
+		}
+	}
+	return t, true
+}
+
+// replace puts s in place of the targeted text and moves point to its start
+func (t target) replace(s string) {
+	text := t.text[:t.start] + s + t.text[t.end:]
+	C.api_buffer_clear(t.bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+	line, col := offsetToLineCol(text, t.start)
+	C.api_set_point(C.int(line), C.int(col))
+}
+
+// describe reports a JSON error with its position in the buffer, moving
+// point there when the error has one
+func (t target) describe(err error) string {
+	offset := -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = int(syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		offset = int(typeErr.Offset)
+	}
+	if offset < 0 {
+		return err.Error()
+	}
+
+	// The offset counts the bytes read, the bad one included
+	pos := t.start + max(offset-1, 0)
+	line, col := offsetToLineCol(t.text, pos)
+	C.api_set_point(C.int(line), C.int(col))
+	return fmt.Sprintf("line %d, column %d: %v", line, col+1, err)
+}
+
+// scope names what a command worked on
+func (t target) scope() string {
+	if t.whole {
+		return "buffer"
+	}
+	return "region"
+}
+
+// reformat rewrites the targeted JSON with fn (json.Indent or Compact)
+func reformat(cmd string, fn func(dst *bytes.Buffer, src []byte) error) C.int {
+	t, ok := currentTarget(cmd)
+	if !ok {
+		return 0
+	}
+	src := strings.TrimSpace(t.json())
+	if src == "" {
+		message("%s: no JSON", cmd)
+		return 0
+	}
+
+	var out bytes.Buffer
+	if err := fn(&out, []byte(src)); err != nil {
+		message("%s: %s", cmd, t.describe(err))
+		return 0
+	}
+	if t.whole {
+		out.WriteByte('\n')
+	}
+	if out.String() == t.json() {
+		message("%s: %s unchanged", cmd, t.scope())
+		return 1
+	}
+	t.replace(out.String())
+	message("%s: %s rewritten", cmd, t.scope())
+	return 1
+}
+
+//export go_json_format
+func go_json_format(f, n C.int) C.int {
+	return reformat("json-format", func(dst *bytes.Buffer, src []byte) error {
+		return json.Indent(dst, src, "", "  ")
+	})
+}
+
+//export go_json_minify
+func go_json_minify(f, n C.int) C.int {
+	return reformat("json-minify", json.Compact)
+}
+
+// decode parses the targeted JSON, keeping numbers exact
+func decode(t target) (interface{}, error) {
+	// Unmarshal positions every error, trailing data and truncation included
+	if err := json.Unmarshal([]byte(t.json()), new(interface{})); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(t.json()))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//export go_json_query
+func go_json_query(f, n C.int) C.int {
+	t, ok := currentTarget("json-query")
+	if !ok {
+		return 0
+	}
+	v, err := decode(t)
+	if err != nil {
+		message("json-query: %s", t.describe(err))
+		return 0
+	}
+
+	path, ok := prompt("JSON path (e.g. .users[0].name): ")
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	result, err := queryPath(v, path)
+	if err != nil {
+		message("json-query: %v", err)
+		return 0
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		message("json-query: %v", err)
+		return 0
+	}
+	s := string(out)
+	if len(s) > maxResultLen {
+		s = s[:maxResultLen] + "..."
+	}
+	message("%s = %s", strings.TrimSpace(path), s)
+	return 1
+}
+
+//export go_json_validate
+func go_json_validate(f, n C.int) C.int {
+	t, ok := currentTarget("json-validate")
+	if !ok {
+		return 0
+	}
+	if _, err := decode(t); err != nil {
+		message("json-validate: %s", t.describe(err))
+		return 0
+	}
+	message("json-validate: %s is valid JSON", t.scope())
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -0,0 +1,163 @@
+package main
+
+// jq-style paths: a dot and a key (.users), an index ([0], [-1] from the
+// end) or a quoted key (["first name"]), chained: .users[0].name. A lone
+// "." is the whole document.
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// queryPath evaluates path against v, a value decoded from JSON
+func queryPath(v interface{}, path string) (interface{}, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "." {
+		return v, nil
+	}
+
+	switch path[0] {
+	case '.':
+		if strings.HasPrefix(path, ".[") {
+			return queryPath(v, path[1:])
+		}
+		end := 1
+		for end < len(path) && path[end] != '.' && path[end] != '[' {
+			end++
+		}
+		key := path[1:end]
+		if key == "" {
+			return nil, fmt.Errorf("empty key in %q", path)
+		}
+		next, err := member(v, key)
+		if err != nil {
+			return nil, err
+		}
+		return queryPath(next, path[end:])
+
+	case '[':
+		end := strings.IndexByte(path, ']')
+		if strings.HasPrefix(path, `["`) {
+			end = closingQuote(path, 2)
+			if end < 0 || end+1 >= len(path) || path[end+1] != ']' {
+				return nil, fmt.Errorf("unterminated key in %q", path)
+			}
+			key, err := strconv.Unquote(path[1 : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad key %s", path[1:end+1])
+			}
+			next, err := member(v, key)
+			if err != nil {
+				return nil, err
+			}
+			return queryPath(next, path[end+2:])
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("missing ] in %q", path)
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(path[1:end]))
+		if err != nil {
+			return nil, fmt.Errorf("bad index [%s]", path[1:end])
+		}
+		next, err := element(v, index)
+		if err != nil {
+			return nil, err
+		}
+		return queryPath(next, path[end+1:])
+	}
+	return nil, fmt.Errorf("expected . or [ at %q", path)
+}
+
+// closingQuote returns the index of the quote ending a string that starts
+// before from, skipping escapes
+func closingQuote(s string, from int) int {
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// member returns key of object v
+func member(v interface{}, key string) (interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot look up %q in %s", key, kind(v))
+	}
+	next, ok := obj[key]
+	if !ok {
+		return nil, fmt.Errorf("no key %q", key)
+	}
+	return next, nil
+}
+
+// element returns item index of array v; negative indexes count from the
+// end
+func element(v interface{}, index int) (interface{}, error) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s", kind(v))
+	}
+	if index < 0 {
+		index += len(arr)
+	}
+	if index < 0 || index >= len(arr) {
+		return nil, fmt.Errorf("index %d out of range (length %d)", index, len(arr))
+	}
+	return arr[index], nil
+}
+
+// kind names the JSON type of v for error messages
+func kind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number, float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// offsetToLineCol converts a byte offset of text to a 1-based line and a
+// 0-based byte column
+func offsetToLineCol(text string, offset int) (line, col int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	line = strings.Count(before, "\n") + 1
+	col = offset - (strings.LastIndexByte(before, '\n') + 1)
+	return line, col
+}
+
+// lineColToOffset converts a 1-based line and 0-based byte column of text
+// to a byte offset, clamped to the text
+func lineColToOffset(text string, line, col int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	end := len(text)
+	if i := strings.IndexByte(text[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return min(offset+max(col, 0), end)
+}
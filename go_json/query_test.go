@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestQueryPath(t *testing.T) {
+	const doc = `{"users": [{"name": "ada", "tags": ["x", "y"]}, {"name": "bob"}], "first name": 7}`
+	dec := json.NewDecoder(bytes.NewReader([]byte(doc)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{".users[0].name", `"ada"`},
+		{".users[-1].name", `"bob"`},
+		{".users[0].tags[1]", `"y"`},
+		{`.["first name"]`, `7`},
+		{`["first name"]`, `7`},
+		{".users[1]", `{"name":"bob"}`},
+	}
+	for _, tt := range tests {
+		got, err := queryPath(v, tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		out, _ := json.Marshal(got)
+		if string(out) != tt.want {
+			t.Errorf("%s = %s, want %s", tt.path, out, tt.want)
+		}
+	}
+
+	for _, bad := range []string{".users[5]", ".users.name", ".missing", ".users[x]", `.["open`} {
+		if _, err := queryPath(v, bad); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
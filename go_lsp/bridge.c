@@ -30,13 +30,18 @@ typedef void (*message_fn)(const char*, ...);
 typedef void (*log_fn)(const char*, ...);
 typedef void *(*current_buffer_fn)(void);
 typedef const char *(*buffer_filename_fn)(void*);
+typedef const char *(*buffer_name_fn)(void*);
 typedef char *(*buffer_contents_fn)(void*, size_t*);
 typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef void (*get_mark_fn)(int*, int*);
+typedef int (*set_mark_fn)(void);
 typedef int (*find_file_line_fn)(const char*, int);
 typedef void *(*buffer_create_fn)(const char*);
 typedef int (*buffer_switch_fn)(void*);
 typedef int (*buffer_clear_fn)(void*);
 typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef void (*buffer_set_unmodified_fn)(void*);
 typedef void (*free_fn)(void*);
 typedef int (*syntax_add_token_fn)(uemacs_line_tokens_t*, int, int);
 typedef void (*syntax_invalidate_buffer_fn)(struct buffer*);
@@ -47,6 +52,8 @@ typedef int (*register_command_fn)(const char*, cmd_fn_t);
 typedef int (*unregister_command_fn)(const char*);
 typedef int (*syntax_register_lexer_fn)(const char*, const char**, uemacs_syntax_lex_fn, void*);
 typedef int (*syntax_unregister_lexer_fn)(const char*);
+typedef int (*config_int_fn)(const char*, const char*, int);
+typedef int (*prompt_fn)(const char*, char*, size_t);
 
 /*
  * Local API struct - only the functions we actually use
@@ -57,13 +64,18 @@ static struct {
     log_fn log_error;
     current_buffer_fn current_buffer;
     buffer_filename_fn buffer_filename;
+    buffer_name_fn buffer_name;
     buffer_contents_fn buffer_contents;
     get_point_fn get_point;
+    set_point_fn set_point;
+    get_mark_fn get_mark;
+    set_mark_fn set_mark;
     find_file_line_fn find_file_line;
     buffer_create_fn buffer_create;
     buffer_switch_fn buffer_switch;
     buffer_clear_fn buffer_clear;
     buffer_insert_fn buffer_insert;
+    buffer_set_unmodified_fn buffer_set_unmodified;
     free_fn free;
     syntax_add_token_fn syntax_add_token;
     syntax_invalidate_buffer_fn syntax_invalidate_buffer;
@@ -74,8 +86,13 @@ static struct {
     unregister_command_fn unregister_command;
     syntax_register_lexer_fn syntax_register_lexer;
     syntax_unregister_lexer_fn syntax_unregister_lexer;
+    config_int_fn config_int;
+    prompt_fn prompt;
 } api;
 
+/* Extension name for config lookups */
+static const char *EXT_NAME = "go_lsp";
+
 /* ============================================================================
  * API wrappers for Go
  * ============================================================================ */
@@ -102,6 +119,11 @@ const char* api_buffer_filename(void *bp) {
     return NULL;
 }
 
+const char* api_buffer_name(void *bp) {
+    if (api.buffer_name) return api.buffer_name(bp);
+    return NULL;
+}
+
 char* api_buffer_contents(void *bp, size_t *len) {
     if (api.buffer_contents) return api.buffer_contents(bp, len);
     return NULL;
@@ -111,6 +133,24 @@ void api_get_point(int *line, int *col) {
     if (api.get_point) api.get_point(line, col);
 }
 
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+/* Mark position; returns 0 if the editor does not export get_mark */
+int api_get_mark(int *line, int *col) {
+    if (!api.get_mark) return 0;
+    api.get_mark(line, col);
+    return 1;
+}
+
+/* Set the mark at point; returns 0 if the editor does not export set_mark */
+int api_set_mark(void) {
+    if (!api.set_mark) return 0;
+    api.set_mark();
+    return 1;
+}
+
 int api_find_file_line(const char *path, int line) {
     if (api.find_file_line) return api.find_file_line(path, line);
     return 0;
@@ -136,6 +176,10 @@ int api_buffer_insert(const char *text, size_t len) {
     return 0;
 }
 
+void api_buffer_set_unmodified(void *bp) {
+    if (api.buffer_set_unmodified) api.buffer_set_unmodified(bp);
+}
+
 void api_free(void *ptr) {
     if (api.free) api.free(ptr);
 }
@@ -151,6 +195,16 @@ void api_syntax_invalidate_buffer(void *bp) {
         api.syntax_invalidate_buffer((struct buffer*)bp);
 }
 
+int api_config_int(const char *key, int default_val) {
+    if (api.config_int) return api.config_int(EXT_NAME, key, default_val);
+    return default_val;
+}
+
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
 int api_emit(const char *event, void *data) {
     if (api.emit) return api.emit(event, data);
     return 0;
@@ -199,15 +253,46 @@ static int cmd_lsp_start(int f, int n) { return go_lsp_start(f, n); }
 static int cmd_lsp_stop(int f, int n) { return go_lsp_stop(f, n); }
 static int cmd_lsp_hover(int f, int n) { return go_lsp_hover(f, n); }
 static int cmd_lsp_definition(int f, int n) { return go_lsp_definition(f, n); }
+static int cmd_lsp_back(int f, int n) { return go_lsp_back(f, n); }
+static int cmd_lsp_forward(int f, int n) { return go_lsp_forward(f, n); }
 static int cmd_lsp_references(int f, int n) { return go_lsp_references(f, n); }
 static int cmd_lsp_refresh_tokens(int f, int n) { return go_lsp_refresh_tokens(f, n); }
 static int cmd_lsp_completion(int f, int n) { return go_lsp_completion(f, n); }
 static int cmd_lsp_diagnostics(int f, int n) { return go_lsp_diagnostics(f, n); }
 static int cmd_lsp_code_action(int f, int n) { return go_lsp_code_action(f, n); }
+static int cmd_lsp_organize_imports(int f, int n) { return go_lsp_organize_imports(f, n); }
 static int cmd_lsp_document_symbols(int f, int n) { return go_lsp_document_symbols(f, n); }
 static int cmd_lsp_workspace_symbols(int f, int n) { return go_lsp_workspace_symbols(f, n); }
+static int cmd_lsp_workspace_functions(int f, int n) { return go_lsp_workspace_functions(f, n); }
 static int cmd_lsp_did_save(int f, int n) { return go_lsp_did_save(f, n); }
 static int cmd_lsp_did_close(int f, int n) { return go_lsp_did_close(f, n); }
+static int cmd_lsp_status(int f, int n) { return go_lsp_status(f, n); }
+static int cmd_lsp_customize_faces(int f, int n) { return go_lsp_customize_faces(f, n); }
+static int cmd_lsp_rename(int f, int n) { return go_lsp_rename(f, n); }
+static int cmd_lsp_format(int f, int n) { return go_lsp_format(f, n); }
+static int cmd_lsp_format_range(int f, int n) { return go_lsp_format_range(f, n); }
+static int cmd_lsp_signature_help(int f, int n) { return go_lsp_signature_help(f, n); }
+static int cmd_lsp_incoming_calls(int f, int n) { return go_lsp_incoming_calls(f, n); }
+static int cmd_lsp_outgoing_calls(int f, int n) { return go_lsp_outgoing_calls(f, n); }
+static int cmd_lsp_goto_result(int f, int n) { return go_lsp_goto_result_line(f, n); }
+static int cmd_lsp_code_lens(int f, int n) { return go_lsp_code_lens(f, n); }
+static int cmd_lsp_execute_code_lens(int f, int n) { return go_lsp_execute_code_lens(f, n); }
+static int cmd_lsp_refresh_code_lens(int f, int n) { return go_lsp_refresh_code_lens(f, n); }
+static int cmd_lsp_inlay_hints(int f, int n) { return go_lsp_inlay_hints(f, n); }
+static int cmd_lsp_refresh_hints(int f, int n) { return go_lsp_refresh_hints(f, n); }
+static int cmd_lsp_server_status(int f, int n) { return go_lsp_server_status(f, n); }
+static int cmd_lsp_next_diagnostic(int f, int n) { return go_lsp_next_diagnostic(f, n); }
+static int cmd_lsp_prev_diagnostic(int f, int n) { return go_lsp_prev_diagnostic(f, n); }
+static int cmd_lsp_first_error(int f, int n) { return go_lsp_first_error(f, n); }
+static int cmd_lsp_list_servers(int f, int n) { return go_lsp_list_servers(f, n); }
+static int cmd_lsp_cancel(int f, int n) { return go_lsp_cancel(f, n); }
+static int cmd_lsp_config(int f, int n) { return go_lsp_config(f, n); }
+static int cmd_lsp_set_timeout(int f, int n) { return go_lsp_set_timeout(f, n); }
+static int cmd_lsp_reload_faces(int f, int n) { return go_lsp_reload_faces(f, n); }
+static int cmd_lsp_expand_selection(int f, int n) { return go_lsp_expand_selection(f, n); }
+static int cmd_lsp_shrink_selection(int f, int n) { return go_lsp_shrink_selection(f, n); }
+static int cmd_lsp_reset_selection(int f, int n) { return go_lsp_reset_selection(f, n); }
+static int cmd_lsp_pull_diagnostics(int f, int n) { return go_lsp_pull_diagnostics(f, n); }
 
 /* Event handlers for automatic didSave/didClose */
 static bool on_buffer_saved(void *buffer, void *user_data) {
@@ -224,6 +309,28 @@ static bool on_buffer_closed(void *buffer, void *user_data) {
     return true;
 }
 
+/* Key handler: Enter jumps to the location in result buffers; edits
+ * trigger signature help and, after a trigger character, completion
+ * (never consumed) */
+static bool on_input_key(void *event_raw, void *user_data) {
+    (void)user_data;
+    uemacs_event_t *event = event_raw;
+    if (!event || !event->data) return false;
+
+    /* Apply workspace edits sent by servers */
+    go_lsp_flush_edits();
+
+    int key = *(int *)event->data;
+    if (key == '\r' || key == '\n') {
+        return go_lsp_result_enter() != 0;
+    }
+    if ((key >= 0x20 && key < 0x7f) || key == 0x7f || key == 0x08) {
+        go_lsp_did_change(key);
+        go_lsp_auto_complete(key);
+    }
+    return false;
+}
+
 /* Language patterns for lexer registration */
 static const char *py_patterns[] = {"*.py", NULL};
 static const char *go_patterns[] = {"*.go", NULL};
@@ -264,13 +371,18 @@ static int lsp_init_c(void *editor_api_raw) {
     api.log_error = (log_fn)LOOKUP(log_error);
     api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
     api.buffer_filename = (buffer_filename_fn)LOOKUP(buffer_filename);
+    api.buffer_name = (buffer_name_fn)LOOKUP(buffer_name);
     api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
     api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.get_mark = (get_mark_fn)LOOKUP(get_mark);
+    api.set_mark = (set_mark_fn)LOOKUP(set_mark);
     api.find_file_line = (find_file_line_fn)LOOKUP(find_file_line);
     api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
     api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
     api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
     api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.buffer_set_unmodified = (buffer_set_unmodified_fn)LOOKUP(buffer_set_unmodified);
     api.free = (free_fn)LOOKUP(free);
     api.syntax_add_token = (syntax_add_token_fn)LOOKUP(syntax_add_token);
     api.syntax_invalidate_buffer = (syntax_invalidate_buffer_fn)LOOKUP(syntax_invalidate_buffer);
@@ -281,6 +393,8 @@ static int lsp_init_c(void *editor_api_raw) {
     api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
     api.syntax_register_lexer = (syntax_register_lexer_fn)LOOKUP(syntax_register_lexer);
     api.syntax_unregister_lexer = (syntax_unregister_lexer_fn)LOOKUP(syntax_unregister_lexer);
+    api.config_int = (config_int_fn)LOOKUP(config_int);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
 
     #undef LOOKUP
 
@@ -295,13 +409,44 @@ static int lsp_init_c(void *editor_api_raw) {
     api.register_command("lsp-stop", cmd_lsp_stop);
     api.register_command("lsp-hover", cmd_lsp_hover);
     api.register_command("lsp-definition", cmd_lsp_definition);
+    api.register_command("lsp-back", cmd_lsp_back);
+    api.register_command("lsp-forward", cmd_lsp_forward);
     api.register_command("lsp-references", cmd_lsp_references);
     api.register_command("lsp-refresh-tokens", cmd_lsp_refresh_tokens);
     api.register_command("lsp-completion", cmd_lsp_completion);
     api.register_command("lsp-diagnostics", cmd_lsp_diagnostics);
     api.register_command("lsp-code-action", cmd_lsp_code_action);
+    api.register_command("lsp-organize-imports", cmd_lsp_organize_imports);
     api.register_command("lsp-document-symbols", cmd_lsp_document_symbols);
     api.register_command("lsp-workspace-symbols", cmd_lsp_workspace_symbols);
+    api.register_command("lsp-workspace-functions", cmd_lsp_workspace_functions);
+    api.register_command("lsp-status", cmd_lsp_status);
+    api.register_command("lsp-customize-faces", cmd_lsp_customize_faces);
+    api.register_command("lsp-rename", cmd_lsp_rename);
+    api.register_command("lsp-format", cmd_lsp_format);
+    api.register_command("lsp-format-range", cmd_lsp_format_range);
+    api.register_command("lsp-signature-help", cmd_lsp_signature_help);
+    api.register_command("lsp-incoming-calls", cmd_lsp_incoming_calls);
+    api.register_command("lsp-outgoing-calls", cmd_lsp_outgoing_calls);
+    api.register_command("lsp-goto-result", cmd_lsp_goto_result);
+    api.register_command("lsp-code-lens", cmd_lsp_code_lens);
+    api.register_command("lsp-execute-code-lens", cmd_lsp_execute_code_lens);
+    api.register_command("lsp-refresh-code-lens", cmd_lsp_refresh_code_lens);
+    api.register_command("lsp-inlay-hints", cmd_lsp_inlay_hints);
+    api.register_command("lsp-refresh-hints", cmd_lsp_refresh_hints);
+    api.register_command("lsp-server-status", cmd_lsp_server_status);
+    api.register_command("lsp-next-diagnostic", cmd_lsp_next_diagnostic);
+    api.register_command("lsp-prev-diagnostic", cmd_lsp_prev_diagnostic);
+    api.register_command("lsp-first-error", cmd_lsp_first_error);
+    api.register_command("lsp-list-servers", cmd_lsp_list_servers);
+    api.register_command("lsp-cancel", cmd_lsp_cancel);
+    api.register_command("lsp-config", cmd_lsp_config);
+    api.register_command("lsp-set-timeout", cmd_lsp_set_timeout);
+    api.register_command("lsp-reload-faces", cmd_lsp_reload_faces);
+    api.register_command("lsp-expand-selection", cmd_lsp_expand_selection);
+    api.register_command("lsp-shrink-selection", cmd_lsp_shrink_selection);
+    api.register_command("lsp-reset-selection", cmd_lsp_reset_selection);
+    api.register_command("lsp-pull-diagnostics", cmd_lsp_pull_diagnostics);
 
     /* Register as lexer for supported languages */
     if (api.syntax_register_lexer) {
@@ -317,6 +462,7 @@ static int lsp_init_c(void *editor_api_raw) {
     if (api.on) {
         api.on("buffer:saved", on_buffer_saved, NULL, 0);
         api.on("buffer:closed", on_buffer_closed, NULL, 0);
+        api.on("input:key", on_input_key, NULL, 0);
     }
 
     api.log_info("lsp_client: Go extension loaded (v5.0, ABI-stable)");
@@ -330,13 +476,44 @@ static void lsp_cleanup_c(void) {
         api.unregister_command("lsp-stop");
         api.unregister_command("lsp-hover");
         api.unregister_command("lsp-definition");
+        api.unregister_command("lsp-back");
+        api.unregister_command("lsp-forward");
         api.unregister_command("lsp-references");
         api.unregister_command("lsp-refresh-tokens");
         api.unregister_command("lsp-completion");
         api.unregister_command("lsp-diagnostics");
         api.unregister_command("lsp-code-action");
+        api.unregister_command("lsp-organize-imports");
         api.unregister_command("lsp-document-symbols");
         api.unregister_command("lsp-workspace-symbols");
+        api.unregister_command("lsp-workspace-functions");
+        api.unregister_command("lsp-status");
+        api.unregister_command("lsp-customize-faces");
+        api.unregister_command("lsp-rename");
+        api.unregister_command("lsp-format");
+        api.unregister_command("lsp-format-range");
+        api.unregister_command("lsp-signature-help");
+        api.unregister_command("lsp-incoming-calls");
+        api.unregister_command("lsp-outgoing-calls");
+        api.unregister_command("lsp-goto-result");
+        api.unregister_command("lsp-code-lens");
+        api.unregister_command("lsp-execute-code-lens");
+        api.unregister_command("lsp-refresh-code-lens");
+        api.unregister_command("lsp-inlay-hints");
+        api.unregister_command("lsp-refresh-hints");
+        api.unregister_command("lsp-server-status");
+        api.unregister_command("lsp-next-diagnostic");
+        api.unregister_command("lsp-prev-diagnostic");
+        api.unregister_command("lsp-first-error");
+        api.unregister_command("lsp-list-servers");
+        api.unregister_command("lsp-cancel");
+        api.unregister_command("lsp-config");
+        api.unregister_command("lsp-set-timeout");
+        api.unregister_command("lsp-reload-faces");
+        api.unregister_command("lsp-expand-selection");
+        api.unregister_command("lsp-shrink-selection");
+        api.unregister_command("lsp-reset-selection");
+        api.unregister_command("lsp-pull-diagnostics");
     }
 
     /* Unregister lexers */
@@ -353,6 +530,7 @@ static void lsp_cleanup_c(void) {
     if (api.off) {
         api.off("buffer:saved", on_buffer_saved);
         api.off("buffer:closed", on_buffer_closed);
+        api.off("input:key", on_input_key);
     }
 }
 
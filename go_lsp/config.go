@@ -0,0 +1,634 @@
+package main
+
+// User-configured language servers from the [lsp.servers] section of
+// settings.toml. The extension config API only reads single keys, so the
+// section is parsed here. Each entry maps an extension or glob to an inline
+// table:
+//
+//	[lsp.servers]
+//	".zig" = { command = "/opt/zls/bin/zls" }
+//	".ex"  = { command = "nil", args = ["--stdio"], language_id = "elixir" }
+//	"*_test.py" = { command = "pylsp", language_id = "python" }
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lspServersSection is the settings.toml table holding server overrides
+const lspServersSection = "lsp.servers"
+
+// lspTimeoutsSection is the settings.toml table holding request timeouts:
+//
+//	[lsp.timeouts]
+//	default = "30s"
+//	"textDocument/hover" = "2s"
+//	"workspace/symbol" = 60
+const lspTimeoutsSection = "lsp.timeouts"
+
+// lspFacesSection is the settings.toml table mapping semantic token types
+// to faces, by name or numeric face ID. [lsp.faces.<language>] tables
+// override it for one language ID; lsp-customize-faces writes to both:
+//
+//	[lsp.faces]
+//	variable = "constant"
+//	parameter = 17
+//
+//	[lsp.faces.go]
+//	variable = "default"
+const lspFacesSection = "lsp.faces"
+
+// LSPServerConfig is one [lsp.servers] entry
+type LSPServerConfig struct {
+	Command    string
+	Args       []string
+	LanguageID string
+}
+
+// lspConfig caches the parsed [lsp.servers] section
+var (
+	lspConfigMu     sync.Mutex
+	lspConfigCache  map[string]LSPServerConfig
+	lspConfigGlobs  []string // Glob patterns among the keys, sorted
+	lspConfigErrors []string // Entries that could not be parsed
+
+	lspTimeoutCache  map[string]time.Duration // [lsp.timeouts], nil until read
+	lspTimeoutErrors []string
+
+	lspFaceCache  map[string]map[string]int // Language -> [lsp.faces], nil until read
+	lspFaceErrors []string
+)
+
+// builtinServerExtensions lists the extensions detectLanguageServer knows
+var builtinServerExtensions = []string{
+	".c", ".cpp", ".go", ".h", ".hpp", ".js", ".py", ".rs", ".ts", ".zig",
+}
+
+// settingsPath returns the path of the editor's settings.toml
+func settingsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs", "settings.toml")
+}
+
+// loadLSPConfig returns the [lsp.servers] entries, reading settings.toml
+// on first use
+func loadLSPConfig() map[string]LSPServerConfig {
+	lspConfigMu.Lock()
+	defer lspConfigMu.Unlock()
+	if lspConfigCache == nil {
+		lspConfigCache, lspConfigErrors = parseLSPConfig(settingsPath())
+		lspConfigGlobs = nil
+		for p := range lspConfigCache {
+			if strings.ContainsAny(p, "*?[") {
+				lspConfigGlobs = append(lspConfigGlobs, p)
+			}
+		}
+		sort.Strings(lspConfigGlobs)
+	}
+	return lspConfigCache
+}
+
+// reloadLSPConfig drops the cached config so the next lookup re-reads it
+func reloadLSPConfig() {
+	lspConfigMu.Lock()
+	lspConfigCache = nil
+	lspTimeoutCache = nil
+	lspFaceCache = nil
+	lspConfigMu.Unlock()
+}
+
+// loadTimeoutConfig returns the [lsp.timeouts] entries, reading
+// settings.toml on first use
+func loadTimeoutConfig() map[string]time.Duration {
+	lspConfigMu.Lock()
+	defer lspConfigMu.Unlock()
+	if lspTimeoutCache == nil {
+		lspTimeoutCache, lspTimeoutErrors = parseTimeoutConfig(settingsPath())
+	}
+	return lspTimeoutCache
+}
+
+// parseTimeoutConfig reads the [lsp.timeouts] section of a settings file:
+// method = duration, with default for methods not listed. A missing file
+// yields an empty config.
+func parseTimeoutConfig(path string) (map[string]time.Duration, []string) {
+	timeouts := make(map[string]time.Duration)
+	var errs []string
+
+	f, err := os.Open(path)
+	if err != nil {
+		return timeouts, nil
+	}
+	defer f.Close()
+
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inSection = strings.TrimSpace(strings.Trim(line, "[]")) == lspTimeoutsSection
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			errs = append(errs, fmt.Sprintf("line %d: expected method = duration", lineNum))
+			continue
+		}
+		method := unquoteTOML(strings.TrimSpace(key))
+		d, err := parseTimeout(unquoteTOML(strings.TrimSpace(value)))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d (%s): %v", lineNum, method, err))
+			continue
+		}
+		timeouts[method] = d
+	}
+	return timeouts, errs
+}
+
+// parseTimeout reads a duration such as "2s" or "500ms"; a bare number is
+// in seconds
+func parseTimeout(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		secs, nerr := strconv.ParseFloat(s, 64)
+		if nerr != nil {
+			return 0, fmt.Errorf("bad duration %q", s)
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return d, nil
+}
+
+// loadFaceConfig returns the [lsp.faces] entries by language ID, the
+// [lsp.faces] table itself under defaultFaceLanguage, reading
+// settings.toml on first use
+func loadFaceConfig() map[string]map[string]int {
+	lspConfigMu.Lock()
+	defer lspConfigMu.Unlock()
+	if lspFaceCache == nil {
+		lspFaceCache, lspFaceErrors = parseFaceConfig(settingsPath())
+	}
+	return lspFaceCache
+}
+
+// parseFaceConfig reads the [lsp.faces] and [lsp.faces.<language>]
+// sections of a settings file: token type = face. A face is a name from
+// faceNames or any non-negative face ID, so faces the editor defines
+// beyond the built-in ones work too. A missing file yields an empty config.
+func parseFaceConfig(path string) (map[string]map[string]int, []string) {
+	faces := map[string]map[string]int{defaultFaceLanguage: {}}
+	var errs []string
+
+	f, err := os.Open(path)
+	if err != nil {
+		return faces, nil
+	}
+	defer f.Close()
+
+	lang := "" // Language of the current face table, "" outside them
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			lang = faceSectionLanguage(line)
+			continue
+		}
+		if lang == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			errs = append(errs, fmt.Sprintf("line %d: expected token-type = face", lineNum))
+			continue
+		}
+		tokenType := unquoteTOML(strings.TrimSpace(key))
+		value = unquoteTOML(strings.TrimSpace(value))
+		face, ok := parseFace(value)
+		if !ok {
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 0 {
+				errs = append(errs, fmt.Sprintf("line %d (%s): unknown face %q", lineNum, tokenType, value))
+				continue
+			}
+			face = id
+		}
+		if faces[lang] == nil {
+			faces[lang] = make(map[string]int)
+		}
+		faces[lang][tokenType] = face
+	}
+	return faces, errs
+}
+
+// faceSectionLanguage returns the language ID whose faces the table
+// header opens: defaultFaceLanguage for [lsp.faces], go for
+// [lsp.faces.go], "" for other tables
+func faceSectionLanguage(header string) string {
+	name := strings.TrimSpace(strings.Trim(header, "[]"))
+	if name == lspFacesSection {
+		return defaultFaceLanguage
+	}
+	if lang, ok := strings.CutPrefix(name, lspFacesSection+"."); ok {
+		return unquoteTOML(strings.TrimSpace(lang))
+	}
+	return ""
+}
+
+// faceSection returns the table header holding languageID's faces
+func faceSection(languageID string) string {
+	if languageID == defaultFaceLanguage {
+		return "[" + lspFacesSection + "]"
+	}
+	return "[" + lspFacesSection + "." + tomlKey(languageID) + "]"
+}
+
+// tomlKey returns s as a TOML key, quoted unless it is a bare key
+func tomlKey(s string) string {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return strconv.Quote(s)
+		}
+	}
+	if s == "" {
+		return `""`
+	}
+	return s
+}
+
+// saveFaceSetting sets tokenType to face in languageID's face table of
+// the settings file at path, or removes the entry when face is negative.
+// The rest of the file is left as it is.
+func saveFaceSetting(path, languageID, tokenType string, face int) error {
+	if path == "" {
+		return fmt.Errorf("no home directory")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	// Find the table and the entry in it
+	section, end, found := -1, len(lines), -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(stripTOMLComment(line))
+		if strings.HasPrefix(trimmed, "[") {
+			if section >= 0 {
+				end = i
+				break
+			}
+			if faceSectionLanguage(trimmed) == languageID {
+				section = i
+			}
+			continue
+		}
+		if key, _, ok := strings.Cut(trimmed, "="); ok && section >= 0 &&
+			unquoteTOML(strings.TrimSpace(key)) == tokenType {
+			found = i
+		}
+	}
+
+	entry := fmt.Sprintf("%s = %q", tomlKey(tokenType), faceName(face))
+	switch {
+	case found >= 0 && face < 0:
+		lines = append(lines[:found], lines[found+1:]...)
+	case found >= 0:
+		lines[found] = entry
+	case face < 0:
+		return nil // Nothing to remove
+	case section >= 0:
+		// After the table's last entry, before the blank lines ending it
+		at := end
+		for at > section+1 && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		lines = append(lines[:at], append([]string{entry}, lines[at:]...)...)
+	default:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, faceSection(languageID), entry)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// parseLSPConfig reads the [lsp.servers] section of a settings file.
+// A missing file yields an empty config.
+func parseLSPConfig(path string) (map[string]LSPServerConfig, []string) {
+	servers := make(map[string]LSPServerConfig)
+	var errs []string
+
+	f, err := os.Open(path)
+	if err != nil {
+		return servers, nil
+	}
+	defer f.Close()
+
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inSection = strings.TrimSpace(strings.Trim(line, "[]")) == lspServersSection
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			errs = append(errs, fmt.Sprintf("line %d: expected key = { ... }", lineNum))
+			continue
+		}
+		pattern := normalizeServerPattern(unquoteTOML(strings.TrimSpace(key)))
+
+		cfg, err := parseServerTable(strings.TrimSpace(value))
+		if err == nil && cfg.Command == "" {
+			err = fmt.Errorf("missing command")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d (%s): %v", lineNum, pattern, err))
+			continue
+		}
+		if cfg.LanguageID == "" {
+			cfg.LanguageID = builtinLanguageID(pattern)
+		}
+		servers[pattern] = cfg
+	}
+	return servers, errs
+}
+
+// stripTOMLComment removes a trailing # comment outside of strings
+func stripTOMLComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// unquoteTOML strips surrounding double quotes and unescapes \" and \\
+func unquoteTOML(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+		s = strings.ReplaceAll(s, `\"`, `"`)
+		s = strings.ReplaceAll(s, `\\`, `\`)
+	}
+	return s
+}
+
+// normalizeServerPattern turns "zig" and "*.zig" into ".zig"; other globs
+// are kept as basename patterns
+func normalizeServerPattern(p string) string {
+	if strings.HasPrefix(p, "*.") && !strings.ContainsAny(p[2:], "*?[") {
+		return p[1:]
+	}
+	if !strings.ContainsAny(p, "*?[.") {
+		return "." + p
+	}
+	return p
+}
+
+// tomlTokens splits an inline table into strings, punctuation and bare words
+func tomlTokens(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("{}[]=,", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t{}[]=,\"", s[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// parseServerTable parses `{ command = "...", args = ["..."], language_id = "..." }`
+func parseServerTable(s string) (LSPServerConfig, error) {
+	var cfg LSPServerConfig
+	tokens, err := tomlTokens(s)
+	if err != nil {
+		return cfg, err
+	}
+	if len(tokens) < 2 || tokens[0] != "{" || tokens[len(tokens)-1] != "}" {
+		return cfg, fmt.Errorf("expected inline table { ... }")
+	}
+	tokens = tokens[1 : len(tokens)-1]
+
+	for len(tokens) > 0 {
+		if len(tokens) < 3 || tokens[1] != "=" {
+			return cfg, fmt.Errorf("expected key = value")
+		}
+		key := unquoteTOML(tokens[0])
+		tokens = tokens[2:]
+
+		switch key {
+		case "command", "language_id":
+			if !strings.HasPrefix(tokens[0], `"`) {
+				return cfg, fmt.Errorf("%s must be a string", key)
+			}
+			if key == "command" {
+				cfg.Command = unquoteTOML(tokens[0])
+			} else {
+				cfg.LanguageID = unquoteTOML(tokens[0])
+			}
+			tokens = tokens[1:]
+		case "args":
+			if tokens[0] != "[" {
+				return cfg, fmt.Errorf("args must be an array of strings")
+			}
+			tokens = tokens[1:]
+			for len(tokens) > 0 && tokens[0] != "]" {
+				if tokens[0] == "," {
+					tokens = tokens[1:]
+					continue
+				}
+				if !strings.HasPrefix(tokens[0], `"`) {
+					return cfg, fmt.Errorf("args must be an array of strings")
+				}
+				cfg.Args = append(cfg.Args, unquoteTOML(tokens[0]))
+				tokens = tokens[1:]
+			}
+			if len(tokens) == 0 {
+				return cfg, fmt.Errorf("unterminated args array")
+			}
+			tokens = tokens[1:]
+		default:
+			return cfg, fmt.Errorf("unknown key %q", key)
+		}
+
+		if len(tokens) > 0 {
+			if tokens[0] != "," {
+				return cfg, fmt.Errorf("expected , between entries")
+			}
+			tokens = tokens[1:]
+		}
+	}
+	return cfg, nil
+}
+
+// builtinLanguageID derives a language ID for a pattern without one
+func builtinLanguageID(pattern string) string {
+	if id := builtinDetectLanguageID("file" + filepath.Ext(pattern)); id != "plaintext" {
+		return id
+	}
+	return strings.TrimPrefix(filepath.Ext(pattern), ".")
+}
+
+// configuredServer returns the [lsp.servers] entry for filename: an exact
+// extension match first, then glob patterns in sorted order
+func configuredServer(filename string) (LSPServerConfig, bool) {
+	servers := loadLSPConfig()
+	if len(servers) == 0 {
+		return LSPServerConfig{}, false
+	}
+	if cfg, ok := servers[filepath.Ext(filename)]; ok {
+		return cfg, true
+	}
+
+	lspConfigMu.Lock()
+	globs := lspConfigGlobs
+	lspConfigMu.Unlock()
+
+	base := filepath.Base(filename)
+	for _, p := range globs {
+		if ok, _ := filepath.Match(p, base); ok {
+			return servers[p], true
+		}
+	}
+	return LSPServerConfig{}, false
+}
+
+// renderLSPConfig formats the resolved per-extension configuration for
+// the *lsp-config* buffer
+func renderLSPConfig() string {
+	servers := loadLSPConfig()
+	lspConfigMu.Lock()
+	errs := lspConfigErrors
+	lspConfigMu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("LSP server configuration\n\n")
+	sb.WriteString(fmt.Sprintf("Settings: %s [%s]\n\n", settingsPath(), lspServersSection))
+
+	patterns := make([]string, 0, len(servers)+len(builtinServerExtensions))
+	seen := make(map[string]bool)
+	for p := range servers {
+		patterns = append(patterns, p)
+		seen[p] = true
+	}
+	for _, ext := range builtinServerExtensions {
+		if !seen[ext] {
+			patterns = append(patterns, ext)
+		}
+	}
+	sort.Strings(patterns)
+
+	sb.WriteString(fmt.Sprintf("%-14s %-12s %-8s %s\n", "PATTERN", "LANGUAGE", "SOURCE", "COMMAND"))
+	for _, p := range patterns {
+		cfg, source := servers[p], "config"
+		if !seen[p] {
+			name := "file" + p
+			cmd, args := builtinDetectLanguageServer(name)
+			cfg = LSPServerConfig{Command: cmd, Args: args, LanguageID: builtinDetectLanguageID(name)}
+			source = "built-in"
+		}
+		command := strings.TrimSpace(cfg.Command + " " + strings.Join(cfg.Args, " "))
+		sb.WriteString(fmt.Sprintf("%-14s %-12s %-8s %s\n", p, cfg.LanguageID, source, command))
+	}
+
+	if len(errs) > 0 {
+		sb.WriteString("\nIgnored entries:\n")
+		for _, e := range errs {
+			sb.WriteString("  " + e + "\n")
+		}
+	}
+
+	timeouts := effectiveTimeouts()
+	lspConfigMu.Lock()
+	timeoutErrs := lspTimeoutErrors
+	lspConfigMu.Unlock()
+	sb.WriteString(fmt.Sprintf("\nRequest timeouts [%s]\n\n", lspTimeoutsSection))
+	methods := make([]string, 0, len(timeouts))
+	for m := range timeouts {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	for _, m := range methods {
+		sb.WriteString(fmt.Sprintf("%-34s %v\n", m, timeouts[m]))
+	}
+	if len(timeoutErrs) > 0 {
+		sb.WriteString("\nIgnored timeouts:\n")
+		for _, e := range timeoutErrs {
+			sb.WriteString("  " + e + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -32,16 +32,24 @@ extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
 extern void* api_current_buffer(void);
 extern const char* api_buffer_filename(void *bp);
+extern const char* api_buffer_name(void *bp);
 extern char* api_buffer_contents(void *bp, size_t *len);
 extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_get_mark(int *line, int *col);
+extern int api_set_mark(void);
 extern int api_find_file_line(const char *path, int line);
 extern void* api_buffer_create(const char *name);
 extern int api_buffer_switch(void *bp);
 extern int api_buffer_clear(void *bp);
 extern int api_buffer_insert(const char *text, size_t len);
+extern void api_buffer_set_unmodified(void *bp);
 extern void api_free(void *ptr);
 extern int api_syntax_add_token(void *tokens, int end_col, int face);
 extern void api_syntax_invalidate_buffer(void *bp);
+extern int api_config_int(const char *key, int default_val);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_emit(const char *event, void *data);
 
 // Diagnostic event types for linter integration
 typedef struct {
@@ -117,21 +125,55 @@ typedef struct { void *data; GoInt len; GoInt cap; } GoSlice;
 extern "C" {
 #endif
 
+extern void go_lsp_flush_edits(void);
 extern int go_lsp_start(int f, int n);
 extern int go_lsp_stop(int f, int n);
+extern int go_lsp_cancel(int f, int n);
+extern int go_lsp_config(int f, int n);
+extern int go_lsp_set_timeout(int f, int n);
+extern int go_lsp_reload_faces(int f, int n);
+extern int go_lsp_list_servers(int f, int n);
 extern int go_lsp_hover(int f, int n);
 extern int go_lsp_definition(int f, int n);
+extern int go_lsp_back(int f, int n);
+extern int go_lsp_forward(int f, int n);
 extern int go_lsp_references(int f, int n);
 extern int go_lsp_refresh_tokens(int f, int n);
 extern int go_lsp_did_save(int f, int n);
 extern int go_lsp_did_close(int f, int n);
 extern int go_lsp_completion(int f, int n);
+extern int go_lsp_auto_complete(int key);
 extern int go_lsp_diagnostics(int f, int n);
+extern int go_lsp_pull_diagnostics(int f, int n);
+extern int go_lsp_next_diagnostic(int f, int n);
+extern int go_lsp_prev_diagnostic(int f, int n);
+extern int go_lsp_first_error(int f, int n);
 extern int go_lsp_code_action(int f, int n);
+extern int go_lsp_organize_imports(int f, int n);
+extern int go_lsp_organize_imports_on_save(int f, int n);
 extern int go_lsp_document_symbols(int f, int n);
 extern int go_lsp_workspace_symbols(int f, int n);
-
-// Lexer callback - called from C for each line
+extern int go_lsp_workspace_functions(int f, int n);
+extern int go_lsp_rename(int f, int n);
+extern int go_lsp_format(int f, int n);
+extern int go_lsp_format_range(int f, int n);
+extern int go_lsp_signature_help(int f, int n);
+extern int go_lsp_did_change(int key);
+extern int go_lsp_incoming_calls(int f, int n);
+extern int go_lsp_outgoing_calls(int f, int n);
+extern int go_lsp_goto_result_line(int f, int n);
+extern int go_lsp_result_enter(void);
+extern int go_lsp_code_lens(int f, int n);
+extern int go_lsp_refresh_code_lens(int f, int n);
+extern int go_lsp_execute_code_lens(int f, int n);
+extern int go_lsp_inlay_hints(int f, int n);
+extern int go_lsp_refresh_hints(int f, int n);
+extern int go_lsp_expand_selection(int f, int n);
+extern int go_lsp_shrink_selection(int f, int n);
+extern int go_lsp_reset_selection(int f, int n);
+extern int go_lsp_status(int f, int n);
+extern int go_lsp_server_status(int f, int n);
+extern int go_lsp_customize_faces(int f, int n);
 extern void go_lsp_lex_line(void* userData, void* buffer, int lineNum, char* line, int lineLen, void* outTokens);
 
 #ifdef __cplusplus
@@ -10,16 +10,24 @@ extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
 extern void* api_current_buffer(void);
 extern const char* api_buffer_filename(void *bp);
+extern const char* api_buffer_name(void *bp);
 extern char* api_buffer_contents(void *bp, size_t *len);
 extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_get_mark(int *line, int *col);
+extern int api_set_mark(void);
 extern int api_find_file_line(const char *path, int line);
 extern void* api_buffer_create(const char *name);
 extern int api_buffer_switch(void *bp);
 extern int api_buffer_clear(void *bp);
 extern int api_buffer_insert(const char *text, size_t len);
+extern void api_buffer_set_unmodified(void *bp);
 extern void api_free(void *ptr);
 extern int api_syntax_add_token(void *tokens, int end_col, int face);
 extern void api_syntax_invalidate_buffer(void *bp);
+extern int api_config_int(const char *key, int default_val);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_emit(const char *event, void *data);
 
 // Diagnostic event types for linter integration
 typedef struct {
@@ -37,6 +45,7 @@ import "C"
 
 import (
 	"bufio"
+	"container/list"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -44,11 +53,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -72,6 +85,8 @@ const (
 	FaceEscape       = 12
 	FaceRegex        = 13
 	FaceSpecial      = 14
+	FaceError        = 15
+	FaceWarning      = 16
 )
 
 // =============================================================================
@@ -93,24 +108,96 @@ type LSPClient struct {
 	// Writer actor channel (replaces mutex on stdin)
 	writeReqs chan *outboundMsg
 
+	// Closed when responseReader returns; process.Wait closes stdout, so
+	// the monitor waits for this first
+	readerDone chan struct{}
+
 	// Request/response handling
 	nextID   atomic.Int64
 	pending  sync.Map // map[int64]chan *jsonRPCResponse
+	inflight sync.Map // map[int64]*inflightRequest
 	ctx      context.Context
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 
 	// Server info
+	languageID string
 	serverCmd  string
 	serverArgs []string
 	rootURI    string
 
 	// Capabilities
 	hasSemanticTokens bool
+	hasCodeLens       bool
+	hasInlayHints     bool
+	pullDiagnostics   bool // Server pulls diagnostics (textDocument/diagnostic)
 	tokenTypes        []string
 	tokenModifiers    []string
+	syncKind          int      // Server's TextDocumentSyncKind (0 none, 1 full, 2 incremental)
+	triggerChars      []string // completionProvider.triggerCharacters
+
+	// Last text sent per open document, for incremental didChange;
+	// syncMu keeps concurrent syncs from sending the same version
+	syncMu        sync.Mutex
+	docsMu        sync.Mutex
+	docs          map[string]*openDocument
+	syncThreshold int // Changed lines above which a full sync is sent
+
+	// Last pull diagnostics resultId per URI
+	diagResultIDs sync.Map // map[string]string
+
+	// Work done progress in flight, from $/progress
+	progressTokens sync.Map // map[string]ProgressState
+
+	// workspace/applyEdit requests waiting for the editor thread, which
+	// applies them in flushEdits; editReady is signalled as one arrives
+	editMu    sync.Mutex
+	editQueue []*jsonRPCServerRequest
+	editReady chan struct{}
+
+	// Per-language face table: language ID -> token type -> face, from
+	// [lsp.faces]; tokenFaceMap holds the built-in faces beneath it
+	faceMu       sync.RWMutex
+	faceTable    map[string]map[string]int
+	tokenFaceMap map[string]int // Token type -> built-in face
+
+	// Request timeouts: per method, else DefaultTimeout
+	timeoutMu      sync.RWMutex
+	MethodTimeouts map[string]time.Duration
+	DefaultTimeout time.Duration
+
+	// Crash recovery
+	stopping    atomic.Bool // Set by go_lsp_stop so the exit isn't treated as a crash
+	maxRestarts int
+	restarts    int    // Restarts so far, carried over to the replacement client
+	lastExit    string // Exit status of the previous server process
+}
+
+// openDocument is the server's view of an open document
+type openDocument struct {
+	languageID string
+	text       string
+	version    int
 }
 
+// defaultMaxRestarts is how many times a crashed server is restarted
+const defaultMaxRestarts = 3
+
+// restartBackoff is the delay before the first restart attempt; it doubles
+// with every further attempt
+const restartBackoff = time.Second
+
+// TextDocumentSyncKind values
+const (
+	syncNone        = 0
+	syncFull        = 1
+	syncIncremental = 2
+)
+
+// defaultSyncThreshold is the number of changed lines above which
+// didChange falls back to sending the full document
+const defaultSyncThreshold = 1000
+
 type jsonRPCRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
 	ID      int64       `json:"id,omitempty"`
@@ -131,6 +218,23 @@ type jsonRPCNotification struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 }
 
+// jsonRPCServerRequest is a request initiated by the server; its ID may be
+// a number or a string and is echoed back verbatim
+type jsonRPCServerRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// jsonRPCReply answers a server request
+type jsonRPCReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
 type jsonRPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -146,13 +250,141 @@ type SemanticToken struct {
 	Length    int
 	TokenType int
 	Modifiers int
+	Face      int // Resolved from the face table when fetched
 }
 
 type BufferTokens struct {
-	mu       sync.RWMutex
-	tokens   []SemanticToken
-	version  int
-	fetching atomic.Bool
+	mu      sync.RWMutex
+	tokens  []SemanticToken
+	version int
+
+	// Request ID of the in-flight semantic tokens fetch (0 if none)
+	lastTokenFetchID atomic.Int64
+}
+
+// defaultTokenCacheSize is the number of buffers whose tokens are kept
+// before the least recently used entry is evicted.
+const defaultTokenCacheSize = 50
+
+// tokenLRU is a bounded, thread-safe cache of per-buffer semantic tokens.
+// Entries are ordered by last access; the back of the list is evicted first.
+type tokenLRU struct {
+	mu        sync.Mutex
+	limit     int
+	order     *list.List // *tokenLRUEntry, most recently used at front
+	entries   map[unsafe.Pointer]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type tokenLRUEntry struct {
+	bp     unsafe.Pointer
+	tokens *BufferTokens
+}
+
+// TokenCacheStats is a snapshot of token cache usage
+type TokenCacheStats struct {
+	Size      int
+	Limit     int
+	Evictions uint64
+	Hits      uint64
+	Misses    uint64
+	HitRate   float64 // 0.0-1.0
+}
+
+func newTokenLRU(limit int) *tokenLRU {
+	if limit <= 0 {
+		limit = defaultTokenCacheSize
+	}
+	return &tokenLRU{
+		limit:   limit,
+		order:   list.New(),
+		entries: make(map[unsafe.Pointer]*list.Element),
+	}
+}
+
+// GetOrCreate returns the tokens for bp, creating an empty entry on a miss
+// and evicting the least recently used buffer if the cache is full.
+func (l *tokenLRU) GetOrCreate(bp unsafe.Pointer) *BufferTokens {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[bp]; ok {
+		l.hits++
+		l.order.MoveToFront(elem)
+		return elem.Value.(*tokenLRUEntry).tokens
+	}
+
+	l.misses++
+	bt := &BufferTokens{}
+	l.entries[bp] = l.order.PushFront(&tokenLRUEntry{bp: bp, tokens: bt})
+	l.evictLocked()
+	return bt
+}
+
+// Delete drops the tokens for bp
+func (l *tokenLRU) Delete(bp unsafe.Pointer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[bp]; ok {
+		l.order.Remove(elem)
+		delete(l.entries, bp)
+	}
+}
+
+// Clear drops all entries (statistics are kept)
+func (l *tokenLRU) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	l.entries = make(map[unsafe.Pointer]*list.Element)
+}
+
+// SetLimit changes the maximum number of cached buffers, evicting as needed
+func (l *tokenLRU) SetLimit(limit int) {
+	if limit <= 0 {
+		limit = defaultTokenCacheSize
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.evictLocked()
+}
+
+func (l *tokenLRU) evictLocked() {
+	for l.order.Len() > l.limit {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*tokenLRUEntry).bp)
+		l.evictions++
+	}
+}
+
+// Stats returns a snapshot of cache size, evictions and hit rate
+func (l *tokenLRU) Stats() TokenCacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := TokenCacheStats{
+		Size:      l.order.Len(),
+		Limit:     l.limit,
+		Evictions: l.evictions,
+		Hits:      l.hits,
+		Misses:    l.misses,
+	}
+	if total := l.hits + l.misses; total > 0 {
+		stats.HitRate = float64(l.hits) / float64(total)
+	}
+	return stats
+}
+
+// tokenCacheStats returns current semantic token cache statistics
+func tokenCacheStats() TokenCacheStats {
+	return tokenCache.Stats()
 }
 
 // Diagnostic represents an LSP diagnostic
@@ -164,24 +396,43 @@ type Diagnostic struct {
 }
 
 var (
-	clientPtr       atomic.Pointer[LSPClient]
-	tokenCache      sync.Map // map[unsafe.Pointer]*BufferTokens (buffer ptr -> tokens)
-	diagnosticCache sync.Map // map[string][]Diagnostic (URI -> diagnostics)
+	clientMap       sync.Map                             // map[string]*LSPClient (language ID -> client)
+	tokenCache      = newTokenLRU(defaultTokenCacheSize) // buffer ptr -> tokens (LRU)
+	diagnosticCache sync.Map                             // map[string][]Diagnostic (URI -> diagnostics)
+	codeLensCache   sync.Map                             // map[string][]CodeLens (URI -> resolved lenses)
+	inlayHintCache  sync.Map                             // map[unsafe.Pointer][]InlayHint (buffer ptr -> hints)
 )
 
 // =============================================================================
 // LSP Client Methods
 // =============================================================================
 
-func NewLSPClient(serverCmd string, serverArgs []string, rootURI string) (*LSPClient, error) {
+// NewLSPClient creates a client for a server, not yet started. opts are
+// applied last, e.g. WithTimeout.
+func NewLSPClient(languageID, serverCmd string, serverArgs []string, rootURI string, opts ...func(*LSPClient)) (*LSPClient, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	c := &LSPClient{
-		serverCmd:  serverCmd,
-		serverArgs: serverArgs,
-		rootURI:    rootURI,
-		ctx:        ctx,
-		cancel:     cancel,
+		languageID:     languageID,
+		serverCmd:      serverCmd,
+		serverArgs:     serverArgs,
+		rootURI:        rootURI,
+		ctx:            ctx,
+		cancel:         cancel,
+		faceTable:      loadFaceTable(),
+		docs:           make(map[string]*openDocument),
+		editReady:      make(chan struct{}, 1),
+		syncKind:       syncFull,
+		MethodTimeouts: make(map[string]time.Duration),
+		DefaultTimeout: defaultRequestTimeout,
+	}
+	c.syncThreshold = defaultSyncThreshold
+	c.maxRestarts = defaultMaxRestarts
+	for m, d := range defaultMethodTimeouts {
+		c.MethodTimeouts[m] = d
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 
 	return c, nil
@@ -209,15 +460,213 @@ func (c *LSPClient) Start() error {
 
 	// Initialize writer channel
 	c.writeReqs = make(chan *outboundMsg, 64)
+	c.readerDone = make(chan struct{})
 
 	// Start reader and writer goroutines
 	c.wg.Add(2)
 	go c.responseReader()
 	go c.writerActor()
 
+	// Not part of wg: Stop waits on wg, and the monitor calls Stop
+	go c.monitorServer()
+
+	return nil
+}
+
+// monitorServer waits for the server process to exit and restarts it if
+// the exit was not requested
+func (c *LSPClient) monitorServer() {
+	// Let the reader drain stdout to EOF before Wait closes the pipe
+	<-c.readerDone
+	err := c.process.Wait()
+	if c.stopping.Load() || c.ctx.Err() != nil {
+		return // Stopped on purpose
+	}
+
+	status := "exit status 0"
+	if err != nil {
+		status = err.Error()
+	}
+	logError("LSP server %s exited unexpectedly (%s)", c.serverCmd, status)
+
+	// Release goroutines and fail pending requests
+	c.Stop()
+
+	if err == nil {
+		// Clean exit without shutdown: don't fight the server
+		clientMap.CompareAndDelete(c.languageID, c)
+		return
+	}
+	c.restart(status)
+}
+
+// restart replaces a crashed client with a new server process, re-opening
+// every tracked document. Gives up after maxRestarts attempts.
+func (c *LSPClient) restart(status string) {
+	c.docsMu.Lock()
+	docs := make(map[string]openDocument, len(c.docs))
+	for uri, doc := range c.docs {
+		docs[uri] = *doc
+	}
+	c.docsMu.Unlock()
+
+	delay := restartBackoff
+	for attempt := c.restarts; attempt < c.maxRestarts; attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		if cur, ok := clientMap.Load(c.languageID); !ok || cur != c {
+			return // Replaced or stopped by the user meanwhile
+		}
+
+		c.timeoutMu.RLock()
+		opts := []func(*LSPClient){WithTimeout(defaultTimeoutKey, c.DefaultTimeout)}
+		for m, d := range c.MethodTimeouts {
+			opts = append(opts, WithTimeout(m, d))
+		}
+		c.timeoutMu.RUnlock()
+
+		nc, err := NewLSPClient(c.languageID, c.serverCmd, c.serverArgs, c.rootURI, opts...)
+		if err != nil {
+			logError("LSP restart: %v", err)
+			continue
+		}
+		nc.syncThreshold = c.syncThreshold
+		nc.maxRestarts = c.maxRestarts
+		nc.restarts = attempt + 1
+		nc.lastExit = status
+
+		if err := nc.Start(); err != nil {
+			logError("LSP restart %d/%d: %v", attempt+1, c.maxRestarts, err)
+			continue
+		}
+		if err := nc.Initialize(); err != nil {
+			nc.stopping.Store(true)
+			nc.Stop()
+			logError("LSP restart %d/%d: init failed: %v", attempt+1, c.maxRestarts, err)
+			continue
+		}
+
+		for uri, doc := range docs {
+			nc.DidOpen(uri, doc.languageID, doc.text)
+		}
+
+		if !clientMap.CompareAndSwap(c.languageID, c, nc) {
+			nc.stopping.Store(true)
+			nc.Stop()
+			return
+		}
+		logInfo("LSP server %s restarted (%d/%d), %d documents re-opened",
+			c.serverCmd, attempt+1, c.maxRestarts, len(docs))
+		return
+	}
+
+	logError("LSP server %s: giving up after %d restarts", c.serverCmd, c.maxRestarts)
+	clientMap.CompareAndDelete(c.languageID, c)
+}
+
+// lookupClient returns the running client for filename's language, or nil
+func lookupClient(filename string) *LSPClient {
+	if val, ok := clientMap.Load(detectLanguageID(filename)); ok {
+		return val.(*LSPClient)
+	}
 	return nil
 }
 
+// forEachClient calls fn for every running client
+func forEachClient(fn func(c *LSPClient)) {
+	clientMap.Range(func(key, value interface{}) bool {
+		fn(value.(*LSPClient))
+		return true
+	})
+}
+
+// getClientForBuffer returns the client for the current buffer's language,
+// starting a server (and opening the buffer) if none is running
+func getClientForBuffer() *LSPClient {
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return nil
+	}
+	if c := lookupClient(filename); c != nil {
+		return c
+	}
+
+	c, err := startClient(filename)
+	if err != nil {
+		logError("LSP start for %s: %v", filepath.Base(filename), err)
+		return nil
+	}
+	openCurrentBuffer(c, filename)
+	return c
+}
+
+// startClient starts and initializes a server for filename's language,
+// replacing any server already running for that language
+func startClient(filename string) (*LSPClient, error) {
+	serverCmd, args := detectLanguageServer(filename)
+	if serverCmd == "" {
+		return nil, fmt.Errorf("no language server for this file type")
+	}
+	if _, err := exec.LookPath(serverCmd); err != nil {
+		return nil, fmt.Errorf("%s not found", serverCmd)
+	}
+
+	languageID := detectLanguageID(filename)
+	if old := lookupClient(filename); old != nil {
+		// Forget it too, so a replacement that fails to start doesn't
+		// leave the stopped client in use
+		old.stopping.Store(true)
+		old.Stop()
+		clientMap.CompareAndDelete(old.languageID, old)
+	}
+
+	workDir := filepath.Dir(filename)
+	c, err := NewLSPClient(languageID, serverCmd, args, "file://"+workDir, timeoutOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+
+	if err := c.Initialize(); err != nil {
+		c.stopping.Store(true)
+		c.Stop()
+		return nil, fmt.Errorf("init failed: %w", err)
+	}
+
+	c.syncThreshold = configInt("sync_threshold_lines", defaultSyncThreshold)
+	c.maxRestarts = configInt("max_restarts", defaultMaxRestarts)
+	clientMap.Store(languageID, c)
+	tokenCache.SetLimit(configInt("token_cache_size", defaultTokenCacheSize))
+	return c, nil
+}
+
+// openCurrentBuffer sends didOpen for the current buffer and fetches its
+// semantic tokens in the background
+func openCurrentBuffer(c *LSPClient, filename string) {
+	bp := C.api_current_buffer()
+	content, ok := bufferText(bp)
+	if !ok {
+		return
+	}
+
+	uri := "file://" + filename
+	c.DidOpen(uri, c.languageID, content)
+	go fetchTokensAsync(unsafe.Pointer(bp), uri)
+}
+
+// stopClient shuts a server down and forgets it
+func stopClient(c *LSPClient) {
+	c.stopping.Store(true)
+	c.Request("shutdown", nil)
+	c.Notify("exit", nil)
+	c.Stop()
+	clientMap.CompareAndDelete(c.languageID, c)
+}
+
 func (c *LSPClient) Stop() {
 	c.cancel()
 
@@ -235,6 +684,7 @@ func (c *LSPClient) Stop() {
 // responseReader runs in a goroutine, reading and dispatching responses
 func (c *LSPClient) responseReader() {
 	defer c.wg.Done()
+	defer close(c.readerDone)
 
 	for {
 		select {
@@ -252,6 +702,13 @@ func (c *LSPClient) responseReader() {
 			return
 		}
 
+		// Server-initiated request (has both ID and method)
+		var req jsonRPCServerRequest
+		if err := json.Unmarshal(msg, &req); err == nil && req.Method != "" && len(req.ID) > 0 {
+			go c.handleServerRequest(&req)
+			continue
+		}
+
 		// Try to parse as response (has ID)
 		var resp jsonRPCResponse
 		if err := json.Unmarshal(msg, &resp); err == nil && resp.ID != 0 {
@@ -325,6 +782,9 @@ func (c *LSPClient) readMessage() ([]byte, error) {
 func (c *LSPClient) handleNotification(notif *jsonRPCNotification) {
 	switch notif.Method {
 	case "textDocument/publishDiagnostics":
+		if c.pullDiagnostics {
+			return // Diagnostics are pulled on save instead
+		}
 		var params struct {
 			URI         string `json:"uri"`
 			Diagnostics []struct {
@@ -370,18 +830,341 @@ func (c *LSPClient) handleNotification(notif *jsonRPCNotification) {
 			message("%s", msg)
 		}
 
+	case "$/progress":
+		c.handleProgress(notif.Params)
+
 	case "window/logMessage":
 		// Could log to debug file
 	}
 }
 
+// ProgressState is the latest state of one work done progress token
+type ProgressState struct {
+	Title      string
+	Message    string
+	Percentage int // -1 until the server reports one
+}
+
+// handleProgress tracks $/progress begin, report and end notifications,
+// showing what the server is working on in the message line
+func (c *LSPClient) handleProgress(params json.RawMessage) {
+	if text, ok := c.updateProgress(params); ok {
+		message("%s", text)
+	}
+}
+
+// updateProgress applies a $/progress notification to progressTokens and
+// returns the status to show: the work still in flight, or "Ready" once
+// the last token has ended
+func (c *LSPClient) updateProgress(params json.RawMessage) (string, bool) {
+	var p struct {
+		Token json.RawMessage `json:"token"`
+		Value struct {
+			Kind       string `json:"kind"`
+			Title      string `json:"title"`
+			Message    string `json:"message"`
+			Percentage *int   `json:"percentage"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", false
+	}
+	// Tokens are integers or strings; the raw JSON tells them apart
+	token := string(p.Token)
+
+	switch p.Value.Kind {
+	case "begin":
+		state := ProgressState{Title: p.Value.Title, Message: p.Value.Message, Percentage: -1}
+		if p.Value.Percentage != nil {
+			state.Percentage = *p.Value.Percentage
+		}
+		c.progressTokens.Store(token, state)
+		return "Indexing: " + state.Title, true
+
+	case "report":
+		v, ok := c.progressTokens.Load(token)
+		if !ok {
+			return "", false
+		}
+		state := v.(ProgressState)
+		if p.Value.Message != "" {
+			state.Message = p.Value.Message
+		}
+		if p.Value.Percentage != nil {
+			state.Percentage = *p.Value.Percentage
+		}
+		c.progressTokens.Store(token, state)
+		return "Indexing: " + state.String(), true
+
+	case "end":
+		if _, ok := c.progressTokens.LoadAndDelete(token); !ok {
+			return "", false
+		}
+		states := c.progress()
+		if len(states) == 0 {
+			return "Ready", true
+		}
+		pending := make([]string, len(states))
+		for i, st := range states {
+			pending[i] = st.String()
+		}
+		return "Indexing: " + strings.Join(pending, "; "), true
+	}
+	return "", false
+}
+
+// String formats the state as "title: message (N%)"
+func (s ProgressState) String() string {
+	text := s.Title
+	if s.Message != "" {
+		text += ": " + s.Message
+	}
+	if s.Percentage >= 0 {
+		text += fmt.Sprintf(" (%d%%)", s.Percentage)
+	}
+	return text
+}
+
+// progress lists the work done progress in flight
+func (c *LSPClient) progress() []ProgressState {
+	var states []ProgressState
+	c.progressTokens.Range(func(_, v interface{}) bool {
+		states = append(states, v.(ProgressState))
+		return true
+	})
+	sort.Slice(states, func(i, j int) bool { return states[i].Title < states[j].Title })
+	return states
+}
+
+// JSON-RPC error code for unsupported server requests
+const errMethodNotFound = -32601
+
+// handleServerRequest answers a request sent by the server.
+// workspace/applyEdit changes buffers, so it is queued for the editor
+// thread and answered once applied.
+func (c *LSPClient) handleServerRequest(req *jsonRPCServerRequest) {
+	reply := jsonRPCReply{JSONRPC: "2.0", ID: req.ID}
+
+	switch req.Method {
+	case "workspace/applyEdit":
+		c.queueEdit(req)
+		return
+	case "window/workDoneProgress/create":
+		// Progress arrives as $/progress notifications on the token
+		reply.Result = json.RawMessage("null")
+	default:
+		reply.Error = &jsonRPCError{Code: errMethodNotFound, Message: "unsupported: " + req.Method}
+	}
+
+	if err := c.sendMessage(context.Background(), reply); err != nil {
+		logError("reply to %s: %v", req.Method, err)
+	}
+}
+
+// queueEdit holds a workspace/applyEdit request until the editor thread
+// runs flushEdits: on the next key, or at once when a command is waiting
+// for the server (executeCommand)
+func (c *LSPClient) queueEdit(req *jsonRPCServerRequest) {
+	c.editMu.Lock()
+	c.editQueue = append(c.editQueue, req)
+	c.editMu.Unlock()
+	select {
+	case c.editReady <- struct{}{}:
+	default:
+	}
+}
+
+// flushEdits applies and answers the queued workspace/applyEdit requests.
+// It must run on the editor thread.
+func (c *LSPClient) flushEdits() {
+	c.editMu.Lock()
+	reqs := c.editQueue
+	c.editQueue = nil
+	c.editMu.Unlock()
+	for _, req := range reqs {
+		c.applyEditRequest(req)
+	}
+}
+
+// applyEditRequest applies a workspace/applyEdit request and replies with
+// whether every edit was made
+func (c *LSPClient) applyEditRequest(req *jsonRPCServerRequest) {
+	reply := jsonRPCReply{JSONRPC: "2.0", ID: req.ID}
+	var params struct {
+		Label string        `json:"label"`
+		Edit  WorkspaceEdit `json:"edit"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		reply.Result = map[string]interface{}{"applied": false, "failureReason": err.Error()}
+	} else {
+		total := 0
+		for _, e := range params.Edit.Changes {
+			total += len(e)
+		}
+		for _, dc := range params.Edit.DocumentChanges {
+			total += len(dc.Edits)
+		}
+
+		files, edits := applyWorkspaceEdit(c, params.Edit)
+		result := map[string]interface{}{"applied": edits == total}
+		if edits != total {
+			result["failureReason"] = fmt.Sprintf("applied %d of %d edits", edits, total)
+		}
+		reply.Result = result
+
+		label := params.Label
+		if label == "" {
+			label = "Workspace edit"
+		}
+		message("%s: %d edit(s) in %d file(s)", label, edits, files)
+	}
+
+	if err := c.sendMessage(context.Background(), reply); err != nil {
+		logError("reply to %s: %v", req.Method, err)
+	}
+}
+
+// go_lsp_flush_edits applies the workspace edits servers sent since the
+// last key. It runs from the key handler, on the editor thread, before the
+// key is processed.
+//
+//export go_lsp_flush_edits
+func go_lsp_flush_edits() {
+	forEachClient(func(c *LSPClient) {
+		c.flushEdits()
+	})
+}
+
 // Request timeout (30 seconds should be plenty for any LSP operation)
-const requestTimeout = 30 * time.Second
+// defaultRequestTimeout applies to methods without a timeout of their own
+const defaultRequestTimeout = 30 * time.Second
+
+// defaultTimeoutKey names DefaultTimeout in [lsp.timeouts], WithTimeout and
+// lsp-set-timeout
+const defaultTimeoutKey = "default"
+
+// defaultMethodTimeouts are the built-in per-method request timeouts
+var defaultMethodTimeouts = map[string]time.Duration{
+	"textDocument/hover": 2 * time.Second,
+	"workspace/symbol":   60 * time.Second,
+	"shutdown":           5 * time.Second,
+}
+
+// userTimeouts are the timeouts set with lsp-set-timeout, applied to
+// servers started later too
+var (
+	userTimeoutsMu sync.Mutex
+	userTimeouts   = make(map[string]time.Duration)
+)
+
+// WithTimeout sets the request timeout for method, or DefaultTimeout for
+// "default"
+func WithTimeout(method string, d time.Duration) func(*LSPClient) {
+	return func(c *LSPClient) {
+		c.setTimeout(method, d)
+	}
+}
+
+// setTimeout changes the request timeout for method
+func (c *LSPClient) setTimeout(method string, d time.Duration) {
+	c.timeoutMu.Lock()
+	defer c.timeoutMu.Unlock()
+	if method == defaultTimeoutKey {
+		c.DefaultTimeout = d
+	} else {
+		c.MethodTimeouts[method] = d
+	}
+}
+
+// timeout returns the request timeout for method
+func (c *LSPClient) timeout(method string) time.Duration {
+	c.timeoutMu.RLock()
+	defer c.timeoutMu.RUnlock()
+	if d, ok := c.MethodTimeouts[method]; ok {
+		return d
+	}
+	return c.DefaultTimeout
+}
+
+// effectiveTimeouts returns the timeouts new servers start with: built-in,
+// then [lsp.timeouts], then lsp-set-timeout. The default is under
+// "default".
+func effectiveTimeouts() map[string]time.Duration {
+	timeouts := map[string]time.Duration{defaultTimeoutKey: defaultRequestTimeout}
+	for m, d := range defaultMethodTimeouts {
+		timeouts[m] = d
+	}
+	for m, d := range loadTimeoutConfig() {
+		timeouts[m] = d
+	}
+	userTimeoutsMu.Lock()
+	for m, d := range userTimeouts {
+		timeouts[m] = d
+	}
+	userTimeoutsMu.Unlock()
+	return timeouts
+}
+
+// timeoutOptions returns the options that give a new client the
+// effective timeouts
+func timeoutOptions() []func(*LSPClient) {
+	var opts []func(*LSPClient)
+	for m, d := range effectiveTimeouts() {
+		opts = append(opts, WithTimeout(m, d))
+	}
+	return opts
+}
+
+// inflightRequest tracks a sent request so it can be cancelled
+type inflightRequest struct {
+	uri    string // Document the request is about ("" for workspace requests)
+	cancel context.CancelFunc
+}
+
+// requestIDKey is the context key for a func(int64) called with the ID of
+// a request as soon as it is assigned
+type requestIDKey struct{}
+
+// withRequestID returns a context that reports request IDs to fn
+func withRequestID(ctx context.Context, fn func(id int64)) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, fn)
+}
+
+// requestURI extracts params.textDocument.uri, if present
+func requestURI(params interface{}) string {
+	m, ok := params.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch td := m["textDocument"].(type) {
+	case map[string]string:
+		return td["uri"]
+	case map[string]interface{}:
+		uri, _ := td["uri"].(string)
+		return uri
+	}
+	return ""
+}
 
 // Request sends a request and waits for response with timeout
 func (c *LSPClient) Request(method string, params interface{}) (*jsonRPCResponse, error) {
+	return c.RequestContext(context.Background(), method, params)
+}
+
+// RequestContext is Request with cancellation: when ctx is done (or
+// CancelRequest is called) the server is sent $/cancelRequest and the
+// pending entry is dropped
+func (c *LSPClient) RequestContext(ctx context.Context, method string, params interface{}) (*jsonRPCResponse, error) {
 	id := c.nextID.Add(1)
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c.inflight.Store(id, &inflightRequest{uri: requestURI(params), cancel: cancel})
+	defer c.inflight.Delete(id)
+	if fn, ok := ctx.Value(requestIDKey{}).(func(int64)); ok {
+		fn(id)
+	}
+
 	req := jsonRPCRequest{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -395,32 +1178,60 @@ func (c *LSPClient) Request(method string, params interface{}) (*jsonRPCResponse
 	defer c.pending.Delete(id)
 
 	// Send request
-	if err := c.sendMessage(req); err != nil {
+	if err := c.sendMessage(ctx, req); err != nil {
 		return nil, err
 	}
 
 	// Wait for response with timeout
+	timeout := c.timeout(method)
 	select {
 	case resp := <-respCh:
 		return resp, nil
-	case <-time.After(requestTimeout):
-		return nil, fmt.Errorf("request timeout after %v", requestTimeout)
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%s: request timeout after %v", method, timeout)
 	case <-c.ctx.Done():
 		return nil, c.ctx.Err()
+	case <-ctx.Done():
+		c.Notify("$/cancelRequest", map[string]interface{}{"id": id})
+		return nil, ctx.Err()
 	}
 }
 
-// Notify sends a notification (no response expected)
-func (c *LSPClient) Notify(method string, params interface{}) error {
-	req := jsonRPCRequest{
-		JSONRPC: "2.0",
-		Method:  method,
+// CancelRequest abandons an in-flight request; the server is told via
+// $/cancelRequest. Unknown IDs are still forwarded to the server.
+func (c *LSPClient) CancelRequest(id int64) {
+	if val, ok := c.inflight.Load(id); ok {
+		val.(*inflightRequest).cancel()
+		return
+	}
+	c.Notify("$/cancelRequest", map[string]interface{}{"id": id})
+}
+
+// CancelRequests cancels every in-flight request about uri and returns
+// how many were cancelled
+func (c *LSPClient) CancelRequests(uri string) int {
+	n := 0
+	c.inflight.Range(func(key, value interface{}) bool {
+		if value.(*inflightRequest).uri == uri {
+			c.CancelRequest(key.(int64))
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Notify sends a notification (no response expected)
+func (c *LSPClient) Notify(method string, params interface{}) error {
+	req := jsonRPCRequest{
+		JSONRPC: "2.0",
+		Method:  method,
 		Params:  params,
 	}
-	return c.sendMessage(req)
+	return c.sendMessage(context.Background(), req)
 }
 
-func (c *LSPClient) sendMessage(msg interface{}) error {
+func (c *LSPClient) sendMessage(ctx context.Context, msg interface{}) error {
 	body, err := json.Marshal(msg)
 	if err != nil {
 		return err
@@ -435,6 +1246,8 @@ func (c *LSPClient) sendMessage(msg interface{}) error {
 		return <-done
 	case <-c.ctx.Done():
 		return c.ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -474,13 +1287,30 @@ func (c *LSPClient) Initialize() error {
 				"documentSymbol": map[string]interface{}{
 					"hierarchicalDocumentSymbolSupport": true,
 				},
+				"rename": map[string]interface{}{
+					"prepareSupport": true,
+				},
+				"callHierarchy":   map[string]interface{}{},
+				"selectionRange":  map[string]interface{}{},
+				"diagnostic":      map[string]interface{}{},
+				"codeLens":        map[string]interface{}{},
+				"inlayHint":       map[string]interface{}{},
+				"formatting":      map[string]interface{}{},
+				"rangeFormatting": map[string]interface{}{},
+				"signatureHelp": map[string]interface{}{
+					"signatureInformation": map[string]interface{}{
+						"parameterInformation": map[string]interface{}{
+							"labelOffsetSupport": true,
+						},
+					},
+				},
 				"publishDiagnostics": map[string]interface{}{
 					"relatedInformation": true,
 				},
 				"synchronization": map[string]interface{}{
 					"didSave":   true,
 					"willSave":  false,
-					"didChange": 1, // TextDocumentSyncKind.Full
+					"didChange": 1, // Full or incremental, per server capability
 				},
 				"semanticTokens": map[string]interface{}{
 					"requests": map[string]interface{}{
@@ -501,7 +1331,14 @@ func (c *LSPClient) Initialize() error {
 					"formats": []string{"relative"},
 				},
 			},
+			"window": map[string]interface{}{
+				"workDoneProgress": true,
+			},
 			"workspace": map[string]interface{}{
+				"applyEdit": true,
+				"workspaceEdit": map[string]interface{}{
+					"documentChanges": true,
+				},
 				"symbol": map[string]interface{}{
 					"symbolKind": map[string]interface{}{
 						"valueSet": []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26},
@@ -523,11 +1360,27 @@ func (c *LSPClient) Initialize() error {
 	// Parse capabilities
 	var result struct {
 		Capabilities struct {
-			SemanticTokensProvider interface{} `json:"semanticTokensProvider"`
+			SemanticTokensProvider interface{}     `json:"semanticTokensProvider"`
+			CodeLensProvider       interface{}     `json:"codeLensProvider"`
+			InlayHintProvider      interface{}     `json:"inlayHintProvider"`
+			DiagnosticProvider     interface{}     `json:"diagnosticProvider"`
+			TextDocumentSync       json.RawMessage `json:"textDocumentSync"`
+			CompletionProvider     *struct {
+				TriggerCharacters []string `json:"triggerCharacters"`
+			} `json:"completionProvider"`
 		} `json:"capabilities"`
 	}
 	if err := json.Unmarshal(resp.Result, &result); err == nil {
 		c.hasSemanticTokens = result.Capabilities.SemanticTokensProvider != nil
+		c.hasCodeLens = result.Capabilities.CodeLensProvider != nil
+		// inlayHintProvider is either a bool or an options object
+		c.hasInlayHints = result.Capabilities.InlayHintProvider != nil &&
+			result.Capabilities.InlayHintProvider != false
+		c.pullDiagnostics = result.Capabilities.DiagnosticProvider != nil
+		c.syncKind = parseSyncKind(result.Capabilities.TextDocumentSync)
+		if cp := result.Capabilities.CompletionProvider; cp != nil {
+			c.triggerChars = cp.TriggerCharacters
+		}
 
 		// Extract token legend if available
 		if provider, ok := result.Capabilities.SemanticTokensProvider.(map[string]interface{}); ok {
@@ -548,11 +1401,19 @@ func (c *LSPClient) Initialize() error {
 		}
 	}
 
+	c.faceMu.Lock()
+	c.tokenFaceMap = builtinTokenFaces()
+	c.faceMu.Unlock()
+
 	// Send initialized notification
 	return c.Notify("initialized", map[string]interface{}{})
 }
 
 func (c *LSPClient) DidOpen(uri, languageID, text string) error {
+	c.docsMu.Lock()
+	c.docs[uri] = &openDocument{languageID: languageID, text: text, version: 1}
+	c.docsMu.Unlock()
+
 	return c.Notify("textDocument/didOpen", map[string]interface{}{
 		"textDocument": map[string]interface{}{
 			"uri":        uri,
@@ -563,18 +1424,69 @@ func (c *LSPClient) DidOpen(uri, languageID, text string) error {
 	})
 }
 
+// DidChange sends the new document text. Servers with incremental sync
+// get only the changed line range unless it exceeds syncThreshold lines.
+// The document must have been opened with DidOpen.
 func (c *LSPClient) DidChange(uri string, version int, text string) error {
+	if c.syncKind == syncNone {
+		return nil
+	}
+
+	c.docsMu.Lock()
+	doc, ok := c.docs[uri]
+	if !ok {
+		c.docsMu.Unlock()
+		return fmt.Errorf("%s is not open", uri)
+	}
+	oldText := doc.text
+	doc.text = text
+	doc.version = version
+	c.docsMu.Unlock()
+
+	changes := []map[string]interface{}{{"text": text}}
+	if c.syncKind == syncIncremental {
+		change, changed, small := incrementalChange(oldText, text, c.syncThreshold)
+		if !changed {
+			return nil
+		}
+		if small {
+			changes = []map[string]interface{}{change}
+		}
+	}
+
 	return c.Notify("textDocument/didChange", map[string]interface{}{
 		"textDocument": map[string]interface{}{
 			"uri":     uri,
 			"version": version,
 		},
-		"contentChanges": []map[string]interface{}{
-			{"text": text},
-		},
+		"contentChanges": changes,
 	})
 }
 
+// documentVersion returns the last version sent for an open document
+func (c *LSPClient) documentVersion(uri string) (int, bool) {
+	c.docsMu.Lock()
+	defer c.docsMu.Unlock()
+	doc, ok := c.docs[uri]
+	if !ok {
+		return 0, false
+	}
+	return doc.version, true
+}
+
+// syncDocument sends text as the next version of uri if it is open
+func (c *LSPClient) syncDocument(uri, text string) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	version, ok := c.documentVersion(uri)
+	if !ok {
+		return
+	}
+	if err := c.DidChange(uri, version+1, text); err != nil {
+		logError("didChange: %v", err)
+	}
+}
+
 func (c *LSPClient) DidSave(uri string, text string) error {
 	return c.Notify("textDocument/didSave", map[string]interface{}{
 		"textDocument": map[string]string{
@@ -585,6 +1497,10 @@ func (c *LSPClient) DidSave(uri string, text string) error {
 }
 
 func (c *LSPClient) DidClose(uri string) error {
+	c.docsMu.Lock()
+	delete(c.docs, uri)
+	c.docsMu.Unlock()
+
 	return c.Notify("textDocument/didClose", map[string]interface{}{
 		"textDocument": map[string]string{
 			"uri": uri,
@@ -592,13 +1508,146 @@ func (c *LSPClient) DidClose(uri string) error {
 	})
 }
 
+// parseSyncKind reads textDocumentSync, which is either a kind number or
+// TextDocumentSyncOptions with a "change" field
+func parseSyncKind(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return syncFull
+	}
+	var kind int
+	if err := json.Unmarshal(raw, &kind); err == nil {
+		return kind
+	}
+	var opts struct {
+		Change *int `json:"change"`
+	}
+	if err := json.Unmarshal(raw, &opts); err == nil && opts.Change != nil {
+		return *opts.Change
+	}
+	return syncFull
+}
+
+// splitLines splits text after each newline, without a trailing empty line
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// utf16Len is the length of s in UTF-16 code units (LSP character offsets)
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// incrementalChange diffs oldText and newText by lines, trimming the common
+// prefix and suffix, and returns a single TextDocumentContentChangeEvent
+// replacing the lines in between. changed is false if the texts are equal;
+// small is false if more than threshold lines changed.
+func incrementalChange(oldText, newText string, threshold int) (change map[string]interface{}, changed, small bool) {
+	if oldText == newText {
+		return nil, false, true
+	}
+
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	oldEnd := len(oldLines) - suffix
+	newEnd := len(newLines) - suffix
+	if max(oldEnd-prefix, newEnd-prefix) > threshold {
+		return nil, true, false
+	}
+
+	// End of the replaced range: start of the first unchanged line, or the
+	// end of the document when the change runs to the last line
+	end := Position{Line: oldEnd}
+	if suffix == 0 && len(oldLines) > 0 && !strings.HasSuffix(oldText, "\n") {
+		last := oldLines[len(oldLines)-1]
+		end = Position{Line: len(oldLines) - 1, Character: utf16Len(last)}
+	}
+
+	return map[string]interface{}{
+		"range": Range{
+			Start: Position{Line: prefix},
+			End:   end,
+		},
+		"text": strings.Join(newLines[prefix:newEnd], ""),
+	}, true, true
+}
+
+// PullDiagnostics requests diagnostics for uri (LSP 3.17 pull model) and
+// stores them like pushed diagnostics. An "unchanged" report keeps the
+// cached list.
+func (c *LSPClient) PullDiagnostics(uri string) ([]Diagnostic, error) {
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+	}
+	if val, ok := c.diagResultIDs.Load(uri); ok {
+		params["previousResultId"] = val.(string)
+	}
+
+	resp, err := c.Request("textDocument/diagnostic", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Message)
+	}
+
+	var report struct {
+		Kind     string       `json:"kind"` // "full" or "unchanged"
+		ResultID string       `json:"resultId"`
+		Items    []Diagnostic `json:"items"`
+	}
+	if err := json.Unmarshal(resp.Result, &report); err != nil {
+		return nil, err
+	}
+	if report.ResultID != "" {
+		c.diagResultIDs.Store(uri, report.ResultID)
+	}
+
+	if report.Kind == "unchanged" {
+		if val, ok := diagnosticCache.Load(uri); ok {
+			return val.([]Diagnostic), nil
+		}
+		return nil, nil
+	}
+
+	diags := report.Items
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	diagnosticCache.Store(uri, diags)
+	emitDiagnosticsEvent(uri, diags)
+	return diags, nil
+}
+
 // FetchSemanticTokens requests semantic tokens for a file
-func (c *LSPClient) FetchSemanticTokens(uri string) ([]SemanticToken, error) {
+func (c *LSPClient) FetchSemanticTokens(ctx context.Context, uri string) ([]SemanticToken, error) {
 	if !c.hasSemanticTokens {
 		return nil, nil
 	}
 
-	resp, err := c.Request("textDocument/semanticTokens/full", map[string]interface{}{
+	resp, err := c.RequestContext(ctx, "textDocument/semanticTokens/full", map[string]interface{}{
 		"textDocument": map[string]string{"uri": uri},
 	})
 	if err != nil {
@@ -654,39 +1703,197 @@ func (c *LSPClient) FetchSemanticTokens(uri string) ([]SemanticToken, error) {
 // Semantic Token to Face Mapping
 // =============================================================================
 
-func (c *LSPClient) tokenTypeToFace(tokenType int) int {
+// defaultFaceLanguage is the face table entry used for token types that a
+// language does not override
+const defaultFaceLanguage = "default"
+
+// faceNames maps face IDs to the names shown in *lsp-faces* and saved to
+// [lsp.faces]
+var faceNames = []string{
+	FaceDefault:      "default",
+	FaceKeyword:      "keyword",
+	FaceString:       "string",
+	FaceComment:      "comment",
+	FaceNumber:       "number",
+	FaceType:         "type",
+	FaceFunction:     "function",
+	FaceOperator:     "operator",
+	FacePreprocessor: "preprocessor",
+	FaceConstant:     "constant",
+	FaceVariable:     "variable",
+	FaceAttribute:    "attribute",
+	FaceEscape:       "escape",
+	FaceRegex:        "regex",
+	FaceSpecial:      "special",
+	FaceError:        "error",
+	FaceWarning:      "warning",
+}
+
+// builtinTokenFaces returns the built-in token type to face mapping
+func builtinTokenFaces() map[string]int {
+	return map[string]int{
+		"keyword":       FaceKeyword,
+		"modifier":      FaceKeyword,
+		"string":        FaceString,
+		"comment":       FaceComment,
+		"number":        FaceNumber,
+		"type":          FaceType,
+		"class":         FaceType,
+		"struct":        FaceType,
+		"enum":          FaceType,
+		"interface":     FaceType,
+		"typeParameter": FaceType,
+		"function":      FaceFunction,
+		"method":        FaceFunction,
+		"operator":      FaceOperator,
+		"macro":         FacePreprocessor,
+		"enumMember":    FaceConstant,
+		"variable":      FaceVariable,
+		"parameter":     FaceVariable,
+		"property":      FaceVariable,
+		"namespace":     FaceAttribute,
+		"regexp":        FaceRegex,
+	}
+}
+
+// faceName returns the name of a face ID
+func faceName(face int) string {
+	if face < 0 || face >= len(faceNames) {
+		return strconv.Itoa(face)
+	}
+	return faceNames[face]
+}
+
+// parseFace accepts a face name ("constant") or numeric ID ("9")
+func parseFace(s string) (int, bool) {
+	for id, name := range faceNames {
+		if strings.EqualFold(s, name) {
+			return id, true
+		}
+	}
+	if id, err := strconv.Atoi(s); err == nil && id >= 0 && id < len(faceNames) {
+		return id, true
+	}
+	return 0, false
+}
+
+// loadFaceTable returns a copy of the [lsp.faces] tables
+func loadFaceTable() map[string]map[string]int {
+	config := loadFaceConfig()
+	table := make(map[string]map[string]int, len(config))
+	for lang, types := range config {
+		table[lang] = make(map[string]int, len(types))
+		for tokenType, face := range types {
+			table[lang][tokenType] = face
+		}
+	}
+	return table
+}
+
+// tokenTypeToFace maps a semantic token type to a face, preferring the
+// language's own entry over the default one, and both over tokenFaceMap
+func (c *LSPClient) tokenTypeToFace(languageID string, tokenType int) int {
 	if tokenType < 0 || tokenType >= len(c.tokenTypes) {
 		return FaceDefault
 	}
+	name := c.tokenTypes[tokenType]
 
-	switch c.tokenTypes[tokenType] {
-	case "keyword", "modifier":
-		return FaceKeyword
-	case "string":
-		return FaceString
-	case "comment":
-		return FaceComment
-	case "number":
-		return FaceNumber
-	case "type", "class", "struct", "enum", "interface", "typeParameter":
-		return FaceType
-	case "function", "method":
-		return FaceFunction
-	case "operator":
-		return FaceOperator
-	case "macro":
-		return FacePreprocessor
-	case "enumMember":
-		return FaceConstant
-	case "variable", "parameter", "property":
-		return FaceVariable
-	case "namespace":
-		return FaceAttribute
-	case "regexp":
-		return FaceRegex
-	default:
-		return FaceDefault
+	c.faceMu.RLock()
+	defer c.faceMu.RUnlock()
+
+	if face, ok := c.faceTable[languageID][name]; ok {
+		return face
+	}
+	if face, ok := c.faceTable[defaultFaceLanguage][name]; ok {
+		return face
+	}
+	if face, ok := c.tokenFaceMap[name]; ok {
+		return face
+	}
+	return FaceDefault
+}
+
+// setFace updates one face table entry and saves it to [lsp.faces]. A
+// negative face removes the entry so the default applies again.
+func (c *LSPClient) setFace(languageID, tokenType string, face int) error {
+	c.faceMu.Lock()
+	if face < 0 {
+		delete(c.faceTable[languageID], tokenType)
+		if len(c.faceTable[languageID]) == 0 && languageID != defaultFaceLanguage {
+			delete(c.faceTable, languageID)
+		}
+	} else {
+		if c.faceTable[languageID] == nil {
+			c.faceTable[languageID] = make(map[string]int)
+		}
+		c.faceTable[languageID][tokenType] = face
+	}
+	c.faceMu.Unlock()
+
+	lspConfigMu.Lock()
+	defer lspConfigMu.Unlock()
+	lspFaceCache = nil // Re-read by the other servers
+	return saveFaceSetting(settingsPath(), languageID, tokenType, face)
+}
+
+// renderFaceTable formats the face table for the *lsp-faces* buffer
+func (c *LSPClient) renderFaceTable() string {
+	c.faceMu.RLock()
+	defer c.faceMu.RUnlock()
+
+	var sb strings.Builder
+	sb.WriteString("LSP semantic token faces\n\n")
+	sb.WriteString(fmt.Sprintf("Saved to: %s [%s]\n", settingsPath(), lspFacesSection))
+	sb.WriteString(fmt.Sprintf("Faces:    %s\n", strings.Join(faceNames, " ")))
+	if len(c.tokenTypes) > 0 {
+		sb.WriteString(fmt.Sprintf("Server token types (%s): %s\n", c.serverCmd, strings.Join(c.tokenTypes, " ")))
+	}
+
+	langs := make([]string, 0, len(c.faceTable))
+	for lang := range c.faceTable {
+		if lang != defaultFaceLanguage {
+			langs = append(langs, lang)
+		}
 	}
+	sort.Strings(langs)
+	langs = append([]string{defaultFaceLanguage}, langs...)
+
+	for _, lang := range langs {
+		types := c.faceTable[lang]
+		if lang == defaultFaceLanguage {
+			// Show the defaults the saved entries sit on
+			merged := make(map[string]int, len(c.tokenFaceMap)+len(types))
+			for t, f := range c.tokenFaceMap {
+				merged[t] = f
+			}
+			for t, f := range types {
+				merged[t] = f
+			}
+			types = merged
+		}
+		names := make([]string, 0, len(types))
+		for t := range types {
+			names = append(names, t)
+		}
+		sort.Strings(names)
+
+		sb.WriteString(fmt.Sprintf("\n[%s]\n", lang))
+		for _, t := range names {
+			sb.WriteString(fmt.Sprintf("  %-16s %s\n", t, faceName(types[t])))
+		}
+	}
+
+	lspConfigMu.Lock()
+	errs := lspFaceErrors
+	lspConfigMu.Unlock()
+	if len(errs) > 0 {
+		sb.WriteString(fmt.Sprintf("\nIgnored [%s] entries:\n", lspFacesSection))
+		for _, e := range errs {
+			sb.WriteString("  " + e + "\n")
+		}
+	}
+
+	return sb.String()
 }
 
 // =============================================================================
@@ -694,13 +1901,7 @@ func (c *LSPClient) tokenTypeToFace(tokenType int) int {
 // =============================================================================
 
 func getOrCreateBufferTokens(bp unsafe.Pointer) *BufferTokens {
-	if val, ok := tokenCache.Load(bp); ok {
-		return val.(*BufferTokens)
-	}
-
-	bt := &BufferTokens{}
-	actual, _ := tokenCache.LoadOrStore(bp, bt)
-	return actual.(*BufferTokens)
+	return tokenCache.GetOrCreate(bp)
 }
 
 func updateBufferTokens(bp unsafe.Pointer, tokens []SemanticToken, version int) {
@@ -733,24 +1934,34 @@ func getTokensForLine(bp unsafe.Pointer, lineNum int) []SemanticToken {
 func fetchTokensAsync(bp unsafe.Pointer, uri string) {
 	bt := getOrCreateBufferTokens(bp)
 
-	// Prevent concurrent fetches for same buffer
-	if !bt.fetching.CompareAndSwap(false, true) {
-		return
-	}
-	defer bt.fetching.Store(false)
-
-	c := clientPtr.Load()
+	c := lookupClient(strings.TrimPrefix(uri, "file://"))
 	if c == nil {
 		return
 	}
 
-	tokens, err := c.FetchSemanticTokens(uri)
+	// A newer fetch supersedes (and cancels) the previous one
+	var id int64
+	ctx := withRequestID(context.Background(), func(reqID int64) {
+		id = reqID
+		if prev := bt.lastTokenFetchID.Swap(reqID); prev != 0 {
+			c.CancelRequest(prev)
+		}
+	})
+
+	tokens, err := c.FetchSemanticTokens(ctx, uri)
+	if !bt.lastTokenFetchID.CompareAndSwap(id, 0) {
+		return // Superseded
+	}
 	if err != nil {
 		logError("fetchTokens: %v", err)
 		return
 	}
 
 	if tokens != nil {
+		langID := detectLanguageID(strings.TrimPrefix(uri, "file://"))
+		for i := range tokens {
+			tokens[i].Face = c.tokenTypeToFace(langID, tokens[i].TokenType)
+		}
 		updateBufferTokens(bp, tokens, 1)
 		// Invalidate buffer to trigger redraw
 		C.api_syntax_invalidate_buffer(bp)
@@ -769,95 +1980,212 @@ func go_lsp_start(f, n C.int) C.int {
 		return 0
 	}
 
-	serverCmd, args := detectLanguageServer(filename)
-	if serverCmd == "" {
-		message("lsp-start: No language server for this file type")
+	c, err := startClient(filename)
+	if err != nil {
+		message("lsp-start: %v", err)
 		return 0
 	}
+	openCurrentBuffer(c, filename)
 
-	if _, err := exec.LookPath(serverCmd); err != nil {
-		message("lsp-start: %s not found", serverCmd)
+	if c.hasSemanticTokens {
+		message("lsp-start: %s started for %s (semantic tokens enabled)", c.serverCmd, c.languageID)
+	} else {
+		message("lsp-start: %s started for %s", c.serverCmd, c.languageID)
+	}
+	return 1
+}
+
+//export go_lsp_stop
+func go_lsp_stop(f, n C.int) C.int {
+	languageID, ok := prompt("Stop server for language (empty for all): ")
+	if !ok {
 		return 0
 	}
+	languageID = strings.TrimSpace(languageID)
 
-	// Stop existing client
-	if old := clientPtr.Load(); old != nil {
-		old.Stop()
+	if languageID != "" {
+		val, ok := clientMap.Load(languageID)
+		if !ok {
+			message("lsp-stop: No %s server running", languageID)
+			return 0
+		}
+		stopClient(val.(*LSPClient))
+		message("lsp-stop: %s server stopped", languageID)
+		return 1
 	}
 
-	// Create new client
-	workDir := filepath.Dir(filename)
-	c, err := NewLSPClient(serverCmd, args, "file://"+workDir)
-	if err != nil {
-		message("lsp-start: %v", err)
+	stopped := 0
+	forEachClient(func(c *LSPClient) {
+		stopClient(c)
+		stopped++
+	})
+	if stopped == 0 {
+		message("lsp-stop: No server running")
 		return 0
 	}
 
-	if err := c.Start(); err != nil {
-		message("lsp-start: %v", err)
+	// Clear token cache
+	tokenCache.Clear()
+
+	message("lsp-stop: %d servers stopped", stopped)
+	return 1
+}
+
+//export go_lsp_cancel
+func go_lsp_cancel(f, n C.int) C.int {
+	filename, _, _ := getCurrentBufferInfo()
+	c := lookupClient(filename)
+	if c == nil {
+		message("lsp-cancel: No server")
 		return 0
 	}
 
-	if err := c.Initialize(); err != nil {
-		c.Stop()
-		message("lsp-start: init failed: %v", err)
-		return 0
+	cancelled := c.CancelRequests("file://" + filename)
+	message("lsp-cancel: %d request(s) cancelled", cancelled)
+	return 1
+}
+
+//export go_lsp_config
+func go_lsp_config(f, n C.int) C.int {
+	// Re-read settings.toml so edits show up without restarting the editor
+	reloadLSPConfig()
+	text := renderLSPConfig()
+
+	bufName := C.CString("*lsp-config*")
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
+
+		cText := C.CString(text)
+		C.api_buffer_insert(cText, C.size_t(len(text)))
+		C.free(unsafe.Pointer(cText))
 	}
 
-	clientPtr.Store(c)
+	message("lsp-config: %d configured server(s)", len(loadLSPConfig()))
+	return 1
+}
 
-	// Open current file
-	bp := C.api_current_buffer()
-	if bp != nil {
-		var contentLen C.size_t
-		cContent := C.api_buffer_contents(bp, &contentLen)
-		if cContent != nil {
-			content := C.GoStringN(cContent, C.int(contentLen))
-			C.api_free(unsafe.Pointer(cContent))
+//export go_lsp_set_timeout
+func go_lsp_set_timeout(f, n C.int) C.int {
+	method, ok := prompt("Timeout for method (e.g. textDocument/hover, or default): ")
+	method = strings.TrimSpace(method)
+	if !ok || method == "" {
+		return 0
+	}
+	current := effectiveTimeouts()[method]
+	if current == 0 {
+		current = effectiveTimeouts()[defaultTimeoutKey]
+	}
+	input, ok := prompt(fmt.Sprintf("Timeout for %s (now %v): ", method, current))
+	input = strings.TrimSpace(input)
+	if !ok || input == "" {
+		return 0
+	}
+	d, err := parseTimeout(input)
+	if err != nil {
+		message("lsp-set-timeout: %v", err)
+		return 0
+	}
 
-			uri := "file://" + filename
-			langID := detectLanguageID(filename)
-			c.DidOpen(uri, langID, content)
+	userTimeoutsMu.Lock()
+	userTimeouts[method] = d
+	userTimeoutsMu.Unlock()
+	servers := 0
+	forEachClient(func(c *LSPClient) {
+		c.setTimeout(method, d)
+		servers++
+	})
+	message("lsp-set-timeout: %s = %v (%d running server(s))", method, d, servers)
+	return 1
+}
+
+//export go_lsp_reload_faces
+func go_lsp_reload_faces(f, n C.int) C.int {
+	// Re-read [lsp.faces]
+	reloadLSPConfig()
+	configured := 0
+	for _, types := range loadFaceConfig() {
+		configured += len(types)
+	}
+	lspConfigMu.Lock()
+	ignored := len(lspFaceErrors)
+	lspConfigMu.Unlock()
+
+	servers := 0
+	forEachClient(func(c *LSPClient) {
+		c.faceMu.Lock()
+		c.faceTable = loadFaceTable()
+		c.faceMu.Unlock()
+		servers++
+	})
 
-			// Fetch semantic tokens in background
-			go fetchTokensAsync(unsafe.Pointer(bp), uri)
+	// Re-fetch so the current buffer shows the new faces now; others
+	// pick them up on their next token update
+	if c := getClientForBuffer(); c != nil && c.hasSemanticTokens {
+		filename, _, _ := getCurrentBufferInfo()
+		if bp := C.api_current_buffer(); bp != nil && filename != "" {
+			go fetchTokensAsync(unsafe.Pointer(bp), "file://"+filename)
 		}
 	}
 
-	if c.hasSemanticTokens {
-		message("lsp-start: %s started (semantic tokens enabled)", serverCmd)
+	if ignored > 0 {
+		message("lsp-reload-faces: %d face(s) from [%s], %d ignored (see lsp-customize-faces), %d server(s)",
+			configured, lspFacesSection, ignored, servers)
 	} else {
-		message("lsp-start: %s started", serverCmd)
+		message("lsp-reload-faces: %d face(s) from [%s], %d server(s)", configured, lspFacesSection, servers)
 	}
 	return 1
 }
 
-//export go_lsp_stop
-func go_lsp_stop(f, n C.int) C.int {
-	c := clientPtr.Load()
-	if c == nil {
-		message("lsp-stop: No server running")
-		return 0
+//export go_lsp_list_servers
+func go_lsp_list_servers(f, n C.int) C.int {
+	var clients []*LSPClient
+	forEachClient(func(c *LSPClient) {
+		clients = append(clients, c)
+	})
+	if len(clients) == 0 {
+		message("lsp-list-servers: No server running")
+		return 1
 	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].languageID < clients[j].languageID
+	})
 
-	c.Request("shutdown", nil)
-	c.Notify("exit", nil)
-	c.Stop()
-	clientPtr.Store(nil)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-12s %8s %-28s %8s  %s\n", "LANGUAGE", "PID", "SERVER", "RESTARTS", "ROOT"))
+	for _, c := range clients {
+		pid := 0
+		if c.process != nil && c.process.Process != nil {
+			pid = c.process.Process.Pid
+		}
+		sb.WriteString(fmt.Sprintf("%-12s %8d %-28s %8d  %s\n",
+			c.languageID, pid, c.serverCmd, c.restarts, strings.TrimPrefix(c.rootURI, "file://")))
+	}
 
-	// Clear token cache
-	tokenCache.Range(func(key, value interface{}) bool {
-		tokenCache.Delete(key)
-		return true
-	})
+	bufName := C.CString("*lsp-servers*")
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
 
-	message("lsp-stop: Server stopped")
+		text := sb.String()
+		cText := C.CString(text)
+		C.api_buffer_insert(cText, C.size_t(len(text)))
+		C.free(unsafe.Pointer(cText))
+	}
+
+	message("%d language servers", len(clients))
 	return 1
 }
 
 //export go_lsp_hover
 func go_lsp_hover(f, n C.int) C.int {
-	c := clientPtr.Load()
+	c := getClientForBuffer()
 	if c == nil {
 		message("lsp-hover: No server (use lsp-start)")
 		return 0
@@ -904,8 +2232,15 @@ func go_lsp_hover(f, n C.int) C.int {
 
 //export go_lsp_definition
 func go_lsp_definition(f, n C.int) C.int {
-	c := clientPtr.Load()
+	c := getClientForBuffer()
 	if c == nil {
+		// Let a tags extension (go_ctags) answer instead
+		cEvent := C.CString("lsp:definition-fallback")
+		handled := C.api_emit(cEvent, nil)
+		C.free(unsafe.Pointer(cEvent))
+		if handled != 0 {
+			return 1
+		}
 		message("lsp-definition: No server")
 		return 0
 	}
@@ -941,32 +2276,121 @@ func go_lsp_definition(f, n C.int) C.int {
 	defFile := strings.TrimPrefix(loc.URI, "file://")
 	defLine := loc.Range.Start.Line + 1
 
+	back := navState.push(NavEntry{File: filename, Line: line})
 	cPath := C.CString(defFile)
 	defer C.free(unsafe.Pointer(cPath))
 	C.api_find_file_line(cPath, C.int(defLine))
-	message("%s:%d", defFile, defLine)
+	message("%s:%d [back %d]", defFile, defLine, back)
 
 	return 1
 }
 
-//export go_lsp_references
-func go_lsp_references(f, n C.int) C.int {
-	c := clientPtr.Load()
-	if c == nil {
-		message("lsp-references: No server")
-		return 0
-	}
+// NavEntry is a position lsp-back and lsp-forward return to
+type NavEntry struct {
+	File string
+	Line int
+}
 
-	filename, line, col := getCurrentBufferInfo()
-	if filename == "" {
-		return 0
-	}
+// maxNavHistory caps the back and forward stacks
+const maxNavHistory = 100
 
-	params := map[string]interface{}{
-		"textDocument": map[string]string{"uri": "file://" + filename},
-		"position":     map[string]int{"line": line - 1, "character": col},
-		"context":      map[string]bool{"includeDeclaration": true},
-	}
+// NavState is the definition navigation history: navHistory holds the
+// positions lsp-definition jumped from, most recent last, and forwardStack
+// those lsp-back left
+type NavState struct {
+	mu           sync.Mutex
+	navHistory   []NavEntry
+	forwardStack []NavEntry
+}
+
+var navState NavState
+
+// push records the position of a new jump, forgetting the forward stack,
+// and returns the number of back levels
+func (s *NavState) push(e NavEntry) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.navHistory = pushNav(s.navHistory, e)
+	s.forwardStack = nil
+	return len(s.navHistory)
+}
+
+// step pops the back stack (the forward stack if !backward), pushes cur
+// onto the other and returns the popped entry and the sizes of the back
+// and forward stacks afterwards
+func (s *NavState) step(cur NavEntry, backward bool) (e NavEntry, back, forward int, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from, to := &s.forwardStack, &s.navHistory
+	if backward {
+		from, to = to, from
+	}
+	if len(*from) == 0 {
+		return NavEntry{}, len(s.navHistory), len(s.forwardStack), false
+	}
+	e = (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = pushNav(*to, cur)
+	return e, len(s.navHistory), len(s.forwardStack), true
+}
+
+// pushNav appends e to a stack, dropping the oldest entries beyond
+// maxNavHistory
+func pushNav(stack []NavEntry, e NavEntry) []NavEntry {
+	stack = append(stack, e)
+	if len(stack) > maxNavHistory {
+		stack = append([]NavEntry(nil), stack[len(stack)-maxNavHistory:]...)
+	}
+	return stack
+}
+
+// navigate moves back or forward through the definition history
+func navigate(cmdName string, backward bool) C.int {
+	filename, line, _ := getCurrentBufferInfo()
+	e, back, forward, ok := navState.step(NavEntry{File: filename, Line: line}, backward)
+	if !ok {
+		message("%s: No more history [back %d, forward %d]", cmdName, back, forward)
+		return 0
+	}
+
+	cPath := C.CString(e.File)
+	defer C.free(unsafe.Pointer(cPath))
+	if C.api_find_file_line(cPath, C.int(e.Line)) == 0 {
+		message("%s: Cannot open %s", cmdName, e.File)
+		return 0
+	}
+	message("%s:%d [back %d, forward %d]", e.File, e.Line, back, forward)
+	return 1
+}
+
+//export go_lsp_back
+func go_lsp_back(f, n C.int) C.int {
+	return navigate("lsp-back", true)
+}
+
+//export go_lsp_forward
+func go_lsp_forward(f, n C.int) C.int {
+	return navigate("lsp-forward", false)
+}
+
+//export go_lsp_references
+func go_lsp_references(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-references: No server")
+		return 0
+	}
+
+	filename, line, col := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file://" + filename},
+		"position":     map[string]int{"line": line - 1, "character": col},
+		"context":      map[string]bool{"includeDeclaration": true},
+	}
 
 	resp, err := c.Request("textDocument/references", params)
 	if err != nil {
@@ -986,6 +2410,7 @@ func go_lsp_references(f, n C.int) C.int {
 	}
 
 	// Create results buffer
+	registerResultBuffer("*lsp-references*", "")
 	bufName := C.CString("*lsp-references*")
 	defer C.free(unsafe.Pointer(bufName))
 
@@ -1011,7 +2436,7 @@ func go_lsp_references(f, n C.int) C.int {
 
 //export go_lsp_refresh_tokens
 func go_lsp_refresh_tokens(f, n C.int) C.int {
-	c := clientPtr.Load()
+	c := getClientForBuffer()
 	if c == nil || !c.hasSemanticTokens {
 		message("lsp-refresh-tokens: No semantic tokens support")
 		return 0
@@ -1033,14 +2458,10 @@ func go_lsp_refresh_tokens(f, n C.int) C.int {
 
 //export go_lsp_did_save
 func go_lsp_did_save(f, n C.int) C.int {
-	c := clientPtr.Load()
-	if c == nil {
-		return 0 // Silent - no server running
-	}
-
 	filename, _, _ := getCurrentBufferInfo()
-	if filename == "" {
-		return 0
+	c := lookupClient(filename)
+	if c == nil || filename == "" {
+		return 0 // Silent - no server running
 	}
 
 	bp := C.api_current_buffer()
@@ -1057,26 +2478,59 @@ func go_lsp_did_save(f, n C.int) C.int {
 	C.api_free(unsafe.Pointer(cContent))
 
 	uri := "file://" + filename
+
+	// Bring the server's copy up to date before saving
+	c.syncDocument(uri, content)
+
 	if err := c.DidSave(uri, content); err != nil {
 		logError("didSave: %v", err)
 		return 0
 	}
 
-	// Refresh tokens after save
+	// Organizing imports edits the buffer just saved; it is written back,
+	// and the server told, so the file and buffer agree
+	if go_lsp_organize_imports_on_save(0, 1) != 0 {
+		if text, ok := bufferText(bp); ok {
+			content = text
+			c.syncDocument(uri, content)
+			if err := c.DidSave(uri, content); err != nil {
+				logError("didSave: %v", err)
+			}
+		}
+	}
+
+	// Refresh tokens, inlay hints and code lenses after save
 	go fetchTokensAsync(unsafe.Pointer(bp), uri)
+	if c.hasInlayHints {
+		go func() {
+			if _, err := c.fetchInlayHints(unsafe.Pointer(bp), uri, content); err != nil {
+				logError("inlayHint: %v", err)
+			}
+		}()
+	}
+	if c.hasCodeLens {
+		go func() {
+			if _, err := c.fetchCodeLenses(uri); err != nil {
+				logError("codeLens: %v", err)
+			}
+		}()
+	}
+	if c.pullDiagnostics {
+		go func() {
+			if _, err := c.PullDiagnostics(uri); err != nil {
+				logError("diagnostic: %v", err)
+			}
+		}()
+	}
 	return 1
 }
 
 //export go_lsp_did_close
 func go_lsp_did_close(f, n C.int) C.int {
-	c := clientPtr.Load()
-	if c == nil {
-		return 0 // Silent - no server running
-	}
-
 	filename, _, _ := getCurrentBufferInfo()
-	if filename == "" {
-		return 0
+	c := lookupClient(filename)
+	if c == nil || filename == "" {
+		return 0 // Silent - no server running
 	}
 
 	uri := "file://" + filename
@@ -1085,10 +2539,11 @@ func go_lsp_did_close(f, n C.int) C.int {
 		return 0
 	}
 
-	// Clear tokens for this buffer
+	// Clear tokens and hints for this buffer
 	bp := C.api_current_buffer()
 	if bp != nil {
 		tokenCache.Delete(unsafe.Pointer(bp))
+		inlayHintCache.Delete(unsafe.Pointer(bp))
 	}
 
 	return 1
@@ -1096,7 +2551,7 @@ func go_lsp_did_close(f, n C.int) C.int {
 
 //export go_lsp_completion
 func go_lsp_completion(f, n C.int) C.int {
-	c := clientPtr.Load()
+	c := getClientForBuffer()
 	if c == nil {
 		message("lsp-completion: No server")
 		return 0
@@ -1106,39 +2561,83 @@ func go_lsp_completion(f, n C.int) C.int {
 	if filename == "" {
 		return 0
 	}
+	bp := C.api_current_buffer()
+	content, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+
+	items, ok := takeAutoCompletion(bp, content, line, col)
+	if !ok {
+		uri := "file://" + filename
+		c.syncDocument(uri, content)
+		var err error
+		if items, err = fetchCompletions(context.Background(), c, uri, line, col); err != nil {
+			message("lsp-completion: %v", err)
+			return 0
+		}
+	}
+	if len(items) == 0 {
+		message("lsp-completion: No completions")
+		return 1
+	}
+	return showCompletions(items, content, line, col)
+}
+
+// completionItem is the part of an LSP CompletionItem that is used
+type completionItem struct {
+	Label      string `json:"label"`
+	Kind       int    `json:"kind"`
+	Detail     string `json:"detail"`
+	InsertText string `json:"insertText"`
+}
+
+// completionDebounce is how long go_lsp_auto_complete waits for typing to
+// pause before requesting completions
+const completionDebounce = 50 * time.Millisecond
+
+// completionMessageWidth truncates the candidates listed in the message line
+const completionMessageWidth = 120
+
+// autoCompleteTimer is the pending debounced completion, if any, and
+// autoCompletion the candidates it fetched, which lsp-completion uses
+// while the buffer and point are unchanged
+var (
+	autoCompleteMu    sync.Mutex
+	autoCompleteTimer *time.Timer
+	autoCompletion    *fetchedCompletion
+)
+
+// fetchedCompletion is a completion result and the state it was fetched in
+type fetchedCompletion struct {
+	bp        unsafe.Pointer // Buffer fetched in; only compared, never read
+	content   string
+	line, col int
+	items     []completionItem
+}
 
+// fetchCompletions requests completions at line/col; a null result gives
+// no items and no error
+func fetchCompletions(ctx context.Context, c *LSPClient, uri string, line, col int) ([]completionItem, error) {
 	params := map[string]interface{}{
-		"textDocument": map[string]string{"uri": "file://" + filename},
+		"textDocument": map[string]string{"uri": uri},
 		"position":     map[string]int{"line": line - 1, "character": col},
 	}
 
-	resp, err := c.Request("textDocument/completion", params)
+	resp, err := c.RequestContext(ctx, "textDocument/completion", params)
 	if err != nil {
-		message("lsp-completion: %v", err)
-		return 0
+		return nil, err
 	}
-
 	if resp.Result == nil || string(resp.Result) == "null" {
-		message("lsp-completion: No completions")
-		return 1
+		return nil, nil
 	}
 
 	// Parse completion items (can be CompletionList or []CompletionItem)
-	var items []struct {
-		Label      string `json:"label"`
-		Kind       int    `json:"kind"`
-		Detail     string `json:"detail"`
-		InsertText string `json:"insertText"`
-	}
+	var items []completionItem
 
 	// Try as CompletionList first
 	var list struct {
-		Items []struct {
-			Label      string `json:"label"`
-			Kind       int    `json:"kind"`
-			Detail     string `json:"detail"`
-			InsertText string `json:"insertText"`
-		} `json:"items"`
+		Items []completionItem `json:"items"`
 	}
 	if err := json.Unmarshal(resp.Result, &list); err == nil && len(list.Items) > 0 {
 		items = list.Items
@@ -1146,10 +2645,27 @@ func go_lsp_completion(f, n C.int) C.int {
 		// Try as []CompletionItem
 		json.Unmarshal(resp.Result, &items)
 	}
+	return items, nil
+}
 
-	if len(items) == 0 {
-		message("lsp-completion: No completions")
-		return 1
+// showCompletions inserts a single completion at point, after what has
+// been typed of it, and lists several in *lsp-completion*
+func showCompletions(items []completionItem, content string, line, col int) C.int {
+	if len(items) == 1 {
+		text := items[0].InsertText
+		if text == "" {
+			text = items[0].Label
+		}
+		offset := min(positionOffset(content, Position{Line: line - 1})+col, len(content))
+		if typed := completionPrefix(content, offset); strings.HasPrefix(text, typed) {
+			if rest := text[len(typed):]; rest != "" {
+				cRest := C.CString(rest)
+				C.api_buffer_insert(cRest, C.size_t(len(rest)))
+				C.free(unsafe.Pointer(cRest))
+			}
+			message("%s [%s]", items[0].Label, completionKindName(items[0].Kind))
+			return 1
+		}
 	}
 
 	// Create completion buffer
@@ -1175,6 +2691,130 @@ func go_lsp_completion(f, n C.int) C.int {
 	return 1
 }
 
+// takeAutoCompletion returns the candidates fetched while typing if the
+// buffer and point are as they were then
+func takeAutoCompletion(bp unsafe.Pointer, content string, line, col int) ([]completionItem, bool) {
+	autoCompleteMu.Lock()
+	defer autoCompleteMu.Unlock()
+	a := autoCompletion
+	autoCompletion = nil
+	if a == nil || a.bp != bp || a.line != line || a.col != col || a.content != content {
+		return nil, false
+	}
+	return a.items, true
+}
+
+// completionSummary lists completions in the message line
+func completionSummary(items []completionItem) string {
+	if len(items) == 1 {
+		return fmt.Sprintf("%s [%s] (lsp-completion inserts it)", items[0].Label, completionKindName(items[0].Kind))
+	}
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.Label
+	}
+	text := fmt.Sprintf("%d completions (lsp-completion lists them): %s", len(items), strings.Join(labels, " "))
+	return truncateRunes(text, completionMessageWidth)
+}
+
+// completionPrefix returns the identifier characters just before offset,
+// the part of a completion already typed
+func completionPrefix(content string, offset int) string {
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(content[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= size
+	}
+	return content[start:offset]
+}
+
+// go_lsp_auto_complete runs on each typed character. When it is the last
+// character of one of the server's completion trigger characters,
+// completions are fetched once typing pauses for completionDebounce and
+// listed in the message line; lsp-completion then applies them without
+// asking the server again. Disabled with auto_complete = 0.
+//
+//export go_lsp_auto_complete
+func go_lsp_auto_complete(key C.int) C.int {
+	if key < 0x20 || key >= 0x7f || configInt("auto_complete", 1) == 0 {
+		return 0
+	}
+	filename, line, col := getCurrentBufferInfo()
+	c := lookupClient(filename)
+	if c == nil || filename == "" {
+		return 0 // Silent - no server running
+	}
+
+	// Any typing makes a pending fetch stale
+	autoCompleteMu.Lock()
+	defer autoCompleteMu.Unlock()
+	if autoCompleteTimer != nil {
+		autoCompleteTimer.Stop()
+	}
+
+	ch := string(rune(key))
+	candidate := false
+	for _, t := range c.triggerChars {
+		if strings.HasSuffix(t, ch) {
+			candidate = true
+			break
+		}
+	}
+	if !candidate {
+		return 0
+	}
+
+	// The key is inserted after this hook returns: check the trigger in
+	// the text as it will be, which the timer then works from
+	bp := C.api_current_buffer()
+	content, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+	offset := min(positionOffset(content, Position{Line: line - 1})+col, len(content))
+	content, offset = typedText(content, offset, int(key))
+	if !atTrigger(c, content[:offset]) {
+		return 0
+	}
+	line, col = offsetLineCol(content, offset)
+	want := &fetchedCompletion{bp: bp, content: content, line: line, col: col}
+	autoCompleteTimer = time.AfterFunc(completionDebounce, func() {
+		autoComplete(c, "file://"+filename, want)
+	})
+	return 1
+}
+
+// autoComplete fetches completions at the point of a, the text typed in
+// a buffer, and stores them in a for lsp-completion. It runs on the timer
+// goroutine, so it works only from a and never reads or changes a buffer.
+func autoComplete(c *LSPClient, uri string, a *fetchedCompletion) {
+	c.syncDocument(uri, a.content)
+
+	items, err := fetchCompletions(context.Background(), c, uri, a.line, a.col)
+	if err != nil || len(items) == 0 {
+		return
+	}
+	a.items = items
+	autoCompleteMu.Lock()
+	autoCompletion = a
+	autoCompleteMu.Unlock()
+	message("%s", completionSummary(items))
+}
+
+// atTrigger reports whether before, the text before point, ends with one
+// of c's completion trigger characters
+func atTrigger(c *LSPClient, before string) bool {
+	for _, t := range c.triggerChars {
+		if t != "" && strings.HasSuffix(before, t) {
+			return true
+		}
+	}
+	return false
+}
+
 //export go_lsp_diagnostics
 func go_lsp_diagnostics(f, n C.int) C.int {
 	filename, _, _ := getCurrentBufferInfo()
@@ -1184,6 +2824,17 @@ func go_lsp_diagnostics(f, n C.int) C.int {
 	}
 
 	uri := "file://" + filename
+
+	// Pull fresh diagnostics from servers that don't push them
+	if c := lookupClient(filename); c != nil && c.pullDiagnostics {
+		if text, ok := bufferText(C.api_current_buffer()); ok {
+			c.syncDocument(uri, text)
+		}
+		if _, err := c.PullDiagnostics(uri); err != nil {
+			logError("diagnostic: %v", err)
+		}
+	}
+
 	val, ok := diagnosticCache.Load(uri)
 	if !ok {
 		message("lsp-diagnostics: No diagnostics")
@@ -1197,6 +2848,7 @@ func go_lsp_diagnostics(f, n C.int) C.int {
 	}
 
 	// Create diagnostics buffer
+	registerResultBuffer("*lsp-diagnostics*", filename)
 	bufName := C.CString("*lsp-diagnostics*")
 	defer C.free(unsafe.Pointer(bufName))
 
@@ -1206,18 +2858,7 @@ func go_lsp_diagnostics(f, n C.int) C.int {
 		C.api_buffer_clear(buf)
 
 		for _, d := range diags {
-			severity := "info"
-			switch d.Severity {
-			case 1:
-				severity = "error"
-			case 2:
-				severity = "warning"
-			case 3:
-				severity = "info"
-			case 4:
-				severity = "hint"
-			}
-			entry := fmt.Sprintf("%d:%d [%s] %s\n", d.Range.Start.Line+1, d.Range.Start.Character+1, severity, d.Message)
+			entry := fmt.Sprintf("%d:%d [%s] %s\n", d.Range.Start.Line+1, d.Range.Start.Character+1, severityName(d.Severity), d.Message)
 
 			cEntry := C.CString(entry)
 			C.api_buffer_insert(cEntry, C.size_t(len(entry)))
@@ -1229,9 +2870,149 @@ func go_lsp_diagnostics(f, n C.int) C.int {
 	return 1
 }
 
+//export go_lsp_pull_diagnostics
+func go_lsp_pull_diagnostics(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-pull-diagnostics: No server")
+		return 0
+	}
+
+	filename, _, _ := getCurrentBufferInfo()
+	uri := "file://" + filename
+	if text, ok := bufferText(C.api_current_buffer()); ok {
+		c.syncDocument(uri, text)
+	}
+
+	diags, err := c.PullDiagnostics(uri)
+	if err != nil {
+		message("lsp-pull-diagnostics: %v", err)
+		return 0
+	}
+
+	errors := 0
+	for _, d := range diags {
+		if d.Severity == 1 {
+			errors++
+		}
+	}
+	message("lsp-pull-diagnostics: %d diagnostics (%d errors)", len(diags), errors)
+	return 1
+}
+
+// severityName returns the display name of a DiagnosticSeverity
+func severityName(severity int) string {
+	switch severity {
+	case 1:
+		return "error"
+	case 2:
+		return "warning"
+	case 4:
+		return "hint"
+	}
+	return "info"
+}
+
+// sortedDiagnostics returns the current file's diagnostics ordered by position
+func sortedDiagnostics(filename string) []Diagnostic {
+	val, ok := diagnosticCache.Load("file://" + filename)
+	if !ok {
+		return nil
+	}
+	diags := append([]Diagnostic(nil), val.([]Diagnostic)...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Range.Start, diags[j].Range.Start
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	})
+	return diags
+}
+
+// gotoDiagnostic moves point to d and shows its message
+func gotoDiagnostic(d Diagnostic, note string) {
+	C.api_set_point(C.int(d.Range.Start.Line+1), C.int(d.Range.Start.Character))
+	message("%d:%d [%s] %s%s", d.Range.Start.Line+1, d.Range.Start.Character+1,
+		severityName(d.Severity), d.Message, note)
+}
+
+//export go_lsp_next_diagnostic
+func go_lsp_next_diagnostic(f, n C.int) C.int {
+	return stepDiagnostic("lsp-next-diagnostic", true)
+}
+
+//export go_lsp_prev_diagnostic
+func go_lsp_prev_diagnostic(f, n C.int) C.int {
+	return stepDiagnostic("lsp-prev-diagnostic", false)
+}
+
+// stepDiagnostic jumps to the first diagnostic on a line after (or before)
+// the cursor line, wrapping around at the end of the file
+func stepDiagnostic(cmdName string, forward bool) C.int {
+	filename, line, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	diags := sortedDiagnostics(filename)
+	if len(diags) == 0 {
+		message("%s: No diagnostics", cmdName)
+		return 1
+	}
+
+	cur := line - 1
+	if forward {
+		for _, d := range diags {
+			if d.Range.Start.Line > cur {
+				gotoDiagnostic(d, "")
+				return 1
+			}
+		}
+		gotoDiagnostic(diags[0], " (wrapped)")
+		return 1
+	}
+
+	for i := len(diags) - 1; i >= 0; i-- {
+		if diags[i].Range.Start.Line < cur {
+			gotoDiagnostic(diags[i], "")
+			return 1
+		}
+	}
+	gotoDiagnostic(diags[len(diags)-1], " (wrapped)")
+	return 1
+}
+
+//export go_lsp_first_error
+func go_lsp_first_error(f, n C.int) C.int {
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	diags := sortedDiagnostics(filename)
+	if len(diags) == 0 {
+		message("lsp-first-error: No diagnostics")
+		return 1
+	}
+
+	// Lowest severity value is most severe; 0 (unset) counts as error
+	best := 0
+	rank := func(d Diagnostic) int {
+		if d.Severity == 0 {
+			return 1
+		}
+		return d.Severity
+	}
+	for i, d := range diags {
+		if rank(d) < rank(diags[best]) {
+			best = i
+		}
+	}
+	gotoDiagnostic(diags[best], "")
+	return 1
+}
+
 //export go_lsp_code_action
 func go_lsp_code_action(f, n C.int) C.int {
-	c := clientPtr.Load()
+	c := getClientForBuffer()
 	if c == nil {
 		message("lsp-code-action: No server")
 		return 0
@@ -1269,30 +3050,1646 @@ func go_lsp_code_action(f, n C.int) C.int {
 		},
 	}
 
-	resp, err := c.Request("textDocument/codeAction", params)
+	resp, err := c.Request("textDocument/codeAction", params)
+	if err != nil {
+		message("lsp-code-action: %v", err)
+		return 0
+	}
+
+	if resp.Result == nil || string(resp.Result) == "null" {
+		message("lsp-code-action: No actions available")
+		return 1
+	}
+
+	var actions []struct {
+		Title string `json:"title"`
+		Kind  string `json:"kind"`
+	}
+	json.Unmarshal(resp.Result, &actions)
+
+	if len(actions) == 0 {
+		message("lsp-code-action: No actions available")
+		return 1
+	}
+
+	// Create actions buffer
+	bufName := C.CString("*lsp-actions*")
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
+
+		for i, action := range actions {
+			entry := fmt.Sprintf("%d. %s [%s]\n", i+1, action.Title, action.Kind)
+
+			cEntry := C.CString(entry)
+			C.api_buffer_insert(cEntry, C.size_t(len(entry)))
+			C.free(unsafe.Pointer(cEntry))
+		}
+	}
+
+	message("%d code actions", len(actions))
+	return 1
+}
+
+// CodeAction is a code action, or a bare Command offered in its place
+type CodeAction struct {
+	Title   string
+	Kind    string
+	Edit    *WorkspaceEdit
+	Command *LSPCommand
+}
+
+// parseCodeActions decodes a textDocument/codeAction result, turning bare
+// Commands into actions that carry them
+func parseCodeActions(data json.RawMessage) []CodeAction {
+	var raw []struct {
+		Title     string            `json:"title"`
+		Kind      string            `json:"kind"`
+		Edit      *WorkspaceEdit    `json:"edit"`
+		Command   json.RawMessage   `json:"command"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+	json.Unmarshal(data, &raw)
+
+	actions := make([]CodeAction, 0, len(raw))
+	for _, r := range raw {
+		action := CodeAction{Title: r.Title, Kind: r.Kind, Edit: r.Edit}
+		var name string
+		if json.Unmarshal(r.Command, &name) == nil {
+			action.Command = &LSPCommand{Title: r.Title, Command: name, Arguments: r.Arguments}
+		} else if len(r.Command) > 0 && string(r.Command) != "null" {
+			var cmd LSPCommand
+			if json.Unmarshal(r.Command, &cmd) == nil {
+				action.Command = &cmd
+			}
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// executeCommand sends workspace/executeCommand. Servers make a command's
+// changes with workspace/applyEdit and reply only once that is answered,
+// so the edits are applied here, on the editor thread, while it waits.
+func (c *LSPClient) executeCommand(params map[string]interface{}) (*jsonRPCResponse, error) {
+	type result struct {
+		resp *jsonRPCResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := c.Request("workspace/executeCommand", params)
+		done <- result{resp, err}
+	}()
+	for {
+		select {
+		case r := <-done:
+			c.flushEdits()
+			return r.resp, r.err
+		case <-c.editReady:
+			c.flushEdits()
+		}
+	}
+}
+
+// executeCodeAction applies an action's edit, then runs its command with
+// workspace/executeCommand; either may be missing
+func (c *LSPClient) executeCodeAction(action CodeAction) error {
+	if action.Edit == nil && action.Command == nil {
+		return fmt.Errorf("%s: nothing to do", action.Title)
+	}
+	if action.Edit != nil {
+		applyWorkspaceEdit(c, *action.Edit)
+	}
+	if action.Command == nil {
+		return nil
+	}
+
+	params := map[string]interface{}{"command": action.Command.Command}
+	if len(action.Command.Arguments) > 0 {
+		params["arguments"] = action.Command.Arguments
+	}
+	resp, err := c.executeCommand(params)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s", resp.Error.Message)
+	}
+	return nil
+}
+
+// organizeImports requests the source.organizeImports action for the
+// whole of uri and executes it. ok is false if the server offers none.
+func (c *LSPClient) organizeImports(uri, content string) (ok bool, err error) {
+	c.syncDocument(uri, content)
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range":        Range{End: endPosition(content)},
+		"context": map[string]interface{}{
+			"diagnostics": []interface{}{},
+			"only":        []string{"source.organizeImports"},
+		},
+	}
+	resp, err := c.Request("textDocument/codeAction", params)
+	if err != nil {
+		return false, err
+	}
+	if resp.Error != nil {
+		return false, fmt.Errorf("%s", resp.Error.Message)
+	}
+
+	for _, action := range parseCodeActions(resp.Result) {
+		if action.Kind == "source.organizeImports" || strings.HasPrefix(action.Kind, "source.organizeImports.") {
+			return true, c.executeCodeAction(action)
+		}
+	}
+	return false, nil
+}
+
+//export go_lsp_organize_imports
+func go_lsp_organize_imports(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-organize-imports: No server")
+		return 0
+	}
+
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+	bp := C.api_current_buffer()
+	content, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+
+	found, err := c.organizeImports("file://"+filename, content)
+	if err != nil {
+		message("lsp-organize-imports: %v", err)
+		return 0
+	}
+	if !found {
+		message("lsp-organize-imports: No action available")
+		return 0
+	}
+	if after, ok := bufferText(bp); ok && after == content {
+		message("lsp-organize-imports: Imports already organized")
+	} else {
+		message("lsp-organize-imports: Imports organized")
+	}
+	return 1
+}
+
+// go_lsp_organize_imports_on_save organizes the imports of a saved Go
+// file, unless organize_imports_on_save = 0, and writes the result back
+// to the file. It reports whether the buffer changed.
+//
+//export go_lsp_organize_imports_on_save
+func go_lsp_organize_imports_on_save(f, n C.int) C.int {
+	filename, _, _ := getCurrentBufferInfo()
+	c := lookupClient(filename)
+	if c == nil || !strings.HasSuffix(filename, ".go") || configInt("organize_imports_on_save", 1) == 0 {
+		return 0
+	}
+	bp := C.api_current_buffer()
+	content, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+
+	if _, err := c.organizeImports("file://"+filename, content); err != nil {
+		logError("organizeImports: %v", err)
+		return 0
+	}
+	after, ok := bufferText(bp)
+	if !ok || after == content {
+		return 0
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(filename, []byte(after), mode); err != nil {
+		logError("organizeImports: writing %s: %v", filename, err)
+		message("Imports organized, but writing %s failed: %v", filepath.Base(filename), err)
+		return 1
+	}
+	C.api_buffer_set_unmodified(bp)
+	return 1
+}
+
+//export go_lsp_document_symbols
+func go_lsp_document_symbols(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-document-symbols: No server")
+		return 0
+	}
+
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file://" + filename},
+	}
+
+	resp, err := c.Request("textDocument/documentSymbol", params)
+	if err != nil {
+		message("lsp-document-symbols: %v", err)
+		return 0
+	}
+
+	if resp.Result == nil || string(resp.Result) == "null" {
+		message("lsp-document-symbols: No symbols")
+		return 1
+	}
+
+	// Can be DocumentSymbol[] or SymbolInformation[]
+	var symbols []struct {
+		Name           string `json:"name"`
+		Kind           int    `json:"kind"`
+		Range          Range  `json:"range"`
+		SelectionRange Range  `json:"selectionRange"`
+		// For SymbolInformation
+		Location struct {
+			Range Range `json:"range"`
+		} `json:"location"`
+	}
+	json.Unmarshal(resp.Result, &symbols)
+
+	if len(symbols) == 0 {
+		message("lsp-document-symbols: No symbols")
+		return 1
+	}
+
+	// Create symbols buffer
+	registerResultBuffer("*lsp-symbols*", filename)
+	bufName := C.CString("*lsp-symbols*")
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
+
+		for _, sym := range symbols {
+			kind := symbolKindName(sym.Kind)
+			line := sym.Range.Start.Line + 1
+			if line == 1 && sym.Location.Range.Start.Line > 0 {
+				line = sym.Location.Range.Start.Line + 1
+			}
+			entry := fmt.Sprintf("%s:%d\t[%s]\t%s\n", filepath.Base(filename), line, kind, sym.Name)
+
+			cEntry := C.CString(entry)
+			C.api_buffer_insert(cEntry, C.size_t(len(entry)))
+			C.free(unsafe.Pointer(cEntry))
+		}
+	}
+
+	message("%d symbols", len(symbols))
+	return 1
+}
+
+//export go_lsp_workspace_symbols
+func go_lsp_workspace_symbols(f, n C.int) C.int {
+	if getClientForBuffer() == nil {
+		message("lsp-workspace-symbols: No server")
+		return 0
+	}
+
+	query, ok := prompt("Workspace symbol query: ")
+	if !ok {
+		return 0
+	}
+
+	filter, ok := prompt("Filter by kind (func/type/var/all): ")
+	if !ok {
+		return 0
+	}
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		filter = "all"
+	}
+
+	return workspaceSymbols("lsp-workspace-symbols", query, filter, symbolKindFilter(filter))
+}
+
+//export go_lsp_workspace_functions
+func go_lsp_workspace_functions(f, n C.int) C.int {
+	if getClientForBuffer() == nil {
+		message("lsp-workspace-functions: No server")
+		return 0
+	}
+
+	query, ok := prompt("Workspace function query: ")
+	if !ok {
+		return 0
+	}
+
+	return workspaceSymbols("lsp-workspace-functions", query, "func", symbolKindFilter("func"))
+}
+
+// symbolKindAliases groups LSP SymbolKind codes under short filter names
+var symbolKindAliases = map[string][]int{
+	"func": {6, 12},             // Method, Function
+	"type": {5, 10, 11, 23, 26}, // Class, Enum, Interface, Struct, TypeParam
+	"var":  {7, 8, 13, 14},      // Property, Field, Variable, Constant
+}
+
+// symbolKindFilter returns a predicate for a kind filter: "all", an alias
+// from symbolKindAliases, or any symbolKindName (case-insensitive)
+func symbolKindFilter(filter string) func(kind int) bool {
+	if filter == "all" || filter == "" {
+		return func(int) bool { return true }
+	}
+	if kinds, ok := symbolKindAliases[filter]; ok {
+		return func(kind int) bool {
+			for _, k := range kinds {
+				if k == kind {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return func(kind int) bool {
+		return strings.EqualFold(symbolKindName(kind), filter)
+	}
+}
+
+// workspaceSymbols runs workspace/symbol and lists the symbols accepted by
+// match in *lsp-workspace-symbols*, with total and filtered counts
+func workspaceSymbols(cmdName, query, filter string, match func(kind int) bool) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("%s: No server", cmdName)
+		return 0
+	}
+
+	params := map[string]interface{}{
+		"query": query,
+	}
+
+	resp, err := c.Request("workspace/symbol", params)
+	if err != nil {
+		message("%s: %v", cmdName, err)
+		return 0
+	}
+
+	if resp.Result == nil || string(resp.Result) == "null" {
+		message("%s: No symbols", cmdName)
+		return 1
+	}
+
+	var symbols []struct {
+		Name     string `json:"name"`
+		Kind     int    `json:"kind"`
+		Location struct {
+			URI   string `json:"uri"`
+			Range Range  `json:"range"`
+		} `json:"location"`
+	}
+	json.Unmarshal(resp.Result, &symbols)
+
+	if len(symbols) == 0 {
+		message("%s: No symbols", cmdName)
+		return 1
+	}
+
+	var sb strings.Builder
+	shown := 0
+	for _, sym := range symbols {
+		if !match(sym.Kind) {
+			continue
+		}
+		shown++
+		kind := symbolKindName(sym.Kind)
+		file := strings.TrimPrefix(sym.Location.URI, "file://")
+		line := sym.Location.Range.Start.Line + 1
+		sb.WriteString(fmt.Sprintf("%s:%d\t[%s]\t%s\n", file, line, kind, sym.Name))
+	}
+
+	// Create symbols buffer
+	registerResultBuffer("*lsp-workspace-symbols*", "")
+	bufName := C.CString("*lsp-workspace-symbols*")
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
+
+		header := fmt.Sprintf("Workspace symbols: query '%s', kind %s (%d of %d)\n\n", query, filter, shown, len(symbols))
+		text := header + sb.String()
+		cText := C.CString(text)
+		C.api_buffer_insert(cText, C.size_t(len(text)))
+		C.free(unsafe.Pointer(cText))
+	}
+
+	message("%d of %d workspace symbols", shown, len(symbols))
+	return 1
+}
+
+//export go_lsp_rename
+func go_lsp_rename(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-rename: No server")
+		return 0
+	}
+
+	filename, line, col := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	uri := "file://" + filename
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": line - 1, "character": col},
+	}
+
+	// Validate the symbol and find its current name; servers without
+	// prepareRename go straight to the prompt
+	oldName := ""
+	resp, err := c.Request("textDocument/prepareRename", params)
+	if err != nil {
+		message("lsp-rename: %v", err)
+		return 0
+	}
+	if resp.Error != nil && resp.Error.Code != errMethodNotFound {
+		message("lsp-rename: %s", resp.Error.Message)
+		return 0
+	}
+	if resp.Error == nil {
+		if resp.Result == nil || string(resp.Result) == "null" {
+			message("lsp-rename: Nothing to rename here")
+			return 1
+		}
+		content, _ := bufferText(C.api_current_buffer())
+		oldName = prepareRenameName(resp.Result, content, line, col)
+	}
+
+	promptText := "Rename to: "
+	if oldName != "" {
+		promptText = fmt.Sprintf("Rename '%s' to: ", oldName)
+	}
+	newName, ok := promptDefault(promptText, oldName)
+	newName = strings.TrimSpace(newName)
+	if !ok || newName == "" || newName == oldName {
+		return 0
+	}
+
+	params["newName"] = newName
+	resp, err = c.Request("textDocument/rename", params)
+	if err != nil {
+		message("lsp-rename: %v", err)
+		return 0
+	}
+	if resp.Error != nil {
+		message("lsp-rename: %s", resp.Error.Message)
+		return 0
+	}
+
+	var edit WorkspaceEdit
+	if err := json.Unmarshal(resp.Result, &edit); err != nil {
+		message("lsp-rename: bad response: %v", err)
+		return 0
+	}
+
+	files, edits := applyWorkspaceEdit(c, edit)
+	if oldName == "" {
+		oldName = "symbol"
+	}
+	message("lsp-rename: %s -> %s: %d edits in %d files", oldName, newName, edits, files)
+	return 1
+}
+
+// prepareRenameName extracts the current symbol name from a prepareRename
+// result: {range, placeholder}, a bare Range, or {defaultBehavior}, for
+// which it is the identifier at line/col of content
+func prepareRenameName(data json.RawMessage, content string, line, col int) string {
+	var result struct {
+		Placeholder     string `json:"placeholder"`
+		Range           *Range `json:"range"`
+		Start           *Position
+		End             *Position
+		DefaultBehavior bool `json:"defaultBehavior"`
+	}
+	if json.Unmarshal(data, &result) != nil {
+		return ""
+	}
+	if result.Placeholder != "" {
+		return result.Placeholder
+	}
+
+	r := result.Range
+	if r == nil && result.Start != nil && result.End != nil {
+		r = &Range{Start: *result.Start, End: *result.End}
+	}
+	if r == nil {
+		if !result.DefaultBehavior {
+			return ""
+		}
+		offset := min(positionOffset(content, Position{Line: line - 1})+col, len(content))
+		end := offset
+		for end < len(content) {
+			ch, size := utf8.DecodeRuneInString(content[end:])
+			if ch != '_' && !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+				break
+			}
+			end += size
+		}
+		return completionPrefix(content, offset) + content[offset:end]
+	}
+	if r.Start.Line != r.End.Line {
+		return ""
+	}
+
+	start, end := positionOffset(content, r.Start), positionOffset(content, r.End)
+	if end <= start {
+		return ""
+	}
+	return content[start:end]
+}
+
+// applyWorkspaceEdit applies a WorkspaceEdit to the affected buffers,
+// visiting each file and replacing its contents, then returns to the
+// original buffer. Returns the number of files and edits applied.
+func applyWorkspaceEdit(c *LSPClient, edit WorkspaceEdit) (files, edits int) {
+	byURI := make(map[string][]TextEdit)
+	for uri, e := range edit.Changes {
+		byURI[uri] = append(byURI[uri], e...)
+	}
+	for _, dc := range edit.DocumentChanges {
+		if dc.TextDocument != nil {
+			byURI[dc.TextDocument.URI] = append(byURI[dc.TextDocument.URI], dc.Edits...)
+		}
+	}
+
+	uris := make([]string, 0, len(byURI))
+	for uri := range byURI {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	origBuf := C.api_current_buffer()
+	var origLine, origCol C.int
+	C.api_get_point(&origLine, &origCol)
+
+	for _, uri := range uris {
+		path := strings.TrimPrefix(uri, "file://")
+		cPath := C.CString(path)
+		found := C.api_find_file_line(cPath, 1)
+		C.free(unsafe.Pointer(cPath))
+
+		bp := C.api_current_buffer()
+		if found == 0 || bp == nil {
+			logError("applyWorkspaceEdit: cannot open %s", path)
+			continue
+		}
+
+		newContent, ok := applyTextEdits(bp, byURI[uri])
+		if !ok {
+			continue
+		}
+
+		// Keep the server's copy in sync with the edited buffer
+		c.syncDocument(uri, newContent)
+
+		files++
+		edits += len(byURI[uri])
+	}
+
+	if origBuf != nil {
+		C.api_buffer_switch(origBuf)
+		C.api_set_point(origLine, origCol)
+	}
+	return files, edits
+}
+
+// bufferText returns the full contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	if bp == nil {
+		return "", false
+	}
+	var contentLen C.size_t
+	cContent := C.api_buffer_contents(bp, &contentLen)
+	if cContent == nil {
+		return "", false
+	}
+	content := C.GoStringN(cContent, C.int(contentLen))
+	C.api_free(unsafe.Pointer(cContent))
+	return content, true
+}
+
+// applyTextEdits splices edits into the contents of buffer bp and replaces
+// the buffer text with the result, which is returned
+func applyTextEdits(bp unsafe.Pointer, edits []TextEdit) (string, bool) {
+	content, ok := bufferText(bp)
+	if !ok {
+		return "", false
+	}
+
+	newContent := spliceTextEdits(content, edits)
+	C.api_buffer_clear(bp)
+	if len(newContent) > 0 {
+		cText := C.CString(newContent)
+		C.api_buffer_insert(cText, C.size_t(len(newContent)))
+		C.free(unsafe.Pointer(cText))
+	}
+	return newContent, true
+}
+
+// spliceTextEdits applies edits to text, last range first so earlier
+// offsets are not shifted by later replacements
+func spliceTextEdits(text string, edits []TextEdit) string {
+	sorted := append([]TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Range.Start, sorted[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line > b.Line
+		}
+		return a.Character > b.Character
+	})
+
+	for _, e := range sorted {
+		start := positionOffset(text, e.Range.Start)
+		end := positionOffset(text, e.Range.End)
+		if end < start {
+			end = start
+		}
+		text = text[:start] + e.NewText + text[end:]
+	}
+	return text
+}
+
+// positionOffset converts an LSP position (line, UTF-16 character) to a
+// byte offset in text, clamped to the line and document
+func positionOffset(text string, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		nl := strings.IndexByte(text[offset:], '\n')
+		if nl < 0 {
+			return len(text)
+		}
+		offset += nl + 1
+	}
+
+	units := 0
+	for i, r := range text[offset:] {
+		if units >= pos.Character || r == '\n' {
+			return offset + i
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(text)
+}
+
+// endPosition returns the LSP position just past the end of text
+func endPosition(text string) Position {
+	line := strings.Count(text, "\n")
+	last := text[strings.LastIndexByte(text, '\n')+1:]
+	return Position{Line: line, Character: utf16Len(last)}
+}
+
+// formattingOptions builds FormattingOptions from [extension.go_lsp]
+func formattingOptions() map[string]interface{} {
+	return map[string]interface{}{
+		"tabSize":      configInt("tab_size", 4),
+		"insertSpaces": configInt("insert_spaces", 0) != 0,
+	}
+}
+
+//export go_lsp_format
+func go_lsp_format(f, n C.int) C.int {
+	return formatBuffer("lsp-format", false)
+}
+
+//export go_lsp_format_range
+func go_lsp_format_range(f, n C.int) C.int {
+	return formatBuffer("lsp-format-range", true)
+}
+
+// formatBuffer formats the current buffer, or the region between point and
+// mark when useRegion is set (the whole buffer if no mark is available)
+func formatBuffer(cmdName string, useRegion bool) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("%s: No server", cmdName)
+		return 0
+	}
+
+	filename, line, col := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+	bp := C.api_current_buffer()
+	content, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+
+	// Server must see the current text before computing edits
+	uri := "file://" + filename
+	c.syncDocument(uri, content)
+
+	method := "textDocument/formatting"
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"options":      formattingOptions(),
+	}
+
+	if useRegion {
+		method = "textDocument/rangeFormatting"
+		start := Position{}
+		end := endPosition(content)
+
+		var markLine, markCol C.int
+		if C.api_get_mark(&markLine, &markCol) != 0 && markLine > 0 {
+			point := Position{Line: line - 1, Character: col}
+			mark := Position{Line: int(markLine) - 1, Character: int(markCol)}
+			start, end = point, mark
+			if mark.Line < point.Line || (mark.Line == point.Line && mark.Character < point.Character) {
+				start, end = mark, point
+			}
+		}
+		params["range"] = Range{Start: start, End: end}
+	}
+
+	resp, err := c.Request(method, params)
+	if err != nil {
+		message("%s: %v", cmdName, err)
+		return 0
+	}
+	if resp.Error != nil {
+		message("%s: %s", cmdName, resp.Error.Message)
+		return 0
+	}
+
+	var edits []TextEdit
+	json.Unmarshal(resp.Result, &edits)
+	if len(edits) == 0 {
+		message("%s: Already formatted", cmdName)
+		return 1
+	}
+
+	newContent, ok := applyTextEdits(bp, edits)
+	if !ok {
+		return 0
+	}
+	C.api_set_point(C.int(line), C.int(col))
+	c.syncDocument(uri, newContent)
+	go fetchTokensAsync(unsafe.Pointer(bp), uri)
+
+	message("%s: %d edits", cmdName, len(edits))
+	return 1
+}
+
+// signatureMessageWidth truncates signature help in the message line
+const signatureMessageWidth = 120
+
+// signatureScanLimit is how far back to look for the opening '(' of a call
+const signatureScanLimit = 2000
+
+// didChangeDebounce is how long go_lsp_did_change waits for typing to
+// pause before syncing the document and looking for signature help
+const didChangeDebounce = 150 * time.Millisecond
+
+// didChangeTimer is the pending debounced sync and didChangeGen numbers
+// edits so a sync overtaken by newer typing drops its result.
+// signatureCancel abandons the signature help request still waiting for
+// an answer, and lastSignatureContext is the call (uri, '(' offset,
+// argument index) last shown, so hints are only requested when it changes.
+var (
+	didChangeMu          sync.Mutex
+	didChangeTimer       *time.Timer
+	didChangeGen         int64
+	signatureCancel      context.CancelFunc
+	lastSignatureContext string
+)
+
+//export go_lsp_signature_help
+func go_lsp_signature_help(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-signature-help: No server")
+		return 0
+	}
+
+	filename, line, col := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+	uri := "file://" + filename
+	if content, ok := bufferText(C.api_current_buffer()); ok {
+		c.syncDocument(uri, content)
+	}
+
+	text, err := signatureHelp(context.Background(), c, uri, line, col)
+	if err != nil {
+		message("lsp-signature-help: %v", err)
+		return 0
+	}
+	if text == "" {
+		message("lsp-signature-help: No signature")
+		return 1
+	}
+	message("%s", text)
+	return 1
+}
+
+// go_lsp_did_change runs on each edit key, before the key is inserted. It
+// reads the buffer as it will be once key is typed and schedules
+// syncTyping with it for when typing pauses for didChangeDebounce, so the
+// key path never waits on the server and the timer never touches the
+// buffer.
+//
+//export go_lsp_did_change
+func go_lsp_did_change(key C.int) C.int {
+	filename, line, col := getCurrentBufferInfo()
+	c := lookupClient(filename)
+	if c == nil || filename == "" {
+		return 0 // Silent - no server running
+	}
+	content, ok := bufferText(C.api_current_buffer())
+	if !ok {
+		return 0
+	}
+	offset := min(positionOffset(content, Position{Line: line - 1})+col, len(content))
+	content, offset = typedText(content, offset, int(key))
+	line, col = offsetLineCol(content, offset)
+	t := typing{uri: "file://" + filename, content: content, line: line, col: col}
+	t.open, t.arg, t.inCall = enclosingCall(content, offset)
+
+	didChangeMu.Lock()
+	defer didChangeMu.Unlock()
+	if didChangeTimer != nil {
+		didChangeTimer.Stop()
+	}
+	if signatureCancel != nil {
+		signatureCancel()
+		signatureCancel = nil
+	}
+	didChangeGen++
+	gen := didChangeGen
+	didChangeTimer = time.AfterFunc(didChangeDebounce, func() {
+		syncTyping(c, t, gen)
+	})
+	return 1
+}
+
+// typing is the state of a buffer after an edit key: its text, point
+// (1-based line, byte column) and the call point is in, if any
+type typing struct {
+	uri       string
+	content   string
+	line, col int
+	open, arg int // '(' offset and argument index of the enclosing call
+	inCall    bool
+}
+
+// typedText returns content and the offset of point after key is typed
+// at offset: a printable key is inserted and backspace (DEL or ^H)
+// deletes the character before point. Other keys change nothing.
+func typedText(content string, offset, key int) (string, int) {
+	switch {
+	case key >= 0x20 && key < 0x7f:
+		return content[:offset] + string(rune(key)) + content[offset:], offset + 1
+	case key == 0x7f || key == 0x08:
+		if offset == 0 {
+			return content, 0
+		}
+		_, size := utf8.DecodeLastRuneInString(content[:offset])
+		return content[:offset-size] + content[offset:], offset - size
+	}
+	return content, offset
+}
+
+// offsetLineCol converts a byte offset in content to a 1-based line and
+// byte column, the editor's point
+func offsetLineCol(content string, offset int) (line, col int) {
+	before := content[:offset]
+	return strings.Count(before, "\n") + 1, offset - (strings.LastIndexByte(before, '\n') + 1)
+}
+
+// syncTyping sends the edited text to the server and, when point is
+// inside a call's argument list, shows the call's signature in the message
+// line once the server answers. gen is the edit that scheduled it; newer
+// edits cancel the request. It runs on the timer goroutine, so it works
+// only from t.
+func syncTyping(c *LSPClient, t typing, gen int64) {
+	c.syncDocument(t.uri, t.content)
+
+	didChangeMu.Lock()
+	if gen != didChangeGen {
+		didChangeMu.Unlock()
+		return
+	}
+	callCtx := fmt.Sprintf("%s:%d:%d", t.uri, t.open, t.arg)
+	if !t.inCall || callCtx == lastSignatureContext {
+		if !t.inCall {
+			lastSignatureContext = ""
+		}
+		didChangeMu.Unlock()
+		return
+	}
+	lastSignatureContext = callCtx
+	ctx, cancel := context.WithCancel(context.Background())
+	signatureCancel = cancel
+	didChangeMu.Unlock()
+	defer cancel()
+
+	text, err := signatureHelp(ctx, c, t.uri, t.line, t.col)
+	if err != nil || text == "" {
+		// Ask again on the next edit in this call
+		didChangeMu.Lock()
+		if lastSignatureContext == callCtx {
+			lastSignatureContext = ""
+		}
+		didChangeMu.Unlock()
+		return
+	}
+	if ctx.Err() == nil {
+		message("%s", text)
+	}
+}
+
+// enclosingCall scans backward from offset for an unclosed '(' and returns
+// its offset and the index of the argument containing offset. Strings and
+// comments are not recognized.
+func enclosingCall(content string, offset int) (open, arg int, ok bool) {
+	depth := 0
+	limit := max(0, offset-signatureScanLimit)
+	for i := offset - 1; i >= limit; i-- {
+		switch content[i] {
+		case ')', ']', '}':
+			depth++
+		case '[':
+			if depth == 0 {
+				arg = 0 // Commas so far were inside an unclosed index or literal
+			} else {
+				depth--
+			}
+		case '(':
+			if depth == 0 {
+				return i, arg, true
+			}
+			depth--
+		case '{', ';':
+			if depth == 0 {
+				return 0, 0, false
+			}
+			if content[i] == '{' {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				arg++
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// signatureHelp requests signature help at line/col and formats the
+// active signature and parameter for the message line, or "" if the
+// server has none
+func signatureHelp(ctx context.Context, c *LSPClient, uri string, line, col int) (string, error) {
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": line - 1, "character": col},
+	}
+
+	resp, err := c.RequestContext(ctx, "textDocument/signatureHelp", params)
+	if err != nil {
+		return "", err
+	}
+
+	var help struct {
+		Signatures []struct {
+			Label           string `json:"label"`
+			ActiveParameter *int   `json:"activeParameter"`
+			Parameters      []struct {
+				Label json.RawMessage `json:"label"`
+			} `json:"parameters"`
+		} `json:"signatures"`
+		ActiveSignature int `json:"activeSignature"`
+		ActiveParameter int `json:"activeParameter"`
+	}
+	if resp.Result == nil || json.Unmarshal(resp.Result, &help) != nil || len(help.Signatures) == 0 {
+		return "", nil
+	}
+
+	sigIdx := help.ActiveSignature
+	if sigIdx < 0 || sigIdx >= len(help.Signatures) {
+		sigIdx = 0
+	}
+	sig := help.Signatures[sigIdx]
+
+	paramIdx := help.ActiveParameter
+	if sig.ActiveParameter != nil {
+		paramIdx = *sig.ActiveParameter
+	}
+
+	text := sig.Label
+	if paramIdx >= 0 && paramIdx < len(sig.Parameters) {
+		if param := parameterLabel(sig.Label, sig.Parameters[paramIdx].Label); param != "" {
+			text = fmt.Sprintf("%s  [%s]", sig.Label, param)
+		}
+	}
+	if len(help.Signatures) > 1 {
+		text = fmt.Sprintf("(%d/%d) %s", sigIdx+1, len(help.Signatures), text)
+	}
+	return truncateRunes(text, signatureMessageWidth), nil
+}
+
+// truncateRunes shortens s to at most width characters, ending in "...",
+// without splitting a UTF-8 sequence
+func truncateRunes(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-3]) + "..."
+}
+
+// parameterLabel returns a ParameterInformation label, which is either a
+// string or [start, end] UTF-16 offsets into the signature label
+func parameterLabel(sigLabel string, raw json.RawMessage) string {
+	var label string
+	if json.Unmarshal(raw, &label) == nil {
+		return label
+	}
+
+	var offsets [2]int
+	if json.Unmarshal(raw, &offsets) != nil {
+		return ""
+	}
+	start := positionOffset(sigLabel, Position{Character: offsets[0]})
+	end := positionOffset(sigLabel, Position{Character: offsets[1]})
+	if end <= start {
+		return ""
+	}
+	return sigLabel[start:end]
+}
+
+// CallHierarchyItem identifies a function for call hierarchy requests
+type CallHierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           int             `json:"kind"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            string          `json:"uri"`
+	Range          Range           `json:"range"`
+	SelectionRange Range           `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+//export go_lsp_incoming_calls
+func go_lsp_incoming_calls(f, n C.int) C.int {
+	return callHierarchy("lsp-incoming-calls", true)
+}
+
+//export go_lsp_outgoing_calls
+func go_lsp_outgoing_calls(f, n C.int) C.int {
+	return callHierarchy("lsp-outgoing-calls", false)
+}
+
+// callHierarchy prepares the item at point and lists its callers
+// (incoming) or callees (outgoing) in *lsp-call-hierarchy*
+func callHierarchy(cmdName string, incoming bool) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("%s: No server", cmdName)
+		return 0
+	}
+
+	filename, line, col := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file://" + filename},
+		"position":     map[string]int{"line": line - 1, "character": col},
+	}
+
+	resp, err := c.Request("textDocument/prepareCallHierarchy", params)
+	if err != nil {
+		message("%s: %v", cmdName, err)
+		return 0
+	}
+
+	var items []CallHierarchyItem
+	json.Unmarshal(resp.Result, &items)
+	if len(items) == 0 {
+		message("%s: No call hierarchy", cmdName)
+		return 1
+	}
+	item := items[0]
+
+	method, direction := "callHierarchy/outgoingCalls", "Outgoing calls from"
+	if incoming {
+		method, direction = "callHierarchy/incomingCalls", "Incoming calls to"
+	}
+
+	resp, err = c.Request(method, map[string]interface{}{"item": item})
+	if err != nil {
+		message("%s: %v", cmdName, err)
+		return 0
+	}
+
+	// Incoming calls carry "from", outgoing calls "to"; fromRanges are the
+	// call sites (in the caller's file for incoming, in item's file for outgoing)
+	var calls []struct {
+		From       *CallHierarchyItem `json:"from"`
+		To         *CallHierarchyItem `json:"to"`
+		FromRanges []Range            `json:"fromRanges"`
+	}
+	json.Unmarshal(resp.Result, &calls)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s (%d)\n\n", direction, item.Name, len(calls)))
+	for _, call := range calls {
+		peer := call.To
+		if incoming {
+			peer = call.From
+		}
+		if peer == nil {
+			continue
+		}
+
+		// Navigate to the call site for callers, the definition for callees
+		file := strings.TrimPrefix(peer.URI, "file://")
+		target := peer.SelectionRange.Start.Line + 1
+		if incoming && len(call.FromRanges) > 0 {
+			target = call.FromRanges[0].Start.Line + 1
+		}
+		sb.WriteString(fmt.Sprintf("%s:%d — %s:%d\n",
+			peer.Name, peer.SelectionRange.Start.Line+1, file, target))
+	}
+
+	registerResultBuffer("*lsp-call-hierarchy*", "")
+	bufName := C.CString("*lsp-call-hierarchy*")
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
+
+		text := sb.String()
+		cText := C.CString(text)
+		C.api_buffer_insert(cText, C.size_t(len(text)))
+		C.free(unsafe.Pointer(cText))
+	}
+
+	message("%s %s: %d", direction, item.Name, len(calls))
+	return 1
+}
+
+// resultBuffers maps navigable result buffer names to the source file their
+// entries refer to ("" when every entry carries its own path)
+var resultBuffers sync.Map
+
+var (
+	resultPathLine = regexp.MustCompile(`(\S+):(\d+)`)
+	resultLineCol  = regexp.MustCompile(`^\s*(\d+):(\d+)`)
+)
+
+// registerResultBuffer marks a buffer as navigable with lsp-goto-result
+func registerResultBuffer(name, source string) {
+	resultBuffers.Store(name, source)
+}
+
+//export go_lsp_goto_result_line
+func go_lsp_goto_result_line(f, n C.int) C.int {
+	if !gotoResultLine() {
+		message("lsp-goto-result: No location on this line")
+		return 0
+	}
+	return 1
+}
+
+// go_lsp_result_enter handles Enter in result buffers; returns 1 if the
+// key was consumed
+//
+//export go_lsp_result_enter
+func go_lsp_result_enter() C.int {
+	if gotoResultLine() {
+		return 1
+	}
+	return 0
+}
+
+// gotoResultLine opens the location on the current line of a result buffer.
+// Entries are "path:line ..." (the last such pair wins, so "name:12 — file:40"
+// goes to file:40) or "line:col ..." relative to the buffer's source file.
+func gotoResultLine() bool {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return false
+	}
+	cName := C.api_buffer_name(bp)
+	if cName == nil {
+		return false
+	}
+	val, ok := resultBuffers.Load(C.GoString(cName))
+	if !ok {
+		return false
+	}
+	source := val.(string)
+
+	content, ok := bufferText(bp)
+	if !ok {
+		return false
+	}
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	lines := strings.Split(content, "\n")
+	if int(line) < 1 || int(line) > len(lines) {
+		return false
+	}
+	text := lines[line-1]
+
+	var path string
+	var target int
+	if m := resultLineCol.FindStringSubmatch(text); m != nil && source != "" {
+		path = source
+		target, _ = strconv.Atoi(m[1])
+	} else if ms := resultPathLine.FindAllStringSubmatch(text, -1); ms != nil {
+		m := ms[len(ms)-1]
+		path = m[1]
+		target, _ = strconv.Atoi(m[2])
+		if !filepath.IsAbs(path) {
+			if source == "" {
+				return false
+			}
+			path = filepath.Join(filepath.Dir(source), path)
+		}
+	} else {
+		return false
+	}
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	C.api_find_file_line(cPath, C.int(target))
+	return true
+}
+
+// LSPCommand is a server command, as carried by code lenses
+type LSPCommand struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CodeLens is a command attached to a source range
+type CodeLens struct {
+	Range   Range           `json:"range"`
+	Command *LSPCommand     `json:"command,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// codeLensBuffer is the companion buffer listing the current file's lenses
+const codeLensBuffer = "*lsp-code-lens*"
+
+// codeLensLines maps lines of the code lens buffer to the lens shown there;
+// codeLensFile is the source file they belong to
+var (
+	codeLensMu    sync.Mutex
+	codeLensLines map[int]CodeLens
+	codeLensFile  string
+)
+
+// fetchCodeLenses requests the lenses for uri, resolves those without a
+// command and stores the result in codeLensCache
+func (c *LSPClient) fetchCodeLenses(uri string) ([]CodeLens, error) {
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+	}
+
+	resp, err := c.Request("textDocument/codeLens", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Message)
+	}
+
+	var lenses []CodeLens
+	json.Unmarshal(resp.Result, &lenses)
+
+	resolved := lenses[:0]
+	for _, lens := range lenses {
+		if lens.Command == nil {
+			resp, err := c.Request("codeLens/resolve", lens)
+			if err != nil || resp.Error != nil {
+				continue
+			}
+			json.Unmarshal(resp.Result, &lens)
+			if lens.Command == nil {
+				continue
+			}
+		}
+		resolved = append(resolved, lens)
+	}
+
+	sort.Slice(resolved, func(i, j int) bool {
+		a, b := resolved[i].Range.Start, resolved[j].Range.Start
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	})
+	codeLensCache.Store(uri, resolved)
+	return resolved, nil
+}
+
+//export go_lsp_code_lens
+func go_lsp_code_lens(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-code-lens: No server")
+		return 0
+	}
+
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	lenses, err := c.fetchCodeLenses("file://" + filename)
+	if err != nil {
+		message("lsp-code-lens: %v", err)
+		return 0
+	}
+	if len(lenses) == 0 {
+		message("lsp-code-lens: No code lenses")
+		return 1
+	}
+
+	// Entries start on line 3, after the header and a blank line
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Code lenses for %s (%d)\n\n", filepath.Base(filename), len(lenses)))
+	lines := make(map[int]CodeLens, len(lenses))
+	for i, lens := range lenses {
+		sb.WriteString(fmt.Sprintf("%d:%d  %s  %s\n",
+			lens.Range.Start.Line+1, lens.Range.Start.Character+1,
+			lens.Command.Title, lens.Command.Command))
+		lines[i+3] = lens
+	}
+
+	codeLensMu.Lock()
+	codeLensLines = lines
+	codeLensFile = filename
+	codeLensMu.Unlock()
+
+	registerResultBuffer(codeLensBuffer, filename)
+	bufName := C.CString(codeLensBuffer)
+	defer C.free(unsafe.Pointer(bufName))
+
+	buf := C.api_buffer_create(bufName)
+	if buf != nil {
+		C.api_buffer_switch(buf)
+		C.api_buffer_clear(buf)
+
+		text := sb.String()
+		cText := C.CString(text)
+		C.api_buffer_insert(cText, C.size_t(len(text)))
+		C.free(unsafe.Pointer(cText))
+	}
+
+	message("%d code lenses", len(lenses))
+	return 1
+}
+
+//export go_lsp_refresh_code_lens
+func go_lsp_refresh_code_lens(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-refresh-code-lens: No server")
+		return 0
+	}
+
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+
+	lenses, err := c.fetchCodeLenses("file://" + filename)
+	if err != nil {
+		message("lsp-refresh-code-lens: %v", err)
+		return 0
+	}
+	message("lsp-refresh-code-lens: %d code lenses", len(lenses))
+	return 1
+}
+
+// selectedCodeLens returns the lens on the current line of the code lens
+// buffer, or the first cached lens on the current line of a source buffer,
+// along with the source file it belongs to
+func selectedCodeLens() (CodeLens, string, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return CodeLens{}, "", false
+	}
+	var line, col C.int
+	C.api_get_point(&line, &col)
+
+	if cName := C.api_buffer_name(bp); cName != nil && C.GoString(cName) == codeLensBuffer {
+		codeLensMu.Lock()
+		lens, ok := codeLensLines[int(line)]
+		filename := codeLensFile
+		codeLensMu.Unlock()
+		return lens, filename, ok
+	}
+
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return CodeLens{}, "", false
+	}
+	val, ok := codeLensCache.Load("file://" + filename)
+	if !ok {
+		return CodeLens{}, "", false
+	}
+	for _, lens := range val.([]CodeLens) {
+		if lens.Range.Start.Line == int(line)-1 {
+			return lens, filename, true
+		}
+	}
+	return CodeLens{}, "", false
+}
+
+//export go_lsp_execute_code_lens
+func go_lsp_execute_code_lens(f, n C.int) C.int {
+	lens, filename, ok := selectedCodeLens()
+	if !ok || lens.Command == nil {
+		message("lsp-execute-code-lens: No code lens on this line")
+		return 0
+	}
+
+	c := lookupClient(filename)
+	if c == nil {
+		message("lsp-execute-code-lens: No server")
+		return 0
+	}
+
+	// Title-only lenses (e.g. "5 references") have no command to run
+	if lens.Command.Command == "" {
+		message("lsp-execute-code-lens: %s", lens.Command.Title)
+		return 1
+	}
+
+	params := map[string]interface{}{
+		"command": lens.Command.Command,
+	}
+	if len(lens.Command.Arguments) > 0 {
+		params["arguments"] = lens.Command.Arguments
+	}
+
+	resp, err := c.executeCommand(params)
+	if err != nil {
+		message("lsp-execute-code-lens: %v", err)
+		return 0
+	}
+	if resp.Error != nil {
+		message("lsp-execute-code-lens: %s", resp.Error.Message)
+		return 0
+	}
+
+	message("lsp-execute-code-lens: %s", lens.Command.Title)
+	return 1
+}
+
+// InlayHint is a parameter name or inferred type shown at a position.
+// Hints are kept per buffer in inlayHintCache so go_lsp_lex_line can give
+// them a face once the editor can render virtual text.
+type InlayHint struct {
+	Position Position        `json:"position"`
+	Label    json.RawMessage `json:"label"` // string or InlayHintLabelPart[]
+	Kind     int             `json:"kind,omitempty"`
+}
+
+// InlayHintKind values
+const (
+	inlayHintType      = 1
+	inlayHintParameter = 2
+)
+
+// hintsBuffer is the companion buffer listing the current file's hints
+const hintsBuffer = "*lsp-hints*"
+
+// Text returns the hint label, joining label parts
+func (h InlayHint) Text() string {
+	var label string
+	if json.Unmarshal(h.Label, &label) == nil {
+		return label
+	}
+	var parts []struct {
+		Value string `json:"value"`
+	}
+	json.Unmarshal(h.Label, &parts)
+	var sb strings.Builder
+	for _, p := range parts {
+		sb.WriteString(p.Value)
+	}
+	return sb.String()
+}
+
+// inlayHintKindName returns a short name for an InlayHintKind
+func inlayHintKindName(kind int) string {
+	switch kind {
+	case inlayHintType:
+		return "type"
+	case inlayHintParameter:
+		return "param"
+	}
+	return "hint"
+}
+
+// fetchInlayHints requests hints for the whole document and stores them
+// for the buffer
+func (c *LSPClient) fetchInlayHints(bp unsafe.Pointer, uri, text string) ([]InlayHint, error) {
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range": Range{
+			Start: Position{Line: 0, Character: 0},
+			End:   endPosition(text),
+		},
+	}
+
+	resp, err := c.Request("textDocument/inlayHint", params)
 	if err != nil {
-		message("lsp-code-action: %v", err)
-		return 0
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Message)
 	}
 
-	if resp.Result == nil || string(resp.Result) == "null" {
-		message("lsp-code-action: No actions available")
-		return 1
+	var hints []InlayHint
+	json.Unmarshal(resp.Result, &hints)
+	sort.Slice(hints, func(i, j int) bool {
+		a, b := hints[i].Position, hints[j].Position
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	})
+	inlayHintCache.Store(bp, hints)
+	return hints, nil
+}
+
+//export go_lsp_inlay_hints
+func go_lsp_inlay_hints(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-inlay-hints: No server")
+		return 0
+	}
+	if !c.hasInlayHints {
+		message("lsp-inlay-hints: Server has no inlay hints")
+		return 0
 	}
 
-	var actions []struct {
-		Title string `json:"title"`
-		Kind  string `json:"kind"`
+	filename, _, _ := getCurrentBufferInfo()
+	if filename == "" {
+		return 0
+	}
+	bp := C.api_current_buffer()
+	text, ok := bufferText(bp)
+	if !ok {
+		return 0
 	}
-	json.Unmarshal(resp.Result, &actions)
 
-	if len(actions) == 0 {
-		message("lsp-code-action: No actions available")
+	uri := "file://" + filename
+	c.syncDocument(uri, text)
+
+	hints, err := c.fetchInlayHints(unsafe.Pointer(bp), uri, text)
+	if err != nil {
+		message("lsp-inlay-hints: %v", err)
+		return 0
+	}
+	if len(hints) == 0 {
+		message("lsp-inlay-hints: No hints")
 		return 1
 	}
 
-	// Create actions buffer
-	bufName := C.CString("*lsp-actions*")
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Inlay hints for %s (%d)\n\n", filepath.Base(filename), len(hints)))
+	for _, h := range hints {
+		sb.WriteString(fmt.Sprintf("%d:%d  %s: %s\n",
+			h.Position.Line+1, h.Position.Character+1,
+			inlayHintKindName(h.Kind), strings.TrimSpace(h.Text())))
+	}
+
+	registerResultBuffer(hintsBuffer, filename)
+	bufName := C.CString(hintsBuffer)
 	defer C.free(unsafe.Pointer(bufName))
 
 	buf := C.api_buffer_create(bufName)
@@ -1300,24 +4697,25 @@ func go_lsp_code_action(f, n C.int) C.int {
 		C.api_buffer_switch(buf)
 		C.api_buffer_clear(buf)
 
-		for i, action := range actions {
-			entry := fmt.Sprintf("%d. %s [%s]\n", i+1, action.Title, action.Kind)
-
-			cEntry := C.CString(entry)
-			C.api_buffer_insert(cEntry, C.size_t(len(entry)))
-			C.free(unsafe.Pointer(cEntry))
-		}
+		out := sb.String()
+		cOut := C.CString(out)
+		C.api_buffer_insert(cOut, C.size_t(len(out)))
+		C.free(unsafe.Pointer(cOut))
 	}
 
-	message("%d code actions", len(actions))
+	message("%d inlay hints", len(hints))
 	return 1
 }
 
-//export go_lsp_document_symbols
-func go_lsp_document_symbols(f, n C.int) C.int {
-	c := clientPtr.Load()
+//export go_lsp_refresh_hints
+func go_lsp_refresh_hints(f, n C.int) C.int {
+	c := getClientForBuffer()
 	if c == nil {
-		message("lsp-document-symbols: No server")
+		message("lsp-refresh-hints: No server")
+		return 0
+	}
+	if !c.hasInlayHints {
+		message("lsp-refresh-hints: Server has no inlay hints")
 		return 0
 	}
 
@@ -1325,129 +4723,285 @@ func go_lsp_document_symbols(f, n C.int) C.int {
 	if filename == "" {
 		return 0
 	}
-
-	params := map[string]interface{}{
-		"textDocument": map[string]string{"uri": "file://" + filename},
+	bp := C.api_current_buffer()
+	text, ok := bufferText(bp)
+	if !ok {
+		return 0
 	}
 
-	resp, err := c.Request("textDocument/documentSymbol", params)
+	uri := "file://" + filename
+	c.syncDocument(uri, text)
+
+	hints, err := c.fetchInlayHints(unsafe.Pointer(bp), uri, text)
 	if err != nil {
-		message("lsp-document-symbols: %v", err)
+		message("lsp-refresh-hints: %v", err)
 		return 0
 	}
+	message("lsp-refresh-hints: %d hints", len(hints))
+	return 1
+}
 
-	if resp.Result == nil || string(resp.Result) == "null" {
-		message("lsp-document-symbols: No symbols")
-		return 1
+// SelectionRange is one level of a selectionRange chain
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+// selectionState is the expansion chain for one buffer. level indexes
+// ranges (0 = innermost); point is where the current level put the cursor,
+// so a moved cursor starts a new chain.
+type selectionState struct {
+	level  atomic.Int32
+	ranges []Range
+	point  Position
+}
+
+// selectionStates maps buffer pointers to their *selectionState
+var selectionStates sync.Map
+
+//export go_lsp_expand_selection
+func go_lsp_expand_selection(f, n C.int) C.int {
+	return stepSelection("lsp-expand-selection", 1)
+}
+
+//export go_lsp_shrink_selection
+func go_lsp_shrink_selection(f, n C.int) C.int {
+	return stepSelection("lsp-shrink-selection", -1)
+}
+
+//export go_lsp_reset_selection
+func go_lsp_reset_selection(f, n C.int) C.int {
+	if bp := C.api_current_buffer(); bp != nil {
+		selectionStates.Delete(unsafe.Pointer(bp))
 	}
+	message("lsp-reset-selection: Selection cleared")
+	return 1
+}
 
-	// Can be DocumentSymbol[] or SymbolInformation[]
-	var symbols []struct {
-		Name           string `json:"name"`
-		Kind           int    `json:"kind"`
-		Range          Range  `json:"range"`
-		SelectionRange Range  `json:"selectionRange"`
-		// For SymbolInformation
-		Location struct {
-			Range Range `json:"range"`
-		} `json:"location"`
+// fetchSelectionRanges returns the selection chain at a position,
+// innermost first
+func fetchSelectionRanges(c *LSPClient, uri string, pos Position) ([]Range, error) {
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"positions":    []Position{pos},
 	}
-	json.Unmarshal(resp.Result, &symbols)
 
-	if len(symbols) == 0 {
-		message("lsp-document-symbols: No symbols")
-		return 1
+	resp, err := c.Request("textDocument/selectionRange", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Message)
 	}
 
-	// Create symbols buffer
-	bufName := C.CString("*lsp-symbols*")
-	defer C.free(unsafe.Pointer(bufName))
+	var results []SelectionRange
+	json.Unmarshal(resp.Result, &results)
+	if len(results) == 0 {
+		return nil, nil
+	}
 
-	buf := C.api_buffer_create(bufName)
-	if buf != nil {
-		C.api_buffer_switch(buf)
-		C.api_buffer_clear(buf)
+	var ranges []Range
+	for sr := &results[0]; sr != nil; sr = sr.Parent {
+		ranges = append(ranges, sr.Range)
+	}
+	return ranges, nil
+}
 
-		for _, sym := range symbols {
-			kind := symbolKindName(sym.Kind)
-			line := sym.Range.Start.Line + 1
-			if line == 1 && sym.Location.Range.Start.Line > 0 {
-				line = sym.Location.Range.Start.Line + 1
-			}
-			entry := fmt.Sprintf("%s:%d\t[%s]\t%s\n", filepath.Base(filename), line, kind, sym.Name)
+// stepSelection moves the selection delta levels out (or in) and selects
+// the range: mark at the end, point at the start
+func stepSelection(cmdName string, delta int32) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("%s: No server", cmdName)
+		return 0
+	}
 
-			cEntry := C.CString(entry)
-			C.api_buffer_insert(cEntry, C.size_t(len(entry)))
-			C.free(unsafe.Pointer(cEntry))
+	filename, line, col := getCurrentBufferInfo()
+	bp := C.api_current_buffer()
+	if filename == "" || bp == nil {
+		return 0
+	}
+	cur := Position{Line: line - 1, Character: col}
+
+	var st *selectionState
+	if val, ok := selectionStates.Load(unsafe.Pointer(bp)); ok && val.(*selectionState).point == cur {
+		st = val.(*selectionState)
+		level := st.level.Load() + delta
+		if level < 0 || int(level) >= len(st.ranges) {
+			message("%s: No further selection", cmdName)
+			return 1
+		}
+		st.level.Store(level)
+	} else {
+		if delta < 0 {
+			message("%s: No selection to shrink", cmdName)
+			return 1
+		}
+		uri := "file://" + filename
+		if text, ok := bufferText(bp); ok {
+			c.syncDocument(uri, text)
+		}
+		ranges, err := fetchSelectionRanges(c, uri, cur)
+		if err != nil {
+			message("%s: %v", cmdName, err)
+			return 0
+		}
+		if len(ranges) == 0 {
+			message("%s: No selection range", cmdName)
+			return 1
 		}
+		st = &selectionState{ranges: ranges}
 	}
 
-	message("%d symbols", len(symbols))
+	r := st.ranges[st.level.Load()]
+	C.api_set_point(C.int(r.End.Line+1), C.int(r.End.Character))
+	hasMark := C.api_set_mark() != 0
+	C.api_set_point(C.int(r.Start.Line+1), C.int(r.Start.Character))
+	st.point = r.Start
+	selectionStates.Store(unsafe.Pointer(bp), st)
+
+	extent := ""
+	if !hasMark {
+		extent = " (no mark support)"
+	}
+	message("%s: %d:%d-%d:%d (level %d/%d)%s", cmdName,
+		r.Start.Line+1, r.Start.Character+1, r.End.Line+1, r.End.Character+1,
+		st.level.Load()+1, len(st.ranges), extent)
 	return 1
 }
 
-//export go_lsp_workspace_symbols
-func go_lsp_workspace_symbols(f, n C.int) C.int {
-	c := clientPtr.Load()
+//export go_lsp_status
+func go_lsp_status(f, n C.int) C.int {
+	stats := tokenCacheStats()
+	cacheInfo := fmt.Sprintf("token cache %d/%d, %d evicted, %.1f%% hits",
+		stats.Size, stats.Limit, stats.Evictions, stats.HitRate*100)
+
+	filename, _, _ := getCurrentBufferInfo()
+	c := lookupClient(filename)
 	if c == nil {
-		message("lsp-workspace-symbols: No server")
-		return 0
+		running := 0
+		forEachClient(func(*LSPClient) { running++ })
+		message("lsp-status: No server for this buffer (%d running) | %s", running, cacheInfo)
+		return 1
 	}
 
-	// For now, search with empty query to get all symbols
-	// Could prompt for query in future
-	params := map[string]interface{}{
-		"query": "",
+	diagFiles := 0
+	diagnosticCache.Range(func(key, value interface{}) bool {
+		if len(value.([]Diagnostic)) > 0 {
+			diagFiles++
+		}
+		return true
+	})
+
+	tokens := "no semantic tokens"
+	if c.hasSemanticTokens {
+		tokens = "semantic tokens"
 	}
 
-	resp, err := c.Request("workspace/symbol", params)
-	if err != nil {
-		message("lsp-workspace-symbols: %v", err)
-		return 0
+	busy := ""
+	if states := c.progress(); len(states) > 0 {
+		busy = fmt.Sprintf(" | busy: %s", states[0])
+		if len(states) > 1 {
+			busy += fmt.Sprintf(" (+%d more)", len(states)-1)
+		}
 	}
 
-	if resp.Result == nil || string(resp.Result) == "null" {
-		message("lsp-workspace-symbols: No symbols")
+	message("lsp-status: %s (%s) | %d files with diagnostics | %s%s",
+		c.serverCmd, tokens, diagFiles, cacheInfo, busy)
+	return 1
+}
+
+//export go_lsp_server_status
+func go_lsp_server_status(f, n C.int) C.int {
+	filename, _, _ := getCurrentBufferInfo()
+	c := lookupClient(filename)
+	if c == nil {
+		message("lsp-server-status: No server for this buffer")
 		return 1
 	}
 
-	var symbols []struct {
-		Name     string `json:"name"`
-		Kind     int    `json:"kind"`
-		Location struct {
-			URI   string `json:"uri"`
-			Range Range  `json:"range"`
-		} `json:"location"`
+	pid := 0
+	if c.process != nil && c.process.Process != nil {
+		pid = c.process.Process.Pid
 	}
-	json.Unmarshal(resp.Result, &symbols)
 
-	if len(symbols) == 0 {
-		message("lsp-workspace-symbols: No symbols")
-		return 1
+	status := fmt.Sprintf("%s (%s) pid %d | %d/%d restarts", c.serverCmd, c.languageID, pid, c.restarts, c.maxRestarts)
+	if c.lastExit != "" {
+		status += fmt.Sprintf(" (last exit: %s)", c.lastExit)
 	}
+	message("lsp-server-status: %s", status)
+	return 1
+}
 
-	// Create symbols buffer
-	bufName := C.CString("*lsp-workspace-symbols*")
+//export go_lsp_customize_faces
+func go_lsp_customize_faces(f, n C.int) C.int {
+	c := getClientForBuffer()
+	if c == nil {
+		message("lsp-customize-faces: No server running")
+		return 0
+	}
+
+	bufName := C.CString("*lsp-faces*")
 	defer C.free(unsafe.Pointer(bufName))
 
 	buf := C.api_buffer_create(bufName)
-	if buf != nil {
-		C.api_buffer_switch(buf)
+	if buf == nil {
+		return 0
+	}
+	C.api_buffer_switch(buf)
+
+	show := func() {
 		C.api_buffer_clear(buf)
+		text := c.renderFaceTable()
+		cText := C.CString(text)
+		C.api_buffer_insert(cText, C.size_t(len(text)))
+		C.free(unsafe.Pointer(cText))
+	}
+	show()
 
-		for _, sym := range symbols {
-			kind := symbolKindName(sym.Kind)
-			file := strings.TrimPrefix(sym.Location.URI, "file://")
-			line := sym.Location.Range.Start.Line + 1
-			entry := fmt.Sprintf("%s:%d\t[%s]\t%s\n", file, line, kind, sym.Name)
+	changes := 0
+	for {
+		input, ok := prompt("Set face (language token-type face|-), empty to finish: ")
+		input = strings.TrimSpace(input)
+		if !ok || input == "" {
+			break
+		}
 
-			cEntry := C.CString(entry)
-			C.api_buffer_insert(cEntry, C.size_t(len(entry)))
-			C.free(unsafe.Pointer(cEntry))
+		fields := strings.Fields(input)
+		if len(fields) != 3 {
+			message("lsp-customize-faces: expected 'language token-type face', e.g. 'go variable constant'")
+			continue
+		}
+
+		face := -1
+		if fields[2] != "-" {
+			var valid bool
+			if face, valid = parseFace(fields[2]); !valid {
+				message("lsp-customize-faces: unknown face '%s'", fields[2])
+				continue
+			}
+		}
+
+		if err := c.setFace(fields[0], fields[1], face); err != nil {
+			message("lsp-customize-faces: save failed: %v", err)
+			return 0
 		}
+		changes++
+
+		// Other servers pick up the saved table
+		forEachClient(func(o *LSPClient) {
+			if o != c {
+				o.faceMu.Lock()
+				o.faceTable = loadFaceTable()
+				o.faceMu.Unlock()
+			}
+		})
+		show()
 	}
 
-	message("%d workspace symbols", len(symbols))
+	if changes > 0 {
+		message("lsp-customize-faces: %d change(s) saved; lsp-refresh-tokens to apply", changes)
+	}
 	return 1
 }
 
@@ -1465,23 +5019,103 @@ func go_lsp_lex_line(
 		return
 	}
 
-	c := clientPtr.Load()
-	if c == nil {
+	cFilename := C.api_buffer_filename(buffer)
+	if cFilename == nil {
+		return
+	}
+	filename := C.GoString(cFilename)
+	if lookupClient(filename) == nil {
 		return
 	}
 
-	// Get tokens for this line
+	// Get tokens and diagnostic marks for this line
 	tokens := getTokensForLine(buffer, int(lineNum))
-	if len(tokens) == 0 {
+	marks := diagnosticSpans("file://"+filename, int(lineNum), int(lineLen))
+	if len(tokens) == 0 && len(marks) == 0 {
+		return
+	}
+
+	// Emit tokens (faces were resolved when the tokens were fetched)
+	if len(marks) == 0 {
+		for _, tok := range tokens {
+			endCol := tok.StartChar + tok.Length
+			C.api_syntax_add_token(outTokens, C.int(endCol), C.int(tok.Face))
+		}
 		return
 	}
 
-	// Emit tokens
+	// Overlay diagnostics on the semantic faces. Tokens only carry an end
+	// column, so every run is emitted, including unstyled gaps.
+	width := int(lineLen)
+	for _, m := range marks {
+		width = max(width, m.end)
+	}
+	faces := make([]int, width)
+	for _, tok := range tokens {
+		for col := tok.StartChar; col < tok.StartChar+tok.Length && col < width; col++ {
+			faces[col] = tok.Face
+		}
+	}
+	for _, m := range marks {
+		for col := m.start; col < m.end; col++ {
+			faces[col] = m.face
+		}
+	}
+
+	last := 0
 	for _, tok := range tokens {
-		face := c.tokenTypeToFace(tok.TokenType)
-		endCol := tok.StartChar + tok.Length
-		C.api_syntax_add_token(outTokens, C.int(endCol), C.int(face))
+		last = max(last, min(tok.StartChar+tok.Length, width))
+	}
+	for _, m := range marks {
+		last = max(last, m.end)
+	}
+	for col := 1; col <= last; col++ {
+		if col == last || faces[col] != faces[col-1] {
+			C.api_syntax_add_token(outTokens, C.int(col), C.int(faces[col-1]))
+		}
+	}
+}
+
+// faceSpan is a column range [start, end) drawn with one face
+type faceSpan struct {
+	start, end int
+	face       int
+}
+
+// diagnosticSpans returns error and warning marks for one line of uri.
+// Errors are returned last so they win where ranges overlap.
+func diagnosticSpans(uri string, line, lineLen int) []faceSpan {
+	val, ok := diagnosticCache.Load(uri)
+	if !ok {
+		return nil
+	}
+
+	var warnings, errs []faceSpan
+	for _, d := range val.([]Diagnostic) {
+		if line < d.Range.Start.Line || line > d.Range.End.Line {
+			continue
+		}
+
+		start, end := 0, lineLen
+		if line == d.Range.Start.Line {
+			start = d.Range.Start.Character
+		}
+		if line == d.Range.End.Line {
+			end = d.Range.End.Character
+		}
+		// Zero-width ranges (e.g. "expected ';'") still get one column
+		if end <= start {
+			end = start + 1
+		}
+
+		switch d.Severity {
+		case 1:
+			errs = append(errs, faceSpan{start, end, FaceError})
+		case 2:
+			warnings = append(warnings, faceSpan{start, end, FaceWarning})
+		}
 	}
+	return append(warnings, errs...)
 }
 
 // =============================================================================
@@ -1503,6 +5137,24 @@ type Location struct {
 	Range Range  `json:"range"`
 }
 
+// TextEdit replaces a range of a document with new text
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit holds edits to several documents, either as "changes"
+// (URI -> edits) or "documentChanges" (versioned; file operations ignored)
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes"`
+	DocumentChanges []struct {
+		TextDocument *struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		Edits []TextEdit `json:"edits"`
+	} `json:"documentChanges"`
+}
+
 func getCurrentBufferInfo() (filename string, line, col int) {
 	buf := C.api_current_buffer()
 	if buf == nil {
@@ -1522,7 +5174,16 @@ func getCurrentBufferInfo() (filename string, line, col int) {
 	return
 }
 
+// detectLanguageServer returns the server command for filename, preferring
+// [lsp.servers] from settings.toml over the built-in table
 func detectLanguageServer(filename string) (string, []string) {
+	if cfg, ok := configuredServer(filename); ok {
+		return cfg.Command, cfg.Args
+	}
+	return builtinDetectLanguageServer(filename)
+}
+
+func builtinDetectLanguageServer(filename string) (string, []string) {
 	ext := filepath.Ext(filename)
 	switch ext {
 	case ".py":
@@ -1541,7 +5202,16 @@ func detectLanguageServer(filename string) (string, []string) {
 	return "", nil
 }
 
+// detectLanguageID returns the LSP language ID for filename, preferring
+// [lsp.servers] from settings.toml over the built-in table
 func detectLanguageID(filename string) string {
+	if cfg, ok := configuredServer(filename); ok {
+		return cfg.LanguageID
+	}
+	return builtinDetectLanguageID(filename)
+}
+
+func builtinDetectLanguageID(filename string) string {
 	ext := filepath.Ext(filename)
 	switch ext {
 	case ".py":
@@ -1725,6 +5395,33 @@ func logError(format string, args ...interface{}) {
 	C.api_log_error(msg)
 }
 
+// prompt asks the user for a line of input; ok is false if cancelled
+func prompt(text string) (string, bool) {
+	return promptDefault(text, "")
+}
+
+// promptDefault is prompt with def as the initial input, for editors that
+// start the prompt from the buffer's contents
+func promptDefault(text, def string) (string, bool) {
+	var buf [256]C.char
+	for i := 0; i < len(def) && i < len(buf)-1; i++ {
+		buf[i] = C.char(def[i])
+	}
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// configInt reads an integer from [extension.go_lsp] in settings.toml
+func configInt(key string, defaultVal int) int {
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	return int(C.api_config_int(ckey, C.int(defaultVal)))
+}
+
 // emitDiagnosticsEvent sends lsp:diagnostics event to the linter
 func emitDiagnosticsEvent(uri string, diags []Diagnostic) {
 	if len(diags) == 0 {
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncrementalChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldText   string
+		newText   string
+		threshold int
+		change    map[string]interface{}
+		changed   bool
+		small     bool
+	}{
+		{"equal", "a\nb\n", "a\nb\n", 10, nil, false, true},
+		{"one line", "a\nb\nc\n", "a\nB\nc\n", 10, map[string]interface{}{
+			"range": Range{Start: Position{Line: 1}, End: Position{Line: 2}},
+			"text":  "B\n",
+		}, true, true},
+		{"appended line", "a\n", "a\nb\n", 10, map[string]interface{}{
+			"range": Range{Start: Position{Line: 1}, End: Position{Line: 1}},
+			"text":  "b\n",
+		}, true, true},
+		{"no trailing newline", "a\nbc", "a\nbd", 10, map[string]interface{}{
+			"range": Range{Start: Position{Line: 1}, End: Position{Line: 1, Character: 2}},
+			"text":  "bd",
+		}, true, true},
+		{"astral last line", "x😀", "y", 10, map[string]interface{}{
+			"range": Range{Start: Position{}, End: Position{Line: 0, Character: 3}},
+			"text":  "y",
+		}, true, true},
+		{"at threshold", "a\nb\nc\nd\ne\n", "A\nB\nC\nd\ne\n", 3, map[string]interface{}{
+			"range": Range{Start: Position{}, End: Position{Line: 3}},
+			"text":  "A\nB\nC\n",
+		}, true, true},
+		{"over threshold", "a\nb\nc\nd\ne\n", "A\nB\nC\nD\ne\n", 3, nil, true, false},
+	}
+	for _, tt := range tests {
+		change, changed, small := incrementalChange(tt.oldText, tt.newText, tt.threshold)
+		if !reflect.DeepEqual(change, tt.change) || changed != tt.changed || small != tt.small {
+			t.Errorf("%s: got %v, %v, %v; want %v, %v, %v", tt.name,
+				change, changed, small, tt.change, tt.changed, tt.small)
+		}
+	}
+}
+
+func TestPositionOffset(t *testing.T) {
+	text := "a😀b\nxy"
+	tests := []struct {
+		pos  Position
+		want int
+	}{
+		{Position{0, 0}, 0},
+		{Position{0, 1}, 1},
+		{Position{0, 2}, 5}, // Inside the surrogate pair
+		{Position{0, 3}, 5},
+		{Position{0, 10}, 6}, // Clamped to the line
+		{Position{1, 1}, 8},
+		{Position{1, 5}, 9},
+		{Position{5, 0}, 9}, // Clamped to the document
+	}
+	for _, tt := range tests {
+		if got := positionOffset(text, tt.pos); got != tt.want {
+			t.Errorf("positionOffset(%v) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestSpliceTextEdits(t *testing.T) {
+	edit := func(sl, sc, el, ec int, text string) TextEdit {
+		return TextEdit{Range: Range{Start: Position{sl, sc}, End: Position{el, ec}}, NewText: text}
+	}
+	tests := []struct {
+		text  string
+		edits []TextEdit
+		want  string
+	}{
+		{"a😀b\nxy", []TextEdit{edit(0, 0, 0, 1, "A"), edit(0, 3, 0, 4, "B")}, "A😀B\nxy"},
+		{"a😀b\nxy", []TextEdit{edit(1, 2, 1, 2, "z"), edit(0, 1, 0, 3, "")}, "ab\nxyz"},
+		{"one\ntwo\n", []TextEdit{edit(0, 3, 1, 3, "")}, "one\n"},
+		{"abc", []TextEdit{edit(0, 2, 0, 1, "X")}, "abXc"}, // End before start inserts
+	}
+	for _, tt := range tests {
+		if got := spliceTextEdits(tt.text, tt.edits); got != tt.want {
+			t.Errorf("spliceTextEdits(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEnclosingCall(t *testing.T) {
+	tests := []struct {
+		content   string
+		open, arg int
+		ok        bool
+	}{
+		{"f(", 1, 0, true},
+		{"f(a, b", 1, 1, true},
+		{"f(a, g(b, c), d", 1, 2, true},
+		{"f(a[1, 2", 1, 0, true},
+		{"f(T{1, 2}, x", 1, 1, true},
+		{"x := []int{1, 2}; g(", 19, 0, true},
+		{"func() {\n\tx", 0, 0, false},
+		{"a; b", 0, 0, false},
+		{"no call", 0, 0, false},
+		{"f(" + strings.Repeat("x", signatureScanLimit), 0, 0, false},
+	}
+	for _, tt := range tests {
+		open, arg, ok := enclosingCall(tt.content, len(tt.content))
+		if open != tt.open || arg != tt.arg || ok != tt.ok {
+			t.Errorf("enclosingCall(%.20q) = %d, %d, %v; want %d, %d, %v",
+				tt.content, open, arg, ok, tt.open, tt.arg, tt.ok)
+		}
+	}
+}
+
+func TestCompletionPrefix(t *testing.T) {
+	tests := []struct {
+		content string
+		offset  int
+		want    string
+	}{
+		{"", 0, ""},
+		{"foo.ba", 6, "ba"},
+		{"foo.", 4, ""},
+		{"abc def", 3, "abc"},
+		{"_x1", 3, "_x1"},
+		{"x := héllo", len("x := héllo"), "héllo"},
+	}
+	for _, tt := range tests {
+		if got := completionPrefix(tt.content, tt.offset); got != tt.want {
+			t.Errorf("completionPrefix(%q, %d) = %q, want %q", tt.content, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareRenameName(t *testing.T) {
+	content := "func main() {\n\tfooBar := 1\n}\n"
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"range":{"start":{"line":1,"character":1},"end":{"line":1,"character":3}},"placeholder":"foo"}`, "foo"},
+		{`{"range":{"start":{"line":1,"character":1},"end":{"line":1,"character":7}}}`, "fooBar"},
+		{`{"start":{"line":1,"character":1},"end":{"line":1,"character":7}}`, "fooBar"},
+		{`{"start":{"line":1,"character":1},"end":{"line":2,"character":0}}`, ""},
+		{`{"start":{"line":1,"character":4},"end":{"line":1,"character":4}}`, ""},
+		{`{"defaultBehavior":true}`, "fooBar"},
+		{`{}`, ""},
+		{`not json`, ""},
+	}
+	for _, tt := range tests {
+		// Point is on the 'B' of fooBar (line 2, byte column 4)
+		if got := prepareRenameName(json.RawMessage(tt.data), content, 2, 4); got != tt.want {
+			t.Errorf("prepareRenameName(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateProgress(t *testing.T) {
+	c := &LSPClient{}
+	steps := []struct {
+		params string
+		text   string
+		ok     bool
+	}{
+		{`{"token":1,"value":{"kind":"begin","title":"Loading"}}`, "Indexing: Loading", true},
+		{`{"token":"b","value":{"kind":"begin","title":"Building","percentage":0}}`, "Indexing: Building", true},
+		{`{"token":1,"value":{"kind":"report","message":"3/10","percentage":30}}`, "Indexing: Loading: 3/10 (30%)", true},
+		{`{"token":"1","value":{"kind":"report","message":"lost"}}`, "", false},
+		{`{"token":1,"value":{"kind":"end"}}`, "Indexing: Building (0%)", true},
+		{`{"token":1,"value":{"kind":"end"}}`, "", false},
+		{`{"token":"b","value":{"kind":"end"}}`, "Ready", true},
+	}
+	for i, s := range steps {
+		text, ok := c.updateProgress(json.RawMessage(s.params))
+		if text != s.text || ok != s.ok {
+			t.Errorf("step %d: got %q, %v; want %q, %v", i, text, ok, s.text, s.ok)
+		}
+	}
+}
+
+func TestTypedText(t *testing.T) {
+	tests := []struct {
+		content     string
+		offset, key int
+		want        string
+		point       int
+	}{
+		{"ab", 1, 'x', "axb", 2},
+		{"ab", 2, 0x7f, "a", 1},
+		{"aé", 3, 0x08, "a", 1},
+		{"ab", 0, 0x7f, "ab", 0},
+		{"ab", 1, '\r', "ab", 1},
+	}
+	for _, tt := range tests {
+		got, point := typedText(tt.content, tt.offset, tt.key)
+		if got != tt.want || point != tt.point {
+			t.Errorf("typedText(%q, %d, %#x) = %q, %d; want %q, %d",
+				tt.content, tt.offset, tt.key, got, point, tt.want, tt.point)
+		}
+		line, col := offsetLineCol(got, point)
+		if line != 1 || col != point {
+			t.Errorf("offsetLineCol(%q, %d) = %d, %d", got, point, line, col)
+		}
+	}
+	if line, col := offsetLineCol("ab\ncd", 4); line != 2 || col != 1 {
+		t.Errorf("offsetLineCol across lines = %d, %d; want 2, 1", line, col)
+	}
+}
@@ -0,0 +1,247 @@
+// go_regex - Interactive regular expression tester for μEmacs
+//
+// Tries Go (RE2) regular expressions against the current buffer. The
+// last pattern is offered as the default at the next prompt.
+//
+// Commands:
+//   regex-test      - List the matches of a pattern, with their lines,
+//                     columns and groups, in *regex-test*
+//   regex-replace   - Preview replacing a pattern's matches in
+//                     *regex-test*, then apply it on confirmation
+//   regex-benchmark - Time matching a pattern against the buffer
+//
+// Replacements use Go's syntax: $1 or ${name} for a group, $$ for a $.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_name(void *bp);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern int api_prompt_yn(const char *prompt);
+extern void api_free(void *ptr);
+extern void api_update_display(void);
+*/
+import "C"
+
+import (
+	"fmt"
+	"regexp"
+	"unsafe"
+)
+
+// testBuffer shows matches and replacement previews
+const testBuffer = "*regex-test*"
+
+// benchmarkRuns is how many times regex-benchmark matches the buffer
+const benchmarkRuns = 1000
+
+// lastPattern is offered as the default pattern
+var lastPattern string
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// replaceText replaces the contents of buffer bp (the current buffer)
+func replaceText(bp unsafe.Pointer, text string) {
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		insertText(text)
+	}
+}
+
+// source returns the current buffer and its text, refusing *regex-test*
+// itself
+func source(cmd string) (unsafe.Pointer, string, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return nil, "", false
+	}
+	if cname := C.api_buffer_name(bp); cname != nil && C.GoString(cname) == testBuffer {
+		message("%s: run it from the buffer to test", cmd)
+		return nil, "", false
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("%s: cannot read buffer", cmd)
+		return nil, "", false
+	}
+	return bp, text, true
+}
+
+// askPattern prompts for a pattern and compiles it
+func askPattern(cmd string) (*regexp.Regexp, bool) {
+	text := "Regex: "
+	if lastPattern != "" {
+		text = fmt.Sprintf("Regex (default %s): ", lastPattern)
+	}
+	input, ok := prompt(text)
+	if !ok {
+		message("Cancelled")
+		return nil, false
+	}
+	if input == "" {
+		input = lastPattern
+	}
+	if input == "" {
+		message("Cancelled")
+		return nil, false
+	}
+	re, err := regexp.Compile(input)
+	if err != nil {
+		message("%s: %v", cmd, err)
+		return nil, false
+	}
+	lastPattern = input
+	return re, true
+}
+
+// show replaces the contents of *regex-test* and switches to it
+func show(text string) bool {
+	cname := C.CString(testBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	out := C.api_buffer_create(cname)
+	if out == nil {
+		return false
+	}
+	C.api_buffer_switch(out)
+	C.api_buffer_clear(out)
+	insertText(text)
+	C.api_set_point(1, 0)
+	return true
+}
+
+//export go_regex_test
+func go_regex_test(f, n C.int) C.int {
+	_, text, ok := source("regex-test")
+	if !ok {
+		return 0
+	}
+	re, ok := askPattern("regex-test")
+	if !ok {
+		return 0
+	}
+
+	matches := findMatches(re, text)
+	if !show(formatMatches(re.String(), matches)) {
+		return 0
+	}
+	message("%d matches of /%s/ on %d lines", len(matches), re, countLines(matches))
+	return 1
+}
+
+//export go_regex_replace
+func go_regex_replace(f, n C.int) C.int {
+	bp, text, ok := source("regex-replace")
+	if !ok {
+		return 0
+	}
+	re, ok := askPattern("regex-replace")
+	if !ok {
+		return 0
+	}
+	repl, ok := prompt(fmt.Sprintf("Replace /%s/ with: ", re))
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+
+	preview, count := replacePreview(re, text, repl)
+	if count == 0 {
+		message("regex-replace: no matches of /%s/", re)
+		return 0
+	}
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	if !show(preview) {
+		return 0
+	}
+	C.api_update_display()
+
+	cq := C.CString(fmt.Sprintf("Replace %d matches? ", count))
+	yes := C.api_prompt_yn(cq)
+	C.free(unsafe.Pointer(cq))
+	C.api_buffer_switch(bp)
+	if yes == 0 {
+		message("Cancelled")
+		return 0
+	}
+	replaceText(bp, re.ReplaceAllString(text, repl))
+	C.api_set_point(line, col)
+	message("Replaced %d matches of /%s/", count, re)
+	return 1
+}
+
+//export go_regex_benchmark
+func go_regex_benchmark(f, n C.int) C.int {
+	_, text, ok := source("regex-benchmark")
+	if !ok {
+		return 0
+	}
+	re, ok := askPattern("regex-benchmark")
+	if !ok {
+		return 0
+	}
+
+	message("Benchmarking /%s/...", re)
+	C.api_update_display()
+	perOp := benchmark(re, text, benchmarkRuns)
+	matches := len(re.FindAllStringIndex(text, -1))
+	message("/%s/: %d ns/op over %d bytes, %d matches (%d runs)",
+		re, perOp.Nanoseconds(), len(text), matches, benchmarkRuns)
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
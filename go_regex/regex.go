@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxListed caps the matches written to *regex-test*
+const maxListed = 1000
+
+// Group is a capture group of a match; Set is false when it took no part
+type Group struct {
+	Name string
+	Text string
+	Set  bool
+}
+
+// Match is one match of a pattern: its 1-based line, 0-based byte column,
+// text and groups
+type Match struct {
+	Line, Col int
+	Text      string
+	Groups    []Group
+}
+
+// lineStarts returns the offset each line of text starts at
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// position converts an offset to a 1-based line and 0-based column
+func position(starts []int, offset int) (line, col int) {
+	i := sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+	return i + 1, offset - starts[i]
+}
+
+// findMatches returns every match of re in text
+func findMatches(re *regexp.Regexp, text string) []Match {
+	starts := lineStarts(text)
+	names := re.SubexpNames()
+	var matches []Match
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		line, col := position(starts, loc[0])
+		m := Match{Line: line, Col: col, Text: text[loc[0]:loc[1]]}
+		for g := 1; g < len(names); g++ {
+			group := Group{Name: names[g]}
+			if loc[2*g] >= 0 {
+				group.Text, group.Set = text[loc[2*g]:loc[2*g+1]], true
+			}
+			m.Groups = append(m.Groups, group)
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// countLines returns the number of distinct lines matches start on
+func countLines(matches []Match) int {
+	n, last := 0, 0
+	for _, m := range matches {
+		if m.Line != last {
+			n, last = n+1, m.Line
+		}
+	}
+	return n
+}
+
+// formatMatches renders matches for *regex-test*
+func formatMatches(pattern string, matches []Match) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "/%s/: %d matches on %d lines\n\n", pattern, len(matches), countLines(matches))
+	for i, m := range matches {
+		if i == maxListed {
+			fmt.Fprintf(&b, "... %d more\n", len(matches)-maxListed)
+			break
+		}
+		fmt.Fprintf(&b, "%d:%d: %s\n", m.Line, m.Col+1, strconv.Quote(m.Text))
+		for g, group := range m.Groups {
+			ref := "$" + strconv.Itoa(g+1)
+			if group.Name != "" {
+				ref += " ${" + group.Name + "}"
+			}
+			if group.Set {
+				fmt.Fprintf(&b, "    %s = %s\n", ref, strconv.Quote(group.Text))
+			} else {
+				fmt.Fprintf(&b, "    %s unset\n", ref)
+			}
+		}
+	}
+	return b.String()
+}
+
+// span is a run of whole lines, as offsets into the text
+type span struct {
+	start, end int
+}
+
+// replacePreview renders the lines holding matches of re before and after
+// replacing them with repl, as ReplaceAllString would, and the number of
+// matches
+func replacePreview(re *regexp.Regexp, text, repl string) (string, int) {
+	locs := re.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return "", 0
+	}
+
+	// Widen each match to whole lines, merging those that touch
+	var spans []span
+	for _, loc := range locs {
+		start := strings.LastIndexByte(text[:loc[0]], '\n') + 1
+		end := len(text)
+		if i := strings.IndexByte(text[loc[1]:], '\n'); i >= 0 {
+			end = loc[1] + i
+		}
+		if n := len(spans); n > 0 && start <= spans[n-1].end {
+			spans[n-1].end = max(spans[n-1].end, end)
+		} else {
+			spans = append(spans, span{start, end})
+		}
+	}
+
+	starts := lineStarts(text)
+	var before, after strings.Builder
+	next := 0
+	for _, s := range spans {
+		line, _ := position(starts, s.start)
+		numberLines(&before, line, text[s.start:s.end])
+
+		var b []byte
+		pos := s.start
+		for ; next < len(locs) && locs[next][0] <= s.end; next++ {
+			loc := locs[next]
+			b = append(b, text[pos:loc[0]]...)
+			b = re.ExpandString(b, repl, text, loc)
+			pos = loc[1]
+		}
+		b = append(b, text[pos:s.end]...)
+		numberLines(&after, line, string(b))
+	}
+	return "---before---\n" + before.String() + "\n---after---\n" + after.String(), len(locs)
+}
+
+// numberLines writes text prefixed with line numbers from first
+func numberLines(b *strings.Builder, first int, text string) {
+	for i, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(b, "%5d: %s\n", first+i, line)
+	}
+}
+
+// benchmark times runs searches of text for every match of re, returning
+// the time per search
+func benchmark(re *regexp.Regexp, text string, runs int) time.Duration {
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		re.FindAllStringIndex(text, -1)
+	}
+	return time.Since(start) / time.Duration(runs)
+}
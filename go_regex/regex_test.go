@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const sample = "alpha = 1\nbeta = 22\n\ngamma=333\n"
+
+func TestFindMatches(t *testing.T) {
+	re := regexp.MustCompile(`(?P<key>\w+) ?= ?(\d+)?`)
+	got := findMatches(re, sample)
+	want := []Match{
+		{1, 0, "alpha = 1", []Group{{"key", "alpha", true}, {"", "1", true}}},
+		{2, 0, "beta = 22", []Group{{"key", "beta", true}, {"", "22", true}}},
+		{4, 0, "gamma=333", []Group{{"key", "gamma", true}, {"", "333", true}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	got = findMatches(regexp.MustCompile(`a(x)?`), "b\nba")
+	if len(got) != 1 || got[0].Line != 2 || got[0].Col != 1 || got[0].Groups[0].Set {
+		t.Errorf("unset group: %+v", got)
+	}
+
+	out := formatMatches(`a(x)?`, got)
+	if !strings.Contains(out, "1 matches on 1 lines") || !strings.Contains(out, "2:2: \"a\"\n    $1 unset\n") {
+		t.Errorf("formatted:\n%s", out)
+	}
+}
+
+func TestReplacePreview(t *testing.T) {
+	re := regexp.MustCompile(`(\w+) = (\d+)`)
+	preview, n := replacePreview(re, sample, "$2 = $1")
+	want := "---before---\n" +
+		"    1: alpha = 1\n" +
+		"    2: beta = 22\n" +
+		"\n---after---\n" +
+		"    1: 1 = alpha\n" +
+		"    2: 22 = beta\n"
+	if n != 2 || preview != want {
+		t.Errorf("got %d matches:\n%s\nwant:\n%s", n, preview, want)
+	}
+
+	// A match across lines takes in both
+	preview, n = replacePreview(regexp.MustCompile(`22\n\ngamma`), sample, "X")
+	if n != 1 || !strings.Contains(preview, "    2: beta = X=333\n") || !strings.Contains(preview, "    4: gamma=333\n") {
+		t.Errorf("multi-line preview:\n%s", preview)
+	}
+
+	if _, n := replacePreview(regexp.MustCompile(`nothing`), sample, ""); n != 0 {
+		t.Errorf("%d matches of nothing", n)
+	}
+}
@@ -39,6 +39,7 @@ typedef int (*buffer_clear_fn)(void*);
 typedef int (*buffer_insert_fn)(const char*, size_t);
 typedef int (*prompt_fn)(const char*, char*, size_t);
 typedef void (*free_fn)(void*);
+typedef int (*set_mark_fn)(void);
 typedef int (*register_command_fn)(const char*, cmd_fn_t);
 typedef int (*unregister_command_fn)(const char*);
 
@@ -61,6 +62,7 @@ static struct {
     buffer_insert_fn buffer_insert;
     prompt_fn prompt;
     free_fn free;
+    set_mark_fn set_mark;
     register_command_fn register_command;
     unregister_command_fn unregister_command;
 } api;
@@ -138,6 +140,13 @@ void api_free(void *ptr) {
     if (api.free) api.free(ptr);
 }
 
+/* Set the mark at point; returns 0 if the editor does not export set_mark */
+int api_set_mark(void) {
+    if (!api.set_mark) return 0;
+    api.set_mark();
+    return 1;
+}
+
 int api_delete_chars(int n) {
     /* Delete n characters at current position - handled via buffer_clear + buffer_insert */
     (void)n;
@@ -155,6 +164,11 @@ static int cmd_sam_v(int f, int n) { return go_sam_v(f, n); }
 static int cmd_sam_edit(int f, int n) { return go_sam_edit(f, n); }
 static int cmd_sam_pipe(int f, int n) { return go_sam_pipe(f, n); }
 static int cmd_sam_help(int f, int n) { return go_sam_help(f, n); }
+static int cmd_sam_prev_command(int f, int n) { return go_sam_prev_command(f, n); }
+static int cmd_sam_next_command(int f, int n) { return go_sam_next_command(f, n); }
+static int cmd_sam_batch(int f, int n) { return go_sam_batch(f, n); }
+static int cmd_sam_undo(int f, int n) { return go_sam_undo(f, n); }
+static int cmd_sam_redo(int f, int n) { return go_sam_redo(f, n); }
 
 /* ============================================================================
  * Extension lifecycle
@@ -198,6 +212,7 @@ static int sam_init_c(void *editor_api_raw) {
     api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
     api.prompt = (prompt_fn)LOOKUP(prompt);
     api.free = (free_fn)LOOKUP(free);
+    api.set_mark = (set_mark_fn)LOOKUP(set_mark);
     api.register_command = (register_command_fn)LOOKUP(register_command);
     api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
 
@@ -220,6 +235,11 @@ static int sam_init_c(void *editor_api_raw) {
     api.register_command("sam-edit", cmd_sam_edit);
     api.register_command("sam-pipe", cmd_sam_pipe);
     api.register_command("sam-help", cmd_sam_help);
+    api.register_command("sam-prev-command", cmd_sam_prev_command);
+    api.register_command("sam-next-command", cmd_sam_next_command);
+    api.register_command("sam-batch", cmd_sam_batch);
+    api.register_command("sam-undo", cmd_sam_undo);
+    api.register_command("sam-redo", cmd_sam_redo);
 
     api.log_info("go_sam: Structural regex extension loaded (Pike's sam commands)");
     return 0;
@@ -235,6 +255,11 @@ static void sam_cleanup_c(void) {
         api.unregister_command("sam-edit");
         api.unregister_command("sam-pipe");
         api.unregister_command("sam-help");
+        api.unregister_command("sam-prev-command");
+        api.unregister_command("sam-next-command");
+        api.unregister_command("sam-batch");
+        api.unregister_command("sam-undo");
+        api.unregister_command("sam-redo");
     }
 }
 
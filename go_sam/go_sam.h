@@ -45,6 +45,7 @@ extern void api_free(void *ptr);
 extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
 extern int api_delete_chars(int n);
+extern int api_set_mark(void);
 
 #line 1 "cgo-generated-wrapper"
 
@@ -114,6 +115,11 @@ extern int go_sam_y(int f, int n);
 extern int go_sam_g(int f, int n);
 extern int go_sam_v(int f, int n);
 extern int go_sam_edit(int f, int n);
+extern int go_sam_prev_command(int f, int n);
+extern int go_sam_next_command(int f, int n);
+extern int go_sam_undo(int f, int n);
+extern int go_sam_redo(int f, int n);
+extern int go_sam_batch(int f, int n);
 extern int go_sam_pipe(int f, int n);
 extern int go_sam_help(int f, int n);
 
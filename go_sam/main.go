@@ -35,12 +35,15 @@ extern void api_free(void *ptr);
 extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
 extern int api_delete_chars(int n);
+extern int api_set_mark(void);
 */
 import "C"
 
 import (
 	"fmt"
 	"go_sam/sam"
+	"os"
+	"path/filepath"
 	"strings"
 	"unsafe"
 )
@@ -53,6 +56,21 @@ var executor *sam.Executor
 func sam_init(api unsafe.Pointer) {
 	// Create executor with API bridge
 	executor = sam.NewExecutor(&apiBridge{})
+
+	if path := historyPath(); path != "" {
+		if err := sam.LoadHistory(path); err != nil && !os.IsNotExist(err) {
+			(&apiBridge{}).LogError(fmt.Sprintf("go_sam: loading %s: %v", path, err))
+		}
+	}
+}
+
+// historyPath returns where the sam command history is saved
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs", "sam_history.json")
 }
 
 // apiBridge implements sam.EditorAPI using CGO calls
@@ -64,20 +82,22 @@ func (a *apiBridge) Message(msg string) {
 	C.free(unsafe.Pointer(cmsg))
 }
 
-func (a *apiBridge) GetBufferContents() (string, error) {
+func (a *apiBridge) GetBufferContents() (string, int, error) {
 	bp := C.api_current_buffer()
 	if bp == nil {
-		return "", fmt.Errorf("no current buffer")
+		return "", 0, fmt.Errorf("no current buffer")
 	}
 
 	var length C.size_t
 	cContents := C.api_buffer_contents(bp, &length)
 	if cContents == nil {
-		return "", fmt.Errorf("failed to get buffer contents")
+		return "", 0, fmt.Errorf("failed to get buffer contents")
 	}
 	defer C.api_free(unsafe.Pointer(cContents))
 
-	return C.GoStringN(cContents, C.int(length)), nil
+	text := C.GoStringN(cContents, C.int(length))
+	line, col := a.GetPoint()
+	return text, sam.LineColToOffset(text, line, col), nil
 }
 
 func (a *apiBridge) ReplaceBufferContents(text string) error {
@@ -99,6 +119,14 @@ func (a *apiBridge) ReplaceBufferContents(text string) error {
 	return nil
 }
 
+func (a *apiBridge) BufferName() string {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return ""
+	}
+	return C.GoString(C.api_buffer_name(bp))
+}
+
 func (a *apiBridge) GetPoint() (line, col int) {
 	var cline, ccol C.int
 	C.api_get_point(&cline, &ccol)
@@ -109,6 +137,20 @@ func (a *apiBridge) SetPoint(line, col int) {
 	C.api_set_point(C.int(line), C.int(col))
 }
 
+// SetSelection converts byte offsets to positions: the mark goes to end
+// and point to start, so the range is the region
+func (a *apiBridge) SetSelection(start, end int) {
+	text, _, err := a.GetBufferContents()
+	if err != nil {
+		return
+	}
+	if end > start {
+		a.SetPoint(sam.OffsetToLineCol(text, end))
+		C.api_set_mark()
+	}
+	a.SetPoint(sam.OffsetToLineCol(text, start))
+}
+
 func (a *apiBridge) Prompt(prompt string) (string, bool) {
 	cprompt := C.CString(prompt)
 	defer C.free(unsafe.Pointer(cprompt))
@@ -174,10 +216,43 @@ func go_sam_v(f, n C.int) C.int {
 
 //export go_sam_edit
 func go_sam_edit(f, n C.int) C.int {
-	// Full structural regex command line
+	return samEdit()
+}
+
+//export go_sam_prev_command
+func go_sam_prev_command(f, n C.int) C.int {
+	if _, ok := sam.PrevHistory(); !ok {
+		(&apiBridge{}).Message("No command history")
+		return 0
+	}
+	return samEdit()
+}
+
+//export go_sam_next_command
+func go_sam_next_command(f, n C.int) C.int {
+	if _, ok := sam.NextHistory(); !ok {
+		(&apiBridge{}).Message("No command history")
+		return 0
+	}
+	return samEdit()
+}
+
+// samEdit prompts for a full structural regex command line. The current
+// history entry is the default, run when the input is empty; successful
+// commands are added to the history.
+func samEdit() C.int {
 	api := &apiBridge{}
 
-	input, ok := api.Prompt("Sam command: ")
+	prompt := "Sam command: "
+	def, hasDefault := sam.CurrentHistory()
+	if hasDefault {
+		prompt = fmt.Sprintf("Sam command (default %s): ", def)
+	}
+
+	input, ok := api.Prompt(prompt)
+	if ok && strings.TrimSpace(input) == "" && hasDefault {
+		input = def
+	}
 	if !ok || strings.TrimSpace(input) == "" {
 		api.Message("Cancelled")
 		return 0
@@ -189,6 +264,94 @@ func go_sam_edit(f, n C.int) C.int {
 		return 0
 	}
 
+	sam.AddHistory(strings.TrimSpace(input))
+	if path := historyPath(); path != "" {
+		if err := sam.SaveHistory(path); err != nil {
+			api.LogError(fmt.Sprintf("go_sam: saving %s: %v", path, err))
+		}
+	}
+	return 1
+}
+
+//export go_sam_undo
+func go_sam_undo(f, n C.int) C.int {
+	api := &apiBridge{}
+	if err := executor.Undo(); err != nil {
+		api.Message(fmt.Sprintf("Sam: %v", err))
+		return 0
+	}
+	api.Message("Sam: edit undone")
+	return 1
+}
+
+//export go_sam_redo
+func go_sam_redo(f, n C.int) C.int {
+	api := &apiBridge{}
+	if err := executor.Redo(); err != nil {
+		api.Message(fmt.Sprintf("Sam: %v", err))
+		return 0
+	}
+	api.Message("Sam: edit redone")
+	return 1
+}
+
+//export go_sam_batch
+func go_sam_batch(f, n C.int) C.int {
+	// Run a command over files on disk; with a prefix argument only show
+	// what would change
+	api := &apiBridge{}
+	dryRun := f != 0
+
+	pattern, ok := api.Prompt("Sam batch files (glob): ")
+	if !ok || strings.TrimSpace(pattern) == "" {
+		api.Message("Cancelled")
+		return 0
+	}
+	cmdStr, ok := api.Prompt("Sam command: ")
+	if !ok || strings.TrimSpace(cmdStr) == "" {
+		api.Message("Cancelled")
+		return 0
+	}
+
+	results, err := executor.RunBatch(strings.TrimSpace(pattern), cmdStr, !dryRun)
+	if err != nil {
+		api.Message(fmt.Sprintf("Error: %v", err))
+		return 0
+	}
+	if len(results) == 0 {
+		api.Message(fmt.Sprintf("No files match %s", pattern))
+		return 0
+	}
+
+	var sb strings.Builder
+	mode := ""
+	if dryRun {
+		mode = " (dry run, nothing written)"
+	}
+	fmt.Fprintf(&sb, "Sam batch: %s on %s%s\n\n", strings.TrimSpace(cmdStr), pattern, mode)
+	changed, failed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(&sb, "  error      %s: %v\n", r.Path, r.Err)
+		case r.Changed:
+			changed++
+			fmt.Fprintf(&sb, "  changed    %s\n", r.Path)
+			if dryRun {
+				sb.WriteString(r.Diff)
+			}
+		default:
+			fmt.Fprintf(&sb, "  unchanged  %s\n", r.Path)
+		}
+	}
+	api.CreateResultsBuffer("*sam-batch*", sb.String())
+
+	verb := "changed"
+	if dryRun {
+		verb = "would change"
+	}
+	api.Message(fmt.Sprintf("%d of %d file(s) %s, %d error(s)", changed, len(results), verb, failed))
 	return 1
 }
 
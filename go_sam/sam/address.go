@@ -6,6 +6,140 @@ import (
 	"strings"
 )
 
+// Address syntax, resolved against dot (the current selection):
+//
+//	.        dot
+//	$        end of file
+//	#n       character offset n (bytes)
+//	n        line n; 0 is the start of the file
+//	/re/     next match of re after dot, wrapping around
+//	?re?     previous match of re before dot, wrapping around
+//	a1,a2    from the start of a1 to the end of a2, both resolved from dot;
+//	         a missing a1 is 0 and a missing a2 is $, so "," is the file
+//	a1;a2    like a1,a2 but a2 is resolved from a1
+
+// isAddressStart reports whether an address begins at the parser position
+func (p *Parser) isAddressStart() bool {
+	if p.atEnd() {
+		return false
+	}
+	c := p.peek()
+	return c == '.' || c == ',' || c == ';' || c == '$' || c == '#' ||
+		c == '/' || c == '?' || (c >= '0' && c <= '9')
+}
+
+// parseAddress parses a simple address optionally joined to a second one
+// by , or ;
+func (p *Parser) parseAddress() (Address, error) {
+	var left Address
+	if c := p.peek(); c != ',' && c != ';' {
+		var err error
+		left, err = p.parseSimpleAddress()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWhitespace()
+	}
+
+	if p.atEnd() || (p.peek() != ',' && p.peek() != ';') {
+		return left, nil
+	}
+	sequential := p.peek() == ';'
+	p.advance()
+	if left == nil {
+		left = &LineAddress{Line: 0}
+	}
+
+	p.skipWhitespace()
+	var right Address = &EndAddress{}
+	if p.isAddressStart() && p.peek() != ',' && p.peek() != ';' {
+		var err error
+		right, err = p.parseSimpleAddress()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &RangeAddress{Start: left, End: right, Sequential: sequential}, nil
+}
+
+// parseSimpleAddress parses one of . $ #n n /re/ ?re?
+func (p *Parser) parseSimpleAddress() (Address, error) {
+	c := p.peek()
+	switch {
+	case c == '.':
+		p.advance()
+		return &DotAddress{}, nil
+	case c == '$':
+		p.advance()
+		return &EndAddress{}, nil
+	case c == '#':
+		p.advance()
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		return &CharAddress{Offset: n}, nil
+	case c >= '0' && c <= '9':
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		return &LineAddress{Line: n}, nil
+	case c == '/' || c == '?':
+		p.advance()
+		pattern, err := p.readDelimited(c)
+		if err != nil {
+			return nil, err
+		}
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		return &RegexAddress{Pattern: re, Forward: c == '/'}, nil
+	default:
+		return nil, fmt.Errorf("invalid address start: %c", c)
+	}
+}
+
+// LineColToOffset converts a 1-based line and 0-based byte column in text
+// to a byte offset, clamped to the text
+func LineColToOffset(text string, line, col int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		nl := strings.IndexByte(text[offset:], '\n')
+		if nl < 0 {
+			return len(text)
+		}
+		offset += nl + 1
+	}
+	lineEnd := strings.IndexByte(text[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(text) - offset
+	}
+	if col < 0 {
+		col = 0
+	}
+	if col > lineEnd {
+		col = lineEnd
+	}
+	return offset + col
+}
+
+// OffsetToLineCol converts a byte offset in text to a 1-based line and
+// 0-based byte column
+func OffsetToLineCol(text string, offset int) (line, col int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := text[:offset]
+	line = strings.Count(before, "\n") + 1
+	col = offset - (strings.LastIndexByte(before, '\n') + 1)
+	return line, col
+}
+
 // Address represents a text address in sam.
 type Address interface {
 	// Resolve converts the address to a concrete region.
@@ -145,10 +279,11 @@ func (a *RegexAddress) resolveBackward(buffer string, current Region) (Region, e
 	return Region{}, fmt.Errorf("pattern not found: %s", a.Pattern.String())
 }
 
-// RangeAddress represents 'addr1,addr2' - a range of text
+// RangeAddress represents 'addr1,addr2' or 'addr1;addr2' - a range of text
 type RangeAddress struct {
-	Start Address
-	End   Address
+	Start      Address
+	End        Address
+	Sequential bool // ';': End is resolved from Start rather than dot
 }
 
 func (a *RangeAddress) Resolve(buffer string, current Region) (Region, error) {
@@ -157,11 +292,17 @@ func (a *RangeAddress) Resolve(buffer string, current Region) (Region, error) {
 		return Region{}, fmt.Errorf("resolving start address: %w", err)
 	}
 
-	// Use start region as context for end address
-	endRegion, err := a.End.Resolve(buffer, startRegion)
+	endContext := current
+	if a.Sequential {
+		endContext = startRegion
+	}
+	endRegion, err := a.End.Resolve(buffer, endContext)
 	if err != nil {
 		return Region{}, fmt.Errorf("resolving end address: %w", err)
 	}
+	if endRegion.End < startRegion.Start {
+		return Region{}, fmt.Errorf("addresses out of order")
+	}
 
 	return Region{
 		Start: startRegion.Start,
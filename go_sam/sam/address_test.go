@@ -0,0 +1,96 @@
+package sam
+
+import "testing"
+
+func TestAddressResolve(t *testing.T) {
+	const buffer = "one\ntwo\nthree\nfour\n"
+	line1 := Region{Start: 0, End: 4}
+	line2 := Region{Start: 4, End: 8}
+	tests := []struct {
+		addr    string
+		dot     Region
+		want    Region
+		wantErr bool
+	}{
+		{addr: "2", dot: line2, want: Region{4, 8}},
+		{addr: "0", dot: line2, want: Region{0, 0}},
+		{addr: "9", dot: line2, want: Region{19, 19}},
+		{addr: "$", dot: line2, want: Region{19, 19}},
+		{addr: ".", dot: line2, want: line2},
+		{addr: "#5", dot: line2, want: Region{5, 5}},
+		{addr: "#20", dot: line2, wantErr: true},
+
+		// Ranges: a missing start is 0 and a missing end is $
+		{addr: "2,3", dot: line1, want: Region{4, 14}},
+		{addr: ",", dot: line2, want: Region{0, 19}},
+		{addr: ",2", dot: line2, want: Region{0, 8}},
+		{addr: "3,", dot: line1, want: Region{8, 19}},
+		{addr: "#3,#2", dot: line1, wantErr: true},
+
+		// Regular expressions search from dot and wrap around
+		{addr: "/o/", dot: line2, want: Region{15, 16}},
+		{addr: "/one/", dot: line2, want: Region{0, 3}},
+		{addr: "?o?", dot: line2, want: Region{0, 1}},
+		{addr: "?t?", dot: line2, want: Region{8, 9}},
+		{addr: `/\//`, dot: line2, wantErr: true},
+		{addr: "/(/", dot: line2, wantErr: true},
+
+		// With ; the end is resolved from the start instead of dot
+		{addr: "3;/o/", dot: line1, want: Region{8, 16}},
+		{addr: "3,/o/", dot: line1, wantErr: true},
+		{addr: "/t/;/e/", dot: line1, want: Region{4, 12}},
+	}
+	for _, tt := range tests {
+		cmd, err := Parse(tt.addr)
+		if err != nil {
+			if !tt.wantErr {
+				t.Errorf("%s: parse: %v", tt.addr, err)
+			}
+			continue
+		}
+		ac, ok := cmd.(*AddressedCommand)
+		if !ok {
+			t.Fatalf("%s parsed to %T, want an address", tt.addr, cmd)
+		}
+		got, err := ac.Addr.Resolve(buffer, tt.dot)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s from %v = %v, want an error", tt.addr, tt.dot, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s from %v: %v", tt.addr, tt.dot, err)
+		} else if got != tt.want {
+			t.Errorf("%s from %v = %v, want %v", tt.addr, tt.dot, got, tt.want)
+		}
+	}
+}
+
+func TestLineColOffset(t *testing.T) {
+	const text = "one\nfünf\n\nlast"
+	for offset := 0; offset <= len(text); offset++ {
+		line, col := OffsetToLineCol(text, offset)
+		if got := LineColToOffset(text, line, col); got != offset {
+			t.Errorf("offset %d -> %d:%d -> %d", offset, line, col, got)
+		}
+	}
+
+	// Positions past a line or the text are clamped
+	tests := []struct {
+		line, col int
+		want      int
+	}{
+		{1, 0, 0},
+		{2, 99, 9},
+		{2, -1, 4},
+		{3, 5, 10},
+		{4, 2, 13},
+		{9, 0, len(text)},
+	}
+	for _, tt := range tests {
+		if got := LineColToOffset(text, tt.line, tt.col); got != tt.want {
+			t.Errorf("LineColToOffset(%d, %d) = %d, want %d", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
@@ -14,6 +14,7 @@
 //   p     - print region
 //   d     - delete region
 //   c/text/ - change region to text
+//   s/re/text/flags - substitute text for matches of re (flags g, i, n)
 //   a/text/ - append text after region
 //   i/text/ - insert text before region
 //   |cmd  - pipe region through shell command
@@ -26,6 +27,7 @@
 //   /re/  - next match of re
 //   ?re?  - previous match of re
 //   $     - end of file
+//   a1,a2 - from a1 to a2 (a1;a2 finds a2 from a1)
 //
 // Commands can be grouped with braces: x/pattern/{g/sub/d}
 
@@ -37,18 +39,26 @@ type EditorAPI interface {
 	// Message displays a message to the user
 	Message(msg string)
 
-	// GetBufferContents returns the entire buffer as a string
-	GetBufferContents() (string, error)
+	// GetBufferContents returns the entire buffer as a string, and the
+	// cursor position as a byte offset into it
+	GetBufferContents() (text string, point int, err error)
 
 	// ReplaceBufferContents replaces the entire buffer
 	ReplaceBufferContents(text string) error
 
+	// BufferName identifies the current buffer
+	BufferName() string
+
 	// GetPoint returns current cursor position (line, col)
 	GetPoint() (line, col int)
 
 	// SetPoint moves cursor to position
 	SetPoint(line, col int)
 
+	// SetSelection selects the byte range start..end of the buffer: point
+	// at start and, when the range is not empty, the mark at end
+	SetSelection(start, end int)
+
 	// Prompt asks user for input, returns (response, ok)
 	Prompt(prompt string) (string, bool)
 
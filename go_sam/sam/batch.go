@@ -0,0 +1,200 @@
+package sam
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BatchResult is the outcome of running a command on one file
+type BatchResult struct {
+	Path    string
+	Changed bool
+	Err     error
+	Diff    string // Line diff of the edit, set when Changed
+}
+
+// binarySniffLen is how much of a file is checked for NUL bytes
+const binarySniffLen = 8000
+
+// RunBatch runs cmdStr on every file matching the glob pattern. Each file
+// is edited in memory with ExecuteOnText and, when write is set, written
+// back if the command changed it; otherwise nothing is written and the
+// results only carry the diffs. Directories are skipped and binary files
+// are reported as errors.
+func (e *Executor) RunBatch(pattern, cmdStr string, write bool) ([]BatchResult, error) {
+	if _, err := Parse(strings.TrimSpace(cmdStr)); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			results = append(results, BatchResult{Path: path, Err: err})
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		results = append(results, e.batchFile(path, info.Mode().Perm(), cmdStr, write))
+	}
+	return results, nil
+}
+
+// batchFile runs cmdStr on one file for RunBatch
+func (e *Executor) batchFile(path string, mode os.FileMode, cmdStr string, write bool) BatchResult {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return BatchResult{Path: path, Err: err}
+	}
+	sniff := content
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+	}
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return BatchResult{Path: path, Err: fmt.Errorf("binary file, skipped")}
+	}
+
+	text := string(content)
+	edited, err := e.ExecuteOnText(text, cmdStr)
+	if err != nil {
+		return BatchResult{Path: path, Err: err}
+	}
+	if edited == text {
+		return BatchResult{Path: path}
+	}
+
+	if write {
+		if err := os.WriteFile(path, []byte(edited), mode); err != nil {
+			return BatchResult{Path: path, Err: err}
+		}
+	}
+	return BatchResult{Path: path, Changed: true, Diff: LineDiff(text, edited)}
+}
+
+// diffContext is how many unchanged lines LineDiff shows around a change
+const diffContext = 2
+
+// maxDiffCells bounds the LCS table; larger edits are shown as one
+// replaced block
+const maxDiffCells = 4000000
+
+// LineDiff returns a unified-style diff of the lines of old and new, with
+// a "@@ -line +line @@" header before each hunk.
+func LineDiff(old, new string) string {
+	a := strings.SplitAfter(old, "\n")
+	b := strings.SplitAfter(new, "\n")
+	if a[len(a)-1] == "" {
+		a = a[:len(a)-1]
+	}
+	if b[len(b)-1] == "" {
+		b = b[:len(b)-1]
+	}
+
+	// Trim the common prefix and suffix before the LCS
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		line string
+	}
+	var ops []op
+	for _, l := range a[:pre] {
+		ops = append(ops, op{' ', l})
+	}
+
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if len(ma)*len(mb) > maxDiffCells {
+		for _, l := range ma {
+			ops = append(ops, op{'-', l})
+		}
+		for _, l := range mb {
+			ops = append(ops, op{'+', l})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of ma[i:] and mb[j:]
+		lcs := make([][]int, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(ma) || j < len(mb) {
+			switch {
+			case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+				ops = append(ops, op{' ', ma[i]})
+				i++
+				j++
+			case j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, op{'-', ma[i]})
+				i++
+			default:
+				ops = append(ops, op{'+', mb[j]})
+				j++
+			}
+		}
+	}
+
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, op{' ', l})
+	}
+
+	// Keep the changes and the context lines around them
+	keep := make([]bool, len(ops))
+	for k, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(ops)-1, k+diffContext); c++ {
+			keep[c] = true
+		}
+	}
+
+	var sb strings.Builder
+	oldLine, newLine := 1, 1
+	inHunk := false
+	for k, o := range ops {
+		if keep[k] {
+			if !inHunk {
+				fmt.Fprintf(&sb, "@@ -%d +%d @@\n", oldLine, newLine)
+				inHunk = true
+			}
+			sb.WriteByte(o.kind)
+			sb.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		} else {
+			inHunk = false
+		}
+		if o.kind != '+' {
+			oldLine++
+		}
+		if o.kind != '-' {
+			newLine++
+		}
+	}
+	return sb.String()
+}
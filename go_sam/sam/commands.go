@@ -140,6 +140,57 @@ func (c *ChangeCommand) Execute(ctx *ExecutionContext, region Region) (string, e
 	return "", nil
 }
 
+// SubstituteCommand implements s/pattern/replacement/flags - replace the
+// first match in the region, or every match with the g flag
+type SubstituteCommand struct {
+	Pattern   *regexp.Regexp
+	Template  string // Replacement, in regexp template syntax unless Literal
+	Literal   bool   // Template has no match references
+	Global    bool   // g: replace all matches
+	CountOnly bool   // n: count matches, change nothing
+}
+
+func (c *SubstituteCommand) Execute(ctx *ExecutionContext, region Region) (string, error) {
+	text := region.Text(ctx.Buffer)
+
+	if c.CountOnly {
+		n := len(c.Pattern.FindAllStringIndex(text, -1))
+		if !c.Global && n > 1 {
+			n = 1
+		}
+		ctx.Counting = true
+		ctx.Matches += n
+		return "", nil
+	}
+
+	var result string
+	switch {
+	case c.Global && c.Literal:
+		result = c.Pattern.ReplaceAllLiteralString(text, c.Template)
+	case c.Global:
+		result = c.Pattern.ReplaceAllString(text, c.Template)
+	default:
+		m := c.Pattern.FindStringSubmatchIndex(text)
+		if m == nil {
+			return "", nil
+		}
+		replacement := c.Template
+		if !c.Literal {
+			replacement = string(c.Pattern.ExpandString(nil, c.Template, text, m))
+		}
+		result = text[:m[0]] + replacement + text[m[1]:]
+	}
+
+	if result != text {
+		ctx.Changes = append(ctx.Changes, Change{
+			Start:   region.Start,
+			End:     region.End,
+			NewText: result,
+		})
+	}
+	return "", nil
+}
+
 // AppendCommand implements a/text/ - append text after region
 type AppendCommand struct {
 	Text string
@@ -227,6 +278,7 @@ func (c *AddressedCommand) Execute(ctx *ExecutionContext, region Region) (string
 	if err != nil {
 		return "", err
 	}
+	ctx.Dot = newRegion
 	return c.Cmd.Execute(ctx, newRegion)
 }
 
@@ -8,11 +8,35 @@ import (
 // Executor runs sam commands against the editor.
 type Executor struct {
 	API EditorAPI
+
+	// Dot is the current selection as byte offsets. Addresses are resolved
+	// from it; it holds while point stays at its start, and collapses to
+	// point once the cursor moves.
+	Dot Region
+
+	// History holds the edits of each buffer, by buffer name, so undo
+	// only ever touches the buffer an edit was made in
+	History map[string]*EditHistory
+}
+
+// EditHistory is the sam edits of one buffer: Undo oldest first, Redo
+// the edits undone, most recent last
+type EditHistory struct {
+	Undo []Snapshot
+	Redo []Snapshot
 }
 
+// Snapshot is the buffer text before and after one edit
+type Snapshot struct {
+	Before, After string
+}
+
+// maxUndo caps the snapshots kept in each EditHistory.Undo
+const maxUndo = 50
+
 // NewExecutor creates a new executor with the given editor API.
 func NewExecutor(api EditorAPI) *Executor {
-	return &Executor{API: api}
+	return &Executor{API: api, History: map[string]*EditHistory{}}
 }
 
 // Execute parses and runs a sam command string.
@@ -29,35 +53,52 @@ func (e *Executor) Execute(cmdStr string) error {
 	}
 
 	// Get buffer contents
-	buffer, err := e.API.GetBufferContents()
+	buffer, point, err := e.API.GetBufferContents()
 	if err != nil {
 		return fmt.Errorf("getting buffer: %w", err)
 	}
 
+	dot := e.Dot
+	if dot.Start != point || dot.End > len(buffer) {
+		dot = Region{Start: point, End: point}
+	}
+
 	// Create execution context
 	ctx := &ExecutionContext{
 		Buffer:  buffer,
 		Changes: nil,
 		API:     e.API,
+		Dot:     dot,
 	}
 
-	// Default region is entire buffer
+	// Addresses are resolved from dot; a bare command runs on the
+	// entire buffer
 	region := Region{Start: 0, End: len(buffer)}
+	if _, ok := cmd.(*AddressedCommand); ok {
+		region = dot
+	}
 
 	// Execute command
 	output, err := cmd.Execute(ctx, region)
 	if err != nil {
 		return fmt.Errorf("execution error: %w", err)
 	}
+	e.setDot(ctx)
+
+	if ctx.Counting && len(ctx.Changes) == 0 && ctx.Output.Len() == 0 {
+		e.showDot()
+		e.API.Message(fmt.Sprintf("%d match(es)", ctx.Matches))
+		return nil
+	}
 
 	// Apply any accumulated changes
 	if len(ctx.Changes) > 0 {
-		newBuffer := ApplyChanges(ctx.Buffer, ctx.Changes)
-		if err := e.API.ReplaceBufferContents(newBuffer); err != nil {
+		if err := e.replace(ctx.Buffer, ApplyChanges(ctx.Buffer, ctx.Changes)); err != nil {
 			return fmt.Errorf("applying changes: %w", err)
 		}
 		e.API.Message(fmt.Sprintf("%d change(s) applied", len(ctx.Changes)))
 	}
+	e.showDot()
 
 	// Show output if any
 	if ctx.Output.Len() > 0 {
@@ -95,7 +136,7 @@ func (e *Executor) ExecuteOnRegion(cmdStr string, start, end int) error {
 		return fmt.Errorf("parse error: %w", err)
 	}
 
-	buffer, err := e.API.GetBufferContents()
+	buffer, _, err := e.API.GetBufferContents()
 	if err != nil {
 		return fmt.Errorf("getting buffer: %w", err)
 	}
@@ -125,19 +166,154 @@ func (e *Executor) ExecuteOnRegion(cmdStr string, start, end int) error {
 	}
 
 	if len(ctx.Changes) > 0 {
-		newBuffer := ApplyChanges(ctx.Buffer, ctx.Changes)
-		if err := e.API.ReplaceBufferContents(newBuffer); err != nil {
+		if err := e.replace(ctx.Buffer, ApplyChanges(ctx.Buffer, ctx.Changes)); err != nil {
 			return err
 		}
 	}
 
 	if ctx.Output.Len() > 0 {
 		e.API.Message(strings.TrimSuffix(ctx.Output.String(), "\n"))
+	} else if ctx.Counting {
+		e.API.Message(fmt.Sprintf("%d match(es)", ctx.Matches))
 	}
 
 	return nil
 }
 
+// ExecuteOnText runs a command on text instead of the editor buffer and
+// returns the edited text. Dot is the start of the text, so a bare command
+// runs on all of it. Printed output is discarded and the editor is not
+// touched.
+func (e *Executor) ExecuteOnText(text, cmdStr string) (string, error) {
+	cmdStr = strings.TrimSpace(cmdStr)
+	if cmdStr == "" {
+		return "", fmt.Errorf("empty command")
+	}
+
+	cmd, err := Parse(cmdStr)
+	if err != nil {
+		return "", fmt.Errorf("parse error: %w", err)
+	}
+
+	ctx := &ExecutionContext{
+		Buffer: text,
+		API:    e.API,
+	}
+
+	region := Region{Start: 0, End: len(text)}
+	if _, ok := cmd.(*AddressedCommand); ok {
+		region = ctx.Dot
+	}
+
+	if _, err := cmd.Execute(ctx, region); err != nil {
+		return "", fmt.Errorf("execution error: %w", err)
+	}
+	return ApplyChanges(text, ctx.Changes), nil
+}
+
+// replace swaps the buffer text from old to text, recording the edit in
+// the current buffer's history. A new edit discards anything that could
+// be redone.
+func (e *Executor) replace(old, text string) error {
+	if err := e.API.ReplaceBufferContents(text); err != nil {
+		return err
+	}
+	h := e.history(e.API.BufferName())
+	h.Undo = pushSnapshot(h.Undo, Snapshot{Before: old, After: text})
+	h.Redo = nil
+	return nil
+}
+
+// history returns the edit history of buffer name, creating it if needed
+func (e *Executor) history(name string) *EditHistory {
+	if e.History == nil {
+		e.History = map[string]*EditHistory{}
+	}
+	h, ok := e.History[name]
+	if !ok {
+		h = &EditHistory{}
+		e.History[name] = h
+	}
+	return h
+}
+
+// pushSnapshot appends snap to stack, dropping the oldest snapshots past
+// maxUndo
+func pushSnapshot(stack []Snapshot, snap Snapshot) []Snapshot {
+	stack = append(stack, snap)
+	if len(stack) > maxUndo {
+		stack = append(stack[:0], stack[len(stack)-maxUndo:]...)
+	}
+	return stack
+}
+
+// Undo restores the current buffer's text from before its last edit.
+func (e *Executor) Undo() error {
+	h := e.history(e.API.BufferName())
+	if len(h.Undo) == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+	return e.swapSnapshot(&h.Undo, &h.Redo, true)
+}
+
+// Redo reapplies the current buffer's last edit undone by Undo.
+func (e *Executor) Redo() error {
+	h := e.history(e.API.BufferName())
+	if len(h.Redo) == 0 {
+		return fmt.Errorf("nothing to redo")
+	}
+	return e.swapSnapshot(&h.Redo, &h.Undo, false)
+}
+
+// swapSnapshot moves the last snapshot of from to to, replacing the buffer
+// with its text before the edit when undo is set and after it otherwise.
+// The buffer must still hold the text the snapshot left, so edits made
+// since by hand are never thrown away.
+func (e *Executor) swapSnapshot(from, to *[]Snapshot, undo bool) error {
+	current, _, err := e.API.GetBufferContents()
+	if err != nil {
+		return fmt.Errorf("getting buffer: %w", err)
+	}
+	snap := (*from)[len(*from)-1]
+	want, text := snap.Before, snap.After
+	if undo {
+		want, text = snap.After, snap.Before
+	}
+	if current != want {
+		return fmt.Errorf("buffer changed since the sam edit")
+	}
+	if err := e.API.ReplaceBufferContents(text); err != nil {
+		return err
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = pushSnapshot(*to, snap)
+	e.Dot = Region{}
+	e.showDot()
+	return nil
+}
+
+// setDot records the selection a command leaves and shows it in the
+// editor: the resolved address, or an empty selection at the first change
+// when the buffer was edited
+func (e *Executor) setDot(ctx *ExecutionContext) {
+	dot := ctx.Dot
+	if len(ctx.Changes) > 0 {
+		first := ctx.Changes[0].Start
+		for _, ch := range ctx.Changes[1:] {
+			if ch.Start < first {
+				first = ch.Start
+			}
+		}
+		dot = Region{Start: first, End: first}
+	}
+	e.Dot = dot
+}
+
+// showDot moves point (and the mark) to the current selection
+func (e *Executor) showDot() {
+	e.API.SetSelection(e.Dot.Start, e.Dot.End)
+}
+
 // Help returns a help string describing sam commands.
 func Help() string {
 	return `Sam Structural Regular Expressions
@@ -152,6 +328,9 @@ Simple Commands:
   p               Print region
   d               Delete region
   c/text/         Change region to text
+  s/re/text/      Substitute text for the first match of re
+                  (& is the match, \1-\9 submatches; flags:
+                  g all matches, i ignore case, n count only)
   a/text/         Append text after region
   i/text/         Insert text before region
   |cmd            Pipe region through shell command
@@ -164,14 +343,19 @@ Addresses:
   #n              Character n
   /pattern/       Next match of pattern
   ?pattern?       Previous match of pattern
+  a1,a2           From a1 to a2, both found from the selection
+  a1;a2           From a1 to a2, a2 found from a1
 
 Examples:
   x/TODO/p                   Print all lines containing TODO
   x/func.*{/d                Delete all function headers
   ,x/old/c/new/              Replace all 'old' with 'new'
+  s/(\w+)=(\w+)/\2=\1/g       Swap both sides of every assignment
+  x/func/ s/old/new/g        Replace inside each match of func
   x/^import/a/ "fmt"/        Add "fmt" after each import
   ,|sort                     Sort entire buffer
   x/error/{g/nil/d}          Delete error checks that use nil
+  1,5 x/func/p               Print func matches in lines 1-5
 
 Commands can be grouped with braces:
   x/func/{g/error/p}         Print functions containing 'error'
@@ -0,0 +1,227 @@
+package sam
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEditor is an EditorAPI over an in-memory buffer
+type fakeEditor struct {
+	name    string
+	buffer  string
+	point   int
+	message string
+}
+
+func (f *fakeEditor) Message(msg string) { f.message = msg }
+func (f *fakeEditor) GetBufferContents() (string, int, error) {
+	return f.buffer, f.point, nil
+}
+func (f *fakeEditor) ReplaceBufferContents(text string) error {
+	f.buffer = text
+	return nil
+}
+func (f *fakeEditor) BufferName() string                        { return f.name }
+func (f *fakeEditor) GetPoint() (line, col int)                 { return OffsetToLineCol(f.buffer, f.point) }
+func (f *fakeEditor) SetPoint(line, col int)                    { f.point = LineColToOffset(f.buffer, line, col) }
+func (f *fakeEditor) SetSelection(start, end int)               { f.point = start }
+func (f *fakeEditor) Prompt(prompt string) (string, bool)       { return "", false }
+func (f *fakeEditor) CreateResultsBuffer(name, contents string) {}
+func (f *fakeEditor) LogInfo(msg string)                        {}
+func (f *fakeEditor) LogError(msg string)                       {}
+
+func TestDeleteAndChangeUndo(t *testing.T) {
+	const original = "# header\ncode() // TODO: one\n# note\nmore() // TODO: two\n"
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{`x/^#.*/ d`, "\ncode() // TODO: one\n\nmore() // TODO: two\n"},
+		{`x/^#.*\n/ d`, "code() // TODO: one\nmore() // TODO: two\n"},
+		{`x/TODO/ c/FIXME/`, "# header\ncode() // FIXME: one\n# note\nmore() // FIXME: two\n"},
+	}
+	for _, tt := range tests {
+		ed := &fakeEditor{buffer: original}
+		e := NewExecutor(ed)
+		if err := e.Execute(tt.cmd); err != nil {
+			t.Fatalf("%s: %v", tt.cmd, err)
+		}
+		if ed.buffer != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.cmd, ed.buffer, tt.want)
+		}
+		if e.Dot.Start != e.Dot.End {
+			t.Errorf("%s: dot %v is not empty after an edit", tt.cmd, e.Dot)
+		}
+
+		if err := e.Undo(); err != nil {
+			t.Fatalf("%s: undo: %v", tt.cmd, err)
+		}
+		if ed.buffer != original {
+			t.Errorf("%s: undo left %q", tt.cmd, ed.buffer)
+		}
+		if err := e.Undo(); err == nil {
+			t.Errorf("%s: second undo succeeded with nothing to undo", tt.cmd)
+		}
+
+		if err := e.Redo(); err != nil {
+			t.Fatalf("%s: redo: %v", tt.cmd, err)
+		}
+		if ed.buffer != tt.want {
+			t.Errorf("%s: redo left %q", tt.cmd, ed.buffer)
+		}
+		if err := e.Redo(); err == nil {
+			t.Errorf("%s: second redo succeeded with nothing to redo", tt.cmd)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt": "one\nTODO two\nthree\n",
+		"b.txt": "nothing here\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := NewExecutor(&fakeEditor{})
+	pattern := filepath.Join(dir, "*.txt")
+
+	// A dry run reports the diff and writes nothing
+	results, err := e.RunBatch(pattern, `x/TODO/ c/DONE/`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Changed || results[1].Changed {
+		t.Fatalf("results %+v", results)
+	}
+	const diff = "@@ -1 +1 @@\n one\n-TODO two\n+DONE two\n three\n"
+	if results[0].Diff != diff {
+		t.Errorf("diff:\ngot  %q\nwant %q", results[0].Diff, diff)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(got) != files["a.txt"] {
+		t.Errorf("dry run wrote %q", got)
+	}
+
+	if _, err := e.RunBatch(pattern, `x/TODO/ c/DONE/`, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(got) != "one\nDONE two\nthree\n" {
+		t.Errorf("a.txt is %q", got)
+	}
+}
+
+func TestUndoCap(t *testing.T) {
+	ed := &fakeEditor{buffer: "a"}
+	e := NewExecutor(ed)
+	for i := 0; i < maxUndo+10; i++ {
+		if err := e.Execute(`x/$/ a/a/`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(e.History[ed.name].Undo); n != maxUndo {
+		t.Fatalf("undo stack holds %d snapshots, want %d", n, maxUndo)
+	}
+	for e.Undo() == nil {
+	}
+	if want := strings.Repeat("a", 11); ed.buffer != want {
+		t.Errorf("undoing everything left %q, want %q", ed.buffer, want)
+	}
+}
+
+func TestUndoPerBuffer(t *testing.T) {
+	ed := &fakeEditor{name: "a.txt", buffer: "one TODO\n"}
+	e := NewExecutor(ed)
+	if err := e.Execute(`x/TODO/ c/DONE/`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another buffer has nothing to undo and is left alone
+	ed.name, ed.buffer = "b.txt", "other text\n"
+	if err := e.Undo(); err == nil {
+		t.Error("undo in b.txt succeeded with only an edit in a.txt")
+	}
+	if ed.buffer != "other text\n" {
+		t.Errorf("undo in b.txt left %q", ed.buffer)
+	}
+
+	// Edits made by hand after the sam edit are not reverted
+	ed.name, ed.buffer = "a.txt", "one DONE\nby hand\n"
+	if err := e.Undo(); err == nil {
+		t.Error("undo succeeded over a manual edit")
+	}
+	if ed.buffer != "one DONE\nby hand\n" {
+		t.Errorf("refused undo left %q", ed.buffer)
+	}
+
+	ed.buffer = "one DONE\n"
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if ed.buffer != "one TODO\n" {
+		t.Errorf("undo left %q", ed.buffer)
+	}
+
+	ed.buffer = "one TODO\nby hand\n"
+	if err := e.Redo(); err == nil {
+		t.Error("redo succeeded over a manual edit")
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		buffer string
+		cmd    string
+		want   string
+	}{
+		{"foo boo\n", `s/o/0/`, "f0o boo\n"},
+		{"foo boo\n", `s/o/0/g`, "f00 b00\n"},
+		{"foo boo\n", `s/fo+/[&]/`, "[foo] boo\n"},
+		{"foo boo\n", `s/(\w+) (\w+)/\2 \1/`, "boo foo\n"},
+		{"foo boo\n", `s/foo/a\&b/`, "a&b boo\n"},
+		{"foo boo\n", `s/foo/$1/`, "$1 boo\n"},
+		{"a/b/c\n", `s/\//-/g`, "a-b-c\n"},
+		{"a-b-c\n", `s/-/\//g`, "a/b/c\n"},
+		{"a/b/c\n", `s|/|&&|`, "a//b/c\n"},
+		{"FOO boo\n", `s/foo/x/i`, "x boo\n"},
+		{"FoO bOo\n", `s/o/0/gi`, "F00 b00\n"},
+		{"foo boo\n", `x/b.*/ s/o/0/g`, "foo b00\n"},
+	}
+	for _, tt := range tests {
+		ed := &fakeEditor{buffer: tt.buffer}
+		if err := NewExecutor(ed).Execute(tt.cmd); err != nil {
+			t.Fatalf("%s: %v", tt.cmd, err)
+		}
+		if ed.buffer != tt.want {
+			t.Errorf("%s on %q:\ngot  %q\nwant %q", tt.cmd, tt.buffer, ed.buffer, tt.want)
+		}
+	}
+}
+
+func TestSubstituteCount(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{`s/o/0/n`, "1 match(es)"},
+		{`s/o/0/gn`, "4 match(es)"},
+		{`s/O/0/gin`, "4 match(es)"},
+		{`x/b../ s/o/0/gn`, "2 match(es)"},
+	}
+	for _, tt := range tests {
+		ed := &fakeEditor{buffer: "foo boo\n"}
+		if err := NewExecutor(ed).Execute(tt.cmd); err != nil {
+			t.Fatalf("%s: %v", tt.cmd, err)
+		}
+		if ed.buffer != "foo boo\n" {
+			t.Errorf("%s changed the buffer to %q", tt.cmd, ed.buffer)
+		}
+		if ed.message != tt.want {
+			t.Errorf("%s: message %q, want %q", tt.cmd, ed.message, tt.want)
+		}
+	}
+}
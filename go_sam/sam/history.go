@@ -0,0 +1,86 @@
+package sam
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// maxHistory bounds the number of remembered commands
+const maxHistory = 100
+
+// commandHistory holds successfully run commands, oldest first.
+// historyIndex is the entry offered as the default at the next prompt.
+var (
+	commandHistory []string
+	historyIndex   int
+)
+
+// AddHistory records a successful command and makes it the current entry.
+// Repeating the last command does not add a duplicate.
+func AddHistory(cmd string) {
+	if n := len(commandHistory); n == 0 || commandHistory[n-1] != cmd {
+		commandHistory = append(commandHistory, cmd)
+		if len(commandHistory) > maxHistory {
+			commandHistory = commandHistory[len(commandHistory)-maxHistory:]
+		}
+	}
+	historyIndex = len(commandHistory) - 1
+}
+
+// CurrentHistory returns the current history entry, if there is one.
+func CurrentHistory() (string, bool) {
+	if historyIndex < 0 || historyIndex >= len(commandHistory) {
+		return "", false
+	}
+	return commandHistory[historyIndex], true
+}
+
+// PrevHistory steps to the previous (older) entry and returns it. At the
+// oldest entry it stays there.
+func PrevHistory() (string, bool) {
+	if historyIndex > 0 {
+		historyIndex--
+	}
+	return CurrentHistory()
+}
+
+// NextHistory steps to the next (newer) entry and returns it. At the
+// newest entry it stays there.
+func NextHistory() (string, bool) {
+	if historyIndex < len(commandHistory)-1 {
+		historyIndex++
+	}
+	return CurrentHistory()
+}
+
+// LoadHistory replaces the history with the JSON list of commands saved
+// at path. The newest entry becomes current.
+func LoadHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cmds []string
+	if err := json.Unmarshal(data, &cmds); err != nil {
+		return err
+	}
+	if len(cmds) > maxHistory {
+		cmds = cmds[len(cmds)-maxHistory:]
+	}
+	commandHistory = cmds
+	historyIndex = len(commandHistory) - 1
+	return nil
+}
+
+// SaveHistory writes the history to path as a JSON list of commands.
+func SaveHistory(path string) error {
+	data, err := json.MarshalIndent(commandHistory, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
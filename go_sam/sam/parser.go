@@ -21,6 +21,11 @@ type ExecutionContext struct {
 	Changes []Change  // Accumulated changes (applied in reverse order)
 	Output  strings.Builder // Accumulated output
 	API     EditorAPI // Editor interface
+
+	Counting bool // An s///n ran: report Matches instead of changing
+	Matches  int  // Matches counted by s///n
+
+	Dot Region // Selection left by the command: the resolved address
 }
 
 // Change represents a text modification.
@@ -100,6 +105,8 @@ func (p *Parser) parseSingleCommand() (Command, error) {
 		return &DeleteCommand{}, nil
 	case 'c':
 		return p.parseChange()
+	case 's':
+		return p.parseSubstitute()
 	case 'a':
 		return p.parseAppend()
 	case 'i':
@@ -127,7 +134,7 @@ func (p *Parser) parseExtract(inverse bool) (Command, error) {
 		return nil, fmt.Errorf("reading pattern: %w", err)
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compilePattern(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
 	}
@@ -165,7 +172,7 @@ func (p *Parser) parseGuard(inverse bool) (Command, error) {
 		return nil, fmt.Errorf("reading pattern: %w", err)
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compilePattern(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
 	}
@@ -206,6 +213,82 @@ func (p *Parser) parseChange() (Command, error) {
 	return &ChangeCommand{Text: text}, nil
 }
 
+// parseSubstitute parses s/pattern/replacement/flags
+func (p *Parser) parseSubstitute() (Command, error) {
+	p.advance() // consume 's'
+
+	delim, err := p.readDelimiter()
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := p.readDelimited(delim)
+	if err != nil {
+		return nil, fmt.Errorf("reading pattern: %w", err)
+	}
+
+	replacement, err := p.readDelimited(delim)
+	if err != nil {
+		return nil, fmt.Errorf("reading replacement: %w", err)
+	}
+
+	cmd := &SubstituteCommand{}
+	for !p.atEnd() && strings.IndexByte("gin", p.peek()) >= 0 {
+		switch p.peek() {
+		case 'g':
+			cmd.Global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		case 'n':
+			cmd.CountOnly = true
+		}
+		p.advance()
+	}
+
+	cmd.Pattern, err = compilePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	cmd.Template, cmd.Literal = substituteTemplate(replacement)
+
+	return cmd, nil
+}
+
+// substituteTemplate converts a sam replacement to a regexp template: &
+// is the matched text and \1..\9 are submatches, while \& is a literal &.
+// literal reports that the replacement refers to no match text, so it can
+// be inserted as is.
+func substituteTemplate(replacement string) (template string, literal bool) {
+	var tmpl, plain strings.Builder
+	literal = true
+	for i := 0; i < len(replacement); i++ {
+		c := replacement[i]
+		switch {
+		case c == '&':
+			tmpl.WriteString("${0}")
+			literal = false
+		case c == '\\' && i+1 < len(replacement) && replacement[i+1] == '&':
+			tmpl.WriteByte('&')
+			plain.WriteByte('&')
+			i++
+		case c == '\\' && i+1 < len(replacement) && replacement[i+1] >= '1' && replacement[i+1] <= '9':
+			tmpl.WriteString("${" + string(replacement[i+1]) + "}")
+			literal = false
+			i++
+		case c == '$':
+			tmpl.WriteString("$$")
+			plain.WriteByte('$')
+		default:
+			tmpl.WriteByte(c)
+			plain.WriteByte(c)
+		}
+	}
+	if literal {
+		return plain.String(), true
+	}
+	return tmpl.String(), false
+}
+
 // parseAppend parses a/text/
 func (p *Parser) parseAppend() (Command, error) {
 	p.advance() // consume 'a'
@@ -283,88 +366,10 @@ func (p *Parser) parseGroup() (Command, error) {
 	return &GroupCommand{Commands: commands}, nil
 }
 
-// Address parsing
-
-func (p *Parser) isAddressStart() bool {
-	if p.atEnd() {
-		return false
-	}
-	c := p.peek()
-	return c == '.' || c == ',' || c == '$' || c == '#' ||
-		c == '/' || c == '?' || (c >= '0' && c <= '9')
-}
-
-func (p *Parser) parseAddress() (Address, error) {
-	var left Address
-
-	// Parse simple address
-	c := p.peek()
-	switch {
-	case c == '.':
-		p.advance()
-		left = &DotAddress{}
-	case c == ',':
-		// Comma alone means 0,$
-		left = &RangeAddress{
-			Start: &LineAddress{Line: 0},
-			End:   &EndAddress{},
-		}
-		p.advance()
-		return left, nil
-	case c == '$':
-		p.advance()
-		left = &EndAddress{}
-	case c == '#':
-		p.advance()
-		n, err := p.parseNumber()
-		if err != nil {
-			return nil, err
-		}
-		left = &CharAddress{Offset: n}
-	case c >= '0' && c <= '9':
-		n, err := p.parseNumber()
-		if err != nil {
-			return nil, err
-		}
-		left = &LineAddress{Line: n}
-	case c == '/':
-		p.advance()
-		pattern, err := p.readDelimited('/')
-		if err != nil {
-			return nil, err
-		}
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return nil, err
-		}
-		left = &RegexAddress{Pattern: re, Forward: true}
-	case c == '?':
-		p.advance()
-		pattern, err := p.readDelimited('?')
-		if err != nil {
-			return nil, err
-		}
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return nil, err
-		}
-		left = &RegexAddress{Pattern: re, Forward: false}
-	default:
-		return nil, fmt.Errorf("invalid address start: %c", c)
-	}
-
-	// Check for range
-	p.skipWhitespace()
-	if !p.atEnd() && p.peek() == ',' {
-		p.advance()
-		right, err := p.parseAddress()
-		if err != nil {
-			return nil, err
-		}
-		return &RangeAddress{Start: left, End: right}, nil
-	}
-
-	return left, nil
+// compilePattern compiles a sam regular expression. As in sam, ^ and $
+// match at line boundaries, not just at the ends of the text.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?m)" + pattern)
 }
 
 func (p *Parser) parseNumber() (int, error) {
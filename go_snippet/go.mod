@@ -0,0 +1,3 @@
+module go_snippet
+
+go 1.21
@@ -0,0 +1,285 @@
+// go_snippet - Code snippets with tab stops for μEmacs
+//
+// Snippets are YAML files in ~/.config/muemacs/snippets/<language>/,
+// the language coming from the current file's extension (go, python,
+// c, ...). Each has a trigger, a description and a body whose tab stops
+// are $1, $2, ..., ${1:default} and, last, $0.
+//
+// Commands:
+//   snippet-expand       - Replace the trigger before point with its
+//                          snippet, leaving point at the first tab stop
+//   snippet-next-tabstop - Move to the next tab stop of the last snippet,
+//                          replacing its marker with its default
+//   snippet-list         - List the current language's snippets in
+//                          *snippets*
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_filename(void *bp);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern char *api_get_current_line(void);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_delete_chars(int n);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern void api_free(void *ptr);
+*/
+import "C"
+
+import (
+	"fmt"
+	"go_snippet/snippet"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// listBuffer shows the available snippets
+const listBuffer = "*snippets*"
+
+// active is the last expanded snippet while it has tab stops left: the
+// lines it was inserted on and the buffer's line count just after
+var active struct {
+	bp          unsafe.Pointer
+	first, last int
+	lineCount   int
+}
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// logError writes a message to the editor log
+func logError(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_log_error(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// snippetDir returns the snippet directory for a language
+func snippetDir(lang string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs", "snippets", lang)
+}
+
+// currentSnippets loads the snippets for the current buffer's language,
+// logging files that fail to parse
+func currentSnippets(cmd string) (lang, dir string, snippets []snippet.Snippet, ok bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return "", "", nil, false
+	}
+	if cname := C.api_buffer_filename(bp); cname != nil {
+		lang = snippet.Language(C.GoString(cname))
+	}
+	if lang == "" {
+		message("%s: cannot tell the buffer's language from its file name", cmd)
+		return "", "", nil, false
+	}
+	dir = snippetDir(lang)
+	snippets, errs := snippet.LoadDir(dir)
+	for _, err := range errs {
+		logError("go_snippet: %s: %v", dir, err)
+	}
+	return lang, dir, snippets, true
+}
+
+// replaceMarker deletes the marker text at point and inserts fill, leaving
+// point at the start of fill
+func replaceMarker(line, col int, marker, fill string) {
+	C.api_set_point(C.int(line), C.int(col))
+	C.api_delete_chars(C.int(utf8.RuneCountInString(marker)))
+	if fill != "" {
+		insertText(fill)
+		C.api_set_point(C.int(line), C.int(col))
+	}
+}
+
+//export go_snippet_expand
+func go_snippet_expand(f, n C.int) C.int {
+	lang, _, snippets, ok := currentSnippets("snippet-expand")
+	if !ok {
+		return 0
+	}
+	if len(snippets) == 0 {
+		message("snippet-expand: no %s snippets", lang)
+		return 0
+	}
+
+	cline := C.api_get_current_line()
+	if cline == nil {
+		return 0
+	}
+	text := strings.TrimRight(C.GoString(cline), "\r\n")
+	C.api_free(unsafe.Pointer(cline))
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	before := text[:min(int(col), len(text))]
+
+	s, ok := snippet.Match(snippets, before)
+	if !ok {
+		message("snippet-expand: no %s snippet matches before point", lang)
+		return 0
+	}
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	body, cursor := snippet.Expand(s.Body, indent)
+
+	start := len(before) - len(s.Trigger)
+	C.api_set_point(line, C.int(start))
+	if C.api_delete_chars(C.int(utf8.RuneCountInString(s.Trigger))) == 0 {
+		message("snippet-expand: cannot delete the trigger")
+		return 0
+	}
+	insertText(body)
+
+	// Point goes to the first tab stop
+	head := body[:cursor]
+	stopLine, stopCol := int(line), start+len(head)
+	if nl := strings.LastIndexByte(head, '\n'); nl >= 0 {
+		stopLine += strings.Count(head, "\n")
+		stopCol = len(head) - nl - 1
+	}
+	C.api_set_point(C.int(stopLine), C.int(stopCol))
+
+	active.bp = nil
+	if _, _, _, more := snippet.NextStop(body); more {
+		bp := C.api_current_buffer()
+		if all, ok := bufferText(bp); ok {
+			active.bp = bp
+			active.first = int(line)
+			active.last = int(line) + strings.Count(body, "\n")
+			active.lineCount = strings.Count(all, "\n")
+		}
+	}
+	message("%s: %s", s.Trigger, s.Description)
+	return 1
+}
+
+//export go_snippet_next_tabstop
+func go_snippet_next_tabstop(f, n C.int) C.int {
+	bp := C.api_current_buffer()
+	if active.bp == nil || bp != active.bp {
+		message("snippet-next-tabstop: no snippet with tab stops left here")
+		return 0
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		return 0
+	}
+
+	// Lines added or removed since the expansion are taken to be inside
+	// the snippet
+	last := active.last + max(strings.Count(text, "\n")-active.lineCount, 0)
+	lines := strings.Split(text, "\n")
+	if active.first > len(lines) {
+		active.bp = nil
+		message("snippet-next-tabstop: the snippet is gone")
+		return 0
+	}
+	last = min(last, len(lines))
+	region := strings.Join(lines[active.first-1:last], "\n")
+
+	start, end, fill, ok := snippet.NextStop(region)
+	if !ok {
+		active.bp = nil
+		message("snippet-next-tabstop: no tab stops left")
+		return 0
+	}
+
+	head := region[:start]
+	line := active.first + strings.Count(head, "\n")
+	col := len(head) - (strings.LastIndexByte(head, '\n') + 1)
+	marker := region[start:end]
+	replaceMarker(line, col, marker, fill)
+
+	rest := region[:start] + fill + region[end:]
+	if _, _, _, more := snippet.NextStop(rest); !more {
+		active.bp = nil
+	}
+	if fill != "" {
+		message("Tab stop %s (default %s)", marker, fill)
+	} else {
+		message("Tab stop %s", marker)
+	}
+	return 1
+}
+
+//export go_snippet_list
+func go_snippet_list(f, n C.int) C.int {
+	lang, dir, snippets, ok := currentSnippets("snippet-list")
+	if !ok {
+		return 0
+	}
+	if len(snippets) == 0 {
+		message("snippet-list: no snippets in %s", dir)
+		return 0
+	}
+
+	width := 0
+	for _, s := range snippets {
+		width = max(width, len(s.Trigger))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s snippets (%s)\n\n", lang, dir)
+	for _, s := range snippets {
+		fmt.Fprintf(&b, "%-*s  %s\n", width, s.Trigger, s.Description)
+	}
+
+	cname := C.CString(listBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	out := C.api_buffer_create(cname)
+	if out == nil {
+		return 0
+	}
+	C.api_buffer_switch(out)
+	C.api_buffer_clear(out)
+	insertText(b.String())
+	C.api_set_point(1, 0)
+	message("%d %s snippets", len(snippets), lang)
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
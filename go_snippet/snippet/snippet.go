@@ -0,0 +1,165 @@
+// Package snippet loads snippet definitions and expands their bodies.
+//
+// A body marks tab stops with $1, $2, ... and ${1:default}; $0 is where
+// the cursor ends up. Expanding fills in the first stop and leaves the
+// markers of the others in the text for NextStop to find.
+package snippet
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snippet is one snippet definition
+type Snippet struct {
+	Trigger     string
+	Description string
+	Body        string
+}
+
+// languages maps file extensions to the snippet directory for them;
+// other extensions use their own name
+var languages = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".hpp":  "cpp",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+	".rs":   "rust",
+	".rb":   "ruby",
+	".sh":   "sh",
+	".bash": "sh",
+	".md":   "markdown",
+	".html": "html",
+	".tex":  "tex",
+	".zig":  "zig",
+	".hs":   "haskell",
+	".yml":  "yaml",
+	".yaml": "yaml",
+}
+
+// Language returns the snippet language of a file, "" if it has none
+func Language(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if lang, ok := languages[ext]; ok {
+		return lang
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// LoadDir reads the snippets of every .yaml and .yml file in dir, sorted
+// by trigger. A missing directory holds no snippets. Files that fail to
+// parse are reported in errs and skipped.
+func LoadDir(dir string) (snippets []Snippet, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		parsed, err := Parse(string(data))
+		if err != nil {
+			errs = append(errs, &os.PathError{Op: "parse", Path: e.Name(), Err: err})
+			continue
+		}
+		snippets = append(snippets, parsed...)
+	}
+	sort.SliceStable(snippets, func(i, j int) bool {
+		return snippets[i].Trigger < snippets[j].Trigger
+	})
+	return snippets, errs
+}
+
+// Match finds the snippet whose trigger ends the text before the cursor,
+// preferring the longest trigger. The trigger must start the line or
+// follow a character that cannot be part of a word.
+func Match(snippets []Snippet, before string) (Snippet, bool) {
+	var best Snippet
+	found := false
+	for _, s := range snippets {
+		if !strings.HasSuffix(before, s.Trigger) || (found && len(s.Trigger) <= len(best.Trigger)) {
+			continue
+		}
+		start := len(before) - len(s.Trigger)
+		if start > 0 && isWordByte(before[start-1]) && isWordByte(s.Trigger[0]) {
+			continue
+		}
+		best, found = s, true
+	}
+	return best, found
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// stopPattern matches $N and ${N:default}
+var stopPattern = regexp.MustCompile(`\$(\d+)|\$\{(\d+)(?::([^}]*))?\}`)
+
+// NextStop finds the next tab stop marker in text: the lowest numbered
+// one from $1 up, else $0. It returns the marker's bounds and the text
+// to put in its place.
+func NextStop(text string) (start, end int, fill string, ok bool) {
+	best := -1
+	for _, m := range stopPattern.FindAllStringSubmatchIndex(text, -1) {
+		numStart, numEnd := m[2], m[3]
+		if numStart < 0 {
+			numStart, numEnd = m[4], m[5]
+		}
+		n, _ := strconv.Atoi(text[numStart:numEnd])
+		// $0 sorts after every other stop
+		if n == 0 {
+			n = int(^uint(0) >> 1)
+		}
+		if best >= 0 && n >= best {
+			continue
+		}
+		best, start, end, fill = n, m[0], m[1], ""
+		if m[6] >= 0 {
+			fill = text[m[6]:m[7]]
+		}
+		ok = true
+	}
+	return start, end, fill, ok
+}
+
+// Expand prepares a body for insertion on a line indented by indent: the
+// lines after the first get the indentation, a final newline is dropped
+// and the first tab stop is filled in. cursor is the byte offset in text
+// where that stop was, or the end of text if the body has none.
+func Expand(body, indent string) (text string, cursor int) {
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	text = strings.Join(lines, "\n")
+
+	start, end, fill, ok := NextStop(text)
+	if !ok {
+		return text, len(text)
+	}
+	return text[:start] + fill + text[end:], start
+}
@@ -0,0 +1,85 @@
+package snippet
+
+import "testing"
+
+const sample = `# Go snippets
+- trigger: fn
+  description: Function declaration
+  body: |
+    func ${1:name}($2) {
+    	$0
+    }
+
+- trigger: "pf"
+  description: 'Printf with a value: it''s quoted'
+  body: fmt.Printf("%v\n", $1)   # a comment
+`
+
+func TestParse(t *testing.T) {
+	snippets, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Snippet{
+		{"fn", "Function declaration", "func ${1:name}($2) {\n\t$0\n}\n"},
+		{"pf", "Printf with a value: it's quoted", `fmt.Printf("%v\n", $1)`},
+	}
+	if len(snippets) != len(want) {
+		t.Fatalf("got %d snippets, want %d", len(snippets), len(want))
+	}
+	for i := range want {
+		if snippets[i] != want[i] {
+			t.Errorf("snippet %d:\ngot  %+v\nwant %+v", i, snippets[i], want[i])
+		}
+	}
+
+	single, err := Parse("trigger: main\nbody: |-\n  int main(void) {\n  }\n")
+	if err != nil || len(single) != 1 || single[0].Body != "int main(void) {\n}" {
+		t.Errorf("single snippet: %+v, %v", single, err)
+	}
+
+	if _, err := Parse("- trigger: x\n"); err == nil {
+		t.Error("snippet without a body parsed")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	text, cursor := Expand("func ${1:name}($2) {\n\t$0\n}\n", "  ")
+	if text != "func name($2) {\n  \t$0\n  }" || cursor != 5 {
+		t.Errorf("expand: %q, cursor %d", text, cursor)
+	}
+
+	start, end, fill, ok := NextStop(text)
+	if !ok || text[start:end] != "$2" || fill != "" {
+		t.Errorf("next stop %q %q %v", text[start:end], fill, ok)
+	}
+	text = text[:start] + text[end:]
+	if start, end, _, ok = NextStop(text); !ok || text[start:end] != "$0" {
+		t.Errorf("last stop %q %v", text[start:end], ok)
+	}
+	if _, _, _, ok := NextStop("no stops"); ok {
+		t.Error("found a stop in plain text")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	snippets := []Snippet{{Trigger: "if"}, {Trigger: "elif"}, {Trigger: "!"}}
+	tests := []struct {
+		before string
+		want   string
+	}{
+		{"    if", "if"},
+		{"x = elif", "elif"},
+		{"gif", ""},
+		{"html!", "!"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if s, ok := Match(snippets, tt.before); ok {
+			got = s.Trigger
+		}
+		if got != tt.want {
+			t.Errorf("%q matched %q, want %q", tt.before, got, tt.want)
+		}
+	}
+}
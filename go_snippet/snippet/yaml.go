@@ -0,0 +1,181 @@
+package snippet
+
+// Snippet files are read with a small YAML subset: a list of mappings (or
+// a single mapping), each key holding a plain, quoted or literal block
+// (|, |- or |+) scalar.
+//
+//	- trigger: fn
+//	  description: Function declaration
+//	  body: |
+//	    func ${1:name}($2) {
+//	    	$0
+//	    }
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse reads the snippets of one YAML file
+func Parse(data string) ([]Snippet, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+
+	var snippets []Snippet
+	keyIndent := -1 // Indentation of the current snippet's keys
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		rest := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(rest)
+		if strings.HasPrefix(rest, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+
+		switch {
+		case rest == "-" || strings.HasPrefix(rest, "- "):
+			snippets = append(snippets, Snippet{})
+			after := strings.TrimLeft(rest[1:], " ")
+			keyIndent = len(raw) - len(after)
+			if after == "" {
+				continue
+			}
+			rest, indent = after, keyIndent
+		case len(snippets) == 0:
+			// A file holding a single snippet
+			snippets = append(snippets, Snippet{})
+			keyIndent = indent
+		}
+		if indent != keyIndent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+
+		key, value, ok := strings.Cut(rest, ":")
+		if !ok || strings.ContainsAny(strings.TrimSpace(key), " \t") {
+			return nil, fmt.Errorf("line %d: expected key: value", i+1)
+		}
+		value = strings.TrimSpace(value)
+
+		var text string
+		var err error
+		if strings.HasPrefix(value, "|") {
+			var used int
+			text, used, err = blockScalar(value, lines[i+1:], keyIndent)
+			i += used
+		} else {
+			text, err = flowScalar(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		s := &snippets[len(snippets)-1]
+		switch strings.TrimSpace(key) {
+		case "trigger":
+			s.Trigger = text
+		case "description":
+			s.Description = text
+		case "body":
+			s.Body = text
+		}
+	}
+
+	for i, s := range snippets {
+		if s.Trigger == "" {
+			return nil, fmt.Errorf("snippet %d has no trigger", i+1)
+		}
+		if s.Body == "" {
+			return nil, fmt.Errorf("snippet %s has no body", s.Trigger)
+		}
+	}
+	return snippets, nil
+}
+
+// flowScalar reads a plain, 'single' or "double" quoted scalar
+func flowScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		for end := 1; end < len(value); end++ {
+			switch value[end] {
+			case '\\':
+				end++
+			case '"':
+				return strconv.Unquote(value[:end+1])
+			}
+		}
+		return "", fmt.Errorf("unterminated string")
+
+	case strings.HasPrefix(value, "'"):
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			if value[i] != '\'' {
+				b.WriteByte(value[i])
+				continue
+			}
+			if i+1 < len(value) && value[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), nil
+		}
+		return "", fmt.Errorf("unterminated string")
+	}
+
+	// A comment needs a space before its #
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// blockScalar reads a literal block scalar from the lines after its key,
+// which must be indented past keyIndent. It returns the text and the
+// number of lines used.
+func blockScalar(header string, lines []string, keyIndent int) (string, int, error) {
+	chomp := strings.TrimSpace(header[1:])
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return "", 0, fmt.Errorf("unsupported block header %q", header)
+	}
+
+	used, blockIndent := 0, -1
+	var body []string
+	for _, line := range lines {
+		rest := strings.TrimLeft(line, " ")
+		indent := len(line) - len(rest)
+		if strings.TrimSpace(line) != "" {
+			if indent <= keyIndent {
+				break
+			}
+			if blockIndent < 0 {
+				blockIndent = indent
+			}
+			if indent < blockIndent {
+				return "", 0, fmt.Errorf("block line less indented than the first")
+			}
+		}
+		used++
+		if len(line) > blockIndent && blockIndent >= 0 {
+			body = append(body, line[blockIndent:])
+		} else {
+			body = append(body, "")
+		}
+	}
+
+	// Blank lines after the block belong to it only with |+
+	kept := len(body)
+	for kept > 0 && strings.TrimSpace(body[kept-1]) == "" {
+		kept--
+	}
+	text := strings.Join(body[:kept], "\n")
+	switch chomp {
+	case "":
+		text += "\n"
+	case "+":
+		text += strings.Repeat("\n", len(body)-kept+1)
+	}
+	return text, used, nil
+}
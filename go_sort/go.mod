@@ -0,0 +1,3 @@
+module go_sort
+
+go 1.21
@@ -0,0 +1,210 @@
+// go_sort - Line sorting for μEmacs
+//
+// Sorts the lines of the region (between mark and point, whole lines) or,
+// when the mark is unset or on the point's line, the whole buffer.
+//
+// Commands:
+//   sort-lines         - Sort lines alphabetically
+//   sort-lines-reverse - Sort lines in descending order
+//   sort-by-length     - Sort lines shortest first
+//   sort-numerically   - Sort lines by the number they start with
+//   sort-by-field      - Sort lines by a whitespace-delimited field, as
+//                        text or as numbers (prompts for both)
+//   sort-unique        - Sort lines and drop duplicates, like sort -u
+//
+// All sorts are stable.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_get_mark(int *line, int *col);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
+extern void api_free(void *ptr);
+*/
+import "C"
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// prompt asks for a line of input, returning false if cancelled
+func prompt(text string) (string, bool) {
+	var buf [1024]C.char
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if C.api_prompt(cText, &buf[0], C.size_t(len(buf))) < 0 {
+		return "", false
+	}
+	return C.GoString(&buf[0]), true
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// replaceText replaces the contents of buffer bp (the current buffer)
+func replaceText(bp unsafe.Pointer, text string) {
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+}
+
+// sortRange returns the 1-based lines, first to last inclusive, to sort
+// out of count: those of the region, or all of them
+func sortRange(count int) (first, last int, region bool) {
+	var line, col, markLine, markCol C.int
+	C.api_get_point(&line, &col)
+	if C.api_get_mark(&markLine, &markCol) == 0 || markLine <= 0 || markLine == line {
+		return 1, count, false
+	}
+	first, last = int(min(line, markLine)), int(max(line, markLine))
+	return max(first, 1), min(last, count), true
+}
+
+// sortLinesWith rewrites the lines to sort with sortFn, which may sort
+// them in place and return a shorter slice
+func sortLinesWith(cmd string, sortFn func([]string) []string) C.int {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("%s: cannot read buffer", cmd)
+		return 0
+	}
+	lines, trailing := splitLines(text)
+	first, last, region := sortRange(len(lines))
+	scope := "buffer"
+	if region {
+		scope = "region"
+	}
+
+	before := lines[first-1 : last]
+	sorted := sortFn(append([]string(nil), before...))
+	if reflect.DeepEqual(sorted, before) {
+		message("%s: %s already sorted", cmd, scope)
+		return 1
+	}
+
+	out := append(append(append([]string(nil), lines[:first-1]...), sorted...), lines[last:]...)
+	replaceText(bp, joinLines(out, trailing))
+	C.api_set_point(C.int(first), 0)
+	if removed := len(before) - len(sorted); removed > 0 {
+		message("%s: sorted %d lines, removed %d duplicates", cmd, len(sorted), removed)
+	} else {
+		message("%s: sorted %d lines", cmd, len(sorted))
+	}
+	return 1
+}
+
+//export go_sort_lines
+func go_sort_lines(f, n C.int) C.int {
+	return sortLinesWith("sort-lines", func(l []string) []string {
+		sortLexical(l, false)
+		return l
+	})
+}
+
+//export go_sort_lines_reverse
+func go_sort_lines_reverse(f, n C.int) C.int {
+	return sortLinesWith("sort-lines-reverse", func(l []string) []string {
+		sortLexical(l, true)
+		return l
+	})
+}
+
+//export go_sort_by_length
+func go_sort_by_length(f, n C.int) C.int {
+	return sortLinesWith("sort-by-length", func(l []string) []string {
+		sortByLength(l)
+		return l
+	})
+}
+
+//export go_sort_numerically
+func go_sort_numerically(f, n C.int) C.int {
+	return sortLinesWith("sort-numerically", func(l []string) []string {
+		sortNumeric(l)
+		return l
+	})
+}
+
+//export go_sort_by_field
+func go_sort_by_field(f, n C.int) C.int {
+	input, ok := prompt("Sort by field (1-based): ")
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	num, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || num < 1 {
+		message("sort-by-field: field must be a number from 1")
+		return 0
+	}
+	input, ok = prompt(fmt.Sprintf("Compare field %d as (t)ext or (n)umbers [t]: ", num))
+	if !ok {
+		message("Cancelled")
+		return 0
+	}
+	var numeric bool
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "", "t", "text":
+	case "n", "number", "numbers", "numeric":
+		numeric = true
+	default:
+		message("sort-by-field: answer t or n")
+		return 0
+	}
+
+	return sortLinesWith("sort-by-field", func(l []string) []string {
+		sortByField(l, num, numeric)
+		return l
+	})
+}
+
+//export go_sort_unique
+func go_sort_unique(f, n C.int) C.int {
+	return sortLinesWith("sort-unique", uniqueSorted)
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
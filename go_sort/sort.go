@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// leadingNumber matches the number a line starts with, after any blanks
+var leadingNumber = regexp.MustCompile(`^\s*([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)`)
+
+// splitLines splits text into lines, reporting whether it ended with a
+// newline (which does not start another line)
+func splitLines(text string) ([]string, bool) {
+	trailing := strings.HasSuffix(text, "\n")
+	if trailing {
+		text = text[:len(text)-1]
+	}
+	return strings.Split(text, "\n"), trailing
+}
+
+// joinLines undoes splitLines
+func joinLines(lines []string, trailing bool) string {
+	text := strings.Join(lines, "\n")
+	if trailing {
+		text += "\n"
+	}
+	return text
+}
+
+// sortLexical sorts lines in byte order, descending if reverse is set
+func sortLexical(lines []string, reverse bool) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		if reverse {
+			return lines[i] > lines[j]
+		}
+		return lines[i] < lines[j]
+	})
+}
+
+// sortByLength sorts lines shortest first, in characters
+func sortByLength(lines []string) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return len([]rune(lines[i])) < len([]rune(lines[j]))
+	})
+}
+
+// number parses the number s starts with
+func number(s string) (float64, bool) {
+	m := leadingNumber.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	return n, err == nil
+}
+
+// sortNumeric sorts lines by the number each starts with, smallest first.
+// Lines without one go last, in their original order.
+func sortNumeric(lines []string) {
+	sortByKey(lines, func(s string) string { return s }, true)
+}
+
+// field returns the 1-based whitespace-delimited field n of s, "" if it
+// has fewer fields
+func field(s string, n int) string {
+	fields := strings.Fields(s)
+	if n < 1 || n > len(fields) {
+		return ""
+	}
+	return fields[n-1]
+}
+
+// sortByField sorts lines by their field n, as text or as numbers
+func sortByField(lines []string, n int, numeric bool) {
+	sortByKey(lines, func(s string) string { return field(s, n) }, numeric)
+}
+
+// sortByKey sorts lines by key(line), compared as text or as numbers;
+// lines whose key is not a number go after the rest when numeric
+func sortByKey(lines []string, key func(string) string, numeric bool) {
+	if !numeric {
+		sort.SliceStable(lines, func(i, j int) bool {
+			return key(lines[i]) < key(lines[j])
+		})
+		return
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		a, aok := number(key(lines[i]))
+		b, bok := number(key(lines[j]))
+		if aok != bok {
+			return aok
+		}
+		return aok && a < b
+	})
+}
+
+// uniqueSorted sorts lines and drops repeats, like sort -u
+func uniqueSorted(lines []string) []string {
+	sortLexical(lines, false)
+	out := lines[:0]
+	for i, l := range lines {
+		if i == 0 || l != lines[i-1] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSorts(t *testing.T) {
+	lines, trailing := splitLines("pear 3\napple 10\nfig\napple 10\nkiwi 2.5\n")
+	if !trailing || len(lines) != 5 {
+		t.Fatalf("split: %q %v", lines, trailing)
+	}
+
+	tests := []struct {
+		name string
+		sort func([]string) []string
+		want []string
+	}{
+		{"lexical", func(l []string) []string { sortLexical(l, false); return l },
+			[]string{"apple 10", "apple 10", "fig", "kiwi 2.5", "pear 3"}},
+		{"reverse", func(l []string) []string { sortLexical(l, true); return l },
+			[]string{"pear 3", "kiwi 2.5", "fig", "apple 10", "apple 10"}},
+		{"length", func(l []string) []string { sortByLength(l); return l },
+			[]string{"fig", "pear 3", "apple 10", "apple 10", "kiwi 2.5"}},
+		{"field 2 numeric", func(l []string) []string { sortByField(l, 2, true); return l },
+			[]string{"kiwi 2.5", "pear 3", "apple 10", "apple 10", "fig"}},
+		{"field 2 text", func(l []string) []string { sortByField(l, 2, false); return l },
+			[]string{"fig", "apple 10", "apple 10", "kiwi 2.5", "pear 3"}},
+		{"unique", uniqueSorted,
+			[]string{"apple 10", "fig", "kiwi 2.5", "pear 3"}},
+	}
+	for _, tt := range tests {
+		got := tt.sort(append([]string(nil), lines...))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	numeric := []string{"10 ten", "x", "-1.5 minus", "  2 two", "1e2 hundred"}
+	sortNumeric(numeric)
+	if want := []string{"-1.5 minus", "  2 two", "10 ten", "1e2 hundred", "x"}; !reflect.DeepEqual(numeric, want) {
+		t.Errorf("numeric: got %q, want %q", numeric, want)
+	}
+
+	if got := joinLines([]string{"a", "b"}, true); got != "a\nb\n" {
+		t.Errorf("join: %q", got)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+ * bridge.c - C/CGO Bridge for Go Spell Extension
+ *
+ * API Version: 4 (ABI-Stable Named Lookup)
+ *
+ * Checks spelling with hunspell or aspell in pipe mode, listing
+ * misspelled words and their suggestions in *spell*.
+ */
+
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <uep/extension_api.h>
+#include "_cgo_export.h"
+
+/*
+ * Minimal types needed for CGO interop.
+ */
+typedef int (*cmd_fn_t)(int, int);
+
+/*
+ * Function pointer types for the API functions we use
+ */
+typedef void (*message_fn)(const char*, ...);
+typedef void (*log_fn)(const char*, ...);
+typedef void *(*current_buffer_fn)(void);
+typedef void *(*find_buffer_fn)(const char*);
+typedef const char *(*buffer_name_fn)(void*);
+typedef char *(*buffer_contents_fn)(void*, size_t*);
+typedef void (*get_point_fn)(int*, int*);
+typedef void (*set_point_fn)(int, int);
+typedef void *(*buffer_create_fn)(const char*);
+typedef int (*buffer_switch_fn)(void*);
+typedef int (*buffer_clear_fn)(void*);
+typedef int (*buffer_insert_fn)(const char*, size_t);
+typedef int (*prompt_yn_fn)(const char*);
+typedef void (*free_fn)(void*);
+typedef const char *(*config_string_fn)(const char*, const char*, const char*);
+typedef int (*register_command_fn)(const char*, cmd_fn_t);
+typedef int (*unregister_command_fn)(const char*);
+
+/*
+ * Local API struct - only the functions we actually use
+ */
+static struct {
+    message_fn message;
+    log_fn log_info;
+    log_fn log_error;
+    current_buffer_fn current_buffer;
+    find_buffer_fn find_buffer;
+    buffer_name_fn buffer_name;
+    buffer_contents_fn buffer_contents;
+    get_point_fn get_point;
+    set_point_fn set_point;
+    buffer_create_fn buffer_create;
+    buffer_switch_fn buffer_switch;
+    buffer_clear_fn buffer_clear;
+    buffer_insert_fn buffer_insert;
+    prompt_yn_fn prompt_yn;
+    free_fn free;
+    config_string_fn config_string;
+    register_command_fn register_command;
+    unregister_command_fn unregister_command;
+} api;
+
+/* Extension name for config lookups */
+static const char *EXT_NAME = "go_spell";
+
+/* ============================================================================
+ * API wrappers for Go
+ * ============================================================================ */
+
+void api_message(const char *msg) {
+    if (api.message) api.message("%s", msg);
+}
+
+void api_log_info(const char *msg) {
+    if (api.log_info) api.log_info("%s", msg);
+}
+
+void api_log_error(const char *msg) {
+    if (api.log_error) api.log_error("%s", msg);
+}
+
+void* api_current_buffer(void) {
+    if (api.current_buffer) return api.current_buffer();
+    return NULL;
+}
+
+void* api_find_buffer(const char *name) {
+    if (api.find_buffer) return api.find_buffer(name);
+    return NULL;
+}
+
+const char* api_buffer_name(void *bp) {
+    if (api.buffer_name) return api.buffer_name(bp);
+    return NULL;
+}
+
+char* api_buffer_contents(void *bp, size_t *len) {
+    if (api.buffer_contents) return api.buffer_contents(bp, len);
+    return NULL;
+}
+
+void api_get_point(int *line, int *col) {
+    if (api.get_point) api.get_point(line, col);
+}
+
+void api_set_point(int line, int col) {
+    if (api.set_point) api.set_point(line, col);
+}
+
+void* api_buffer_create(const char *name) {
+    if (api.buffer_create) return api.buffer_create(name);
+    return NULL;
+}
+
+int api_buffer_switch(void *bp) {
+    if (api.buffer_switch) return api.buffer_switch(bp);
+    return 0;
+}
+
+int api_buffer_clear(void *bp) {
+    if (api.buffer_clear) return api.buffer_clear(bp);
+    return 0;
+}
+
+int api_buffer_insert(const char *text, size_t len) {
+    if (api.buffer_insert) return api.buffer_insert(text, len);
+    return 0;
+}
+
+int api_prompt_yn(const char *prompt) {
+    if (api.prompt_yn) return api.prompt_yn(prompt);
+    return 0;
+}
+
+void api_free(void *ptr) {
+    if (api.free) api.free(ptr);
+}
+
+const char* api_config_string(const char *key, const char *default_val) {
+    if (api.config_string) return api.config_string(EXT_NAME, key, default_val);
+    return default_val;
+}
+
+/* ============================================================================
+ * Command wrappers (call Go functions)
+ * ============================================================================ */
+
+static int cmd_spell_check(int f, int n) { return go_spell_check(f, n); }
+static int cmd_spell_next_error(int f, int n) { return go_spell_next_error(f, n); }
+static int cmd_spell_fix(int f, int n) { return go_spell_fix(f, n); }
+static int cmd_spell_add(int f, int n) { return go_spell_add(f, n); }
+
+/* ============================================================================
+ * Extension lifecycle
+ * ============================================================================ */
+
+typedef struct {
+    int api_version;
+    const char *name;
+    const char *version;
+    const char *description;
+    int (*init)(void*);
+    void (*cleanup)(void);
+} uemacs_extension;
+
+static int spell_init_c(void *editor_api_raw) {
+    struct uemacs_api *editor_api = (struct uemacs_api *)editor_api_raw;
+
+    /*
+     * Use get_function() for ABI stability.
+     */
+    if (!editor_api->get_function) {
+        fprintf(stderr, "go_spell: Requires μEmacs with get_function() support\n");
+        return -1;
+    }
+
+    /* Look up all API functions by name */
+    #define LOOKUP(name) editor_api->get_function(#name)
+
+    api.message = (message_fn)LOOKUP(message);
+    api.log_info = (log_fn)LOOKUP(log_info);
+    api.log_error = (log_fn)LOOKUP(log_error);
+    api.current_buffer = (current_buffer_fn)LOOKUP(current_buffer);
+    api.find_buffer = (find_buffer_fn)LOOKUP(find_buffer);
+    api.buffer_name = (buffer_name_fn)LOOKUP(buffer_name);
+    api.buffer_contents = (buffer_contents_fn)LOOKUP(buffer_contents);
+    api.get_point = (get_point_fn)LOOKUP(get_point);
+    api.set_point = (set_point_fn)LOOKUP(set_point);
+    api.buffer_create = (buffer_create_fn)LOOKUP(buffer_create);
+    api.buffer_switch = (buffer_switch_fn)LOOKUP(buffer_switch);
+    api.buffer_clear = (buffer_clear_fn)LOOKUP(buffer_clear);
+    api.buffer_insert = (buffer_insert_fn)LOOKUP(buffer_insert);
+    api.prompt_yn = (prompt_yn_fn)LOOKUP(prompt_yn);
+    api.free = (free_fn)LOOKUP(free);
+    api.config_string = (config_string_fn)LOOKUP(config_string);
+    api.register_command = (register_command_fn)LOOKUP(register_command);
+    api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+
+    #undef LOOKUP
+
+    /* Verify critical functions were found */
+    if (!api.register_command || !api.log_info) {
+        fprintf(stderr, "go_spell: Missing critical API functions\n");
+        return -1;
+    }
+
+    /* Register commands */
+    api.register_command("spell-check", cmd_spell_check);
+    api.register_command("spell-next-error", cmd_spell_next_error);
+    api.register_command("spell-fix", cmd_spell_fix);
+    api.register_command("spell-add", cmd_spell_add);
+
+    api.log_info("go_spell: Extension loaded");
+    api.log_info("  Commands: spell-check, spell-next-error, spell-fix, spell-add");
+    return 0;
+}
+
+static void spell_cleanup_c(void) {
+    /* Unregister commands */
+    if (api.unregister_command) {
+        api.unregister_command("spell-check");
+        api.unregister_command("spell-next-error");
+        api.unregister_command("spell-fix");
+        api.unregister_command("spell-add");
+    }
+
+    /* Cleanup Go side */
+    go_spell_cleanup();
+}
+
+/* ============================================================================
+ * Extension entry point
+ * ============================================================================ */
+
+static uemacs_extension ext = {
+    .api_version = 4,
+    .name = "go_spell",
+    .version = "1.0.0",
+    .description = "Spellcheck with hunspell/aspell",
+    .init = spell_init_c,
+    .cleanup = spell_cleanup_c,
+};
+
+uemacs_extension* uemacs_extension_entry(void) {
+    return &ext;
+}
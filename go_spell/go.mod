@@ -0,0 +1,3 @@
+module go_spell
+
+go 1.21
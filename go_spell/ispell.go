@@ -0,0 +1,231 @@
+package main
+
+// The checker speaks the ispell pipe protocol that hunspell -a and
+// aspell -a (aspell pipe) both implement: after a banner line, each line
+// of input is answered with one result line per word and an empty line.
+//
+//	*                      correct
+//	+ ROOT                 correct, via affixes
+//	-                      correct, as a compound
+//	& word N offset: s, s  misspelled, with N suggestions
+//	# word offset          misspelled, no suggestions
+//
+// Input lines are sent prefixed with ^ so they are never read as
+// commands; *word adds word to the personal dictionary and # saves it.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Miss is a misspelled word in a line of text
+type Miss struct {
+	Word        string
+	Col         int // Byte offset of the word in its line
+	Suggestions []string
+}
+
+// parseResult parses one result line; misspelled is false for lines that
+// report a correctly spelled word
+func parseResult(line string) (word string, suggestions []string, misspelled bool, err error) {
+	switch {
+	case line == "" || line[0] == '*' || line[0] == '+' || line[0] == '-':
+		return "", nil, false, nil
+	case line[0] == '#':
+		fields := strings.Fields(line[1:])
+		if len(fields) < 1 {
+			return "", nil, false, fmt.Errorf("bad result %q", line)
+		}
+		return fields[0], nil, true, nil
+	case line[0] == '&' || line[0] == '?':
+		head, list, found := strings.Cut(line[1:], ":")
+		fields := strings.Fields(head)
+		if !found || len(fields) < 1 {
+			return "", nil, false, fmt.Errorf("bad result %q", line)
+		}
+		for _, s := range strings.Split(list, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				suggestions = append(suggestions, s)
+			}
+		}
+		return fields[0], suggestions, true, nil
+	}
+	return "", nil, false, fmt.Errorf("unexpected result %q", line)
+}
+
+// locate finds word as a whole word in line at or after byte offset from,
+// returning -1 if it is not there. The checker reports offsets in
+// characters, so misses are placed by searching in order instead.
+func locate(line, word string, from int) int {
+	for from <= len(line) {
+		i := strings.Index(line[from:], word)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(word)
+		if !isWordByteBefore(line, i) && !isWordByteAfter(line, end) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+func isWordByteBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r)
+}
+
+func isWordByteAfter(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r)
+}
+
+// wordAt returns the bounds of the word around byte offset i of text,
+// letters and inner apostrophes making up words
+func wordAt(text string, i int) (start, end int, ok bool) {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || r == '\'' }
+	start, end = i, i
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		if !isWord(r) {
+			break
+		}
+		start -= size
+	}
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isWord(r) {
+			break
+		}
+		end += size
+	}
+	for start < end && text[start] == '\'' {
+		start++
+	}
+	for end > start && text[end-1] == '\'' {
+		end--
+	}
+	return start, end, start < end
+}
+
+// Checker is a running hunspell or aspell in pipe mode
+type Checker struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// startChecker runs hunspell -a, or aspell -a when there is no hunspell,
+// for language (hunspell's default dictionary when empty)
+func startChecker(language string) (*Checker, error) {
+	var cmd *exec.Cmd
+	switch {
+	case hasProgram("hunspell"):
+		args := []string{"-a"}
+		if language != "" {
+			args = append(args, "-d", language)
+		}
+		cmd = exec.Command("hunspell", args...)
+	case hasProgram("aspell"):
+		args := []string{"-a"}
+		if language != "" {
+			args = append(args, "--lang="+language)
+		}
+		cmd = exec.Command("aspell", args...)
+	default:
+		return nil, fmt.Errorf("neither hunspell nor aspell is installed")
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	c := &Checker{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}
+
+	// The banner, or nothing if the dictionary failed to load
+	if _, err := c.stdout.ReadString('\n'); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s exited; is the %q dictionary installed?", cmd.Path, language)
+	}
+	return c, nil
+}
+
+// hasProgram reports whether name is on PATH
+func hasProgram(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Check returns the misspelled words of one line of text
+func (c *Checker) Check(line string) ([]Miss, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Lines come split on \n; a stray \r would end the input line early
+	line = strings.ReplaceAll(line, "\r", "")
+	if _, err := fmt.Fprintf(c.stdin, "^%s\n", line); err != nil {
+		return nil, err
+	}
+
+	var misses []Miss
+	from := 0
+	for {
+		result, err := c.stdout.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		result = strings.TrimRight(result, "\r\n")
+		if result == "" {
+			return misses, nil
+		}
+		word, suggestions, bad, err := parseResult(result)
+		if err != nil {
+			return nil, err
+		}
+		if !bad {
+			continue
+		}
+		col := locate(line, word, from)
+		if col < 0 {
+			continue
+		}
+		misses = append(misses, Miss{Word: word, Col: col, Suggestions: suggestions})
+		from = col + len(word)
+	}
+}
+
+// Add adds word to the personal dictionary and saves it
+func (c *Checker) Add(word string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.stdin, "*%s\n#\n", word)
+	return err
+}
+
+// Close stops the checker
+func (c *Checker) Close() {
+	c.stdin.Close()
+	c.cmd.Wait()
+}
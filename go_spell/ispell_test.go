@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResult(t *testing.T) {
+	word, suggestions, misspelled, err := parseResult("& teh 3 4: the, tech, ten")
+	if err != nil || !misspelled || word != "teh" || !reflect.DeepEqual(suggestions, []string{"the", "tech", "ten"}) {
+		t.Errorf("& line: %q %q %v %v", word, suggestions, misspelled, err)
+	}
+	word, suggestions, misspelled, err = parseResult("# qzxv 0")
+	if err != nil || !misspelled || word != "qzxv" || suggestions != nil {
+		t.Errorf("# line: %q %q %v %v", word, suggestions, misspelled, err)
+	}
+	for _, ok := range []string{"*", "+ WALK", "-"} {
+		if _, _, misspelled, err := parseResult(ok); err != nil || misspelled {
+			t.Errorf("%q: misspelled %v, %v", ok, misspelled, err)
+		}
+	}
+	if _, _, _, err := parseResult("@(#) International Ispell"); err == nil {
+		t.Error("banner parsed as a result")
+	}
+}
+
+func TestLocate(t *testing.T) {
+	const line = "thee cat sat on thee mat, the end"
+	if i := locate(line, "the", 0); i != 26 {
+		t.Errorf("whole word the at %d, want 26", i)
+	}
+	if i := locate(line, "thee", 1); i != 16 {
+		t.Errorf("second thee at %d, want 16", i)
+	}
+	if i := locate(line, "dog", 0); i != -1 {
+		t.Errorf("dog at %d, want -1", i)
+	}
+}
+
+func TestWordAt(t *testing.T) {
+	const text = "it's  'quoted' naïve"
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "it's"},
+		{3, "it's"},
+		{9, "quoted"},
+		{17, "naïve"},
+	}
+	for _, tt := range tests {
+		start, end, ok := wordAt(text, tt.i)
+		if !ok || text[start:end] != tt.want {
+			t.Errorf("word at %d = %q, want %q", tt.i, text[start:end], tt.want)
+		}
+	}
+	if _, _, ok := wordAt(text, 5); ok {
+		t.Error("found a word between the spaces")
+	}
+}
@@ -0,0 +1,390 @@
+// go_spell - Spellcheck with hunspell or aspell for μEmacs
+//
+// Keeps hunspell -a (or aspell -a) running and feeds it the buffer line
+// by line, listing the misspelled words and their suggestions in *spell*.
+//
+// Commands:
+//   spell-check      - Check the current buffer into *spell*
+//   spell-next-error - Jump to the next misspelled word of the last check
+//   spell-fix        - Offer the suggestions for the word at point one by
+//                      one and replace it with the one accepted
+//   spell-add        - Add the word at point to the personal dictionary
+//
+// The dictionary is language in [extension.go_spell] of settings.toml
+// (default en_US).
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern void *api_find_buffer(const char *name);
+extern const char *api_buffer_name(void *bp);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern int api_prompt_yn(const char *prompt);
+extern void api_free(void *ptr);
+extern const char *api_config_string(const char *key, const char *default_val);
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// spellBuffer lists the misspelled words of the last check
+const spellBuffer = "*spell*"
+
+// SpellError is a misspelled word found by spell-check
+type SpellError struct {
+	Line int // 1-based
+	Miss
+}
+
+// checker runs for the session once started
+var checker *Checker
+
+// State of the last check: the buffer checked, its misspelled words and
+// the one last jumped to (-1 before the first)
+var (
+	checkedName string
+	spellErrors []SpellError
+	errorIndex  = -1
+)
+
+// defaultLanguage is used when settings.toml sets no language
+const defaultLanguage = "en_US"
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// configuredLanguage returns language from [extension.go_spell], or
+// defaultLanguage
+func configuredLanguage() string {
+	ckey := C.CString("language")
+	defer C.free(unsafe.Pointer(ckey))
+	cdefault := C.CString(defaultLanguage)
+	defer C.free(unsafe.Pointer(cdefault))
+	if v := C.api_config_string(ckey, cdefault); v != nil {
+		if lang := strings.TrimSpace(C.GoString(v)); lang != "" {
+			return lang
+		}
+	}
+	return defaultLanguage
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// replaceText replaces the contents of buffer bp (the current buffer)
+func replaceText(bp unsafe.Pointer, text string) {
+	C.api_buffer_clear(bp)
+	if len(text) > 0 {
+		ctext := C.CString(text)
+		C.api_buffer_insert(ctext, C.size_t(len(text)))
+		C.free(unsafe.Pointer(ctext))
+	}
+}
+
+// bufferName returns the name of buffer bp
+func bufferName(bp unsafe.Pointer) string {
+	if cname := C.api_buffer_name(bp); cname != nil {
+		return C.GoString(cname)
+	}
+	return ""
+}
+
+// getChecker returns the running checker, starting it if need be
+func getChecker(cmd string) (*Checker, bool) {
+	if checker != nil {
+		return checker, true
+	}
+	c, err := startChecker(configuredLanguage())
+	if err != nil {
+		message("%s: %v", cmd, err)
+		return nil, false
+	}
+	checker = c
+	return checker, true
+}
+
+// checkFailed reports a checker that stopped answering and drops it, so
+// the next command starts a new one
+func checkFailed(cmd string, err error) C.int {
+	checker.Close()
+	checker = nil
+	message("%s: spell checker failed: %v", cmd, err)
+	return 0
+}
+
+// lineColToOffset converts a 1-based line and 0-based byte column of text
+// to a byte offset, clamped to the text
+func lineColToOffset(text string, line, col int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	end := len(text)
+	if i := strings.IndexByte(text[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return min(offset+max(col, 0), end)
+}
+
+// offsetToLineCol converts a byte offset of text to a 1-based line and a
+// 0-based byte column
+func offsetToLineCol(text string, offset int) (line, col int) {
+	before := text[:min(offset, len(text))]
+	line = strings.Count(before, "\n") + 1
+	col = len(before) - (strings.LastIndexByte(before, '\n') + 1)
+	return line, col
+}
+
+// describe renders a misspelled word with its suggestions
+func describe(e SpellError) string {
+	if len(e.Suggestions) == 0 {
+		return e.Word + " — no suggestions"
+	}
+	return e.Word + " — " + strings.Join(e.Suggestions, ", ")
+}
+
+//export go_spell_check
+func go_spell_check(f, n C.int) C.int {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return 0
+	}
+	name := bufferName(bp)
+	if name == spellBuffer {
+		message("spell-check: switch to the buffer to check first")
+		return 0
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("spell-check: cannot read buffer")
+		return 0
+	}
+	c, ok := getChecker("spell-check")
+	if !ok {
+		return 0
+	}
+
+	var found []SpellError
+	for i, line := range strings.Split(text, "\n") {
+		misses, err := c.Check(line)
+		if err != nil {
+			return checkFailed("spell-check", err)
+		}
+		for _, m := range misses {
+			found = append(found, SpellError{Line: i + 1, Miss: m})
+		}
+	}
+	checkedName, spellErrors, errorIndex = name, found, -1
+
+	if len(found) == 0 {
+		message("spell-check: no misspelled words in %s", name)
+		return 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spelling of %s: %d misspelled words\n\n", name, len(found))
+	for _, e := range found {
+		fmt.Fprintf(&b, "%d:%d: %s\n", e.Line, e.Col+1, describe(e))
+	}
+	cname := C.CString(spellBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	out := C.api_buffer_create(cname)
+	if out == nil {
+		return 0
+	}
+	C.api_buffer_switch(out)
+	replaceText(out, b.String())
+	C.api_set_point(1, 0)
+	message("spell-check: %d misspelled words (spell-next-error to visit them)", len(found))
+	return 1
+}
+
+//export go_spell_next_error
+func go_spell_next_error(f, n C.int) C.int {
+	if len(spellErrors) == 0 {
+		message("spell-next-error: no misspelled words (run spell-check)")
+		return 0
+	}
+	cname := C.CString(checkedName)
+	bp := C.api_find_buffer(cname)
+	C.free(unsafe.Pointer(cname))
+	if bp == nil {
+		message("spell-next-error: buffer %s is gone", checkedName)
+		return 0
+	}
+
+	// In the checked buffer, go to the first error after point; elsewhere
+	// carry on from the last one visited
+	next := errorIndex + 1
+	if C.api_current_buffer() == bp {
+		var line, col C.int
+		C.api_get_point(&line, &col)
+		next = len(spellErrors)
+		for i, e := range spellErrors {
+			if e.Line > int(line) || (e.Line == int(line) && e.Col > int(col)) {
+				next = i
+				break
+			}
+		}
+	}
+	if next >= len(spellErrors) {
+		message("No more misspelled words")
+		return 0
+	}
+
+	errorIndex = next
+	e := spellErrors[next]
+	C.api_buffer_switch(bp)
+	C.api_set_point(C.int(e.Line), C.int(e.Col))
+	message("%s (%d/%d)", describe(e), next+1, len(spellErrors))
+	return 1
+}
+
+// wordAtPoint returns the current buffer, its text and the bounds of the
+// word at point
+func wordAtPoint(cmd string) (bp unsafe.Pointer, text string, start, end int, ok bool) {
+	bp = C.api_current_buffer()
+	if bp == nil {
+		return nil, "", 0, 0, false
+	}
+	text, ok = bufferText(bp)
+	if !ok {
+		message("%s: cannot read buffer", cmd)
+		return nil, "", 0, 0, false
+	}
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	start, end, ok = wordAt(text, lineColToOffset(text, int(line), int(col)))
+	if !ok {
+		message("%s: no word at point", cmd)
+	}
+	return bp, text, start, end, ok
+}
+
+// forget drops the errors of the last check that drop picks
+func forget(drop func(SpellError) bool) {
+	kept := spellErrors[:0]
+	for i, e := range spellErrors {
+		if drop(e) {
+			if i <= errorIndex {
+				errorIndex--
+			}
+			continue
+		}
+		kept = append(kept, e)
+	}
+	spellErrors = kept
+}
+
+//export go_spell_fix
+func go_spell_fix(f, n C.int) C.int {
+	bp, text, start, end, ok := wordAtPoint("spell-fix")
+	if !ok {
+		return 0
+	}
+	c, ok := getChecker("spell-fix")
+	if !ok {
+		return 0
+	}
+	word := text[start:end]
+	misses, err := c.Check(word)
+	if err != nil {
+		return checkFailed("spell-fix", err)
+	}
+	if len(misses) == 0 {
+		message("\"%s\" is spelled correctly", word)
+		return 1
+	}
+	if len(misses[0].Suggestions) == 0 {
+		message("spell-fix: no suggestions for \"%s\"", word)
+		return 0
+	}
+
+	for _, s := range misses[0].Suggestions {
+		cq := C.CString(fmt.Sprintf("Replace \"%s\" with \"%s\"? ", word, s))
+		yes := C.api_prompt_yn(cq)
+		C.free(unsafe.Pointer(cq))
+		if yes == 0 {
+			continue
+		}
+
+		replaceText(bp, text[:start]+s+text[end:])
+		line, col := offsetToLineCol(text, start)
+		C.api_set_point(C.int(line), C.int(col+len(s)))
+		if bufferName(bp) == checkedName {
+			forget(func(e SpellError) bool { return e.Line == line && e.Col == col })
+		}
+		message("Replaced \"%s\" with \"%s\"", word, s)
+		return 1
+	}
+	message("\"%s\" left as it is", word)
+	return 0
+}
+
+//export go_spell_add
+func go_spell_add(f, n C.int) C.int {
+	_, text, start, end, ok := wordAtPoint("spell-add")
+	if !ok {
+		return 0
+	}
+	c, ok := getChecker("spell-add")
+	if !ok {
+		return 0
+	}
+	word := text[start:end]
+	if err := c.Add(word); err != nil {
+		return checkFailed("spell-add", err)
+	}
+	forget(func(e SpellError) bool { return e.Word == word })
+	message("Added \"%s\" to the personal dictionary", word)
+	return 1
+}
+
+//export go_spell_cleanup
+func go_spell_cleanup() {
+	if checker != nil {
+		checker.Close()
+		checker = nil
+	}
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}
@@ -18,6 +18,7 @@
  * Minimal types needed for CGO interop.
  */
 typedef int (*cmd_fn_t)(int, int);
+typedef bool (*event_fn_t)(void*, void*);
 
 /*
  * Function pointer types for the API functions we use
@@ -33,9 +34,12 @@ typedef int (*buffer_insert_fn)(const char*, size_t);
 typedef void (*get_point_fn)(int*, int*);
 typedef void (*set_point_fn)(int, int);
 typedef void (*update_display_fn)(void);
+typedef int (*prompt_fn)(const char*, char*, size_t);
 typedef int (*prompt_yn_fn)(const char*);
 typedef int (*register_command_fn)(const char*, cmd_fn_t);
 typedef int (*unregister_command_fn)(const char*);
+typedef int (*on_fn)(const char*, event_fn_t, void*, int);
+typedef int (*off_fn)(const char*, event_fn_t);
 
 /*
  * Local API struct - only the functions we actually use
@@ -53,9 +57,12 @@ static struct {
     get_point_fn get_point;
     set_point_fn set_point;
     update_display_fn update_display;
+    prompt_fn prompt;
     prompt_yn_fn prompt_yn;
     register_command_fn register_command;
     unregister_command_fn unregister_command;
+    on_fn on;
+    off_fn off;
 } api;
 
 /* ============================================================================
@@ -116,6 +123,11 @@ void api_update_display(void) {
     if (api.update_display) api.update_display();
 }
 
+int api_prompt(const char *prompt, char *buf, size_t buflen) {
+    if (api.prompt) return api.prompt(prompt, buf, buflen);
+    return -1;
+}
+
 int api_prompt_yn(const char *prompt) {
     if (api.prompt_yn) return api.prompt_yn(prompt);
     return 0;
@@ -129,6 +141,10 @@ static int cmd_sudoku_new(int f, int n) {
     return GoSudokuNew(f, n);
 }
 
+static int cmd_sudoku_generate(int f, int n) {
+    return GoSudokuGenerate(f, n);
+}
+
 static int cmd_sudoku_check(int f, int n) {
     return GoSudokuCheck(f, n);
 }
@@ -145,6 +161,48 @@ static int cmd_sudoku_reset(int f, int n) {
     return GoSudokuReset(f, n);
 }
 
+static int cmd_sudoku_toggle_pencil(int f, int n) {
+    return GoSudokuTogglePencil(f, n);
+}
+
+static int cmd_sudoku_undo(int f, int n) {
+    return GoSudokuUndo(f, n);
+}
+
+static int cmd_sudoku_redo(int f, int n) {
+    return GoSudokuRedo(f, n);
+}
+
+static int cmd_sudoku_import(int f, int n) {
+    return GoSudokuImport(f, n);
+}
+
+static int cmd_sudoku_export(int f, int n) {
+    return GoSudokuExport(f, n);
+}
+
+static int cmd_sudoku_pause(int f, int n) {
+    return GoSudokuPause(f, n);
+}
+
+static int cmd_sudoku_resume(int f, int n) {
+    return GoSudokuResume(f, n);
+}
+
+/* ============================================================================
+ * Event handlers
+ * ============================================================================ */
+
+/* Keys in *sudoku* move the highlighted cell */
+static bool on_input_key(void *event_raw, void *user_data) {
+    (void)user_data;
+    uemacs_event_t *event = event_raw;
+    if (!event || !event->data) return false;
+
+    int key = *(int *)event->data;
+    return GoSudokuCursorMoved(key) != 0;
+}
+
 /* ============================================================================
  * Extension lifecycle
  * ============================================================================ */
@@ -184,9 +242,12 @@ static int sudoku_init(void *editor_api_raw) {
     api.get_point = (get_point_fn)LOOKUP(get_point);
     api.set_point = (set_point_fn)LOOKUP(set_point);
     api.update_display = (update_display_fn)LOOKUP(update_display);
+    api.prompt = (prompt_fn)LOOKUP(prompt);
     api.prompt_yn = (prompt_yn_fn)LOOKUP(prompt_yn);
     api.register_command = (register_command_fn)LOOKUP(register_command);
     api.unregister_command = (unregister_command_fn)LOOKUP(unregister_command);
+    api.on = (on_fn)LOOKUP(on);
+    api.off = (off_fn)LOOKUP(off);
 
     #undef LOOKUP
 
@@ -198,29 +259,56 @@ static int sudoku_init(void *editor_api_raw) {
 
     /* Register commands */
     api.register_command("sudoku-new", cmd_sudoku_new);
+    api.register_command("sudoku-generate", cmd_sudoku_generate);
     api.register_command("sudoku-check", cmd_sudoku_check);
     api.register_command("sudoku-hint", cmd_sudoku_hint);
     api.register_command("sudoku-solve", cmd_sudoku_solve);
     api.register_command("sudoku-reset", cmd_sudoku_reset);
+    api.register_command("sudoku-toggle-pencil", cmd_sudoku_toggle_pencil);
+    api.register_command("sudoku-undo", cmd_sudoku_undo);
+    api.register_command("sudoku-redo", cmd_sudoku_redo);
+    api.register_command("sudoku-import", cmd_sudoku_import);
+    api.register_command("sudoku-export", cmd_sudoku_export);
+    api.register_command("sudoku-pause", cmd_sudoku_pause);
+    api.register_command("sudoku-resume", cmd_sudoku_resume);
+
+    /* Track cursor movement in the game buffer */
+    if (api.on) {
+        api.on("input:key", on_input_key, NULL, 0);
+    }
 
     /* Initialize Go side */
     GoSudokuInit();
 
     api.log_info("go_sudoku: Extension v2.0.0 loaded");
-    api.log_info("  Commands: sudoku-new, sudoku-check, sudoku-hint");
-    api.log_info("            sudoku-solve, sudoku-reset");
+    api.log_info("  Commands: sudoku-new, sudoku-generate, sudoku-check, sudoku-hint");
+    api.log_info("            sudoku-solve, sudoku-reset, sudoku-toggle-pencil");
+    api.log_info("            sudoku-undo, sudoku-redo, sudoku-import, sudoku-export");
+    api.log_info("            sudoku-pause, sudoku-resume");
 
     return 0;
 }
 
 static void sudoku_cleanup(void) {
+    if (api.off) {
+        api.off("input:key", on_input_key);
+    }
+
     /* Unregister commands */
     if (api.unregister_command) {
         api.unregister_command("sudoku-new");
+        api.unregister_command("sudoku-generate");
         api.unregister_command("sudoku-check");
         api.unregister_command("sudoku-hint");
         api.unregister_command("sudoku-solve");
         api.unregister_command("sudoku-reset");
+        api.unregister_command("sudoku-toggle-pencil");
+        api.unregister_command("sudoku-undo");
+        api.unregister_command("sudoku-redo");
+        api.unregister_command("sudoku-import");
+        api.unregister_command("sudoku-export");
+        api.unregister_command("sudoku-pause");
+        api.unregister_command("sudoku-resume");
     }
 
     if (api.log_info) {
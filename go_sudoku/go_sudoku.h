@@ -21,7 +21,7 @@ extern const char *_GoStringPtr(_GoString_ s);
 /* Start of preamble from import "C" comments.  */
 
 
-#line 15 "main.go"
+#line 24 "main.go"
 
 #include <stdlib.h>
 #include <stdint.h>
@@ -40,6 +40,7 @@ extern void api_set_point(int line, int col);
 extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
 extern void api_update_display(void);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
 extern int api_prompt_yn(const char *prompt);
 
 #line 1 "cgo-generated-wrapper"
@@ -109,6 +110,10 @@ extern "C" {
 //
 extern int GoSudokuNew(int f, int n);
 
+// GoSudokuGenerate starts a game with a freshly generated puzzle
+//
+extern int GoSudokuGenerate(int f, int n);
+
 // GoSudokuCheck checks for errors
 //
 extern int GoSudokuCheck(int f, int n);
@@ -125,6 +130,41 @@ extern int GoSudokuSolve(int f, int n);
 //
 extern int GoSudokuReset(int f, int n);
 
+// GoSudokuTogglePencil switches pencil marks on or off
+//
+extern int GoSudokuTogglePencil(int f, int n);
+
+// GoSudokuUndo reverts the last move
+//
+extern int GoSudokuUndo(int f, int n);
+
+// GoSudokuRedo re-applies the last undone move
+//
+extern int GoSudokuRedo(int f, int n);
+
+// GoSudokuImport loads a puzzle from the 81-character interchange format
+//
+extern int GoSudokuImport(int f, int n);
+
+// GoSudokuExport shows the current grid as an 81-character string
+//
+extern int GoSudokuExport(int f, int n);
+
+// GoSudokuPause stops the game clock
+//
+extern int GoSudokuPause(int f, int n);
+
+// GoSudokuResume restarts the game clock
+//
+extern int GoSudokuResume(int f, int n);
+
+// GoSudokuCursorMoved is called for each key typed in the sudoku buffer.
+// C-n, C-p, C-f and C-b move cell by cell within the grid and are consumed;
+// they pass through at the edges. Other motion is picked up here on the
+// next key, or by the next clock redraw. Returns 1 if the key was consumed.
+//
+extern int GoSudokuCursorMoved(int key);
+
 // GoSudokuInit initializes the extension
 //
 extern void GoSudokuInit(void);
@@ -1,14 +1,23 @@
 // go_sudoku - Sudoku game extension for μEmacs
 //
 // Provides a playable Sudoku game with intelligent solving using
-// constraint propagation and backtracking.
+// constraint propagation and backtracking, or exact cover search
+// (Dancing Links) for hard puzzles.
 //
 // Commands:
 //   sudoku-new     - Start a new puzzle
+//   sudoku-generate - Generate a random puzzle (prefix 2=medium, 3=hard)
 //   sudoku-check   - Check for errors
 //   sudoku-hint    - Reveal one cell
 //   sudoku-solve   - Show the solution
 //   sudoku-reset   - Reset to original puzzle
+//   sudoku-toggle-pencil - Show candidates (pencil marks) in empty cells
+//   sudoku-undo    - Undo the last move (entries and hints)
+//   sudoku-redo    - Redo an undone move
+//   sudoku-import  - Load a puzzle from an 81-character string
+//   sudoku-export  - Show the grid as an 81-character string
+//   sudoku-pause   - Stop the game clock
+//   sudoku-resume  - Restart the game clock
 
 package main
 
@@ -30,29 +39,77 @@ extern void api_set_point(int line, int col);
 extern void api_log_info(const char *msg);
 extern void api_log_error(const char *msg);
 extern void api_update_display(void);
+extern int api_prompt(const char *prompt, char *buf, size_t buflen);
 extern int api_prompt_yn(const char *prompt);
 */
 import "C"
 
 import (
 	"fmt"
+	"math/rand"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	"go_sudoku/sudoku"
 )
 
+// SudokuMove is one change to a cell, kept for undo/redo
+type SudokuMove struct {
+	row, col           int
+	oldValue, newValue int
+}
+
 // Game state
 type GameState struct {
-	puzzle   sudoku.Grid // Current player state
-	original sudoku.Grid // Original puzzle (fixed clues)
-	solution sudoku.Grid // Solved version
-	active   bool        // Is game active
+	puzzle   sudoku.Grid   // Current player state
+	original sudoku.Grid   // Original puzzle (fixed clues)
+	solution sudoku.Grid   // Solved version
+	rating   sudoku.Rating // Graded difficulty of original
+	active   bool          // Is game active
+
+	showPencilMarks bool           // Render candidates in empty cells
+	solver          *sudoku.Solver // Loaded with puzzle; its candidates are the pencil marks
+
+	cursorRow, cursorCol int // Cell under the cursor; its peers are highlighted
+
+	MoveHistory []SudokuMove // Moves to undo, oldest first
+	RedoStack   []SudokuMove // Undone moves, most recent last
+
+	startTime time.Time     // When the clock last started running
+	elapsed   time.Duration // Time on the clock before startTime
+	paused    bool          // Clock stopped by sudoku-pause
+	finished  bool          // Solved or revealed; the clock is stopped for good
 }
 
 var game GameState
 var bufferName = "*sudoku*"
 
+// gameMu guards game, which commands change on the editor thread while the
+// clock goroutine reads it
+var gameMu sync.Mutex
+
+// lastCommand is when a command last ran. The clock keeps off the message
+// line for clockQuiet afterwards so the command's own message can be read.
+var lastCommand time.Time
+
+const clockQuiet = 3 * time.Second
+
+// timerStop ends the goroutine that shows the clock
+var timerStop chan struct{}
+
+// cellRect is where a cell was drawn in the buffer: lines and columns are
+// 1-based, the cell spans lines rows of width characters
+type cellRect struct {
+	line, col    int
+	lines, width int
+}
+
+// cellLayout maps each cell to its place in the last rendered grid
+var cellLayout [9][9]cellRect
+
 // Predefined puzzles
 var easyPuzzle = sudoku.Grid{
 	{5, 3, 0, 0, 7, 0, 0, 0, 0},
@@ -94,54 +151,327 @@ var hardPuzzle = sudoku.Grid{
 func renderGrid() string {
 	var sb strings.Builder
 
-	// Title
-	sb.WriteString("                    SUDOKU\n\n")
+	// Title and the clock as of this redraw; while it runs, the live
+	// reading is shown in the message line
+	sb.WriteString(fmt.Sprintf("                    SUDOKU  (%s, score %d)\n", game.rating.Label, game.rating.Score))
+	sb.WriteString("  ⏱ " + formatElapsed(timerElapsed()))
+	if game.paused {
+		sb.WriteString(" (paused)")
+	}
+	sb.WriteString("\n\n")
 
-	// Grid with box-drawing characters
-	topBorder := "      +-------+-------+-------+\n"
-	midBorder := "      +-------+-------+-------+\n"
+	if game.showPencilMarks {
+		renderPencilGrid(&sb)
+	} else {
+		renderPlainGrid(&sb)
+	}
+	renderStatus(&sb)
 
-	sb.WriteString(topBorder)
+	// Instructions
+	sb.WriteString("  Commands:\n")
+	sb.WriteString("    M-x sudoku-new    - Start new game (prefix 2=medium, 3=hard)\n")
+	sb.WriteString("    M-x sudoku-generate - Random new game (prefix 2=medium, 3=hard)\n")
+	sb.WriteString("    M-x sudoku-check  - Check for errors\n")
+	sb.WriteString("    M-x sudoku-hint   - Reveal one cell\n")
+	sb.WriteString("    M-x sudoku-solve  - Show solution\n")
+	sb.WriteString("    M-x sudoku-reset  - Reset to original\n")
+	sb.WriteString("    M-x sudoku-toggle-pencil - Show/hide pencil marks\n")
+	sb.WriteString("    M-x sudoku-undo / sudoku-redo - Undo or redo a move\n")
+	sb.WriteString("    M-x sudoku-pause / sudoku-resume - Stop or restart the clock\n")
+	sb.WriteString("    C-n C-p C-f C-b   - Move between cells; [ ] and (N) mark the peers\n")
+
+	return sb.String()
+}
+
+// renderPlainGrid renders one digit per cell. The peers of the cell under
+// the cursor (its row, column and box) are bracketed: [ ] when empty, (N)
+// when filled.
+func renderPlainGrid(sb *strings.Builder) {
+	border := "      +" + strings.Repeat(strings.Repeat("-", 9)+"+", 3) + "\n"
 
+	sb.WriteString(border)
 	for row := 0; row < 9; row++ {
 		sb.WriteString(fmt.Sprintf("    %d ", row+1))
 		for col := 0; col < 9; col++ {
 			if col%3 == 0 {
-				sb.WriteString("| ")
+				sb.WriteString("|")
 			}
+			line, col0 := endPosition(sb)
+			cellLayout[row][col] = cellRect{line: line, col: col0, lines: 1, width: 3}
+			sb.WriteString(plainCell(row, col))
+		}
+		sb.WriteString("|\n")
+
+		if (row+1)%3 == 0 {
+			sb.WriteString(border)
+		}
+	}
+
+	// Column numbers, centred under each cell
+	numbers := "      "
+	for col := 0; col < 9; col++ {
+		if col%3 == 0 {
+			numbers += " "
+		}
+		numbers += fmt.Sprintf(" %d ", col+1)
+	}
+	sb.WriteString(strings.TrimRight(numbers, " ") + "\n\n")
+}
+
+// endPosition returns the line and column at which the next character
+// written to sb will appear
+func endPosition(sb *strings.Builder) (int, int) {
+	text := sb.String()
+	lineStart := strings.LastIndex(text, "\n") + 1
+	return strings.Count(text, "\n") + 1, utf8.RuneCountInString(text[lineStart:]) + 1
+}
+
+// plainCell returns the three characters drawn for a cell
+func plainCell(row, col int) string {
+	val := game.puzzle[row][col]
+	switch {
+	case !isPeer(row, col) && val == 0:
+		return " . "
+	case !isPeer(row, col):
+		return fmt.Sprintf(" %d ", val)
+	case val == 0:
+		return "[ ]"
+	default:
+		return fmt.Sprintf("(%d)", val)
+	}
+}
+
+// isPeer reports whether a cell shares a row, column or box with the cell
+// under the cursor (or is that cell)
+func isPeer(row, col int) bool {
+	r, c := game.cursorRow, game.cursorCol
+	return row == r || col == c || (row/3 == r/3 && col/3 == c/3)
+}
+
+// renderStatus renders the status line under the grid
+func renderStatus(sb *strings.Builder) {
+	sb.WriteString(fmt.Sprintf("  History: %d moves to undo, %d to redo\n\n",
+		len(game.MoveHistory), len(game.RedoStack)))
+}
 
-			val := game.puzzle[row][col]
-			if val == 0 {
-				sb.WriteString(". ")
-			} else if game.original[row][col] != 0 {
-				// Fixed clue (shown as-is)
-				sb.WriteString(fmt.Sprintf("%d ", val))
+// renderPencilGrid renders each cell as a 3x3 block of its candidates,
+// like pencil marks: digit v at row (v-1)/3, column (v-1)%3, and · where
+// a candidate has been ruled out. Filled cells show their digit in the
+// middle. The 81 cells make 27 rows of 27 characters, plus separators.
+func renderPencilGrid(sb *strings.Builder) {
+	border := "      +" + strings.Repeat(strings.Repeat("-", 13)+"+", 3) + "\n"
+
+	sb.WriteString(border)
+	for row := 0; row < 9; row++ {
+		for sub := 0; sub < 3; sub++ {
+			if sub == 1 {
+				sb.WriteString(fmt.Sprintf("    %d ", row+1))
 			} else {
-				// Player entry (shown as-is for now)
-				sb.WriteString(fmt.Sprintf("%d ", val))
+				sb.WriteString("      ")
 			}
+			for col := 0; col < 9; col++ {
+				if col%3 == 0 {
+					sb.WriteString("| ")
+				}
+				if sub == 0 {
+					line, col0 := endPosition(sb)
+					cellLayout[row][col] = cellRect{line: line, col: col0, lines: 3, width: 3}
+				}
+				sb.WriteString(pencilCellRow(row, col, sub))
+				sb.WriteString(" ")
+			}
+			sb.WriteString("|\n")
 		}
-		sb.WriteString("|\n")
-
 		if (row+1)%3 == 0 {
-			sb.WriteString(midBorder)
+			sb.WriteString(border)
 		}
 	}
 
-	// Column numbers
-	sb.WriteString("        1 2 3   4 5 6   7 8 9\n\n")
+	// Column numbers, centred under each cell
+	numbers := "      "
+	for col := 0; col < 9; col++ {
+		if col%3 == 0 {
+			numbers += "  "
+		}
+		numbers += fmt.Sprintf(" %d  ", col+1)
+	}
+	sb.WriteString(strings.TrimRight(numbers, " ") + "\n\n")
+}
 
-	// Instructions
-	sb.WriteString("  Commands:\n")
-	sb.WriteString("    M-x sudoku-new    - Start new game (prefix 2=medium, 3=hard)\n")
-	sb.WriteString("    M-x sudoku-check  - Check for errors\n")
-	sb.WriteString("    M-x sudoku-hint   - Reveal one cell\n")
-	sb.WriteString("    M-x sudoku-solve  - Show solution\n")
-	sb.WriteString("    M-x sudoku-reset  - Reset to original\n")
+// pencilCellRow returns row sub (0-2) of a cell's 3x3 pencil mark block
+func pencilCellRow(row, col, sub int) string {
+	if val := game.puzzle[row][col]; val != 0 {
+		if sub == 1 {
+			return fmt.Sprintf(" %d ", val)
+		}
+		return "   "
+	}
 
+	mask := uint16(0)
+	if game.solver != nil {
+		mask = game.solver.GetCandidateMask(row, col)
+	}
+	var sb strings.Builder
+	for v := sub*3 + 1; v <= sub*3+3; v++ {
+		if mask&(1<<v) != 0 {
+			sb.WriteString(fmt.Sprintf("%d", v))
+		} else {
+			sb.WriteString("·")
+		}
+	}
 	return sb.String()
 }
 
+// playMove sets a cell and records the change for undo. A new move makes
+// the undone moves unreachable, so it clears the redo stack.
+func playMove(row, col, value int) {
+	game.MoveHistory = append(game.MoveHistory, SudokuMove{
+		row: row, col: col,
+		oldValue: game.puzzle[row][col], newValue: value,
+	})
+	game.RedoStack = nil
+	game.puzzle[row][col] = value
+}
+
+// clearHistory forgets all moves, for a new or reset grid
+func clearHistory() {
+	game.MoveHistory = nil
+	game.RedoStack = nil
+}
+
+// refreshSolver reloads the live solver from the current grid, so the
+// pencil marks follow every change to it
+func refreshSolver() {
+	if game.solver == nil {
+		game.solver = sudoku.New()
+	}
+	game.solver.LoadPuzzle(game.puzzle)
+}
+
+// timerElapsed returns the time on the game clock
+func timerElapsed() time.Duration {
+	if game.paused || game.finished {
+		return game.elapsed
+	}
+	return game.elapsed + time.Since(game.startTime)
+}
+
+// formatElapsed formats a clock reading as mm:ss
+func formatElapsed(d time.Duration) string {
+	secs := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
+
+// startTimer restarts the clock from zero for a new game and launches the
+// goroutine that shows it every second
+func startTimer() {
+	stopTimer()
+	game.startTime = time.Now()
+	game.elapsed = 0
+	game.paused = false
+	game.finished = false
+
+	stop := make(chan struct{})
+	timerStop = stop
+	go runTimer(stop)
+}
+
+// stopTimer ends the clock goroutine, if one is running
+func stopTimer() {
+	if timerStop != nil {
+		close(timerStop)
+		timerStop = nil
+	}
+}
+
+// finishTimer freezes the clock at its current reading
+func finishTimer() {
+	game.elapsed = timerElapsed()
+	game.finished = true
+	stopTimer()
+}
+
+// runTimer shows the clock in the message line every second while the
+// sudoku buffer is current, until stopped or the game ends. It only reads
+// the game; the buffer is redrawn by commands on the editor thread.
+func runTimer(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			gameMu.Lock()
+			active := game.active
+			reading, show := clockReading()
+			gameMu.Unlock()
+			if !active {
+				return
+			}
+			if show {
+				msg := C.CString(reading)
+				C.api_message(msg)
+				C.free(unsafe.Pointer(msg))
+			}
+		}
+	}
+}
+
+// clockReading returns the clock as shown in the message line, and whether
+// to show it now: the clock is running, the sudoku buffer is current and
+// no command has just shown a message. gameMu must be held.
+func clockReading() (string, bool) {
+	if game.paused || game.finished || time.Since(lastCommand) < clockQuiet {
+		return "", false
+	}
+	cname := C.CString(bufferName)
+	defer C.free(unsafe.Pointer(cname))
+	bp := C.api_find_buffer(cname)
+	if bp == nil || bp != C.api_current_buffer() {
+		return "", false
+	}
+	return "Sudoku ⏱ " + formatElapsed(timerElapsed()), true
+}
+
+// lockGame takes gameMu for a command and notes that it ran
+func lockGame() {
+	gameMu.Lock()
+	lastCommand = time.Now()
+}
+
+// cellAt maps a buffer position to the cell drawn there
+func cellAt(line, col int) (int, int, bool) {
+	for row := 0; row < 9; row++ {
+		for c := 0; c < 9; c++ {
+			r := cellLayout[row][c]
+			if line >= r.line && line < r.line+r.lines && col >= r.col && col < r.col+r.width {
+				return row, c, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// syncCursor moves the highlighted cell to the one under point, if point
+// is on a cell, and reports whether it changed
+func syncCursor() bool {
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	row, c, ok := cellAt(int(line), int(col))
+	if !ok || (row == game.cursorRow && c == game.cursorCol) {
+		return false
+	}
+	game.cursorRow, game.cursorCol = row, c
+	return true
+}
+
+// setPointToCell puts point in the middle of a cell as last rendered
+func setPointToCell(row, col int) {
+	r := cellLayout[row][col]
+	C.api_set_point(C.int(r.line+r.lines/2), C.int(r.col+r.width/2))
+}
+
 // Show or update the sudoku buffer
 func updateBuffer() {
 	// Find or create buffer
@@ -149,7 +479,8 @@ func updateBuffer() {
 	defer C.free(unsafe.Pointer(cname))
 
 	bp := C.api_find_buffer(cname)
-	if bp == nil {
+	existed := bp != nil
+	if !existed {
 		bp = C.api_buffer_create(cname)
 	}
 	if bp == nil {
@@ -157,15 +488,22 @@ func updateBuffer() {
 	}
 
 	C.api_buffer_switch(bp)
+	if existed {
+		// Keep the highlight on the cell the player is at
+		syncCursor()
+	}
 	C.api_buffer_clear(bp)
 
+	// Every change to the grid is shown through here
+	refreshSolver()
+
 	// Render and insert
 	content := renderGrid()
 	ccontent := C.CString(content)
 	defer C.free(unsafe.Pointer(ccontent))
 	C.api_buffer_insert(ccontent, C.size_t(len(content)))
 
-	C.api_set_point(3, 9) // Position cursor at first cell
+	setPointToCell(game.cursorRow, game.cursorCol)
 	C.api_update_display()
 }
 
@@ -173,13 +511,11 @@ func updateBuffer() {
 //
 //export GoSudokuNew
 func GoSudokuNew(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
 	// Default to easy, use prefix arg for difficulty
-	difficulty := "easy"
-	if n == 2 {
-		difficulty = "medium"
-	} else if n >= 3 {
-		difficulty = "hard"
-	}
+	difficulty := difficultyFromPrefix(n)
 
 	switch difficulty {
 	case "medium":
@@ -195,15 +531,59 @@ func GoSudokuNew(f, n C.int) C.int {
 
 	// Solve to get solution
 	solver := sudoku.New()
+	solver.EnableAdvancedStrategies(true)
 	solver.LoadPuzzle(game.original)
 	solver.Solve()
 	game.solution = solver.GetGrid()
+	game.rating = sudoku.RatePuzzle(game.original)
 
+	clearHistory()
 	game.active = true
+	startTimer()
 
 	updateBuffer()
 
-	msg := C.CString(fmt.Sprintf("Sudoku (%s) - Good luck!", difficulty))
+	msg := C.CString(fmt.Sprintf("Sudoku (%s, rated %s, score %d) - Good luck!",
+		difficulty, game.rating.Label, game.rating.Score))
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+
+	return 1
+}
+
+// difficultyFromPrefix maps the numeric prefix to a difficulty name
+func difficultyFromPrefix(n C.int) string {
+	if n == 2 {
+		return "medium"
+	} else if n >= 3 {
+		return "hard"
+	}
+	return "easy"
+}
+
+// GoSudokuGenerate starts a game with a freshly generated puzzle
+//
+//export GoSudokuGenerate
+func GoSudokuGenerate(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	difficulty := difficultyFromPrefix(n)
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	puzzle, solution := sudoku.GeneratePuzzle(difficulty, rng)
+	game.puzzle = puzzle
+	game.original = puzzle
+	game.solution = solution
+	game.rating = sudoku.RatePuzzle(puzzle)
+	clearHistory()
+	game.active = true
+	startTimer()
+
+	updateBuffer()
+
+	msg := C.CString(fmt.Sprintf("Sudoku (%s, %d clues, rated %s, score %d) - Good luck!",
+		difficulty, puzzle.CountFilledCells(), game.rating.Label, game.rating.Score))
 	defer C.free(unsafe.Pointer(msg))
 	C.api_message(msg)
 
@@ -214,6 +594,9 @@ func GoSudokuNew(f, n C.int) C.int {
 //
 //export GoSudokuCheck
 func GoSudokuCheck(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
 	if !game.active {
 		msg := C.CString("No active game. Use M-x sudoku-new")
 		defer C.free(unsafe.Pointer(msg))
@@ -237,6 +620,19 @@ func GoSudokuCheck(f, n C.int) C.int {
 	var msgStr string
 	if errors == 0 && complete {
 		msgStr = "Congratulations! Puzzle solved correctly!"
+		if !game.finished {
+			finishTimer()
+			updateBuffer()
+			msgStr = fmt.Sprintf("Congratulations! Puzzle solved correctly in %s!", formatElapsed(game.elapsed))
+			err := recordCompletion(CompletedGame{
+				Puzzle:   game.original.Encode(),
+				Seconds:  int(game.elapsed / time.Second),
+				Finished: time.Now(),
+			})
+			if err != nil {
+				msgStr += fmt.Sprintf(" (stats not saved: %v)", err)
+			}
+		}
 	} else if errors == 0 {
 		msgStr = "No errors so far. Keep going!"
 	} else {
@@ -254,6 +650,9 @@ func GoSudokuCheck(f, n C.int) C.int {
 //
 //export GoSudokuHint
 func GoSudokuHint(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
 	if !game.active {
 		msg := C.CString("No active game")
 		defer C.free(unsafe.Pointer(msg))
@@ -265,7 +664,7 @@ func GoSudokuHint(f, n C.int) C.int {
 	for r := 0; r < 9; r++ {
 		for c := 0; c < 9; c++ {
 			if game.original[r][c] == 0 && game.puzzle[r][c] != game.solution[r][c] {
-				game.puzzle[r][c] = game.solution[r][c]
+				playMove(r, c, game.solution[r][c])
 				updateBuffer()
 
 				msgStr := fmt.Sprintf("Hint: Row %d, Col %d = %d", r+1, c+1, game.solution[r][c])
@@ -287,6 +686,9 @@ func GoSudokuHint(f, n C.int) C.int {
 //
 //export GoSudokuSolve
 func GoSudokuSolve(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
 	if !game.active {
 		msg := C.CString("No active game")
 		defer C.free(unsafe.Pointer(msg))
@@ -295,6 +697,8 @@ func GoSudokuSolve(f, n C.int) C.int {
 	}
 
 	game.puzzle = game.solution
+	clearHistory()
+	finishTimer()
 	updateBuffer()
 
 	msg := C.CString("Solution revealed")
@@ -307,6 +711,9 @@ func GoSudokuSolve(f, n C.int) C.int {
 //
 //export GoSudokuReset
 func GoSudokuReset(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
 	if !game.active {
 		msg := C.CString("No active game")
 		defer C.free(unsafe.Pointer(msg))
@@ -315,6 +722,10 @@ func GoSudokuReset(f, n C.int) C.int {
 	}
 
 	game.puzzle = game.original
+	clearHistory()
+	if game.finished {
+		startTimer()
+	}
 	updateBuffer()
 
 	msg := C.CString("Puzzle reset to original")
@@ -323,6 +734,258 @@ func GoSudokuReset(f, n C.int) C.int {
 	return 1
 }
 
+// GoSudokuTogglePencil switches pencil marks on or off
+//
+//export GoSudokuTogglePencil
+func GoSudokuTogglePencil(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	if !game.active {
+		msg := C.CString("No active game")
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	game.showPencilMarks = !game.showPencilMarks
+	updateBuffer()
+
+	state := "off"
+	if game.showPencilMarks {
+		state = "on"
+	}
+	msg := C.CString("Pencil marks " + state)
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuUndo reverts the last move
+//
+//export GoSudokuUndo
+func GoSudokuUndo(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	if !game.active || len(game.MoveHistory) == 0 {
+		msg := C.CString("Nothing to undo")
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	move := game.MoveHistory[len(game.MoveHistory)-1]
+	game.MoveHistory = game.MoveHistory[:len(game.MoveHistory)-1]
+	game.RedoStack = append(game.RedoStack, move)
+	game.puzzle[move.row][move.col] = move.oldValue
+	updateBuffer()
+
+	msg := C.CString(fmt.Sprintf("Undo: Row %d, Col %d (%d more)", move.row+1, move.col+1, len(game.MoveHistory)))
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuRedo re-applies the last undone move
+//
+//export GoSudokuRedo
+func GoSudokuRedo(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	if !game.active || len(game.RedoStack) == 0 {
+		msg := C.CString("Nothing to redo")
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	move := game.RedoStack[len(game.RedoStack)-1]
+	game.RedoStack = game.RedoStack[:len(game.RedoStack)-1]
+	game.MoveHistory = append(game.MoveHistory, move)
+	game.puzzle[move.row][move.col] = move.newValue
+	updateBuffer()
+
+	msg := C.CString(fmt.Sprintf("Redo: Row %d, Col %d = %d", move.row+1, move.col+1, move.newValue))
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuImport loads a puzzle from the 81-character interchange format
+//
+//export GoSudokuImport
+func GoSudokuImport(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	var buf [256]C.char
+	prompt := C.CString("Puzzle (81 cells, 1-9 and 0 or . for empty): ")
+	defer C.free(unsafe.Pointer(prompt))
+	if C.api_prompt(prompt, &buf[0], 256) < 0 {
+		return 0
+	}
+
+	puzzle, err := sudoku.ParseGrid(C.GoString(&buf[0]))
+	if err == nil && sudoku.SolutionCount(puzzle, 1) == 0 {
+		err = fmt.Errorf("puzzle has no solution")
+	}
+	if err != nil {
+		msg := C.CString(fmt.Sprintf("Import failed: %v", err))
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	solver := sudoku.New()
+	solver.EnableAdvancedStrategies(true)
+	solver.LoadPuzzle(puzzle)
+	solver.Solve()
+
+	game.puzzle = puzzle
+	game.original = puzzle
+	game.solution = solver.GetGrid()
+	game.rating = sudoku.RatePuzzle(game.original)
+	clearHistory()
+	game.active = true
+	startTimer()
+
+	updateBuffer()
+
+	msgStr := fmt.Sprintf("Imported puzzle (%d clues, rated %s, score %d)",
+		puzzle.CountFilledCells(), game.rating.Label, game.rating.Score)
+	if !sudoku.HasUniqueSolution(puzzle) {
+		msgStr += " - warning: more than one solution"
+	}
+	msg := C.CString(msgStr)
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuExport shows the current grid as an 81-character string
+//
+//export GoSudokuExport
+func GoSudokuExport(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	if !game.active {
+		msg := C.CString("No active game")
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	msg := C.CString(game.puzzle.Encode())
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuPause stops the game clock
+//
+//export GoSudokuPause
+func GoSudokuPause(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	if !game.active || game.finished || game.paused {
+		msg := C.CString("Clock is not running")
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	game.elapsed += time.Since(game.startTime)
+	game.paused = true
+	updateBuffer()
+
+	msg := C.CString("Paused at " + formatElapsed(game.elapsed))
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuResume restarts the game clock
+//
+//export GoSudokuResume
+func GoSudokuResume(f, n C.int) C.int {
+	lockGame()
+	defer gameMu.Unlock()
+
+	if !game.active || game.finished || !game.paused {
+		msg := C.CString("Clock is not paused")
+		defer C.free(unsafe.Pointer(msg))
+		C.api_message(msg)
+		return 0
+	}
+
+	game.startTime = time.Now()
+	game.paused = false
+	updateBuffer()
+
+	msg := C.CString("Resumed at " + formatElapsed(game.elapsed))
+	defer C.free(unsafe.Pointer(msg))
+	C.api_message(msg)
+	return 1
+}
+
+// GoSudokuCursorMoved is called for each key typed in the sudoku buffer.
+// C-n, C-p, C-f and C-b move cell by cell within the grid and are consumed;
+// they pass through at the edges. Other motion is picked up here on the
+// next key. Returns 1 if the key was consumed.
+//
+//export GoSudokuCursorMoved
+func GoSudokuCursorMoved(key C.int) C.int {
+	gameMu.Lock()
+	defer gameMu.Unlock()
+
+	if !game.active {
+		return 0
+	}
+	cname := C.CString(bufferName)
+	defer C.free(unsafe.Pointer(cname))
+	bp := C.api_find_buffer(cname)
+	if bp == nil || bp != C.api_current_buffer() {
+		return 0
+	}
+
+	dr, dc := 0, 0
+	switch key {
+	case 0x0e: // C-n
+		dr = 1
+	case 0x10: // C-p
+		dr = -1
+	case 0x06: // C-f
+		dc = 1
+	case 0x02: // C-b
+		dc = -1
+	}
+
+	var line, col C.int
+	C.api_get_point(&line, &col)
+	row, c, onCell := cellAt(int(line), int(col))
+
+	if dr != 0 || dc != 0 {
+		row, c = row+dr, c+dc
+		if !onCell || row < 0 || row > 8 || c < 0 || c > 8 {
+			return 0
+		}
+		setPointToCell(row, c)
+		updateBuffer()
+		return 1
+	}
+
+	if syncCursor() {
+		updateBuffer()
+		C.api_set_point(line, col)
+		C.api_update_display()
+	}
+	return 0
+}
+
 // GoSudokuInit initializes the extension
 //
 //export GoSudokuInit
@@ -336,7 +999,10 @@ func GoSudokuInit() {
 //
 //export GoSudokuCleanup
 func GoSudokuCleanup() {
+	gameMu.Lock()
+	defer gameMu.Unlock()
 	game.active = false
+	stopTimer()
 }
 
 func main() {}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CompletedGame is one correctly finished puzzle
+type CompletedGame struct {
+	Puzzle   string    `json:"puzzle"`   // Original grid, 81-character format
+	Seconds  int       `json:"seconds"`  // Time on the clock, pauses excluded
+	Finished time.Time `json:"finished"` // When sudoku-check confirmed it
+}
+
+// SudokuStats is the content of the stats file
+type SudokuStats struct {
+	Games []CompletedGame `json:"games"`
+}
+
+// statsPath returns where completed games are recorded
+func statsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "muemacs", "sudoku_stats.json")
+}
+
+// recordCompletion appends a finished game to the stats file. A missing
+// file starts a new history; a corrupt one is left alone and reported.
+func recordCompletion(game CompletedGame) error {
+	path := statsPath()
+	if path == "" {
+		return os.ErrNotExist
+	}
+
+	var stats SudokuStats
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &stats); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	stats.Games = append(stats.Games, game)
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -116,6 +116,7 @@ func (s *Solver) propagateConstraints() bool {
 		if s.advancedStrategies {
 			// Apply medium-level tactics once per loop until no further change.
 			progress = s.findNakedPairs() || progress
+			progress = s.findXWing() || progress
 			progress = s.findHiddenPairs() || progress
 			progress = s.findPointingPairs() || progress
 		}
@@ -426,6 +427,71 @@ func (s *Solver) findPointingPairs() bool {
 	return changed
 }
 
+// findXWing eliminates candidates with X-Wings: when a value can go in
+// exactly two cells in each of two rows, and those cells share the same
+// two columns, the value occupies one cell of each column within those
+// rows, so it is removed from the rest of both columns. The same applies
+// with rows and columns swapped.
+func (s *Solver) findXWing() bool {
+	changed := false
+	for v := 1; v <= 9; v++ {
+		changed = s.xWingForValue(v, true) || changed
+		changed = s.xWingForValue(v, false) || changed
+	}
+	return changed
+}
+
+// xWingForValue looks for X-Wings on value v with rows as the base lines
+// (columns when byRow is false)
+func (s *Solver) xWingForValue(v int, byRow bool) bool {
+	bit := uint16(1 << v)
+	cellAt := func(line, pos int) (int, int) {
+		if byRow {
+			return line, pos
+		}
+		return pos, line
+	}
+
+	// positions[line] has bit pos set when v is a candidate there
+	var positions [9]uint16
+	for line := 0; line < 9; line++ {
+		for pos := 0; pos < 9; pos++ {
+			r, c := cellAt(line, pos)
+			if s.grid[r][c] == 0 && s.candidates[r][c]&bit != 0 {
+				positions[line] |= 1 << pos
+			}
+		}
+	}
+
+	changed := false
+	for a := 0; a < 9; a++ {
+		if bits.OnesCount16(positions[a]) != 2 {
+			continue
+		}
+		for b := a + 1; b < 9; b++ {
+			if positions[b] != positions[a] {
+				continue
+			}
+			for pos := 0; pos < 9; pos++ {
+				if positions[a]&(1<<pos) == 0 {
+					continue
+				}
+				for line := 0; line < 9; line++ {
+					if line == a || line == b {
+						continue
+					}
+					r, c := cellAt(line, pos)
+					if s.grid[r][c] == 0 && s.candidates[r][c]&bit != 0 {
+						s.candidates[r][c] &^= bit
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return changed
+}
+
 // Helper utilities for advanced strategies
 func containsIndex(list []int, idx int) bool {
 	for _, v := range list {
@@ -0,0 +1,30 @@
+package sudoku
+
+import "testing"
+
+func TestXWingSolvesWithoutBacktracking(t *testing.T) {
+	// X-Wing example from sudokuwiki.org: singles, pairs and pointing
+	// pairs stall, and an X-Wing unlocks the rest
+	g, err := ParseGrid("100000569492056108056109240009640801064010000218035604040500016905061402621000005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := RequiredLevel(g); level != LevelXWing {
+		t.Fatalf("puzzle needs %s, not X-Wing", level)
+	}
+	if rating := RateDifficulty(g); rating != "Hard" {
+		t.Errorf("rated %s, want Hard", rating)
+	}
+
+	s := New()
+	s.LoadPuzzle(g)
+	s.EnableAdvancedStrategies(true)
+	for s.StepConstraints() {
+	}
+	if s.GetStats().BacktrackSteps != 0 {
+		t.Errorf("backtracked %d times", s.GetStats().BacktrackSteps)
+	}
+	if !s.GetGrid().IsSolved() {
+		t.Fatalf("not solved by constraint propagation:\n%s", s.GetGrid())
+	}
+}
@@ -0,0 +1,201 @@
+// Exact cover solving with Algorithm X and Dancing Links
+package sudoku
+
+// Sudoku as exact cover: each of the 729 (row, column, value) placements
+// is a matrix row covering four of 324 constraint columns, and a solution
+// is a set of rows covering every column exactly once. The columns are,
+// in blocks of 81:
+//
+//	cell   - row*9 + col         (each cell holds one value)
+//	row    - 81 + row*9 + v-1    (each row holds each value once)
+//	column - 162 + col*9 + v-1   (each column holds each value once)
+//	box    - 243 + box*9 + v-1   (each box holds each value once)
+const dlxColumns = 4 * 81
+
+// dlxMinEmptyCells is where Solve switches to DLX when advanced strategies
+// are on. With fewer empty cells backtracking finishes before the matrix
+// would even be built.
+const dlxMinEmptyCells = 30
+
+// dlx is a Dancing Links matrix. Nodes live in parallel slices and link by
+// index: node 0 is the root, nodes 1..dlxColumns are the column headers
+// and the rest are the 1s of the matrix.
+type dlx struct {
+	left, right, up, down []int
+	col                   []int // Column header of each node
+	row                   []int // Placement of each node: row*81 + col*9 + v-1
+	size                  []int // Nodes remaining in each column, by header
+
+	solution []int  // Placements chosen on the current search path
+	steps    uint64 // Placements tried
+}
+
+// newDLX builds the matrix for g: filled cells contribute only their given
+// placement, empty cells one placement per remaining candidate
+func newDLX(g Grid, candidates *[9][9]uint16) *dlx {
+	n := dlxColumns + 1
+	d := &dlx{
+		left:  make([]int, n, n+729*4),
+		right: make([]int, n, n+729*4),
+		up:    make([]int, n, n+729*4),
+		down:  make([]int, n, n+729*4),
+		col:   make([]int, n, n+729*4),
+		row:   make([]int, n, n+729*4),
+		size:  make([]int, n),
+	}
+	for i := 0; i < n; i++ {
+		d.left[i] = (i + n - 1) % n
+		d.right[i] = (i + 1) % n
+		d.up[i], d.down[i] = i, i
+		d.col[i], d.row[i] = i, -1
+	}
+
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			for v := 1; v <= 9; v++ {
+				if g[r][c] != 0 && g[r][c] != v {
+					continue
+				}
+				if g[r][c] == 0 && candidates[r][c]&(1<<v) == 0 {
+					continue
+				}
+				box := (r/3)*3 + c/3
+				d.addRow(r*81+c*9+v-1, [4]int{
+					r*9 + c,
+					81 + r*9 + v - 1,
+					162 + c*9 + v - 1,
+					243 + box*9 + v - 1,
+				})
+			}
+		}
+	}
+	return d
+}
+
+// addRow appends a placement covering the given constraint columns
+func (d *dlx) addRow(placement int, cols [4]int) {
+	first := -1
+	for _, c := range cols {
+		h := c + 1
+		x := len(d.up)
+
+		d.up = append(d.up, d.up[h])
+		d.down = append(d.down, h)
+		d.down[d.up[h]] = x
+		d.up[h] = x
+		d.col = append(d.col, h)
+		d.row = append(d.row, placement)
+		d.size[h]++
+
+		if first < 0 {
+			first = x
+			d.left = append(d.left, x)
+			d.right = append(d.right, x)
+		} else {
+			d.left = append(d.left, d.left[first])
+			d.right = append(d.right, first)
+			d.right[d.left[first]] = x
+			d.left[first] = x
+		}
+	}
+}
+
+// cover removes column c and every row that intersects it
+func (d *dlx) cover(c int) {
+	d.right[d.left[c]] = d.right[c]
+	d.left[d.right[c]] = d.left[c]
+	for i := d.down[c]; i != c; i = d.down[i] {
+		for j := d.right[i]; j != i; j = d.right[j] {
+			d.down[d.up[j]] = d.down[j]
+			d.up[d.down[j]] = d.up[j]
+			d.size[d.col[j]]--
+		}
+	}
+}
+
+// uncover restores column c, undoing cover in reverse order
+func (d *dlx) uncover(c int) {
+	for i := d.up[c]; i != c; i = d.up[i] {
+		for j := d.left[i]; j != i; j = d.left[j] {
+			d.size[d.col[j]]++
+			d.down[d.up[j]] = j
+			d.up[d.down[j]] = j
+		}
+	}
+	d.right[d.left[c]] = c
+	d.left[d.right[c]] = c
+}
+
+// search runs Algorithm X, always branching on the column with the fewest
+// rows, and returns the number of solutions found up to limit. found, if
+// not nil, is called with the placements of each solution.
+func (d *dlx) search(limit int, found func(placements []int)) int {
+	if d.right[0] == 0 {
+		if found != nil {
+			found(d.solution)
+		}
+		return 1
+	}
+
+	c := d.right[0]
+	for j := d.right[c]; j != 0; j = d.right[j] {
+		if d.size[j] < d.size[c] {
+			c = j
+		}
+	}
+	if d.size[c] == 0 {
+		return 0 // A constraint nothing can satisfy
+	}
+
+	count := 0
+	d.cover(c)
+	for r := d.down[c]; r != c && count < limit; r = d.down[r] {
+		d.steps++
+		d.solution = append(d.solution, d.row[r])
+		for j := d.right[r]; j != r; j = d.right[j] {
+			d.cover(d.col[j])
+		}
+
+		count += d.search(limit-count, found)
+
+		for j := d.left[r]; j != r; j = d.left[j] {
+			d.uncover(d.col[j])
+		}
+		d.solution = d.solution[:len(d.solution)-1]
+	}
+	d.uncover(c)
+	return count
+}
+
+// solveDLX solves the loaded puzzle as an exact cover problem
+func (s *Solver) solveDLX() bool {
+	d := newDLX(s.grid, &s.candidates)
+	var placements []int
+	found := d.search(1, func(p []int) {
+		placements = append([]int(nil), p...)
+	})
+	s.stats.DLXSteps += d.steps
+	if found == 0 {
+		return false
+	}
+
+	for _, p := range placements {
+		row, col, value := p/81, p/9%9, p%9+1
+		if s.grid[row][col] == 0 {
+			s.makeMove(row, col, value)
+		}
+	}
+	return true
+}
+
+// CountSolutions counts the solutions of the loaded puzzle, stopping at
+// max. The grid is left unchanged.
+func (s *Solver) CountSolutions(max int) int {
+	if max <= 0 {
+		return 0
+	}
+	d := newDLX(s.grid, &s.candidates)
+	count := d.search(max, nil)
+	s.stats.DLXSteps += d.steps
+	return count
+}
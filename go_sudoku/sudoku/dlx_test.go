@@ -0,0 +1,59 @@
+package sudoku
+
+import "testing"
+
+func TestDLXSolvesHardPuzzle(t *testing.T) {
+	// Arto Inkala's "world's hardest sudoku"
+	g, err := ParseGrid("8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.EnableAdvancedStrategies(true)
+	s.LoadPuzzle(g)
+	if ok, _ := s.Solve(); !ok {
+		t.Fatal("no solution found")
+	}
+	solved := s.GetGrid()
+	if !solved.IsSolved() {
+		t.Fatalf("invalid solution:\n%s", solved)
+	}
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			if g[r][c] != 0 && solved[r][c] != g[r][c] {
+				t.Fatalf("clue at row %d, column %d changed", r+1, c+1)
+			}
+		}
+	}
+	if s.GetStats().DLXSteps == 0 {
+		t.Error("solved without DLX")
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	g, err := ParseGrid("100000569492056108056109240009640801064010000218035604040500016905061402621000005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := SolutionCount(g, 2); n != 1 {
+		t.Errorf("unique puzzle: got %d solutions", n)
+	}
+
+	// Dropping clues from a unique puzzle opens up alternatives
+	g[0][0], g[8][8] = 0, 0
+	g[0][8], g[8][0] = 0, 0
+	if n := SolutionCount(g, 2); n != 2 {
+		t.Errorf("under-constrained puzzle: got %d solutions, want the limit of 2", n)
+	}
+
+	var empty Grid
+	s := New()
+	s.LoadPuzzle(empty)
+	if n := s.CountSolutions(5); n != 5 {
+		t.Errorf("empty grid: got %d solutions, want the limit of 5", n)
+	}
+	if s.GetGrid() != empty {
+		t.Error("CountSolutions changed the grid")
+	}
+}
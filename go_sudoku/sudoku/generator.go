@@ -0,0 +1,210 @@
+// Puzzle generation and technique-based grading
+package sudoku
+
+import "math/rand"
+
+// SolveLevel is the hardest deduction technique a puzzle needs
+type SolveLevel int
+
+const (
+	LevelNakedSingles  SolveLevel = iota + 1 // Cells with one candidate
+	LevelHiddenSingles                       // Values with one place in a unit
+	LevelPairs                               // Naked, hidden and pointing pairs
+	LevelXWing                               // X-Wing eliminations
+	LevelBacktracking                        // Not solvable by the techniques above
+)
+
+// String returns the name of the technique level
+func (l SolveLevel) String() string {
+	switch l {
+	case LevelNakedSingles:
+		return "naked singles"
+	case LevelHiddenSingles:
+		return "hidden singles"
+	case LevelPairs:
+		return "pairs"
+	case LevelXWing:
+		return "X-Wing"
+	default:
+		return "backtracking"
+	}
+}
+
+// Rating returns the difficulty label of puzzles that need the level:
+// "Easy" (naked singles), "Medium" (hidden singles), "Hard" (pairs or
+// X-Wing) or "Expert" (backtracking)
+func (l SolveLevel) Rating() string {
+	switch {
+	case l <= LevelNakedSingles:
+		return "Easy"
+	case l == LevelHiddenSingles:
+		return "Medium"
+	case l < LevelBacktracking:
+		return "Hard"
+	default:
+		return "Expert"
+	}
+}
+
+// DifficultyLevel maps a difficulty name ("easy", "medium", "hard") to the
+// hardest technique level its puzzles may need. Other names are treated as
+// easy.
+func DifficultyLevel(difficulty string) SolveLevel {
+	switch difficulty {
+	case "medium":
+		return LevelHiddenSingles
+	case "hard":
+		return LevelXWing
+	default:
+		return LevelNakedSingles
+	}
+}
+
+// generateAttempts bounds how many puzzles GeneratePuzzle tries while
+// looking for one rated exactly at the target difficulty
+const generateAttempts = 20
+
+// GeneratePuzzle returns a new puzzle and its solution. It starts from a
+// random complete grid and removes clues in random order, keeping each
+// removal only while the puzzle has a unique solution and RatePuzzle finds
+// it no harder than the difficulty ("easy", "medium" or "hard"). Puzzles
+// rated easier than the target are regenerated a bounded number of times.
+func GeneratePuzzle(difficulty string, rng *rand.Rand) (Grid, Grid) {
+	target := DifficultyLevel(difficulty)
+	targetRating := target.Rating()
+
+	var puzzle, solution Grid
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		solution = randomSolution(rng)
+		puzzle = solution
+		for _, idx := range rng.Perm(81) {
+			row, col := idx/9, idx%9
+			value := puzzle[row][col]
+			puzzle[row][col] = 0
+			if RequiredLevel(puzzle) > target || !HasUniqueSolution(puzzle) {
+				puzzle[row][col] = value
+			}
+		}
+		if RateDifficulty(puzzle) == targetRating {
+			break
+		}
+	}
+	return puzzle, solution
+}
+
+// randomSolution returns a random complete grid: the three diagonal boxes
+// (which do not constrain each other) are filled with random permutations
+// and the solver completes the rest
+func randomSolution(rng *rand.Rand) Grid {
+	var g Grid
+	for box := 0; box < 9; box += 4 {
+		startRow, startCol := (box/3)*3, (box%3)*3
+		for i, v := range rng.Perm(9) {
+			g[startRow+i/3][startCol+i%3] = v + 1
+		}
+	}
+
+	s := New()
+	s.SetStrategy(StrategyBasic)
+	s.LoadPuzzle(g)
+	s.Solve()
+	return s.GetGrid()
+}
+
+// HasUniqueSolution reports whether g has exactly one solution
+func HasUniqueSolution(g Grid) bool {
+	return SolutionCount(g, 2) == 1
+}
+
+// SolutionCount counts the solutions of g, stopping at limit
+func SolutionCount(g Grid, limit int) int {
+	if !g.IsValid() {
+		return 0
+	}
+	s := New()
+	s.LoadPuzzle(g)
+	return s.CountSolutions(limit)
+}
+
+// Rating is the graded difficulty of a puzzle
+type Rating struct {
+	Level SolveLevel // Hardest technique needed
+	Label string     // Level.Rating(): "Easy", "Medium", "Hard" or "Expert"
+	Score int        // Effort: weighted deduction steps, plus search steps
+}
+
+// techniqueWeight is the score of one deduction step at each level
+var techniqueWeight = map[SolveLevel]int{
+	LevelNakedSingles:  1,
+	LevelHiddenSingles: 2,
+	LevelPairs:         5,
+	LevelXWing:         8,
+}
+
+// searchWeight is the score of one placement tried by the DLX search that
+// finishes puzzles the techniques cannot
+const searchWeight = 10
+
+// RatePuzzle solves g in instrumented mode: each step applies only the
+// easiest technique that makes progress, so the hardest one used is the
+// one the puzzle requires. Every step counts as one of the solver's
+// ConstraintSteps and adds its technique's weight to the score. When the
+// techniques stall the puzzle needs backtracking, and the search steps
+// that finish it are added to the score too.
+func RatePuzzle(g Grid) Rating {
+	rating := Rating{Level: LevelNakedSingles}
+	if !g.IsValid() {
+		rating.Level = LevelBacktracking
+		rating.Label = rating.Level.Rating()
+		return rating
+	}
+
+	s := New()
+	s.LoadPuzzle(g)
+	for !s.isComplete() {
+		level := s.easiestStep()
+		if level == LevelBacktracking {
+			break
+		}
+		s.stats.ConstraintSteps++
+		rating.Score += techniqueWeight[level]
+		if level > rating.Level {
+			rating.Level = level
+		}
+	}
+	if !s.isComplete() {
+		rating.Level = LevelBacktracking
+		s.CountSolutions(1)
+		rating.Score += searchWeight * int(s.stats.DLXSteps)
+	}
+	rating.Label = rating.Level.Rating()
+	return rating
+}
+
+// RateDifficulty returns the difficulty label of g: "Easy", "Medium",
+// "Hard" or "Expert"
+func RateDifficulty(g Grid) string {
+	return RatePuzzle(g).Label
+}
+
+// RequiredLevel returns the hardest technique level needed to solve g
+// without guessing, or LevelBacktracking when the techniques do not
+func RequiredLevel(g Grid) SolveLevel {
+	return RatePuzzle(g).Level
+}
+
+// easiestStep applies the easiest technique that makes progress and
+// returns its level, or LevelBacktracking when none does
+func (s *Solver) easiestStep() SolveLevel {
+	switch {
+	case s.findNakedSingles():
+		return LevelNakedSingles
+	case s.findHiddenSingles():
+		return LevelHiddenSingles
+	case s.findNakedPairs(), s.findHiddenPairs(), s.findPointingPairs():
+		return LevelPairs
+	case s.findXWing():
+		return LevelXWing
+	}
+	return LevelBacktracking
+}
@@ -87,6 +87,12 @@ func (s *Solver) Solve() (bool, time.Duration) {
 		s.updateGlobalStatistics()
 	}()
 
+	// Exact cover search is fastest on hard puzzles; leave nearly full
+	// boards to backtracking
+	if s.advancedStrategies && s.countEmptyCells() >= dlxMinEmptyCells {
+		return s.solveDLX(), time.Since(s.startTime)
+	}
+
 	switch s.strategy {
 	case StrategyBasic:
 		return s.solveBasic(0, 0), time.Since(s.startTime)
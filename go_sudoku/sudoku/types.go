@@ -35,6 +35,7 @@ type Stats struct {
 	ConstraintSteps   uint64
 	HeuristicSteps    uint64
 	CandidateUpdates  uint64
+	DLXSteps          uint64
 	
 	// Strategy effectiveness
 	StrategySuccess   [5]uint64
@@ -6,6 +6,48 @@ import (
 	"strings"
 )
 
+// ParseGrid reads a puzzle in the 81-character interchange format: cells
+// row by row, 1-9 for clues and 0 or . for empty cells. Surrounding
+// whitespace is ignored. The grid must not repeat a value in any row,
+// column or box.
+func ParseGrid(s string) (Grid, error) {
+	var g Grid
+	s = strings.TrimSpace(s)
+	if len(s) != 81 {
+		return g, fmt.Errorf("need 81 cells, got %d", len(s))
+	}
+	for i := 0; i < 81; i++ {
+		ch := s[i]
+		switch {
+		case ch >= '1' && ch <= '9':
+			g[i/9][i%9] = int(ch - '0')
+		case ch == '0' || ch == '.':
+		default:
+			return g, fmt.Errorf("invalid character %q at row %d, column %d", ch, i/9+1, i%9+1)
+		}
+	}
+	if !g.IsValid() {
+		return g, fmt.Errorf("a row, column or box repeats a value")
+	}
+	return g, nil
+}
+
+// Encode returns the grid in the 81-character interchange format, with .
+// for empty cells
+func (g Grid) Encode() string {
+	var sb strings.Builder
+	for i := 0; i < 9; i++ {
+		for j := 0; j < 9; j++ {
+			if g[i][j] == 0 {
+				sb.WriteByte('.')
+			} else {
+				sb.WriteByte(byte('0' + g[i][j]))
+			}
+		}
+	}
+	return sb.String()
+}
+
 // DifficultyString returns a string representation of difficulty
 func (d Difficulty) String() string {
 	names := []string{"Easy", "Medium", "Hard", "Extreme", "Unknown"}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counts are what wc reports
+type Counts struct {
+	Lines, Words, Chars, Bytes int
+}
+
+// WordCount is how often a word occurs
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// Stats are the counts of a text and more about its lines and words
+type Stats struct {
+	Counts
+	Longest int
+	Average float64
+	Blank   int
+	Unique  int
+	Top     []WordCount
+}
+
+// count counts text as wc does, except that a last line without a
+// newline still counts
+func count(text string) Counts {
+	c := Counts{
+		Lines: strings.Count(text, "\n"),
+		Words: len(strings.Fields(text)),
+		Chars: utf8.RuneCountInString(text),
+		Bytes: len(text),
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		c.Lines++
+	}
+	return c
+}
+
+// normalize folds a word for counting: lower case, without the
+// punctuation around it
+func normalize(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+// stats computes the statistics of text, keeping the top most common
+// words
+func stats(text string, top int) Stats {
+	s := Stats{Counts: count(text)}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+	total := 0
+	for _, line := range lines {
+		n := utf8.RuneCountInString(line)
+		total += n
+		s.Longest = max(s.Longest, n)
+		if strings.TrimSpace(line) == "" {
+			s.Blank++
+		}
+	}
+	if len(lines) > 0 {
+		s.Average = float64(total) / float64(len(lines))
+	}
+
+	freq := make(map[string]int)
+	seen := make(map[string]struct{})
+	for _, field := range strings.Fields(text) {
+		word := normalize(field)
+		if word == "" {
+			continue
+		}
+		seen[word] = struct{}{}
+		freq[word]++
+	}
+	s.Unique = len(seen)
+
+	for word, n := range freq {
+		s.Top = append(s.Top, WordCount{word, n})
+	}
+	sort.Slice(s.Top, func(i, j int) bool {
+		if s.Top[i].Count != s.Top[j].Count {
+			return s.Top[i].Count > s.Top[j].Count
+		}
+		return s.Top[i].Word < s.Top[j].Word
+	})
+	if len(s.Top) > top {
+		s.Top = s.Top[:top]
+	}
+	return s
+}
+
+// summary renders counts for the message line
+func summary(c Counts) string {
+	return fmt.Sprintf("%d lines, %d words, %d characters, %d bytes", c.Lines, c.Words, c.Chars, c.Bytes)
+}
+
+// formatStats renders statistics for *wc-stats*
+func formatStats(name string, s Stats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Statistics for %s\n\n", name)
+	fmt.Fprintf(&b, "Lines:               %d\n", s.Lines)
+	fmt.Fprintf(&b, "Words:               %d\n", s.Words)
+	fmt.Fprintf(&b, "Characters:          %d\n", s.Chars)
+	fmt.Fprintf(&b, "Bytes:               %d\n", s.Bytes)
+	fmt.Fprintf(&b, "Longest line:        %d characters\n", s.Longest)
+	fmt.Fprintf(&b, "Average line length: %.1f characters\n", s.Average)
+	fmt.Fprintf(&b, "Blank lines:         %d\n", s.Blank)
+	fmt.Fprintf(&b, "Unique words:        %d\n", s.Unique)
+	if len(s.Top) == 0 {
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\nMost common words\n\n")
+	width := 0
+	for _, w := range s.Top {
+		width = max(width, utf8.RuneCountInString(w.Word))
+	}
+	for i, w := range s.Top {
+		pad := width - utf8.RuneCountInString(w.Word)
+		fmt.Fprintf(&b, "%3d. %s%s  %d\n", i+1, w.Word, strings.Repeat(" ", pad), w.Count)
+	}
+	return b.String()
+}
+
+// lineColToOffset converts a 1-based line and 0-based byte column to an
+// offset in text, clamping to the line and text
+func lineColToOffset(text string, line, col int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	end := len(text)
+	if i := strings.IndexByte(text[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return min(offset+max(col, 0), end)
+}
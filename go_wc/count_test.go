@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want Counts
+	}{
+		{"", Counts{}},
+		{"one two\nthree\n", Counts{2, 3, 14, 14}},
+		{"no newline", Counts{1, 2, 10, 10}},
+		{"café au lait\n\n", Counts{2, 3, 14, 15}},
+	}
+	for _, tt := range tests {
+		if got := count(tt.text); got != tt.want {
+			t.Errorf("count(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := stats("The cat sat.\n\nthe CAT, the mat\n  \n", 2)
+	if s.Lines != 4 || s.Longest != 16 || s.Blank != 2 || s.Unique != 4 {
+		t.Errorf("got %+v", s)
+	}
+	if s.Average != 7.5 {
+		t.Errorf("average %v, want 7.5", s.Average)
+	}
+	want := []WordCount{{"the", 3}, {"cat", 2}}
+	if !reflect.DeepEqual(s.Top, want) {
+		t.Errorf("top %+v, want %+v", s.Top, want)
+	}
+
+	if empty := stats("", 10); empty.Average != 0 || empty.Top != nil {
+		t.Errorf("empty text: %+v", empty)
+	}
+}
+
+func TestLineColToOffset(t *testing.T) {
+	text := "ab\ncdef\ng"
+	tests := []struct{ line, col, want int }{
+		{1, 0, 0},
+		{2, 2, 5},
+		{2, 99, 7},
+		{3, 1, 9},
+		{9, 0, 9},
+	}
+	for _, tt := range tests {
+		if got := lineColToOffset(text, tt.line, tt.col); got != tt.want {
+			t.Errorf("lineColToOffset(%d, %d) = %d, want %d", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
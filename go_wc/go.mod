@@ -0,0 +1,3 @@
+module go_wc
+
+go 1.21
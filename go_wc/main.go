@@ -0,0 +1,150 @@
+// go_wc - Word count and text statistics for μEmacs
+//
+// Commands:
+//   wc-buffer  - Show the buffer's lines, words, characters and bytes
+//   wc-region  - Show the same counts for the region between mark and
+//                point
+//   wc-verbose - Show fuller statistics for the buffer in *wc-stats*:
+//                line lengths, blank lines, unique and most common words
+//
+// Words are runs of non-space characters, as for wc; unique and common
+// words ignore case and surrounding punctuation.
+//
+// Built with CGO as a shared library for μEmacs extension system.
+
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Bridge function declarations (implemented in bridge.c)
+extern void api_message(const char *msg);
+extern void api_log_info(const char *msg);
+extern void api_log_error(const char *msg);
+extern void *api_current_buffer(void);
+extern const char *api_buffer_name(void *bp);
+extern char *api_buffer_contents(void *bp, size_t *len);
+extern void api_get_point(int *line, int *col);
+extern void api_set_point(int line, int col);
+extern int api_get_mark(int *line, int *col);
+extern void *api_buffer_create(const char *name);
+extern int api_buffer_switch(void *bp);
+extern int api_buffer_clear(void *bp);
+extern int api_buffer_insert(const char *text, size_t len);
+extern void api_free(void *ptr);
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// statsBuffer shows the fuller statistics
+const statsBuffer = "*wc-stats*"
+
+// topWords is how many of the most common words *wc-stats* lists
+const topWords = 10
+
+// message shows a formatted message in the echo area
+func message(format string, args ...interface{}) {
+	cmsg := C.CString(fmt.Sprintf(format, args...))
+	C.api_message(cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
+
+// insertText inserts text at point in the current buffer
+func insertText(text string) {
+	ctext := C.CString(text)
+	C.api_buffer_insert(ctext, C.size_t(len(text)))
+	C.free(unsafe.Pointer(ctext))
+}
+
+// bufferText returns the contents of a buffer
+func bufferText(bp unsafe.Pointer) (string, bool) {
+	var length C.size_t
+	cContents := C.api_buffer_contents(bp, &length)
+	if cContents == nil {
+		return "", false
+	}
+	defer C.api_free(unsafe.Pointer(cContents))
+	return C.GoStringN(cContents, C.int(length)), true
+}
+
+// currentText returns the current buffer, its name and its text
+func currentText(cmd string) (unsafe.Pointer, string, string, bool) {
+	bp := C.api_current_buffer()
+	if bp == nil {
+		return nil, "", "", false
+	}
+	text, ok := bufferText(bp)
+	if !ok {
+		message("%s: cannot read buffer", cmd)
+		return nil, "", "", false
+	}
+	name := ""
+	if cname := C.api_buffer_name(bp); cname != nil {
+		name = C.GoString(cname)
+	}
+	return bp, name, text, true
+}
+
+//export go_wc_buffer
+func go_wc_buffer(f, n C.int) C.int {
+	_, _, text, ok := currentText("wc-buffer")
+	if !ok {
+		return 0
+	}
+	message("Buffer: %s", summary(count(text)))
+	return 1
+}
+
+//export go_wc_region
+func go_wc_region(f, n C.int) C.int {
+	_, _, text, ok := currentText("wc-region")
+	if !ok {
+		return 0
+	}
+	var line, col, markLine, markCol C.int
+	C.api_get_point(&line, &col)
+	if C.api_get_mark(&markLine, &markCol) == 0 || markLine <= 0 {
+		message("wc-region: no mark set")
+		return 0
+	}
+	point := lineColToOffset(text, int(line), int(col))
+	mark := lineColToOffset(text, int(markLine), int(markCol))
+	message("Region: %s", summary(count(text[min(point, mark):max(point, mark)])))
+	return 1
+}
+
+//export go_wc_verbose
+func go_wc_verbose(f, n C.int) C.int {
+	_, name, text, ok := currentText("wc-verbose")
+	if !ok {
+		return 0
+	}
+	if name == statsBuffer {
+		message("wc-verbose: run it from the buffer to count")
+		return 0
+	}
+	s := stats(text, topWords)
+
+	cname := C.CString(statsBuffer)
+	defer C.free(unsafe.Pointer(cname))
+	out := C.api_buffer_create(cname)
+	if out == nil {
+		return 0
+	}
+	C.api_buffer_switch(out)
+	C.api_buffer_clear(out)
+	insertText(formatStats(name, s))
+	C.api_set_point(1, 0)
+	message("%s: %s", name, summary(s.Counts))
+	return 1
+}
+
+func main() {
+	// Required for CGO shared library, but never called
+}